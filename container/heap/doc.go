@@ -0,0 +1,2 @@
+// Implementation of a thread-safe binary heap container
+package heap
@@ -0,0 +1,163 @@
+package heap
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// SafeHeap is a thread-safe binary heap.  The heap's ordering is defined by
+// the less comparator, i.e., Pop returns the smallest item when less is "<"
+// (min heap), and the largest item when less is ">" (max heap).
+type SafeHeap struct {
+	mutex sync.Mutex
+	less  func(a interface{}, b interface{}) bool
+	items []interface{}
+}
+
+// This returns an empty heap ordered by the less comparator.
+func NewSafeHeap(less func(a interface{}, b interface{}) bool) *SafeHeap {
+	return &SafeHeap{
+		less:  less,
+		items: make([]interface{}, 0),
+	}
+}
+
+// Len returns the number of items in the heap.
+func (h *SafeHeap) Len() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.items)
+}
+
+// Push adds an item to the heap in O(log n).
+func (h *SafeHeap) Push(item interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.items = append(h.items, item)
+	h.up(len(h.items) - 1)
+}
+
+// PushAll adds a batch of items to the heap.  When the batch is large
+// relative to the heap, the heap is rebuilt in O(n) (Floyd's algorithm)
+// rather than pushing each item in O(log n).
+func (h *SafeHeap) PushAll(items []interface{}) {
+	if len(items) == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	start := len(h.items)
+	h.items = append(h.items, items...)
+
+	n := len(h.items)
+	if len(items)*bits.Len(uint(n)) < n {
+		// Few items; sift each item up.
+		for i := start; i < n; i++ {
+			h.up(i)
+		}
+		return
+	}
+
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i)
+	}
+}
+
+// Pop removes and returns the smallest item (according to less).  This
+// returns false if the heap is empty.
+func (h *SafeHeap) Pop() (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.items) == 0 {
+		return nil, false
+	}
+	return h.pop(), true
+}
+
+// PopN removes and returns up to n smallest items (according to less), in
+// order.
+func (h *SafeHeap) PopN(n int) []interface{} {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if n > len(h.items) {
+		n = len(h.items)
+	}
+	if n <= 0 {
+		return []interface{}{}
+	}
+
+	result := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		result[i] = h.pop()
+	}
+	return result
+}
+
+// Peek returns the smallest item (according to less) without removing it.
+// This returns false if the heap is empty.
+func (h *SafeHeap) Peek() (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.items) == 0 {
+		return nil, false
+	}
+	return h.items[0], true
+}
+
+// Must be called while holding the mutex, with a non-empty heap.
+func (h *SafeHeap) pop() interface{} {
+	last := len(h.items) - 1
+
+	item := h.items[0]
+	h.items[0] = h.items[last]
+	h.items[last] = nil // don't hold on to the popped item
+	h.items = h.items[:last]
+
+	if last > 0 {
+		h.down(0)
+	}
+
+	return item
+}
+
+func (h *SafeHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(h.items[i], h.items[parent]) {
+			break
+		}
+		h.items[i], h.items[parent] = h.items[parent], h.items[i]
+		i = parent
+	}
+}
+
+func (h *SafeHeap) down(i int) {
+	n := len(h.items)
+	for {
+		smallest := i
+
+		left := 2*i + 1
+		if left < n && h.less(h.items[left], h.items[smallest]) {
+			smallest = left
+		}
+
+		right := left + 1
+		if right < n && h.less(h.items[right], h.items[smallest]) {
+			smallest = right
+		}
+
+		if smallest == i {
+			return
+		}
+
+		h.items[i], h.items[smallest] = h.items[smallest], h.items[i]
+		i = smallest
+	}
+}
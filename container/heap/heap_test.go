@@ -0,0 +1,174 @@
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type SafeHeapSuite struct {
+}
+
+var _ = Suite(&SafeHeapSuite{})
+
+func intLess(a interface{}, b interface{}) bool {
+	return a.(int) < b.(int)
+}
+
+func intGreater(a interface{}, b interface{}) bool {
+	return a.(int) > b.(int)
+}
+
+func randomInts(n int) []interface{} {
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = rand.Intn(1000)
+	}
+	return items
+}
+
+func sortedInts(items []interface{}) []interface{} {
+	ints := make([]int, len(items))
+	for i, item := range items {
+		ints[i] = item.(int)
+	}
+	sort.Ints(ints)
+
+	result := make([]interface{}, len(ints))
+	for i, v := range ints {
+		result[i] = v
+	}
+	return result
+}
+
+func (s *SafeHeapSuite) TestEmpty(c *C) {
+	h := NewSafeHeap(intLess)
+	c.Assert(h.Len(), Equals, 0)
+
+	_, ok := h.Pop()
+	c.Assert(ok, IsFalse)
+
+	_, ok = h.Peek()
+	c.Assert(ok, IsFalse)
+
+	c.Assert(h.PopN(3), DeepEquals, []interface{}{})
+
+	h.PushAll(nil)
+	c.Assert(h.Len(), Equals, 0)
+}
+
+func (s *SafeHeapSuite) TestMinHeap(c *C) {
+	h := NewSafeHeap(intLess)
+	for _, v := range []int{5, 3, 8, 1, 9, 1} {
+		h.Push(v)
+	}
+	c.Assert(h.Len(), Equals, 6)
+
+	v, ok := h.Peek()
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 1)
+	c.Assert(h.Len(), Equals, 6)
+
+	for _, expected := range []int{1, 1, 3, 5, 8, 9} {
+		v, ok = h.Pop()
+		c.Assert(ok, IsTrue)
+		c.Assert(v, Equals, expected)
+	}
+
+	_, ok = h.Pop()
+	c.Assert(ok, IsFalse)
+}
+
+func (s *SafeHeapSuite) TestMaxHeap(c *C) {
+	h := NewSafeHeap(intGreater)
+	h.PushAll([]interface{}{5, 3, 8, 1, 9})
+
+	c.Assert(h.PopN(3), DeepEquals, []interface{}{9, 8, 5})
+	c.Assert(h.PopN(10), DeepEquals, []interface{}{3, 1})
+	c.Assert(h.Len(), Equals, 0)
+}
+
+func (s *SafeHeapSuite) TestPushAll(c *C) {
+	for _, sizes := range [][2]int{
+		{0, 1000}, // rebuild into empty heap
+		{1000, 1}, // sift up into large heap
+		{1000, 5},
+		{10, 1000}, // rebuild into small heap
+		{100, 100},
+	} {
+		h := NewSafeHeap(intLess)
+
+		existing := randomInts(sizes[0])
+		for _, item := range existing {
+			h.Push(item)
+		}
+
+		batch := randomInts(sizes[1])
+		h.PushAll(batch)
+
+		all := append(existing, batch...)
+		c.Assert(h.Len(), Equals, len(all))
+		c.Assert(h.PopN(len(all)), DeepEquals, sortedInts(all))
+	}
+}
+
+func (s *SafeHeapSuite) TestConcurrentAccess(c *C) {
+	h := NewSafeHeap(intLess)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h.Push(j)
+			}
+			h.PushAll(randomInts(100))
+			_ = h.PopN(50)
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(h.Len(), Equals, 10*(100+100-50))
+
+	prev := -1
+	for h.Len() > 0 {
+		v, ok := h.Pop()
+		c.Assert(ok, IsTrue)
+		c.Assert(v.(int) >= prev, IsTrue)
+		prev = v.(int)
+	}
+}
+
+func BenchmarkPushAll1000(b *testing.B) {
+	items := randomInts(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h := NewSafeHeap(intLess)
+		h.PushAll(items)
+	}
+}
+
+func BenchmarkPush1000(b *testing.B) {
+	items := randomInts(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h := NewSafeHeap(intLess)
+		for _, item := range items {
+			h.Push(item)
+		}
+	}
+}
@@ -77,6 +77,20 @@ func (cache *LRUCache) Delete(key string) (val interface{}, existed bool) {
 	return val, existed
 }
 
+// RemoveOldest removes the least recently used item.  ok is false when the
+// cache is empty.
+func (cache *LRUCache) RemoveOldest() (key string, val interface{}, ok bool) {
+	elem := cache.itemsList.Back()
+	if elem == nil {
+		return "", nil, false
+	}
+
+	kv := elem.Value.(*keyValue)
+	cache.itemsList.Remove(elem)
+	delete(cache.itemsMap, kv.key)
+	return kv.key, kv.value, true
+}
+
 func (cache *LRUCache) MaxSize() int {
 	return cache.maxSize
 }
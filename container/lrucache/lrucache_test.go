@@ -79,6 +79,34 @@ func (s *LRUCacheSuite) TestBasic(c *C) {
 	c.Assert(cache.MaxSize(), Equals, 2)
 }
 
+func (s *LRUCacheSuite) TestRemoveOldest(c *C) {
+	cache := New(3)
+	cache.Set("1", 1)
+	cache.Set("2", 2)
+	cache.Set("3", 3)
+
+	// Accessing "1" makes "2" the least recently used item.
+	_, _ = cache.Get("1")
+
+	key, v, ok := cache.RemoveOldest()
+	c.Assert(ok, IsTrue)
+	c.Assert(key, Equals, "2")
+	c.Assert(v, Equals, 2)
+	c.Assert(cache.Len(), Equals, 2)
+
+	key, _, ok = cache.RemoveOldest()
+	c.Assert(ok, IsTrue)
+	c.Assert(key, Equals, "3")
+
+	key, _, ok = cache.RemoveOldest()
+	c.Assert(ok, IsTrue)
+	c.Assert(key, Equals, "1")
+
+	_, _, ok = cache.RemoveOldest()
+	c.Assert(ok, IsFalse)
+	c.Assert(cache.Len(), Equals, 0)
+}
+
 func (s *LRUCacheSuite) TestInvalidCacheSize(c *C) {
 	//Specifying nonsensical sizes result in panic
 	defer func() {
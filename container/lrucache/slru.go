@@ -0,0 +1,155 @@
+package lrucache
+
+import (
+	"container/list"
+)
+
+type slruEntry struct {
+	key         string
+	value       interface{}
+	isProtected bool
+}
+
+// SLRU is a segmented LRU cache, which is more resistant to sequential scans
+// than a plain LRU cache.  New entries are inserted into the probationary
+// segment.  An entry is promoted into the protected segment when it is
+// accessed again while in the probationary segment.  When the protected
+// segment is full, its least recently used entry is demoted back into the
+// probationary segment (instead of being evicted).  Entries are only evicted
+// from the probationary segment; hence, a scan of one-time accesses can only
+// evict probationary entries.  This is the algorithm used by InnoDB's buffer
+// pool (where the segments are called the old and new sublists).
+//
+// NOTE: like LRUCache, SLRU is not thread safe.
+type SLRU struct {
+	probationList *list.List
+	protectedList *list.List
+	itemsMap      map[string]*list.Element
+
+	probationSize int
+	protectedSize int
+
+	numGets          int64
+	numProtectedHits int64
+}
+
+// This returns a segmented LRU cache which holds at most probationSize +
+// protectedSize entries.
+func NewSLRU(probationSize int, protectedSize int) *SLRU {
+	if probationSize < 1 || protectedSize < 1 {
+		panic("nonsensical SLRU cache size specified")
+	}
+
+	return &SLRU{
+		probationList: list.New(),
+		protectedList: list.New(),
+		itemsMap:      make(map[string]*list.Element),
+		probationSize: probationSize,
+		protectedSize: protectedSize,
+	}
+}
+
+// Pushes the entry to the front of the probationary segment, evicting the
+// least recently used probationary entry when the segment is full.
+func (cache *SLRU) pushProbation(entry *slruEntry) {
+	entry.isProtected = false
+	cache.itemsMap[entry.key] = cache.probationList.PushFront(entry)
+
+	if cache.probationList.Len() > cache.probationSize {
+		removedElem := cache.probationList.Back()
+		cache.probationList.Remove(removedElem)
+		delete(cache.itemsMap, removedElem.Value.(*slruEntry).key)
+	}
+}
+
+// Moves the element to the front of the protected segment (promoting it from
+// the probationary segment if needed).
+func (cache *SLRU) access(elem *list.Element) *slruEntry {
+	entry := elem.Value.(*slruEntry)
+	if entry.isProtected {
+		cache.protectedList.MoveToFront(elem)
+		return entry
+	}
+
+	cache.probationList.Remove(elem)
+	entry.isProtected = true
+	cache.itemsMap[entry.key] = cache.protectedList.PushFront(entry)
+
+	if cache.protectedList.Len() > cache.protectedSize {
+		demotedElem := cache.protectedList.Back()
+		cache.protectedList.Remove(demotedElem)
+		cache.pushProbation(demotedElem.Value.(*slruEntry))
+	}
+
+	return entry
+}
+
+// Sets the entry's value.  Setting an existing entry counts as an access.
+func (cache *SLRU) Set(key string, val interface{}) {
+	elem, ok := cache.itemsMap[key]
+	if ok {
+		cache.access(elem).value = val
+		return
+	}
+
+	cache.pushProbation(&slruEntry{key: key, value: val})
+}
+
+func (cache *SLRU) Get(key string) (val interface{}, ok bool) {
+	cache.numGets++
+
+	elem, ok := cache.itemsMap[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*slruEntry)
+	if entry.isProtected {
+		cache.numProtectedHits++
+	}
+
+	return cache.access(elem).value, true
+}
+
+func (cache *SLRU) Delete(key string) (val interface{}, existed bool) {
+	elem, existed := cache.itemsMap[key]
+
+	if existed {
+		entry := elem.Value.(*slruEntry)
+		val = entry.value
+		if entry.isProtected {
+			cache.protectedList.Remove(elem)
+		} else {
+			cache.probationList.Remove(elem)
+		}
+		delete(cache.itemsMap, key)
+	}
+	return val, existed
+}
+
+func (cache *SLRU) Len() int {
+	return len(cache.itemsMap)
+}
+
+// This returns the number of entries in the probationary segment.
+func (cache *SLRU) ProbationLen() int {
+	return cache.probationList.Len()
+}
+
+// This returns the number of entries in the protected segment.
+func (cache *SLRU) ProtectedLen() int {
+	return cache.protectedList.Len()
+}
+
+func (cache *SLRU) MaxSize() int {
+	return cache.probationSize + cache.protectedSize
+}
+
+// This returns the fraction of Get calls which were served from the protected
+// segment (or 0 if Get was never called).
+func (cache *SLRU) HitRate() float64 {
+	if cache.numGets == 0 {
+		return 0
+	}
+	return float64(cache.numProtectedHits) / float64(cache.numGets)
+}
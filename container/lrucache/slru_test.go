@@ -0,0 +1,140 @@
+package lrucache
+
+import (
+	"strconv"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type SLRUSuite struct {
+}
+
+var _ = Suite(&SLRUSuite{})
+
+func (s *SLRUSuite) TestBasic(c *C) {
+	cache := NewSLRU(2, 2)
+	c.Assert(cache.MaxSize(), Equals, 4)
+
+	cache.Set("1", 1)
+	cache.Set("2", 2)
+	cache.Set("3", 3)
+
+	// "1" is evicted from the probationary segment.
+	c.Assert(cache.Len(), Equals, 2)
+	c.Assert(cache.ProbationLen(), Equals, 2)
+
+	v, ok := cache.Get("1")
+	c.Assert(ok, IsFalse)
+	c.Assert(v, IsNil)
+
+	// "2" is promoted.
+	v, ok = cache.Get("2")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 2)
+	c.Assert(cache.ProbationLen(), Equals, 1)
+	c.Assert(cache.ProtectedLen(), Equals, 1)
+
+	cache.Set("4", 4)
+	cache.Set("5", 5)
+
+	v, ok = cache.Get("3")
+	c.Assert(ok, IsFalse)
+
+	v, ok = cache.Get("2")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 2)
+
+	v, existed := cache.Delete("2")
+	c.Assert(existed, IsTrue)
+	c.Assert(v, Equals, 2)
+	c.Assert(cache.ProtectedLen(), Equals, 0)
+
+	_, existed = cache.Delete("2")
+	c.Assert(existed, IsFalse)
+
+	v, existed = cache.Delete("4")
+	c.Assert(existed, IsTrue)
+	c.Assert(v, Equals, 4)
+	c.Assert(cache.Len(), Equals, 1)
+}
+
+func (s *SLRUSuite) TestSetExisting(c *C) {
+	cache := NewSLRU(2, 2)
+
+	cache.Set("1", 1)
+	cache.Set("1", 10)
+	c.Assert(cache.Len(), Equals, 1)
+	c.Assert(cache.ProtectedLen(), Equals, 1)
+
+	v, ok := cache.Get("1")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, 10)
+}
+
+func (s *SLRUSuite) TestDemotion(c *C) {
+	cache := NewSLRU(2, 2)
+
+	for _, key := range []string{"1", "2", "3"} {
+		cache.Set(key, key)
+		_, ok := cache.Get(key)
+		c.Assert(ok, IsTrue)
+	}
+
+	// "1" is demoted (not evicted) when "3" is promoted.
+	c.Assert(cache.ProtectedLen(), Equals, 2)
+	c.Assert(cache.ProbationLen(), Equals, 1)
+
+	v, ok := cache.Get("1")
+	c.Assert(ok, IsTrue)
+	c.Assert(v, Equals, "1")
+
+	// Re-promoting "1" demotes "2".
+	c.Assert(cache.ProtectedLen(), Equals, 2)
+	c.Assert(cache.ProbationLen(), Equals, 1)
+
+	cache.Set("4", "4")
+	cache.Set("5", "5")
+
+	// "2" was the least recently used probationary entry.
+	_, ok = cache.Get("2")
+	c.Assert(ok, IsFalse)
+}
+
+func (s *SLRUSuite) TestScanResistance(c *C) {
+	cache := NewSLRU(2, 2)
+
+	cache.Set("hot1", 1)
+	cache.Set("hot2", 2)
+	cache.Get("hot1")
+	cache.Get("hot2")
+
+	for i := 0; i < 100; i++ {
+		cache.Set(strconv.Itoa(i), i)
+	}
+
+	_, ok := cache.Get("hot1")
+	c.Assert(ok, IsTrue)
+	_, ok = cache.Get("hot2")
+	c.Assert(ok, IsTrue)
+}
+
+func (s *SLRUSuite) TestHitRate(c *C) {
+	cache := NewSLRU(2, 2)
+	c.Assert(cache.HitRate(), Equals, 0.0)
+
+	cache.Set("1", 1)
+
+	cache.Get("1") // probationary hit
+	cache.Get("1") // protected hit
+	cache.Get("1") // protected hit
+	cache.Get("2") // miss
+
+	c.Assert(cache.HitRate(), Equals, 0.5)
+}
+
+func (s *SLRUSuite) TestInvalidSize(c *C) {
+	c.Assert(func() { NewSLRU(0, 1) }, Panics, "nonsensical SLRU cache size specified")
+	c.Assert(func() { NewSLRU(1, 0) }, Panics, "nonsensical SLRU cache size specified")
+}
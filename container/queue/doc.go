@@ -0,0 +1,2 @@
+// Concurrent queue containers, e.g., for parallel task schedulers
+package queue
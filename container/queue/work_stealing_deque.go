@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+const initialDequeLogSize = 5 // i.e., 32 slots
+
+// A fixed size circular array.  Indices are not wrapped by the caller.
+type circularArray struct {
+	logSize uint
+	mask    int64
+	slots   []unsafe.Pointer // *interface{}
+}
+
+func newCircularArray(logSize uint) *circularArray {
+	size := int64(1) << logSize
+	return &circularArray{
+		logSize: logSize,
+		mask:    size - 1,
+		slots:   make([]unsafe.Pointer, size),
+	}
+}
+
+func (a *circularArray) size() int64 {
+	return a.mask + 1
+}
+
+func (a *circularArray) get(i int64) interface{} {
+	ptr := atomic.LoadPointer(&a.slots[i&a.mask])
+	if ptr == nil { // the slot was released (only seen by stale thieves)
+		return nil
+	}
+	return *(*interface{})(ptr)
+}
+
+func (a *circularArray) put(i int64, item interface{}) {
+	atomic.StorePointer(&a.slots[i&a.mask], unsafe.Pointer(&item))
+}
+
+func (a *circularArray) clear(i int64) {
+	atomic.StorePointer(&a.slots[i&a.mask], nil)
+}
+
+// Returns a copy of the array with twice the size, which contains the items
+// in [top, bottom).
+func (a *circularArray) grow(top int64, bottom int64) *circularArray {
+	n := newCircularArray(a.logSize + 1)
+	for i := top; i < bottom; i++ {
+		atomic.StorePointer(
+			&n.slots[i&n.mask],
+			atomic.LoadPointer(&a.slots[i&a.mask]))
+	}
+	return n
+}
+
+// WorkStealingDeque is a single producer, multi consumer double ended queue
+// based on "Dynamic Circular Work-Stealing Deque" by Chase and Lev.  The deque
+// is owned by a single worker, which pushes and pops items at the back of the
+// deque (i.e., the owner processes its own items in LIFO order).  Other
+// workers (thieves) take items from the front of the deque.  The deque is lock
+// free; the owner only synchronizes with thieves (via compare and swap) when
+// the deque has a single item left.
+//
+// NOTE: PushBack and PopBack must only be called by the owner goroutine.
+// StealFront is safe to call from any goroutine.
+type WorkStealingDeque struct {
+	top    int64          // the front index (incremented by thieves)
+	bottom int64          // the back index (only modified by the owner)
+	array  unsafe.Pointer // *circularArray
+}
+
+// Returns an empty work stealing deque.
+func NewWorkStealingDeque() *WorkStealingDeque {
+	return &WorkStealingDeque{
+		array: unsafe.Pointer(newCircularArray(initialDequeLogSize)),
+	}
+}
+
+func (d *WorkStealingDeque) loadArray() *circularArray {
+	return (*circularArray)(atomic.LoadPointer(&d.array))
+}
+
+// PushBack adds an item to the back of the deque.  The deque grows as needed.
+// This must only be called by the owner.
+func (d *WorkStealingDeque) PushBack(item interface{}) {
+	bottom := atomic.LoadInt64(&d.bottom)
+	top := atomic.LoadInt64(&d.top)
+	array := d.loadArray()
+
+	if bottom-top >= array.size()-1 {
+		array = array.grow(top, bottom)
+		atomic.StorePointer(&d.array, unsafe.Pointer(array))
+	}
+
+	array.put(bottom, item)
+	atomic.StoreInt64(&d.bottom, bottom+1)
+}
+
+// PopBack removes and returns the item at the back of the deque (i.e., the
+// most recently pushed item).  This returns false if the deque is empty, or
+// if the last item was stolen concurrently.  This must only be called by the
+// owner.
+func (d *WorkStealingDeque) PopBack() (interface{}, bool) {
+	bottom := atomic.LoadInt64(&d.bottom) - 1
+	array := d.loadArray()
+
+	// Reserve the back item before checking for thieves.
+	atomic.StoreInt64(&d.bottom, bottom)
+	top := atomic.LoadInt64(&d.top)
+
+	if bottom < top { // empty
+		atomic.StoreInt64(&d.bottom, top)
+		return nil, false
+	}
+
+	item := array.get(bottom)
+	if bottom > top {
+		// Thieves can't reach the item, hence it's safe to release the slot.
+		array.clear(bottom)
+		return item, true
+	}
+
+	// This is the last item, race against thieves for it.
+	ok := atomic.CompareAndSwapInt64(&d.top, top, top+1)
+	atomic.StoreInt64(&d.bottom, top+1)
+	if !ok {
+		return nil, false
+	}
+	return item, true
+}
+
+// StealFront removes and returns the item at the front of the deque (i.e.,
+// the least recently pushed item).  This returns false if the deque is empty,
+// or if the thief lost the race for the front item to another thief (or to
+// the owner), in which case the caller may retry.  This is safe to call from
+// any goroutine.
+func (d *WorkStealingDeque) StealFront() (interface{}, bool) {
+	top := atomic.LoadInt64(&d.top)
+	bottom := atomic.LoadInt64(&d.bottom)
+
+	if top >= bottom { // empty
+		return nil, false
+	}
+
+	// NOTE: the item must be read before the compare and swap, since the
+	// owner may reuse the slot once top is incremented.
+	item := d.loadArray().get(top)
+	if !atomic.CompareAndSwapInt64(&d.top, top, top+1) {
+		return nil, false
+	}
+	return item, true
+}
+
+// Len returns the number of items in the deque.  When the deque is accessed
+// concurrently, the result is only an approximation.
+func (d *WorkStealingDeque) Len() int {
+	size := atomic.LoadInt64(&d.bottom) - atomic.LoadInt64(&d.top)
+	if size < 0 {
+		return 0
+	}
+	return int(size)
+}
@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type WorkStealingDequeSuite struct {
+}
+
+var _ = Suite(&WorkStealingDequeSuite{})
+
+func (s *WorkStealingDequeSuite) TestEmpty(c *C) {
+	d := NewWorkStealingDeque()
+	c.Assert(d.Len(), Equals, 0)
+
+	_, ok := d.PopBack()
+	c.Assert(ok, IsFalse)
+
+	_, ok = d.StealFront()
+	c.Assert(ok, IsFalse)
+
+	c.Assert(d.Len(), Equals, 0)
+}
+
+func (s *WorkStealingDequeSuite) TestPopBack(c *C) {
+	d := NewWorkStealingDeque()
+	for i := 0; i < 10; i++ {
+		d.PushBack(i)
+	}
+	c.Assert(d.Len(), Equals, 10)
+
+	for i := 9; i >= 0; i-- {
+		item, ok := d.PopBack()
+		c.Assert(ok, IsTrue)
+		c.Assert(item, Equals, i)
+	}
+
+	_, ok := d.PopBack()
+	c.Assert(ok, IsFalse)
+	c.Assert(d.Len(), Equals, 0)
+}
+
+func (s *WorkStealingDequeSuite) TestStealFront(c *C) {
+	d := NewWorkStealingDeque()
+	for i := 0; i < 10; i++ {
+		d.PushBack(i)
+	}
+
+	for i := 0; i < 10; i++ {
+		item, ok := d.StealFront()
+		c.Assert(ok, IsTrue)
+		c.Assert(item, Equals, i)
+	}
+
+	_, ok := d.StealFront()
+	c.Assert(ok, IsFalse)
+	c.Assert(d.Len(), Equals, 0)
+}
+
+func (s *WorkStealingDequeSuite) TestMixed(c *C) {
+	d := NewWorkStealingDeque()
+	d.PushBack(1)
+	d.PushBack(2)
+	d.PushBack(3)
+
+	item, ok := d.StealFront()
+	c.Assert(ok, IsTrue)
+	c.Assert(item, Equals, 1)
+
+	item, ok = d.PopBack()
+	c.Assert(ok, IsTrue)
+	c.Assert(item, Equals, 3)
+
+	d.PushBack(4)
+	c.Assert(d.Len(), Equals, 2)
+
+	item, ok = d.StealFront()
+	c.Assert(ok, IsTrue)
+	c.Assert(item, Equals, 2)
+
+	item, ok = d.PopBack()
+	c.Assert(ok, IsTrue)
+	c.Assert(item, Equals, 4)
+
+	_, ok = d.PopBack()
+	c.Assert(ok, IsFalse)
+	_, ok = d.StealFront()
+	c.Assert(ok, IsFalse)
+}
+
+func (s *WorkStealingDequeSuite) TestGrow(c *C) {
+	d := NewWorkStealingDeque()
+
+	// Advance the indices so that the items wrap around the initial array.
+	for i := 0; i < 20; i++ {
+		d.PushBack(i)
+		_, ok := d.StealFront()
+		c.Assert(ok, IsTrue)
+	}
+
+	n := 1000
+	for i := 0; i < n; i++ {
+		d.PushBack(i)
+	}
+	c.Assert(d.Len(), Equals, n)
+
+	for i := 0; i < n/2; i++ {
+		item, ok := d.StealFront()
+		c.Assert(ok, IsTrue)
+		c.Assert(item, Equals, i)
+	}
+	for i := n - 1; i >= n/2; i-- {
+		item, ok := d.PopBack()
+		c.Assert(ok, IsTrue)
+		c.Assert(item, Equals, i)
+	}
+	c.Assert(d.Len(), Equals, 0)
+}
+
+func (s *WorkStealingDequeSuite) TestConcurrentSteal(c *C) {
+	d := NewWorkStealingDeque()
+
+	numItems := 100000
+	numThieves := 4
+
+	taken := make([]int32, numItems)
+	var mutex sync.Mutex
+	take := func(item interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		taken[item.(int)]++
+	}
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < numThieves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, ok := d.StealFront()
+				if ok {
+					take(item)
+					continue
+				}
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < numItems; i++ {
+		d.PushBack(i)
+		if i%3 == 0 {
+			item, ok := d.PopBack()
+			if ok {
+				take(item)
+			}
+		}
+	}
+	for {
+		item, ok := d.PopBack()
+		if !ok {
+			break
+		}
+		take(item)
+	}
+
+	close(done)
+	wg.Wait()
+
+	// Every item is taken exactly once.
+	for i, count := range taken {
+		c.Assert(count, Equals, int32(1), Commentf("item %d", i))
+	}
+}
@@ -0,0 +1,145 @@
+// Package ring provides fixed capacity ring buffers.
+package ring
+
+import (
+	"context"
+	"sync"
+)
+
+// BlockingBuffer is a thread-safe, fixed capacity FIFO ring buffer.  Push
+// blocks while the buffer is full, and Pop blocks while the buffer is empty.
+// Unlike a buffered channel, blocked calls can be canceled via their context,
+// and the buffer's state can be inspected.
+//
+// NOTE: go 1.13 has no generics; items are stored as interface{}.
+type BlockingBuffer struct {
+	mutex sync.Mutex
+	items []interface{}
+	head  int // index of the oldest item.
+	size  int
+
+	// Blocked calls wait for these channels to be closed.  A channel is only
+	// closed (and replaced) when there are waiters, hence the non-blocking
+	// paths don't allocate.
+	notEmpty    chan struct{}
+	notFull     chan struct{}
+	popWaiters  int
+	pushWaiters int
+}
+
+// This returns a blocking buffer which holds up to capacity items.
+func NewBlockingBuffer(capacity int) *BlockingBuffer {
+	if capacity < 1 {
+		panic("nonsensical ring buffer capacity specified")
+	}
+
+	return &BlockingBuffer{
+		items:    make([]interface{}, capacity),
+		notEmpty: make(chan struct{}),
+		notFull:  make(chan struct{}),
+	}
+}
+
+// Push appends the item to the end of the buffer.  When the buffer is full,
+// Push blocks until an item is popped, or until the context is done (in
+// which case the context's error is returned, and the item is not added).
+func (b *BlockingBuffer) Push(ctx context.Context, item interface{}) error {
+	for {
+		b.mutex.Lock()
+		if b.size < len(b.items) {
+			b.items[(b.head+b.size)%len(b.items)] = item
+			b.size++
+
+			if b.popWaiters > 0 {
+				close(b.notEmpty)
+				b.notEmpty = make(chan struct{})
+			}
+			b.mutex.Unlock()
+			return nil
+		}
+
+		b.pushWaiters++
+		notFull := b.notFull
+		b.mutex.Unlock()
+
+		err := b.wait(ctx, notFull, &b.pushWaiters)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Pop removes and returns the item at the front of the buffer.  When the
+// buffer is empty, Pop blocks until an item is pushed, or until the context
+// is done (in which case the context's error is returned).
+func (b *BlockingBuffer) Pop(ctx context.Context) (interface{}, error) {
+	for {
+		b.mutex.Lock()
+		if b.size > 0 {
+			item := b.items[b.head]
+			b.items[b.head] = nil // allow the item to be garbage collected.
+			b.head = (b.head + 1) % len(b.items)
+			b.size--
+
+			if b.pushWaiters > 0 {
+				close(b.notFull)
+				b.notFull = make(chan struct{})
+			}
+			b.mutex.Unlock()
+			return item, nil
+		}
+
+		b.popWaiters++
+		notEmpty := b.notEmpty
+		b.mutex.Unlock()
+
+		err := b.wait(ctx, notEmpty, &b.popWaiters)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// This waits for the signal channel to be closed, or for the context to be
+// done.  waiters is decremented (under the mutex) once the wait is over.
+func (b *BlockingBuffer) wait(
+	ctx context.Context,
+	signal chan struct{},
+	waiters *int) error {
+
+	var err error
+	select {
+	case <-signal:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	b.mutex.Lock()
+	*waiters--
+	b.mutex.Unlock()
+
+	return err
+}
+
+// Len returns the number of items in the buffer.
+func (b *BlockingBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.size
+}
+
+// Cap returns the buffer's capacity.
+func (b *BlockingBuffer) Cap() int {
+	return len(b.items)
+}
+
+// IsFull returns true if Push would block.
+func (b *BlockingBuffer) IsFull() bool {
+	return b.Len() == len(b.items)
+}
+
+// IsEmpty returns true if Pop would block.
+func (b *BlockingBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
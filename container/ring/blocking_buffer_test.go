@@ -0,0 +1,229 @@
+package ring
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type BlockingBufferSuite struct {
+}
+
+var _ = Suite(&BlockingBufferSuite{})
+
+func (s *BlockingBufferSuite) TestPushPop(c *C) {
+	b := NewBlockingBuffer(3)
+	ctx := context.Background()
+
+	c.Assert(b.Cap(), Equals, 3)
+	c.Assert(b.Len(), Equals, 0)
+	c.Assert(b.IsEmpty(), IsTrue)
+	c.Assert(b.IsFull(), IsFalse)
+
+	// Wrap around the end of the ring a few times.
+	next := 0
+	for i := 0; i < 10; i++ {
+		c.Assert(b.Push(ctx, i), IsNil)
+		if b.Len() < 2 {
+			continue
+		}
+
+		item, err := b.Pop(ctx)
+		c.Assert(err, IsNil)
+		c.Assert(item, Equals, next)
+		next++
+	}
+
+	c.Assert(b.Push(ctx, 10), IsNil)
+	c.Assert(b.Push(ctx, 11), IsNil)
+	c.Assert(b.Len(), Equals, 3)
+	c.Assert(b.IsFull(), IsTrue)
+	c.Assert(b.IsEmpty(), IsFalse)
+
+	for ; next <= 11; next++ {
+		item, err := b.Pop(ctx)
+		c.Assert(err, IsNil)
+		c.Assert(item, Equals, next)
+	}
+	c.Assert(b.IsEmpty(), IsTrue)
+}
+
+func (s *BlockingBufferSuite) TestInvalidCapacity(c *C) {
+	c.Assert(
+		func() { NewBlockingBuffer(0) },
+		Panics,
+		"nonsensical ring buffer capacity specified")
+}
+
+func (s *BlockingBufferSuite) TestPushCanceled(c *C) {
+	b := NewBlockingBuffer(1)
+	c.Assert(b.Push(context.Background(), 1), IsNil)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+
+	c.Assert(b.Push(ctx, 2), Equals, context.DeadlineExceeded)
+	c.Assert(b.Len(), Equals, 1)
+
+	item, err := b.Pop(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(item, Equals, 1)
+}
+
+func (s *BlockingBufferSuite) TestPopCanceled(c *C) {
+	b := NewBlockingBuffer(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	item, err := b.Pop(ctx)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(item, IsNil)
+
+	// A canceled context doesn't prevent non-blocking pushes.
+	c.Assert(b.Push(ctx, 1), IsNil)
+	c.Assert(b.Len(), Equals, 1)
+}
+
+func (s *BlockingBufferSuite) TestBlocking(c *C) {
+	b := NewBlockingBuffer(1)
+	ctx := context.Background()
+
+	popped := make(chan interface{})
+	go func() {
+		item, err := b.Pop(ctx)
+		c.Check(err, IsNil)
+		popped <- item
+	}()
+
+	select {
+	case <-popped:
+		c.Fatal("Pop should block on empty buffer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Assert(b.Push(ctx, 1), IsNil)
+	c.Assert(<-popped, Equals, 1)
+
+	c.Assert(b.Push(ctx, 2), IsNil)
+
+	pushed := make(chan struct{})
+	go func() {
+		c.Check(b.Push(ctx, 3), IsNil)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		c.Fatal("Push should block on full buffer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	item, err := b.Pop(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(item, Equals, 2)
+
+	<-pushed
+	item, err = b.Pop(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(item, Equals, 3)
+}
+
+func (s *BlockingBufferSuite) TestConcurrent(c *C) {
+	b := NewBlockingBuffer(4)
+	ctx := context.Background()
+
+	numProducers := 4
+	numItems := 1000
+
+	wg := sync.WaitGroup{}
+	for p := 0; p < numProducers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < numItems; i++ {
+				c.Check(b.Push(ctx, p*numItems+i), IsNil)
+			}
+		}(p)
+	}
+
+	results := make(chan []int, numProducers)
+	for p := 0; p < numProducers; p++ {
+		go func() {
+			items := make([]int, 0, numItems)
+			for i := 0; i < numItems; i++ {
+				item, err := b.Pop(ctx)
+				c.Check(err, IsNil)
+				items = append(items, item.(int))
+			}
+			results <- items
+		}()
+	}
+
+	seen := make(map[int]bool)
+	for p := 0; p < numProducers; p++ {
+		for _, item := range <-results {
+			c.Assert(seen[item], IsFalse)
+			seen[item] = true
+		}
+	}
+	wg.Wait()
+
+	c.Assert(seen, HasLen, numProducers*numItems)
+	c.Assert(b.IsEmpty(), IsTrue)
+}
+
+const benchmarkCapacity = 64
+
+func BenchmarkBlockingBufferSPSC(b *testing.B) {
+	buf := NewBlockingBuffer(benchmarkCapacity)
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			_, _ = buf.Pop(ctx)
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = buf.Push(ctx, i)
+	}
+	<-done
+}
+
+func BenchmarkChannelSPSC(b *testing.B) {
+	ch := make(chan interface{}, benchmarkCapacity)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			<-ch
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch <- i
+	}
+	<-done
+}
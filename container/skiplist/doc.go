@@ -0,0 +1,2 @@
+// Ordered key-value skip lists, safe for concurrent use
+package skiplist
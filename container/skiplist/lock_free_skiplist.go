@@ -0,0 +1,292 @@
+package skiplist
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+type lockFreeNode struct {
+	key      interface{}
+	value    unsafe.Pointer   // *interface{}
+	next     []unsafe.Pointer // *lockFreeNode
+	topLevel int
+
+	lock        sync.Mutex
+	marked      int32 // logically deleted
+	fullyLinked int32
+}
+
+func newLockFreeNode(
+	key interface{},
+	value interface{},
+	topLevel int) *lockFreeNode {
+
+	n := &lockFreeNode{
+		key:      key,
+		next:     make([]unsafe.Pointer, topLevel+1),
+		topLevel: topLevel,
+	}
+	n.storeValue(value)
+	return n
+}
+
+func (n *lockFreeNode) loadNext(level int) *lockFreeNode {
+	return (*lockFreeNode)(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *lockFreeNode) storeNext(level int, next *lockFreeNode) {
+	atomic.StorePointer(&n.next[level], unsafe.Pointer(next))
+}
+
+func (n *lockFreeNode) loadValue() interface{} {
+	return *(*interface{})(atomic.LoadPointer(&n.value))
+}
+
+func (n *lockFreeNode) storeValue(value interface{}) {
+	atomic.StorePointer(&n.value, unsafe.Pointer(&value))
+}
+
+func (n *lockFreeNode) isMarked() bool {
+	return atomic.LoadInt32(&n.marked) == 1
+}
+
+func (n *lockFreeNode) isFullyLinked() bool {
+	return atomic.LoadInt32(&n.fullyLinked) == 1
+}
+
+// LockFreeSkipList is a concurrent skip list based on "A Simple Optimistic
+// Skip-List Algorithm" by Herlihy et al.  Get and Range are lock free (and
+// never block).  Put and Delete do not use a global lock; they only lock the
+// modified node and its immediate predecessors, hence writers which modify
+// different parts of the list proceed in parallel.
+type LockFreeSkipList struct {
+	compare Compare
+	levels  levelGenerator
+
+	head   *lockFreeNode
+	length int64
+}
+
+// Returns an empty lock free skip list.
+func NewLockFreeSkipList(compare Compare) *LockFreeSkipList {
+	return &LockFreeSkipList{
+		compare: compare,
+		head:    newLockFreeNode(nil, nil, maxLevel-1),
+	}
+}
+
+// Populates the predecessors and successors of key on each level.  Returns
+// the highest level on which key is found, or -1 when the key is not found.
+// (nil successors are treated as +infinity.)
+func (s *LockFreeSkipList) find(
+	key interface{},
+	preds []*lockFreeNode,
+	succs []*lockFreeNode) int {
+
+	foundLevel := -1
+	pred := s.head
+	for level := maxLevel - 1; level >= 0; level-- {
+		curr := pred.loadNext(level)
+		cmp := 1
+		for curr != nil {
+			cmp = s.compare(curr.key, key)
+			if cmp >= 0 {
+				break
+			}
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+
+		if foundLevel == -1 && curr != nil && cmp == 0 {
+			foundLevel = level
+		}
+		preds[level] = pred
+		succs[level] = curr
+	}
+	return foundLevel
+}
+
+// Unlocks the distinct predecessors locked on levels [0, highestLocked].
+func unlockPreds(preds []*lockFreeNode, highestLocked int) {
+	var prev *lockFreeNode
+	for level := 0; level <= highestLocked; level++ {
+		if preds[level] != prev {
+			preds[level].lock.Unlock()
+			prev = preds[level]
+		}
+	}
+}
+
+// See SkipList for documentation.
+func (s *LockFreeSkipList) Put(key interface{}, value interface{}) bool {
+	topLevel := s.levels.randomLevel() - 1
+
+	var preds, succs [maxLevel]*lockFreeNode
+	for {
+		foundLevel := s.find(key, preds[:], succs[:])
+		if foundLevel != -1 {
+			found := succs[foundLevel]
+			if !found.isMarked() {
+				// Wait for the concurrent insertion to finish.
+				for !found.isFullyLinked() {
+					runtime.Gosched()
+				}
+				found.storeValue(value)
+				return false
+			}
+			// The node is being deleted; retry.
+			continue
+		}
+
+		// Lock the predecessors (from the bottom up) and validate that the
+		// predecessors / successors are still adjacent.
+		highestLocked := -1
+		valid := true
+		var prev *lockFreeNode
+		for level := 0; valid && level <= topLevel; level++ {
+			pred := preds[level]
+			succ := succs[level]
+			if pred != prev {
+				pred.lock.Lock()
+				highestLocked = level
+				prev = pred
+			} else {
+				highestLocked = level
+			}
+
+			valid = !pred.isMarked() &&
+				(succ == nil || !succ.isMarked()) &&
+				pred.loadNext(level) == succ
+		}
+
+		if !valid {
+			unlockPreds(preds[:], highestLocked)
+			continue
+		}
+
+		n := newLockFreeNode(key, value, topLevel)
+		for level := 0; level <= topLevel; level++ {
+			n.next[level] = unsafe.Pointer(succs[level])
+		}
+		for level := 0; level <= topLevel; level++ {
+			preds[level].storeNext(level, n)
+		}
+		atomic.StoreInt32(&n.fullyLinked, 1)
+
+		unlockPreds(preds[:], highestLocked)
+
+		atomic.AddInt64(&s.length, 1)
+		return true
+	}
+}
+
+// See SkipList for documentation.
+func (s *LockFreeSkipList) Get(key interface{}) (interface{}, bool) {
+	pred := s.head
+	for level := maxLevel - 1; level >= 0; level-- {
+		curr := pred.loadNext(level)
+		for curr != nil {
+			cmp := s.compare(curr.key, key)
+			if cmp > 0 {
+				break
+			}
+			if cmp == 0 {
+				if curr.isFullyLinked() && !curr.isMarked() {
+					return curr.loadValue(), true
+				}
+				return nil, false
+			}
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+	}
+	return nil, false
+}
+
+// See SkipList for documentation.
+func (s *LockFreeSkipList) Delete(key interface{}) (interface{}, bool) {
+	var victim *lockFreeNode
+	isMarked := false
+
+	var preds, succs [maxLevel]*lockFreeNode
+	for {
+		foundLevel := s.find(key, preds[:], succs[:])
+
+		if !isMarked {
+			if foundLevel == -1 {
+				return nil, false
+			}
+
+			victim = succs[foundLevel]
+			if !victim.isFullyLinked() ||
+				victim.topLevel != foundLevel ||
+				victim.isMarked() {
+
+				// Either the node is not fully inserted yet (i.e., it is
+				// not in the list yet), or it is being deleted.
+				return nil, false
+			}
+
+			victim.lock.Lock()
+			if victim.isMarked() {
+				victim.lock.Unlock()
+				return nil, false
+			}
+			atomic.StoreInt32(&victim.marked, 1)
+			isMarked = true
+		}
+
+		// Lock the predecessors (from the bottom up) and validate that they
+		// still point to the victim.
+		highestLocked := -1
+		valid := true
+		var prev *lockFreeNode
+		for level := 0; valid && level <= victim.topLevel; level++ {
+			pred := preds[level]
+			if pred != prev {
+				pred.lock.Lock()
+				prev = pred
+			}
+			highestLocked = level
+
+			valid = !pred.isMarked() && pred.loadNext(level) == victim
+		}
+
+		if !valid {
+			unlockPreds(preds[:], highestLocked)
+			continue
+		}
+
+		for level := victim.topLevel; level >= 0; level-- {
+			preds[level].storeNext(level, victim.loadNext(level))
+		}
+		value := victim.loadValue()
+
+		victim.lock.Unlock()
+		unlockPreds(preds[:], highestLocked)
+
+		atomic.AddInt64(&s.length, -1)
+		return value, true
+	}
+}
+
+// See SkipList for documentation.
+func (s *LockFreeSkipList) Range(
+	f func(key interface{}, value interface{}) bool) {
+
+	for n := s.head.loadNext(0); n != nil; n = n.loadNext(0) {
+		if !n.isFullyLinked() || n.isMarked() {
+			continue
+		}
+		if !f(n.key, n.loadValue()) {
+			return
+		}
+	}
+}
+
+// See SkipList for documentation.
+func (s *LockFreeSkipList) Len() int {
+	return int(atomic.LoadInt64(&s.length))
+}
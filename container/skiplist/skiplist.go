@@ -0,0 +1,182 @@
+package skiplist
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+const maxLevel = 32
+
+// Compare returns a negative number if a < b, zero if a == b, and a positive
+// number if a > b.
+type Compare func(a interface{}, b interface{}) int
+
+// An ordered key-value map.  All implementations are thread-safe.
+type SkipList interface {
+	// Sets the key's value.  Returns true if the key was not in the list.
+	Put(key interface{}, value interface{}) bool
+
+	// Retrieves the key's value and indicates whether it exists or not.
+	Get(key interface{}) (interface{}, bool)
+
+	// Deletes the key.  Returns the deleted value and whether the key
+	// existed.
+	Delete(key interface{}) (interface{}, bool)
+
+	// Calls f for each entry, in ascending key order, until f returns false.
+	// Concurrent modifications may or may not be observed by Range.
+	Range(f func(key interface{}, value interface{}) bool)
+
+	// Retrieves the number of entries in the list.
+	Len() int
+}
+
+// Generates random levels with P(level >= l) = 2^-l, without locking.
+type levelGenerator struct {
+	state uint64
+}
+
+func (g *levelGenerator) randomLevel() int {
+	// splitmix64
+	z := atomic.AddUint64(&g.state, 0x9e3779b97f4a7c15)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+
+	return bits.TrailingZeros64(z|(1<<(maxLevel-1))) + 1
+}
+
+//
+// Mutex based skip list ------------------------------------------------------
+//
+
+type node struct {
+	key   interface{}
+	value interface{}
+	next  []*node
+}
+
+type mutexSkipList struct {
+	compare Compare
+	levels  levelGenerator
+
+	lock   sync.RWMutex
+	head   *node
+	level  int
+	length int
+}
+
+// Returns a skip list which guards all operations using a single
+// sync.RWMutex.  NOTE: Range holds the read lock while calling f, hence f
+// must not modify the list.
+func NewMutexSkipList(compare Compare) SkipList {
+	return &mutexSkipList{
+		compare: compare,
+		head:    &node{next: make([]*node, maxLevel)},
+		level:   1,
+	}
+}
+
+// Returns the rightmost node on each level whose key is less than key.
+func (s *mutexSkipList) findPredecessors(
+	key interface{},
+	preds []*node) *node {
+
+	pred := s.head
+	for level := s.level - 1; level >= 0; level-- {
+		for next := pred.next[level]; next != nil; next = pred.next[level] {
+			if s.compare(next.key, key) >= 0 {
+				break
+			}
+			pred = next
+		}
+		if preds != nil {
+			preds[level] = pred
+		}
+	}
+
+	next := pred.next[0]
+	if next != nil && s.compare(next.key, key) == 0 {
+		return next
+	}
+	return nil
+}
+
+func (s *mutexSkipList) Put(key interface{}, value interface{}) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var preds [maxLevel]*node
+	if found := s.findPredecessors(key, preds[:]); found != nil {
+		found.value = value
+		return false
+	}
+
+	level := s.levels.randomLevel()
+	for l := s.level; l < level; l++ {
+		preds[l] = s.head
+	}
+	if level > s.level {
+		s.level = level
+	}
+
+	n := &node{
+		key:   key,
+		value: value,
+		next:  make([]*node, level),
+	}
+	for l := 0; l < level; l++ {
+		n.next[l] = preds[l].next[l]
+		preds[l].next[l] = n
+	}
+
+	s.length++
+	return true
+}
+
+func (s *mutexSkipList) Get(key interface{}) (interface{}, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if found := s.findPredecessors(key, nil); found != nil {
+		return found.value, true
+	}
+	return nil, false
+}
+
+func (s *mutexSkipList) Delete(key interface{}) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var preds [maxLevel]*node
+	found := s.findPredecessors(key, preds[:])
+	if found == nil {
+		return nil, false
+	}
+
+	for l := 0; l < len(found.next); l++ {
+		preds[l].next[l] = found.next[l]
+	}
+
+	s.length--
+	return found.value, true
+}
+
+func (s *mutexSkipList) Range(f func(key interface{}, value interface{}) bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		if !f(n.key, n.value) {
+			return
+		}
+	}
+}
+
+func (s *mutexSkipList) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.length
+}
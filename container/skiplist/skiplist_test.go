@@ -0,0 +1,229 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+func intCompare(a interface{}, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+type SkipListSuite struct {
+	newList func(Compare) SkipList
+}
+
+var _ = Suite(&SkipListSuite{
+	newList: NewMutexSkipList,
+})
+
+var _ = Suite(&SkipListSuite{
+	newList: func(compare Compare) SkipList {
+		return NewLockFreeSkipList(compare)
+	},
+})
+
+func (s *SkipListSuite) keys(list SkipList) []int {
+	keys := []int{}
+	list.Range(func(key interface{}, value interface{}) bool {
+		keys = append(keys, key.(int))
+		return true
+	})
+	return keys
+}
+
+func (s *SkipListSuite) TestEmpty(c *C) {
+	list := s.newList(intCompare)
+	c.Assert(list.Len(), Equals, 0)
+
+	_, ok := list.Get(1)
+	c.Assert(ok, IsFalse)
+
+	_, ok = list.Delete(1)
+	c.Assert(ok, IsFalse)
+
+	c.Assert(s.keys(list), DeepEquals, []int{})
+}
+
+func (s *SkipListSuite) TestPutGetDelete(c *C) {
+	list := s.newList(intCompare)
+
+	c.Assert(list.Put(2, "two"), IsTrue)
+	c.Assert(list.Put(1, "one"), IsTrue)
+	c.Assert(list.Put(3, "three"), IsTrue)
+	c.Assert(list.Len(), Equals, 3)
+
+	c.Assert(list.Put(2, "TWO"), IsFalse)
+	c.Assert(list.Len(), Equals, 3)
+
+	value, ok := list.Get(2)
+	c.Assert(ok, IsTrue)
+	c.Assert(value, Equals, "TWO")
+
+	_, ok = list.Get(4)
+	c.Assert(ok, IsFalse)
+
+	value, ok = list.Delete(1)
+	c.Assert(ok, IsTrue)
+	c.Assert(value, Equals, "one")
+	c.Assert(list.Len(), Equals, 2)
+
+	_, ok = list.Get(1)
+	c.Assert(ok, IsFalse)
+
+	_, ok = list.Delete(1)
+	c.Assert(ok, IsFalse)
+
+	c.Assert(s.keys(list), DeepEquals, []int{2, 3})
+}
+
+func (s *SkipListSuite) TestOrdering(c *C) {
+	list := s.newList(intCompare)
+
+	expected := rand.Perm(1000)
+	for _, key := range expected {
+		list.Put(key, strconv.Itoa(key))
+	}
+	for i := 0; i < 1000; i += 3 {
+		_, ok := list.Delete(expected[i])
+		c.Assert(ok, IsTrue)
+	}
+
+	remaining := []int{}
+	for i, key := range expected {
+		if i%3 != 0 {
+			remaining = append(remaining, key)
+		}
+	}
+	sort.Ints(remaining)
+
+	c.Assert(list.Len(), Equals, len(remaining))
+	c.Assert(s.keys(list), DeepEquals, remaining)
+
+	for _, key := range remaining {
+		value, ok := list.Get(key)
+		c.Assert(ok, IsTrue)
+		c.Assert(value, Equals, strconv.Itoa(key))
+	}
+}
+
+func (s *SkipListSuite) TestRangeStop(c *C) {
+	list := s.newList(intCompare)
+	for i := 0; i < 10; i++ {
+		list.Put(i, i)
+	}
+
+	keys := []int{}
+	list.Range(func(key interface{}, value interface{}) bool {
+		keys = append(keys, key.(int))
+		return len(keys) < 3
+	})
+	c.Assert(keys, DeepEquals, []int{0, 1, 2})
+}
+
+func (s *SkipListSuite) TestConcurrentWriters(c *C) {
+	list := s.newList(intCompare)
+
+	numWriters := 8
+	numKeys := 500
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < numWriters; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			// Writers insert overlapping key ranges, and delete the odd keys
+			// within their own range.
+			for i := 0; i < numKeys; i++ {
+				key := w*numKeys/2 + i
+				list.Put(key, key)
+				list.Get(key - 1)
+			}
+			for i := 1; i < numKeys; i += 2 {
+				list.Delete(w*numKeys/2 + i)
+			}
+		}(w)
+	}
+
+	// Concurrent readers must always observe keys in order.
+	stop := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			prev := -1
+			list.Range(func(key interface{}, value interface{}) bool {
+				c.Check(key.(int) > prev, IsTrue)
+				prev = key.(int)
+				return true
+			})
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	<-readerDone
+
+	keys := s.keys(list)
+	c.Assert(list.Len(), Equals, len(keys))
+	for i, key := range keys {
+		if i > 0 {
+			c.Assert(key > keys[i-1], IsTrue)
+		}
+		value, ok := list.Get(key)
+		c.Assert(ok, IsTrue)
+		c.Assert(value, Equals, key)
+	}
+}
+
+func benchmarkConcurrentWriters(b *testing.B, list SkipList) {
+	numWriters := 8
+	perWriter := b.N/numWriters + 1
+
+	b.ResetTimer()
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < numWriters; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWriter; i++ {
+				key := rng.Intn(1 << 20)
+				if i%4 == 3 {
+					list.Delete(key)
+				} else {
+					list.Put(key, i)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func BenchmarkMutexSkipList8Writers(b *testing.B) {
+	benchmarkConcurrentWriters(b, NewMutexSkipList(intCompare))
+}
+
+func BenchmarkLockFreeSkipList8Writers(b *testing.B) {
+	benchmarkConcurrentWriters(b, NewLockFreeSkipList(intCompare))
+}
@@ -0,0 +1,227 @@
+// Package ttlmap is a map whose entries expire after a per entry time to
+// live, with expiry notifications.
+package ttlmap
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/time2"
+)
+
+// ExpireFunc is invoked with the expired entry's key and value.
+type ExpireFunc func(key string, val interface{})
+
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+	index     int // the entry's index within the expiry heap.
+}
+
+type expiryHeap []*entry
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// TTLMap is a string keyed map whose entries expire once their time to live
+// elapses.  Expired entries are never returned.  They are removed (and their
+// watchers are notified) lazily, by the first operation on the map after the
+// entries' expiry, or by RemoveExpired.  Since there's no background
+// goroutine, callers which rely on timely notifications should call
+// RemoveExpired periodically.
+//
+// Watchers are invoked synchronously (without holding the map's lock) by the
+// goroutine which removed the expired entries.  Explicitly deleted or
+// overwritten entries are not reported.
+//
+// TTLMap is thread safe.
+type TTLMap struct {
+	clock time2.Clock
+
+	mutex    sync.Mutex
+	entries  map[string]*entry
+	expiry   expiryHeap
+	watchers map[string][]ExpireFunc // prefix -> callbacks
+}
+
+// This returns an empty map.  When clock is nil, time2.DefaultClock is used.
+func New(clock time2.Clock) *TTLMap {
+	if clock == nil {
+		clock = time2.DefaultClock
+	}
+
+	return &TTLMap{
+		clock:    clock,
+		entries:  make(map[string]*entry),
+		watchers: make(map[string][]ExpireFunc),
+	}
+}
+
+// WatchPrefix registers fn to be invoked for every expired entry whose key
+// starts with prefix.  When multiple prefixes match a key, the callbacks are
+// invoked in order of increasing prefix length (and in registration order
+// for the same prefix).
+func (m *TTLMap) WatchPrefix(prefix string, fn ExpireFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.watchers[prefix] = append(m.watchers[prefix], fn)
+}
+
+// WatchAll registers fn to be invoked for every expired entry.  This is
+// equivalent to WatchPrefix("", fn).
+func (m *TTLMap) WatchAll(fn ExpireFunc) {
+	m.WatchPrefix("", fn)
+}
+
+// Set inserts (or replaces) the entry, which expires after ttl.
+func (m *TTLMap) Set(key string, val interface{}, ttl time.Duration) {
+	expired := m.lockAndRemoveExpired()
+	defer m.unlockAndNotify(expired)
+
+	expiresAt := m.clock.Now().Add(ttl)
+
+	if e, ok := m.entries[key]; ok {
+		e.value = val
+		e.expiresAt = expiresAt
+		heap.Fix(&m.expiry, e.index)
+		return
+	}
+
+	e := &entry{
+		key:       key,
+		value:     val,
+		expiresAt: expiresAt,
+	}
+	m.entries[key] = e
+	heap.Push(&m.expiry, e)
+}
+
+// Get returns the entry's value, or false if the entry does not exist (or
+// has expired).
+func (m *TTLMap) Get(key string) (val interface{}, ok bool) {
+	expired := m.lockAndRemoveExpired()
+	defer m.unlockAndNotify(expired)
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// TTL returns the entry's remaining time to live, or false if the entry does
+// not exist (or has expired).
+func (m *TTLMap) TTL(key string) (ttl time.Duration, ok bool) {
+	expired := m.lockAndRemoveExpired()
+	defer m.unlockAndNotify(expired)
+
+	e, ok := m.entries[key]
+	if !ok {
+		return 0, false
+	}
+	return e.expiresAt.Sub(m.clock.Now()), true
+}
+
+// Delete removes the entry without notifying the watchers.
+func (m *TTLMap) Delete(key string) (val interface{}, existed bool) {
+	expired := m.lockAndRemoveExpired()
+	defer m.unlockAndNotify(expired)
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	delete(m.entries, key)
+	heap.Remove(&m.expiry, e.index)
+	return e.value, true
+}
+
+// Len returns the number of unexpired entries.
+func (m *TTLMap) Len() int {
+	expired := m.lockAndRemoveExpired()
+	defer m.unlockAndNotify(expired)
+
+	return len(m.entries)
+}
+
+// RemoveExpired removes all expired entries, notifies the watchers, and
+// returns the number of removed entries.
+func (m *TTLMap) RemoveExpired() int {
+	expired := m.lockAndRemoveExpired()
+	m.unlockAndNotify(expired)
+
+	return len(expired)
+}
+
+// This acquires the lock and removes the expired entries (in expiry order).
+func (m *TTLMap) lockAndRemoveExpired() []*entry {
+	m.mutex.Lock()
+
+	now := m.clock.Now()
+
+	var expired []*entry
+	for len(m.expiry) > 0 && !m.expiry[0].expiresAt.After(now) {
+		e := heap.Pop(&m.expiry).(*entry)
+		delete(m.entries, e.key)
+		expired = append(expired, e)
+	}
+
+	return expired
+}
+
+// This releases the lock, and then invokes the watchers for the expired
+// entries.
+func (m *TTLMap) unlockAndNotify(expired []*entry) {
+	if len(expired) == 0 || len(m.watchers) == 0 {
+		m.mutex.Unlock()
+		return
+	}
+
+	type notification struct {
+		fn ExpireFunc
+		e  *entry
+	}
+
+	notifications := []notification{}
+	for _, e := range expired {
+		for i := 0; i <= len(e.key); i++ {
+			for _, fn := range m.watchers[e.key[:i]] {
+				notifications = append(notifications, notification{fn, e})
+			}
+		}
+	}
+
+	m.mutex.Unlock()
+
+	for _, n := range notifications {
+		n.fn(n.e.key, n.e.value)
+	}
+}
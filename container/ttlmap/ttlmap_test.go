@@ -0,0 +1,162 @@
+package ttlmap
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	"github.com/dropbox/godropbox/time2"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type TTLMapSuite struct {
+	clock *time2.MockClock
+	m     *TTLMap
+}
+
+var _ = Suite(&TTLMapSuite{})
+
+func (s *TTLMapSuite) SetUpTest(c *C) {
+	s.clock = time2.NewMockClock(time.Unix(1000, 0))
+	s.m = New(s.clock)
+}
+
+type expiredEntry struct {
+	key string
+	val interface{}
+}
+
+func (s *TTLMapSuite) TestGetSet(c *C) {
+	s.m.Set("a", 1, time.Second)
+	s.m.Set("b", 2, 2*time.Second)
+
+	val, ok := s.m.Get("a")
+	c.Check(ok, IsTrue)
+	c.Check(val, Equals, 1)
+	c.Check(s.m.Len(), Equals, 2)
+
+	ttl, ok := s.m.TTL("b")
+	c.Check(ok, IsTrue)
+	c.Check(ttl, Equals, 2*time.Second)
+
+	s.clock.Advance(time.Second)
+
+	_, ok = s.m.Get("a")
+	c.Check(ok, IsFalse)
+	val, ok = s.m.Get("b")
+	c.Check(ok, IsTrue)
+	c.Check(val, Equals, 2)
+	c.Check(s.m.Len(), Equals, 1)
+
+	// Overwriting resets the ttl.
+	s.m.Set("b", 3, 2*time.Second)
+	s.clock.Advance(time.Second)
+	val, ok = s.m.Get("b")
+	c.Check(ok, IsTrue)
+	c.Check(val, Equals, 3)
+
+	s.clock.Advance(time.Second)
+	c.Check(s.m.Len(), Equals, 0)
+}
+
+func (s *TTLMapSuite) TestDelete(c *C) {
+	expired := []expiredEntry{}
+	s.m.WatchAll(func(key string, val interface{}) {
+		expired = append(expired, expiredEntry{key, val})
+	})
+
+	s.m.Set("a", 1, time.Second)
+	s.m.Set("b", 2, time.Second)
+
+	val, existed := s.m.Delete("a")
+	c.Check(existed, IsTrue)
+	c.Check(val, Equals, 1)
+
+	_, existed = s.m.Delete("a")
+	c.Check(existed, IsFalse)
+
+	s.clock.Advance(time.Second)
+	c.Check(s.m.RemoveExpired(), Equals, 1)
+
+	// Deleted entries are not reported.
+	c.Check(expired, DeepEquals, []expiredEntry{{"b", 2}})
+}
+
+func (s *TTLMapSuite) TestWatchAll(c *C) {
+	expired := []expiredEntry{}
+	s.m.WatchAll(func(key string, val interface{}) {
+		expired = append(expired, expiredEntry{key, val})
+	})
+
+	s.m.Set("c", 3, 3*time.Second)
+	s.m.Set("a", 1, time.Second)
+	s.m.Set("b", 2, 2*time.Second)
+
+	s.clock.Advance(2 * time.Second)
+	c.Check(s.m.RemoveExpired(), Equals, 2)
+	c.Check(expired, DeepEquals, []expiredEntry{{"a", 1}, {"b", 2}})
+
+	// Expired entries are also removed lazily by other operations.
+	s.clock.Advance(time.Second)
+	_, ok := s.m.Get("c")
+	c.Check(ok, IsFalse)
+	c.Check(
+		expired,
+		DeepEquals,
+		[]expiredEntry{{"a", 1}, {"b", 2}, {"c", 3}})
+
+	c.Check(s.m.RemoveExpired(), Equals, 0)
+}
+
+func (s *TTLMapSuite) TestWatchPrefix(c *C) {
+	calls := []string{}
+	watch := func(name string) ExpireFunc {
+		return func(key string, val interface{}) {
+			calls = append(calls, name+":"+key)
+		}
+	}
+
+	s.m.WatchPrefix("user:", watch("user"))
+	s.m.WatchPrefix("user:admin:", watch("admin"))
+	s.m.WatchPrefix("session:", watch("session"))
+	s.m.WatchAll(watch("all"))
+	s.m.WatchPrefix("user:", watch("user2"))
+
+	s.m.Set("user:admin:1", 1, time.Second)
+	s.m.Set("user:2", 2, 2*time.Second)
+	s.m.Set("other", 3, 3*time.Second)
+
+	s.clock.Advance(3 * time.Second)
+	c.Check(s.m.RemoveExpired(), Equals, 3)
+
+	c.Check(calls, DeepEquals, []string{
+		"all:user:admin:1",
+		"user:user:admin:1",
+		"user2:user:admin:1",
+		"admin:user:admin:1",
+		"all:user:2",
+		"user:user:2",
+		"user2:user:2",
+		"all:other",
+	})
+}
+
+func (s *TTLMapSuite) TestWatcherMayAccessMap(c *C) {
+	// Watchers are invoked without holding the lock.
+	s.m.WatchPrefix("a", func(key string, val interface{}) {
+		s.m.Set("renewed:"+key, val, time.Second)
+	})
+
+	s.m.Set("a", 1, time.Second)
+	s.clock.Advance(time.Second)
+	c.Check(s.m.RemoveExpired(), Equals, 1)
+
+	val, ok := s.m.Get("renewed:a")
+	c.Check(ok, IsTrue)
+	c.Check(val, Equals, 1)
+}
@@ -0,0 +1,641 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// ColumnPolicy controls how AnonymizingTransformer handles a column's values.
+type ColumnPolicy int
+
+const (
+	// Replace the column's values with type consistent fake values.
+	AnonymizeColumn ColumnPolicy = iota
+
+	// Keep the column's values as is.
+	PreserveColumn
+)
+
+// Policy maps "<database>.<table>" (for all of the table's columns) or
+// "<database>.<table>.<column>" (for a single column) to the column policy.
+// Column level entries take precedence over table level entries.  Columns
+// are named col_<index> when the table map event does not include the
+// column names.  Columns without an entry are anonymized.
+type Policy map[string]ColumnPolicy
+
+// The maximum absolute time shift applied to temporal values.
+const maxAnonymizedTimeShift = 10 * 365 * 24 * time.Hour
+
+// AnonymizingTransformer replaces rows events' column values with type
+// consistent fakes, for using production binlogs in development / testing
+// environments.  Non-null values are replaced as follows:
+//   - string and blob values (including lazy blobs), and all other []byte
+//     values, are replaced by random lower case letters of the same length
+//   - integer values are replaced by random values in the column's range
+//     (int64 driver values by random values with the same sign and bit
+//     length, and unsigned BIGINT string driver values by random values
+//     beyond math.MaxInt64)
+//   - float / double values are replaced by random values with the same
+//     sign and magnitude
+//   - decimal values (including their string driver values) are replaced by
+//     random values with the same number of digits and scale
+//   - date / datetime / timestamp values are shifted by a constant (random)
+//     offset, hence deltas between values are preserved while the absolute
+//     times are not
+//   - typed array (multi-valued index) json elements (including the json
+//     array string driver values) are replaced by random values of the same
+//     type and size
+//
+// Enum and set values, time values and zero date strings are kept as is.
+// NULL values remain NULL.  Values of any other type (or of an unexpected
+// type for the column) cannot be anonymized; Transform returns an error for
+// the values' rows events instead of leaking the values.  Values decoded
+// with DecodeOptions.DriverValues are supported.  The anonymized rows events
+// do not retain the original event's bytes (see Transformer).
+//
+// Query text (query, rows query and annotate rows events) has its string
+// literals (and, for DML statements, numeric literals) replaced by random
+// values of the same length, and LOAD DATA blocks have their alphanumeric
+// bytes replaced, regardless of the policy.  Legacy (pre mysql 5.0.3) load
+// events cannot be anonymized; Transform returns an error for these events.
+//
+// AnonymizingTransformer is thread safe.
+type AnonymizingTransformer struct {
+	policy    Policy
+	timeShift time.Duration
+
+	mutex sync.Mutex
+	rng   *rand.Rand
+}
+
+// This returns an anonymizing transformer which applies the policy.  The
+// fake values (and the time shift) are derived from the seed, hence
+// transformers with the same seed generate the same values for the same
+// input.
+func NewAnonymizingTransformer(
+	policy Policy,
+	seed int64) *AnonymizingTransformer {
+
+	rng := rand.New(rand.NewSource(seed))
+
+	shift := time.Duration(rng.Int63n(int64(maxAnonymizedTimeShift)))
+	if shift == 0 {
+		shift = time.Hour
+	}
+	if rng.Intn(2) == 0 {
+		shift = -shift
+	}
+
+	return &AnonymizingTransformer{
+		policy:    policy,
+		timeShift: shift,
+		rng:       rng,
+	}
+}
+
+// TimeShift returns the offset added to temporal values.
+func (t *AnonymizingTransformer) TimeShift() time.Duration {
+	return t.timeShift
+}
+
+// See Transformer for documentation.
+func (t *AnonymizingTransformer) Transform(event Event) (Event, error) {
+	transformed, ok, err := transformRowsEvent(event, t.anonymizeRow)
+	if ok {
+		return transformed, err
+	}
+
+	switch e := event.(type) {
+	case *QueryEvent:
+		return t.anonymizeQueryEvent(e), nil
+	case *ExecuteLoadQueryEvent:
+		anonymized := *e
+		anonymized.QueryEvent = t.anonymizeQueryEvent(e.QueryEvent)
+		return &anonymized, nil
+	case *RowsQueryEvent:
+		anonymized := *e
+		anonymized.Event = scrubEventBytes(e.Event)
+		anonymized.truncatedQuery = t.anonymizeQuery(e.truncatedQuery)
+		return &anonymized, nil
+	case *MariadbAnnotateRowsLogEvent:
+		anonymized := *e
+		anonymized.Event = scrubEventBytes(e.Event)
+		anonymized.query = t.anonymizeQuery(e.query)
+		return &anonymized, nil
+	case *AppendBlockEvent:
+		anonymized := t.anonymizeBlock(e)
+		return &anonymized, nil
+	case *BeginLoadQueryEvent:
+		anonymized := *e
+		anonymized.AppendBlockEvent = t.anonymizeBlock(&e.AppendBlockEvent)
+		return &anonymized, nil
+	case *LegacyLoadEvent, *CreateFileEvent:
+		return nil, errors.Newf("Cannot anonymize legacy load event (%T)", e)
+	}
+
+	return event, nil
+}
+
+func (t *AnonymizingTransformer) anonymizeQueryEvent(
+	e *QueryEvent) *QueryEvent {
+
+	anonymized := *e
+	anonymized.Event = scrubEventBytes(e.Event)
+	anonymized.query = t.anonymizeQuery(e.query)
+	return &anonymized
+}
+
+func (t *AnonymizingTransformer) anonymizeBlock(
+	e *AppendBlockEvent) AppendBlockEvent {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	anonymized := *e
+	anonymized.Event = scrubEventBytes(e.Event)
+	anonymized.blockData = scrubBlockData(e.blockData, t.randomByte)
+	return anonymized
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) randomByte(alphabet string) byte {
+	return alphabet[t.rng.Intn(len(alphabet))]
+}
+
+func (t *AnonymizingTransformer) columnPolicy(
+	context TableContext,
+	pos int) ColumnPolicy {
+
+	table := string(context.DatabaseName()) + "." + string(context.TableName())
+	if policy, ok := t.policy[table+"."+columnName(context, pos)]; ok {
+		return policy
+	}
+	if policy, ok := t.policy[table]; ok {
+		return policy
+	}
+	return AnonymizeColumn
+}
+
+func (t *AnonymizingTransformer) anonymizeRow(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) (RowValues, error) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := make(RowValues, len(row))
+	for idx, value := range row {
+		col := usedColumns[idx]
+		if value == nil ||
+			t.columnPolicy(context, col.IndexPosition()) == PreserveColumn {
+
+			result[idx] = value
+			continue
+		}
+
+		anonymized, err := t.anonymizeValue(col.Type(), value)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"Failed to anonymize %s.%s.%s",
+				context.DatabaseName(),
+				context.TableName(),
+				columnName(context, col.IndexPosition()))
+		}
+		result[idx] = anonymized
+	}
+	return result, nil
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) anonymizeValue(
+	fieldType mysql_proto.FieldType_Type,
+	value interface{}) (interface{}, error) {
+
+	switch v := value.(type) {
+	case time.Time:
+		return v.Add(t.timeShift), nil
+	case TimeValue:
+		return v, nil
+	case *LazyBlob:
+		return &LazyBlob{raw: t.randomString(v.Len())}, nil
+	case []byte:
+		return t.randomString(len(v)), nil
+	case []interface{}:
+		// Typed array elements.
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			result[i] = t.anonymizeJsonScalar(element)
+		}
+		return result, nil
+	case Decimal:
+		return t.randomDecimal(v), nil
+	case float64:
+		return v * (0.5 + t.rng.Float64()), nil
+	case uint8, uint16, uint32, uint64:
+		return t.anonymizeUnsigned(fieldType, value)
+	case int64:
+		// Integer, enum and set driver values.
+		if isEnumOrSetType(fieldType) {
+			return v, nil
+		}
+		if isIntegerType(fieldType) {
+			return t.randomInt64(v), nil
+		}
+	case string:
+		return t.anonymizeString(fieldType, v)
+	}
+
+	return nil, errors.Newf(
+		"Cannot anonymize %s value of type %T",
+		fieldType.String(),
+		value)
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) anonymizeUnsigned(
+	fieldType mysql_proto.FieldType_Type,
+	value interface{}) (interface{}, error) {
+
+	var numBits uint
+	switch fieldType {
+	case mysql_proto.FieldType_TINY:
+		numBits = 8
+	case mysql_proto.FieldType_SHORT:
+		numBits = 16
+	case mysql_proto.FieldType_INT24:
+		numBits = 24
+	case mysql_proto.FieldType_LONG:
+		numBits = 32
+	case mysql_proto.FieldType_LONGLONG:
+		numBits = 64
+	case mysql_proto.FieldType_ENUM, mysql_proto.FieldType_SET:
+		return value, nil
+	default:
+		return nil, errors.Newf(
+			"Cannot anonymize %s value of type %T",
+			fieldType.String(),
+			value)
+	}
+
+	random := t.rng.Uint64() >> (64 - numBits)
+	switch value.(type) {
+	case uint8:
+		return uint8(random), nil
+	case uint16:
+		return uint16(random), nil
+	case uint32:
+		return uint32(random), nil
+	}
+	return random, nil
+}
+
+// String values are decimal, time, typed array and unsigned BIGINT (beyond
+// math.MaxInt64) driver values (see ToDriverValue), and zero dates (see
+// ZeroDateAsString).
+//
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) anonymizeString(
+	fieldType mysql_proto.FieldType_Type,
+	value string) (interface{}, error) {
+
+	switch fieldType {
+	case mysql_proto.FieldType_NEWDECIMAL, mysql_proto.FieldType_DECIMAL:
+		result := []byte(value)
+		for i, b := range result {
+			if isDigit(b) {
+				result[i] = byte('0' + t.rng.Intn(10))
+			}
+		}
+		return string(result), nil
+	case mysql_proto.FieldType_LONGLONG:
+		if _, err := strconv.ParseUint(value, 10, 64); err == nil {
+			// Keep the value beyond math.MaxInt64.
+			random := t.rng.Uint64() | (1 << 63)
+			return strconv.FormatUint(random, 10), nil
+		}
+	case mysql_proto.FieldType_TIME, mysql_proto.FieldType_TIME2:
+		return value, nil
+	case mysql_proto.FieldType_DATETIME,
+		mysql_proto.FieldType_DATETIME2,
+		mysql_proto.FieldType_TIMESTAMP,
+		mysql_proto.FieldType_TIMESTAMP2:
+
+		if strings.HasPrefix(value, zeroDateString) {
+			return value, nil
+		}
+	case TypedArrayFieldType:
+		return t.anonymizeJsonArray(value)
+	}
+
+	return nil, errors.Newf(
+		"Cannot anonymize %s string value",
+		fieldType.String())
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) anonymizeJsonArray(
+	array string) (interface{}, error) {
+
+	decoder := json.NewDecoder(strings.NewReader(array))
+	decoder.UseNumber()
+
+	elements := []interface{}{}
+	err := decoder.Decode(&elements)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode typed array")
+	}
+
+	for i, element := range elements {
+		// Convert the elements into the decoded typed array element types.
+		switch v := element.(type) {
+		case string:
+			element = []byte(v)
+		case json.Number:
+			element, err = parseJsonNumber(v)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		elements[i] = t.anonymizeJsonScalar(element)
+	}
+
+	return typedArrayToJson(elements)
+}
+
+func parseJsonNumber(n json.Number) (interface{}, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u, nil
+	}
+	if f, err := n.Float64(); err == nil {
+		return f, nil
+	}
+	return nil, errors.Newf("Invalid typed array number: %s", n)
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) anonymizeJsonScalar(
+	value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case []byte:
+		return t.randomString(len(v))
+	case float64:
+		return v * (0.5 + t.rng.Float64())
+	case uint64:
+		return t.randomBits(bits.Len64(v))
+	case int64:
+		return t.randomInt64(v)
+	}
+
+	// null / true / false literals.
+	return value
+}
+
+// This returns a random value with the same sign and bit length as the
+// value.  Values with the same sign and bit length fit in the same integer
+// type.
+//
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) randomInt64(value int64) int64 {
+	if value < 0 {
+		return -int64(t.randomBits(bits.Len64(uint64(-(value + 1))))) - 1
+	}
+	return int64(t.randomBits(bits.Len64(uint64(value))))
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) randomBits(numBits int) uint64 {
+	if numBits == 0 {
+		return 0
+	}
+	return t.rng.Uint64() >> uint(64-numBits)
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) randomString(length int) []byte {
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = byte('a' + t.rng.Intn(26))
+	}
+	return result
+}
+
+// NOTE: the caller must hold the mutex.
+func (t *AnonymizingTransformer) randomDecimal(d Decimal) Decimal {
+	unscaled := d.Unscaled()
+	numDigits := len(new(big.Int).Abs(unscaled).String())
+
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(numDigits)), nil)
+	random := new(big.Int).Rand(t.rng, limit)
+	if unscaled.Sign() < 0 {
+		random.Neg(random)
+	}
+
+	return NewDecimal(random, d.Scale())
+}
+
+var dmlKeywords = [][]byte{
+	[]byte("INSERT"),
+	[]byte("UPDATE"),
+	[]byte("DELETE"),
+	[]byte("REPLACE"),
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isIdentifierByte(b byte) bool {
+	return isDigit(b) ||
+		(b >= 'a' && b <= 'z') ||
+		(b >= 'A' && b <= 'Z') ||
+		b == '_' ||
+		b == '$' ||
+		b >= 0x80
+}
+
+// This returns true if the statement's first keyword is a DML keyword.
+func isDmlQuery(query []byte) bool {
+	query = bytes.TrimLeft(query, " \t\r\n(")
+	for _, keyword := range dmlKeywords {
+		if len(query) >= len(keyword) &&
+			bytes.EqualFold(query[:len(keyword)], keyword) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// This returns the index of the quoted string's closing quote (or the
+// query's length when the string is not terminated).  start is the index of
+// the opening quote.
+func closingQuoteIndex(query []byte, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++ // skip the escaped byte
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++ // doubled quote
+				continue
+			}
+			return i
+		}
+	}
+	return len(query)
+}
+
+// This replaces the query's string literals by random letters (hex / bit
+// literals by random hex / binary digits), and, for DML statements, the
+// digits of numeric literals by random digits.  The result has the same
+// length as the query.  Identifiers and comments are kept as is.
+func (t *AnonymizingTransformer) anonymizeQuery(query []byte) []byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return scrubQueryLiterals(query, t.randomByte)
+}
+
+// The alphabets of the literals' characters (see literalByteReplacer).
+const (
+	letterAlphabet = "abcdefghijklmnopqrstuvwxyz"
+	hexAlphabet    = "0123456789abcdef"
+	bitAlphabet    = "01"
+	digitAlphabet  = "0123456789"
+)
+
+// literalByteReplacer returns the replacement of a literal's character.  The
+// alphabet (one of the *Alphabet constants) determines the valid
+// replacements.
+type literalByteReplacer func(alphabet string) byte
+
+// This replaces the LOAD DATA block's alphanumeric bytes (digits by
+// digitAlphabet characters, and other identifier bytes by letterAlphabet
+// characters).  Delimiters (e.g., separators, quotes and newlines) are kept.
+func scrubBlockData(data []byte, replace literalByteReplacer) []byte {
+	block := make([]byte, len(data))
+	for i, b := range data {
+		switch {
+		case isDigit(b):
+			block[i] = replace(digitAlphabet)
+		case isIdentifierByte(b):
+			block[i] = replace(letterAlphabet)
+		default:
+			block[i] = b
+		}
+	}
+	return block
+}
+
+// This replaces the characters of the query's string literals (hex / bit
+// literals' digits), and, for DML statements, the digits of numeric
+// literals.  The result has the same length as the query.  Identifiers and
+// comments are kept as is.
+func scrubQueryLiterals(query []byte, replace literalByteReplacer) []byte {
+	result := make([]byte, len(query))
+	copy(result, query)
+
+	scrubNumbers := isDmlQuery(query)
+
+	// Whether or not the byte preceding idx is part of an identifier /
+	// keyword / number.
+	continuesToken := func(idx int) bool {
+		return idx > 0 && isIdentifierByte(result[idx-1])
+	}
+
+	for i := 0; i < len(result); {
+		b := result[i]
+		switch {
+		case b == '`':
+			i = closingQuoteIndex(result, i) + 1
+		case b == '\'' || b == '"':
+			end := closingQuoteIndex(result, i)
+
+			// x'...' (hex) and b'...' (bit) literals.
+			alphabet := letterAlphabet
+			if i > 0 && !continuesToken(i-1) {
+				switch result[i-1] {
+				case 'x', 'X':
+					alphabet = hexAlphabet
+				case 'b', 'B':
+					alphabet = bitAlphabet
+				}
+			}
+
+			for j := i + 1; j < end; j++ {
+				result[j] = replace(alphabet)
+			}
+			i = end + 1
+		case b == '#' ||
+			(b == '-' && bytes.HasPrefix(result[i:], []byte("-- "))):
+
+			end := bytes.IndexByte(result[i:], '\n')
+			if end < 0 {
+				return result
+			}
+			i += end + 1
+		case b == '/' &&
+			bytes.HasPrefix(result[i:], []byte("/*")) &&
+			!bytes.HasPrefix(result[i:], []byte("/*!")):
+
+			// NOTE: versioned comments (/*! ... */) are executed, and are
+			// scrubbed like the rest of the query.
+			end := bytes.Index(result[i+2:], []byte("*/"))
+			if end < 0 {
+				return result
+			}
+			i += end + 4
+		case isDigit(b) && !continuesToken(i):
+			end := i
+			for end < len(result) &&
+				(isIdentifierByte(result[end]) || result[end] == '.') {
+
+				end++
+			}
+
+			if !scrubNumbers {
+				i = end
+				continue
+			}
+
+			if end-i > 2 && b == '0' &&
+				(result[i+1] == 'x' || result[i+1] == 'X') {
+
+				for j := i + 2; j < end; j++ {
+					result[j] = replace(hexAlphabet)
+				}
+				i = end
+				continue
+			}
+
+			for j := i; j < end; j++ {
+				if isDigit(result[j]) {
+					result[j] = replace(digitAlphabet)
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+
+	return result
+}
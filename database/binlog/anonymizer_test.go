@@ -0,0 +1,659 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type AnonymizerSuite struct {
+	context *TableMapEvent
+}
+
+var _ = Suite(&AnonymizerSuite{})
+
+func (s *AnonymizerSuite) SetUpTest(c *C) {
+	varchar, _, err := NewVarcharFieldDescriptor(Nullable, []byte{100, 0})
+	c.Assert(err, IsNil)
+	datetime, _, err := NewDateTime2FieldDescriptor(Nullable, []byte{0})
+	c.Assert(err, IsNil)
+	double, _, err := NewDoubleFieldDescriptor(Nullable, []byte{8})
+	c.Assert(err, IsNil)
+	decimal, _, err := NewNewDecimalFieldDescriptor(Nullable, []byte{10, 2})
+	c.Assert(err, IsNil)
+
+	s.context = &TableMapEvent{
+		databaseName: []byte("db"),
+		tableName:    []byte("users"),
+		columnDescriptors: []ColumnDescriptor{
+			NewColumnDescriptor(NewTinyFieldDescriptor(Nullable), 0),
+			NewColumnDescriptor(varchar, 1),
+			NewColumnDescriptor(datetime, 2),
+			NewColumnDescriptor(double, 3),
+			NewColumnDescriptor(decimal, 4),
+			NewColumnDescriptor(NewLongLongFieldDescriptor(Nullable), 5),
+		},
+		optionalMetadata: &TableMapOptionalMetadata{
+			ColumnNames: [][]byte{
+				[]byte("age"),
+				[]byte("name"),
+				[]byte("created"),
+				[]byte("score"),
+				[]byte("balance"),
+				[]byte("id"),
+			},
+		},
+	}
+}
+
+func (s *AnonymizerSuite) row(created time.Time) RowValues {
+	return RowValues{
+		uint64(42),
+		[]byte("alice"),
+		created,
+		float64(-12.5),
+		NewDecimal(big.NewInt(123456), 2),
+		nil,
+	}
+}
+
+func (s *AnonymizerSuite) writeRowsEvent(rows ...RowValues) *WriteRowsEvent {
+	return &WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors(),
+		rows:          rows,
+	}
+}
+
+func (s *AnonymizerSuite) TestAnonymize(c *C) {
+	t := NewAnonymizingTransformer(nil, 1)
+	c.Assert(t.TimeShift(), Not(Equals), time.Duration(0))
+
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(90 * time.Minute)
+	original := s.writeRowsEvent(s.row(t1), s.row(t2))
+
+	event, err := t.Transform(original)
+	c.Assert(err, IsNil)
+
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(len(rows), Equals, 2)
+
+	// The original event is not modified.
+	c.Assert(original.InsertedRows()[0], DeepEquals, s.row(t1))
+
+	for _, row := range rows {
+		age, ok := row[0].(uint64)
+		c.Assert(ok, IsTrue)
+		c.Assert(age < 256, IsTrue)
+
+		name, ok := row[1].([]byte)
+		c.Assert(ok, IsTrue)
+		c.Assert(len(name), Equals, len("alice"))
+		c.Assert(string(name), Not(Equals), "alice")
+
+		score, ok := row[3].(float64)
+		c.Assert(ok, IsTrue)
+		c.Assert(score <= -6.25 && score >= -18.75, IsTrue)
+
+		balance, ok := row[4].(Decimal)
+		c.Assert(ok, IsTrue)
+		c.Assert(balance.Scale(), Equals, 2)
+		c.Assert(balance.Sign() >= 0, IsTrue)
+		c.Assert(balance.Unscaled().Cmp(big.NewInt(1000000)) < 0, IsTrue)
+
+		c.Assert(row[5], IsNil)
+	}
+
+	// Deltas between temporal values are preserved.
+	created1 := rows[0][2].(time.Time)
+	created2 := rows[1][2].(time.Time)
+	c.Assert(created1.Equal(t1.Add(t.TimeShift())), IsTrue)
+	c.Assert(created2.Sub(created1), Equals, 90*time.Minute)
+
+	// The same seed generates the same values.
+	other, err := NewAnonymizingTransformer(nil, 1).Transform(original)
+	c.Assert(err, IsNil)
+	c.Assert(other.(*WriteRowsEvent).InsertedRows(), DeepEquals, rows)
+}
+
+func (s *AnonymizerSuite) TestPolicy(c *C) {
+	t := NewAnonymizingTransformer(
+		Policy{
+			"db.users":         PreserveColumn,
+			"db.users.name":    AnonymizeColumn,
+			"db.other.created": AnonymizeColumn,
+		},
+		2)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event, err := t.Transform(s.writeRowsEvent(s.row(created)))
+	c.Assert(err, IsNil)
+
+	row := event.(*WriteRowsEvent).InsertedRows()[0]
+	expected := s.row(created)
+	c.Assert(string(row[1].([]byte)), Not(Equals), "alice")
+
+	row[1] = expected[1]
+	c.Assert(row, DeepEquals, expected)
+}
+
+func (s *AnonymizerSuite) TestUpdateAndDelete(c *C) {
+	t := NewAnonymizingTransformer(Policy{"db.users.id": PreserveColumn}, 3)
+
+	columns := s.context.ColumnDescriptors()
+	event, err := t.Transform(&UpdateRowsEvent{
+		BaseRowsEvent:          BaseRowsEvent{context: s.context},
+		beforeImageUsedColumns: []ColumnDescriptor{columns[5], columns[1]},
+		afterImageUsedColumns:  []ColumnDescriptor{columns[1]},
+		rows: []UpdateRowValues{
+			{
+				BeforeImage: RowValues{uint64(7), []byte("bob")},
+				AfterImage:  RowValues{[]byte("carol")},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	updated := event.(*UpdateRowsEvent).UpdatedRows()
+	c.Assert(updated[0].BeforeImage[0], Equals, uint64(7))
+	c.Assert(len(updated[0].BeforeImage[1].([]byte)), Equals, 3)
+	c.Assert(string(updated[0].AfterImage[0].([]byte)), Not(Equals), "carol")
+	c.Assert(len(updated[0].AfterImage[0].([]byte)), Equals, 5)
+
+	event, err = t.Transform(&DeleteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   columns[5:],
+		rows:          []RowValues{{uint64(9)}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(
+		event.(*DeleteRowsEvent).DeletedRows(),
+		DeepEquals,
+		[]RowValues{{uint64(9)}})
+
+	// Non-rows events are not modified.
+	xid := &XidEvent{}
+	event, err = t.Transform(xid)
+	c.Assert(err, IsNil)
+	c.Assert(event, Equals, xid)
+}
+
+func (s *AnonymizerSuite) TestTypedArray(c *C) {
+	array, _, err := NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_VARCHAR), 100, 0})
+	c.Assert(err, IsNil)
+
+	context := &TableMapEvent{
+		databaseName:      []byte("db"),
+		tableName:         []byte("tags"),
+		columnDescriptors: []ColumnDescriptor{NewColumnDescriptor(array, 0)},
+	}
+
+	original := []interface{}{
+		[]byte("alice"),
+		int64(-5),
+		uint64(300),
+		float64(1.5),
+		nil,
+		true,
+	}
+
+	t := NewAnonymizingTransformer(nil, 4)
+	event, err := t.Transform(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: context},
+		usedColumns:   context.ColumnDescriptors(),
+		rows:          []RowValues{{original}},
+	})
+	c.Assert(err, IsNil)
+
+	elements, ok := event.(*WriteRowsEvent).InsertedRows()[0][0].([]interface{})
+	c.Assert(ok, IsTrue)
+	c.Assert(elements, HasLen, len(original))
+
+	name := elements[0].([]byte)
+	c.Assert(len(name), Equals, len("alice"))
+	c.Assert(string(name), Not(Equals), "alice")
+
+	i := elements[1].(int64)
+	c.Assert(i >= -8 && i < 0, IsTrue)
+
+	u := elements[2].(uint64)
+	c.Assert(u < 512, IsTrue)
+
+	f := elements[3].(float64)
+	c.Assert(f >= 0.75 && f < 2.25, IsTrue)
+
+	c.Assert(elements[4], IsNil)
+	c.Assert(elements[5], Equals, true)
+}
+
+func (s *AnonymizerSuite) TestDriverValues(c *C) {
+	events := &EventParserSuite{}
+	events.SetUpTest(c)
+	events.parsers = NewV4EventParserMapWithOptions(
+		DecodeOptions{DriverValues: true})
+	events.reader = NewParsedV4EventReader(events.rawReader, events.parsers)
+
+	names := []byte{}
+	for _, name := range []string{"id", "balance", "name"} {
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+	}
+
+	// id BIGINT, balance DECIMAL(7, 2), name VARCHAR(100)
+	tableMap := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		1, 0,
+		// db name length
+		2,
+		// db name
+		'd', 'b', 0,
+		// table name length
+		5,
+		// table name
+		'u', 's', 'e', 'r', 's', 0,
+		// number of columns
+		3,
+		// column types
+		8, 246, 15,
+		// metadata size
+		4,
+		// metadata (decimal precision / scale, varchar max length)
+		7, 2, 100, 0,
+		// null bits
+		7,
+	}
+	tableMap = append(
+		tableMap,
+		optionalMetadataField(optionalMetadataSignedness, 0)...)
+	tableMap = append(
+		tableMap,
+		optionalMetadataField(optionalMetadataColumnName, names...)...)
+
+	rows := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		0, 0,
+		// number of columns
+		3,
+		// used columns
+		7,
+		// null bits
+		0,
+		// id = 123456
+		0x40, 0xe2, 0x01, 0, 0, 0, 0, 0,
+		// balance = 12345.67
+		0x80, 0x30, 0x39, 0x43,
+		// name
+		5, 'a', 'l', 'i', 'c', 'e',
+	}
+
+	events.WriteEvent(mysql_proto.LogEventType_TABLE_MAP_EVENT, 0, tableMap)
+	events.WriteEvent(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 0, rows)
+
+	_, err := events.NextEvent()
+	c.Assert(err, IsNil)
+	event, err := events.NextEvent()
+	c.Assert(err, IsNil)
+
+	// Sanity check: the values are decoded as driver values.
+	original := RowValues{int64(123456), "12345.67", []byte("alice")}
+	c.Assert(
+		event.(*WriteRowsEvent).InsertedRows(),
+		DeepEquals,
+		[]RowValues{original})
+
+	anonymized, err := NewAnonymizingTransformer(nil, 8).Transform(event)
+	c.Assert(err, IsNil)
+
+	row := anonymized.(*WriteRowsEvent).InsertedRows()[0]
+	c.Assert(row, HasLen, 3)
+
+	id, ok := row[0].(int64)
+	c.Assert(ok, IsTrue)
+	c.Assert(id, Not(Equals), int64(123456))
+	c.Assert(id >= 0 && id < 1<<17, IsTrue)
+
+	balance, ok := row[1].(string)
+	c.Assert(ok, IsTrue)
+	c.Assert(balance, Not(Equals), "12345.67")
+	c.Assert(balance, Matches, "[0-9]{5}\\.[0-9]{2}")
+
+	name, ok := row[2].([]byte)
+	c.Assert(ok, IsTrue)
+	c.Assert(len(name), Equals, len("alice"))
+	c.Assert(string(name), Not(Equals), "alice")
+}
+
+func (s *AnonymizerSuite) TestDriverValueTypedArray(c *C) {
+	array, _, err := NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_VARCHAR), 100, 0})
+	c.Assert(err, IsNil)
+
+	context := &TableMapEvent{
+		databaseName:      []byte("db"),
+		tableName:         []byte("tags"),
+		columnDescriptors: []ColumnDescriptor{NewColumnDescriptor(array, 0)},
+	}
+
+	t := NewAnonymizingTransformer(nil, 9)
+	event, err := t.Transform(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: context},
+		usedColumns:   context.ColumnDescriptors(),
+		rows: []RowValues{
+			{`["alice",-5,300,1.5,null,true]`},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	encoded, ok := event.(*WriteRowsEvent).InsertedRows()[0][0].(string)
+	c.Assert(ok, IsTrue)
+	c.Assert(strings.Contains(encoded, "alice"), IsFalse)
+
+	elements := []interface{}{}
+	c.Assert(json.Unmarshal([]byte(encoded), &elements), IsNil)
+	c.Assert(elements, HasLen, 6)
+	c.Assert(len(elements[0].(string)), Equals, len("alice"))
+	c.Assert(elements[1].(float64) >= -8 && elements[1].(float64) < 0, IsTrue)
+	c.Assert(elements[2].(float64) < 512, IsTrue)
+	c.Assert(elements[4], IsNil)
+	c.Assert(elements[5], Equals, true)
+}
+
+func (s *AnonymizerSuite) TestUnsupportedValues(c *C) {
+	t := NewAnonymizingTransformer(nil, 10)
+
+	for _, value := range []interface{}{
+		struct{}{},   // unknown type
+		"alice",      // unexpected string value for varchar
+		float32(1.5), // unexpected float type
+		true,
+	} {
+		_, err := t.Transform(&WriteRowsEvent{
+			BaseRowsEvent: BaseRowsEvent{context: s.context},
+			usedColumns:   s.context.ColumnDescriptors()[1:2],
+			rows:          []RowValues{{value}},
+		})
+		c.Assert(err, NotNil, Commentf("%T", value))
+	}
+
+	// Unsigned BIGINT driver values beyond MaxInt64.
+	event, err := t.Transform(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[5:],
+		rows:          []RowValues{{"18446744073709551615"}},
+	})
+	c.Assert(err, IsNil)
+
+	id := event.(*WriteRowsEvent).InsertedRows()[0][0].(string)
+	c.Assert(id, Not(Equals), "18446744073709551615")
+	u, err := strconv.ParseUint(id, 10, 64)
+	c.Assert(err, IsNil)
+	c.Assert(u > math.MaxInt64, IsTrue)
+
+	// Integer values of non-integer columns.
+	_, err = t.Transform(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[3:4],
+		rows:          []RowValues{{int64(7)}},
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *AnonymizerSuite) TestAnonymizeQuery(c *C) {
+	t := NewAnonymizingTransformer(nil, 5)
+
+	ddl := "CREATE TABLE t1 (a VARCHAR(255) DEFAULT 'secret')"
+	anonymized := string(t.anonymizeQuery([]byte(ddl)))
+	c.Assert(len(anonymized), Equals, len(ddl))
+	c.Assert(anonymized[:len(ddl)-8], Equals, ddl[:len(ddl)-8])
+	c.Assert(anonymized[len(ddl)-2:], Equals, "')")
+	c.Assert(anonymized, Not(Equals), ddl)
+
+	prefix := "INSERT INTO t1 (`a'b`) VALUES ("
+	suffix := ") # it's a comment"
+	dml := prefix +
+		"12.5, 0x1F, x'AB', b'101', 'it''s', \"a\\\"b\", _utf8'secret'" +
+		suffix
+	anonymized = string(t.anonymizeQuery([]byte(dml)))
+	c.Assert(len(anonymized), Equals, len(dml))
+	c.Assert(anonymized[:len(prefix)], Equals, prefix)
+	c.Assert(anonymized[len(anonymized)-len(suffix):], Equals, suffix)
+
+	values := anonymized[len(prefix) : len(anonymized)-len(suffix)]
+	c.Assert(values[2], Equals, byte('.'))
+	c.Assert(values[6:8], Equals, "0x")
+	c.Assert(values[12:14], Equals, "x'")
+	c.Assert(values[16:19], Equals, "', ")
+	for _, b := range values[21:24] {
+		c.Assert(b == '0' || b == '1', IsTrue)
+	}
+	c.Assert(values[len(values)-7-6:len(values)-7], Equals, "_utf8'")
+
+	for _, literal := range []string{"12", "1F", "AB", "it", "secret"} {
+		c.Assert(bytes.Contains([]byte(values), []byte(literal)), IsFalse)
+	}
+}
+
+// This returns the concatenation of all byte slices and strings reachable
+// from value (including unexported fields).
+func reachableBytes(value interface{}) []byte {
+	buf := &bytes.Buffer{}
+	visited := make(map[uintptr]bool)
+
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || visited[v.Pointer()] {
+				return
+			}
+			visited[v.Pointer()] = true
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		case reflect.Slice:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				buf.Write(v.Bytes())
+				buf.WriteByte(0)
+				return
+			}
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			iter := v.MapRange()
+			for iter.Next() {
+				walk(iter.Key())
+				walk(iter.Value())
+			}
+		case reflect.String:
+			buf.WriteString(v.String())
+			buf.WriteByte(0)
+		}
+	}
+
+	walk(reflect.ValueOf(value))
+	return buf.Bytes()
+}
+
+type AnonymizerEventSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&AnonymizerEventSuite{})
+
+const (
+	anonymizerSecretEmail = "alice@example.com"
+	anonymizerSecretBlob  = "top-secret-blob-data"
+	anonymizerSecretQuery = "INSERT INTO t VALUES ('alice@example.com', 31337)"
+)
+
+func (s *AnonymizerEventSuite) SetUpTest(c *C) {
+	s.EventParserSuite.SetUpTest(c)
+
+	s.rawReader = NewRawV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		RawV4EventReaderOptions{RetainRawBytes: true})
+	s.parsers = NewV4EventParserMapWithOptions(DecodeOptions{
+		LazyBlobs: true,
+		RawValues: true,
+	})
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+
+	varchar, _, err := NewVarcharFieldDescriptor(Nullable, []byte{100, 0})
+	c.Assert(err, IsNil)
+	blob, _, err := NewLazyBlobFieldDescriptor(Nullable, []byte{2})
+	c.Assert(err, IsNil)
+
+	s.parsers.SetTableContext(&testTableContext{
+		columns: []ColumnDescriptor{
+			NewColumnDescriptor(varchar, 0),
+			NewColumnDescriptor(blob, 1),
+		},
+	})
+}
+
+func (s *AnonymizerEventSuite) writeEvents() {
+	row := []byte{0}
+	row = append(row, byte(len(anonymizerSecretEmail)))
+	row = append(row, anonymizerSecretEmail...)
+	row = append(row, byte(len(anonymizerSecretBlob)), 0)
+	row = append(row, anonymizerSecretBlob...)
+
+	// An insert and an update (from the row to itself).
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		uint16(0),
+		append(
+			[]byte{testRowsTableId, 0, 0, 0, 0, 0, 0, 0, 2, 3},
+			row...))
+	s.WriteEvent(
+		mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1,
+		uint16(0),
+		append(
+			append(
+				[]byte{testRowsTableId, 0, 0, 0, 0, 0, 0, 0, 2, 3, 3},
+				row...),
+			row...))
+
+	query := []byte{
+		// thread id
+		1, 0, 0, 0,
+		// duration
+		0, 0, 0, 0,
+		// db name length
+		2,
+		// error code
+		0, 0,
+		// status length
+		0, 0,
+		// db name
+		'd', 'b', 0,
+	}
+	s.WriteEvent(
+		mysql_proto.LogEventType_QUERY_EVENT,
+		uint16(0),
+		append(query, anonymizerSecretQuery...))
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_ROWS_QUERY_LOG_EVENT,
+		uint16(0),
+		append(
+			[]byte{byte(len(anonymizerSecretQuery))},
+			anonymizerSecretQuery...))
+}
+
+func (s *AnonymizerEventSuite) TestNoOriginalBytes(c *C) {
+	s.writeEvents()
+
+	t := NewAnonymizingTransformer(nil, 6)
+	for i := 0; i < 4; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+
+		// Sanity check: the original event includes the secrets.
+		original := reachableBytes(event)
+		if i < 2 {
+			c.Assert(
+				bytes.Contains(original, []byte(anonymizerSecretBlob)),
+				IsTrue)
+		} else {
+			c.Assert(bytes.Contains(original, []byte("31337")), IsTrue)
+		}
+
+		anonymized, err := t.Transform(event)
+		c.Assert(err, IsNil)
+		c.Assert(
+			reflect.TypeOf(anonymized),
+			Equals,
+			reflect.TypeOf(event))
+
+		result := reachableBytes(anonymized)
+		for _, secret := range []string{
+			anonymizerSecretEmail,
+			anonymizerSecretBlob,
+			"alice",
+			"31337",
+		} {
+			c.Assert(
+				bytes.Contains(result, []byte(secret)),
+				IsFalse,
+				Commentf("%T leaks %s", anonymized, secret))
+		}
+	}
+
+	_, err := s.NextEvent()
+	c.Assert(err, NotNil)
+}
+
+func (s *AnonymizerSuite) TestLegacyLoadEvent(c *C) {
+	t := NewAnonymizingTransformer(nil, 7)
+
+	_, err := t.Transform(&CreateFileEvent{})
+	c.Assert(err, NotNil)
+
+	block := &AppendBlockEvent{
+		fileId:    1,
+		blockData: []byte("alice,31337\nbob,\"x\"\n"),
+	}
+	event, err := t.Transform(block)
+	c.Assert(err, IsNil)
+
+	anonymized := event.(*AppendBlockEvent)
+	c.Assert(anonymized.FileId(), Equals, uint32(1))
+
+	data := anonymized.BlockData()
+	c.Assert(len(data), Equals, len(block.blockData))
+	c.Assert(bytes.Contains(data, []byte("alice")), IsFalse)
+	c.Assert(bytes.Contains(data, []byte("31337")), IsFalse)
+	c.Assert(data[5], Equals, byte(','))
+	c.Assert(data[11], Equals, byte('\n'))
+}
@@ -0,0 +1,222 @@
+package binlog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/dropbox/godropbox/time2"
+)
+
+// Batcher accumulates row changes into batches for sinks which prefer
+// batched writes.  A batch is flushed when it reaches maxSize changes, or
+// when its oldest change has been pending for maxLatency.  Changes are added
+// one transaction at a time, and a transaction is never split across batches
+// unless the transaction by itself exceeds maxSize (in which case the
+// transaction is flushed as consecutive batches of up to maxSize changes,
+// without any other transaction's changes).
+//
+// The flush function is called synchronously while the batcher is locked,
+// hence a slow sink blocks AddTransaction (i.e., the sink applies
+// backpressure on the producer).
+//
+// A failed batch is dropped (i.e., it is never retried).  The flush error of
+// a flush triggered by a call (size triggered flushes in AddTransaction, or
+// Flush / Close) is returned to that caller.  Latency triggered flushes run
+// in the background; the error of a failed background flush is returned by
+// the next AddTransaction, Flush or Close call instead of performing the
+// call (i.e., AddTransaction does not add the changes, and Flush does not
+// flush), except that Close always flushes the current batch.  When multiple
+// background flushes fail before the error is returned, only the first
+// error is returned.
+//
+// Batcher is thread safe.
+type Batcher struct {
+	maxSize    int
+	maxLatency time.Duration
+	flush      func(batch []*RowChange) error
+	clock      time2.Clock
+
+	mutex    sync.Mutex
+	pending  []*RowChange
+	batchId  uint64 // incremented on every flush, for ignoring stale timers.
+	err      error  // the error returned by a latency triggered flush.
+	isClosed bool
+
+	// Closed to stop the current batch's latency timer (nil when there is no
+	// timer).
+	stopTimer chan struct{}
+	timers    sync.WaitGroup
+}
+
+// This returns a batcher which passes the batches to flush.  When
+// maxLatency is non-positive, batches are only flushed when full (or on
+// Flush / Close).  When clock is nil, time2.DefaultClock is used.
+func NewBatcher(
+	maxSize int,
+	maxLatency time.Duration,
+	flush func(batch []*RowChange) error,
+	clock time2.Clock) (*Batcher, error) {
+
+	if maxSize < 1 {
+		return nil, errors.Newf("Invalid max batch size: %d", maxSize)
+	}
+
+	if clock == nil {
+		clock = time2.DefaultClock
+	}
+
+	return &Batcher{
+		maxSize:    maxSize,
+		maxLatency: maxLatency,
+		flush:      flush,
+		clock:      clock,
+	}, nil
+}
+
+// AddTransaction adds a transaction's row changes to the current batch.
+// This returns the flush function's error when a flush (including a latency
+// triggered flush since the last call) fails; the failed batch is dropped.
+func (b *Batcher) AddTransaction(changes []*RowChange) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err := b.checkState(); err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if len(b.pending) > 0 && len(b.pending)+len(changes) > b.maxSize {
+		if err := b.flushLocked(); err != nil {
+			return err
+		}
+	}
+
+	if len(changes) > b.maxSize {
+		for len(changes) > 0 {
+			size := b.maxSize
+			if size > len(changes) {
+				size = len(changes)
+			}
+
+			b.pending = changes[:size:size]
+			changes = changes[size:]
+
+			if err := b.flushLocked(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	startTimer := len(b.pending) == 0
+	b.pending = append(b.pending, changes...)
+
+	if len(b.pending) >= b.maxSize {
+		return b.flushLocked()
+	}
+
+	if startTimer && b.maxLatency > 0 {
+		b.stopTimer = make(chan struct{})
+		b.timers.Add(1)
+		go b.flushAfterLatency(b.batchId, b.stopTimer)
+	}
+	return nil
+}
+
+// Flush flushes the current batch (if any).
+func (b *Batcher) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err := b.checkState(); err != nil {
+		return err
+	}
+	return b.flushLocked()
+}
+
+// Close flushes the current batch, and stops the latency timer.  The
+// batcher can't be used afterward.  This returns the error of a failed
+// background flush (see Batcher) if any, or else the flush's error.
+func (b *Batcher) Close() error {
+	b.mutex.Lock()
+
+	if b.isClosed {
+		b.mutex.Unlock()
+		return nil
+	}
+
+	err := b.checkState()
+	flushErr := b.flushLocked()
+	if err == nil {
+		err = flushErr
+	}
+
+	b.isClosed = true
+	b.mutex.Unlock()
+
+	// Wait for the timer goroutine (if any) to exit.
+	b.timers.Wait()
+	return err
+}
+
+// NumPending returns the number of changes in the current batch.
+func (b *Batcher) NumPending() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.pending)
+}
+
+// NOTE: The caller must hold the mutex.
+func (b *Batcher) checkState() error {
+	if b.isClosed {
+		return errors.New("Batcher is closed")
+	}
+
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// NOTE: The caller must hold the mutex.
+func (b *Batcher) flushLocked() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	batch := b.pending
+	b.pending = nil
+	b.batchId++
+
+	if b.stopTimer != nil {
+		close(b.stopTimer)
+		b.stopTimer = nil
+	}
+
+	return b.flush(batch)
+}
+
+func (b *Batcher) flushAfterLatency(batchId uint64, stop chan struct{}) {
+	defer b.timers.Done()
+
+	select {
+	case <-b.clock.After(b.maxLatency):
+	case <-stop:
+		return // the batch was already flushed
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.isClosed || b.batchId != batchId {
+		return // the batch was already flushed
+	}
+
+	if err := b.flushLocked(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
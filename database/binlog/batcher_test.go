@@ -0,0 +1,288 @@
+package binlog
+
+import (
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/dropbox/godropbox/time2"
+)
+
+type BatcherSuite struct {
+	clock *time2.MockClock
+
+	mutex   sync.Mutex
+	batches [][]*RowChange
+	flushed chan struct{}
+}
+
+var _ = Suite(&BatcherSuite{})
+
+func (s *BatcherSuite) SetUpTest(c *C) {
+	s.clock = time2.NewMockClock(time.Unix(1000, 0))
+	s.batches = nil
+	s.flushed = make(chan struct{}, 100)
+}
+
+func (s *BatcherSuite) flush(batch []*RowChange) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.batches = append(s.batches, batch)
+	s.flushed <- struct{}{}
+	return nil
+}
+
+func (s *BatcherSuite) batchSizes() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sizes := []int{}
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func (s *BatcherSuite) newBatcher(
+	c *C,
+	maxSize int,
+	maxLatency time.Duration) *Batcher {
+
+	b, err := NewBatcher(maxSize, maxLatency, s.flush, s.clock)
+	c.Assert(err, IsNil)
+	return b
+}
+
+func txn(table string, size int) []*RowChange {
+	changes := make([]*RowChange, 0, size)
+	for i := 0; i < size; i++ {
+		changes = append(changes, &RowChange{
+			Action: InsertAction,
+			Table:  table,
+			After:  map[string]interface{}{"id": uint64(i)},
+		})
+	}
+	return changes
+}
+
+// Waits for the latency timer goroutine to block on the mock clock.
+func (s *BatcherSuite) waitForTimer(c *C) {
+	s.waitForWakeups(c, 1)
+}
+
+// Waits for the mock clock to have numWakeups pending wakeups (including
+// stale wakeups of stopped timers).
+func (s *BatcherSuite) waitForWakeups(c *C, numWakeups int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for s.clock.WakeupsCount() < numWakeups {
+		c.Assert(time.Now().Before(deadline), Equals, true)
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+}
+
+func (s *BatcherSuite) TestInvalidSize(c *C) {
+	_, err := NewBatcher(0, time.Second, s.flush, s.clock)
+	c.Assert(err, NotNil)
+}
+
+func (s *BatcherSuite) TestCountTriggeredFlush(c *C) {
+	b := s.newBatcher(c, 5, 0)
+
+	c.Assert(b.AddTransaction(txn("a", 2)), IsNil)
+	c.Assert(b.AddTransaction(txn("b", 3)), IsNil)
+	c.Assert(s.batchSizes(), DeepEquals, []int{5})
+	c.Assert(b.NumPending(), Equals, 0)
+
+	c.Assert(b.AddTransaction(txn("c", 1)), IsNil)
+	c.Assert(b.AddTransaction(nil), IsNil)
+	c.Assert(b.NumPending(), Equals, 1)
+	c.Assert(s.batchSizes(), DeepEquals, []int{5})
+
+	c.Assert(b.Close(), IsNil)
+	c.Assert(s.batchSizes(), DeepEquals, []int{5, 1})
+
+	c.Assert(b.AddTransaction(txn("d", 1)), NotNil)
+	c.Assert(b.Close(), IsNil)
+}
+
+func (s *BatcherSuite) TestTimeTriggeredFlush(c *C) {
+	b := s.newBatcher(c, 10, time.Second)
+	defer b.Close()
+
+	c.Assert(b.AddTransaction(txn("a", 2)), IsNil)
+	s.waitForTimer(c)
+
+	// Adding to the pending batch does not extend its deadline.
+	s.clock.Advance(500 * time.Millisecond)
+	c.Assert(b.AddTransaction(txn("b", 1)), IsNil)
+	s.clock.Advance(499 * time.Millisecond)
+	c.Assert(s.batchSizes(), DeepEquals, []int{})
+
+	s.clock.Advance(time.Millisecond)
+	select {
+	case <-s.flushed:
+	case <-time.After(5 * time.Second):
+		c.Fatal("Timed out waiting for flush")
+	}
+	c.Assert(s.batchSizes(), DeepEquals, []int{3})
+	c.Assert(b.NumPending(), Equals, 0)
+
+	// The timer of a count triggered batch is ignored.
+	c.Assert(b.AddTransaction(txn("c", 4)), IsNil)
+	s.waitForTimer(c)
+	c.Assert(b.AddTransaction(txn("d", 6)), IsNil)
+	c.Assert(s.batchSizes(), DeepEquals, []int{3, 10})
+	<-s.flushed
+
+	s.clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(s.batchSizes(), DeepEquals, []int{3, 10})
+}
+
+func (s *BatcherSuite) TestTransactionBoundaries(c *C) {
+	b := s.newBatcher(c, 5, 0)
+
+	c.Assert(b.AddTransaction(txn("a", 3)), IsNil)
+
+	// The transaction does not fit into the current batch; the current batch
+	// is flushed instead of splitting the transaction.
+	c.Assert(b.AddTransaction(txn("b", 3)), IsNil)
+	c.Assert(s.batchSizes(), DeepEquals, []int{3})
+	c.Assert(s.batches[0][0].Table, Equals, "a")
+	c.Assert(b.NumPending(), Equals, 3)
+
+	// Oversized transactions are split, but never share a batch with other
+	// transactions.
+	c.Assert(b.AddTransaction(txn("c", 12)), IsNil)
+	c.Assert(s.batchSizes(), DeepEquals, []int{3, 3, 5, 5, 2})
+	for _, batch := range s.batches[2:] {
+		for _, change := range batch {
+			c.Assert(change.Table, Equals, "c")
+		}
+	}
+	c.Assert(b.NumPending(), Equals, 0)
+}
+
+func (s *BatcherSuite) TestFlushError(c *C) {
+	fail := true
+	b, err := NewBatcher(
+		2,
+		0,
+		func(batch []*RowChange) error {
+			if fail {
+				return errors.New("sink is down")
+			}
+			return nil
+		},
+		s.clock)
+	c.Assert(err, IsNil)
+
+	c.Assert(b.AddTransaction(txn("a", 2)), NotNil)
+	c.Assert(b.NumPending(), Equals, 0)
+
+	fail = false
+	c.Assert(b.AddTransaction(txn("a", 1)), IsNil)
+	c.Assert(b.Flush(), IsNil)
+}
+
+func (s *BatcherSuite) TestLatencyFlushError(c *C) {
+	fail := make(chan bool, 100)
+	attempted := make(chan struct{}, 100)
+	b, err := NewBatcher(
+		10,
+		time.Second,
+		func(batch []*RowChange) error {
+			defer func() { attempted <- struct{}{} }()
+			if <-fail {
+				return errors.New("sink is down")
+			}
+			return nil
+		},
+		s.clock)
+	c.Assert(err, IsNil)
+
+	fail <- true
+	c.Assert(b.AddTransaction(txn("a", 1)), IsNil)
+	s.waitForTimer(c)
+	s.clock.Advance(time.Second)
+	<-attempted
+
+	// The next call returns the background flush's error, without adding
+	// the changes.
+	c.Assert(b.AddTransaction(txn("b", 2)), NotNil)
+	c.Assert(b.NumPending(), Equals, 0)
+
+	// The error is only returned once.
+	c.Assert(b.AddTransaction(txn("c", 3)), IsNil)
+	c.Assert(b.NumPending(), Equals, 3)
+
+	// Errors of call triggered flushes are returned to the caller.
+	fail <- true
+	c.Assert(b.Flush(), NotNil)
+	c.Assert(b.NumPending(), Equals, 0)
+	<-attempted
+
+	// Close flushes the current batch even when reporting a background
+	// flush error.
+	fail <- true
+	c.Assert(b.AddTransaction(txn("d", 1)), IsNil)
+	s.waitForWakeups(c, 2) // c's timer was stopped, but is still registered
+	s.clock.Advance(time.Second)
+	<-attempted
+
+	fail <- false
+	c.Assert(b.AddTransaction(nil), NotNil)
+	c.Assert(b.AddTransaction(txn("e", 1)), IsNil)
+	c.Assert(b.Close(), IsNil)
+	<-attempted
+	c.Assert(b.NumPending(), Equals, 0)
+}
+
+func (s *BatcherSuite) TestCloseStopsTimer(c *C) {
+	b := s.newBatcher(c, 10, time.Hour)
+
+	c.Assert(b.AddTransaction(txn("a", 1)), IsNil)
+	s.waitForTimer(c)
+
+	// Close waits for the timer goroutine, which would otherwise block on
+	// the (never advanced) clock.
+	closed := make(chan error, 1)
+	go func() { closed <- b.Close() }()
+
+	select {
+	case err := <-closed:
+		c.Assert(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("Close did not stop the latency timer")
+	}
+	c.Assert(s.batchSizes(), DeepEquals, []int{1})
+
+	s.clock.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(s.batchSizes(), DeepEquals, []int{1})
+}
+
+func (s *BatcherSuite) TestNormalizeTransaction(c *C) {
+	context := newTestTableContext()
+	n := NewRowChangeNormalizer()
+
+	changes := n.NormalizeTransaction(&Transaction{
+		Events: []Event{
+			&WriteRowsEvent{
+				BaseRowsEvent: BaseRowsEvent{context: context},
+				usedColumns:   context.ColumnDescriptors()[:1],
+				rows:          []RowValues{{uint64(1)}, {uint64(2)}},
+			},
+			&XidEvent{},
+		},
+		Committed: true,
+	})
+	c.Assert(len(changes), Equals, 2)
+	c.Assert(changes[1].After, DeepEquals, map[string]interface{}{
+		"col_0": uint64(2),
+	})
+}
@@ -0,0 +1,159 @@
+package binlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+var binlogStatementPrefix = []byte("BINLOG '")
+
+// binlogStatementDecoder extracts the base64 encoded events from the
+// BINLOG '...' statements within mysqlbinlog's text output, and decodes them.
+type binlogStatementDecoder struct {
+	scanner *bufio.Scanner
+	lineNum int
+
+	inStatement  bool
+	statementNum int // the line number of the current statement's beginning.
+
+	// base64 characters which do not form a complete quantum yet.
+	pending []byte
+
+	decoded []byte
+	err     error
+}
+
+// This returns a reader which extracts the BINLOG '...' statements from
+// mysqlbinlog's text output (e.g., "mysqlbinlog mysql-bin.000001"), and
+// returns the statements' base64 decoded content, i.e., the raw events (the
+// log file magic marker is not included).  Everything outside of BINLOG
+// statements (comments, pseudo sql from --verbose, other sql statements) is
+// ignored.
+//
+// Like the server's BINLOG statement handling (see
+// mysql_client_binlog_statement), a statement may consist of multiple
+// concatenated base64 encoded chunks (mysqlbinlog encodes each event
+// separately), and whitespace / line breaks within the statement are
+// ignored.
+func NewBinlogStatementDecoder(src io.Reader) io.Reader {
+	scanner := bufio.NewScanner(src)
+	// The base64 encoded events are wrapped, hence lines are usually short,
+	// but other sql statements may be arbitrarily long.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+
+	return &binlogStatementDecoder{
+		scanner: scanner,
+	}
+}
+
+func (d *binlogStatementDecoder) Read(p []byte) (int, error) {
+	for len(d.decoded) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		d.err = d.decodeNextLine()
+	}
+
+	n := copy(p, d.decoded)
+	d.decoded = d.decoded[n:]
+	return n, nil
+}
+
+func (d *binlogStatementDecoder) decodeNextLine() error {
+	if !d.scanner.Scan() {
+		if err := d.scanner.Err(); err != nil {
+			return errors.Wrapf(
+				err,
+				"Failed to read line %d",
+				d.lineNum+1)
+		}
+
+		if d.inStatement {
+			return errors.Newf(
+				"Unterminated BINLOG statement (starting at line %d)",
+				d.statementNum)
+		}
+
+		return io.EOF
+	}
+
+	d.lineNum++
+	line := d.scanner.Bytes()
+
+	if !d.inStatement {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if !bytes.HasPrefix(trimmed, binlogStatementPrefix) {
+			return nil
+		}
+
+		d.inStatement = true
+		d.statementNum = d.lineNum
+		line = trimmed[len(binlogStatementPrefix):]
+	}
+
+	if end := bytes.IndexByte(line, '\''); end >= 0 {
+		line = line[:end]
+		d.inStatement = false
+	}
+
+	for _, ch := range line {
+		switch ch {
+		case ' ', '\t', '\r':
+			continue
+		}
+		d.pending = append(d.pending, ch)
+	}
+
+	// Each 4 characters quantum is decoded independently, which handles
+	// padding in the middle of the statement (i.e., concatenated chunks).
+	numQuanta := len(d.pending) / 4
+	decoded := make([]byte, 0, 3*numQuanta)
+	buf := [3]byte{}
+	for i := 0; i < numQuanta; i++ {
+		n, err := base64.StdEncoding.Decode(buf[:], d.pending[4*i:4*i+4])
+		if err != nil {
+			return errors.Wrapf(
+				err,
+				"Invalid base64 in BINLOG statement at line %d",
+				d.lineNum)
+		}
+		decoded = append(decoded, buf[:n]...)
+	}
+	d.pending = d.pending[:copy(d.pending, d.pending[4*numQuanta:])]
+
+	if !d.inStatement && len(d.pending) > 0 {
+		return errors.Newf(
+			"Truncated base64 in BINLOG statement at line %d",
+			d.lineNum)
+	}
+
+	d.decoded = decoded
+	return nil
+}
+
+// This returns an EventReader which reads the events embedded within
+// mysqlbinlog's text output (see NewBinlogStatementDecoder), with the
+// appropriate parsers applied on each event.  The first BINLOG statement must
+// contain the format description event (mysqlbinlog always outputs the
+// format description event first).  NOTE: the events' source positions are
+// relative to the decoded event stream (prefixed with the log file magic
+// marker), not to the text output.
+func NewBinlogStatementEventReader(
+	src io.Reader,
+	srcName string,
+	parsers V4EventParserMap,
+	logger Logger) EventReader {
+
+	return NewLogFileV4EventReader(
+		io.MultiReader(
+			bytes.NewReader(logFileMagic),
+			NewBinlogStatementDecoder(src)),
+		srcName,
+		parsers,
+		logger)
+}
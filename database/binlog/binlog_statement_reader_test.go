@@ -0,0 +1,240 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type BinlogStatementReaderSuite struct {
+	fde       []byte
+	tableMap  []byte
+	writeRows []byte
+}
+
+var _ = Suite(&BinlogStatementReaderSuite{})
+
+func binlogStatementTestEvent(
+	eventType mysql_proto.LogEventType_Type,
+	data []byte) []byte {
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0), // timestamp
+		uint8(eventType),
+		uint32(1),    // server id
+		uint32(1234), // next position
+		uint16(0),
+		data)
+	if err != nil {
+		panic(err)
+	}
+	return eventBytes
+}
+
+func (s *BinlogStatementReaderSuite) SetUpSuite(c *C) {
+	s.fde = binlogStatementTestEvent(
+		mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT,
+		[]byte{
+			// binlog version
+			4, 0,
+			// server version
+			53, 46, 54, 46, 49, 53, 45, 54, 51, 46,
+			48, 45, 108, 111, 103, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			// created timestamp
+			0, 0, 0, 0,
+			// total header size
+			19,
+			// fixed length data size per event type
+			56, 13, 0, 8, 0, 18, 0, 4, 4, 4, 4, 18, 0, 0, 92, 0, 4, 26,
+			8, 0, 0, 0, 8, 8, 8, 2, 0, 0, 0, 10, 10, 10, 25, 25, 0,
+			// checksum algorithm (off)
+			0,
+			// checksum
+			0, 0, 0, 0})
+
+	s.tableMap = binlogStatementTestEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			1, 0,
+			// db name length
+			4,
+			// db name
+			't', 'e', 's', 't', 0,
+			// table name length
+			1,
+			// table name
+			't', 0,
+			// number of columns
+			2,
+			// column types (long, short)
+			3, 2,
+			// metadata size
+			0,
+			// null bits
+			2})
+
+	s.writeRows = binlogStatementTestEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			0, 0,
+			// number of columns
+			2,
+			// used columns
+			3,
+			// row 1: null bits, long, short
+			0, 1, 0, 0, 0, 2, 0,
+			// row 2: null bits, long, short (null)
+			2, 3, 0, 0, 0})
+}
+
+// This formats the events as mysqlbinlog does, i.e., each event is base64
+// encoded separately, and wrapped at 76 characters.
+func binlogStatement(events ...[]byte) string {
+	lines := []string{"BINLOG '"}
+	for _, event := range events {
+		encoded := base64.StdEncoding.EncodeToString(event)
+		for len(encoded) > 76 {
+			lines = append(lines, encoded[:76])
+			encoded = encoded[76:]
+		}
+		lines = append(lines, encoded)
+	}
+	lines = append(lines, "'/*!*/;")
+	return strings.Join(lines, "\n")
+}
+
+func (s *BinlogStatementReaderSuite) mysqlbinlogOutput() string {
+	return strings.Join([]string{
+		"/*!50530 SET @@SESSION.PSEUDO_SLAVE_MODE=1*/;",
+		"DELIMITER /*!*/;",
+		"# at 4",
+		"#200101  0:00:00 server id 1  end_log_pos 120 CRC32 0x00000000 " +
+			"Start: binlog v 4, server v 5.6.15-log created 200101  0:00:00",
+		binlogStatement(s.fde),
+		"# at 120",
+		"#200101  0:00:00 server id 1  end_log_pos 160 CRC32 0x00000000 " +
+			"Table_map: `test`.`t` mapped to number 76",
+		"# at 160",
+		"#200101  0:00:00 server id 1  end_log_pos 200 CRC32 0x00000000 " +
+			"Write_rows: table id 76 flags: STMT_END_F",
+		"",
+		binlogStatement(s.tableMap, s.writeRows),
+		"### INSERT INTO `test`.`t`",
+		"### SET",
+		"###   @1=1",
+		"###   @2=2",
+		"DELIMITER ;",
+		"# End of log file",
+	}, "\n")
+}
+
+func (s *BinlogStatementReaderSuite) TestDecoder(c *C) {
+	decoded, err := ioutil.ReadAll(
+		NewBinlogStatementDecoder(strings.NewReader(s.mysqlbinlogOutput())))
+	c.Assert(err, IsNil)
+
+	expected := append([]byte{}, s.fde...)
+	expected = append(expected, s.tableMap...)
+	expected = append(expected, s.writeRows...)
+	c.Check(decoded, DeepEquals, expected)
+}
+
+func (s *BinlogStatementReaderSuite) TestDecoderSingleLineStatement(c *C) {
+	input := "  BINLOG '" + base64.StdEncoding.EncodeToString(s.tableMap) +
+		"'/*!*/;\n"
+
+	decoded, err := ioutil.ReadAll(
+		NewBinlogStatementDecoder(strings.NewReader(input)))
+	c.Assert(err, IsNil)
+	c.Check(decoded, DeepEquals, s.tableMap)
+}
+
+func (s *BinlogStatementReaderSuite) TestDecoderUnterminatedStatement(c *C) {
+	input := "# at 4\nBINLOG '\n" +
+		base64.StdEncoding.EncodeToString(s.tableMap) + "\n"
+
+	_, err := ioutil.ReadAll(
+		NewBinlogStatementDecoder(strings.NewReader(input)))
+	c.Check(
+		errors.GetMessage(err),
+		Equals,
+		"Unterminated BINLOG statement (starting at line 2)")
+}
+
+func (s *BinlogStatementReaderSuite) TestDecoderInvalidBase64(c *C) {
+	input := "BINLOG '\nAAAA\nAA!A\n'/*!*/;\n"
+
+	_, err := ioutil.ReadAll(
+		NewBinlogStatementDecoder(strings.NewReader(input)))
+	c.Check(
+		strings.HasPrefix(
+			errors.GetMessage(err),
+			"Invalid base64 in BINLOG statement at line 3"),
+		IsTrue)
+
+	input = "BINLOG '\nAAAAA\n'/*!*/;\n"
+
+	_, err = ioutil.ReadAll(
+		NewBinlogStatementDecoder(strings.NewReader(input)))
+	c.Check(
+		errors.GetMessage(err),
+		Equals,
+		"Truncated base64 in BINLOG statement at line 3")
+}
+
+func (s *BinlogStatementReaderSuite) TestEventReader(c *C) {
+	reader := NewBinlogStatementEventReader(
+		bytes.NewBufferString(s.mysqlbinlogOutput()),
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	tm, ok := event.(*TableMapEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(tm.DatabaseName()), Equals, "test")
+	c.Check(string(tm.TableName()), Equals, "t")
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	rows, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rows.TableId(), Equals, uint64(76))
+	c.Check(
+		rows.InsertedRows(),
+		DeepEquals,
+		[]RowValues{
+			{uint64(1), uint64(2)},
+			{uint64(3), nil},
+		})
+
+	_, err = reader.NextEvent()
+	c.Check(err, NotNil)
+}
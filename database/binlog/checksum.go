@@ -0,0 +1,135 @@
+package binlog
+
+import (
+	"fmt"
+	"hash/crc32"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// ChecksumStatus is the result of an event's checksum verification.
+type ChecksumStatus int
+
+const (
+	// The event's checksum was not verified, either because verification is
+	// disabled, or because the event does not have a checksum footer.
+	ChecksumNone ChecksumStatus = iota
+
+	// The event's checksum matched the event's content.
+	ChecksumVerified
+
+	// The event's checksum did not match the event's content.
+	ChecksumMismatch
+)
+
+func (s ChecksumStatus) String() string {
+	switch s {
+	case ChecksumNone:
+		return "NONE"
+	case ChecksumVerified:
+		return "VERIFIED"
+	case ChecksumMismatch:
+		return "MISMATCH"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// ChecksumVerifier defines the interface for handling an event checksum
+// algorithm.
+type ChecksumVerifier interface {
+	// Algorithm returns the checksum algorithm handled by this verifier.
+	Algorithm() mysql_proto.ChecksumAlgorithm_Type
+
+	// Size returns the size of the checksum footer appended to each event.
+	Size() int
+
+	// Verify returns an error if the event's checksum does not match the
+	// event's content.
+	Verify(event Event) error
+}
+
+// This returns a verifier for ChecksumAlgorithm_OFF.  Events do not have
+// checksum footers, and verification always succeeds.
+func NewNoneChecksumVerifier() ChecksumVerifier {
+	return &noneChecksumVerifier{}
+}
+
+type noneChecksumVerifier struct {
+}
+
+func (v *noneChecksumVerifier) Algorithm() mysql_proto.ChecksumAlgorithm_Type {
+	return mysql_proto.ChecksumAlgorithm_OFF
+}
+
+func (v *noneChecksumVerifier) Size() int {
+	return 0
+}
+
+func (v *noneChecksumVerifier) Verify(event Event) error {
+	return nil
+}
+
+// This returns a verifier for ChecksumAlgorithm_CRC32.  The checksum footer
+// is the little endian IEEE crc32 checksum of the event's header and body.
+// See Log_event::event_checksum_test (in sql/log_event.cc) for details.
+func NewCRC32ChecksumVerifier() ChecksumVerifier {
+	return &crc32ChecksumVerifier{}
+}
+
+type crc32ChecksumVerifier struct {
+}
+
+func (v *crc32ChecksumVerifier) Algorithm() mysql_proto.ChecksumAlgorithm_Type {
+	return mysql_proto.ChecksumAlgorithm_CRC32
+}
+
+func (v *crc32ChecksumVerifier) Size() int {
+	return 4
+}
+
+func (v *crc32ChecksumVerifier) Verify(event Event) error {
+	checksum := event.Checksum()
+	if len(checksum) != v.Size() {
+		return errors.Newf(
+			"Invalid checksum size: %d (expected: %d)",
+			len(checksum),
+			v.Size())
+	}
+
+	data := event.Bytes()
+	data = data[:len(data)-len(checksum)]
+
+	expected := LittleEndian.Uint32(checksum)
+	actual := computeEventCRC32(event.EventType(), event.Flags(), data)
+	if expected != actual {
+		return errors.Newf(
+			"Checksum mismatch (expected: %08x actual: %08x)",
+			expected,
+			actual)
+	}
+
+	return nil
+}
+
+// The in use flag (LogEventBinlogInUse) is set in the FDE while the log file
+// is being written.  mysql computes the FDE's checksum with the flag cleared.
+func computeEventCRC32(
+	eventType mysql_proto.LogEventType_Type,
+	flags uint16,
+	data []byte) uint32 {
+
+	if eventType != mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT ||
+		!EventFlags(flags).IsInUse() ||
+		len(data) < sizeOfBasicV4EventHeader {
+
+		return crc32.ChecksumIEEE(data)
+	}
+
+	header := make([]byte, sizeOfBasicV4EventHeader)
+	copy(header, data)
+	header[flagsOffsetInV4EventHeader] &^= byte(LogEventBinlogInUse)
+
+	crc := crc32.ChecksumIEEE(header)
+	return crc32.Update(crc, crc32.IEEETable, data[sizeOfBasicV4EventHeader:])
+}
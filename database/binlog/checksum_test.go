@@ -0,0 +1,469 @@
+package binlog
+
+import (
+	"bytes"
+	"hash/crc32"
+	"log"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type ChecksumSuite struct {
+	src *bytes.Buffer
+}
+
+var _ = Suite(&ChecksumSuite{})
+
+func (s *ChecksumSuite) SetUpTest(c *C) {
+	s.src = &bytes.Buffer{}
+	s.src.Write(logFileMagic)
+}
+
+func (s *ChecksumSuite) NewReader(
+	options LogFileV4EventReaderOptions) EventReader {
+
+	return NewLogFileV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		options)
+}
+
+// This writes the event with a crc32 checksum footer, and returns the
+// event's bytes.
+func (s *ChecksumSuite) WriteEvent(
+	eventType mysql_proto.LogEventType_Type,
+	data []byte) []byte {
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0), // timestamp
+		uint8(eventType),
+		uint32(1),    // server id
+		uint32(1234), // next position
+		uint16(0),
+		append(data, 0, 0, 0, 0))
+	if err != nil {
+		panic(err)
+	}
+
+	end := len(eventBytes) - 4
+	LittleEndian.PutUint32(eventBytes[end:], crc32.ChecksumIEEE(eventBytes[:end]))
+
+	s.src.Write(eventBytes)
+	return eventBytes
+}
+
+func (s *ChecksumSuite) WriteFDE(alg mysql_proto.ChecksumAlgorithm_Type) {
+	s.WriteEvent(
+		mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT,
+		[]byte{
+			// binlog version
+			4, 0,
+			// server version
+			53, 46, 54, 46, 49, 53, 45, 54, 51, 46,
+			48, 45, 108, 111, 103, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			// created timestamp
+			0, 0, 0, 0,
+			// total header size
+			19,
+			// fixed length data size per event type
+			56, 13, 0, 8, 0, 18, 0, 4, 4, 4, 4, 18, 0, 0, 92, 0, 4, 26,
+			8, 0, 0, 0, 8, 8, 8, 2, 0, 0, 0, 10, 10, 10, 25, 25, 0,
+			// checksum algorithm
+			byte(alg)})
+}
+
+func (s *ChecksumSuite) WriteXid() []byte {
+	return s.WriteEvent(
+		mysql_proto.LogEventType_XID_EVENT,
+		[]byte{117, 77, 99, 230, 0, 0, 0, 0})
+}
+
+func (s *ChecksumSuite) TestCRC32(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	xid, ok := event.(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(xid.Xid(), Equals, uint64(3865267573))
+	c.Check(event.Checksum(), DeepEquals, xidBytes[len(xidBytes)-4:])
+	c.Check(len(event.VariableLengthData()), Equals, 8)
+}
+
+func (s *ChecksumSuite) TestCRC32InUseFDE(c *C) {
+	fdeBytes := &bytes.Buffer{}
+	s.src = fdeBytes
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+
+	// mysql computes the FDE checksum without the in use flag.
+	data := fdeBytes.Bytes()
+	data[flagsOffsetInV4EventHeader] |= byte(LogEventBinlogInUse)
+
+	s.src = &bytes.Buffer{}
+	s.src.Write(logFileMagic)
+	s.src.Write(data)
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.Flags(), Equals, uint16(LogEventBinlogInUse))
+}
+
+func (s *ChecksumSuite) TestCRC32Mismatch(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+
+	// corrupt the xid
+	s.src.Bytes()[s.src.Len()-len(xidBytes)+sizeOfBasicV4EventHeader] ^= 0xff
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, NotNil)
+	c.Assert(event, NotNil)
+	_, ok := event.(*XidEvent)
+	c.Check(ok, IsTrue)
+}
+
+func (s *ChecksumSuite) TestCRC32MismatchNotVerified(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+
+	s.src.Bytes()[s.src.Len()-1] ^= 0xff
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{})
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.Checksum(), Not(DeepEquals), xidBytes[len(xidBytes)-4:])
+}
+
+func (s *ChecksumSuite) TestNone(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+
+	// Disabling the checksum does not strip the trailer.
+	reader := s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_OFF.Enum(),
+		VerifyChecksum:    true,
+	})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.Checksum(), DeepEquals, []byte{})
+	c.Check(
+		event.VariableLengthData(),
+		DeepEquals,
+		xidBytes[sizeOfBasicV4EventHeader:])
+}
+
+type testChecksumVerifier struct {
+	numVerified int
+}
+
+func (v *testChecksumVerifier) Algorithm() mysql_proto.ChecksumAlgorithm_Type {
+	return mysql_proto.ChecksumAlgorithm_CRC32
+}
+
+func (v *testChecksumVerifier) Size() int {
+	return 4
+}
+
+func (v *testChecksumVerifier) Verify(event Event) error {
+	v.numVerified++
+	if v.numVerified > 1 {
+		return errors.New("Test verification failure")
+	}
+	return nil
+}
+
+func (s *ChecksumSuite) TestCustomVerifier(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	s.WriteXid()
+	s.WriteXid()
+
+	verifier := &testChecksumVerifier{}
+	reader := s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumVerifiers: []ChecksumVerifier{verifier},
+		VerifyChecksum:    true,
+	})
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(verifier.numVerified, Equals, 1) // FDE
+
+	_, err = reader.NextEvent()
+	c.Assert(err, NotNil)
+	c.Check(verifier.numVerified, Equals, 2)
+}
+
+func (s *ChecksumSuite) TestMissingVerifier(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_UNDEFINED.Enum(),
+	})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+}
+
+func (s *ChecksumSuite) TestChecksumStatusVerified(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	s.WriteXid()
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumVerified)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumVerified)
+}
+
+func (s *ChecksumSuite) TestChecksumStatusNone(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	s.WriteXid()
+
+	// Not verified.
+	reader := s.NewReader(LogFileV4EventReaderOptions{})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumNone)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumNone)
+
+	// No checksum footer.
+	s.SetUpTest(c)
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	s.WriteXid()
+
+	reader = s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_OFF.Enum(),
+		VerifyChecksum:    true,
+	})
+
+	_, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumNone)
+}
+
+func (s *ChecksumSuite) TestChecksumStatusMismatch(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+	s.WriteXid()
+
+	// corrupt the first xid
+	offset := s.src.Len() - 2*len(xidBytes) + sizeOfBasicV4EventHeader
+	s.src.Bytes()[offset] ^= 0xff
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, NotNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumMismatch)
+}
+
+func (s *ChecksumSuite) TestChecksumStatusMismatchLenient(c *C) {
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	xidBytes := s.WriteXid()
+	s.WriteXid()
+
+	// corrupt the first xid
+	offset := s.src.Len() - 2*len(xidBytes) + sizeOfBasicV4EventHeader
+	s.src.Bytes()[offset] ^= 0xff
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{
+		VerifyChecksum:  true,
+		LenientChecksum: true,
+	})
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	// The stream continues past the corrupted event.
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*XidEvent)
+	c.Check(ok, IsTrue)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumMismatch)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.ChecksumStatus(), Equals, ChecksumVerified)
+}
+
+func (s *ChecksumSuite) TestChecksumStatusString(c *C) {
+	c.Check(ChecksumNone.String(), Equals, "NONE")
+	c.Check(ChecksumVerified.String(), Equals, "VERIFIED")
+	c.Check(ChecksumMismatch.String(), Equals, "MISMATCH")
+	c.Check(ChecksumStatus(7).String(), Equals, "UNKNOWN(7)")
+}
+
+// This writes an artificial rotate event (as sent by the master at the
+// beginning of a replication stream), with a crc32 checksum footer when
+// checksummed is true.
+func (s *ChecksumSuite) WriteArtificialRotate(checksummed bool) {
+	data := []byte{4, 0, 0, 0, 0, 0, 0, 0}
+	data = append(data, "mysql-bin.000042"...)
+	if checksummed {
+		data = append(data, 0, 0, 0, 0)
+	}
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0), // timestamp
+		uint8(mysql_proto.LogEventType_ROTATE_EVENT),
+		uint32(1), // server id
+		uint32(0), // next position
+		uint16(LogEventArtificial),
+		data)
+	if err != nil {
+		panic(err)
+	}
+
+	if checksummed {
+		end := len(eventBytes) - 4
+		LittleEndian.PutUint32(
+			eventBytes[end:],
+			crc32.ChecksumIEEE(eventBytes[:end]))
+	}
+
+	s.src.Write(eventBytes)
+}
+
+func (s *ChecksumSuite) TestArtificialRotateCRC32(c *C) {
+	s.WriteArtificialRotate(true)
+	s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+	s.WriteXid()
+
+	for _, options := range []LogFileV4EventReaderOptions{
+		{},
+		{VerifyChecksum: true},
+		{ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_CRC32.Enum()},
+	} {
+		reader := s.NewReader(options)
+
+		event, err := reader.NextEvent()
+		c.Assert(err, IsNil)
+		rotate, ok := event.(*RotateEvent)
+		c.Assert(ok, IsTrue)
+		c.Check(rotate.IsArtificial(), IsTrue)
+		c.Check(string(rotate.NewLogName()), Equals, "mysql-bin.000042")
+		c.Check(rotate.Checksum(), HasLen, 4)
+
+		expectedStatus := ChecksumNone
+		if options.VerifyChecksum {
+			expectedStatus = ChecksumVerified
+		}
+		c.Check(rotate.ChecksumStatus(), Equals, expectedStatus)
+
+		tracker := NewPositionTracker(LogPosition{testSourceName, 4}, true)
+		tracker.Processed(rotate)
+		c.Check(tracker.LogFile(), Equals, "mysql-bin.000042")
+
+		_, err = reader.NextEvent()
+		c.Assert(err, IsNil)
+		event, err = reader.NextEvent()
+		c.Assert(err, IsNil)
+		_, ok = event.(*XidEvent)
+		c.Check(ok, IsTrue)
+
+		// Rewind the stream for the next reader.
+		s.SetUpTest(c)
+		s.WriteArtificialRotate(true)
+		s.WriteFDE(mysql_proto.ChecksumAlgorithm_CRC32)
+		s.WriteXid()
+	}
+}
+
+func (s *ChecksumSuite) TestArtificialRotateWithoutChecksum(c *C) {
+	s.WriteArtificialRotate(false)
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{})
+
+	// The trailing bytes do not match the crc32 checksum, and are kept.
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	rotate, ok := event.(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(rotate.NewLogName()), Equals, "mysql-bin.000042")
+	c.Check(rotate.Checksum(), HasLen, 0)
+
+	s.SetUpTest(c)
+	s.WriteArtificialRotate(true)
+
+	// The negotiated checksum algorithm takes precedence over detection.
+	reader = s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_OFF.Enum(),
+	})
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(
+		event.(*RotateEvent).NewLogName(),
+		HasLen,
+		len("mysql-bin.000042")+4)
+}
+
+func (s *ChecksumSuite) TestArtificialRotateCRC32Mismatch(c *C) {
+	s.WriteArtificialRotate(true)
+	s.src.Bytes()[s.src.Len()-1] ^= 0xff
+
+	reader := s.NewReader(LogFileV4EventReaderOptions{
+		ChecksumAlgorithm: mysql_proto.ChecksumAlgorithm_CRC32.Enum(),
+		VerifyChecksum:    true,
+	})
+
+	event, err := reader.NextEvent()
+	c.Assert(err, NotNil)
+	rotate, ok := event.(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(rotate.NewLogName()), Equals, "mysql-bin.000042")
+	c.Check(rotate.ChecksumStatus(), Equals, ChecksumMismatch)
+}
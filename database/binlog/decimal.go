@@ -0,0 +1,270 @@
+package binlog
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// Decimal is an exact (arbitrary precision) decimal value, i.e.,
+// unscaled * 10^-scale.  Decimal values are immutable; arithmetic operations
+// return new values.  The zero value is 0 (with scale 0).
+type Decimal struct {
+	unscaled *big.Int // nil means 0
+	scale    int
+}
+
+var bigTen = big.NewInt(10)
+
+// This returns unscaled * 10^-scale.  The scale must be non-negative.
+func NewDecimal(unscaled *big.Int, scale int) Decimal {
+	if scale < 0 {
+		panic("Invalid decimal scale")
+	}
+	return Decimal{
+		unscaled: new(big.Int).Set(unscaled),
+		scale:    scale,
+	}
+}
+
+// This parses a decimal string of the form [+-]digits[.digits].  The
+// decimal's scale is the number of fractional digits.
+func ParseDecimal(s string) (Decimal, error) {
+	digits := s
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+
+	intPart := digits
+	fracPart := ""
+	if idx := strings.IndexByte(digits, '.'); idx >= 0 {
+		intPart = digits[:idx]
+		fracPart = digits[idx+1:]
+	}
+
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, errors.Newf("Invalid decimal: %s", s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return Decimal{}, errors.Newf("Invalid decimal: %s", s)
+			}
+		}
+	}
+
+	unscaled, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Decimal{}, errors.Newf("Invalid decimal: %s", s)
+	}
+	if s[0] == '-' {
+		unscaled.Neg(unscaled)
+	}
+
+	return Decimal{
+		unscaled: unscaled,
+		scale:    len(fracPart),
+	}, nil
+}
+
+func (d Decimal) bigInt() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// Unscaled returns a copy of the decimal's unscaled value.
+func (d Decimal) Unscaled() *big.Int {
+	return new(big.Int).Set(d.bigInt())
+}
+
+// Scale returns the number of fractional digits.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0, and +1 if d > 0.
+func (d Decimal) Sign() int {
+	return d.bigInt().Sign()
+}
+
+// This returns the unscaled value with the scale increased to the given
+// (larger or equal) scale.
+func (d Decimal) unscaledAt(scale int) *big.Int {
+	result := new(big.Int).Set(d.bigInt())
+	if scale > d.scale {
+		factor := new(big.Int).Exp(bigTen, big.NewInt(int64(scale-d.scale)), nil)
+		result.Mul(result, factor)
+	}
+	return result
+}
+
+func maxScale(d1 Decimal, d2 Decimal) int {
+	if d1.scale > d2.scale {
+		return d1.scale
+	}
+	return d2.scale
+}
+
+// Add returns d + other.  The result's scale is the larger of the two scales.
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := maxScale(d, other)
+	result := d.unscaledAt(scale)
+	result.Add(result, other.unscaledAt(scale))
+	return Decimal{unscaled: result, scale: scale}
+}
+
+// Sub returns d - other.  The result's scale is the larger of the two scales.
+func (d Decimal) Sub(other Decimal) Decimal {
+	scale := maxScale(d, other)
+	result := d.unscaledAt(scale)
+	result.Sub(result, other.unscaledAt(scale))
+	return Decimal{unscaled: result, scale: scale}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{
+		unscaled: new(big.Int).Neg(d.bigInt()),
+		scale:    d.scale,
+	}
+}
+
+// Cmp compares the decimal values (regardless of scale) and returns -1 if
+// d < other, 0 if d == other, and +1 if d > other.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := maxScale(d, other)
+	return d.unscaledAt(scale).Cmp(other.unscaledAt(scale))
+}
+
+// String returns the decimal's string representation, with exactly Scale()
+// fractional digits (e.g., "-12.340").
+func (d Decimal) String() string {
+	unscaled := d.bigInt()
+
+	digits := new(big.Int).Abs(unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		split := len(digits) - d.scale
+		digits = digits[:split] + "." + digits[split:]
+	}
+
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+//
+// Binary (NEWDECIMAL) codec.  See decimal2bin / bin2decimal in
+// strings/decimal.c for details.
+//
+
+const (
+	digitsPerDecimalWord = 9
+	bytesPerDecimalWord  = 4
+)
+
+// The number of bytes used to store the leftover (< 9) digits.
+var decimalDigitsToBytes = [digitsPerDecimalWord + 1]int{
+	0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+var decimalWordBase = big.NewInt(1000000000)
+
+// This returns the binary size of a decimal(precision, scale) value.
+func decimalBinarySize(precision int, scale int) int {
+	intg := precision - scale
+	return (intg/digitsPerDecimalWord)*bytesPerDecimalWord +
+		decimalDigitsToBytes[intg%digitsPerDecimalWord] +
+		(scale/digitsPerDecimalWord)*bytesPerDecimalWord +
+		decimalDigitsToBytes[scale%digitsPerDecimalWord]
+}
+
+func decodeDecimal(data []byte, precision int, scale int) (
+	value Decimal,
+	remaining []byte,
+	err error) {
+
+	size := decimalBinarySize(precision, scale)
+	data, remaining, err = readSlice(data, size)
+	if err != nil {
+		return Decimal{}, nil, err
+	}
+
+	// The sign bit is stored inverted (set for non-negative values), and
+	// negative values are stored with all bits inverted.
+	isNegative := data[0]&0x80 == 0
+	mask := byte(0)
+	if isNegative {
+		mask = 0xff
+	}
+
+	buf := make([]byte, size)
+	for i, b := range data {
+		buf[i] = b ^ mask
+	}
+	buf[0] ^= 0x80
+
+	unscaled := new(big.Int)
+	word := new(big.Int)
+
+	appendDigits := func(numDigits int) error {
+		numBytes := bytesPerDecimalWord
+		if numDigits < digitsPerDecimalWord {
+			numBytes = decimalDigitsToBytes[numDigits]
+		}
+		if numBytes == 0 {
+			return nil
+		}
+
+		val := uint64(0)
+		for _, b := range buf[:numBytes] {
+			val = val<<8 | uint64(b)
+		}
+		buf = buf[numBytes:]
+
+		factor := decimalWordBase
+		if numDigits < digitsPerDecimalWord {
+			factor = new(big.Int).Exp(bigTen, big.NewInt(int64(numDigits)), nil)
+		}
+		if val >= factor.Uint64() {
+			return errors.Newf("Invalid decimal digits: %d", val)
+		}
+
+		unscaled.Mul(unscaled, factor)
+		unscaled.Add(unscaled, word.SetUint64(val))
+		return nil
+	}
+
+	intg := precision - scale
+
+	// Leading (partial) integer word, then the full integer words.
+	if err = appendDigits(intg % digitsPerDecimalWord); err != nil {
+		return Decimal{}, nil, err
+	}
+	for i := 0; i < intg/digitsPerDecimalWord; i++ {
+		if err = appendDigits(digitsPerDecimalWord); err != nil {
+			return Decimal{}, nil, err
+		}
+	}
+
+	// Full fractional words, then the trailing (partial) fractional word.
+	for i := 0; i < scale/digitsPerDecimalWord; i++ {
+		if err = appendDigits(digitsPerDecimalWord); err != nil {
+			return Decimal{}, nil, err
+		}
+	}
+	if err = appendDigits(scale % digitsPerDecimalWord); err != nil {
+		return Decimal{}, nil, err
+	}
+
+	if isNegative {
+		unscaled.Neg(unscaled)
+	}
+
+	return Decimal{unscaled: unscaled, scale: scale}, remaining, nil
+}
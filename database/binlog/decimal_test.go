@@ -0,0 +1,175 @@
+package binlog
+
+import (
+	"math/big"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type DecimalSuite struct {
+}
+
+var _ = Suite(&DecimalSuite{})
+
+func mustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (s *DecimalSuite) TestParseAndString(c *C) {
+	for _, input := range []string{
+		"0", "1", "-1", "12.34", "-12.340", "0.05", "-0.005", "1234567890.1234",
+		"123456789012345678901234567890.123456789012345678901234567890",
+	} {
+		d, err := ParseDecimal(input)
+		c.Assert(err, IsNil)
+		c.Check(d.String(), Equals, input)
+	}
+
+	d := mustParseDecimal("+.5")
+	c.Check(d.String(), Equals, "0.5")
+	c.Check(d.Scale(), Equals, 1)
+	c.Check(d.Unscaled(), DeepEquals, big.NewInt(5))
+
+	d = mustParseDecimal("7.")
+	c.Check(d.String(), Equals, "7")
+	c.Check(d.Scale(), Equals, 0)
+
+	for _, input := range []string{"", "-", ".", "1.2.3", "1e5", "abc", " 1"} {
+		_, err := ParseDecimal(input)
+		c.Check(err, NotNil, Commentf("input: %s", input))
+	}
+}
+
+func (s *DecimalSuite) TestZeroValue(c *C) {
+	var zero Decimal
+	c.Check(zero.String(), Equals, "0")
+	c.Check(zero.Sign(), Equals, 0)
+	c.Check(zero.Add(mustParseDecimal("1.5")).String(), Equals, "1.5")
+	c.Check(zero.Cmp(mustParseDecimal("0.000")), Equals, 0)
+}
+
+func (s *DecimalSuite) TestSumDifferingScales(c *C) {
+	sum := Decimal{}
+	for _, v := range []string{"1.5", "2.25", "-0.125", "100", "0.0000001"} {
+		sum = sum.Add(mustParseDecimal(v))
+	}
+
+	c.Check(sum.String(), Equals, "103.6250001")
+	c.Check(sum.Scale(), Equals, 7)
+
+	// Amounts which are not exactly representable as floats.
+	sum = Decimal{}
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(mustParseDecimal("0.1"))
+	}
+	c.Check(sum.Cmp(mustParseDecimal("1")), Equals, 0)
+	c.Check(sum.String(), Equals, "1.0")
+}
+
+func (s *DecimalSuite) TestSub(c *C) {
+	d := mustParseDecimal("10.5").Sub(mustParseDecimal("0.75"))
+	c.Check(d.String(), Equals, "9.75")
+
+	d = mustParseDecimal("0.75").Sub(mustParseDecimal("10.5"))
+	c.Check(d.String(), Equals, "-9.75")
+	c.Check(d.Sign(), Equals, -1)
+	c.Check(d.Neg().String(), Equals, "9.75")
+
+	d = mustParseDecimal("1.10").Sub(mustParseDecimal("1.1"))
+	c.Check(d.String(), Equals, "0.00")
+	c.Check(d.Sign(), Equals, 0)
+}
+
+func (s *DecimalSuite) TestCmp(c *C) {
+	c.Check(mustParseDecimal("1.50").Cmp(mustParseDecimal("1.5")), Equals, 0)
+	c.Check(mustParseDecimal("1.49").Cmp(mustParseDecimal("1.5")), Equals, -1)
+	c.Check(mustParseDecimal("2").Cmp(mustParseDecimal("1.999")), Equals, 1)
+	c.Check(mustParseDecimal("-2").Cmp(mustParseDecimal("-1.999")), Equals, -1)
+}
+
+func (s *DecimalSuite) TestImmutable(c *C) {
+	unscaled := big.NewInt(1234)
+	d := NewDecimal(unscaled, 2)
+	unscaled.SetInt64(1)
+	c.Check(d.String(), Equals, "12.34")
+
+	d.Unscaled().SetInt64(1)
+	c.Check(d.String(), Equals, "12.34")
+
+	_ = d.Add(mustParseDecimal("1.111"))
+	c.Check(d.String(), Equals, "12.34")
+}
+
+func (s *DecimalSuite) TestNewDecimalFieldDescriptor(c *C) {
+	d, remaining, err := NewNewDecimalFieldDescriptor(
+		true,
+		[]byte{14, 4, 'r'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "r")
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_NEWDECIMAL)
+	c.Check(d.IsNullable(), IsTrue)
+
+	for _, metadata := range [][]byte{{14}, {0, 0}, {66, 0}, {65, 31}, {4, 5}} {
+		_, _, err = NewNewDecimalFieldDescriptor(true, metadata)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *DecimalSuite) TestNewDecimalParseValue(c *C) {
+	type testCase struct {
+		precision byte
+		scale     byte
+		input     []byte
+		expected  string
+	}
+
+	for _, t := range []testCase{
+		// Example from the mysql documentation.
+		{14, 4, []byte{0x81, 0x0d, 0xfb, 0x38, 0xd2, 0x04, 0xd2}, "1234567890.1234"},
+		{14, 4, []byte{0x7e, 0xf2, 0x04, 0xc7, 0x2d, 0xfb, 0x2d}, "-1234567890.1234"},
+		{4, 2, []byte{0x8c, 0x22}, "12.34"},
+		{4, 2, []byte{0x73, 0xdd}, "-12.34"},
+		{4, 2, []byte{0x80, 0x00}, "0.00"},
+		{4, 2, []byte{0x80, 0x05}, "0.05"},
+		{3, 0, []byte{0x80, 0x7b}, "123"},
+		{2, 2, []byte{0x80 | 0x63}, "0.99"},
+		// 9 integer digits + 9 fractional digits (full words only).
+		{18, 9,
+			[]byte{0x87, 0x5b, 0xcd, 0x15, 0x07, 0x5b, 0xcd, 0x15},
+			"123456789.123456789"},
+	} {
+		d, _, err := NewNewDecimalFieldDescriptor(
+			true,
+			[]byte{t.precision, t.scale})
+		c.Assert(err, IsNil)
+
+		val, remaining, err := d.ParseValue(append(t.input, "rest"...))
+		c.Assert(err, IsNil, Commentf("expected: %s", t.expected))
+		c.Check(string(remaining), Equals, "rest")
+
+		dec, ok := val.(Decimal)
+		c.Assert(ok, IsTrue)
+		c.Check(dec.String(), Equals, t.expected)
+		c.Check(dec.Scale(), Equals, int(t.scale))
+	}
+}
+
+func (s *DecimalSuite) TestNewDecimalParseValueErrors(c *C) {
+	d, _, err := NewNewDecimalFieldDescriptor(true, []byte{14, 4})
+	c.Assert(err, IsNil)
+
+	// Too few bytes.
+	_, _, err = d.ParseValue([]byte{0x81, 0x0d, 0xfb, 0x38, 0xd2, 0x04})
+	c.Check(err, NotNil)
+
+	// Invalid digits (4 fractional digits > 9999).
+	_, _, err = d.ParseValue([]byte{0x81, 0x0d, 0xfb, 0x38, 0xd2, 0xff, 0xff})
+	c.Check(err, NotNil)
+}
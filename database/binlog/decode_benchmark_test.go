@@ -0,0 +1,511 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// Benchmarks and allocation budgets for the decode hot path.  The expected
+// allocs/op are:
+//
+//	event header parse:        0
+//	integer value decode:      1 (the boxed interface{} value)
+//	  with ParseUint64:        0
+//	temporal value decode:     1 (the boxed time.Time value)
+//	rows event decode (V1):    2 + 1 per row + 1 per boxed value
+//	                           + O(log(# rows)) for growing the rows slice
+//	  with a RowPool:          2 + 1 per boxed value
+//	                           + O(log(# rows)) for growing the rows slice
+//
+// NOTE: integer values less than 256 are not boxed (the go runtime uses
+// static values), and null values never allocate.
+//
+// DecodeAllocsSuite enforces these budgets.
+
+const benchmarkNumRows = 100
+
+func benchmarkHeaderBytes() []byte {
+	b, err := CreateEventBytes(
+		uint32(1234),
+		uint8(mysql_proto.LogEventType_XID_EVENT),
+		uint32(1),
+		uint32(5678),
+		uint16(0),
+		make([]byte, 8))
+	if err != nil {
+		panic(err)
+	}
+	return b[:sizeOfBasicV4EventHeader]
+}
+
+func benchmarkLongLongBytes() []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, 1<<40+12345)
+	return b
+}
+
+// Each row contains a nil tiny (column 0), a short, an int24, a long and a
+// longlong; none of the values fit in a single byte.
+func benchmarkWriteRowsEvent(numRows int) *RawV4Event {
+	data := []byte{
+		// table id
+		testRowsTableId, 0, 0, 0, 0, 0,
+		// table flags,
+		14, 0,
+		// # known columns
+		5,
+		// used column bits
+		0x1f,
+	}
+
+	for i := 0; i < numRows; i++ {
+		data = append(data,
+			1,          // null column bits
+			0x34, 0x12, // short
+			0x56, 0x34, 0x12, // int24
+			0x78, 0x56, 0x34, 0x12, // long
+			0x78, 0x56, 0x34, 0x12, 0x78, 0x56, 0x34, 0x12) // longlong
+	}
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0),
+		uint8(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1),
+		uint32(1),
+		uint32(1234),
+		uint16(0),
+		data)
+	if err != nil {
+		panic(err)
+	}
+
+	raw := &RawV4Event{data: eventBytes}
+	err = parseBasicV4EventHeader(eventBytes, &raw.header)
+	if err != nil {
+		panic(err)
+	}
+
+	return raw
+}
+
+func newBenchmarkWriteRowsParser() V4EventParser {
+	parser := newWriteRowsEventV1Parser()
+	parser.(*WriteRowsEventParser).SetTableContext(newTestTableContext())
+	return parser
+}
+
+func parseBenchmarkWriteRowsEvent(parser V4EventParser, raw *RawV4Event) {
+	err := raw.SetFixedLengthDataSize(parser.FixedLengthDataSize())
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = parser.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func BenchmarkParseEventHeader(b *testing.B) {
+	headerBytes := benchmarkHeaderBytes()
+	header := basicV4EventHeader{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = parseBasicV4EventHeader(headerBytes, &header)
+	}
+}
+
+func BenchmarkDecodeLongLong(b *testing.B) {
+	d := NewLongLongFieldDescriptor(Nullable)
+	valBytes := benchmarkLongLongBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = d.ParseValue(valBytes)
+	}
+}
+
+func BenchmarkDecodeLongLongUint64(b *testing.B) {
+	d := NewLongLongFieldDescriptor(Nullable).(IntegerFieldDescriptor)
+	valBytes := benchmarkLongLongBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = d.ParseUint64(valBytes)
+	}
+}
+
+// The generic fixed length descriptor (closure based) integer decode path,
+// which was used prior to integerFieldDescriptor.  Kept for comparison.
+func BenchmarkDecodeLongLongFixedLength(b *testing.B) {
+	d := newFixedLengthFieldDescriptor(
+		mysql_proto.FieldType_LONGLONG,
+		Nullable,
+		8,
+		func(b []byte) interface{} { return LittleEndian.Uint64(b) })
+	valBytes := benchmarkLongLongBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = d.ParseValue(valBytes)
+	}
+}
+
+// The byte by byte integer assembly, which was used by bytesToLEUint prior to
+// the fixed size loads.  Kept for comparison.
+func bytesToLEUintByteByByte(valBytes []byte) uint64 {
+	val := uint64(0)
+	for i, b := range valBytes {
+		val += uint64(b) << (uint(i) * 8)
+	}
+	return val
+}
+
+var benchmarkLEUintInputs = [][]byte{
+	{0x01},
+	{0x01, 0x02},
+	{0x01, 0x02, 0x03},
+	{0x01, 0x02, 0x03, 0x04},
+	{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+}
+
+var benchmarkLEUintSink uint64
+
+func BenchmarkBytesToLEUint(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchmarkLEUintInputs {
+			benchmarkLEUintSink += bytesToLEUint(input)
+		}
+	}
+}
+
+func BenchmarkBytesToLEUintByteByByte(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchmarkLEUintInputs {
+			benchmarkLEUintSink += bytesToLEUintByteByByte(input)
+		}
+	}
+}
+
+func BenchmarkDecodeDateTime(b *testing.B) {
+	d := NewDateTimeFieldDescriptor(Nullable)
+	valBytes := testDateTimeBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = d.ParseValue(valBytes)
+	}
+}
+
+func BenchmarkDecodeDateTime2(b *testing.B) {
+	d, _, err := NewDateTime2FieldDescriptor(Nullable, []byte{0})
+	if err != nil {
+		b.Fatal(err)
+	}
+	valBytes := testDateTime2Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = d.ParseValue(valBytes)
+	}
+}
+
+func BenchmarkDecodeWriteRowsEvent(b *testing.B) {
+	parser := newBenchmarkWriteRowsParser()
+	raw := benchmarkWriteRowsEvent(benchmarkNumRows)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw.data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parseBenchmarkWriteRowsEvent(parser, raw)
+	}
+}
+
+func newBenchmarkPooledWriteRowsParser(pool *RowPool) V4EventParser {
+	parser := newBenchmarkWriteRowsParser()
+	parser.(*WriteRowsEventParser).SetRowAllocator(pool)
+	return parser
+}
+
+// Parses the event and frees the decoded rows.
+func parseAndFreeBenchmarkWriteRowsEvent(
+	parser V4EventParser,
+	raw *RawV4Event,
+	pool *RowPool) {
+
+	err := raw.SetFixedLengthDataSize(parser.FixedLengthDataSize())
+	if err != nil {
+		panic(err)
+	}
+
+	event, err := parser.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, row := range event.(*WriteRowsEvent).InsertedRows() {
+		pool.FreeRow(row)
+	}
+}
+
+func BenchmarkDecodeWriteRowsEventWithRowPool(b *testing.B) {
+	pool := NewRowPool(benchmarkNumRows)
+	parser := newBenchmarkPooledWriteRowsParser(pool)
+	raw := benchmarkWriteRowsEvent(benchmarkNumRows)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw.data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parseAndFreeBenchmarkWriteRowsEvent(parser, raw, pool)
+	}
+}
+
+const (
+	benchmarkNumBlobRows = 10
+	benchmarkBlobSize    = 64 * 1024
+)
+
+// Each row contains a long (column 0) and a 64KB blob (column 1).
+func benchmarkBlobTableContext(lazy bool) TableContext {
+	newBlob := NewBlobFieldDescriptor
+	if lazy {
+		newBlob = NewLazyBlobFieldDescriptor
+	}
+
+	blob, _, err := newBlob(Nullable, []byte{3})
+	if err != nil {
+		panic(err)
+	}
+
+	return &testTableContext{
+		columns: []ColumnDescriptor{
+			NewColumnDescriptor(NewLongFieldDescriptor(Nullable), 0),
+			NewColumnDescriptor(blob, 1),
+		},
+	}
+}
+
+func benchmarkBlobWriteRowsEvent() *RawV4Event {
+	data := []byte{
+		// table id
+		testRowsTableId, 0, 0, 0, 0, 0,
+		// table flags,
+		14, 0,
+		// # known columns
+		2,
+		// used column bits
+		0x03,
+	}
+
+	size := uint32(benchmarkBlobSize)
+	blob := make([]byte, size)
+	for i := 0; i < benchmarkNumBlobRows; i++ {
+		data = append(data,
+			0,                      // null column bits
+			0x78, 0x56, 0x34, 0x12, // long
+			// blob length (3 bytes)
+			byte(size),
+			byte(size>>8),
+			byte(size>>16))
+		data = append(data, blob...)
+	}
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0),
+		uint8(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1),
+		uint32(1),
+		uint32(1234),
+		uint16(0),
+		data)
+	if err != nil {
+		panic(err)
+	}
+
+	raw := &RawV4Event{data: eventBytes}
+	err = parseBasicV4EventHeader(eventBytes, &raw.header)
+	if err != nil {
+		panic(err)
+	}
+
+	return raw
+}
+
+func benchmarkDecodeBlobs(b *testing.B, lazy bool, use func(interface{})) {
+	parser := newWriteRowsEventV1Parser()
+	parser.(*WriteRowsEventParser).SetTableContext(
+		benchmarkBlobTableContext(lazy))
+	raw := benchmarkBlobWriteRowsEvent()
+
+	err := raw.SetFixedLengthDataSize(parser.FixedLengthDataSize())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw.data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		event, err := parser.Parse(raw)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, row := range event.(*WriteRowsEvent).InsertedRows() {
+			use(row[1])
+		}
+	}
+}
+
+var benchmarkBlobSink []byte
+
+// Eager blob decoding is zero-copy: the decoded []byte values reference the
+// event's data.
+func BenchmarkDecodeBlobsEager(b *testing.B) {
+	benchmarkDecodeBlobs(b, false, func(value interface{}) {})
+}
+
+// NOTE: The copy is made by the benchmark's consumer, not by the decoder.  It
+// models consumers which retain the values beyond the event's lifetime (and
+// hence must copy eagerly decoded values), for comparison with
+// BenchmarkDecodeBlobsLazyCopied.
+func BenchmarkDecodeBlobsEagerCopied(b *testing.B) {
+	benchmarkDecodeBlobs(b, false, func(value interface{}) {
+		raw := value.([]byte)
+		benchmarkBlobSink = make([]byte, len(raw))
+		copy(benchmarkBlobSink, raw)
+	})
+}
+
+func BenchmarkDecodeBlobsLazySkipped(b *testing.B) {
+	benchmarkDecodeBlobs(b, true, func(value interface{}) {})
+}
+
+func BenchmarkDecodeBlobsLazyCopied(b *testing.B) {
+	benchmarkDecodeBlobs(b, true, func(value interface{}) {
+		benchmarkBlobSink = value.(*LazyBlob).Bytes()
+	})
+}
+
+type DecodeAllocsSuite struct {
+}
+
+var _ = Suite(&DecodeAllocsSuite{})
+
+func (s *DecodeAllocsSuite) TestParseEventHeaderAllocs(c *C) {
+	headerBytes := benchmarkHeaderBytes()
+	header := basicV4EventHeader{}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = parseBasicV4EventHeader(headerBytes, &header)
+	})
+	c.Check(allocs, Equals, 0.0)
+	c.Check(header.EventLength, Equals, uint32(sizeOfBasicV4EventHeader+8))
+	c.Check(header.NextPosition, Equals, uint32(5678))
+}
+
+func (s *DecodeAllocsSuite) TestDecodeIntegerAllocs(c *C) {
+	d := NewLongLongFieldDescriptor(Nullable)
+	valBytes := benchmarkLongLongBytes()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _, _ = d.ParseValue(valBytes)
+	})
+	c.Check(allocs <= 1, Equals, true, Commentf("allocs: %v", allocs))
+}
+
+func (s *DecodeAllocsSuite) TestDecodeIntegerUint64Allocs(c *C) {
+	d := NewLongLongFieldDescriptor(Nullable).(IntegerFieldDescriptor)
+	valBytes := benchmarkLongLongBytes()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _, _ = d.ParseUint64(valBytes)
+	})
+	c.Check(allocs, Equals, 0.0)
+}
+
+func (s *DecodeAllocsSuite) TestBytesToLEUint(c *C) {
+	data := []byte{0xf1, 0xe2, 0xd3, 0xc4, 0xb5, 0xa6, 0x97, 0x88}
+	for n := 0; n <= len(data); n++ {
+		c.Check(
+			bytesToLEUint(data[:n]),
+			Equals,
+			bytesToLEUintByteByByte(data[:n]),
+			Commentf("width: %d", n))
+	}
+}
+
+func (s *DecodeAllocsSuite) TestDecodeTemporalAllocs(c *C) {
+	d := NewDateTimeFieldDescriptor(Nullable)
+	valBytes := testDateTimeBytes()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _, _ = d.ParseValue(valBytes)
+	})
+	c.Check(allocs <= 1, Equals, true, Commentf("allocs: %v", allocs))
+
+	d2, _, err := NewDateTime2FieldDescriptor(Nullable, []byte{0})
+	c.Assert(err, IsNil)
+	valBytes = testDateTime2Bytes()
+
+	allocs = testing.AllocsPerRun(100, func() {
+		_, _, _ = d2.ParseValue(valBytes)
+	})
+	c.Check(allocs <= 1, Equals, true, Commentf("allocs: %v", allocs))
+}
+
+func (s *DecodeAllocsSuite) TestDecodeWriteRowsEventAllocs(c *C) {
+	parser := newBenchmarkWriteRowsParser()
+	raw := benchmarkWriteRowsEvent(benchmarkNumRows)
+
+	// Sanity check the fixture.
+	err := raw.SetFixedLengthDataSize(parser.FixedLengthDataSize())
+	c.Assert(err, IsNil)
+	event, err := parser.Parse(raw)
+	c.Assert(err, IsNil)
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(len(rows), Equals, benchmarkNumRows)
+	c.Assert(rows[0][0], IsNil)
+	c.Assert(rows[0][4], Equals, uint64(0x1234567812345678))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		parseBenchmarkWriteRowsEvent(parser, raw)
+	})
+
+	// 4 boxed values per row; the rows slice grows ~log2(100) times.
+	budget := float64(2 + benchmarkNumRows*(1+4) + 10)
+	c.Check(allocs <= budget, Equals, true, Commentf(
+		"allocs: %v budget: %v", allocs, budget))
+}
+
+func (s *DecodeAllocsSuite) TestDecodeWriteRowsEventWithRowPoolAllocs(c *C) {
+	pool := NewRowPool(benchmarkNumRows)
+	parser := newBenchmarkPooledWriteRowsParser(pool)
+	raw := benchmarkWriteRowsEvent(benchmarkNumRows)
+
+	// Warm up the pool.
+	parseAndFreeBenchmarkWriteRowsEvent(parser, raw, pool)
+	c.Assert(pool.NumFreeRows(), Equals, benchmarkNumRows)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		parseAndFreeBenchmarkWriteRowsEvent(parser, raw, pool)
+	})
+
+	// Rows are recycled; only the boxed values and the rows slice allocate.
+	budget := float64(2 + benchmarkNumRows*4 + 10)
+	c.Check(allocs <= budget, Equals, true, Commentf(
+		"allocs: %v budget: %v", allocs, budget))
+}
@@ -0,0 +1,175 @@
+package binlog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dropbox/godropbox/container/lrucache"
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// All mysql field types fit in a single byte.
+const numFieldTypes = 256
+
+// DecodeMetrics collects decode metrics across all rows events parsed by the
+// parsers it's attached to (see DecodeOptions.Metrics).  The metrics are
+// enabled separately:
+//   - value timing (see EnableValueTiming) accumulates the time spent
+//     decoding column values, per field type.  This is useful for finding
+//     out which column types dominate the decode cost of wide tables.  NOTE:
+//     NULL values are not decoded, hence they are not counted.
+//   - table throughput (see EnableTableMetrics) tracks per-table row change
+//     counts and bytes.
+//
+// The zero value is ready to use, and does not collect any metrics.
+// DecodeMetrics is thread safe.
+type DecodeMetrics struct {
+	valueTiming int32 // 1 when value timing is enabled.
+
+	durations [numFieldTypes]int64 // in nanoseconds
+	counts    [numFieldTypes]int64
+
+	tableMutex sync.Mutex
+	tableLru   *lrucache.LRUCache // nil when table tracking is disabled.
+	tables     map[string]*TableThroughput
+}
+
+// TableThroughput is the amount of row changes decoded for a table.
+type TableThroughput struct {
+	// The number of changed rows (an update's before / after image pair
+	// counts as a single row change).
+	RowChanges int64
+
+	// The total size of the table's rows events (including event headers).
+	Bytes int64
+}
+
+// EnableValueTiming turns on per field type value decode timing.  Timing adds
+// a couple of clock reads per decoded value.
+func (m *DecodeMetrics) EnableValueTiming() {
+	atomic.StoreInt32(&m.valueTiming, 1)
+}
+
+func (m *DecodeMetrics) isValueTimingEnabled() bool {
+	return atomic.LoadInt32(&m.valueTiming) == 1
+}
+
+func (m *DecodeMetrics) record(
+	fieldType mysql_proto.FieldType_Type,
+	duration time.Duration) {
+
+	idx := uint8(fieldType)
+	atomic.AddInt64(&m.durations[idx], int64(duration))
+	atomic.AddInt64(&m.counts[idx], 1)
+}
+
+// Duration returns the total time spent decoding values of the field type.
+func (m *DecodeMetrics) Duration(fieldType mysql_proto.FieldType_Type) time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.durations[uint8(fieldType)]))
+}
+
+// NumValues returns the number of decoded values of the field type.
+func (m *DecodeMetrics) NumValues(fieldType mysql_proto.FieldType_Type) int64 {
+	return atomic.LoadInt64(&m.counts[uint8(fieldType)])
+}
+
+// Durations returns the total decode time of every field type which had at
+// least one value decoded.
+func (m *DecodeMetrics) Durations() map[mysql_proto.FieldType_Type]time.Duration {
+	result := make(map[mysql_proto.FieldType_Type]time.Duration)
+	for idx := 0; idx < numFieldTypes; idx++ {
+		if atomic.LoadInt64(&m.counts[idx]) == 0 {
+			continue
+		}
+		result[mysql_proto.FieldType_Type(idx)] = time.Duration(
+			atomic.LoadInt64(&m.durations[idx]))
+	}
+	return result
+}
+
+// EnableTableMetrics turns on per-table throughput tracking, keyed by
+// "<database>.<table>".  Up to maxTables tables are tracked; when the limit
+// is reached, the least recently updated table's throughput is dropped.
+// Calling EnableTableMetrics again clears the tracked tables.  maxTables
+// must be positive.
+func (m *DecodeMetrics) EnableTableMetrics(maxTables int) error {
+	if maxTables <= 0 {
+		return errors.Newf("Invalid max number of tables: %d", maxTables)
+	}
+
+	m.tableMutex.Lock()
+	defer m.tableMutex.Unlock()
+
+	m.tableLru = lrucache.New(maxTables)
+	m.tables = make(map[string]*TableThroughput)
+	return nil
+}
+
+func (m *DecodeMetrics) recordTable(
+	context TableContext,
+	numRows int,
+	numBytes int) {
+
+	m.tableMutex.Lock()
+	defer m.tableMutex.Unlock()
+
+	if m.tableLru == nil {
+		return
+	}
+
+	key := string(context.DatabaseName()) + "." + string(context.TableName())
+
+	throughput, ok := m.tables[key]
+	if ok {
+		// Mark the table as recently used.
+		m.tableLru.Get(key)
+	} else {
+		if m.tableLru.Len() >= m.tableLru.MaxSize() {
+			oldest, _, _ := m.tableLru.RemoveOldest()
+			delete(m.tables, oldest)
+		}
+
+		throughput = &TableThroughput{}
+		m.tables[key] = throughput
+		m.tableLru.Set(key, nil)
+	}
+
+	throughput.RowChanges += int64(numRows)
+	throughput.Bytes += int64(numBytes)
+}
+
+// TableMetrics returns a snapshot of the tracked tables' throughput, keyed by
+// "<database>.<table>".  This returns nil when table tracking is disabled.
+func (m *DecodeMetrics) TableMetrics() map[string]TableThroughput {
+	m.tableMutex.Lock()
+	defer m.tableMutex.Unlock()
+
+	if m.tableLru == nil {
+		return nil
+	}
+
+	result := make(map[string]TableThroughput, len(m.tables))
+	for key, throughput := range m.tables {
+		result[key] = *throughput
+	}
+	return result
+}
+
+// Reset clears all accumulated metrics.  Value timing and table tracking
+// remain enabled if they were enabled.
+func (m *DecodeMetrics) Reset() {
+	for idx := 0; idx < numFieldTypes; idx++ {
+		atomic.StoreInt64(&m.durations[idx], 0)
+		atomic.StoreInt64(&m.counts[idx], 0)
+	}
+
+	m.tableMutex.Lock()
+	defer m.tableMutex.Unlock()
+
+	if m.tableLru != nil {
+		m.tableLru = lrucache.New(m.tableLru.MaxSize())
+		m.tables = make(map[string]*TableThroughput)
+	}
+}
@@ -0,0 +1,213 @@
+package binlog
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type DecodeMetricsSuite struct {
+}
+
+var _ = Suite(&DecodeMetricsSuite{})
+
+func (s *DecodeMetricsSuite) parseRows(c *C, options DecodeOptions) {
+	src := &bytes.Buffer{}
+	parsers := NewV4EventParserMapWithOptions(options)
+	parsers.SetTableContext(newTestTableContext())
+	reader := NewParsedV4EventReader(
+		NewRawV4EventReader(src, testSourceName),
+		parsers)
+
+	_, _ = src.Write(benchmarkWriteRowsEvent(3).data)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Assert(len(event.(*WriteRowsEvent).InsertedRows()), Equals, 3)
+}
+
+func (s *DecodeMetricsSuite) TestDisabledByDefault(c *C) {
+	parsers := NewV4EventParserMap()
+	p := parsers.Get(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1)
+	c.Assert(p.(*WriteRowsEventParser).metrics, IsNil)
+
+	// Value timing must be enabled explicitly.
+	metrics := &DecodeMetrics{}
+	s.parseRows(c, DecodeOptions{Metrics: metrics})
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_LONGLONG), Equals, int64(0))
+
+	metrics.EnableValueTiming()
+	s.parseRows(c, DecodeOptions{Metrics: metrics})
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_LONGLONG), Equals, int64(3))
+
+	// Parsers without metrics don't accumulate timings.
+	s.parseRows(c, DecodeOptions{})
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_LONGLONG), Equals, int64(3))
+	c.Assert(len(metrics.Durations()), Equals, 4)
+
+	empty := &DecodeMetrics{}
+	c.Assert(empty.Durations(), DeepEquals,
+		map[mysql_proto.FieldType_Type]time.Duration{})
+}
+
+func (s *DecodeMetricsSuite) TestAccumulate(c *C) {
+	metrics := &DecodeMetrics{}
+	metrics.EnableValueTiming()
+
+	s.parseRows(c, DecodeOptions{Metrics: metrics})
+	s.parseRows(c, DecodeOptions{Metrics: metrics})
+
+	// The tiny column is always NULL, hence it's never decoded.
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_TINY), Equals, int64(0))
+
+	durations := metrics.Durations()
+	c.Assert(len(durations), Equals, 4)
+	for _, t := range []mysql_proto.FieldType_Type{
+		mysql_proto.FieldType_SHORT,
+		mysql_proto.FieldType_INT24,
+		mysql_proto.FieldType_LONG,
+		mysql_proto.FieldType_LONGLONG,
+	} {
+		c.Assert(metrics.NumValues(t), Equals, int64(6))
+		c.Assert(metrics.Duration(t) >= 0, IsTrue)
+		_, ok := durations[t]
+		c.Assert(ok, IsTrue)
+	}
+
+	metrics.Reset()
+	c.Assert(len(metrics.Durations()), Equals, 0)
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_LONG), Equals, int64(0))
+
+	// Value timing remains enabled.
+	s.parseRows(c, DecodeOptions{Metrics: metrics})
+	c.Assert(metrics.NumValues(mysql_proto.FieldType_LONG), Equals, int64(3))
+}
+
+func (s *DecodeMetricsSuite) tableContext(
+	tableId uint64,
+	table string) TableContext {
+
+	return &TableMapEvent{
+		tableId:      tableId,
+		databaseName: []byte("db"),
+		tableName:    []byte(table),
+		// long
+		columnTypesBytes: []byte{3},
+		columnDescriptors: []ColumnDescriptor{
+			NewColumnDescriptor(NewLongFieldDescriptor(false), 0),
+		},
+	}
+}
+
+// This returns a rows event for a single long column table.  Each row is a
+// single long value (update rows have a before and an after image value).
+func (s *DecodeMetricsSuite) rowsEvent(
+	c *C,
+	eventType mysql_proto.LogEventType_Type,
+	tableId uint64,
+	numValues int) *RawV4Event {
+
+	data := []byte{
+		// table id
+		byte(tableId), 0, 0, 0, 0, 0,
+		// flags
+		0, 0,
+		// number of columns
+		1,
+		// used columns
+		1,
+	}
+	if eventType == mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1 {
+		// after image used columns
+		data = append(data, 1)
+	}
+
+	for i := 0; i < numValues; i++ {
+		// null bits, long
+		data = append(data, 0, byte(i), 0, 0, 0)
+	}
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0),
+		uint8(eventType),
+		uint32(1),
+		uint32(1234),
+		uint16(0),
+		data)
+	c.Assert(err, IsNil)
+
+	raw := &RawV4Event{data: eventBytes}
+	c.Assert(parseBasicV4EventHeader(eventBytes, &raw.header), IsNil)
+	return raw
+}
+
+func (s *DecodeMetricsSuite) TestTableMetrics(c *C) {
+	metrics := &DecodeMetrics{}
+	c.Assert(metrics.TableMetrics(), IsNil)
+
+	c.Assert(metrics.EnableTableMetrics(0), NotNil)
+	c.Assert(metrics.EnableTableMetrics(-1), NotNil)
+	c.Assert(metrics.TableMetrics(), IsNil)
+
+	c.Assert(metrics.EnableTableMetrics(2), IsNil)
+	c.Assert(metrics.TableMetrics(), DeepEquals, map[string]TableThroughput{})
+
+	parsers := NewV4EventParserMapWithOptions(DecodeOptions{Metrics: metrics})
+
+	users := s.tableContext(1, "users")
+	posts := s.tableContext(2, "posts")
+	likes := s.tableContext(3, "likes")
+
+	parse := func(
+		context TableContext,
+		eventType mysql_proto.LogEventType_Type,
+		numValues int) int64 {
+
+		raw := s.rowsEvent(c, eventType, context.TableId(), numValues)
+
+		parser := parsers.Get(eventType)
+		parser.SetTableContext(context)
+		c.Assert(
+			raw.SetFixedLengthDataSize(parser.FixedLengthDataSize()),
+			IsNil)
+		_, err := parser.Parse(raw)
+		c.Assert(err, IsNil)
+
+		return int64(len(raw.Bytes()))
+	}
+
+	usersBytes := parse(users, mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 3)
+	postsBytes := parse(posts, mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 1)
+	usersBytes += parse(users, mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1, 4)
+	usersBytes += parse(users, mysql_proto.LogEventType_DELETE_ROWS_EVENT_V1, 1)
+	postsBytes += parse(posts, mysql_proto.LogEventType_DELETE_ROWS_EVENT_V1, 2)
+
+	c.Assert(metrics.TableMetrics(), DeepEquals, map[string]TableThroughput{
+		"db.users": {RowChanges: 6, Bytes: usersBytes},
+		"db.posts": {RowChanges: 3, Bytes: postsBytes},
+	})
+
+	// Table tracking does not enable value timing.
+	c.Assert(metrics.Durations(), DeepEquals,
+		map[mysql_proto.FieldType_Type]time.Duration{})
+
+	// The least recently updated table (users) is evicted.
+	likesBytes := parse(likes, mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 5)
+	c.Assert(metrics.TableMetrics(), DeepEquals, map[string]TableThroughput{
+		"db.posts": {RowChanges: 3, Bytes: postsBytes},
+		"db.likes": {RowChanges: 5, Bytes: likesBytes},
+	})
+
+	metrics.Reset()
+	c.Assert(metrics.TableMetrics(), DeepEquals, map[string]TableThroughput{})
+
+	usersBytes = parse(users, mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 1)
+	c.Assert(metrics.TableMetrics(), DeepEquals, map[string]TableThroughput{
+		"db.users": {RowChanges: 1, Bytes: usersBytes},
+	})
+}
@@ -7,14 +7,39 @@ import (
 	"github.com/dropbox/godropbox/errors"
 )
 
+// This decodes a little endian unsigned integer of up to 8 bytes.  The common
+// widths are decoded using fixed size loads (see decodeLEUint), the
+// remaining widths are assembled byte by byte.
 func bytesToLEUint(valBytes []byte) uint64 {
+	switch len(valBytes) {
+	case 1, 2, 3, 4, 8:
+		return decodeLEUint(valBytes)
+	}
+
 	val := uint64(0)
 	for i, b := range valBytes {
-		val += uint64(b) << (uint(i) * 8)
+		val |= uint64(b) << (uint(i) * 8)
 	}
 	return val
 }
 
+// This decodes a 1, 2, 3, 4 or 8 bytes little endian unsigned integer.  The
+// caller must validate the slice's length.  NOTE: This is kept small enough
+// to be inlined.
+func decodeLEUint(b []byte) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 3:
+		return uint64(LittleEndian.Uint24(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
 const NullLength = uint64(^uint32(0))
 
 // Note: this is equivalent to net_field_length in sql-common/pack.c
@@ -89,3 +114,23 @@ func readBitArray(bytes []byte, numVals int) (
 	}
 	return bitVector, bytes[bytesUsed:], nil
 }
+
+// Same as readBitArray, but returns the (unpacked) bitmap bytes instead of
+// allocating a bool slice.  Use isBitSet to check individual bits.
+func readBitmap(bytes []byte, numVals int) (
+	bitmap []byte,
+	remaining []byte,
+	err error) {
+
+	bytesUsed := ((numVals + 7) / 8)
+
+	if len(bytes) < bytesUsed {
+		return nil, nil, errors.New("Not enough bytes")
+	}
+
+	return bytes[:bytesUsed], bytes[bytesUsed:], nil
+}
+
+func isBitSet(bitmap []byte, i int) bool {
+	return (uint8(bitmap[i/8]) & (1 << (uint(i) % 8))) != 0
+}
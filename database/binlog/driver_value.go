@@ -0,0 +1,200 @@
+package binlog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// ToDriverValue converts a decoded column value (see
+// FieldDescriptor.ParseValue) into a database/sql/driver.Value, i.e., one of
+// nil, int64, float64, bool, []byte, string or time.Time.  The conversions
+// are:
+//
+//   - integers (of any width), set bitmasks and enum indices are converted
+//     to int64.  NOTE: the sign is uninterpreted (see SignExtend).  Values
+//     beyond math.MaxInt64 (i.e., unsigned BIGINT values, which do not fit
+//     in int64) are converted to their decimal string representations
+//     instead (which mysql accepts for BIGINT UNSIGNED columns).
+//   - Decimal and TimeValue values are converted to their canonical string
+//     representations (which mysql accepts for DECIMAL / TIME columns).
+//   - *LazyBlob values are copied into []byte.
+//   - typed array values are converted to json array strings, with string
+//     elements encoded as json strings.
+//
+// Values which are already driver.Value compatible are returned as is.
+func ToDriverValue(value interface{}) (driver.Value, error) {
+	switch v := value.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time:
+		return v, nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10), nil
+		}
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case Decimal:
+		return v.String(), nil
+	case TimeValue:
+		return v.String(), nil
+	case *LazyBlob:
+		return v.Bytes(), nil
+	case []interface{}:
+		return typedArrayToJson(v)
+	}
+
+	return nil, errors.Newf("Unsupported value type: %T", value)
+}
+
+func typedArrayToJson(elements []interface{}) (driver.Value, error) {
+	converted := make([]interface{}, len(elements))
+	for i, element := range elements {
+		if b, ok := element.([]byte); ok {
+			converted[i] = string(b) // json.Marshal base64 encodes []byte.
+		} else {
+			converted[i] = element
+		}
+	}
+
+	encoded, err := json.Marshal(converted)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode typed array")
+	}
+	return string(encoded), nil
+}
+
+// SignExtend reinterprets an integer column's decoded (unsigned) value as a
+// signed value, based on the column's width.  For example, a TINYINT's 0xff
+// is sign extended to -1.  value must be an integer column's decoded value
+// (see IntegerWidth).
+func SignExtend(
+	fieldType mysql_proto.FieldType_Type,
+	value interface{}) (int64, error) {
+
+	var val uint64
+	switch v := value.(type) {
+	case uint8:
+		val = uint64(v)
+	case uint16:
+		val = uint64(v)
+	case uint32:
+		val = uint64(v)
+	case uint64:
+		val = v
+	default:
+		return 0, errors.Newf("Unsupported integer value type: %T", value)
+	}
+
+	numBits := uint(0)
+	switch fieldType {
+	case mysql_proto.FieldType_TINY:
+		numBits = 8
+	case mysql_proto.FieldType_SHORT:
+		numBits = 16
+	case mysql_proto.FieldType_INT24:
+		numBits = 24
+	case mysql_proto.FieldType_LONG:
+		numBits = 32
+	case mysql_proto.FieldType_LONGLONG:
+		numBits = 64
+	default:
+		return 0, errors.Newf("Not an integer type: %s", fieldType.String())
+	}
+
+	shift := 64 - numBits
+	return int64(val<<shift) >> shift, nil
+}
+
+// driverValueFieldDescriptor converts the wrapped descriptor's decoded
+// values into driver.Values.  See DecodeOptions.DriverValues.
+type driverValueFieldDescriptor struct {
+	FieldDescriptor
+
+	// When true, integer values are sign extended.
+	signed bool
+}
+
+func (d *driverValueFieldDescriptor) ParseValue(data []byte) (
+	value interface{},
+	remaining []byte,
+	err error) {
+
+	value, remaining, _, err = d.parseValueWithLoss(data)
+	return value, remaining, err
+}
+
+// The conversion itself is lossless; only the wrapped descriptor's lossy
+// conversions are reported.
+func (d *driverValueFieldDescriptor) parseValueWithLoss(data []byte) (
+	value interface{},
+	remaining []byte,
+	loss string,
+	err error) {
+
+	if lossy, ok := asLossyFieldDescriptor(d.FieldDescriptor); ok {
+		value, remaining, loss, err = lossy.parseValueWithLoss(data)
+	} else {
+		value, remaining, err = d.FieldDescriptor.ParseValue(data)
+	}
+	if err != nil || value == nil {
+		return value, remaining, loss, err
+	}
+
+	if d.signed {
+		value, err = SignExtend(d.Type(), value)
+	} else {
+		value, err = ToDriverValue(value)
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return value, remaining, loss, nil
+}
+
+func isIntegerType(t mysql_proto.FieldType_Type) bool {
+	switch t {
+	case mysql_proto.FieldType_TINY,
+		mysql_proto.FieldType_SHORT,
+		mysql_proto.FieldType_INT24,
+		mysql_proto.FieldType_LONG,
+		mysql_proto.FieldType_LONGLONG:
+		return true
+	}
+	return false
+}
+
+// This wraps the table's column descriptors with driverValueFieldDescriptors.
+// Integer columns are sign extended when the table's optional metadata marks
+// them as signed (integer columns are treated as unsigned when the
+// signedness is unknown, hence a negative BIGINT value of such a column is
+// converted to the decimal string of its unsigned reinterpretation).
+func wrapDriverValueDescriptors(t *TableMapEvent) {
+	var unsigned []bool
+	if t.optionalMetadata != nil {
+		unsigned = t.optionalMetadata.UnsignedColumns
+	}
+
+	for idx, cd := range t.columnDescriptors {
+		signed := unsigned != nil && isIntegerType(cd.Type()) && !unsigned[idx]
+
+		t.columnDescriptors[idx] = NewColumnDescriptor(
+			&driverValueFieldDescriptor{
+				FieldDescriptor: cd,
+				signed:          signed,
+			},
+			cd.IndexPosition())
+	}
+}
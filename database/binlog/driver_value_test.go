@@ -0,0 +1,272 @@
+package binlog
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"math"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type DriverValueSuite struct {
+}
+
+var _ = Suite(&DriverValueSuite{})
+
+func (s *DriverValueSuite) TestToDriverValue(c *C) {
+	now := time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC)
+
+	type testCase struct {
+		input    interface{}
+		expected driver.Value
+	}
+
+	for _, t := range []testCase{
+		{nil, nil},
+		{uint8(0xff), int64(0xff)},
+		{uint16(0xffff), int64(0xffff)},
+		{uint32(0xffffffff), int64(0xffffffff)},
+		{uint64(123), int64(123)},
+		{uint64(math.MaxInt64), int64(math.MaxInt64)},
+		{uint64(math.MaxInt64) + 1, "9223372036854775808"},
+		{^uint64(0), "18446744073709551615"},
+		{int64(-5), int64(-5)},
+		{float32(1.5), float64(1.5)},
+		{float64(2.5), float64(2.5)},
+		{true, true},
+		{[]byte("foo"), []byte("foo")},
+		{"bar", "bar"},
+		{now, now},
+		{mustParseDecimal("-12.340"), "-12.340"},
+		{TimeValue{negative: true, hour: 1, minute: 2, second: 3}, "-01:02:03"},
+		{&LazyBlob{raw: []byte("blob")}, []byte("blob")},
+		{
+			[]interface{}{int64(-1), uint64(2), 1.5, []byte("a\"b"), nil, true},
+			`[-1,2,1.5,"a\"b",null,true]`,
+		},
+	} {
+		val, err := ToDriverValue(t.input)
+		c.Assert(err, IsNil)
+		c.Check(val, DeepEquals, t.expected)
+		c.Check(driver.IsValue(val), IsTrue)
+	}
+
+	_, err := ToDriverValue(struct{}{})
+	c.Check(err, NotNil)
+}
+
+func (s *DriverValueSuite) TestSignExtend(c *C) {
+	type testCase struct {
+		fieldType mysql_proto.FieldType_Type
+		input     interface{}
+		expected  int64
+	}
+
+	for _, t := range []testCase{
+		{mysql_proto.FieldType_TINY, uint8(0xff), -1},
+		{mysql_proto.FieldType_TINY, uint64(0x7f), 127},
+		{mysql_proto.FieldType_SHORT, uint16(0x8000), -32768},
+		{mysql_proto.FieldType_INT24, uint32(0xfffffe), -2},
+		{mysql_proto.FieldType_INT24, uint64(0x7fffff), 0x7fffff},
+		{mysql_proto.FieldType_LONG, uint32(0xffffffff), -1},
+		{mysql_proto.FieldType_LONGLONG, ^uint64(0), -1},
+		{mysql_proto.FieldType_LONGLONG, uint64(42), 42},
+	} {
+		val, err := SignExtend(t.fieldType, t.input)
+		c.Assert(err, IsNil)
+		c.Check(val, Equals, t.expected)
+	}
+
+	_, err := SignExtend(mysql_proto.FieldType_DOUBLE, uint64(1))
+	c.Check(err, NotNil)
+
+	_, err = SignExtend(mysql_proto.FieldType_LONG, "1")
+	c.Check(err, NotNil)
+}
+
+type driverValueTestColumn struct {
+	colType  mysql_proto.FieldType_Type
+	metadata []byte
+	data     []byte
+}
+
+// One column per decodable field type.
+func driverValueTestColumns() []driverValueTestColumn {
+	return []driverValueTestColumn{
+		{mysql_proto.FieldType_TINY, nil, []byte{0xff}},
+		{mysql_proto.FieldType_SHORT, nil, []byte{0xfe, 0xff}},
+		{mysql_proto.FieldType_INT24, nil, []byte{0xfd, 0xff, 0xff}},
+		{mysql_proto.FieldType_LONG, nil, []byte{0xfc, 0xff, 0xff, 0xff}},
+		{
+			mysql_proto.FieldType_LONGLONG,
+			nil,
+			[]byte{0xfb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{mysql_proto.FieldType_FLOAT, []byte{4}, []byte{0, 0, 0xc0, 0x3f}},
+		{
+			mysql_proto.FieldType_DOUBLE,
+			[]byte{8},
+			[]byte{0, 0, 0, 0, 0, 0, 0x04, 0x40},
+		},
+		{mysql_proto.FieldType_NULL, nil, nil},
+		{mysql_proto.FieldType_TIMESTAMP, nil, []byte{1, 0, 0, 0}},
+		{mysql_proto.FieldType_DATETIME, nil, testDateTimeBytes()},
+		{mysql_proto.FieldType_YEAR, nil, []byte{115}},
+		{mysql_proto.FieldType_VARCHAR, []byte{10, 0}, []byte{3, 'a', 'b', 'c'}},
+		{mysql_proto.FieldType_TIMESTAMP2, []byte{0}, []byte{0, 0, 0, 2}},
+		{mysql_proto.FieldType_DATETIME2, []byte{0}, testDateTime2Bytes()},
+		{mysql_proto.FieldType_TIME2, []byte{0}, []byte{0x80, 0x10, 0x83}},
+		{mysql_proto.FieldType_NEWDECIMAL, []byte{4, 2}, []byte{0x8c, 0x22}},
+		{mysql_proto.FieldType_BLOB, []byte{2}, []byte{3, 0, 'x', 'y', 'z'}},
+		// char(5)
+		{
+			mysql_proto.FieldType_STRING,
+			[]byte{byte(mysql_proto.FieldType_STRING), 5},
+			[]byte{2, 'h', 'i'},
+		},
+		// enum
+		{
+			mysql_proto.FieldType_STRING,
+			[]byte{byte(mysql_proto.FieldType_ENUM), 1},
+			[]byte{2},
+		},
+		// set
+		{
+			mysql_proto.FieldType_STRING,
+			[]byte{byte(mysql_proto.FieldType_SET), 1},
+			[]byte{5},
+		},
+		{
+			TypedArrayFieldType,
+			[]byte{byte(mysql_proto.FieldType_LONGLONG)},
+			typedArrayValueBytes(testIntArrayJsonb),
+		},
+	}
+}
+
+func (s *DriverValueSuite) parseTestRow(
+	c *C,
+	options DecodeOptions,
+	unsigned []bool) []interface{} {
+
+	columns := driverValueTestColumns()
+
+	table := &TableMapEvent{
+		nullColumnsBytes: make([]byte, (len(columns)+7)/8),
+	}
+
+	data := []byte{}
+	for _, col := range columns {
+		table.columnTypesBytes = append(
+			table.columnTypesBytes,
+			byte(col.colType))
+		table.metadataBytes = append(table.metadataBytes, col.metadata...)
+		data = append(data, col.data...)
+	}
+
+	p := &TableMapEventParser{options: options}
+	c.Assert(p.parseColumns(table), IsNil)
+	if unsigned != nil {
+		table.optionalMetadata = &TableMapOptionalMetadata{
+			UnsignedColumns: unsigned,
+		}
+	}
+	if options.DriverValues {
+		wrapDriverValueDescriptors(table)
+	}
+
+	descriptors := table.ColumnDescriptors()
+	c.Assert(descriptors, HasLen, len(columns))
+
+	values := []interface{}{}
+	for idx, cd := range descriptors {
+		c.Check(cd.Type(), Equals, table.columnDescriptors[idx].Type())
+		c.Check(cd.IndexPosition(), Equals, idx)
+
+		var val interface{}
+		var err error
+		val, data, err = cd.ParseValue(data)
+		c.Assert(err, IsNil, Commentf("column: %d", idx))
+		values = append(values, val)
+	}
+	c.Check(data, HasLen, 0)
+
+	return values
+}
+
+func (s *DriverValueSuite) TestAllColumnTypes(c *C) {
+	options := DecodeOptions{
+		DriverValues: true,
+		LazyBlobs:    true,
+		IntegerWidth: NativeIntegerWidth,
+	}
+	values := s.parseTestRow(c, options, nil)
+
+	for idx, val := range values {
+		c.Check(
+			driver.IsValue(val),
+			IsTrue,
+			Commentf("column %d: %#v (%T)", idx, val, val))
+	}
+
+	datetime := time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC)
+	c.Check(values, DeepEquals, []interface{}{
+		// Without signedness info, integers are not sign extended.
+		int64(0xff),
+		int64(0xfffe),
+		int64(0xfffffd),
+		int64(0xfffffffc),
+		"18446744073709551611",
+		float64(1.5),
+		float64(2.5),
+		nil,
+		time.Unix(1, 0).UTC(),
+		datetime,
+		time.Date(2015, 0, 0, 0, 0, 0, 0, time.UTC),
+		[]byte("abc"),
+		time.Unix(2, 0).UTC(),
+		datetime,
+		"01:02:03",
+		"12.34",
+		[]byte("xyz"),
+		append([]byte("hi"), 0, 0, 0),
+		int64(2),
+		int64(5),
+		"[1,-2,5000000000]",
+	})
+}
+
+func (s *DriverValueSuite) TestSignedColumns(c *C) {
+	unsigned := make([]bool, len(driverValueTestColumns()))
+	unsigned[1] = true // SHORT
+	unsigned[3] = true // LONG
+
+	values := s.parseTestRow(c, DecodeOptions{DriverValues: true}, unsigned)
+
+	c.Check(values[:5], DeepEquals, []interface{}{
+		int64(-1),
+		int64(0xfffe),
+		int64(-3),
+		int64(0xfffffffc),
+		int64(-5),
+	})
+}
+
+func (s *DriverValueSuite) TestDisabled(c *C) {
+	values := s.parseTestRow(c, DecodeOptions{}, nil)
+
+	c.Check(values[0], Equals, uint64(0xff))
+	c.Check(values[15], DeepEquals, mustParseDecimal("12.34"))
+
+	_, ok := values[14].(TimeValue)
+	c.Check(ok, IsTrue)
+
+	_, ok = values[20].([]interface{})
+	c.Check(ok, IsTrue)
+
+	c.Check(bytes.Equal(values[16].([]byte), []byte("xyz")), IsTrue)
+}
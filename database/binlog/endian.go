@@ -17,10 +17,12 @@ func (littleEndian) Uint8(b []byte) uint8 {
 }
 
 func (littleEndian) Uint24(b []byte) uint32 {
+	_ = b[2] // bounds check hint to compiler
 	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
 }
 
 func (littleEndian) Uint48(b []byte) uint64 {
+	_ = b[5] // bounds check hint to compiler
 	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 |
 		uint64(b[3])<<24 | uint64(b[4])<<32 | uint64(b[5])<<40
 }
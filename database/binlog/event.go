@@ -36,6 +36,10 @@ type Event interface {
 	// Flags returns the event's flags.
 	Flags() uint16
 
+	// HeaderFlags returns the event's flags as EventFlags, which provides
+	// accessors for the defined flag bits (e.g., IsArtificial).
+	HeaderFlags() EventFlags
+
 	// Bytes returns the event payload (header + data)
 	Bytes() []byte
 
@@ -58,10 +62,43 @@ type Event interface {
 	// checksum is an optional field introduced in 5.6.  The checksum
 	// algorithm used is defined in the format description event.
 	Checksum() []byte
+
+	// RawBytes returns a copy of the event's bytes as read from the source
+	// stream, or nil if the reader was not configured to retain raw bytes
+	// (see RawV4EventReaderOptions).  Unlike Bytes, the returned slice does
+	// not share memory with the decoded event; it is owned by the caller and
+	// remains valid after the event is discarded.
+	RawBytes() []byte
+
+	// ChecksumStatus returns the result of the event's checksum verification.
+	// This is ChecksumNone when the event was not verified (e.g., the reader
+	// was not configured to verify checksums, or the event has no checksum).
+	ChecksumStatus() ChecksumStatus
+
+	// setChecksumStatus is used by the reader to record the verification
+	// result.
+	setChecksumStatus(status ChecksumStatus)
+}
+
+// NextReadPosition returns the position (relative to the beginning of the
+// event's source stream) at which the event following the given event
+// begins, i.e., the position which should be checkpointed for resuming the
+// stream right after the event (see EventReader.NextPosition for the
+// reader's equivalent).  The event's length includes the checksum footer,
+// hence the result is unaffected by checksum stripping.
+//
+// NOTE: Unlike the event's NextPosition (log_pos), which is set by the
+// server which originally wrote the event (and is 0 for artificial events),
+// this is always relative to the stream the event was read from.
+func NextReadPosition(event Event) int64 {
+	return event.SourcePosition() + int64(event.EventLength())
 }
 
 const sizeOfBasicV4EventHeader = 19 // sizeof(basicV4EventHeader)
 
+// Offset of the (2-byte) flags field within the basic v4 event header.
+const flagsOffsetInV4EventHeader = 17
+
 // Fixed-length portion of the v4 event header as described in
 // http://dev.mysql.com/doc/internals/en/event-structure.html
 type basicV4EventHeader struct {
@@ -73,6 +110,25 @@ type basicV4EventHeader struct {
 	Flags        uint16
 }
 
+// This decodes the basic v4 event header from the (little endian) header
+// bytes.  Unlike readLittleEndian, this does not allocate.
+func parseBasicV4EventHeader(b []byte, header *basicV4EventHeader) error {
+	if len(b) < sizeOfBasicV4EventHeader {
+		return errors.Newf(
+			"Not enough bytes for event header (expected: %d actual: %d)",
+			sizeOfBasicV4EventHeader,
+			len(b))
+	}
+
+	header.Timestamp = LittleEndian.Uint32(b[0:4])
+	header.EventType = b[4]
+	header.ServerId = LittleEndian.Uint32(b[5:9])
+	header.EventLength = LittleEndian.Uint32(b[9:13])
+	header.NextPosition = LittleEndian.Uint32(b[13:17])
+	header.Flags = LittleEndian.Uint16(b[17:19])
+	return nil
+}
+
 // A generic v4 Event entry.  This event is event type agnostic, i.e., the data
 // (including the extra headers) is not interpreted.
 type RawV4Event struct {
@@ -85,6 +141,10 @@ type RawV4Event struct {
 	fixedLengthDataSize int
 	checksumSize        int
 	data                []byte
+
+	rawBytes []byte // only set when retaining raw bytes
+
+	checksumStatus ChecksumStatus
 }
 
 // SourceName returns the name of the event's source stream.
@@ -132,6 +192,11 @@ func (e *RawV4Event) Flags() uint16 {
 	return e.header.Flags
 }
 
+// HeaderFlags returns the event's flags as EventFlags.
+func (e *RawV4Event) HeaderFlags() EventFlags {
+	return EventFlags(e.header.Flags)
+}
+
 // Bytes returns the event payload (header + data)
 func (e *RawV4Event) Bytes() []byte {
 	return e.data
@@ -179,6 +244,21 @@ func (e *RawV4Event) Checksum() []byte {
 	return e.data[len(e.data)-e.checksumSize:]
 }
 
+// RawBytes returns a copy of the event's bytes as read from the source stream,
+// or nil if the reader was not configured to retain raw bytes.
+func (e *RawV4Event) RawBytes() []byte {
+	return e.rawBytes
+}
+
+// ChecksumStatus returns the result of the event's checksum verification.
+func (e *RawV4Event) ChecksumStatus() ChecksumStatus {
+	return e.checksumStatus
+}
+
+func (e *RawV4Event) setChecksumStatus(status ChecksumStatus) {
+	e.checksumStatus = status
+}
+
 // Set the extra headers' size.
 func (e *RawV4Event) SetExtraHeadersSize(size int) error {
 	newFixedSize := (size +
@@ -0,0 +1,136 @@
+package binlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// EventFlags is the event header's 2-byte flags field.  See
+// http://dev.mysql.com/doc/internals/en/binlog-event-flag.html
+type EventFlags uint16
+
+const (
+	// Set in the format description event while the binlog file is being
+	// written to.  The flag is cleared when the file is closed properly,
+	// hence a set flag in a non-active file indicates a server crash.
+	LogEventBinlogInUse EventFlags = 0x1
+
+	// Unused (deprecated).
+	LogEventForcedRotate EventFlags = 0x2
+
+	// The query event's statement depends on the connection's thread (e.g.,
+	// it references temporary tables).
+	LogEventThreadSpecific EventFlags = 0x4
+
+	// The query event's statement should be executed without changing the
+	// default database (i.e., the event's database is not USE'd).
+	LogEventSuppressUse EventFlags = 0x8
+
+	// Unused (deprecated).
+	LogEventUpdateTableMapVersion EventFlags = 0x10
+
+	// The event was generated by the server rather than written by a client
+	// statement (e.g., the fake rotate event at the beginning of a relay log
+	// / binlog dump stream).  Artificial events have zero log_pos, and should
+	// not be used for tracking the source's positions.
+	LogEventArtificial EventFlags = 0x20
+
+	// The event was created by the slave's io thread (i.e., it is written to
+	// the relay log, but not to the master's binlog).
+	LogEventRelayLog EventFlags = 0x40
+
+	// The event may be ignored by servers which do not recognize the event's
+	// type.
+	LogEventIgnorable EventFlags = 0x80
+
+	// The event is not filtered by replication filters.
+	LogEventNoFilter EventFlags = 0x100
+
+	// The event's event group must be applied in isolation by the
+	// multi-threaded slave.
+	LogEventMtsIsolate EventFlags = 0x200
+)
+
+var eventFlagNames = []struct {
+	flag EventFlags
+	name string
+}{
+	{LogEventBinlogInUse, "BINLOG_IN_USE"},
+	{LogEventForcedRotate, "FORCED_ROTATE"},
+	{LogEventThreadSpecific, "THREAD_SPECIFIC"},
+	{LogEventSuppressUse, "SUPPRESS_USE"},
+	{LogEventUpdateTableMapVersion, "UPDATE_TABLE_MAP_VERSION"},
+	{LogEventArtificial, "ARTIFICIAL"},
+	{LogEventRelayLog, "RELAY_LOG"},
+	{LogEventIgnorable, "IGNORABLE"},
+	{LogEventNoFilter, "NO_FILTER"},
+	{LogEventMtsIsolate, "MTS_ISOLATE"},
+}
+
+// Has returns true if all of the specified flag bits are set.
+func (f EventFlags) Has(flags EventFlags) bool {
+	return f&flags == flags
+}
+
+// IsInUse returns true if LogEventBinlogInUse is set.
+func (f EventFlags) IsInUse() bool {
+	return f.Has(LogEventBinlogInUse)
+}
+
+// IsThreadSpecific returns true if LogEventThreadSpecific is set.
+func (f EventFlags) IsThreadSpecific() bool {
+	return f.Has(LogEventThreadSpecific)
+}
+
+// IsSuppressUse returns true if LogEventSuppressUse is set.
+func (f EventFlags) IsSuppressUse() bool {
+	return f.Has(LogEventSuppressUse)
+}
+
+// IsArtificial returns true if LogEventArtificial is set.
+func (f EventFlags) IsArtificial() bool {
+	return f.Has(LogEventArtificial)
+}
+
+// IsRelayLog returns true if LogEventRelayLog is set.
+func (f EventFlags) IsRelayLog() bool {
+	return f.Has(LogEventRelayLog)
+}
+
+// IsIgnorable returns true if LogEventIgnorable is set.
+func (f EventFlags) IsIgnorable() bool {
+	return f.Has(LogEventIgnorable)
+}
+
+// IsNoFilter returns true if LogEventNoFilter is set.
+func (f EventFlags) IsNoFilter() bool {
+	return f.Has(LogEventNoFilter)
+}
+
+// IsMtsIsolate returns true if LogEventMtsIsolate is set.
+func (f EventFlags) IsMtsIsolate() bool {
+	return f.Has(LogEventMtsIsolate)
+}
+
+// String returns the set flags' names joined by '|', e.g.,
+// "BINLOG_IN_USE|ARTIFICIAL".  Undefined bits are formatted in hex.
+func (f EventFlags) String() string {
+	if f == 0 {
+		return "NONE"
+	}
+
+	names := []string{}
+	remaining := f
+	for _, entry := range eventFlagNames {
+		if f.Has(entry.flag) {
+			names = append(names, entry.name)
+			remaining &^= entry.flag
+		}
+	}
+
+	if remaining != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(remaining), 16))
+	}
+
+	return strings.Join(names, "|")
+}
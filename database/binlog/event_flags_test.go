@@ -0,0 +1,100 @@
+package binlog
+
+import (
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type EventFlagsSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&EventFlagsSuite{})
+
+func (s *EventFlagsSuite) TestAccessors(c *C) {
+	type accessor func(EventFlags) bool
+
+	accessors := []struct {
+		flag     EventFlags
+		accessor accessor
+	}{
+		{LogEventBinlogInUse, EventFlags.IsInUse},
+		{LogEventThreadSpecific, EventFlags.IsThreadSpecific},
+		{LogEventSuppressUse, EventFlags.IsSuppressUse},
+		{LogEventArtificial, EventFlags.IsArtificial},
+		{LogEventRelayLog, EventFlags.IsRelayLog},
+		{LogEventIgnorable, EventFlags.IsIgnorable},
+		{LogEventNoFilter, EventFlags.IsNoFilter},
+		{LogEventMtsIsolate, EventFlags.IsMtsIsolate},
+	}
+
+	for _, a := range accessors {
+		c.Check(a.accessor(EventFlags(0)), IsFalse)
+		c.Check(a.accessor(a.flag), IsTrue)
+		c.Check(a.accessor(EventFlags(0xffff)), IsTrue)
+		c.Check(a.accessor(EventFlags(0xffff)&^a.flag), IsFalse)
+
+		for _, other := range accessors {
+			if other.flag != a.flag {
+				c.Check(a.accessor(other.flag), IsFalse)
+			}
+		}
+	}
+
+	flags := LogEventBinlogInUse | LogEventArtificial
+	c.Check(flags.Has(LogEventArtificial), IsTrue)
+	c.Check(flags.Has(LogEventBinlogInUse|LogEventArtificial), IsTrue)
+	c.Check(flags.Has(LogEventArtificial|LogEventRelayLog), IsFalse)
+}
+
+func (s *EventFlagsSuite) TestString(c *C) {
+	c.Check(EventFlags(0).String(), Equals, "NONE")
+	c.Check(LogEventArtificial.String(), Equals, "ARTIFICIAL")
+	c.Check(
+		(LogEventBinlogInUse | LogEventIgnorable).String(),
+		Equals,
+		"BINLOG_IN_USE|IGNORABLE")
+	c.Check(
+		(LogEventRelayLog | EventFlags(0x8400)).String(),
+		Equals,
+		"RELAY_LOG|0x8400")
+}
+
+func (s *EventFlagsSuite) TestDecodeFlags(c *C) {
+	// A fake rotate event, as generated by the master at the beginning of a
+	// binlog dump stream.
+	s.WriteEvent(
+		mysql_proto.LogEventType_ROTATE_EVENT,
+		uint16(0x20),
+		[]byte{
+			// new log position
+			4, 0, 0, 0, 0, 0, 0, 0,
+			// new log name
+			'b', 'i', 'n', '.', '0', '0', '0', '0', '0', '1'})
+
+	// An xid event with multiple flag bits set.
+	s.WriteEvent(
+		mysql_proto.LogEventType_XID_EVENT,
+		uint16(0x1|0x80),
+		[]byte{1, 0, 0, 0, 0, 0, 0, 0})
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	rotate, ok := event.(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rotate.Flags(), Equals, uint16(0x20))
+	c.Check(rotate.HeaderFlags(), Equals, LogEventArtificial)
+	c.Check(rotate.HeaderFlags().IsArtificial(), IsTrue)
+	c.Check(rotate.HeaderFlags().IsInUse(), IsFalse)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	xid, ok := event.(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(xid.HeaderFlags().IsArtificial(), IsFalse)
+	c.Check(xid.HeaderFlags().IsInUse(), IsTrue)
+	c.Check(xid.HeaderFlags().IsIgnorable(), IsTrue)
+	c.Check(xid.HeaderFlags().IsRelayLog(), IsFalse)
+}
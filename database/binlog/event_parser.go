@@ -90,6 +90,12 @@ type v4EventParserMap struct {
 // NewV4EventParserMap returns an initialize V4EventParserMap with all handled
 // event types' parsers registered.
 func NewV4EventParserMap() V4EventParserMap {
+	return NewV4EventParserMapWithOptions(DecodeOptions{})
+}
+
+// Same as NewV4EventParserMap, but the registered parsers decode column values
+// according to the provided options.
+func NewV4EventParserMapWithOptions(options DecodeOptions) V4EventParserMap {
 	m := &v4EventParserMap{
 		extraHeadersSize: nonFDEExtraHeadersSize,
 		checksumSize:     0,
@@ -98,21 +104,40 @@ func NewV4EventParserMap() V4EventParserMap {
 
 	// TODO(patrick): implement parsers
 	m.set(&FormatDescriptionEventParser{})
-	m.set(&QueryEventParser{})
+	m.set(&QueryEventParser{session: options.Session})
 	m.set(&RotateEventParser{})
-	m.set(&TableMapEventParser{})
+	m.set(&TableMapEventParser{options: options})
 	m.set(&XidEventParser{})
 	m.set(&RowsQueryEventParser{})
 	m.set(&GtidLogEventParser{})
 	m.set(&PreviousGtidsLogEventParser{})
 
-	m.set(newWriteRowsEventV1Parser())
-	m.set(newWriteRowsEventV2Parser())
-	m.set(newUpdateRowsEventV1Parser())
-	m.set(newUpdateRowsEventV2Parser())
-	m.set(newDeleteRowsEventV1Parser())
-	m.set(newDeleteRowsEventV2Parser())
+	for _, p := range []V4EventParser{
+		newWriteRowsEventV1Parser(),
+		newWriteRowsEventV2Parser(),
+		newUpdateRowsEventV1Parser(),
+		newUpdateRowsEventV2Parser(),
+		newDeleteRowsEventV1Parser(),
+		newDeleteRowsEventV2Parser(),
+	} {
+		p.(rowAllocatorSetter).SetRowAllocator(options.RowAllocator)
+		p.(decodeMetricsSetter).SetDecodeMetrics(options.Metrics)
+		p.(lossyConversionHandlerSetter).SetLossyConversionHandler(
+			options.OnLossyConversion)
+		p.(rawValuesSetter).SetRawValues(options.RawValues)
+		m.set(p)
+	}
 	m.set(newStopEventParser())
+	m.set(newAppendBlockEventParser())
+	m.set(newBeginLoadQueryEventParser())
+	m.set(&ExecuteLoadQueryEventParser{})
+	m.set(newLoadEventParser())
+	m.set(newNewLoadEventParser())
+	m.set(newCreateFileEventParser())
+	m.set(newExecuteLoadEventParser())
+	m.set(newDeleteFileEventParser())
+	m.set(&MariadbGtidLogEventParser{})
+	m.set(&MariadbAnnotateRowsEventParser{})
 
 	m.numSupportedEventTypes = len(mysql_proto.LogEventType_Type_name)
 	return m
@@ -155,6 +180,14 @@ func (m *v4EventParserMap) SetNumSupportedEventTypes(num int) {
 	m.numSupportedEventTypes = num
 }
 
+type rowAllocatorSetter interface {
+	SetRowAllocator(allocator RowAllocator)
+}
+
+type decodeMetricsSetter interface {
+	SetDecodeMetrics(metrics *DecodeMetrics)
+}
+
 type hasNoTableContext struct {
 }
 
@@ -24,6 +24,15 @@ type EventReader interface {
 	// an error.
 	Close() error
 
+	// NextPosition returns the position (relative to the beginning of the
+	// most recently returned event's source stream) at which the next event
+	// begins, i.e., the position which should be checkpointed for resuming
+	// the stream right after the most recently returned event.  The
+	// position accounts for the events' checksum footers, even when the
+	// checksums are stripped from the events' data.  This returns 0 before
+	// the stream is read.
+	NextPosition() int64
+
 	// peekHeaderBytes returns up to sizeOfBasicV4EventHeader number of bytes
 	// from the event header.  This is used for checking binlog magic marker
 	// and format version at the beginning of the event stream.
@@ -40,6 +49,19 @@ type EventReader interface {
 	nextEventEndPosition() int64
 }
 
+// ResettableEventReader is an EventReader which can be reused for reading
+// another event stream.  This avoids reallocating the reader's buffers and
+// state when processing many log files sequentially (e.g., in batch jobs).
+type ResettableEventReader interface {
+	EventReader
+
+	// Reset discards all per-stream state (including any partially read
+	// event), and switches the reader to the src event stream.  The reader
+	// is reopened if it was closed.  Reusable resources (e.g., buffers) are
+	// retained.
+	Reset(src io.Reader, srcName string)
+}
+
 // When tailing logs on a mysql box, there's a potential race conditions where
 // the rotate event is written, but a new log file is not created yet.  It's
 // probably safe to retry when this occurs (before quitting).
@@ -50,7 +72,7 @@ type FailedToOpenFileError struct {
 
 // This returns true if the error returned by the event parser is retryable.
 func IsRetryableError(err error) bool {
-	if err == io.EOF {
+	if err == io.EOF || err == ErrIncompleteEvent {
 		return true
 	}
 	if _, ok := err.(*FailedToOpenFileError); ok {
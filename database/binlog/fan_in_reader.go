@@ -0,0 +1,261 @@
+package binlog
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// MergeStrategy determines the order in which FanInReader returns events
+// from its sources.
+type MergeStrategy int
+
+const (
+	// Events are returned in (event header) timestamp order across all
+	// sources.  Ties are broken by source index.  Events with zero
+	// timestamps (e.g., artificial rotate events) are returned as soon as
+	// they are read.
+	MergeByTimestamp MergeStrategy = iota
+
+	// Events are returned from each source in turn.  Sources which have no
+	// events available are skipped.
+	MergeRoundRobin
+)
+
+// TimestampedEvent is an event returned by FanInReader, annotated with the
+// index of the source it was read from.
+type TimestampedEvent struct {
+	Event
+
+	// The index (into the reader list passed to NewFanInReader) of the
+	// event's source.
+	Source int
+
+	// The event's header timestamp.
+	Time time.Time
+}
+
+// SourceInfo describes the progress of a single FanInReader source.
+type SourceInfo struct {
+	// The index (into the reader list passed to NewFanInReader) of the
+	// source.
+	Index int
+
+	// The source name of the most recently read event.  This is empty when
+	// no event has been read from the source.
+	Name string
+
+	// The timestamp of the most recently returned (non-zero timestamp) event
+	// from the source.  This is the zero time when no such event has been
+	// returned.
+	LastEventTime time.Time
+
+	// How far the source is behind the most advanced source, i.e., the
+	// difference between the newest event timestamp seen across all sources
+	// and LastEventTime.  This is zero when LastEventTime is unset.
+	Lag time.Duration
+
+	// The number of events read from the source, but not yet returned.
+	Buffered int
+
+	// The most recent error returned by the source (nil if the most recent
+	// read succeeded).
+	LastError error
+}
+
+type fanInSource struct {
+	reader EventReader
+
+	name      string
+	head      *TimestampedEvent // at most one event is buffered per source
+	last      time.Time
+	lastError error
+}
+
+// FanInReader merges the event streams of multiple EventReaders (e.g., the
+// binlogs of multiple primaries in a multi-master setup) into a single
+// stream.  At most one event per source is buffered.
+//
+// When merging by timestamp, an event is only returned once every source
+// has an event buffered (otherwise an idle source's next event could have
+// an earlier timestamp).  Hence, a source which returns a (retryable) error
+// stalls the merged stream until the source makes progress.
+//
+// Note that binlog timestamps have second granularity, and are assigned by
+// each source's local clock; the merged order is only as accurate as the
+// sources' clocks are synchronized.
+//
+// FanInReader is not threadsafe.
+type FanInReader struct {
+	strategy MergeStrategy
+	sources  []*fanInSource
+
+	next   int       // the next source to read from (round robin only)
+	newest time.Time // the newest timestamp seen across all sources
+}
+
+// This returns a FanInReader which merges events from the readers according
+// to the strategy.
+func NewFanInReader(
+	readers []EventReader,
+	strategy MergeStrategy) (*FanInReader, error) {
+
+	if len(readers) == 0 {
+		return nil, errors.New("No source readers")
+	}
+
+	if strategy != MergeByTimestamp && strategy != MergeRoundRobin {
+		return nil, errors.Newf("Invalid merge strategy: %d", strategy)
+	}
+
+	sources := make([]*fanInSource, 0, len(readers))
+	for _, reader := range readers {
+		sources = append(sources, &fanInSource{reader: reader})
+	}
+
+	return &FanInReader{
+		strategy: strategy,
+		sources:  sources,
+	}, nil
+}
+
+// Next returns the next event across all sources.  When a source returns an
+// error, the error is returned as is (events buffered from other sources are
+// retained); it is safe to call Next again on retryable errors.
+func (r *FanInReader) Next() (*TimestampedEvent, error) {
+	if r.strategy == MergeRoundRobin {
+		return r.nextRoundRobin()
+	}
+	return r.nextByTimestamp()
+}
+
+// Sources returns the progress of each source, ordered by source index.
+func (r *FanInReader) Sources() []SourceInfo {
+	infos := make([]SourceInfo, 0, len(r.sources))
+	for idx, src := range r.sources {
+		info := SourceInfo{
+			Index:         idx,
+			Name:          src.name,
+			LastEventTime: src.last,
+			LastError:     src.lastError,
+		}
+
+		if src.head != nil {
+			info.Buffered = 1
+		}
+
+		if !src.last.IsZero() {
+			info.Lag = r.newest.Sub(src.last)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Close closes all source readers.  The first close error (if any) is
+// returned.
+func (r *FanInReader) Close() error {
+	var firstErr error
+	for _, src := range r.sources {
+		err := src.reader.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// This ensures the source has an event buffered.
+func (r *FanInReader) fill(idx int) error {
+	src := r.sources[idx]
+	if src.head != nil {
+		return nil
+	}
+
+	event, err := src.reader.NextEvent()
+	src.lastError = err
+	if err != nil {
+		return err
+	}
+
+	if event == nil {
+		src.lastError = errors.Newf("Source %d returned a nil event", idx)
+		return src.lastError
+	}
+
+	src.name = event.SourceName()
+	src.head = &TimestampedEvent{
+		Event:  event,
+		Source: idx,
+		Time:   time.Unix(int64(event.Timestamp()), 0),
+	}
+
+	if event.Timestamp() != 0 && src.head.Time.After(r.newest) {
+		r.newest = src.head.Time
+	}
+
+	return nil
+}
+
+// This removes and returns the source's buffered event.
+func (r *FanInReader) pop(idx int) *TimestampedEvent {
+	src := r.sources[idx]
+
+	event := src.head
+	src.head = nil
+
+	if event.Timestamp() != 0 {
+		src.last = event.Time
+	}
+
+	return event
+}
+
+func (r *FanInReader) nextByTimestamp() (*TimestampedEvent, error) {
+	for idx, src := range r.sources {
+		err := r.fill(idx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Zero timestamp events are not subjected to ordering.
+		if src.head.Timestamp() == 0 {
+			return r.pop(idx), nil
+		}
+	}
+
+	min := 0
+	for idx, src := range r.sources {
+		if src.head.Timestamp() < r.sources[min].head.Timestamp() {
+			min = idx
+		}
+	}
+
+	return r.pop(min), nil
+}
+
+func (r *FanInReader) nextRoundRobin() (*TimestampedEvent, error) {
+	var firstErr error
+	for i := 0; i < len(r.sources); i++ {
+		idx := r.next
+		r.next = (r.next + 1) % len(r.sources)
+
+		err := r.fill(idx)
+		if err == nil {
+			return r.pop(idx), nil
+		}
+
+		if !IsRetryableError(err) {
+			return nil, err
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, firstErr
+}
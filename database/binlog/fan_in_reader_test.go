@@ -0,0 +1,176 @@
+package binlog
+
+import (
+	"io"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type FanInReaderSuite struct {
+}
+
+var _ = Suite(&FanInReaderSuite{})
+
+func (s *FanInReaderSuite) event(source string, timestamp uint32) Event {
+	return &RawV4Event{
+		sourceName: source,
+		header:     basicV4EventHeader{Timestamp: timestamp},
+	}
+}
+
+func (s *FanInReaderSuite) reader(
+	source string,
+	timestamps ...uint32) *sliceEventReader {
+
+	reader := &sliceEventReader{}
+	for _, ts := range timestamps {
+		reader.events = append(reader.events, s.event(source, ts))
+	}
+	return reader
+}
+
+type fanInResult struct {
+	source    int
+	timestamp uint32
+}
+
+func (s *FanInReaderSuite) readAll(c *C, r *FanInReader) []fanInResult {
+	results := []fanInResult{}
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return results
+		}
+		c.Assert(err, IsNil)
+		c.Assert(
+			event.Source,
+			Equals,
+			int(event.SourceName()[0]-'a'))
+		c.Assert(
+			event.Time,
+			Equals,
+			time.Unix(int64(event.Timestamp()), 0))
+
+		results = append(
+			results,
+			fanInResult{event.Source, event.Timestamp()})
+	}
+}
+
+func (s *FanInReaderSuite) TestInvalidArguments(c *C) {
+	_, err := NewFanInReader(nil, MergeByTimestamp)
+	c.Check(err, NotNil)
+
+	_, err = NewFanInReader(
+		[]EventReader{s.reader("a")},
+		MergeStrategy(42))
+	c.Check(err, NotNil)
+}
+
+func (s *FanInReaderSuite) TestMergeByTimestamp(c *C) {
+	r, err := NewFanInReader(
+		[]EventReader{
+			s.reader("a", 0, 10, 12, 12, 20),
+			s.reader("b", 11, 12, 13),
+			s.reader("c", 5, 0, 30),
+		},
+		MergeByTimestamp)
+	c.Assert(err, IsNil)
+
+	// The merge stops once any source is exhausted since the ordering is
+	// unknown after that point.
+	c.Check(
+		s.readAll(c, r),
+		DeepEquals,
+		[]fanInResult{
+			{0, 0},
+			{2, 5},
+			{2, 0},
+			{0, 10},
+			{1, 11},
+			{0, 12},
+			{0, 12},
+			{1, 12},
+			{1, 13},
+		})
+
+	sources := r.Sources()
+	c.Assert(sources, HasLen, 3)
+
+	c.Check(sources[0].Index, Equals, 0)
+	c.Check(sources[0].Name, Equals, "a")
+	c.Check(sources[0].LastEventTime, Equals, time.Unix(12, 0))
+	c.Check(sources[0].Lag, Equals, 18*time.Second)
+	c.Check(sources[0].Buffered, Equals, 1)
+	c.Check(sources[0].LastError, IsNil)
+
+	c.Check(sources[1].Index, Equals, 1)
+	c.Check(sources[1].Name, Equals, "b")
+	c.Check(sources[1].LastEventTime, Equals, time.Unix(13, 0))
+	c.Check(sources[1].Lag, Equals, 17*time.Second)
+	c.Check(sources[1].Buffered, Equals, 0)
+	c.Check(sources[1].LastError, Equals, io.EOF)
+
+	c.Check(sources[2].Index, Equals, 2)
+	c.Check(sources[2].Name, Equals, "c")
+	c.Check(sources[2].LastEventTime, Equals, time.Unix(5, 0))
+	c.Check(sources[2].Lag, Equals, 25*time.Second)
+	c.Check(sources[2].Buffered, Equals, 1)
+	c.Check(sources[2].LastError, IsNil)
+}
+
+func (s *FanInReaderSuite) TestMergeByTimestampRetry(c *C) {
+	a := s.reader("a", 1, 3)
+	b := s.reader("b")
+
+	r, err := NewFanInReader([]EventReader{a, b}, MergeByTimestamp)
+	c.Assert(err, IsNil)
+
+	// The idle source stalls the merged stream.
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+	c.Check(r.Sources()[0].Buffered, Equals, 1)
+
+	b.events = append(b.events, s.event("b", 2))
+
+	c.Check(
+		s.readAll(c, r),
+		DeepEquals,
+		[]fanInResult{
+			{0, 1},
+			{1, 2},
+		})
+}
+
+func (s *FanInReaderSuite) TestMergeRoundRobin(c *C) {
+	r, err := NewFanInReader(
+		[]EventReader{
+			s.reader("a", 3, 2, 1),
+			s.reader("b"),
+			s.reader("c", 9, 8),
+		},
+		MergeRoundRobin)
+	c.Assert(err, IsNil)
+
+	c.Check(
+		s.readAll(c, r),
+		DeepEquals,
+		[]fanInResult{
+			{0, 3},
+			{2, 9},
+			{0, 2},
+			{2, 8},
+			{0, 1},
+		})
+
+	sources := r.Sources()
+	c.Assert(sources, HasLen, 3)
+	c.Check(sources[0].Lag, Equals, 8*time.Second)
+	c.Check(sources[1].Name, Equals, "")
+	c.Check(sources[1].LastEventTime.IsZero(), IsTrue)
+	c.Check(sources[1].Lag, Equals, time.Duration(0))
+	c.Check(sources[2].Lag, Equals, time.Second)
+}
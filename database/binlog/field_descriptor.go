@@ -1,6 +1,8 @@
 package binlog
 
 import (
+	"time"
+
 	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
 )
 
@@ -11,6 +13,112 @@ const (
 	NotNullable NullableColumn = false
 )
 
+// DecodeOptions controls how column values are decoded by the field
+// descriptors created by the table map event parser.  The zero value
+// corresponds to the default decoding behavior.
+type DecodeOptions struct {
+	// DATETIME / DATETIME2 values are stored as wall-clock time without
+	// timezone.  When DateTimeLocation is set, the wall-clock time is
+	// interpreted in this location and converted to UTC.  When nil, the
+	// wall-clock time is returned as-is in UTC.
+	DateTimeLocation *time.Location
+
+	// The width of decoded integer values.  Defaults to UniformIntegerWidth.
+	IntegerWidth IntegerWidth
+
+	// By default, decoding a DATETIME2 value with an out of range component
+	// (e.g., a year after 9999, or minute 60) returns an error.  When
+	// LenientDateTime is set, such values are decoded as nil (i.e., NULL)
+	// instead.
+	LenientDateTime bool
+
+	// When set, BLOB / TEXT values are decoded as *LazyBlob (which are only
+	// copied out of the event's data on demand) instead of []byte.  NOTE:
+	// eagerly decoded []byte values also reference the event's data without
+	// copying; LazyBlob only saves copies for consumers which would
+	// otherwise copy every value they may retain.
+	LazyBlobs bool
+
+	// When set, column values are decoded as database/sql/driver.Value
+	// compatible types (see ToDriverValue), e.g., for replaying decoded rows
+	// through database/sql.  Integer columns are sign extended when the
+	// table map event's optional metadata includes the columns' signedness.
+	DriverValues bool
+
+	// The allocator used by the rows parsers for decoded rows.  When nil,
+	// DefaultRowAllocator is used.
+	RowAllocator RowAllocator
+
+	// When set, the rows parsers record decode metrics into Metrics: the
+	// time spent decoding column values (per field type) when value timing
+	// is enabled (see DecodeMetrics.EnableValueTiming), and per-table
+	// throughput when table tracking is enabled (see
+	// DecodeMetrics.EnableTableMetrics).  Both are disabled by default;
+	// value timing adds a couple of clock reads per decoded value.
+	Metrics *DecodeMetrics
+
+	// When set, the rows parsers invoke OnLossyConversion for each value
+	// which was not decoded losslessly (e.g., invalid DATETIME2 values
+	// decoded as nil when LenientDateTime is set), along with the value's
+	// event / table / row / column context.  This is useful for deciding
+	// whether or not the chosen decode options are appropriate.
+	OnLossyConversion LossyConversionHandler
+
+	// When set, the query event parser updates Session from the parsed query
+	// events (see SessionState.Update), and DATETIME / DATETIME2 columns are
+	// interpreted in the session's time zone at the time the table map event
+	// is parsed.  The session's time zone takes precedence over
+	// DateTimeLocation, which is used while the session's time zone is
+	// unknown (or SYSTEM).  Similarly, zero dates are interpreted according
+	// to the session's sql_mode (see ZeroDates).
+	Session *SessionState
+
+	// How zero dates (e.g., '0000-00-00 00:00:00') in DATETIME / DATETIME2 /
+	// TIMESTAMP / TIMESTAMP2 columns are decoded.  Defaults to
+	// ZeroDateAsTime.  When Session's sql_mode is known at the time the table
+	// map event is parsed, the sql_mode takes precedence: zero dates are
+	// decoded as ZeroDateAsString when NO_ZERO_DATE is disabled.  When
+	// NO_ZERO_DATE is enabled (as in mysql 5.7's default sql_mode), zero
+	// dates are decoded as ZeroDateInvalid, leniently (i.e., as NULL, and
+	// reported to OnLossyConversion) regardless of LenientDateTime, since
+	// rows written before NO_ZERO_DATE was enabled may still contain zero
+	// dates (e.g., in update before images).  NOTE: when ZeroDates is
+	// explicitly set to ZeroDateInvalid (without Session), zero dates fail
+	// the rows event's parsing unless LenientDateTime is set.
+	ZeroDates ZeroDateMode
+
+	// When set, the rows parsers also retain each decoded value's packed
+	// bytes (see RawValue), which are returned by the rows events'
+	// InsertedRawRows / DeletedRawRows / UpdatedRawRows.  This is intended
+	// for debugging, e.g., when a decoded value looks wrong.
+	RawValues bool
+
+	// The tables' generated columns.  mysql does not log which columns are
+	// generated, hence this must be derived from the tables' schemas.  The
+	// table map event parser marks the generated columns (see
+	// TableMapEvent.GeneratedColumnTypes).  The marks are informational
+	// only: the rows events' used column bitmaps determine which columns'
+	// values are in the row data, and mysql does log virtual generated
+	// columns' values (e.g., in FULL row images), hence generated columns
+	// are decoded like regular columns.
+	GeneratedColumns GeneratedColumns
+}
+
+// IntegerWidth controls the go type of decoded integer values.  NOTE: the
+// values' sign is uninterpreted in either case.
+type IntegerWidth int
+
+const (
+	// Integer values are always returned as uint64, regardless of the
+	// column's width.
+	UniformIntegerWidth IntegerWidth = iota
+
+	// Integer values are returned in the column's native width, i.e., uint8
+	// for TINYINT, uint16 for SMALLINT, uint32 for MEDIUMINT and INT, and
+	// uint64 for BIGINT.
+	NativeIntegerWidth
+)
+
 // FieldDescriptor defines the common interface for interpreting all mysql
 // field types.
 type FieldDescriptor interface {
@@ -21,9 +129,13 @@ type FieldDescriptor interface {
 	IsNullable() bool
 
 	// ParseValue extracts a single mysql value from the data array.  The value
-	// must an uint64 for int fields (NOTE that sign is uninterpreted), double
-	// for floating point fields, []byte for string fields, and time.Time
-	// (in UTC) for temporal fields.
+	// must an uint64 (or a native width unsigned integer, see IntegerWidth)
+	// for int fields (NOTE that sign is uninterpreted), double
+	// for floating point fields, Decimal for (new) decimal fields, uint64
+	// bitmask for set fields, uint64 index for enum fields, []byte for
+	// string fields, TimeValue for time2 fields, []interface{} for typed
+	// array fields, and time.Time (in UTC)
+	// for other temporal fields.
 	ParseValue(data []byte) (value interface{}, remaining []byte, err error)
 }
 
@@ -0,0 +1,178 @@
+package binlog
+
+import (
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const defaultFollowFilePollInterval = 100 * time.Millisecond
+
+// An EventReader wrapper which also closes the underlying log file.
+type closingEventReader struct {
+	EventReader
+	closer io.Closer
+}
+
+func (r *closingEventReader) Close() error {
+	err := r.EventReader.Close()
+	closeErr := r.closer.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+type followFileV4EventReader struct {
+	pollInterval time.Duration
+
+	mutex  sync.Mutex
+	stream EventReader
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// This returns an EventReader which tails a (bin / relay) log stream composed
+// of multiple log files which are still being written.  Unlike the log stream
+// reader, NextEvent does not return on EOF (or on a partially written
+// trailing event); instead, it polls the current file every pollInterval
+// until the rest of the event is appended.  Similarly, when the next log file
+// (as specified by the rotate event) does not exist yet, the reader polls
+// until the file is created.  NextEvent only returns non-retryable errors (see
+// IsRetryableError), or an error after the reader is closed.  Close may be
+// called from a different goroutine to unblock NextEvent.
+func NewFollowFileV4EventReader(
+	logDirectory string,
+	logPrefix string,
+	startingLogFileNum uint,
+	isRelayLog bool,
+	pollInterval time.Duration,
+	logger Logger) EventReader {
+
+	openLogReader := func(
+		dir string,
+		file string,
+		parsers V4EventParserMap) (
+		EventReader,
+		error) {
+
+		filePath := path.Join(dir, file)
+		logFile, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		reader := NewLogFileV4EventReaderWithOptions(
+			logFile,
+			filePath,
+			parsers,
+			logger,
+			LogFileV4EventReaderOptions{
+				RawV4EventReaderOptions: RawV4EventReaderOptions{
+					ReportIncompleteEvent: true,
+				},
+			})
+
+		return &closingEventReader{
+			EventReader: reader,
+			closer:      logFile,
+		}, nil
+	}
+
+	if pollInterval <= 0 {
+		pollInterval = defaultFollowFilePollInterval
+	}
+
+	return &followFileV4EventReader{
+		pollInterval: pollInterval,
+		stream: NewLogStreamV4EventReaderWithLogFileReaderCreator(
+			logDirectory,
+			logPrefix,
+			startingLogFileNum,
+			isRelayLog,
+			logger,
+			openLogReader),
+		closed: make(chan struct{}),
+	}
+}
+
+func (r *followFileV4EventReader) peekHeaderBytes(numBytes int) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stream.peekHeaderBytes(numBytes)
+}
+
+func (r *followFileV4EventReader) consumeHeaderBytes(numBytes int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stream.consumeHeaderBytes(numBytes)
+}
+
+func (r *followFileV4EventReader) nextEventEndPosition() int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stream.nextEventEndPosition()
+}
+
+func (r *followFileV4EventReader) NextPosition() int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stream.NextPosition()
+}
+
+func (r *followFileV4EventReader) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *followFileV4EventReader) nextStreamEvent() (Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.isClosed() {
+		return nil, errors.New("reader is closed")
+	}
+
+	return r.stream.NextEvent()
+}
+
+func (r *followFileV4EventReader) NextEvent() (Event, error) {
+	for {
+		event, err := r.nextStreamEvent()
+		if err == nil || event != nil || !IsRetryableError(err) {
+			return event, err
+		}
+
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-timer.C:
+		case <-r.closed:
+			timer.Stop()
+			return nil, errors.New("reader is closed")
+		}
+	}
+}
+
+func (r *followFileV4EventReader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.stream.Close()
+}
@@ -0,0 +1,152 @@
+package binlog
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type FollowFileV4EventReaderSuite struct {
+	dir string
+}
+
+var _ = Suite(&FollowFileV4EventReaderSuite{})
+
+func (s *FollowFileV4EventReaderSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "binlog_follow_test")
+	c.Assert(err, IsNil)
+}
+
+func (s *FollowFileV4EventReaderSuite) TearDownTest(c *C) {
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *FollowFileV4EventReaderSuite) Append(
+	c *C,
+	num int,
+	data []byte) {
+
+	f, err := os.OpenFile(
+		path.Join(s.dir, logName(testBinPrefix, num)),
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0644)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	_, err = f.Write(data)
+	c.Assert(err, IsNil)
+}
+
+// Appends data to the log file after a short delay.
+func (s *FollowFileV4EventReaderSuite) AppendLater(
+	c *C,
+	num int,
+	data []byte) {
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.Append(c, num, data)
+	}()
+}
+
+func (s *FollowFileV4EventReaderSuite) NewReader() EventReader {
+	return NewFollowFileV4EventReader(
+		s.dir,
+		testBinPrefix,
+		0,
+		false,
+		time.Millisecond,
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+}
+
+func (s *FollowFileV4EventReaderSuite) TestAppendAndRotate(c *C) {
+	f0 := NewMockLogFile()
+	f0.WriteLogFileMagic()
+	f0.WriteFDE()
+	start := len(f0.logBuffer)
+	f0.WriteXid(0)
+	xid0 := f0.logBuffer[start:]
+
+	start = len(f0.logBuffer)
+	f0.WriteRotate(testBinPrefix, 1)
+	rotate0 := f0.logBuffer[start:]
+
+	f1 := NewMockLogFile()
+	f1.WriteLogFileMagic()
+	f1.WriteFDE()
+	f1.WriteXid(1)
+
+	// Only part of the magic marker is written.
+	s.Append(c, 0, f0.logBuffer[:2])
+
+	reader := s.NewReader()
+	defer reader.Close()
+
+	Next := func() Event {
+		e, err := reader.NextEvent()
+		c.Assert(err, IsNil)
+		c.Assert(e, NotNil)
+		return e
+	}
+
+	s.AppendLater(c, 0, f0.logBuffer[2:start-len(xid0)])
+
+	e := Next()
+	_, ok := e.(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+
+	// Partially written event.
+	s.Append(c, 0, xid0[:5])
+	s.AppendLater(c, 0, xid0[5:])
+
+	e = Next()
+	x, ok := e.(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(0))
+
+	s.AppendLater(c, 0, rotate0)
+
+	e = Next()
+	r, ok := e.(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(r.NewLogName()), Equals, logName(testBinPrefix, 1))
+
+	// The next log file is created after the rotate event is written.
+	s.AppendLater(c, 1, f1.logBuffer)
+
+	e = Next()
+	_, ok = e.(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+
+	e = Next()
+	x, ok = e.(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(1))
+}
+
+func (s *FollowFileV4EventReaderSuite) TestClose(c *C) {
+	reader := s.NewReader()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = reader.Close()
+	}()
+
+	// The log file does not exist; NextEvent blocks until the reader is
+	// closed.
+	e, err := reader.NextEvent()
+	c.Assert(e, IsNil)
+	c.Assert(err, NotNil)
+	c.Check(IsRetryableError(err), IsFalse)
+}
@@ -50,6 +50,11 @@ func (e *FormatDescriptionEvent) ServerVersion() []byte {
 	return e.serverVersion
 }
 
+// IsMariadb returns true if the events were emitted by a MariaDB server.
+func (e *FormatDescriptionEvent) IsMariadb() bool {
+	return IsMariadbServerVersion(e.serverVersion)
+}
+
 // CreatedTimestamp returns the fde's creation timestamp.  NOTE: mysql log
 // writer may leave the timestamp undefined.
 func (e *FormatDescriptionEvent) CreatedTimestamp() uint32 {
@@ -154,6 +159,11 @@ func (p *FormatDescriptionEventParser) Parse(raw *RawV4Event) (Event, error) {
 	if len(data) == 27 { // mysql 5.5(.37)
 		numEvents = 28
 		hasChecksum = false
+	} else if IsMariadbServerVersion(serverVersion) && len(data) > 40 {
+		// MariaDB 10.0+.  MariaDB specific event types start at 160 (i.e.,
+		// outside of mysql_proto.LogEventType), hence all post header sizes
+		// (i.e., all but the checksum algorithm and checksum) are retained.
+		numEvents = len(data) - 5 + 1
 	} else if len(data) == 40 { // mysql 5.6(.17)
 
 		// This is a relay log where the master is 5.5 and slave is 5.6
@@ -0,0 +1,77 @@
+package binlog
+
+import (
+	"strconv"
+)
+
+// GeneratedColumnType describes whether (and how) a column is generated.
+type GeneratedColumnType int
+
+const (
+	// A regular (non-generated) column.
+	NotGenerated GeneratedColumnType = iota
+
+	// A virtual generated column.  Virtual column values are computed on
+	// read, i.e., they are not stored.
+	VirtualGenerated
+
+	// A stored generated column.  Stored column values are computed on
+	// write, and are stored like regular column values.
+	StoredGenerated
+)
+
+func (t GeneratedColumnType) String() string {
+	switch t {
+	case NotGenerated:
+		return "NOT GENERATED"
+	case VirtualGenerated:
+		return "VIRTUAL"
+	case StoredGenerated:
+		return "STORED"
+	}
+	return "UNKNOWN(" + strconv.Itoa(int(t)) + ")"
+}
+
+// GeneratedColumns maps "<database>.<table>" to the table's generated
+// columns' types, keyed by column name.  Columns are named col_<index> when
+// the table map event does not include the column names.  Columns without an
+// entry are not generated.
+type GeneratedColumns map[string]map[string]GeneratedColumnType
+
+// This marks the table's generated columns, as specified by the decode
+// options.  Entries for unknown columns are ignored (the table's schema may
+// have changed).
+func (p *TableMapEventParser) markGeneratedColumns(t *TableMapEvent) {
+	table := string(t.databaseName) + "." + string(t.tableName)
+	columns := p.options.GeneratedColumns[table]
+	if len(columns) == 0 {
+		return
+	}
+
+	types := make([]GeneratedColumnType, t.NumColumns())
+	marked := false
+	for idx := range types {
+		if genType, ok := columns[columnName(t, idx)]; ok {
+			types[idx] = genType
+			marked = true
+		}
+	}
+
+	if marked {
+		t.generatedColumns = types
+	}
+}
+
+// GeneratedColumnTypes returns the columns' generated column types.  This
+// returns nil when none of the table's columns are marked as generated (see
+// DecodeOptions.GeneratedColumns).
+func (e *TableMapEvent) GeneratedColumnTypes() []GeneratedColumnType {
+	return e.generatedColumns
+}
+
+// IsVirtualColumn returns true if the column (at index position idx) is a
+// virtual generated column.
+func (e *TableMapEvent) IsVirtualColumn(idx int) bool {
+	return idx < len(e.generatedColumns) &&
+		e.generatedColumns[idx] == VirtualGenerated
+}
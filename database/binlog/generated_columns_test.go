@@ -0,0 +1,235 @@
+package binlog
+
+import (
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type GeneratedColumnsSuite struct {
+}
+
+var _ = Suite(&GeneratedColumnsSuite{})
+
+func (s *GeneratedColumnsSuite) parse(
+	c *C,
+	parsers V4EventParserMap,
+	eventType mysql_proto.LogEventType_Type,
+	data []byte) Event {
+
+	eventBytes, err := CreateEventBytes(
+		uint32(0),
+		uint8(eventType),
+		uint32(1),
+		uint32(1234),
+		uint16(0),
+		data)
+	c.Assert(err, IsNil)
+
+	raw := &RawV4Event{data: eventBytes}
+	c.Assert(parseBasicV4EventHeader(eventBytes, &raw.header), IsNil)
+
+	parser := parsers.Get(eventType)
+	c.Assert(raw.SetFixedLengthDataSize(parser.FixedLengthDataSize()), IsNil)
+
+	event, err := parser.Parse(raw)
+	c.Assert(err, IsNil)
+	return event
+}
+
+// This parses the table map event of a table with a virtual and a stored
+// generated column: CREATE TABLE test.t (id INT NOT NULL, a INT NOT NULL,
+// v INT AS (a + 1) VIRTUAL, s INT AS (a * 2) STORED)
+func (s *GeneratedColumnsSuite) parseTableMap(
+	c *C,
+	parsers V4EventParserMap) *TableMapEvent {
+
+	event := s.parse(
+		c,
+		parsers,
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			1, 0,
+			// db name length
+			4,
+			// db name
+			't', 'e', 's', 't', 0,
+			// table name length
+			1,
+			// table name
+			't', 0,
+			// number of columns
+			4,
+			// column types (all long)
+			3, 3, 3, 3,
+			// metadata size
+			0,
+			// null bits
+			0x0c,
+			// optional metadata: column names
+			optionalMetadataColumnName, 9,
+			2, 'i', 'd',
+			1, 'a',
+			1, 'v',
+			1, 's',
+		})
+
+	table, ok := event.(*TableMapEvent)
+	c.Assert(ok, IsTrue)
+
+	parsers.SetTableContext(table)
+	return table
+}
+
+func (s *GeneratedColumnsSuite) TestGeneratedColumns(c *C) {
+	parsers := NewV4EventParserMapWithOptions(DecodeOptions{
+		GeneratedColumns: GeneratedColumns{
+			"test.t": {
+				"v":       VirtualGenerated,
+				"s":       StoredGenerated,
+				"missing": VirtualGenerated,
+			},
+			"test.other": {
+				"a": VirtualGenerated,
+			},
+		},
+	})
+
+	table := s.parseTableMap(c, parsers)
+	c.Assert(
+		table.GeneratedColumnTypes(),
+		DeepEquals,
+		[]GeneratedColumnType{
+			NotGenerated,
+			NotGenerated,
+			VirtualGenerated,
+			StoredGenerated,
+		})
+	c.Assert(table.IsVirtualColumn(1), IsFalse)
+	c.Assert(table.IsVirtualColumn(2), IsTrue)
+	c.Assert(table.IsVirtualColumn(3), IsFalse)
+	c.Assert(table.IsVirtualColumn(4), IsFalse)
+
+	// The used columns bitmap (e.g., of a FULL row image) includes the
+	// virtual column, hence the row data includes the virtual column's
+	// value.
+	event := s.parse(
+		c,
+		parsers,
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			0, 0,
+			// number of columns
+			4,
+			// used columns
+			0x0f,
+			// row 1: null bits, id, a, v, s
+			0,
+			1, 0, 0, 0,
+			10, 0, 0, 0,
+			11, 0, 0, 0,
+			20, 0, 0, 0,
+			// row 2: null bits (s is null), id, a, v
+			0x08,
+			2, 0, 0, 0,
+			30, 0, 0, 0,
+			31, 0, 0, 0,
+		})
+
+	rows, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+
+	columns := table.ColumnDescriptors()
+	c.Assert(rows.UsedColumns(), DeepEquals, columns)
+	c.Assert(rows.InsertedRows(), DeepEquals, []RowValues{
+		{uint64(1), uint64(10), uint64(11), uint64(20)},
+		{uint64(2), uint64(30), uint64(31), nil},
+	})
+
+	changes := NewRowChangeNormalizer().Normalize(rows)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(changes[0].After, DeepEquals, map[string]interface{}{
+		"id": uint64(1),
+		"a":  uint64(10),
+		"v":  uint64(11),
+		"s":  uint64(20),
+	})
+
+	// The virtual column is only decoded when the bitmap includes it.
+	event = s.parse(
+		c,
+		parsers,
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			0, 0,
+			// number of columns
+			4,
+			// used columns (id, a, s)
+			0x0b,
+			// null bits, id, a, s
+			0,
+			3, 0, 0, 0,
+			40, 0, 0, 0,
+			80, 0, 0, 0,
+		})
+
+	rows, ok = event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(
+		rows.UsedColumns(),
+		DeepEquals,
+		[]ColumnDescriptor{columns[0], columns[1], columns[3]})
+	c.Assert(rows.InsertedRows(), DeepEquals, []RowValues{
+		{uint64(3), uint64(40), uint64(80)},
+	})
+}
+
+func (s *GeneratedColumnsSuite) TestNoGeneratedColumns(c *C) {
+	parsers := NewV4EventParserMap()
+
+	table := s.parseTableMap(c, parsers)
+	c.Assert(table.GeneratedColumnTypes(), IsNil)
+	c.Assert(table.IsVirtualColumn(2), IsFalse)
+
+	event := s.parse(
+		c,
+		parsers,
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			0, 0,
+			// number of columns
+			4,
+			// used columns
+			0x0f,
+			// null bits, id, a, v, s
+			0,
+			1, 0, 0, 0,
+			10, 0, 0, 0,
+			11, 0, 0, 0,
+			20, 0, 0, 0,
+		})
+
+	c.Assert(event.(*WriteRowsEvent).InsertedRows(), DeepEquals, []RowValues{
+		{uint64(1), uint64(10), uint64(11), uint64(20)},
+	})
+}
+
+func (s *GeneratedColumnsSuite) TestString(c *C) {
+	c.Assert(NotGenerated.String(), Equals, "NOT GENERATED")
+	c.Assert(VirtualGenerated.String(), Equals, "VIRTUAL")
+	c.Assert(StoredGenerated.String(), Equals, "STORED")
+	c.Assert(GeneratedColumnType(7).String(), Equals, "UNKNOWN(7)")
+}
@@ -0,0 +1,254 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const sidLength = 16
+
+// Add adds the transaction (sid, gno) to the set.  The sid's ranges are kept
+// sorted, and adjacent / overlapping ranges are merged.
+func (s GtidSet) Add(sid []byte, gno uint64) {
+	s.AddRange(sid, GtidRange{Start: gno, End: gno + 1})
+}
+
+// AddRange adds the transactions [r.Start, r.End) from sid to the set.
+func (s GtidSet) AddRange(sid []byte, r GtidRange) {
+	if r.Start >= r.End {
+		return
+	}
+
+	key := string(sid)
+	ranges := s[key]
+
+	// The index of the first range which ends at or after r.Start (i.e., the
+	// first range which may be merged with r).
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].End >= r.Start
+	})
+
+	// The index after the last range which starts at or before r.End.
+	j := i
+	for j < len(ranges) && ranges[j].Start <= r.End {
+		if ranges[j].Start < r.Start {
+			r.Start = ranges[j].Start
+		}
+		if ranges[j].End > r.End {
+			r.End = ranges[j].End
+		}
+		j++
+	}
+
+	merged := make([]GtidRange, 0, len(ranges)-(j-i)+1)
+	merged = append(merged, ranges[:i]...)
+	merged = append(merged, r)
+	merged = append(merged, ranges[j:]...)
+
+	s[key] = merged
+}
+
+// Contains returns true if the transaction (sid, gno) is in the set.
+func (s GtidSet) Contains(sid []byte, gno uint64) bool {
+	ranges := s[string(sid)]
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].End > gno
+	})
+	return i < len(ranges) && ranges[i].Start <= gno
+}
+
+// Copy returns a deep copy of the set.
+func (s GtidSet) Copy() GtidSet {
+	result := make(GtidSet, len(s))
+	for sid, ranges := range s {
+		result[sid] = append([]GtidRange{}, ranges...)
+	}
+	return result
+}
+
+func (s GtidSet) sortedSids() []string {
+	sids := make([]string, 0, len(s))
+	for sid, ranges := range s {
+		if len(ranges) > 0 {
+			sids = append(sids, sid)
+		}
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// String returns the set in mysql's text format, e.g.,
+// "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,...".  Sids are sorted.
+func (s GtidSet) String() string {
+	buf := &bytes.Buffer{}
+	for i, sid := range s.sortedSids() {
+		if i > 0 {
+			_ = buf.WriteByte(',')
+		}
+		_, _ = buf.WriteString(formatSid([]byte(sid)))
+
+		for _, r := range s[sid] {
+			_ = buf.WriteByte(':')
+			_, _ = buf.WriteString(strconv.FormatUint(r.Start, 10))
+			if r.End-1 > r.Start {
+				_ = buf.WriteByte('-')
+				_, _ = buf.WriteString(strconv.FormatUint(r.End-1, 10))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// Encode returns the set in mysql's binary format (as used by the previous
+// gtids log event and the COM_BINLOG_DUMP_GTID command).  Sids are sorted.
+func (s GtidSet) Encode() []byte {
+	sids := s.sortedSids()
+
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, LittleEndian, uint64(len(sids)))
+	for _, sid := range sids {
+		_, _ = buf.WriteString(sid)
+		_ = binary.Write(buf, LittleEndian, uint64(len(s[sid])))
+		for _, r := range s[sid] {
+			_ = binary.Write(buf, LittleEndian, r.Start)
+			_ = binary.Write(buf, LittleEndian, r.End)
+		}
+	}
+	return buf.Bytes()
+}
+
+func formatSid(sid []byte) string {
+	h := hex.EncodeToString(sid)
+	if len(h) != 2*sidLength {
+		return h
+	}
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func parseSid(s string) ([]byte, error) {
+	sid, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(sid) != sidLength {
+		return nil, errors.Newf("Invalid sid: %s", s)
+	}
+	return sid, nil
+}
+
+// ParseGtidSet parses a gtid set in mysql's text format (e.g., as returned
+// by GtidSet's String or SELECT @@GLOBAL.gtid_executed).
+func ParseGtidSet(s string) (GtidSet, error) {
+	set := make(GtidSet)
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return set, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 {
+			return nil, errors.Newf("Invalid gtid set entry: %s", entry)
+		}
+
+		sid, err := parseSid(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, interval := range parts[1:] {
+			bounds := strings.SplitN(interval, "-", 2)
+
+			start, err := strconv.ParseUint(bounds[0], 10, 64)
+			if err != nil || start == 0 {
+				return nil, errors.Newf("Invalid gtid interval: %s", interval)
+			}
+
+			end := start
+			if len(bounds) == 2 {
+				end, err = strconv.ParseUint(bounds[1], 10, 64)
+				if err != nil || end < start {
+					return nil, errors.Newf(
+						"Invalid gtid interval: %s",
+						interval)
+				}
+			}
+
+			set.AddRange(sid, GtidRange{Start: start, End: end + 1})
+		}
+	}
+
+	return set, nil
+}
+
+//
+// ExecutedGtidSetTracker -----------------------------------------------------
+//
+
+// ExecutedGtidSetTracker maintains the set of gtids executed by a consumer
+// which processes the transactions returned by a TransactionGrouper.  The
+// set can be checkpointed (e.g., via String) and used on restart to tell the
+// master which transactions to skip via COM_BINLOG_DUMP_GTID.
+//
+// ExecutedGtidSetTracker is thread safe.
+type ExecutedGtidSetTracker struct {
+	mutex sync.Mutex
+	set   GtidSet
+}
+
+// This returns a tracker whose executed set is initialized to a copy of the
+// initial set (e.g., the restored checkpoint, or the set from the log's
+// previous gtids log event).  initial may be nil.
+func NewExecutedGtidSetTracker(initial GtidSet) *ExecutedGtidSetTracker {
+	set := make(GtidSet)
+	if initial != nil {
+		set = initial.Copy()
+	}
+
+	return &ExecutedGtidSetTracker{
+		set: set,
+	}
+}
+
+// MarkExecuted adds the transaction's gtid to the executed set.  This should
+// be called once the consumer has finished processing the transaction.
+// Transactions which are not committed, or are not gtid transactions, are
+// ignored.  This returns true if the gtid is added to the set.
+func (t *ExecutedGtidSetTracker) MarkExecuted(txn *Transaction) bool {
+	if txn == nil || !txn.Committed || len(txn.Events) == 0 {
+		return false
+	}
+
+	gtid, ok := txn.Events[0].(*GtidLogEvent)
+	if !ok {
+		return false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.set.Add(gtid.Sid(), gtid.Gno())
+	return true
+}
+
+// IsExecuted returns true if the transaction (sid, gno) is in the executed
+// set.
+func (t *ExecutedGtidSetTracker) IsExecuted(sid []byte, gno uint64) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.set.Contains(sid, gno)
+}
+
+// GtidSet returns a copy of the executed set.
+func (t *ExecutedGtidSetTracker) GtidSet() GtidSet {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.set.Copy()
+}
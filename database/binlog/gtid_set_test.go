@@ -0,0 +1,255 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type GtidSetSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&GtidSetSuite{})
+
+var (
+	testSid1 = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	testSid2 = []byte{
+		0xf0, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7,
+		0xf8, 0xf9, 0xfa, 0xfb, 0xfc, 0xfd, 0xfe, 0xff}
+)
+
+const (
+	testSid1String = "00010203-0405-0607-0809-0a0b0c0d0e0f"
+	testSid2String = "f0f1f2f3-f4f5-f6f7-f8f9-fafbfcfdfeff"
+)
+
+func (s *GtidSetSuite) NextGtidEvent(
+	c *C,
+	sid []byte,
+	gno uint64) *GtidLogEvent {
+
+	data := &bytes.Buffer{}
+	data.WriteByte(1)
+	data.Write(sid)
+	binary.Write(data, LittleEndian, gno)
+	s.WriteEvent(
+		mysql_proto.LogEventType_GTID_LOG_EVENT,
+		uint16(0),
+		data.Bytes())
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	gtid, ok := event.(*GtidLogEvent)
+	c.Assert(ok, IsTrue)
+	return gtid
+}
+
+func (s *GtidSetSuite) NextXidEvent(c *C, xid uint64) *XidEvent {
+	data := &bytes.Buffer{}
+	binary.Write(data, LittleEndian, xid)
+	s.WriteEvent(mysql_proto.LogEventType_XID_EVENT, uint16(0), data.Bytes())
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	x, ok := event.(*XidEvent)
+	c.Assert(ok, IsTrue)
+	return x
+}
+
+func (s *GtidSetSuite) GtidTransaction(
+	c *C,
+	sid []byte,
+	gno uint64,
+	committed bool) *Transaction {
+
+	return &Transaction{
+		Events: []Event{
+			s.NextGtidEvent(c, sid, gno),
+			s.NextXidEvent(c, gno),
+		},
+		Committed: committed,
+	}
+}
+
+func (s *GtidSetSuite) TestAdd(c *C) {
+	set := make(GtidSet)
+
+	set.Add(testSid1, 3)
+	set.Add(testSid1, 1)
+	c.Check(set.String(), Equals, testSid1String+":1:3")
+
+	// Fills the gap.
+	set.Add(testSid1, 2)
+	c.Check(set[string(testSid1)], DeepEquals, []GtidRange{{1, 4}})
+
+	// Already in the set.
+	set.Add(testSid1, 2)
+	c.Check(set[string(testSid1)], DeepEquals, []GtidRange{{1, 4}})
+
+	set.Add(testSid1, 10)
+	set.Add(testSid1, 4)
+	set.Add(testSid2, 7)
+	c.Check(
+		set.String(),
+		Equals,
+		testSid1String+":1-4:10,"+testSid2String+":7")
+
+	c.Check(set.Contains(testSid1, 4), IsTrue)
+	c.Check(set.Contains(testSid1, 5), IsFalse)
+	c.Check(set.Contains(testSid1, 10), IsTrue)
+	c.Check(set.Contains(testSid2, 7), IsTrue)
+	c.Check(set.Contains(testSid2, 1), IsFalse)
+}
+
+func (s *GtidSetSuite) TestAddRangeMergesOverlappingRanges(c *C) {
+	set := make(GtidSet)
+	set.AddRange(testSid1, GtidRange{1, 3})
+	set.AddRange(testSid1, GtidRange{5, 7})
+	set.AddRange(testSid1, GtidRange{9, 11})
+
+	set.AddRange(testSid1, GtidRange{2, 10})
+	c.Check(set[string(testSid1)], DeepEquals, []GtidRange{{1, 11}})
+
+	// Empty range.
+	set.AddRange(testSid2, GtidRange{5, 5})
+	_, ok := set[string(testSid2)]
+	c.Check(ok, IsFalse)
+}
+
+func (s *GtidSetSuite) TestParseGtidSet(c *C) {
+	set, err := ParseGtidSet(
+		testSid2String + ":7, " + testSid1String + ":10:1-4")
+	c.Assert(err, IsNil)
+	c.Check(set[string(testSid1)], DeepEquals, []GtidRange{{1, 5}, {10, 11}})
+	c.Check(set[string(testSid2)], DeepEquals, []GtidRange{{7, 8}})
+
+	c.Check(
+		set.String(),
+		Equals,
+		testSid1String+":1-4:10,"+testSid2String+":7")
+
+	set, err = ParseGtidSet("")
+	c.Assert(err, IsNil)
+	c.Check(len(set), Equals, 0)
+
+	for _, invalid := range []string{
+		testSid1String,
+		"0001:1",
+		testSid1String + ":0",
+		testSid1String + ":5-3",
+		testSid1String + ":a",
+	} {
+		_, err = ParseGtidSet(invalid)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *GtidSetSuite) TestEncode(c *C) {
+	set := make(GtidSet)
+	set.AddRange(testSid2, GtidRange{7, 8})
+	set.AddRange(testSid1, GtidRange{10, 11})
+	set.AddRange(testSid1, GtidRange{1, 5})
+
+	single := GtidSet{string(testSid1): set[string(testSid1)]}
+	c.Check(single.Encode(), DeepEquals, serializeGtidSet(single))
+
+	// Sids are sorted, hence the encoding is deterministic.
+	encoded := set.Encode()
+	c.Check(encoded[8:24], DeepEquals, testSid1)
+	c.Check(set.Copy().Encode(), DeepEquals, encoded)
+
+	// The encoded set is readable by the previous gtids log event parser.
+	s.WriteEvent(
+		mysql_proto.LogEventType_PREVIOUS_GTIDS_LOG_EVENT,
+		uint16(0),
+		set.Encode())
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	pgle, ok := event.(*PreviousGtidsLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(pgle.GtidSet(), DeepEquals, set)
+}
+
+func (s *GtidSetSuite) TestTrackerGrowsAcrossCommittedTransactions(c *C) {
+	tracker := NewExecutedGtidSetTracker(nil)
+
+	for _, t := range []struct {
+		sid      []byte
+		gno      uint64
+		expected string
+	}{
+		{testSid1, 1, testSid1String + ":1"},
+		{testSid1, 2, testSid1String + ":1-2"},
+		{testSid2, 1, testSid1String + ":1-2," + testSid2String + ":1"},
+		{testSid1, 5, testSid1String + ":1-2:5," + testSid2String + ":1"},
+		{testSid1, 3, testSid1String + ":1-3:5," + testSid2String + ":1"},
+		{testSid1, 4, testSid1String + ":1-5," + testSid2String + ":1"},
+	} {
+		txn := s.GtidTransaction(c, t.sid, t.gno, true)
+		c.Assert(tracker.MarkExecuted(txn), IsTrue)
+		c.Check(tracker.GtidSet().String(), Equals, t.expected)
+	}
+
+	c.Check(tracker.IsExecuted(testSid1, 3), IsTrue)
+	c.Check(tracker.IsExecuted(testSid1, 6), IsFalse)
+	c.Check(tracker.IsExecuted(testSid2, 1), IsTrue)
+	c.Check(tracker.IsExecuted(testSid2, 2), IsFalse)
+}
+
+func (s *GtidSetSuite) TestTrackerIgnoresUncommittedTransactions(c *C) {
+	tracker := NewExecutedGtidSetTracker(nil)
+
+	c.Check(
+		tracker.MarkExecuted(s.GtidTransaction(c, testSid1, 1, true)),
+		IsTrue)
+
+	// Rolled back / truncated.
+	c.Check(
+		tracker.MarkExecuted(s.GtidTransaction(c, testSid1, 2, false)),
+		IsFalse)
+
+	// Not a gtid transaction.
+	c.Check(
+		tracker.MarkExecuted(&Transaction{
+			Events:    []Event{s.NextXidEvent(c, 3)},
+			Committed: true,
+		}),
+		IsFalse)
+
+	c.Check(tracker.MarkExecuted(&Transaction{Committed: true}), IsFalse)
+	c.Check(tracker.MarkExecuted(nil), IsFalse)
+
+	c.Check(
+		tracker.MarkExecuted(s.GtidTransaction(c, testSid1, 4, true)),
+		IsTrue)
+
+	c.Check(tracker.GtidSet().String(), Equals, testSid1String+":1:4")
+}
+
+func (s *GtidSetSuite) TestTrackerCheckpoint(c *C) {
+	initial, err := ParseGtidSet(testSid1String + ":1-10")
+	c.Assert(err, IsNil)
+
+	tracker := NewExecutedGtidSetTracker(initial)
+	c.Assert(
+		tracker.MarkExecuted(s.GtidTransaction(c, testSid1, 11, true)),
+		IsTrue)
+
+	checkpoint := tracker.GtidSet()
+	c.Check(checkpoint.String(), Equals, testSid1String+":1-11")
+
+	// The initial set and the checkpoint are copies.
+	c.Check(initial.String(), Equals, testSid1String+":1-10")
+	checkpoint.Add(testSid1, 20)
+	c.Check(tracker.IsExecuted(testSid1, 20), IsFalse)
+
+	restored, err := ParseGtidSet(checkpoint.String())
+	c.Assert(err, IsNil)
+	c.Check(restored, DeepEquals, checkpoint)
+}
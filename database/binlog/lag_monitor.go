@@ -0,0 +1,247 @@
+package binlog
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/time2"
+)
+
+const defaultLagHistory = 10 * time.Minute
+
+type lagSample struct {
+	receivedAt time.Time
+	lag        time.Duration
+}
+
+type lagThreshold struct {
+	threshold time.Duration
+	callback  func(time.Duration)
+	exceeded  bool
+}
+
+// LagMonitor is an EventReader wrapper which tracks replication delay, i.e.,
+// the difference between the local time and the (primary's) commit timestamp
+// in the most recent event's header.  Events with zero timestamps (e.g.,
+// artificial rotate events) are ignored.
+//
+// Lag samples are kept (at one second granularity) for the monitor's history
+// duration; LagMovingAvg / MaxLag windows longer than the history are
+// truncated to the history.
+//
+// NOTE: Lag, LagMovingAvg and MaxLag are thread safe, and may be called
+// concurrently with NextEvent.
+type LagMonitor struct {
+	reader  EventReader
+	clock   time2.Clock
+	history time.Duration
+
+	mutex              sync.Mutex
+	hasEvent           bool
+	lastEventTimestamp time.Time
+	samples            []lagSample // ordered by receivedAt
+	thresholds         []*lagThreshold
+}
+
+// This returns a LagMonitor which wraps the given reader.  When clock is nil,
+// time2.DefaultClock is used.  When history is non-positive, 10 minutes of lag
+// history is kept.
+func NewLagMonitor(
+	reader EventReader,
+	clock time2.Clock,
+	history time.Duration) *LagMonitor {
+
+	if clock == nil {
+		clock = time2.DefaultClock
+	}
+	if history <= 0 {
+		history = defaultLagHistory
+	}
+
+	return &LagMonitor{
+		reader:  reader,
+		clock:   clock,
+		history: history,
+	}
+}
+
+func (m *LagMonitor) peekHeaderBytes(numBytes int) ([]byte, error) {
+	return m.reader.peekHeaderBytes(numBytes)
+}
+
+func (m *LagMonitor) consumeHeaderBytes(numBytes int) error {
+	return m.reader.consumeHeaderBytes(numBytes)
+}
+
+func (m *LagMonitor) nextEventEndPosition() int64 {
+	return m.reader.nextEventEndPosition()
+}
+
+func (m *LagMonitor) NextPosition() int64 {
+	return m.reader.NextPosition()
+}
+
+func (m *LagMonitor) Close() error {
+	return m.reader.Close()
+}
+
+func (m *LagMonitor) NextEvent() (Event, error) {
+	event, err := m.reader.NextEvent()
+	if event == nil || event.Timestamp() == 0 {
+		return event, err
+	}
+
+	m.record(time.Unix(int64(event.Timestamp()), 0))
+
+	return event, err
+}
+
+// OnLagThresholdExceeded registers a callback which is invoked (from the
+// NextEvent goroutine) whenever the lag rises above the threshold.  The
+// callback is invoked once per crossing; it is re-armed once the lag drops
+// back to (or below) the threshold.
+func (m *LagMonitor) OnLagThresholdExceeded(
+	threshold time.Duration,
+	fn func(time.Duration)) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.thresholds = append(
+		m.thresholds,
+		&lagThreshold{
+			threshold: threshold,
+			callback:  fn,
+		})
+}
+
+func (m *LagMonitor) record(eventTimestamp time.Time) {
+	now := m.clock.Now()
+	lag := now.Sub(eventTimestamp)
+	if lag < 0 { // clock skew between the primary and the local box.
+		lag = 0
+	}
+
+	var callbacks []func(time.Duration)
+
+	m.mutex.Lock()
+
+	m.hasEvent = true
+	m.lastEventTimestamp = eventTimestamp
+
+	// Coalesce samples received within the same second.
+	last := len(m.samples) - 1
+	if last >= 0 && now.Sub(m.samples[last].receivedAt) < time.Second {
+		if lag > m.samples[last].lag {
+			m.samples[last].lag = lag
+		}
+	} else {
+		m.samples = append(m.samples, lagSample{receivedAt: now, lag: lag})
+	}
+	m.pruneSamples(now)
+
+	for _, t := range m.thresholds {
+		if lag > t.threshold {
+			if !t.exceeded {
+				t.exceeded = true
+				callbacks = append(callbacks, t.callback)
+			}
+		} else {
+			t.exceeded = false
+		}
+	}
+
+	m.mutex.Unlock()
+
+	for _, fn := range callbacks {
+		fn(lag)
+	}
+}
+
+func (m *LagMonitor) pruneSamples(now time.Time) {
+	cutoff := now.Add(-m.history)
+
+	idx := 0
+	for idx < len(m.samples) && m.samples[idx].receivedAt.Before(cutoff) {
+		idx++
+	}
+
+	if idx > 0 {
+		m.samples = append(m.samples[:0], m.samples[idx:]...)
+	}
+}
+
+// Must be called while holding the mutex.
+func (m *LagMonitor) lag(now time.Time) time.Duration {
+	if !m.hasEvent {
+		return 0
+	}
+
+	lag := now.Sub(m.lastEventTimestamp)
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// Lag returns the current replication delay, i.e., now - the most recent
+// event's timestamp.  Note that the lag keeps growing when no new events
+// are read.  This returns zero when no event has been read.
+func (m *LagMonitor) Lag() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.lag(m.clock.Now())
+}
+
+// LagMovingAvg returns the exponential moving average of the lag (in
+// seconds), where each sample in the window is weighted by
+// exp(-age / window).  The current lag is included as the newest sample.
+// This returns zero when no event has been read.
+func (m *LagMonitor) LagMovingAvg(window time.Duration) float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.hasEvent || window <= 0 {
+		return 0
+	}
+
+	now := m.clock.Now()
+	cutoff := now.Add(-window)
+
+	weightedSum := m.lag(now).Seconds()
+	totalWeight := 1.0
+	for _, sample := range m.samples {
+		if sample.receivedAt.Before(cutoff) {
+			continue
+		}
+
+		age := now.Sub(sample.receivedAt)
+		weight := math.Exp(-float64(age) / float64(window))
+
+		weightedSum += weight * sample.lag.Seconds()
+		totalWeight += weight
+	}
+
+	return weightedSum / totalWeight
+}
+
+// MaxLag returns the maximum lag observed within the window (including the
+// current lag).
+func (m *LagMonitor) MaxLag(window time.Duration) time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.clock.Now()
+	cutoff := now.Add(-window)
+
+	maxLag := m.lag(now)
+	for _, sample := range m.samples {
+		if !sample.receivedAt.Before(cutoff) && sample.lag > maxLag {
+			maxLag = sample.lag
+		}
+	}
+
+	return maxLag
+}
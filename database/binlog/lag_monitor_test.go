@@ -0,0 +1,164 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+	"github.com/dropbox/godropbox/time2"
+)
+
+type LagMonitorSuite struct {
+	clock *time2.MockClock
+	buf   *bytes.Buffer
+}
+
+var _ = Suite(&LagMonitorSuite{})
+
+const lagMonitorTestEpoch = 1500000000
+
+func (s *LagMonitorSuite) SetUpTest(c *C) {
+	s.clock = time2.NewMockClock(time.Unix(lagMonitorTestEpoch, 0))
+	s.buf = &bytes.Buffer{}
+}
+
+func (s *LagMonitorSuite) writeEvent(c *C, timestamp uint32) {
+	b, err := CreateEventBytes(
+		timestamp,
+		uint8(mysql_proto.LogEventType_XID_EVENT),
+		uint32(1),
+		uint32(0),
+		uint16(0),
+		make([]byte, 8))
+	c.Assert(err, IsNil)
+	s.buf.Write(b)
+}
+
+func (s *LagMonitorSuite) newMonitor() *LagMonitor {
+	return NewLagMonitor(NewRawV4EventReader(s.buf, "test"), s.clock, 0)
+}
+
+func (s *LagMonitorSuite) TestLag(c *C) {
+	m := s.newMonitor()
+
+	c.Check(m.Lag(), Equals, time.Duration(0))
+	c.Check(m.MaxLag(time.Minute), Equals, time.Duration(0))
+	c.Check(m.LagMovingAvg(time.Minute), Equals, 0.0)
+
+	s.writeEvent(c, lagMonitorTestEpoch-5)
+	_, err := m.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(m.Lag(), Equals, 5*time.Second)
+
+	// The lag grows while no new events are read.
+	s.clock.Advance(3 * time.Second)
+	c.Check(m.Lag(), Equals, 8*time.Second)
+
+	_, err = m.NextEvent()
+	c.Check(err, Equals, io.EOF)
+	c.Check(m.Lag(), Equals, 8*time.Second)
+
+	s.writeEvent(c, lagMonitorTestEpoch+2)
+	_, err = m.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(m.Lag(), Equals, time.Second)
+}
+
+func (s *LagMonitorSuite) TestIgnoreZeroTimestamps(c *C) {
+	m := s.newMonitor()
+
+	s.writeEvent(c, lagMonitorTestEpoch-5)
+	s.writeEvent(c, 0)
+
+	_, err := m.NextEvent()
+	c.Assert(err, IsNil)
+	event, err := m.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.Timestamp(), Equals, uint32(0))
+
+	c.Check(m.Lag(), Equals, 5*time.Second)
+}
+
+func (s *LagMonitorSuite) TestMaxLag(c *C) {
+	m := s.newMonitor()
+
+	s.writeEvent(c, lagMonitorTestEpoch-30)
+	_, err := m.NextEvent()
+	c.Assert(err, IsNil)
+
+	s.clock.Advance(time.Minute)
+	s.writeEvent(c, lagMonitorTestEpoch+58)
+	_, err = m.NextEvent()
+	c.Assert(err, IsNil)
+
+	c.Check(m.Lag(), Equals, 2*time.Second)
+	c.Check(m.MaxLag(2*time.Minute), Equals, 30*time.Second)
+	c.Check(m.MaxLag(30*time.Second), Equals, 2*time.Second)
+}
+
+func (s *LagMonitorSuite) TestLagMovingAvg(c *C) {
+	m := s.newMonitor()
+
+	// Constant lag.
+	for i := 0; i < 10; i++ {
+		s.writeEvent(c, uint32(lagMonitorTestEpoch+i*10-4))
+		_, err := m.NextEvent()
+		c.Assert(err, IsNil)
+		s.clock.Advance(10 * time.Second)
+	}
+	s.clock.Advance(-10 * time.Second)
+	c.Check(m.LagMovingAvg(time.Minute), Equals, 4.0)
+
+	// A lag spike pulls the average up, but not all the way.
+	s.clock.Advance(10 * time.Second)
+	s.writeEvent(c, lagMonitorTestEpoch+100-24)
+	_, err := m.NextEvent()
+	c.Assert(err, IsNil)
+
+	avg := m.LagMovingAvg(time.Minute)
+	c.Check(avg > 4.0, IsTrue, Commentf("avg: %v", avg))
+	c.Check(avg < 24.0, IsTrue, Commentf("avg: %v", avg))
+
+	// A shorter window weighs the spike more heavily.
+	c.Check(m.LagMovingAvg(10*time.Second) > avg, IsTrue)
+}
+
+func (s *LagMonitorSuite) TestSampleHistory(c *C) {
+	m := NewLagMonitor(
+		NewRawV4EventReader(s.buf, "test"),
+		s.clock,
+		time.Minute)
+
+	for i := 0; i < 100; i++ {
+		s.writeEvent(c, uint32(lagMonitorTestEpoch+i))
+		_, err := m.NextEvent()
+		c.Assert(err, IsNil)
+		s.clock.Advance(time.Second)
+	}
+
+	c.Check(len(m.samples) <= 61, IsTrue, Commentf("%d", len(m.samples)))
+}
+
+func (s *LagMonitorSuite) TestOnLagThresholdExceeded(c *C) {
+	m := s.newMonitor()
+
+	var exceeded []time.Duration
+	m.OnLagThresholdExceeded(10*time.Second, func(lag time.Duration) {
+		exceeded = append(exceeded, lag)
+	})
+
+	for _, lag := range []uint32{5, 11, 15, 10, 20, 3} {
+		s.writeEvent(c, lagMonitorTestEpoch-lag)
+		_, err := m.NextEvent()
+		c.Assert(err, IsNil)
+	}
+
+	c.Check(exceeded, DeepEquals, []time.Duration{
+		11 * time.Second,
+		20 * time.Second,
+	})
+}
@@ -0,0 +1,279 @@
+package binlog
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// Prior to 5.0.3 (or when the master is older than 5.0.3), LOAD DATA INFILE
+// statements were replicated using the legacy load event family: a create
+// file event (containing the load description and the first block of the
+// file), zero or more append block events (containing the remaining blocks),
+// followed by an execute load event (or a delete file event when the
+// statement failed).  The events are linked together by the file id.  The
+// legacy load / new load events (which reference a file on the master's
+// local file system) were used by even older servers.
+//
+// These events are only decoded partially (the post header is decoded, but
+// the load description, i.e., the field / line terminators and the field /
+// table / database / file names, is returned as raw bytes); this is
+// sufficient for scanning archival binlogs.
+
+// The size of the load event's post header.
+const legacyLoadHeaderSize = 18
+
+// A representation of the load / new load event.
+//
+// Load / new load event's binlog payload is structured as follow:
+//
+//  Common to both 5.0 and 5.5 / 5.6:
+//      19 bytes for common v4 event header
+//      4 bytes (uint32) for thread id
+//      4 bytes (uint32) for execution duration (in seconds)
+//      4 bytes (uint32) for number of lines to skip
+//      1 byte (uint8) for table name length
+//      1 byte (uint8) for database name length
+//      4 bytes (uint32) for number of fields
+//      the remaining is the (undecoded) load description
+//  5.6 Specific:
+//      (optional) 4 bytes footer for checksum.
+type LegacyLoadEvent struct {
+	Event
+
+	threadId        uint32
+	duration        uint32
+	skipLines       uint32
+	tableNameLength uint8
+	dbNameLength    uint8
+	numFields       uint32
+	description     []byte
+}
+
+// ThreadId returns the thread id which executed the load statement.
+func (e *LegacyLoadEvent) ThreadId() uint32 {
+	return e.threadId
+}
+
+// Duration returns the amount of time in second the load statement took to
+// execute.
+func (e *LegacyLoadEvent) Duration() uint32 {
+	return e.duration
+}
+
+// SkipLines returns the number of (header) lines to skip.
+func (e *LegacyLoadEvent) SkipLines() uint32 {
+	return e.skipLines
+}
+
+// TableNameLength returns the length of the table name in the load
+// description.
+func (e *LegacyLoadEvent) TableNameLength() uint8 {
+	return e.tableNameLength
+}
+
+// DatabaseNameLength returns the length of the database name in the load
+// description.
+func (e *LegacyLoadEvent) DatabaseNameLength() uint8 {
+	return e.dbNameLength
+}
+
+// NumFields returns the number of fields listed in the load description.
+func (e *LegacyLoadEvent) NumFields() uint32 {
+	return e.numFields
+}
+
+// Description returns the raw (undecoded) load description.  For create file
+// events, the description is followed by the first block of the file.
+func (e *LegacyLoadEvent) Description() []byte {
+	return e.description
+}
+
+// A representation of the create file event.
+//
+// Create file event's binlog payload is structured as follow:
+//
+//  Common to both 5.0 and 5.5 / 5.6:
+//      19 bytes for common v4 event header
+//      18 bytes for the load event's post header (see LegacyLoadEvent)
+//      4 bytes (uint32) for file id
+//      the remaining is the (undecoded) load description, followed by the
+//      first block of the file
+//  5.6 Specific:
+//      (optional) 4 bytes footer for checksum.
+type CreateFileEvent struct {
+	LegacyLoadEvent
+
+	fileId uint32
+}
+
+// FileId returns the id of the created file.
+func (e *CreateFileEvent) FileId() uint32 {
+	return e.fileId
+}
+
+// A representation of the execute load / delete file event.
+//
+// Execute load / delete file event's binlog payload is structured as follow:
+//
+//  Common to both 5.0 and 5.5 / 5.6:
+//      19 bytes for common v4 event header
+//      4 bytes (uint32) for file id
+//  5.6 Specific:
+//      (optional) 4 bytes footer for checksum.
+type ExecuteLoadEvent struct {
+	Event
+
+	fileId uint32
+}
+
+// FileId returns the id of the loaded (or deleted) file.
+func (e *ExecuteLoadEvent) FileId() uint32 {
+	return e.fileId
+}
+
+// A representation of the delete file event.  The delete file event's binlog
+// payload is identical to the execute load event's.
+type DeleteFileEvent struct {
+	ExecuteLoadEvent
+}
+
+//
+// LegacyLoadEventParser ------------------------------------------------------
+//
+
+type LegacyLoadEventParser struct {
+	hasNoTableContext
+
+	eventType mysql_proto.LogEventType_Type
+}
+
+func newLoadEventParser() *LegacyLoadEventParser {
+	return &LegacyLoadEventParser{
+		eventType: mysql_proto.LogEventType_LOAD_EVENT,
+	}
+}
+
+func newNewLoadEventParser() *LegacyLoadEventParser {
+	return &LegacyLoadEventParser{
+		eventType: mysql_proto.LogEventType_NEW_LOAD_EVENT,
+	}
+}
+
+func newCreateFileEventParser() *LegacyLoadEventParser {
+	return &LegacyLoadEventParser{
+		eventType: mysql_proto.LogEventType_CREATE_FILE_EVENT,
+	}
+}
+
+// LegacyLoadEventParser's EventType returns either
+// mysql_proto.LogEventType_LOAD_EVENT, mysql_proto.LogEventType_NEW_LOAD_EVENT
+// or mysql_proto.LogEventType_CREATE_FILE_EVENT.
+func (p *LegacyLoadEventParser) EventType() mysql_proto.LogEventType_Type {
+	return p.eventType
+}
+
+// LegacyLoadEventParser's FixedLengthDataSize returns 22 for create file
+// events, and 18 otherwise.  NOTE: the format description event's create
+// file event size (4) excludes the load event's post header.
+func (p *LegacyLoadEventParser) FixedLengthDataSize() int {
+	if p.eventType == mysql_proto.LogEventType_CREATE_FILE_EVENT {
+		return legacyLoadHeaderSize + 4
+	}
+	return legacyLoadHeaderSize
+}
+
+// LegacyLoadEventParser's Parse processes a raw load / new load / create file
+// event into a LegacyLoadEvent / CreateFileEvent.
+func (p *LegacyLoadEventParser) Parse(raw *RawV4Event) (Event, error) {
+	type fixedHeaderStruct struct {
+		ThreadId        uint32
+		Duration        uint32
+		SkipLines       uint32
+		TableNameLength uint8
+		DbNameLength    uint8
+		NumFields       uint32
+	}
+
+	fixed := fixedHeaderStruct{}
+
+	data, err := readLittleEndian(raw.FixedLengthData(), &fixed)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read fixed header")
+	}
+
+	load := LegacyLoadEvent{
+		Event:           raw,
+		threadId:        fixed.ThreadId,
+		duration:        fixed.Duration,
+		skipLines:       fixed.SkipLines,
+		tableNameLength: fixed.TableNameLength,
+		dbNameLength:    fixed.DbNameLength,
+		numFields:       fixed.NumFields,
+		description:     raw.VariableLengthData(),
+	}
+
+	if p.eventType != mysql_proto.LogEventType_CREATE_FILE_EVENT {
+		return &load, nil
+	}
+
+	create := &CreateFileEvent{LegacyLoadEvent: load}
+
+	_, err = readLittleEndian(data, &create.fileId)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read file id")
+	}
+
+	return create, nil
+}
+
+//
+// ExecuteLoadEventParser -----------------------------------------------------
+//
+
+type ExecuteLoadEventParser struct {
+	hasNoTableContext
+
+	eventType mysql_proto.LogEventType_Type
+}
+
+func newExecuteLoadEventParser() *ExecuteLoadEventParser {
+	return &ExecuteLoadEventParser{
+		eventType: mysql_proto.LogEventType_EXEC_LOAD_EVENT,
+	}
+}
+
+func newDeleteFileEventParser() *ExecuteLoadEventParser {
+	return &ExecuteLoadEventParser{
+		eventType: mysql_proto.LogEventType_DELETE_FILE_EVENT,
+	}
+}
+
+// ExecuteLoadEventParser's EventType returns either
+// mysql_proto.LogEventType_EXEC_LOAD_EVENT or
+// mysql_proto.LogEventType_DELETE_FILE_EVENT.
+func (p *ExecuteLoadEventParser) EventType() mysql_proto.LogEventType_Type {
+	return p.eventType
+}
+
+// ExecuteLoadEventParser's FixedLengthDataSize always returns 4.
+func (p *ExecuteLoadEventParser) FixedLengthDataSize() int {
+	return 4
+}
+
+// ExecuteLoadEventParser's Parse processes a raw execute load / delete file
+// event into an ExecuteLoadEvent / DeleteFileEvent.
+func (p *ExecuteLoadEventParser) Parse(raw *RawV4Event) (Event, error) {
+	exec := ExecuteLoadEvent{
+		Event: raw,
+	}
+
+	_, err := readLittleEndian(raw.FixedLengthData(), &exec.fileId)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read file id")
+	}
+
+	if p.eventType == mysql_proto.LogEventType_DELETE_FILE_EVENT {
+		return &DeleteFileEvent{ExecuteLoadEvent: exec}, nil
+	}
+	return &exec, nil
+}
@@ -0,0 +1,159 @@
+package binlog
+
+import (
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type LegacyLoadEventSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&LegacyLoadEventSuite{})
+
+var testLegacyLoadHeader = []byte{
+	// thread id
+	7, 0, 0, 0,
+	// duration
+	2, 0, 0, 0,
+	// skip lines
+	1, 0, 0, 0,
+	// table name length
+	1,
+	// db name length
+	2,
+	// number of fields
+	3, 0, 0, 0,
+}
+
+// A new style load description (the field / line terminators, the field
+// names, the table name, the database name and the file name).
+var testLegacyLoadDescription = []byte{
+	// field terminator, enclosed by, line terminator, line starting by,
+	// escaped by
+	1, ',', 0, 1, '\n', 0, 1, '\\',
+	// opt flags
+	0,
+	// field name lengths
+	1, 1, 1,
+	// field names
+	'a', 0, 'b', 0, 'c', 0,
+	// table name
+	't', 0,
+	// db name
+	'd', 'b', 0,
+	// file name
+	'/', 't', 'm', 'p', '/', 'f',
+}
+
+func (s *LegacyLoadEventSuite) checkLoadHeader(c *C, load *LegacyLoadEvent) {
+	c.Check(load.ThreadId(), Equals, uint32(7))
+	c.Check(load.Duration(), Equals, uint32(2))
+	c.Check(load.SkipLines(), Equals, uint32(1))
+	c.Check(load.TableNameLength(), Equals, uint8(1))
+	c.Check(load.DatabaseNameLength(), Equals, uint8(2))
+	c.Check(load.NumFields(), Equals, uint32(3))
+}
+
+func (s *LegacyLoadEventSuite) TestLoadEvents(c *C) {
+	data := append([]byte{}, testLegacyLoadHeader...)
+	data = append(data, testLegacyLoadDescription...)
+
+	s.WriteEvent(mysql_proto.LogEventType_LOAD_EVENT, uint16(0), data)
+	s.WriteEvent(mysql_proto.LogEventType_NEW_LOAD_EVENT, uint16(0), data)
+
+	for _, eventType := range []mysql_proto.LogEventType_Type{
+		mysql_proto.LogEventType_LOAD_EVENT,
+		mysql_proto.LogEventType_NEW_LOAD_EVENT,
+	} {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+		c.Assert(event.EventType(), Equals, eventType)
+
+		load, ok := event.(*LegacyLoadEvent)
+		c.Assert(ok, IsTrue)
+
+		s.checkLoadHeader(c, load)
+		c.Check(load.Description(), DeepEquals, testLegacyLoadDescription)
+	}
+}
+
+func (s *LegacyLoadEventSuite) TestCreateFileSequence(c *C) {
+	data := append([]byte{}, testLegacyLoadHeader...)
+	data = append(data, 42, 0, 0, 0) // file id
+	data = append(data, testLegacyLoadDescription...)
+	data = append(data, 0)            // file name terminator
+	data = append(data, "1,2,3\n"...) // first block
+	s.WriteEvent(mysql_proto.LogEventType_CREATE_FILE_EVENT, uint16(0), data)
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_APPEND_BLOCK_EVENT,
+		uint16(0),
+		[]byte{42, 0, 0, 0, '4', ',', '5', ',', '6', '\n'})
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_EXEC_LOAD_EVENT,
+		uint16(0),
+		[]byte{42, 0, 0, 0})
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_DELETE_FILE_EVENT,
+		uint16(0),
+		[]byte{43, 0, 0, 0})
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	create, ok := event.(*CreateFileEvent)
+	c.Assert(ok, IsTrue)
+	s.checkLoadHeader(c, &create.LegacyLoadEvent)
+	c.Check(create.FileId(), Equals, uint32(42))
+	c.Check(
+		string(create.Description()),
+		Equals,
+		string(testLegacyLoadDescription)+"\x001,2,3\n")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	block, ok := event.(*AppendBlockEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(block.FileId(), Equals, uint32(42))
+	c.Check(string(block.BlockData()), Equals, "4,5,6\n")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	exec, ok := event.(*ExecuteLoadEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(exec.FileId(), Equals, uint32(42))
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	del, ok := event.(*DeleteFileEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(del.FileId(), Equals, uint32(43))
+}
+
+func (s *LegacyLoadEventSuite) TestTruncatedEvent(c *C) {
+	// The event is shorter than the load event's post header.
+	s.WriteEvent(
+		mysql_proto.LogEventType_LOAD_EVENT,
+		uint16(0),
+		[]byte{1, 2, 3})
+	s.WriteEvent(
+		mysql_proto.LogEventType_EXEC_LOAD_EVENT,
+		uint16(0),
+		[]byte{42, 0, 0, 0})
+
+	event, err := s.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := event.(*RawV4Event)
+	c.Assert(ok, IsTrue)
+
+	// The truncated event is skipped by its event size.
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	exec, ok := event.(*ExecuteLoadEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(exec.FileId(), Equals, uint32(42))
+}
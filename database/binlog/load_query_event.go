@@ -0,0 +1,299 @@
+package binlog
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// LOAD DATA INFILE statements are replicated as a begin load query event
+// (containing the first block of the file), zero or more append block events
+// (containing the remaining blocks), followed by an execute load query event.
+// The events are linked together by the file id.
+
+// A representation of the append block event.
+//
+// Append block event's binlog payload is structured as follow:
+//
+//  Common to both 5.5 and 5.6:
+//      19 bytes for common v4 event header
+//      4 bytes (uint32) for file id
+//      the remaining is the block data
+//  5.6 Specific:
+//      (optional) 4 bytes footer for checksum.
+type AppendBlockEvent struct {
+	Event
+
+	fileId    uint32
+	blockData []byte
+}
+
+// FileId returns the id of the file the block belongs to.
+func (e *AppendBlockEvent) FileId() uint32 {
+	return e.fileId
+}
+
+// BlockData returns the block's data.
+func (e *AppendBlockEvent) BlockData() []byte {
+	return e.blockData
+}
+
+// A representation of the begin load query event.  The begin load query
+// event's binlog payload is identical to the append block event's, except the
+// block data is the first block of the file.
+type BeginLoadQueryEvent struct {
+	AppendBlockEvent
+}
+
+// How LOAD DATA INFILE handles rows which duplicate existing unique keys.
+type LoadDupHandling uint8
+
+const (
+	LoadDupError   = LoadDupHandling(0)
+	LoadDupIgnore  = LoadDupHandling(1)
+	LoadDupReplace = LoadDupHandling(2)
+)
+
+// A representation of the execute load query event.
+//
+// Execute load query event's binlog payload is structured as follow:
+//
+//  Common to both 5.5 and 5.6:
+//      19 bytes for common v4 event header
+//      13 bytes for the query event's fixed length data (see QueryEvent)
+//      4 bytes (uint32) for file id
+//      4 bytes (uint32) for the file name's start position in the query
+//      4 bytes (uint32) for the file name's end position in the query
+//      1 byte (uint8) for duplicate handling
+//      the remaining is identical to the query event's variable length data
+//  5.6 Specific:
+//      (optional) 4 bytes footer for checksum.
+type ExecuteLoadQueryEvent struct {
+	*QueryEvent
+
+	fileId           uint32
+	fileNameStartPos uint32
+	fileNameEndPos   uint32
+	dupHandling      LoadDupHandling
+}
+
+// FileId returns the id of the file loaded by the query.
+func (e *ExecuteLoadQueryEvent) FileId() uint32 {
+	return e.fileId
+}
+
+// FileNameStartPos returns the start position of the file name within the
+// query.
+func (e *ExecuteLoadQueryEvent) FileNameStartPos() uint32 {
+	return e.fileNameStartPos
+}
+
+// FileNameEndPos returns the end position (exclusive) of the file name within
+// the query.
+func (e *ExecuteLoadQueryEvent) FileNameEndPos() uint32 {
+	return e.fileNameEndPos
+}
+
+// FileName returns the (primary's) file name portion of the query.
+func (e *ExecuteLoadQueryEvent) FileName() []byte {
+	return e.Query()[e.fileNameStartPos:e.fileNameEndPos]
+}
+
+// DupHandling returns the query's duplicate key handling mode.
+func (e *ExecuteLoadQueryEvent) DupHandling() LoadDupHandling {
+	return e.dupHandling
+}
+
+//
+// AppendBlockEventParser -----------------------------------------------------
+//
+
+type AppendBlockEventParser struct {
+	hasNoTableContext
+
+	eventType mysql_proto.LogEventType_Type
+}
+
+func newAppendBlockEventParser() *AppendBlockEventParser {
+	return &AppendBlockEventParser{
+		eventType: mysql_proto.LogEventType_APPEND_BLOCK_EVENT,
+	}
+}
+
+func newBeginLoadQueryEventParser() *AppendBlockEventParser {
+	return &AppendBlockEventParser{
+		eventType: mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT,
+	}
+}
+
+// AppendBlockEventParser's EventType returns either
+// mysql_proto.LogEventType_APPEND_BLOCK_EVENT or
+// mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT.
+func (p *AppendBlockEventParser) EventType() mysql_proto.LogEventType_Type {
+	return p.eventType
+}
+
+// AppendBlockEventParser's FixedLengthDataSize always returns 4.
+func (p *AppendBlockEventParser) FixedLengthDataSize() int {
+	return 4
+}
+
+// AppendBlockEventParser's Parse processes a raw append block / begin load
+// query event into an AppendBlockEvent / BeginLoadQueryEvent.
+func (p *AppendBlockEventParser) Parse(raw *RawV4Event) (Event, error) {
+	block := AppendBlockEvent{
+		Event:     raw,
+		blockData: raw.VariableLengthData(),
+	}
+
+	_, err := readLittleEndian(raw.FixedLengthData(), &block.fileId)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read file id")
+	}
+
+	if p.eventType == mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT {
+		return &BeginLoadQueryEvent{AppendBlockEvent: block}, nil
+	}
+	return &block, nil
+}
+
+//
+// ExecuteLoadQueryEventParser ------------------------------------------------
+//
+
+type ExecuteLoadQueryEventParser struct {
+	hasNoTableContext
+
+	queryParser QueryEventParser
+}
+
+// ExecuteLoadQueryEventParser's EventType always returns
+// mysql_proto.LogEventType_EXECUTE_LOAD_QUERY_EVENT.
+func (p *ExecuteLoadQueryEventParser) EventType() mysql_proto.LogEventType_Type {
+	return mysql_proto.LogEventType_EXECUTE_LOAD_QUERY_EVENT
+}
+
+// ExecuteLoadQueryEventParser's FixedLengthDataSize always returns 26.
+func (p *ExecuteLoadQueryEventParser) FixedLengthDataSize() int {
+	return p.queryParser.FixedLengthDataSize() + 13
+}
+
+// ExecuteLoadQueryEventParser's Parse processes a raw execute load query
+// event into an ExecuteLoadQueryEvent.
+func (p *ExecuteLoadQueryEventParser) Parse(raw *RawV4Event) (Event, error) {
+	fixedData := raw.FixedLengthData()
+
+	query, err := p.queryParser.parseQuery(raw, fixedData)
+	if err != nil {
+		return raw, err
+	}
+
+	type fixedBodyStruct struct {
+		FileId           uint32
+		FileNameStartPos uint32
+		FileNameEndPos   uint32
+		DupHandling      uint8
+	}
+
+	fixed := fixedBodyStruct{}
+
+	_, err = readLittleEndian(
+		fixedData[p.queryParser.FixedLengthDataSize():],
+		&fixed)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read fixed body")
+	}
+
+	if fixed.FileNameStartPos > fixed.FileNameEndPos ||
+		int(fixed.FileNameEndPos) > len(query.Query()) {
+
+		return raw, errors.Newf(
+			"Invalid file name position (start: %d end: %d query length: %d)",
+			fixed.FileNameStartPos,
+			fixed.FileNameEndPos,
+			len(query.Query()))
+	}
+
+	return &ExecuteLoadQueryEvent{
+		QueryEvent:       query,
+		fileId:           fixed.FileId,
+		fileNameStartPos: fixed.FileNameStartPos,
+		fileNameEndPos:   fixed.FileNameEndPos,
+		dupHandling:      LoadDupHandling(fixed.DupHandling),
+	}, nil
+}
+
+//
+// LoadDataAssembler ----------------------------------------------------------
+//
+
+// LoadData is a reassembled LOAD DATA INFILE file.
+type LoadData struct {
+	// The execute load query event which loaded the file.
+	Query *ExecuteLoadQueryEvent
+
+	// The file's content.
+	Data []byte
+}
+
+// LoadDataAssembler reassembles LOAD DATA INFILE files from begin load query
+// and append block events.  Files are keyed by file id.
+//
+// NOTE: LoadDataAssembler is not thread safe.
+type LoadDataAssembler struct {
+	files map[uint32][]byte
+}
+
+func NewLoadDataAssembler() *LoadDataAssembler {
+	return &LoadDataAssembler{
+		files: make(map[uint32][]byte),
+	}
+}
+
+// Process updates the assembler's state with the event.  This returns the
+// reassembled file when the event is an execute load query event; otherwise,
+// this returns nil.  Events unrelated to LOAD DATA INFILE are ignored.
+func (a *LoadDataAssembler) Process(event Event) (*LoadData, error) {
+	switch e := event.(type) {
+	case *BeginLoadQueryEvent:
+		if _, ok := a.files[e.FileId()]; ok {
+			return nil, errors.Newf("Duplicate file id: %d", e.FileId())
+		}
+		a.files[e.FileId()] = append([]byte{}, e.BlockData()...)
+
+	case *AppendBlockEvent:
+		data, ok := a.files[e.FileId()]
+		if !ok {
+			return nil, errors.Newf(
+				"Append block for unknown file id: %d",
+				e.FileId())
+		}
+		a.files[e.FileId()] = append(data, e.BlockData()...)
+
+	case *ExecuteLoadQueryEvent:
+		data, ok := a.files[e.FileId()]
+		if !ok {
+			return nil, errors.Newf(
+				"Execute load query for unknown file id: %d",
+				e.FileId())
+		}
+		delete(a.files, e.FileId())
+
+		return &LoadData{
+			Query: e,
+			Data:  data,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// NumPendingFiles returns the number of partially assembled files.
+func (a *LoadDataAssembler) NumPendingFiles() int {
+	return len(a.files)
+}
+
+// Discard drops the partially assembled file (e.g., when the load was
+// aborted).
+func (a *LoadDataAssembler) Discard(fileId uint32) {
+	delete(a.files, fileId)
+}
@@ -0,0 +1,186 @@
+package binlog
+
+import (
+	"encoding/binary"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type LoadQueryEventSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&LoadQueryEventSuite{})
+
+func (s *LoadQueryEventSuite) writeBlock(
+	eventType mysql_proto.LogEventType_Type,
+	fileId uint32,
+	block string) {
+
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, fileId)
+	s.WriteEvent(eventType, uint16(0), append(data, block...))
+}
+
+func (s *LoadQueryEventSuite) writeExecute(
+	fileId uint32,
+	fileName string,
+	dupHandling LoadDupHandling) {
+
+	prefix := "LOAD DATA INFILE '"
+	query := prefix + fileName + "' INTO TABLE t"
+
+	data := []byte{
+		// thread id
+		1, 0, 0, 0,
+		// duration
+		0, 0, 0, 0,
+		// db name length
+		2,
+		// error code
+		0, 0,
+		// status length
+		0, 0,
+	}
+
+	extra := make([]byte, 13)
+	binary.LittleEndian.PutUint32(extra[0:], fileId)
+	binary.LittleEndian.PutUint32(extra[4:], uint32(len(prefix)))
+	binary.LittleEndian.PutUint32(extra[8:], uint32(len(prefix)+len(fileName)))
+	extra[12] = byte(dupHandling)
+
+	data = append(data, extra...)
+	data = append(data, "db\x00"...)
+	data = append(data, query...)
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_EXECUTE_LOAD_QUERY_EVENT,
+		uint16(0),
+		data)
+}
+
+func (s *LoadQueryEventSuite) TestBeginLoadQuery(c *C) {
+	s.writeBlock(mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT, 7, "1,a\n")
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	e, ok := event.(*BeginLoadQueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(e.FileId(), Equals, uint32(7))
+	c.Check(string(e.BlockData()), Equals, "1,a\n")
+}
+
+func (s *LoadQueryEventSuite) TestAppendBlock(c *C) {
+	s.writeBlock(mysql_proto.LogEventType_APPEND_BLOCK_EVENT, 0x01020304, "")
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	e, ok := event.(*AppendBlockEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(e.FileId(), Equals, uint32(0x01020304))
+	c.Check(len(e.BlockData()), Equals, 0)
+}
+
+func (s *LoadQueryEventSuite) TestExecuteLoadQuery(c *C) {
+	s.writeExecute(7, "/tmp/SQL_LOAD-1-2-3.data", LoadDupReplace)
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	e, ok := event.(*ExecuteLoadQueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(e.FileId(), Equals, uint32(7))
+	c.Check(e.DupHandling(), Equals, LoadDupReplace)
+	c.Check(string(e.FileName()), Equals, "/tmp/SQL_LOAD-1-2-3.data")
+	c.Check(string(e.DatabaseName()), Equals, "db")
+	c.Check(
+		string(e.Query()),
+		Equals,
+		"LOAD DATA INFILE '/tmp/SQL_LOAD-1-2-3.data' INTO TABLE t")
+	c.Check(e.ThreadId(), Equals, uint32(1))
+}
+
+func (s *LoadQueryEventSuite) TestExecuteLoadQueryInvalidFileNamePos(c *C) {
+	data := make([]byte, 26)
+	data[8] = 0                                   // db name length
+	binary.LittleEndian.PutUint32(data[17:], 100) // start pos
+	binary.LittleEndian.PutUint32(data[21:], 200) // end pos
+	data = append(data, "\x00select 1"...)
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_EXECUTE_LOAD_QUERY_EVENT,
+		uint16(0),
+		data)
+
+	event, err := s.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+}
+
+func (s *LoadQueryEventSuite) TestAssembleFileIdLinkage(c *C) {
+	// Two interleaved loads.
+	s.writeBlock(mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT, 1, "1,a\n")
+	s.writeBlock(mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT, 2, "x\n")
+	s.writeBlock(mysql_proto.LogEventType_APPEND_BLOCK_EVENT, 1, "2,b\n")
+	s.writeBlock(mysql_proto.LogEventType_APPEND_BLOCK_EVENT, 2, "y\n")
+	s.writeBlock(mysql_proto.LogEventType_APPEND_BLOCK_EVENT, 1, "3,c\n")
+	s.writeExecute(2, "f2", LoadDupError)
+	s.writeExecute(1, "f1", LoadDupIgnore)
+
+	assembler := NewLoadDataAssembler()
+
+	var loaded []*LoadData
+	for i := 0; i < 7; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+
+		load, err := assembler.Process(event)
+		c.Assert(err, IsNil)
+		if load != nil {
+			loaded = append(loaded, load)
+		}
+	}
+
+	c.Assert(len(loaded), Equals, 2)
+
+	c.Check(loaded[0].Query.FileId(), Equals, uint32(2))
+	c.Check(string(loaded[0].Query.FileName()), Equals, "f2")
+	c.Check(string(loaded[0].Data), Equals, "x\ny\n")
+
+	c.Check(loaded[1].Query.FileId(), Equals, uint32(1))
+	c.Check(loaded[1].Query.DupHandling(), Equals, LoadDupIgnore)
+	c.Check(string(loaded[1].Data), Equals, "1,a\n2,b\n3,c\n")
+
+	c.Check(assembler.NumPendingFiles(), Equals, 0)
+}
+
+func (s *LoadQueryEventSuite) TestAssembleErrors(c *C) {
+	s.writeBlock(mysql_proto.LogEventType_APPEND_BLOCK_EVENT, 1, "orphan")
+	s.writeExecute(1, "f1", LoadDupError)
+	s.writeBlock(mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT, 2, "a")
+	s.writeBlock(mysql_proto.LogEventType_BEGIN_LOAD_QUERY_EVENT, 2, "b")
+
+	assembler := NewLoadDataAssembler()
+
+	for i := 0; i < 4; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+
+		_, err = assembler.Process(event)
+		if i == 2 {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, NotNil)
+		}
+	}
+
+	c.Check(assembler.NumPendingFiles(), Equals, 1)
+	assembler.Discard(2)
+	c.Check(assembler.NumPendingFiles(), Equals, 0)
+}
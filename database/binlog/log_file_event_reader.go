@@ -40,12 +40,66 @@ func (h *formatVersionHeader) version() int {
 	return 3
 }
 
+// Options for configuring the log file v4 event reader.
+type LogFileV4EventReaderOptions struct {
+	RawV4EventReaderOptions
+
+	// When set, this overrides the checksum algorithm specified by the format
+	// description event for all non-FDE events (including the artificial
+	// rotate event preceding the first format description event).  For
+	// example, setting this to ChecksumAlgorithm_OFF disables checksum footer
+	// stripping.  This should be set to the checksum algorithm negotiated
+	// with the master (see @master_binlog_checksum) when reading a
+	// replication stream.
+	ChecksumAlgorithm *mysql_proto.ChecksumAlgorithm_Type
+
+	// Additional checksum verifiers.  These take precedence over the built-in
+	// verifiers (for ChecksumAlgorithm_OFF and ChecksumAlgorithm_CRC32) with
+	// the same algorithm.
+	ChecksumVerifiers []ChecksumVerifier
+
+	// When true, the reader verifies each event's checksum and returns the
+	// event along with an error on mismatch.
+	VerifyChecksum bool
+
+	// When true (and VerifyChecksum is set), checksum mismatches are not
+	// returned as errors.  The mismatched events are returned with
+	// ChecksumMismatch status instead (see Event.ChecksumStatus).
+	LenientChecksum bool
+
+	// The maximum number of table map derived schemas cached by the reader.
+	// When non-positive, DefaultSchemaCacheSize is used.
+	SchemaCacheSize int
+
+	// How events with unknown type codes are handled.  Defaults to
+	// PassThroughUnknownEvents.
+	UnknownEventMode UnknownEventMode
+
+	// When true, query events are dropped without being parsed (i.e.,
+	// neither DDL nor statement based DML is returned), except for
+	// transaction control statements (e.g., BEGIN / COMMIT / ROLLBACK),
+	// which are kept for transaction boundary detection.  All other events
+	// (including table map events, which are needed for decoding rows
+	// events) are returned as usual.  NOTE: the dropped events' checksums
+	// are not verified.
+	DMLOnly bool
+}
+
 type logFileV4EventReader struct {
 	reader                      EventReader
+	rawReader                   *rawV4EventReader
 	parsers                     V4EventParserMap
 	passedMagicBytesCheck       bool
 	passedLogFormatVersionCheck bool
 	logger                      Logger
+
+	checksumAlgorithm *mysql_proto.ChecksumAlgorithm_Type
+	checksumVerifiers map[mysql_proto.ChecksumAlgorithm_Type]ChecksumVerifier
+	verifyChecksum    bool
+	lenientChecksum   bool
+
+	// The verifier for the current FDE's (non-FDE) events.
+	checksumVerifier ChecksumVerifier
 }
 
 // This returns an EventReader which read events from a single (bin / relay)
@@ -54,21 +108,76 @@ type logFileV4EventReader struct {
 // reader will return the original event along with the error.  NOTE: this
 // reader is responsible for checking the log file magic marker, the binlog
 // format version and all format description events within the stream.  It is
-// also responsible for setting the checksum size for non-FDE events.
+// also responsible for setting the checksum size for non-FDE events.  The
+// stream may begin with an artificial rotate event (as sent by the master at
+// the beginning of a replication stream) followed by the format description
+// event.  Since the checksum algorithm is not yet known, the artificial
+// rotate event's checksum footer is only stripped when the checksum algorithm
+// is specified by the options, or (otherwise) when the event ends with a
+// matching crc32 checksum.  The returned reader implements
+// ResettableEventReader.
 func NewLogFileV4EventReader(
 	src io.Reader,
 	srcName string,
 	parsers V4EventParserMap,
 	logger Logger) EventReader {
 
-	rawReader := NewRawV4EventReader(src, srcName)
+	return NewLogFileV4EventReaderWithOptions(
+		src,
+		srcName,
+		parsers,
+		logger,
+		LogFileV4EventReaderOptions{})
+}
+
+// Same as NewLogFileV4EventReader, but with configurable options.
+func NewLogFileV4EventReaderWithOptions(
+	src io.Reader,
+	srcName string,
+	parsers V4EventParserMap,
+	logger Logger,
+	options LogFileV4EventReaderOptions) EventReader {
+
+	rawReader := NewRawV4EventReaderWithOptions(
+		src,
+		srcName,
+		options.RawV4EventReaderOptions)
+
+	verifiers := make(map[mysql_proto.ChecksumAlgorithm_Type]ChecksumVerifier)
+	for _, v := range []ChecksumVerifier{
+		NewNoneChecksumVerifier(),
+		NewCRC32ChecksumVerifier(),
+	} {
+		verifiers[v.Algorithm()] = v
+	}
+	for _, v := range options.ChecksumVerifiers {
+		verifiers[v.Algorithm()] = v
+	}
+
+	schemaCacheSize := options.SchemaCacheSize
+	if schemaCacheSize <= 0 {
+		schemaCacheSize = DefaultSchemaCacheSize
+	}
+
+	parsedReader := &parsedV4EventReader{
+		reader:           rawReader,
+		eventParsers:     parsers,
+		schemas:          NewSchemaCache(schemaCacheSize),
+		unknownEventMode: options.UnknownEventMode,
+		dmlOnly:          options.DMLOnly,
+	}
 
 	return &logFileV4EventReader{
-		reader:                      NewParsedV4EventReader(rawReader, parsers),
+		reader:                      parsedReader,
+		rawReader:                   rawReader.(*rawV4EventReader),
 		parsers:                     parsers,
 		passedMagicBytesCheck:       false,
 		passedLogFormatVersionCheck: false,
-		logger: logger,
+		logger:                      logger,
+		checksumAlgorithm:           options.ChecksumAlgorithm,
+		checksumVerifiers:           verifiers,
+		verifyChecksum:              options.VerifyChecksum,
+		lenientChecksum:             options.LenientChecksum,
 	}
 }
 
@@ -84,10 +193,37 @@ func (r *logFileV4EventReader) nextEventEndPosition() int64 {
 	return r.reader.nextEventEndPosition()
 }
 
+func (r *logFileV4EventReader) NextPosition() int64 {
+	return r.reader.NextPosition()
+}
+
 func (r *logFileV4EventReader) Close() error {
 	return r.reader.Close()
 }
 
+// See ResettableEventReader for documentation.  In addition to the raw
+// reader's state, this discards the format description event derived state
+// (i.e., the checksum size, the number of supported event types and whether
+// the stream is written by a MariaDB server) as well
+// as the cached table schemas, since the new stream may be written by a
+// different server.
+func (r *logFileV4EventReader) Reset(src io.Reader, srcName string) {
+	r.rawReader.Reset(src, srcName)
+
+	parsed := r.reader.(*parsedV4EventReader)
+	parsed.schemas.Clear()
+	parsed.mariadb = false
+
+	r.parsers.SetTableContext(nil)
+	r.parsers.SetChecksumSize(0)
+	r.parsers.SetNumSupportedEventTypes(
+		len(mysql_proto.LogEventType_Type_name))
+
+	r.passedMagicBytesCheck = false
+	r.passedLogFormatVersionCheck = false
+	r.checksumVerifier = nil
+}
+
 func (r *logFileV4EventReader) maybeCheckMagicBytes() error {
 	if r.passedMagicBytesCheck {
 		return nil
@@ -128,6 +264,24 @@ func (r *logFileV4EventReader) maybeCheckLogFormatVersion() error {
 		return err
 	}
 
+	if mysql_proto.LogEventType_Type(header.EventType) ==
+		mysql_proto.LogEventType_ROTATE_EVENT {
+
+		// A replication stream (i.e., binlog dump) begins with an artificial
+		// rotate event, which precedes the format description event.  The
+		// check is deferred to the next event.
+		headerBytes, err = r.peekHeaderBytes(sizeOfBasicV4EventHeader)
+		if err != nil {
+			return err
+		}
+
+		flags := EventFlags(LittleEndian.Uint16(
+			headerBytes[flagsOffsetInV4EventHeader:]))
+		if flags.IsArtificial() {
+			return nil
+		}
+	}
+
 	if version := header.version(); version != 4 {
 		return errors.Newf(
 			"Binary log reader does not support V%d binlog format",
@@ -138,6 +292,12 @@ func (r *logFileV4EventReader) maybeCheckLogFormatVersion() error {
 	return nil
 }
 
+func isMysqlGtidEventType(t mysql_proto.LogEventType_Type) bool {
+	return t == mysql_proto.LogEventType_GTID_LOG_EVENT ||
+		t == mysql_proto.LogEventType_ANONYMOUS_GTID_LOG_EVENT ||
+		t == mysql_proto.LogEventType_PREVIOUS_GTIDS_LOG_EVENT
+}
+
 func (r *logFileV4EventReader) checkFDE(fde *FormatDescriptionEvent) error {
 	if fde.BinlogVersion() != 4 {
 		return errors.Newf(
@@ -152,9 +312,7 @@ func (r *logFileV4EventReader) checkFDE(fde *FormatDescriptionEvent) error {
 	}
 
 	alg := fde.ChecksumAlgorithm()
-	if alg != mysql_proto.ChecksumAlgorithm_OFF &&
-		alg != mysql_proto.ChecksumAlgorithm_CRC32 {
-
+	if _, ok := r.checksumVerifiers[alg]; !ok {
 		return errors.Newf(
 			"Invalid checksum algorithm: %d (%s)",
 			alg,
@@ -165,7 +323,26 @@ func (r *logFileV4EventReader) checkFDE(fde *FormatDescriptionEvent) error {
 	for i := 0; i < fde.NumKnownEventTypes(); i++ {
 		t := mysql_proto.LogEventType_Type(i)
 
-		if t == mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT {
+		if fde.IsMariadb() && isMysqlGtidEventType(t) {
+			// MariaDB does not use mysql's gtid events (and does not
+			// necessarily report their sizes).
+			continue
+		}
+
+		if t == mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT &&
+			fde.IsMariadb() {
+
+			// The fde's post header covers all of MariaDB's event types.
+			expected := 2 + 50 + 4 + 1 + (fde.NumKnownEventTypes() - 1)
+			actual := fde.FixedLengthDataSizeForType(t)
+			if actual != expected {
+				errMsg += fmt.Sprintf(
+					"%s (expected: %d (MariaDB) actual: %d); ",
+					t.String(),
+					expected,
+					actual)
+			}
+		} else if t == mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT {
 			actual := fde.FixedLengthDataSizeForType(t)
 			if actual != FDEFixedLengthDataSizeFor56 &&
 				actual != FDEFixedLengthDataSizeFor55 {
@@ -185,6 +362,13 @@ func (r *logFileV4EventReader) checkFDE(fde *FormatDescriptionEvent) error {
 
 			expected := parser.FixedLengthDataSize()
 			actual := fde.FixedLengthDataSizeForType(t)
+			if t == mysql_proto.LogEventType_CREATE_FILE_EVENT {
+				// The create file event's post header is preceded by the
+				// load event's post header, which is not included in the
+				// fde's create file event size.
+				actual += fde.FixedLengthDataSizeForType(
+					mysql_proto.LogEventType_LOAD_EVENT)
+			}
 			if expected != actual {
 				errMsg += fmt.Sprintf(
 					"%s (expected: %d actual: %d); ",
@@ -220,14 +404,36 @@ func (r *logFileV4EventReader) NextEvent() (Event, error) {
 
 	fde, ok := event.(*FormatDescriptionEvent)
 	if !ok {
-		return event, nil // just return the non-FDE event
+		rotate, ok := event.(*RotateEvent)
+		if ok && r.checksumVerifier == nil && rotate.IsArtificial() {
+			return event, r.stripLeadingRotateChecksum(rotate)
+		}
+
+		// just return the non-FDE event
+		return event, r.maybeVerifyChecksum(event, r.checksumVerifier)
+	}
+
+	if fde.ChecksumAlgorithm() == mysql_proto.ChecksumAlgorithm_CRC32 {
+		// The FDE's checksum always uses the FDE's own algorithm.
+		err = r.maybeVerifyChecksum(
+			event,
+			r.checksumVerifiers[mysql_proto.ChecksumAlgorithm_CRC32])
+		if err != nil {
+			return event, err
+		}
+	}
+
+	alg := fde.ChecksumAlgorithm()
+	if r.checksumAlgorithm != nil {
+		alg = *r.checksumAlgorithm
 	}
 
 	// Always set checksum size, even when fde check fails.
 	// TODO(patrick): revisit this if it becomes an issue.
+	r.checksumVerifier = r.checksumVerifiers[alg]
 	checksumSize := 0
-	if fde.ChecksumAlgorithm() == mysql_proto.ChecksumAlgorithm_CRC32 {
-		checksumSize = 4
+	if r.checksumVerifier != nil {
+		checksumSize = r.checksumVerifier.Size()
 	}
 	r.logger.VerboseInfof("Setting event checksum size to %d", checksumSize)
 	r.parsers.SetChecksumSize(checksumSize)
@@ -237,5 +443,102 @@ func (r *logFileV4EventReader) NextEvent() (Event, error) {
 		fde.NumKnownEventTypes())
 	r.parsers.SetNumSupportedEventTypes(fde.NumKnownEventTypes())
 
-	return fde, r.checkFDE(fde)
+	err = r.checkFDE(fde)
+	if err != nil {
+		return fde, err
+	}
+
+	if r.checksumVerifier == nil {
+		return fde, errors.Newf(
+			"No verifier for checksum algorithm: %d (%s)",
+			alg,
+			alg.String())
+	}
+
+	return fde, nil
+}
+
+// The artificial rotate event preceding the first format description event
+// is parsed without checksum footer, since the checksum algorithm is not yet
+// known.  This strips the footer from the rotate event's new log name.
+func (r *logFileV4EventReader) stripLeadingRotateChecksum(
+	rotate *RotateEvent) error {
+
+	raw, ok := rotate.Event.(*RawV4Event)
+	if !ok {
+		return nil
+	}
+
+	var verifier ChecksumVerifier
+	if r.checksumAlgorithm != nil {
+		alg := *r.checksumAlgorithm
+		verifier = r.checksumVerifiers[alg]
+		if verifier == nil {
+			return errors.Newf(
+				"No verifier for checksum algorithm: %d (%s)",
+				alg,
+				alg.String())
+		}
+	} else {
+		// mysql's default checksum algorithm (since 5.6.6).
+		verifier = r.checksumVerifiers[mysql_proto.ChecksumAlgorithm_CRC32]
+	}
+
+	size := verifier.Size()
+	if size == 0 {
+		return nil
+	}
+	if len(raw.VariableLengthData()) < size {
+		if r.checksumAlgorithm == nil {
+			return nil
+		}
+		return errors.Newf(
+			"Artificial rotate event at %s:%d is too short for checksum",
+			raw.SourceName(),
+			raw.SourcePosition())
+	}
+
+	err := raw.SetChecksumSize(size)
+	if err != nil {
+		return err
+	}
+
+	if r.checksumAlgorithm == nil && verifier.Verify(raw) != nil {
+		// The event does not have a checksum footer.
+		return raw.SetChecksumSize(0)
+	}
+
+	rotate.newLogName = raw.VariableLengthData()
+	return r.maybeVerifyChecksum(rotate, verifier)
+}
+
+func (r *logFileV4EventReader) maybeVerifyChecksum(
+	event Event,
+	verifier ChecksumVerifier) error {
+
+	if !r.verifyChecksum || verifier == nil || verifier.Size() == 0 {
+		return nil
+	}
+
+	err := verifier.Verify(event)
+	if err != nil {
+		event.setChecksumStatus(ChecksumMismatch)
+		if r.lenientChecksum {
+			r.logger.VerboseInfof(
+				"Ignoring invalid checksum for event at %s:%d: %s",
+				event.SourceName(),
+				event.SourcePosition(),
+				errors.GetMessage(err))
+			return nil
+		}
+
+		return errors.Wrapf(
+			err,
+			"Invalid checksum for event at %s:%d",
+			event.SourceName(),
+			event.SourcePosition())
+	}
+
+	event.setChecksumStatus(ChecksumVerified)
+	return nil
 }
@@ -543,6 +543,106 @@ func (s *LogFileV4EventReaderSuite) Test56StreamWithChecksum(c *C) {
 	c.Check(s.parsers.Get(mysql_proto.LogEventType_WRITE_ROWS_EVENT), NotNil)
 }
 
+func (s *LogFileV4EventReaderSuite) TestNextPosition(c *C) {
+	s.checksumed = true
+
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteXidEvent()
+	s.WriteXidEvent()
+
+	data := append([]byte{}, s.src.Bytes()...)
+
+	c.Check(s.reader.NextPosition(), Equals, int64(0))
+
+	events := []Event{}
+	positions := []int64{}
+	for i := 0; i < 3; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+		events = append(events, event)
+		positions = append(positions, s.reader.NextPosition())
+	}
+
+	c.Check(events[0].SourcePosition(), Equals, int64(len(logFileMagic)))
+	for i := 0; i < 2; i++ {
+		// The checksum footer is stripped from the event's data, but not
+		// from the next position.
+		c.Check(len(events[i+1].Checksum()), Equals, 4)
+		c.Check(positions[i], Equals, events[i+1].SourcePosition())
+		c.Check(positions[i], Equals, NextReadPosition(events[i]))
+	}
+	c.Check(positions[2], Equals, int64(len(data)))
+
+	// Resuming from the checkpointed position yields the following event.
+	resumed, err := NewRawV4EventReader(
+		bytes.NewReader(data[positions[1]:]),
+		testSourceName).NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(resumed.Bytes(), DeepEquals, events[2].Bytes())
+
+	// The position does not move past a partially written event.
+	s.Write(data[positions[0] : positions[1]-1])
+	_, err = s.NextEvent()
+	c.Assert(err, NotNil)
+	c.Check(s.reader.NextPosition(), Equals, int64(len(data)))
+}
+
+func (s *LogFileV4EventReaderSuite) TestRetainRawBytes(c *C) {
+	s.reader = NewLogFileV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		s.parsers,
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		LogFileV4EventReaderOptions{
+			RawV4EventReaderOptions: RawV4EventReaderOptions{
+				RetainRawBytes: true,
+			},
+		})
+	s.checksumed = true
+
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteXidEvent()
+	s.WriteRotateEvent()
+
+	input := append([]byte{}, s.src.Bytes()[len(logFileMagic):]...)
+
+	var output []byte
+	for i := 0; i < 3; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+
+		raw := event.RawBytes()
+		c.Assert(len(raw), Equals, int(event.EventLength()))
+		c.Check(raw, DeepEquals, event.Bytes())
+
+		// The raw bytes do not share memory with the decoded event.
+		event.Bytes()[0] ^= 0xff
+		c.Check(raw, Not(DeepEquals), event.Bytes())
+
+		output = append(output, raw...)
+	}
+
+	c.Check(output, DeepEquals, input)
+}
+
+func (s *LogFileV4EventReaderSuite) TestRawBytesNotRetainedByDefault(c *C) {
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteXidEvent()
+
+	for i := 0; i < 2; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+		c.Check(event.RawBytes(), IsNil)
+	}
+}
+
 func (s *LogFileV4EventReaderSuite) Test56RelayStreamFrom55Master(c *C) {
 	s.WriteLogFileMagic()
 	s.Write55Master56FDE()
@@ -635,3 +735,252 @@ func (s *LogFileV4EventReaderSuite) TestClose(c *C) {
 	c.Assert(event, IsNil)
 	c.Assert(err, NotNil)
 }
+
+func (s *LogFileV4EventReaderSuite) WriteQueryEvent(query string) {
+	data := []byte{
+		// thread id
+		1, 0, 0, 0,
+		// duration
+		0, 0, 0, 0,
+		// db name length
+		4,
+		// error code
+		0, 0,
+		// status length
+		0, 0,
+		// db name
+		't', 'e', 's', 't', 0}
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_QUERY_EVENT,
+		append(data, []byte(query)...))
+}
+
+func (s *LogFileV4EventReaderSuite) WriteTableMapAndRowsEvents() {
+	s.WriteTableMapEvent()
+	s.WriteRowsEvent()
+}
+
+func (s *LogFileV4EventReaderSuite) WriteTableMapEvent() {
+	s.WriteEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			1, 0,
+			// db name length
+			4,
+			// db name
+			't', 'e', 's', 't', 0,
+			// table name length
+			1,
+			// table name
+			't', 0,
+			// number of columns
+			2,
+			// column types (long, short)
+			3, 2,
+			// metadata size
+			0,
+			// null bits
+			2})
+}
+
+func (s *LogFileV4EventReaderSuite) WriteRowsEvent() {
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			0, 0,
+			// number of columns
+			2,
+			// used columns
+			3,
+			// row: null bits, long, short
+			0, 1, 0, 0, 0, 2, 0})
+}
+
+func (s *LogFileV4EventReaderSuite) TestDMLOnly(c *C) {
+	s.reader = NewLogFileV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		s.parsers,
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		LogFileV4EventReaderOptions{DMLOnly: true})
+	s.checksumed = true
+
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteQueryEvent("CREATE TABLE t (a INT, b SMALLINT)")
+	s.WriteQueryEvent("BEGIN")
+	s.WriteTableMapAndRowsEvents()
+	s.WriteXidEvent()
+	s.WriteQueryEvent("ALTER TABLE t ADD COLUMN c INT")
+	s.WriteQueryEvent("DROP TABLE t")
+	s.WriteQueryEvent("begin")
+	s.WriteQueryEvent("INSERT INTO t VALUES (1, 2)")
+	s.WriteQueryEvent(" COMMIT")
+	s.WriteRotateEvent()
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	q, ok := event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, "BEGIN")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*TableMapEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	rows, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rows.InsertedRows(), DeepEquals, []RowValues{
+		{uint64(1), uint64(2)},
+	})
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*XidEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	q, ok = event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, "begin")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	q, ok = event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, " COMMIT")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*RotateEvent)
+	c.Check(ok, IsTrue)
+
+	_, err = s.NextEvent()
+	c.Check(err, NotNil)
+}
+
+func (s *LogFileV4EventReaderSuite) TestDDLNotDroppedByDefault(c *C) {
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteQueryEvent("CREATE TABLE t (a INT, b SMALLINT)")
+
+	_, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	q, ok := event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, "CREATE TABLE t (a INT, b SMALLINT)")
+}
+
+func (s *LogFileV4EventReaderSuite) TestReset(c *C) {
+	s.checksumed = true
+
+	s.WriteLogFileMagic()
+	s.Write56FDE()
+	s.WriteTableMapAndRowsEvents()
+	s.WriteXidEvent()
+
+	// Abandon the first file in the middle of the rows event.
+	for i := 0; i < 2; i++ {
+		_, err := s.NextEvent()
+		c.Assert(err, IsNil)
+	}
+
+	reader, ok := s.reader.(ResettableEventReader)
+	c.Assert(ok, IsTrue)
+
+	s.src = &bytes.Buffer{}
+	s.checksumed = false
+	reader.Reset(s.src, "second-file")
+
+	s.WriteLogFileMagic()
+	s.Write55FDE()
+	s.WriteXidEvent()
+	s.WriteTableMapAndRowsEvents()
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+	c.Check(event.SourceName(), Equals, "second-file")
+	c.Check(event.SourcePosition(), Equals, int64(len(logFileMagic)))
+
+	// The first file's checksum size is not carried over.
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*XidEvent)
+	c.Check(ok, IsTrue)
+	c.Check(event.Checksum(), DeepEquals, []byte{})
+	c.Check(event.SourceName(), Equals, "second-file")
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = event.(*TableMapEvent)
+	c.Check(ok, IsTrue)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	rows, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rows.InsertedRows(), DeepEquals, []RowValues{
+		{uint64(1), uint64(2)},
+	})
+
+	_, err = s.NextEvent()
+	c.Check(err, Equals, io.EOF)
+
+	c.Check(s.parsers.Get(mysql_proto.LogEventType_WRITE_ROWS_EVENT), IsNil)
+}
+
+func (s *LogFileV4EventReaderSuite) TestResetClearsTableSchemas(c *C) {
+	s.WriteLogFileMagic()
+	s.Write55FDE()
+	s.WriteTableMapAndRowsEvents()
+
+	for i := 0; i < 3; i++ {
+		_, err := s.NextEvent()
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(s.reader.Close(), IsNil)
+
+	s.src = &bytes.Buffer{}
+	s.reader.(ResettableEventReader).Reset(s.src, testSourceName)
+
+	s.WriteLogFileMagic()
+	s.Write55FDE()
+	s.WriteRowsEvent()
+
+	_, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	// The rows event is not decodable without its table map event.
+	event, err := s.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := err.(*TableContextNotSetError)
+	c.Check(ok, IsTrue)
+	_, ok = event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+}
@@ -0,0 +1,257 @@
+package binlog
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// This returns the log file paths listed in a (bin / relay) log index file,
+// in order.  Relative paths are resolved against the index file's directory.
+func ReadLogIndexFile(indexPath string) ([]string, error) {
+	indexFile, err := os.Open(indexPath)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to open log index file: %s",
+			indexPath)
+	}
+	defer indexFile.Close()
+
+	return parseLogIndex(indexFile, path.Dir(indexPath))
+}
+
+func parseLogIndex(src io.Reader, indexDirectory string) ([]string, error) {
+	logPaths := []string{}
+
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" {
+			continue
+		}
+
+		if !path.IsAbs(entry) {
+			entry = path.Join(indexDirectory, entry)
+		}
+		logPaths = append(logPaths, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read log index")
+	}
+
+	return logPaths, nil
+}
+
+type logIndexV4EventReader struct {
+	isClosed bool
+
+	logPaths     []string
+	nextLogIndex int
+
+	reader  EventReader
+	parsers V4EventParserMap
+
+	// The current (or, after rotation, the previous) log file reader's
+	// NextPosition as of the most recently returned event.
+	nextPosition int64
+
+	// The log file name specified by the last rotate event.
+	rotateLogName string
+
+	newLogFileReader LogFileReaderCreator
+
+	logger Logger
+}
+
+// This returns an EventReader which reads and parses events from the
+// (bin) log files listed in the log index file, as a single continuous
+// stream.  The reader transparently switches to the next listed log file
+// when the current log file ends with a rotate (or stop) event.  The reader
+// validates each log file's magic bytes, and returns an *InvalidRotationError
+// when the rotate event does not point to the next listed log file, or when
+// a log file (other than the last listed file) ends without rotating.  The
+// reader returns io.EOF once the last listed log file is exhausted.
+func NewLogIndexV4EventReader(
+	indexPath string,
+	logger Logger) (EventReader, error) {
+
+	logPaths, err := ReadLogIndexFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	openLogReader := func(
+		dir string,
+		file string,
+		parsers V4EventParserMap) (
+		EventReader,
+		error) {
+
+		filePath := path.Join(dir, file)
+		logFile, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return &closingEventReader{
+			EventReader: NewLogFileV4EventReader(
+				logFile,
+				filePath,
+				parsers,
+				logger),
+			closer: logFile,
+		}, nil
+	}
+
+	return NewLogIndexV4EventReaderWithLogFileReaderCreator(
+		logPaths,
+		logger,
+		openLogReader), nil
+}
+
+// Same as NewLogIndexV4EventReader, but reads the given log file paths (e.g.,
+// as returned by ReadLogIndexFile) using the log file reader creator.
+func NewLogIndexV4EventReaderWithLogFileReaderCreator(
+	logPaths []string,
+	logger Logger,
+	newLogFileReader LogFileReaderCreator) EventReader {
+
+	return &logIndexV4EventReader{
+		logPaths:         logPaths,
+		parsers:          NewV4EventParserMap(),
+		newLogFileReader: newLogFileReader,
+		logger:           logger,
+	}
+}
+
+func (r *logIndexV4EventReader) getLogFileReader() (EventReader, error) {
+	if r.isClosed {
+		return nil, errors.New("reader is closed")
+	}
+
+	if r.reader != nil {
+		return r.reader, nil
+	}
+
+	if r.nextLogIndex >= len(r.logPaths) {
+		return nil, io.EOF
+	}
+
+	logPath := r.logPaths[r.nextLogIndex]
+
+	if r.rotateLogName != "" && r.rotateLogName != path.Base(logPath) {
+		return nil, &InvalidRotationError{
+			errors.Newf(
+				"Invalid log rotation.  Rotate event log file %s does not "+
+					"match the next indexed log file: %s",
+				r.rotateLogName,
+				logPath),
+		}
+	}
+
+	r.logger.Infof("Opening log file: %s", logPath)
+
+	reader, err := r.newLogFileReader(
+		path.Dir(logPath),
+		path.Base(logPath),
+		r.parsers)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open file: %s", logPath)
+	}
+
+	r.reader = reader
+	return reader, nil
+}
+
+func (r *logIndexV4EventReader) peekHeaderBytes(numBytes int) ([]byte, error) {
+	reader, err := r.getLogFileReader()
+	if err != nil {
+		return nil, err
+	}
+	return reader.peekHeaderBytes(numBytes)
+}
+
+func (r *logIndexV4EventReader) consumeHeaderBytes(numBytes int) error {
+	reader, err := r.getLogFileReader()
+	if err != nil {
+		return err
+	}
+	return reader.consumeHeaderBytes(numBytes)
+}
+
+func (r *logIndexV4EventReader) NextPosition() int64 {
+	return r.nextPosition
+}
+
+func (r *logIndexV4EventReader) nextEventEndPosition() int64 {
+	r.logger.Fatalf(
+		"nextEventEndPosition is invalid for logIndexV4EventReader")
+	return -1
+}
+
+func (r *logIndexV4EventReader) closeLogFile() error {
+	err := r.reader.Close()
+	r.reader = nil
+	r.nextLogIndex++
+	return err
+}
+
+func (r *logIndexV4EventReader) NextEvent() (Event, error) {
+	reader, err := r.getLogFileReader()
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := reader.NextEvent()
+	if event != nil {
+		r.nextPosition = reader.NextPosition()
+	}
+	if err == io.EOF && event == nil {
+		if r.nextLogIndex == len(r.logPaths)-1 {
+			return nil, io.EOF
+		}
+
+		return nil, &InvalidRotationError{
+			errors.Newf(
+				"Invalid log rotation.  Log file %s ended without a rotate "+
+					"or stop event",
+				r.logPaths[r.nextLogIndex]),
+		}
+	}
+	if err != nil {
+		return event, err
+	}
+
+	switch e := event.(type) {
+	case *RotateEvent:
+		r.rotateLogName = string(e.NewLogName())
+	case *StopEvent:
+		r.rotateLogName = ""
+	default:
+		return event, nil
+	}
+
+	r.logger.Infof(
+		"Reached end of log file %s",
+		r.logPaths[r.nextLogIndex])
+
+	return event, r.closeLogFile()
+}
+
+func (r *logIndexV4EventReader) Close() error {
+	r.isClosed = true
+
+	if r.reader == nil {
+		return nil
+	}
+
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
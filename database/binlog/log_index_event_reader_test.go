@@ -0,0 +1,226 @@
+package binlog
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type LogIndexV4EventReaderSuite struct {
+	dir string
+}
+
+var _ = Suite(&LogIndexV4EventReaderSuite{})
+
+func (s *LogIndexV4EventReaderSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "binlog_index_test")
+	c.Assert(err, IsNil)
+}
+
+func (s *LogIndexV4EventReaderSuite) TearDownTest(c *C) {
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *LogIndexV4EventReaderSuite) WriteFile(
+	c *C,
+	name string,
+	data []byte) {
+
+	err := ioutil.WriteFile(path.Join(s.dir, name), data, 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *LogIndexV4EventReaderSuite) WriteIndex(c *C, entries ...string) {
+	s.WriteFile(c, "bin.index", []byte(strings.Join(entries, "\n")+"\n"))
+}
+
+func (s *LogIndexV4EventReaderSuite) NewReader(c *C) EventReader {
+	reader, err := NewLogIndexV4EventReader(
+		path.Join(s.dir, "bin.index"),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+	c.Assert(err, IsNil)
+	return reader
+}
+
+func newTestLogFile() *MockLogFile {
+	f := NewMockLogFile()
+	f.WriteLogFileMagic()
+	f.WriteFDE()
+	return f
+}
+
+func (s *LogIndexV4EventReaderSuite) TestReadLogIndexFile(c *C) {
+	s.WriteIndex(c, "./bin.000001", "", "  /abs/bin.000002  ")
+
+	logPaths, err := ReadLogIndexFile(path.Join(s.dir, "bin.index"))
+	c.Assert(err, IsNil)
+	c.Check(
+		logPaths,
+		DeepEquals,
+		[]string{path.Join(s.dir, "bin.000001"), "/abs/bin.000002"})
+
+	_, err = ReadLogIndexFile(path.Join(s.dir, "missing.index"))
+	c.Check(err, NotNil)
+}
+
+func (s *LogIndexV4EventReaderSuite) TestTwoFileIndex(c *C) {
+	f1 := newTestLogFile()
+	f1.WriteXid(1)
+	f1.WriteRotate(testBinPrefix, 2)
+
+	f2 := newTestLogFile()
+	f2.WriteXid(2)
+
+	s.WriteFile(c, logName(testBinPrefix, 1), f1.logBuffer)
+	s.WriteFile(c, logName(testBinPrefix, 2), f2.logBuffer)
+	s.WriteIndex(
+		c,
+		"./"+logName(testBinPrefix, 1),
+		"./"+logName(testBinPrefix, 2))
+
+	reader := s.NewReader(c)
+	defer reader.Close()
+
+	Next := func() Event {
+		e, err := reader.NextEvent()
+		c.Assert(err, IsNil)
+		c.Assert(e, NotNil)
+		return e
+	}
+
+	_, ok := Next().(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	x, ok := Next().(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(1))
+
+	r, ok := Next().(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(r.NewLogName()), Equals, logName(testBinPrefix, 2))
+
+	_, ok = Next().(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	x, ok = Next().(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(2))
+
+	e, err := reader.NextEvent()
+	c.Check(e, IsNil)
+	c.Check(err, Equals, io.EOF)
+}
+
+func (s *LogIndexV4EventReaderSuite) TestStopEvent(c *C) {
+	f1 := newTestLogFile()
+	f1.WriteStop()
+
+	f2 := newTestLogFile()
+
+	s.WriteFile(c, logName(testBinPrefix, 1), f1.logBuffer)
+	s.WriteFile(c, logName(testBinPrefix, 2), f2.logBuffer)
+	s.WriteIndex(c, logName(testBinPrefix, 1), logName(testBinPrefix, 2))
+
+	reader := s.NewReader(c)
+	defer reader.Close()
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	e, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := e.(*StopEvent)
+	c.Check(ok, IsTrue)
+
+	e, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok = e.(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+
+	_, err = reader.NextEvent()
+	c.Check(err, Equals, io.EOF)
+}
+
+func (s *LogIndexV4EventReaderSuite) TestRotateMismatch(c *C) {
+	f1 := newTestLogFile()
+	f1.WriteRotate(testBinPrefix, 3)
+
+	f2 := newTestLogFile()
+
+	s.WriteFile(c, logName(testBinPrefix, 1), f1.logBuffer)
+	s.WriteFile(c, logName(testBinPrefix, 2), f2.logBuffer)
+	s.WriteIndex(c, logName(testBinPrefix, 1), logName(testBinPrefix, 2))
+
+	reader := s.NewReader(c)
+	defer reader.Close()
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	_, err = reader.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := err.(*InvalidRotationError)
+	c.Check(ok, IsTrue)
+}
+
+func (s *LogIndexV4EventReaderSuite) TestMissingRotate(c *C) {
+	f1 := newTestLogFile()
+	f1.WriteXid(1)
+
+	f2 := newTestLogFile()
+
+	s.WriteFile(c, logName(testBinPrefix, 1), f1.logBuffer)
+	s.WriteFile(c, logName(testBinPrefix, 2), f2.logBuffer)
+	s.WriteIndex(c, logName(testBinPrefix, 1), logName(testBinPrefix, 2))
+
+	reader := s.NewReader(c)
+	defer reader.Close()
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	_, err = reader.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := err.(*InvalidRotationError)
+	c.Check(ok, IsTrue)
+}
+
+func (s *LogIndexV4EventReaderSuite) TestInvalidMagic(c *C) {
+	f1 := newTestLogFile()
+	f1.WriteRotate(testBinPrefix, 2)
+
+	f2 := NewMockLogFile()
+	f2.Write([]byte("asdf"))
+	f2.WriteFDE()
+
+	s.WriteFile(c, logName(testBinPrefix, 1), f1.logBuffer)
+	s.WriteFile(c, logName(testBinPrefix, 2), f2.logBuffer)
+	s.WriteIndex(c, logName(testBinPrefix, 1), logName(testBinPrefix, 2))
+
+	reader := s.NewReader(c)
+	defer reader.Close()
+
+	_, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	_, err = reader.NextEvent()
+	c.Check(err, NotNil)
+}
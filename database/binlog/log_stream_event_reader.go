@@ -36,6 +36,10 @@ type logStreamV4EventReader struct {
 	reader  EventReader
 	parsers V4EventParserMap
 
+	// The current (or, after rotation, the previous) log file reader's
+	// NextPosition as of the most recently returned event.
+	nextPosition int64
+
 	newLogFileReader LogFileReaderCreator
 
 	logger Logger
@@ -49,7 +53,9 @@ type logStreamV4EventReader struct {
 // are not returned).  When the reader fails to open a log file, it will return
 // a *FailedToOpenFileError; it is safe to retry reading, assuming the filename
 // is valid.  When the reader encounters an invalid rotate event, it will
-// return both the rotate event and an *InvalidRotationError.
+// return both the rotate event and an *InvalidRotationError.  Artificial
+// rotate events (see RotateEvent.IsArtificial) are returned as is, without
+// switching log files.
 func NewLogStreamV4EventReader(
 	logDirectory string,
 	logPrefix string,
@@ -156,6 +162,10 @@ func (r *logStreamV4EventReader) consumeHeaderBytes(numBytes int) error {
 	return reader.consumeHeaderBytes(numBytes)
 }
 
+func (r *logStreamV4EventReader) NextPosition() int64 {
+	return r.nextPosition
+}
+
 func (r *logStreamV4EventReader) nextEventEndPosition() int64 {
 	r.logger.Fatalf(
 		"nextEventEndPosition is invalid for logStreamV4EventReader")
@@ -171,6 +181,9 @@ func (r *logStreamV4EventReader) NextEvent() (Event, error) {
 	}
 
 	event, err := reader.NextEvent()
+	if event != nil {
+		r.nextPosition = reader.NextPosition()
+	}
 	if err != nil {
 		return event, err
 	}
@@ -196,6 +209,18 @@ func (r *logStreamV4EventReader) NextEvent() (Event, error) {
 		return event, nil
 	}
 
+	if isRotate && rotate.IsArtificial() {
+		// Artificial rotate events only announce the master's current log
+		// file; they do not terminate the current log file.
+		r.logger.VerboseInfof(
+			"Ignored artificial rotate event. "+
+				"Rotate event log file: %s (current file: %s%06d)",
+			string(rotate.NewLogName()),
+			r.logPrefix,
+			r.nextLogFileNum)
+		return event, nil
+	}
+
 	nextFileNum := -1
 	if isRotate {
 		// In case of rotate event we can do extra verification
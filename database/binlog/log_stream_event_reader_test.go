@@ -592,3 +592,64 @@ func (s *LogStreamV4EventReaderSuite) TestRelayLog(c *C) {
 	c.Check(x.Xid(), Equals, uint64(300))
 
 }
+
+func (s *LogStreamV4EventReaderSuite) TestArtificialRotate(c *C) {
+	prefix := testBinPrefix
+
+	// The artificial rotate event announces the current log file, which
+	// must not be treated as a log rotation.
+	f0 := s.GetFile(prefix, 0)
+	f0.WriteArtificialRotate(prefix, 0)
+	f0.WriteXid(0)
+	f0.WriteArtificialRotate(prefix, 7)
+	f0.WriteXid(1)
+	f0.WriteRotate(prefix, 1)
+
+	f1 := s.GetFile(prefix, 1)
+	f1.WriteXid(2)
+
+	stream := s.NewStream(prefix, 0)
+
+	Next := func() Event {
+		e, err := stream.NextEvent()
+		c.Assert(err, IsNil)
+		c.Assert(e, NotNil)
+		return e
+	}
+
+	_, ok := Next().(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+
+	r, ok := Next().(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(r.IsArtificial(), IsTrue)
+	c.Check(string(r.NewLogName()), Equals, logName(prefix, 0))
+
+	x, ok := Next().(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(0))
+
+	r, ok = Next().(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(r.IsArtificial(), IsTrue)
+
+	x, ok = Next().(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(1))
+
+	r, ok = Next().(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(r.IsArtificial(), IsFalse)
+	c.Check(string(r.NewLogName()), Equals, logName(prefix, 1))
+
+	_, ok = Next().(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+
+	x, ok = Next().(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, uint64(2))
+
+	e, err := stream.NextEvent()
+	c.Assert(e, IsNil)
+	c.Check(err, Equals, io.EOF)
+}
@@ -0,0 +1,76 @@
+package binlog
+
+import (
+	"fmt"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// LossyConversion describes a column value which could not be decoded
+// losslessly with the chosen decode options, e.g., an invalid DATETIME2
+// value decoded as nil (see DecodeOptions.LenientDateTime).
+type LossyConversion struct {
+	// The rows event's source name / position.
+	SourceName     string
+	SourcePosition int64
+
+	TableId      uint64
+	DatabaseName string
+	TableName    string
+
+	// The row's index within the rows event (for update rows events, the
+	// index of the before / after image pair).
+	RowIndex int
+
+	// The column's table index position, and field type.
+	ColumnIndex int
+	FieldType   mysql_proto.FieldType_Type
+
+	// Why the value was not decoded losslessly.
+	Reason string
+}
+
+func (c *LossyConversion) String() string {
+	return fmt.Sprintf(
+		"%s:%d %s.%s (table id %d) row %d column %d (%s): %s",
+		c.SourceName,
+		c.SourcePosition,
+		c.DatabaseName,
+		c.TableName,
+		c.TableId,
+		c.RowIndex,
+		c.ColumnIndex,
+		c.FieldType.String(),
+		c.Reason)
+}
+
+// LossyConversionHandler is invoked synchronously by the rows parsers for
+// each lossily decoded value.  See DecodeOptions.OnLossyConversion.
+type LossyConversionHandler func(conversion *LossyConversion)
+
+// lossyFieldDescriptor is implemented by field descriptors which may decode
+// values lossily.
+type lossyFieldDescriptor interface {
+	// Same as ParseValue, but also returns the reason the value was decoded
+	// lossily (or "" if the value was decoded losslessly).
+	parseValueWithLoss(data []byte) (
+		value interface{},
+		remaining []byte,
+		loss string,
+		err error)
+}
+
+// This returns the descriptor's lossyFieldDescriptor implementation (if
+// any).  Column descriptors are unwrapped.
+func asLossyFieldDescriptor(fd FieldDescriptor) (lossyFieldDescriptor, bool) {
+	if cd, ok := fd.(*columnDescriptorImpl); ok {
+		fd = cd.FieldDescriptor
+	}
+
+	lossy, ok := fd.(lossyFieldDescriptor)
+	return lossy, ok
+}
+
+type lossyConversionHandlerSetter interface {
+	SetLossyConversionHandler(handler LossyConversionHandler)
+}
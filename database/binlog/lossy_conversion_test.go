@@ -0,0 +1,139 @@
+package binlog
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type LossyConversionSuite struct {
+	EventParserSuite
+
+	conversions []*LossyConversion
+}
+
+var _ = Suite(&LossyConversionSuite{})
+
+func (s *LossyConversionSuite) SetUpTest(c *C) {
+	s.EventParserSuite.SetUpTest(c)
+	s.conversions = nil
+}
+
+func (s *LossyConversionSuite) useParsers(c *C, options DecodeOptions) {
+	datetime, _, err := NewDateTime2FieldDescriptorWithOptions(
+		true,
+		[]byte{0},
+		DecodeOptions{LenientDateTime: true})
+	c.Assert(err, IsNil)
+
+	s.parsers = NewV4EventParserMapWithOptions(options)
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+	s.parsers.SetTableContext(&testTableContext{
+		columns: []ColumnDescriptor{
+			NewColumnDescriptor(datetime, 0),
+			NewColumnDescriptor(
+				&driverValueFieldDescriptor{
+					FieldDescriptor: NewLongLongFieldDescriptor(true),
+				},
+				1),
+		},
+	})
+}
+
+func (s *LossyConversionSuite) writeRows() {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{
+		// table id
+		testRowsTableId, 0, 0, 0, 0, 0,
+		// flags
+		0, 0,
+		// # of columns
+		2,
+		// used columns
+		3,
+	})
+
+	// row 0: datetime2 (lossy), longlong (lossless)
+	buf.WriteByte(0)
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	buf.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+
+	// row 1: datetime2 (lossless), longlong (lossless, beyond MaxInt64)
+	buf.WriteByte(0)
+	buf.Write(testDateTime2Bytes())
+	buf.Write([]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	// row 2: datetime2 (null), longlong (lossless, beyond MaxInt64)
+	buf.WriteByte(1)
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		uint16(0),
+		buf.Bytes())
+}
+
+func (s *LossyConversionSuite) TestReportLossyConversions(c *C) {
+	s.useParsers(c, DecodeOptions{
+		OnLossyConversion: func(conversion *LossyConversion) {
+			s.conversions = append(s.conversions, conversion)
+		},
+	})
+	s.writeRows()
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(rows, HasLen, 3)
+	c.Check(rows[0], DeepEquals, RowValues{nil, int64(1)})
+	c.Check(
+		rows[1],
+		DeepEquals,
+		RowValues{
+			time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC),
+			"18446744073709551614",
+		})
+	c.Check(rows[2], DeepEquals, RowValues{nil, "18446744073709551615"})
+
+	// Unsigned BIGINT values beyond MaxInt64 are converted to strings
+	// losslessly.
+	c.Assert(s.conversions, HasLen, 1)
+
+	c.Check(s.conversions[0], DeepEquals, &LossyConversion{
+		SourceName:     testSourceName,
+		SourcePosition: 0,
+		TableId:        testRowsTableId,
+		DatabaseName:   "database",
+		TableName:      "table",
+		RowIndex:       0,
+		ColumnIndex:    0,
+		FieldType:      mysql_proto.FieldType_DATETIME2,
+		Reason: "Invalid datetime2 value: 10082-05-31 " +
+			"31:63:63 (decoded as NULL)",
+	})
+
+	c.Check(
+		s.conversions[0].String(),
+		Equals,
+		testSourceName+":0 database.table (table id 42) row 0 column 0 "+
+			"(DATETIME2): Invalid datetime2 value: 10082-05-31 31:63:63 "+
+			"(decoded as NULL)")
+}
+
+func (s *LossyConversionSuite) TestNotReportedByDefault(c *C) {
+	s.useParsers(c, DecodeOptions{})
+	s.writeRows()
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	// The values are decoded the same way.
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(rows, HasLen, 3)
+	c.Check(rows[0], DeepEquals, RowValues{nil, int64(1)})
+	c.Check(rows[2], DeepEquals, RowValues{nil, "18446744073709551615"})
+}
@@ -0,0 +1,212 @@
+package binlog
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// MariaDB specific event types.  MariaDB allocates its event type codes
+// starting at 160 (i.e., these are not part of mysql_proto.LogEventType).
+// The parsed event reader only treats these as known event types (and
+// dispatches them to their parsers) after reading a format description event
+// written by a MariaDB server.  In other streams (or before the first format
+// description event), these are handled as unknown event types (see
+// UnknownEventMode).
+const (
+	MariadbAnnotateRowsEvent     = mysql_proto.LogEventType_Type(160)
+	MariadbBinlogCheckpointEvent = mysql_proto.LogEventType_Type(161)
+	MariadbGtidEvent             = mysql_proto.LogEventType_Type(162)
+	MariadbGtidListEvent         = mysql_proto.LogEventType_Type(163)
+)
+
+// This returns true if the event type is a MariaDB specific event type
+// handled by this package.
+func isMariadbEventType(t mysql_proto.LogEventType_Type) bool {
+	return t >= MariadbAnnotateRowsEvent && t <= MariadbGtidListEvent
+}
+
+// This returns true if the server version (as reported by the format
+// description event) belongs to a MariaDB server, e.g., "10.5.8-MariaDB-log".
+func IsMariadbServerVersion(serverVersion []byte) bool {
+	return bytes.Contains(
+		bytes.ToLower(serverVersion),
+		[]byte("mariadb"))
+}
+
+//
+// MariadbGtidLogEvent --------------------------------------------------------
+//
+
+const (
+	// The transaction is a single statement without BEGIN / COMMIT (e.g.,
+	// DDL).
+	mariadbGtidFlagStandalone = 0x01
+
+	// The event includes the group commit id.
+	mariadbGtidFlagGroupCommitId = 0x02
+)
+
+// MariadbGtid is a MariaDB global transaction id, which is formatted as
+// <domain id>-<server id>-<sequence number>.
+type MariadbGtid struct {
+	DomainId uint32
+	ServerId uint32
+	SeqNo    uint64
+}
+
+func (g MariadbGtid) String() string {
+	return fmt.Sprintf("%d-%d-%d", g.DomainId, g.ServerId, g.SeqNo)
+}
+
+// A representation of the MariaDB GTID event.  Unlike MySQL, MariaDB does not
+// log a BEGIN query event after the GTID event; the GTID event itself starts
+// the transaction.
+//
+// MariaDB GTID event's binlog payload is structured as follows:
+//
+//  MariaDB 10.0+:
+//      19 bytes for common v4 event header
+//      8 bytes for sequence number
+//      4 bytes for domain id
+//      1 byte for flags
+//      8 bytes for commit id (when the group commit id flag is set), or
+//          6 bytes of padding (otherwise).
+//      (optional) 4 bytes footer for checksum.
+type MariadbGtidLogEvent struct {
+	Event
+
+	gtid     MariadbGtid
+	flags    uint8
+	commitId *uint64
+}
+
+// Gtid returns the transaction's gtid.  The server id is taken from the event
+// header.
+func (e *MariadbGtidLogEvent) Gtid() MariadbGtid {
+	return e.gtid
+}
+
+// GtidFlags returns the gtid event specific flags.
+func (e *MariadbGtidLogEvent) GtidFlags() uint8 {
+	return e.flags
+}
+
+// IsStandalone returns true if the transaction is a single statement which is
+// not wrapped by BEGIN / COMMIT (e.g., DDL).
+func (e *MariadbGtidLogEvent) IsStandalone() bool {
+	return e.flags&mariadbGtidFlagStandalone != 0
+}
+
+// CommitId returns the transaction's group commit id.  This is nil when the
+// transaction was not group committed.
+func (e *MariadbGtidLogEvent) CommitId() *uint64 {
+	return e.commitId
+}
+
+type MariadbGtidLogEventParser struct {
+	hasNoTableContext
+}
+
+// MariadbGtidLogEventParser's EventType always returns MariadbGtidEvent.
+func (p *MariadbGtidLogEventParser) EventType() mysql_proto.LogEventType_Type {
+	return MariadbGtidEvent
+}
+
+// MariadbGtidLogEventParser's FixedLengthDataSize always returns 19.
+func (p *MariadbGtidLogEventParser) FixedLengthDataSize() int {
+	return 19
+}
+
+// MariadbGtidLogEventParser's Parse processes a raw MariaDB gtid event into a
+// MariadbGtidLogEvent.
+func (p *MariadbGtidLogEventParser) Parse(raw *RawV4Event) (Event, error) {
+	gle := &MariadbGtidLogEvent{
+		Event: raw,
+	}
+
+	gle.gtid.ServerId = raw.ServerId()
+
+	// The commit id straddles the fixed length data and the variable length
+	// data.
+	data := make([]byte, 0, len(raw.FixedLengthData())+2)
+	data = append(data, raw.FixedLengthData()...)
+	data = append(data, raw.VariableLengthData()...)
+
+	data, err := readLittleEndian(data, &gle.gtid.SeqNo)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read sequence number")
+	}
+
+	data, err = readLittleEndian(data, &gle.gtid.DomainId)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read domain id")
+	}
+
+	data, err = readLittleEndian(data, &gle.flags)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to read flags")
+	}
+
+	if gle.flags&mariadbGtidFlagGroupCommitId != 0 {
+		commitId := uint64(0)
+		_, err = readLittleEndian(data, &commitId)
+		if err != nil {
+			return raw, errors.Wrap(err, "Failed to read commit id")
+		}
+		gle.commitId = &commitId
+	}
+
+	return gle, nil
+}
+
+//
+// MariadbAnnotateRowsEvent ---------------------------------------------------
+//
+
+// A representation of the MariaDB annotate rows event, which is MariaDB's
+// equivalent of the rows-query event (i.e., the statement which generated
+// the following rows events).  Annotate rows events are only logged when
+// binlog_annotate_row_events is enabled.
+//
+// MariaDB annotate rows event's binlog payload is structured as follows:
+//
+//      19 bytes for common v4 event header
+//      the remaining is for the query (not zero terminated).
+//      (optional) 4 bytes footer for checksum.
+type MariadbAnnotateRowsLogEvent struct {
+	Event
+
+	query []byte
+}
+
+// Query returns the statement which generated the following rows events.
+func (e *MariadbAnnotateRowsLogEvent) Query() []byte {
+	return e.query
+}
+
+type MariadbAnnotateRowsEventParser struct {
+	hasNoTableContext
+}
+
+// MariadbAnnotateRowsEventParser's EventType always returns
+// MariadbAnnotateRowsEvent.
+func (p *MariadbAnnotateRowsEventParser) EventType() mysql_proto.LogEventType_Type {
+	return MariadbAnnotateRowsEvent
+}
+
+// MariadbAnnotateRowsEventParser's FixedLengthDataSize always returns 0.
+func (p *MariadbAnnotateRowsEventParser) FixedLengthDataSize() int {
+	return 0
+}
+
+// MariadbAnnotateRowsEventParser's Parse processes a raw annotate rows event
+// into a MariadbAnnotateRowsLogEvent.
+func (p *MariadbAnnotateRowsEventParser) Parse(raw *RawV4Event) (Event, error) {
+	return &MariadbAnnotateRowsLogEvent{
+		Event: raw,
+		query: raw.VariableLengthData(),
+	}, nil
+}
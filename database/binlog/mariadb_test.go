@@ -0,0 +1,282 @@
+package binlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// TODO: The MariaDB events below (and testdata/mariadb-10.5.binlog, which
+// holds the same events as a crc32 checksummed log file ending with a rotate
+// event) are assembled following MariaDB's documented event layouts, not
+// captured from a MariaDB server.  Replace the fixture with a binlog captured
+// from a real MariaDB server (e.g., 10.5 with binlog_format=ROW and
+// binlog_annotate_row_events=ON).
+type MariadbSuite struct {
+	file *MockLogFile
+}
+
+var _ = Suite(&MariadbSuite{})
+
+func (s *MariadbSuite) SetUpTest(c *C) {
+	s.file = NewMockLogFile()
+	s.file.WriteLogFileMagic()
+}
+
+// Writes the events which MariaDB logs at the beginning of each log file.
+func (s *MariadbSuite) WriteMariadbPreamble() {
+	s.file.WriteMariadbFDE()
+	s.file.WriteMariadbGtidList()
+	s.file.WriteMariadbBinlogCheckpoint("mariadb-bin", 1)
+}
+
+func (s *MariadbSuite) WriteTransactions() {
+	s.file.WriteMariadbGtid(0, 42, false, 7)
+	s.file.WriteMariadbAnnotateRows("INSERT INTO t VALUES (1)")
+	s.file.WriteTableMap()
+	s.file.WriteInsertV1(1) // MariaDB does not log v2 rows events
+	s.file.WriteXid(5)
+
+	s.file.WriteMariadbGtid(1, 43, true, 0)
+	s.file.WriteQuery("CREATE TABLE t2 (a int)")
+}
+
+func (s *MariadbSuite) NewReader() EventReader {
+	return NewLogFileV4EventReader(
+		s.file.GetReader(),
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+}
+
+func (s *MariadbSuite) NextEvent(c *C, reader EventReader) Event {
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Assert(event, NotNil)
+	return event
+}
+
+func (s *MariadbSuite) TestIsMariadbServerVersion(c *C) {
+	c.Check(IsMariadbServerVersion([]byte("10.5.8-MariaDB-log")), IsTrue)
+	c.Check(IsMariadbServerVersion([]byte("5.5.5-10.3.27-MariaDB")), IsTrue)
+	c.Check(IsMariadbServerVersion([]byte("5.6.15-63.0-log")), IsFalse)
+	c.Check(IsMariadbServerVersion([]byte("8.0.32")), IsFalse)
+}
+
+func (s *MariadbSuite) TestPreamble(c *C) {
+	s.WriteMariadbPreamble()
+	reader := s.NewReader()
+
+	fde, ok := s.NextEvent(c, reader).(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(fde.ServerVersion()), Equals, "10.5.8-MariaDB-log")
+	c.Check(fde.IsMariadb(), IsTrue)
+	c.Check(fde.NumKnownEventTypes(), Equals, 172)
+	c.Check(fde.FixedLengthDataSizeForType(MariadbGtidEvent), Equals, 19)
+	c.Check(
+		fde.ChecksumAlgorithm(),
+		Equals,
+		mysql_proto.ChecksumAlgorithm_OFF)
+
+	// The gtid list and binlog checkpoint events are not parsed.
+	event := s.NextEvent(c, reader)
+	_, ok = event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+	c.Check(event.EventType(), Equals, MariadbGtidListEvent)
+
+	event = s.NextEvent(c, reader)
+	_, ok = event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+	c.Check(event.EventType(), Equals, MariadbBinlogCheckpointEvent)
+}
+
+func (s *MariadbSuite) TestEvents(c *C) {
+	s.WriteMariadbPreamble()
+	s.WriteTransactions()
+	reader := s.NewReader()
+
+	for i := 0; i < 3; i++ {
+		s.NextEvent(c, reader)
+	}
+
+	gtid, ok := s.NextEvent(c, reader).(*MariadbGtidLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(gtid.Gtid(), Equals, MariadbGtid{DomainId: 0, ServerId: 1, SeqNo: 42})
+	c.Check(gtid.Gtid().String(), Equals, "0-1-42")
+	c.Check(gtid.IsStandalone(), IsFalse)
+	c.Assert(gtid.CommitId(), NotNil)
+	c.Check(*gtid.CommitId(), Equals, uint64(7))
+
+	annotate, ok := s.NextEvent(c, reader).(*MariadbAnnotateRowsLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(annotate.Query()), Equals, "INSERT INTO t VALUES (1)")
+
+	_, ok = s.NextEvent(c, reader).(*TableMapEvent)
+	c.Check(ok, IsTrue)
+
+	rows, ok := s.NextEvent(c, reader).(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(
+		rows.EventType(),
+		Equals,
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1)
+	c.Check(rows.InsertedRows(), DeepEquals, []RowValues{{uint64(1)}})
+
+	_, ok = s.NextEvent(c, reader).(*XidEvent)
+	c.Check(ok, IsTrue)
+
+	gtid, ok = s.NextEvent(c, reader).(*MariadbGtidLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(gtid.Gtid().String(), Equals, "1-1-43")
+	c.Check(gtid.IsStandalone(), IsTrue)
+	c.Check(gtid.CommitId(), IsNil)
+
+	query, ok := s.NextEvent(c, reader).(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(query.Query()), Equals, "CREATE TABLE t2 (a int)")
+}
+
+func (s *MariadbSuite) TestTransactionGrouper(c *C) {
+	s.WriteMariadbPreamble()
+	s.WriteTransactions()
+	grouper := NewTransactionGrouper(s.NewReader(), false)
+
+	// fde, gtid list and binlog checkpoint
+	for i := 0; i < 3; i++ {
+		txn, err := grouper.NextTransaction()
+		c.Assert(err, IsNil)
+		c.Check(txn.Events, HasLen, 1)
+		c.Check(txn.Committed, IsTrue)
+	}
+
+	// MariaDB does not log BEGIN; the gtid event starts the transaction.
+	txn, err := grouper.NextTransaction()
+	c.Assert(err, IsNil)
+	c.Check(txn.Events, HasLen, 5)
+	c.Check(txn.Committed, IsTrue)
+
+	// The standalone DDL statement is implicitly committed.
+	txn, err = grouper.NextTransaction()
+	c.Assert(err, IsNil)
+	c.Check(txn.Events, HasLen, 2)
+	c.Check(txn.Committed, IsTrue)
+}
+
+func (s *MariadbSuite) TestMysqlStream(c *C) {
+	s.file.WriteFDE()
+	s.file.WriteMariadbGtid(0, 42, false, 0)
+	reader := s.NewReader()
+
+	fde, ok := s.NextEvent(c, reader).(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(fde.IsMariadb(), IsFalse)
+
+	// MariaDB events are unknown event types in mysql streams.
+	event := s.NextEvent(c, reader)
+	_, ok = event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+	c.Check(event.EventType(), Equals, MariadbGtidEvent)
+
+	reader = NewLogFileV4EventReaderWithOptions(
+		s.file.GetReader(),
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		LogFileV4EventReaderOptions{UnknownEventMode: StrictUnknownEvents})
+
+	s.NextEvent(c, reader)
+	_, err := reader.NextEvent()
+	c.Assert(err, NotNil)
+	unknown, ok := err.(*UnknownEventTypeError)
+	c.Assert(ok, IsTrue)
+	c.Check(unknown.TypeCode, Equals, uint8(MariadbGtidEvent))
+}
+
+const mariadbFixturePath = "testdata/mariadb-10.5.binlog"
+
+func (s *MariadbSuite) TestLogFileFixture(c *C) {
+	data, err := ioutil.ReadFile(mariadbFixturePath)
+	c.Assert(err, IsNil)
+
+	reader := NewLogFileV4EventReaderWithOptions(
+		bytes.NewReader(data),
+		mariadbFixturePath,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		LogFileV4EventReaderOptions{
+			VerifyChecksum:   true,
+			UnknownEventMode: StrictUnknownEvents,
+		})
+
+	events := []Event{}
+	for i := 0; i < 11; i++ {
+		event := s.NextEvent(c, reader)
+		c.Check(event.ChecksumStatus(), Equals, ChecksumVerified)
+		c.Check(event.Checksum(), HasLen, 4)
+		events = append(events, event)
+	}
+	c.Check(reader.NextPosition(), Equals, int64(len(data)))
+
+	fde, ok := events[0].(*FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(fde.IsMariadb(), IsTrue)
+	c.Check(
+		fde.ChecksumAlgorithm(),
+		Equals,
+		mysql_proto.ChecksumAlgorithm_CRC32)
+
+	c.Check(events[1].EventType(), Equals, MariadbGtidListEvent)
+	c.Check(events[2].EventType(), Equals, MariadbBinlogCheckpointEvent)
+
+	gtid, ok := events[3].(*MariadbGtidLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(gtid.Gtid().String(), Equals, "0-1-42")
+	c.Assert(gtid.CommitId(), NotNil)
+	c.Check(*gtid.CommitId(), Equals, uint64(7))
+
+	annotate, ok := events[4].(*MariadbAnnotateRowsLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(annotate.Query()), Equals, "INSERT INTO t VALUES (1)")
+
+	_, ok = events[5].(*TableMapEvent)
+	c.Check(ok, IsTrue)
+
+	rows, ok := events[6].(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rows.InsertedRows(), DeepEquals, []RowValues{{uint64(1)}})
+
+	_, ok = events[7].(*XidEvent)
+	c.Check(ok, IsTrue)
+
+	gtid, ok = events[8].(*MariadbGtidLogEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(gtid.Gtid().String(), Equals, "1-1-43")
+	c.Check(gtid.IsStandalone(), IsTrue)
+
+	query, ok := events[9].(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(query.Query()), Equals, "CREATE TABLE t2 (a int)")
+
+	rotate, ok := events[10].(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(rotate.IsArtificial(), IsFalse)
+	c.Check(rotate.NewPosition(), Equals, uint64(4))
+	c.Check(NextReadPosition(rotate), Equals, int64(len(data)))
+}
@@ -47,17 +47,29 @@ func (mlf *MockLogFile) Write(contents []byte) {
 func (mlf *MockLogFile) writeWithHeader(
 	contents []byte, logEventType mysql_proto.LogEventType_Type) {
 
+	mlf.writeWithFlags(contents, logEventType, LogEventBinlogInUse)
+}
+
+// Artificial events are written with zero log_pos.
+func (mlf *MockLogFile) writeWithFlags(
+	contents []byte,
+	logEventType mysql_proto.LogEventType_Type,
+	flags EventFlags) {
+
 	mlf.mu.Lock()
 	defer mlf.mu.Unlock()
 
 	nextPosition := len(mlf.logBuffer) + sizeOfBasicV4EventHeader + len(contents)
+	if flags.IsArtificial() {
+		nextPosition = 0
+	}
 
 	e, _ := CreateEventBytes(
 		uint32(0),
 		uint8(logEventType),
 		uint32(1),
 		uint32(nextPosition),
-		uint16(1),
+		uint16(flags),
 		contents)
 	mlf.logBuffer = append(mlf.logBuffer, e...)
 }
@@ -87,6 +99,122 @@ func (mlf *MockLogFile) WriteFDE() {
 	mlf.writeWithHeader(data, mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT)
 }
 
+// This writes a 5.6 FDE (with checksum off), which supports gtid events and
+// v2 rows events.
+func (mlf *MockLogFile) Write56FDE() {
+	data := []byte{
+		// binlog version
+		4, 0,
+		// server version
+		53, 46, 54, 46, 49, 53, 45, 54, 51, 46,
+		48, 45, 108, 111, 103, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		// created timestamp
+		0, 0, 0, 0,
+		// total header size
+		19,
+		// fixed length data size per event type
+		56, 13, 0, 8, 0, 18, 0, 4, 4, 4, 4, 18, 0, 0, 92, 0, 4, 26,
+		8, 0, 0, 0, 8, 8, 8, 2, 0, 0, 0, 10, 10, 10, 25, 25, 0,
+		// checksum algorithm (off)
+		0,
+		// checksum
+		0, 0, 0, 0}
+
+	mlf.writeWithHeader(data, mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT)
+}
+
+// This writes a MariaDB 10.5 FDE (with checksum off).  MariaDB specific event
+// types start at 160; the mysql specific gtid event types are reported with
+// zero sizes.
+func (mlf *MockLogFile) WriteMariadbFDE() {
+	const numEventTypes = 171
+
+	data := &bytes.Buffer{}
+	// binlog version
+	data.Write([]byte{4, 0})
+	// server version
+	version := make([]byte, 50)
+	copy(version, "10.5.8-MariaDB-log")
+	data.Write(version)
+	// created timestamp
+	data.Write([]byte{0, 0, 0, 0})
+	// total header size
+	data.WriteByte(19)
+
+	// fixed length data size per event type
+	sizes := make([]byte, numEventTypes)
+	copy(sizes, []byte{
+		56, 13, 0, 8, 0, 18, 0, 4, 4, 4, 4, 18, 0, 0, 0, 0, 4, 26,
+		8, 0, 0, 0, 8, 8, 8, 2, 0, 0, 0, 10, 10, 10, 0, 0, 0})
+	sizes[mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT-1] =
+		byte(2 + 50 + 4 + 1 + numEventTypes)
+	copy(
+		sizes[MariadbAnnotateRowsEvent-1:],
+		[]byte{0, 4, 19, 4, 0, 13, 8, 8, 8, 10, 10, 10})
+	data.Write(sizes)
+
+	// checksum algorithm (off)
+	data.WriteByte(0)
+	// checksum
+	data.Write([]byte{0, 0, 0, 0})
+
+	mlf.writeWithHeader(
+		data.Bytes(),
+		mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT)
+}
+
+// This writes an empty MariaDB gtid list event.
+func (mlf *MockLogFile) WriteMariadbGtidList() {
+	mlf.writeWithHeader([]byte{0, 0, 0, 0}, MariadbGtidListEvent)
+}
+
+// This writes a MariaDB binlog checkpoint event for the log file.
+func (mlf *MockLogFile) WriteMariadbBinlogCheckpoint(prefix string, num int) {
+	name := logName(prefix, num)
+
+	data := &bytes.Buffer{}
+	binary.Write(data, LittleEndian, uint32(len(name)))
+	data.WriteString(name)
+
+	mlf.writeWithHeader(data.Bytes(), MariadbBinlogCheckpointEvent)
+}
+
+// This writes a MariaDB gtid event.  The group commit id is only written when
+// commitId is non-zero.
+func (mlf *MockLogFile) WriteMariadbGtid(
+	domainId uint32,
+	seqNo uint64,
+	standalone bool,
+	commitId uint64) {
+
+	flags := uint8(0)
+	if standalone {
+		flags |= mariadbGtidFlagStandalone
+	}
+	if commitId != 0 {
+		flags |= mariadbGtidFlagGroupCommitId
+	}
+
+	data := &bytes.Buffer{}
+	binary.Write(data, LittleEndian, seqNo)
+	binary.Write(data, LittleEndian, domainId)
+	data.WriteByte(flags)
+	if commitId != 0 {
+		binary.Write(data, LittleEndian, commitId)
+	} else {
+		data.Write(make([]byte, 6))
+	}
+
+	mlf.writeWithHeader(data.Bytes(), MariadbGtidEvent)
+}
+
+func (mlf *MockLogFile) WriteMariadbAnnotateRows(query string) {
+	mlf.writeWithHeader([]byte(query), MariadbAnnotateRowsEvent)
+}
+
 func serializeGtidSet(set GtidSet) []byte {
 	data := &bytes.Buffer{}
 
@@ -136,6 +264,21 @@ func (mlf *MockLogFile) WriteRotate(prefix string, num int) {
 	mlf.writeWithHeader(data.Bytes(), mysql_proto.LogEventType_ROTATE_EVENT)
 }
 
+// This writes a fake rotate event (as generated by the master at the
+// beginning of a replication stream) which announces the master's log file.
+func (mlf *MockLogFile) WriteArtificialRotate(prefix string, num int) {
+	pos := uint64(4)
+
+	data := &bytes.Buffer{}
+	binary.Write(data, binary.LittleEndian, pos)
+	data.WriteString(logName(prefix, num))
+
+	mlf.writeWithFlags(
+		data.Bytes(),
+		mysql_proto.LogEventType_ROTATE_EVENT,
+		LogEventArtificial)
+}
+
 func (mlf *MockLogFile) WriteStop() {
 	mlf.writeWithHeader([]byte{}, mysql_proto.LogEventType_STOP_EVENT)
 }
@@ -239,6 +382,29 @@ func (mlf *MockLogFile) WriteInsert(value int) {
 	mlf.WriteInsertWithParam(value, 0)
 }
 
+// This writes a v1 write rows event (i.e., without the variable size header),
+// as logged by mysql 5.5 and MariaDB.
+func (mlf *MockLogFile) WriteInsertV1(value int) {
+	data := &bytes.Buffer{}
+	data.Write([]byte{
+		// table id
+		0, 0, 0, 0, 0, 0,
+		// flags
+		0, 0,
+		// number of columns
+		1,
+		// columns used bitmap
+		1,
+		// row data's "is null" bit map
+		0,
+	})
+	binary.Write(data, binary.LittleEndian, uint32(value))
+
+	mlf.writeWithHeader(
+		data.Bytes(),
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1)
+}
+
 func (mlf *MockLogFile) WriteDeleteWithParam(value int, tableId int8) {
 	data := &bytes.Buffer{}
 	data.Write([]byte{
@@ -17,6 +17,7 @@ type MockMultifileReader struct {
 	fetchFiles       MockFileFetcher
 	currentFileIndex int
 	isClosed         bool
+	nextPosition     int64
 }
 
 var _ EventReader = &MockMultifileReader{}
@@ -89,6 +90,10 @@ func (r *MockMultifileReader) nextEventEndPosition() int64 {
 	return reader.nextEventEndPosition()
 }
 
+func (r *MockMultifileReader) NextPosition() int64 {
+	return r.nextPosition
+}
+
 func (r *MockMultifileReader) Close() error {
 	r.isClosed = true
 	if r.reader == nil {
@@ -109,6 +114,7 @@ func (r *MockMultifileReader) NextEvent() (Event, error) {
 
 		event, err := reader.NextEvent()
 		if err == nil {
+			r.nextPosition = reader.NextPosition()
 			return event, nil
 		} else if err == io.EOF && r.currentFileIndex+1 < len(r.fetchFiles()) {
 			// There's another file, so we'll try again.
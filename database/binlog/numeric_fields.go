@@ -30,47 +30,158 @@ import (
 
 // This returns a field descriptor for FieldType_TINY (i.e., Field_tiny).
 func NewTinyFieldDescriptor(nullable NullableColumn) FieldDescriptor {
-	return newFixedLengthFieldDescriptor(
+	return newIntegerFieldDescriptor(
 		mysql_proto.FieldType_TINY,
 		nullable,
-		1,
-		func(b []byte) interface{} { return uint64(b[0]) })
+		UniformIntegerWidth)
 }
 
 // This returns a field descriptor for FieldType_SHORT (i.e., Field_shart)
 func NewShortFieldDescriptor(nullable NullableColumn) FieldDescriptor {
-	return newFixedLengthFieldDescriptor(
+	return newIntegerFieldDescriptor(
 		mysql_proto.FieldType_SHORT,
 		nullable,
-		2,
-		func(b []byte) interface{} { return uint64(LittleEndian.Uint16(b)) })
+		UniformIntegerWidth)
 }
 
 // This returns a field descriptor for FieldType_INT24 (i.e., Field_medium)
 func NewInt24FieldDescriptor(nullable NullableColumn) FieldDescriptor {
-	return newFixedLengthFieldDescriptor(
+	return newIntegerFieldDescriptor(
 		mysql_proto.FieldType_INT24,
 		nullable,
-		3,
-		func(b []byte) interface{} { return uint64(LittleEndian.Uint24(b)) })
+		UniformIntegerWidth)
 }
 
 // This returns a field descriptor for FieldType_LONG (i.e., Field_long)
 func NewLongFieldDescriptor(nullable NullableColumn) FieldDescriptor {
-	return newFixedLengthFieldDescriptor(
+	return newIntegerFieldDescriptor(
 		mysql_proto.FieldType_LONG,
 		nullable,
-		4,
-		func(b []byte) interface{} { return uint64(LittleEndian.Uint32(b)) })
+		UniformIntegerWidth)
 }
 
 // This returns a field descriptor for FieldType_LONGLONG (i.e., Field_longlong)
 func NewLongLongFieldDescriptor(nullable NullableColumn) FieldDescriptor {
-	return newFixedLengthFieldDescriptor(
+	return newIntegerFieldDescriptor(
 		mysql_proto.FieldType_LONGLONG,
 		nullable,
-		8,
-		func(b []byte) interface{} { return LittleEndian.Uint64(b) })
+		UniformIntegerWidth)
+}
+
+// This returns a field descriptor for the integer field type (TINY, SHORT,
+// INT24, LONG or LONGLONG), which returns values of the specified width.
+func NewIntegerFieldDescriptorWithWidth(
+	fieldType mysql_proto.FieldType_Type,
+	nullable NullableColumn,
+	width IntegerWidth) (FieldDescriptor, error) {
+
+	switch fieldType {
+	case mysql_proto.FieldType_TINY,
+		mysql_proto.FieldType_SHORT,
+		mysql_proto.FieldType_INT24,
+		mysql_proto.FieldType_LONG,
+		mysql_proto.FieldType_LONGLONG:
+	default:
+		return nil, errors.Newf(
+			"Invalid integer field type: %s",
+			fieldType.String())
+	}
+
+	switch width {
+	case UniformIntegerWidth, NativeIntegerWidth:
+	default:
+		return nil, errors.Newf("Invalid integer width: %d", width)
+	}
+
+	return newIntegerFieldDescriptor(fieldType, nullable, width), nil
+}
+
+// IntegerFieldDescriptor is implemented by the integer field descriptors
+// (TINY, SHORT, INT24, LONG and LONGLONG).  In addition to ParseValue, the
+// descriptor provides an allocation free decode path, which returns the
+// unboxed value.
+type IntegerFieldDescriptor interface {
+	FieldDescriptor
+
+	// ParseUint64 is the same as ParseValue, but returns the value as uint64
+	// (regardless of the descriptor's integer width) without boxing it.
+	ParseUint64(data []byte) (value uint64, remaining []byte, err error)
+}
+
+type integerFieldDescriptor struct {
+	baseFieldDescriptor
+
+	numBytes int
+	native   bool
+}
+
+func newIntegerFieldDescriptor(
+	fieldType mysql_proto.FieldType_Type,
+	nullable NullableColumn,
+	width IntegerWidth) FieldDescriptor {
+
+	var numBytes int
+	switch fieldType {
+	case mysql_proto.FieldType_TINY:
+		numBytes = 1
+	case mysql_proto.FieldType_SHORT:
+		numBytes = 2
+	case mysql_proto.FieldType_INT24:
+		numBytes = 3
+	case mysql_proto.FieldType_LONG:
+		numBytes = 4
+	case mysql_proto.FieldType_LONGLONG:
+		numBytes = 8
+	default:
+		panic("Invalid integer field type: " + fieldType.String())
+	}
+
+	return &integerFieldDescriptor{
+		baseFieldDescriptor: baseFieldDescriptor{
+			fieldType:  fieldType,
+			isNullable: nullable,
+		},
+		numBytes: numBytes,
+		native:   width == NativeIntegerWidth,
+	}
+}
+
+// See IntegerFieldDescriptor for documentation.
+func (d *integerFieldDescriptor) ParseUint64(data []byte) (
+	value uint64,
+	remaining []byte,
+	err error) {
+
+	if len(data) < d.numBytes {
+		return 0, nil, errors.New("not enough bytes")
+	}
+
+	return decodeLEUint(data[:d.numBytes]), data[d.numBytes:], nil
+}
+
+func (d *integerFieldDescriptor) ParseValue(data []byte) (
+	value interface{},
+	remaining []byte,
+	err error) {
+
+	val, remaining, err := d.ParseUint64(data)
+	if err != nil {
+		return nil, remaining, err
+	}
+
+	if !d.native {
+		return val, remaining, nil
+	}
+
+	switch d.numBytes {
+	case 1:
+		return uint8(val), remaining, nil
+	case 2:
+		return uint16(val), remaining, nil
+	case 3, 4:
+		return uint32(val), remaining, nil
+	}
+	return val, remaining, nil
 }
 
 // This returns a field descriptor for FieldType_FLOAT (i.e., Field_float)
@@ -145,6 +256,12 @@ func (d *decimalFieldDescriptor) ParseValue(data []byte) (
 	return nil, nil, errors.New("TODO")
 }
 
+// See DECIMAL_MAX_PRECISION and DECIMAL_MAX_SCALE in include/decimal.h
+const (
+	maxDecimalPrecision = 65
+	maxDecimalScale     = 30
+)
+
 type newDecimalFieldDescriptor struct {
 	baseFieldDescriptor
 
@@ -163,21 +280,41 @@ func NewNewDecimalFieldDescriptor(nullable NullableColumn, metadata []byte) (
 		return nil, nil, errors.New("Metadata has too few bytes")
 	}
 
+	precision := uint8(metadata[0])
+	decimals := uint8(metadata[1])
+	if precision == 0 || precision > maxDecimalPrecision ||
+		decimals > maxDecimalScale || decimals > precision {
+
+		return nil, nil, errors.Newf(
+			"Invalid decimal metadata (precision: %d decimals: %d)",
+			precision,
+			decimals)
+	}
+
 	return &newDecimalFieldDescriptor{
 		baseFieldDescriptor: baseFieldDescriptor{
 			fieldType:  mysql_proto.FieldType_NEWDECIMAL,
 			isNullable: nullable,
 		},
 
-		precision: uint8(metadata[0]),
-		decimals:  uint8(metadata[1]),
+		precision: precision,
+		decimals:  decimals,
 	}, metadata[2:], nil
 }
 
+// The value is returned as a Decimal, with scale equals to the column's
+// decimals.
 func (d *newDecimalFieldDescriptor) ParseValue(data []byte) (
 	value interface{},
 	remaining []byte,
 	err error) {
 
-	return nil, nil, errors.New("TODO")
+	dec, remaining, err := decodeDecimal(
+		data,
+		int(d.precision),
+		int(d.decimals))
+	if err != nil {
+		return nil, nil, err
+	}
+	return dec, remaining, nil
 }
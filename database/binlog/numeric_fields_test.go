@@ -226,3 +226,166 @@ func (s *NumericFieldsSuite) TestDoubleParseValueTooFewBytes(c *C) {
 }
 
 // TODO(patrick): implement decimal / new decimal field descriptors / tests.
+
+func (s *NumericFieldsSuite) TestIntegerWidth(c *C) {
+	tinyBytes := []byte{0xfe, 'r', 'e', 's', 't'}
+	longLongBytes := []byte{
+		0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 'r', 'e', 's', 't'}
+
+	// Uniform width (default)
+	for _, t := range []FieldDescriptor{
+		NewTinyFieldDescriptor(true),
+		mustIntegerFieldDescriptor(
+			mysql_proto.FieldType_TINY,
+			UniformIntegerWidth),
+	} {
+		val, remaining, err := t.ParseValue(tinyBytes)
+		c.Assert(err, IsNil)
+		c.Check(val, Equals, uint64(0xfe))
+		c.Check(string(remaining), Equals, "rest")
+	}
+
+	for _, t := range []FieldDescriptor{
+		NewLongLongFieldDescriptor(true),
+		mustIntegerFieldDescriptor(
+			mysql_proto.FieldType_LONGLONG,
+			UniformIntegerWidth),
+	} {
+		val, remaining, err := t.ParseValue(longLongBytes)
+		c.Assert(err, IsNil)
+		c.Check(val, Equals, uint64(0xfffffffffffffffe))
+		c.Check(string(remaining), Equals, "rest")
+	}
+
+	// Native width
+	t := mustIntegerFieldDescriptor(
+		mysql_proto.FieldType_TINY,
+		NativeIntegerWidth)
+	c.Check(t.Type(), Equals, mysql_proto.FieldType_TINY)
+	val, remaining, err := t.ParseValue(tinyBytes)
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint8(0xfe))
+	c.Check(string(remaining), Equals, "rest")
+
+	t = mustIntegerFieldDescriptor(
+		mysql_proto.FieldType_LONGLONG,
+		NativeIntegerWidth)
+	c.Check(t.Type(), Equals, mysql_proto.FieldType_LONGLONG)
+	val, remaining, err = t.ParseValue(longLongBytes)
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint64(0xfffffffffffffffe))
+	c.Check(string(remaining), Equals, "rest")
+
+	t = mustIntegerFieldDescriptor(
+		mysql_proto.FieldType_SHORT,
+		NativeIntegerWidth)
+	val, _, err = t.ParseValue([]byte{0x34, 0x12})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint16(0x1234))
+
+	t = mustIntegerFieldDescriptor(
+		mysql_proto.FieldType_INT24,
+		NativeIntegerWidth)
+	val, _, err = t.ParseValue([]byte{0x56, 0x34, 0x12})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint32(0x123456))
+
+	t = mustIntegerFieldDescriptor(
+		mysql_proto.FieldType_LONG,
+		NativeIntegerWidth)
+	val, _, err = t.ParseValue([]byte{0x78, 0x56, 0x34, 0x12})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint32(0x12345678))
+}
+
+func (s *NumericFieldsSuite) TestParseUint64(c *C) {
+	data := []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, 0xff}
+
+	for _, test := range []struct {
+		fieldType mysql_proto.FieldType_Type
+		numBytes  int
+		value     uint64
+	}{
+		{mysql_proto.FieldType_TINY, 1, 0x08},
+		{mysql_proto.FieldType_SHORT, 2, 0x0708},
+		{mysql_proto.FieldType_INT24, 3, 0x060708},
+		{mysql_proto.FieldType_LONG, 4, 0x05060708},
+		{mysql_proto.FieldType_LONGLONG, 8, 0x0102030405060708},
+	} {
+		for _, width := range []IntegerWidth{
+			UniformIntegerWidth,
+			NativeIntegerWidth,
+		} {
+			d := mustIntegerFieldDescriptor(test.fieldType, width)
+			intDesc, ok := d.(IntegerFieldDescriptor)
+			c.Assert(ok, IsTrue)
+
+			val, remaining, err := intDesc.ParseUint64(data)
+			c.Assert(err, IsNil)
+			c.Check(val, Equals, test.value)
+			c.Check(remaining, DeepEquals, data[test.numBytes:])
+
+			_, _, err = intDesc.ParseUint64(data[:test.numBytes-1])
+			c.Check(err, NotNil)
+		}
+	}
+}
+
+func (s *NumericFieldsSuite) TestIntegerWidthErrors(c *C) {
+	_, err := NewIntegerFieldDescriptorWithWidth(
+		mysql_proto.FieldType_DOUBLE,
+		true,
+		NativeIntegerWidth)
+	c.Check(err, NotNil)
+
+	_, err = NewIntegerFieldDescriptorWithWidth(
+		mysql_proto.FieldType_TINY,
+		true,
+		IntegerWidth(10))
+	c.Check(err, NotNil)
+}
+
+func (s *NumericFieldsSuite) TestTableMapIntegerWidth(c *C) {
+	table := &TableMapEvent{
+		columnTypesBytes: []byte{
+			byte(mysql_proto.FieldType_TINY),
+			byte(mysql_proto.FieldType_LONGLONG),
+		},
+		metadataBytes:    []byte{},
+		nullColumnsBytes: []byte{0},
+	}
+
+	p := &TableMapEventParser{}
+	err := p.parseColumns(table)
+	c.Assert(err, IsNil)
+
+	val, _, err := table.ColumnDescriptors()[0].ParseValue([]byte{7})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint64(7))
+
+	p = &TableMapEventParser{
+		options: DecodeOptions{IntegerWidth: NativeIntegerWidth},
+	}
+	err = p.parseColumns(table)
+	c.Assert(err, IsNil)
+
+	val, _, err = table.ColumnDescriptors()[0].ParseValue([]byte{7})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint8(7))
+
+	val, _, err = table.ColumnDescriptors()[1].ParseValue(
+		[]byte{7, 0, 0, 0, 0, 0, 0, 0})
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, uint64(7))
+}
+
+func mustIntegerFieldDescriptor(
+	fieldType mysql_proto.FieldType_Type,
+	width IntegerWidth) FieldDescriptor {
+
+	fd, err := NewIntegerFieldDescriptorWithWidth(fieldType, true, width)
+	if err != nil {
+		panic(err)
+	}
+	return fd
+}
@@ -1,25 +1,72 @@
 package binlog
 
 import (
+	"bytes"
+
 	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
 )
 
 type parsedV4EventReader struct {
 	reader       EventReader
 	eventParsers V4EventParserMap
+
+	schemas *SchemaCache
+
+	unknownEventMode UnknownEventMode
+
+	// When true, query events (other than transaction control statements)
+	// are dropped before parsing.  See LogFileV4EventReaderOptions.DMLOnly.
+	dmlOnly bool
+
+	// True when the stream's (latest) format description event is written
+	// by a MariaDB server.  MariaDB specific event types are treated as
+	// unknown event types in other streams.
+	mariadb bool
 }
 
 // This returns an EventReader which applies the appropriate parser on each
 // raw v4 event in the stream.  If no parser is available for the event,
 // or if an error occurs during parsing, then the reader will return the
-// original event along with the error.
+// original event along with the error.  Up to DefaultSchemaCacheSize table
+// schemas are cached.
 func NewParsedV4EventReader(
 	reader EventReader,
 	parsers V4EventParserMap) EventReader {
 
+	return NewParsedV4EventReaderWithSchemaCache(
+		reader,
+		parsers,
+		NewSchemaCache(DefaultSchemaCacheSize))
+}
+
+// Same as NewParsedV4EventReader, but table map derived schemas are cached in
+// the provided schema cache.  Rows events are parsed using the cached schema
+// of the event's table.
+func NewParsedV4EventReaderWithSchemaCache(
+	reader EventReader,
+	parsers V4EventParserMap,
+	schemas *SchemaCache) EventReader {
+
+	return NewParsedV4EventReaderWithUnknownEventMode(
+		reader,
+		parsers,
+		schemas,
+		PassThroughUnknownEvents)
+}
+
+// Same as NewParsedV4EventReaderWithSchemaCache, but events with unknown
+// type codes are handled according to the provided mode.
+func NewParsedV4EventReaderWithUnknownEventMode(
+	reader EventReader,
+	parsers V4EventParserMap,
+	schemas *SchemaCache,
+	unknownEventMode UnknownEventMode) EventReader {
+
 	return &parsedV4EventReader{
-		reader:       reader,
-		eventParsers: parsers,
+		reader:           reader,
+		eventParsers:     parsers,
+		schemas:          schemas,
+		unknownEventMode: unknownEventMode,
 	}
 }
 
@@ -35,11 +82,26 @@ func (r *parsedV4EventReader) nextEventEndPosition() int64 {
 	return r.reader.nextEventEndPosition()
 }
 
+func (r *parsedV4EventReader) NextPosition() int64 {
+	return r.reader.NextPosition()
+}
+
 func (r *parsedV4EventReader) Close() error {
 	return r.reader.Close()
 }
 
 func (r *parsedV4EventReader) NextEvent() (Event, error) {
+	for {
+		event, err := r.nextEvent()
+		if event == nil && err == nil {
+			continue // the event was dropped
+		}
+		return event, err
+	}
+}
+
+// This returns (nil, nil) when the event is dropped.
+func (r *parsedV4EventReader) nextEvent() (Event, error) {
 	event, err := r.reader.NextEvent()
 	if err != nil {
 		return event, err
@@ -65,6 +127,10 @@ func (r *parsedV4EventReader) NextEvent() (Event, error) {
 		return event, err // return both raw event and error
 	}
 
+	if !isKnownEventType(raw.EventType(), r.mariadb) {
+		return handleUnknownEvent(raw, r.unknownEventMode)
+	}
+
 	parser := r.eventParsers.Get(raw.EventType())
 	if parser == nil {
 		return event, nil // no parser available, just return the raw event
@@ -75,15 +141,76 @@ func (r *parsedV4EventReader) NextEvent() (Event, error) {
 		return event, err // return both raw event and error
 	}
 
+	if r.dmlOnly &&
+		raw.EventType() == mysql_proto.LogEventType_QUERY_EVENT &&
+		!isRawTransactionControlQuery(raw) {
+
+		return nil, nil
+	}
+
+	if isRowsEventType(raw.EventType()) {
+		fixed := raw.FixedLengthData()
+		if len(fixed) >= 6 {
+			// On cache miss, the last table context is used (and the rows
+			// event parser will reject the event if the table id mismatch).
+			context, ok := r.schemas.Get(LittleEndian.Uint48(fixed))
+			if ok {
+				r.eventParsers.SetTableContext(context)
+			}
+		}
+	}
+
 	event, err = parser.Parse(raw)
 	if err != nil {
 		return event, err
 	}
 
-	tm, ok := event.(*TableMapEvent)
-	if ok {
-		r.eventParsers.SetTableContext(tm)
+	switch e := event.(type) {
+	case *TableMapEvent:
+		r.schemas.Add(e)
+		r.eventParsers.SetTableContext(e)
+	case *FormatDescriptionEvent:
+		r.mariadb = e.IsMariadb()
 	}
 
 	return event, nil
 }
+
+var transactionControlPrefixes = [][]byte{
+	[]byte("BEGIN"),
+	[]byte("COMMIT"),
+	[]byte("ROLLBACK"),
+	[]byte("SAVEPOINT"),
+	[]byte("XA "),
+}
+
+// This checks if the raw query event's statement is a transaction control
+// statement (e.g., BEGIN / COMMIT), without parsing the event's status
+// block.  Malformed events are treated as transaction control statements
+// (i.e., they are not dropped), so that the query event parser can report
+// the error.
+func isRawTransactionControlQuery(raw *RawV4Event) bool {
+	fixed := raw.FixedLengthData()
+	if len(fixed) < 13 {
+		return true
+	}
+
+	dbNameLength := int(fixed[8])
+	statusLength := int(LittleEndian.Uint16(fixed[11:13]))
+
+	data := raw.VariableLengthData()
+	start := statusLength + dbNameLength + 1
+	if start > len(data) {
+		return true
+	}
+
+	query := bytes.TrimSpace(data[start:])
+	for _, prefix := range transactionControlPrefixes {
+		if len(query) >= len(prefix) &&
+			bytes.EqualFold(query[:len(prefix)], prefix) {
+
+			return true
+		}
+	}
+	return false
+}
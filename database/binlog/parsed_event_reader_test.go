@@ -324,3 +324,67 @@ func (s *ParsedV4EventReaderSuite) TestReadStream(c *C) {
 	c.Check(event, IsNil)
 	c.Check(err, Equals, io.EOF)
 }
+
+const testUnknownEventType = mysql_proto.LogEventType_Type(200)
+
+func (s *ParsedV4EventReaderSuite) writeUnknownEvents() {
+	s.WriteEvent(testUnknownEventType, []byte("header  unknown body"))
+	s.WriteEvent(
+		testRegisteredEventType,
+		[]byte("header  \xde\xca\xfb\xadrest of the body"))
+}
+
+func (s *ParsedV4EventReaderSuite) TestPassThroughUnknownEvent(c *C) {
+	s.writeUnknownEvents()
+
+	event, err := s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	_, ok := event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+}
+
+func (s *ParsedV4EventReaderSuite) TestLenientUnknownEvent(c *C) {
+	s.reader = NewParsedV4EventReaderWithUnknownEventMode(
+		s.rawReader,
+		s.parsers,
+		NewSchemaCache(DefaultSchemaCacheSize),
+		LenientUnknownEvents)
+
+	s.writeUnknownEvents()
+
+	event, err := s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	unknown, ok := event.(*UnknownEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(unknown.TypeCode(), Equals, uint8(200))
+	c.Check(unknown.EventLength(), Equals, uint32(sizeOfBasicV4EventHeader+20))
+	c.Check(
+		unknown.Bytes()[sizeOfBasicV4EventHeader:],
+		DeepEquals,
+		[]byte("header  unknown body"))
+
+	// The following event is parsed normally.
+	event, err = s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	e, ok := event.(*parsedTestEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(e.value, Equals, uint32(0xdecafbad))
+}
+
+func (s *ParsedV4EventReaderSuite) TestStrictUnknownEvent(c *C) {
+	s.reader = NewParsedV4EventReaderWithUnknownEventMode(
+		s.rawReader,
+		s.parsers,
+		NewSchemaCache(DefaultSchemaCacheSize),
+		StrictUnknownEvents)
+
+	s.writeUnknownEvents()
+
+	event, err := s.reader.NextEvent()
+	c.Assert(err, NotNil)
+	typeErr, ok := err.(*UnknownEventTypeError)
+	c.Assert(ok, IsTrue)
+	c.Check(typeErr.TypeCode, Equals, uint8(200))
+	_, ok = event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+}
@@ -0,0 +1,157 @@
+package binlog
+
+import (
+	"sync"
+)
+
+// LogPosition identifies a position within a named binlog stream.
+type LogPosition struct {
+	SourceName string
+	Position   int64
+}
+
+// PositionTracker maintains the position from which a consumer should resume
+// reading after a restart (e.g., after a crash).  The consumer should call
+// Processed for each event once the event has been applied, and periodically
+// persist the Checkpoint.
+//
+// By default, the checkpoint only advances at transaction boundaries (using
+// the same boundary rules as TransactionGrouper): while a transaction is in
+// progress, the checkpoint is the position of the transaction's first event
+// (i.e., the GTID event, or the BEGIN query event when the transaction has
+// no GTID event).  Hence, a consumer which crashes mid-transaction re-reads
+// the whole transaction on restart, and is able to apply the transaction
+// atomically.  When perEvent is set, the checkpoint advances past every
+// processed event instead.
+//
+// Artificial rotate events (see RotateEvent.IsArtificial), e.g., the fake
+// rotate event at the beginning of a replication stream, never advance the
+// checkpoint since their log_pos is zero; the tracker only records the
+// announced log file (see LogFile).
+//
+// PositionTracker is thread safe.
+type PositionTracker struct {
+	perEvent bool
+
+	mutex         sync.Mutex
+	checkpoint    LogPosition
+	inTransaction bool
+	sawBegin      bool
+	numEvents     int // # of events processed in the current transaction.
+	logFile       string
+}
+
+// This returns a tracker whose checkpoint is initialized to initial (e.g.,
+// the restored checkpoint, or the beginning of the first log file).
+func NewPositionTracker(
+	initial LogPosition,
+	perEvent bool) *PositionTracker {
+
+	return &PositionTracker{
+		perEvent:   perEvent,
+		checkpoint: initial,
+	}
+}
+
+// Processed advances the checkpoint (if appropriate) past the event.  Events
+// must be processed in stream order.
+func (t *PositionTracker) Processed(event Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if rotate, ok := event.(*RotateEvent); ok {
+		t.logFile = string(rotate.NewLogName())
+		if rotate.IsArtificial() {
+			return
+		}
+	}
+
+	next := LogPosition{
+		SourceName: event.SourceName(),
+		Position:   NextReadPosition(event),
+	}
+
+	if t.perEvent {
+		t.checkpoint = next
+		return
+	}
+
+	if !t.inTransaction {
+		if !isTransactionStart(event) {
+			t.checkpoint = next
+			return
+		}
+
+		t.startTransaction(event)
+		return
+	}
+
+	// The transaction consists of only the GTID event when a BEGIN query has
+	// not been seen.
+	gtidOnly := !t.sawBegin && t.numEvents == 1
+
+	if isTransactionStart(event) && !(gtidOnly && isBeginQuery(event)) {
+		// The current transaction ended without a commit marker.  The
+		// incomplete transaction is never replayed.
+		t.startTransaction(event)
+		return
+	}
+
+	t.numEvents++
+
+	switch e := event.(type) {
+	case *XidEvent:
+		t.endTransaction(next)
+	case *QueryEvent:
+		if isBeginQuery(e) {
+			t.sawBegin = true
+		} else if isQuery(e, "COMMIT") || isQuery(e, "ROLLBACK") || gtidOnly {
+			// NOTE: statements (e.g., DDL) which are not wrapped by BEGIN
+			// are implicitly committed.
+			t.endTransaction(next)
+		}
+	}
+}
+
+func (t *PositionTracker) startTransaction(event Event) {
+	t.checkpoint = LogPosition{
+		SourceName: event.SourceName(),
+		Position:   event.SourcePosition(),
+	}
+	t.inTransaction = true
+	t.sawBegin = isBeginQuery(event) || isMariadbBegin(event)
+	t.numEvents = 1
+}
+
+func (t *PositionTracker) endTransaction(next LogPosition) {
+	t.checkpoint = next
+	t.inTransaction = false
+	t.sawBegin = false
+	t.numEvents = 0
+}
+
+// Checkpoint returns the position from which reading should resume.
+func (t *PositionTracker) Checkpoint() LogPosition {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.checkpoint
+}
+
+// LogFile returns the log file name announced by the most recently processed
+// rotate event (artificial or not), or "" if no rotate event was processed.
+func (t *PositionTracker) LogFile() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.logFile
+}
+
+// InTransaction returns true if the last processed event is part of an
+// unfinished transaction.
+func (t *PositionTracker) InTransaction() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.inTransaction
+}
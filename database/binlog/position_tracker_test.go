@@ -0,0 +1,329 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"log"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type PositionTrackerSuite struct {
+	file *MockLogFile
+}
+
+var _ = Suite(&PositionTrackerSuite{})
+
+func (s *PositionTrackerSuite) SetUpTest(c *C) {
+	s.file = NewMockLogFile()
+	s.file.WriteLogFileMagic()
+
+	// NOTE: MockLogFile's WriteFDE writes a 5.5 FDE, which does not support
+	// gtid events and v2 rows events.
+	s.file.Write56FDE()
+}
+
+func (s *PositionTrackerSuite) newReader(src io.Reader) EventReader {
+	return NewLogFileV4EventReader(
+		src,
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+}
+
+func (s *PositionTrackerSuite) readAll(c *C, src io.Reader) []Event {
+	reader := s.newReader(src)
+
+	events := []Event{}
+	for {
+		event, err := reader.NextEvent()
+		if err != nil {
+			c.Assert(err, Equals, io.EOF)
+			return events
+		}
+		events = append(events, event)
+	}
+}
+
+func (s *PositionTrackerSuite) TestTransactionBoundaries(c *C) {
+	s.file.WriteGtid(testSid1, 1)
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(1)
+	s.file.WriteXid(1)
+	s.file.WriteGtid(testSid1, 2)
+	s.file.WriteQuery("CREATE TABLE foo (id INT)")
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(2)
+	s.file.WriteQuery("ROLLBACK")
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(3)
+	s.file.WriteQuery("COMMIT")
+
+	events := s.readAll(c, s.file.GetReader())
+	c.Assert(events, HasLen, 16)
+
+	tracker := NewPositionTracker(LogPosition{testSourceName, 4}, false)
+	c.Assert(tracker.Checkpoint(), Equals, LogPosition{testSourceName, 4})
+	c.Assert(tracker.InTransaction(), IsFalse)
+
+	position := func(event Event) LogPosition {
+		return LogPosition{testSourceName, event.SourcePosition()}
+	}
+	nextPosition := func(event Event) LogPosition {
+		return LogPosition{testSourceName, NextReadPosition(event)}
+	}
+
+	// The checkpoint of each event, after the event is processed.
+	expected := []LogPosition{
+		nextPosition(events[0]),  // FDE
+		position(events[1]),      // GTID 1
+		position(events[1]),      // BEGIN
+		position(events[1]),      // table map
+		position(events[1]),      // insert
+		nextPosition(events[5]),  // XID
+		position(events[6]),      // GTID 2
+		nextPosition(events[7]),  // DDL
+		position(events[8]),      // BEGIN
+		position(events[8]),      // table map
+		position(events[8]),      // insert
+		nextPosition(events[11]), // ROLLBACK
+		position(events[12]),     // BEGIN
+		position(events[12]),     // table map
+		position(events[12]),     // insert
+		nextPosition(events[15]), // COMMIT
+	}
+
+	inTransaction := []bool{
+		false,
+		true, true, true, true, false,
+		true, false,
+		true, true, true, false,
+		true, true, true, false,
+	}
+
+	for idx, event := range events {
+		tracker.Processed(event)
+		c.Check(
+			tracker.Checkpoint(),
+			Equals,
+			expected[idx],
+			Commentf("event %d", idx))
+		c.Check(
+			tracker.InTransaction(),
+			Equals,
+			inTransaction[idx],
+			Commentf("event %d", idx))
+	}
+}
+
+func (s *PositionTrackerSuite) TestMariadbTransactions(c *C) {
+	s.file = NewMockLogFile()
+	s.file.WriteLogFileMagic()
+	s.file.WriteMariadbFDE()
+
+	// A statement based transaction (the non-standalone gtid implies
+	// BEGIN), followed by a standalone DDL.
+	s.file.WriteMariadbGtid(0, 1, false, 0)
+	s.file.WriteQuery("INSERT INTO foo VALUES (1)")
+	s.file.WriteQuery("INSERT INTO foo VALUES (2)")
+	s.file.WriteXid(1)
+	s.file.WriteMariadbGtid(0, 2, true, 0)
+	s.file.WriteQuery("CREATE TABLE bar (id INT)")
+
+	events := s.readAll(c, s.file.GetReader())
+	c.Assert(events, HasLen, 7)
+
+	position := LogPosition{testSourceName, events[1].SourcePosition()}
+	expected := []LogPosition{
+		{testSourceName, NextReadPosition(events[0])}, // FDE
+		position, // GTID 1
+		position, // insert
+		position, // insert
+		{testSourceName, NextReadPosition(events[4])}, // XID
+		{testSourceName, events[5].SourcePosition()},  // GTID 2
+		{testSourceName, NextReadPosition(events[6])}, // DDL
+	}
+	inTransaction := []bool{false, true, true, true, false, true, false}
+
+	tracker := NewPositionTracker(LogPosition{testSourceName, 4}, false)
+	for idx, event := range events {
+		tracker.Processed(event)
+		c.Check(
+			tracker.Checkpoint(),
+			Equals,
+			expected[idx],
+			Commentf("event %d", idx))
+		c.Check(
+			tracker.InTransaction(),
+			Equals,
+			inTransaction[idx],
+			Commentf("event %d", idx))
+	}
+}
+
+func (s *PositionTrackerSuite) TestIncompleteTransaction(c *C) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(1)
+	s.file.WriteGtid(testSid1, 2)
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+
+	events := s.readAll(c, s.file.GetReader())
+	c.Assert(events, HasLen, 7)
+
+	tracker := NewPositionTracker(LogPosition{}, false)
+	for _, event := range events {
+		tracker.Processed(event)
+	}
+
+	// The incomplete transaction is abandoned.
+	c.Assert(
+		tracker.Checkpoint(),
+		Equals,
+		LogPosition{testSourceName, events[4].SourcePosition()})
+	c.Assert(tracker.InTransaction(), IsTrue)
+}
+
+func (s *PositionTrackerSuite) TestPerEvent(c *C) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(1)
+	s.file.WriteXid(1)
+
+	tracker := NewPositionTracker(LogPosition{}, true)
+	for _, event := range s.readAll(c, s.file.GetReader()) {
+		tracker.Processed(event)
+		c.Assert(
+			tracker.Checkpoint(),
+			Equals,
+			LogPosition{testSourceName, NextReadPosition(event)})
+		c.Assert(tracker.InTransaction(), IsFalse)
+	}
+}
+
+func (s *PositionTrackerSuite) TestResumeAfterCrash(c *C) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(1)
+	s.file.WriteXid(1)
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(2)
+	s.file.WriteInsert(3)
+	s.file.WriteXid(2)
+
+	data := s.file.Copy().logBuffer
+
+	reader := s.newReader(bytes.NewReader(data))
+	tracker := NewPositionTracker(LogPosition{}, false)
+
+	fde, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	tracker.Processed(fde)
+
+	// Process the first transaction, and part of the second transaction
+	// before crashing.
+	var applied []Event
+	for i := 0; i < 7; i++ {
+		event, err := reader.NextEvent()
+		c.Assert(err, IsNil)
+		tracker.Processed(event)
+		applied = append(applied, event)
+	}
+
+	q, ok := applied[4].(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(string(q.Query()), Equals, "BEGIN")
+
+	checkpoint := tracker.Checkpoint()
+	c.Assert(checkpoint.Position, Equals, applied[4].SourcePosition())
+	c.Assert(tracker.InTransaction(), IsTrue)
+
+	// Resume from the checkpoint (the log file header and the format
+	// description event must precede the resumed events).
+	resumed := []byte{}
+	resumed = append(resumed, data[:NextReadPosition(fde)]...)
+	resumed = append(resumed, data[checkpoint.Position:]...)
+
+	events := s.readAll(c, bytes.NewReader(resumed))
+	c.Assert(events, HasLen, 6)
+	events = events[1:]
+
+	// The whole second transaction is replayed.
+	c.Assert(events, HasLen, 5)
+	q, ok = events[0].(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(string(q.Query()), Equals, "BEGIN")
+
+	c.Assert(events[2].(*WriteRowsEvent).InsertedRows(), HasLen, 1)
+	c.Assert(events[3].(*WriteRowsEvent).InsertedRows(), HasLen, 1)
+
+	x, ok := events[4].(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(x.Xid(), Equals, uint64(2))
+
+	for idx, event := range events[:3] {
+		c.Assert(event.Bytes(), DeepEquals, applied[idx+4].Bytes())
+	}
+}
+
+func (s *PositionTrackerSuite) TestLeadingArtificialRotate(c *C) {
+	// A replication stream begins with an artificial rotate event, followed
+	// by the master's format description event.
+	stream := NewMockLogFile()
+	stream.WriteLogFileMagic()
+	stream.WriteArtificialRotate(testBinPrefix, 42)
+	stream.Write(s.file.logBuffer[len(logFileMagic):])
+	stream.WriteBegin()
+	stream.WriteTableMap()
+	stream.WriteInsert(1)
+	stream.WriteXid(1)
+	stream.WriteRotate(testBinPrefix, 43)
+
+	events := s.readAll(c, stream.GetReader())
+	c.Assert(events, HasLen, 7)
+
+	rotate, ok := events[0].(*RotateEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(rotate.IsArtificial(), IsTrue)
+	c.Assert(rotate.NextPosition(), Equals, uint32(0))
+
+	initial := LogPosition{testSourceName, 4}
+	for _, perEvent := range []bool{false, true} {
+		tracker := NewPositionTracker(initial, perEvent)
+		c.Assert(tracker.LogFile(), Equals, "")
+
+		tracker.Processed(events[0])
+		c.Assert(tracker.Checkpoint(), Equals, initial)
+		c.Assert(tracker.InTransaction(), IsFalse)
+		c.Assert(tracker.LogFile(), Equals, logName(testBinPrefix, 42))
+
+		for _, event := range events[1:6] {
+			tracker.Processed(event)
+		}
+		c.Assert(
+			tracker.Checkpoint(),
+			Equals,
+			LogPosition{testSourceName, NextReadPosition(events[5])})
+		c.Assert(tracker.LogFile(), Equals, logName(testBinPrefix, 42))
+
+		// Regular rotate events advance the checkpoint.
+		c.Assert(events[6].(*RotateEvent).IsArtificial(), IsFalse)
+		tracker.Processed(events[6])
+		c.Assert(
+			tracker.Checkpoint(),
+			Equals,
+			LogPosition{testSourceName, NextReadPosition(events[6])})
+		c.Assert(tracker.LogFile(), Equals, logName(testBinPrefix, 43))
+	}
+}
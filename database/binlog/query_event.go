@@ -9,6 +9,19 @@ import (
 
 const MaxDbsInEventMts = 254
 
+// Query status codes which are not defined by mysql_proto.QueryStatusCode.
+const (
+	queryStatusExplicitDefaultsForTimestamp mysql_proto.QueryStatusCode_Type = 16
+	queryStatusDdlLoggedWithXid             mysql_proto.QueryStatusCode_Type = 17
+	queryStatusDefaultCollationForUtf8mb4   mysql_proto.QueryStatusCode_Type = 18
+	queryStatusSqlRequirePrimaryKey         mysql_proto.QueryStatusCode_Type = 19
+	queryStatusDefaultTableEncryption       mysql_proto.QueryStatusCode_Type = 20
+
+	// MariaDB specific status codes.
+	queryStatusMariadbHrnow mysql_proto.QueryStatusCode_Type = 128
+	queryStatusMariadbXid   mysql_proto.QueryStatusCode_Type = 129
+)
+
 // A representation of the query event.
 //
 // Query event's binlog payload is structured as follow:
@@ -53,7 +66,7 @@ const MaxDbsInEventMts = 254
 //          table map for update:
 //              1 byte for Q_TABLE_MAP_FOR_UPDATE (= 9)
 //              8 bytes (uint64) for table map for update
-//          master data written: (not used by v4 events)
+//          master data written: (only written to relay logs by old slaves)
 //              1 byte for Q_MASTER_DATA_WRITTEN (= 10)
 //              4 bytes (uint32) for master data written
 //          invoker:
@@ -70,10 +83,35 @@ const MaxDbsInEventMts = 254
 //          microseconds:
 //              1 byte for Q_MICROSECONDS (= 13)
 //              3 bytes (uint24) for microseconds
+//          explicit defaults for timestamp:
+//              1 byte for Q_EXPLICIT_DEFAULTS_FOR_TIMESTAMP (= 16)
+//              1 byte (bool) for explicit_defaults_for_timestamp
+//          ddl logged with xid:
+//              1 byte for Q_DDL_LOGGED_WITH_XID (= 17)
+//              8 bytes (uint64) for the ddl's xid
+//          default collation for utf8mb4:
+//              1 byte for Q_DEFAULT_COLLATION_FOR_UTF8MB4 (= 18)
+//              2 bytes (uint16) for collation number
+//          sql require primary key:
+//              1 byte for Q_SQL_REQUIRE_PRIMARY_KEY (= 19)
+//              1 byte (bool) for sql_require_primary_key
+//          default table encryption:
+//              1 byte for Q_DEFAULT_TABLE_ENCRYPTION (= 20)
+//              1 byte (bool) for default_table_encryption
+//          (MariaDB) high resolution now:
+//              1 byte for Q_HRNOW (= 128)
+//              3 bytes (uint24), skipped
+//          (MariaDB) xid:
+//              1 byte for Q_XID (= 129)
+//              8 bytes (uint64), skipped
 //      X bytes for the database name (zero terminated)
 //      the remaining is for the query (not zero terminated).
 //  5.6 Specific:
 //      (optional) 4 bytes footer for checksum.
+//
+// NOTE: Like mysql, the parser stops parsing the status block at the first
+// unknown status code, since the code's value length is unknown (the rest of
+// the status block is still accessible via StatusBytes).
 type QueryEvent struct {
 	Event
 
@@ -101,6 +139,13 @@ type QueryEvent struct {
 	numUpdatedDbs         *uint8
 	updatedDbNames        [][]byte
 	microseconds          *uint32
+
+	masterDataWritten            *uint32
+	explicitDefaultsForTimestamp *bool
+	ddlXid                       *uint64
+	defaultCollationForUtf8mb4   *uint16
+	sqlRequirePrimaryKey         *bool
+	defaultTableEncryption       *bool
 }
 
 // ThreadId returns the thread id which executed the query.
@@ -236,12 +281,51 @@ func (e *QueryEvent) Microseconds() *uint32 {
 	return e.microseconds
 }
 
+// MasterDataWritten returns the master data written status.  This returns nil
+// if the status is not set.
+func (e *QueryEvent) MasterDataWritten() *uint32 {
+	return e.masterDataWritten
+}
+
+// ExplicitDefaultsForTimestamp returns the explicit_defaults_for_timestamp
+// status.  This returns nil if the status is not set.
+func (e *QueryEvent) ExplicitDefaultsForTimestamp() *bool {
+	return e.explicitDefaultsForTimestamp
+}
+
+// DdlXid returns the xid of the (atomic) ddl statement.  This returns nil if
+// the status is not set.
+func (e *QueryEvent) DdlXid() *uint64 {
+	return e.ddlXid
+}
+
+// DefaultCollationForUtf8mb4 returns the default collation number for
+// utf8mb4 status.  This returns nil if the status is not set.
+func (e *QueryEvent) DefaultCollationForUtf8mb4() *uint16 {
+	return e.defaultCollationForUtf8mb4
+}
+
+// SqlRequirePrimaryKey returns the sql_require_primary_key status.  This
+// returns nil if the status is not set.
+func (e *QueryEvent) SqlRequirePrimaryKey() *bool {
+	return e.sqlRequirePrimaryKey
+}
+
+// DefaultTableEncryption returns the default_table_encryption status.  This
+// returns nil if the status is not set.
+func (e *QueryEvent) DefaultTableEncryption() *bool {
+	return e.defaultTableEncryption
+}
+
 //
 // QueryEventParser -----------------------------------------------------------
 //
 
 type QueryEventParser struct {
 	hasNoTableContext
+
+	// When non-nil, parsed query events are applied to the session state.
+	session *SessionState
 }
 
 // QueryEventParser's EventType always returns
@@ -257,6 +341,25 @@ func (p *QueryEventParser) FixedLengthDataSize() int {
 
 // QueryEventParser's Parse processes a raw query event into a QueryEvent.
 func (p *QueryEventParser) Parse(raw *RawV4Event) (Event, error) {
+	query, err := p.parseQuery(raw, raw.FixedLengthData())
+	if err != nil {
+		return raw, err
+	}
+
+	if p.session != nil {
+		p.session.Update(query)
+	}
+
+	return query, nil
+}
+
+// This parses the query event's body.  fixedData's prefix must contain
+// the query event's 13 bytes fixed length data.  (This is shared with the
+// execute load query event, which extends the fixed length data.)
+func (p *QueryEventParser) parseQuery(raw *RawV4Event, fixedData []byte) (
+	*QueryEvent,
+	error) {
+
 	query := &QueryEvent{
 		Event: raw,
 	}
@@ -271,9 +374,9 @@ func (p *QueryEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	fixed := fixedBodyStruct{}
 
-	_, err := readLittleEndian(raw.FixedLengthData(), &fixed)
+	_, err := readLittleEndian(fixedData, &fixed)
 	if err != nil {
-		return raw, errors.Wrap(err, "Failed to read fixed body")
+		return nil, errors.Wrap(err, "Failed to read fixed body")
 	}
 
 	query.threadId = fixed.ThreadId
@@ -284,7 +387,7 @@ func (p *QueryEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	dbNameEnd := int(fixed.StatusLength) + int(fixed.DatabaseNameLength)
 	if dbNameEnd+1 > len(data) {
-		return raw, errors.Newf("Invalid message length")
+		return nil, errors.Newf("Invalid message length")
 	}
 
 	query.statusBytes = data[:fixed.StatusLength]
@@ -295,7 +398,7 @@ func (p *QueryEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	err = p.parseStatus(query)
 	if err != nil {
-		return raw, err
+		return nil, err
 	}
 
 	return query, nil
@@ -347,8 +450,8 @@ func (p *QueryEventParser) parseStatus(q *QueryEvent) error {
 			data, err = readLittleEndian(data, q.tableMapForUpdate)
 
 		case mysql_proto.QueryStatusCode_MASTER_DATA_WRITTEN:
-			return errors.New(
-				"V4 events does not support master data written status")
+			q.masterDataWritten = new(uint32)
+			data, err = readLittleEndian(data, q.masterDataWritten)
 
 		case mysql_proto.QueryStatusCode_INVOKER:
 			data, err = p.parseInvoker(data, q)
@@ -359,8 +462,33 @@ func (p *QueryEventParser) parseStatus(q *QueryEvent) error {
 		case mysql_proto.QueryStatusCode_MICROSECONDS:
 			data, err = p.parseMircoseconds(data, q)
 
+		case queryStatusExplicitDefaultsForTimestamp:
+			q.explicitDefaultsForTimestamp, data, err = readBoolStatus(data)
+
+		case queryStatusDdlLoggedWithXid:
+			q.ddlXid = new(uint64)
+			data, err = readLittleEndian(data, q.ddlXid)
+
+		case queryStatusDefaultCollationForUtf8mb4:
+			q.defaultCollationForUtf8mb4 = new(uint16)
+			data, err = readLittleEndian(data, q.defaultCollationForUtf8mb4)
+
+		case queryStatusSqlRequirePrimaryKey:
+			q.sqlRequirePrimaryKey, data, err = readBoolStatus(data)
+
+		case queryStatusDefaultTableEncryption:
+			q.defaultTableEncryption, data, err = readBoolStatus(data)
+
+		case queryStatusMariadbHrnow:
+			_, data, err = readSlice(data, 3)
+
+		case queryStatusMariadbXid:
+			_, data, err = readSlice(data, 8)
+
 		default:
-			return errors.Newf("Unknown query status code: %d", int(code))
+			// The value's length is unknown, hence the rest of the status
+			// block can't be parsed.
+			return nil
 		}
 
 		if err != nil {
@@ -374,6 +502,16 @@ func (p *QueryEventParser) parseStatus(q *QueryEvent) error {
 	return nil
 }
 
+func readBoolStatus(data []byte) (*bool, []byte, error) {
+	b, data, err := readSlice(data, 1)
+	if err != nil {
+		return nil, data, err
+	}
+
+	value := b[0] != 0
+	return &value, data, nil
+}
+
 func (p *QueryEventParser) parseAutoIncStatus(data []byte, q *QueryEvent) (
 	[]byte,
 	error) {
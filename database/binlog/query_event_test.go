@@ -303,3 +303,76 @@ func (s *QueryEventSuite) TestUpdatedDbNamesStatus(c *C) {
 	c.Check(string(q.UpdatedDbNames()[2]), Equals, "asdf")
 	c.Check(string(q.UpdatedDbNames()[3]), Equals, "zzz")
 }
+
+func (s *QueryEventSuite) TestAdditionalStatus(c *C) {
+	s.WriteEventStatus([]byte{
+		// master data written
+		10, 42, 0, 0, 0,
+		// updated db names
+		12, 2, 'f', 'o', 'o', 0, 'b', 'a', 'r', 0,
+		// explicit defaults for timestamp
+		16, 1,
+		// ddl logged with xid
+		17, 8, 0, 0, 0, 0, 0, 0, 0,
+		// default collation for utf8mb4
+		18, 255, 0,
+		// sql require primary key
+		19, 0,
+		// default table encryption
+		20, 1,
+		// (MariaDB) hrnow
+		128, 1, 2, 3,
+		// (MariaDB) xid
+		129, 1, 2, 3, 4, 5, 6, 7, 8,
+		// microseconds
+		13, 9, 0, 0})
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	q, ok := event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+
+	c.Assert(q.MasterDataWritten(), NotNil)
+	c.Assert(q.NumUpdatedDbs(), NotNil)
+	c.Assert(q.ExplicitDefaultsForTimestamp(), NotNil)
+	c.Assert(q.DdlXid(), NotNil)
+	c.Assert(q.DefaultCollationForUtf8mb4(), NotNil)
+	c.Assert(q.SqlRequirePrimaryKey(), NotNil)
+	c.Assert(q.DefaultTableEncryption(), NotNil)
+	c.Assert(q.Microseconds(), NotNil)
+
+	c.Check(*q.MasterDataWritten(), Equals, uint32(42))
+	c.Check(*q.NumUpdatedDbs(), Equals, uint8(2))
+	c.Assert(len(q.UpdatedDbNames()), Equals, 2)
+	c.Check(string(q.UpdatedDbNames()[0]), Equals, "foo")
+	c.Check(string(q.UpdatedDbNames()[1]), Equals, "bar")
+	c.Check(*q.ExplicitDefaultsForTimestamp(), IsTrue)
+	c.Check(*q.DdlXid(), Equals, uint64(8))
+	c.Check(*q.DefaultCollationForUtf8mb4(), Equals, uint16(255))
+	c.Check(*q.SqlRequirePrimaryKey(), IsFalse)
+	c.Check(*q.DefaultTableEncryption(), IsTrue)
+	c.Check(*q.Microseconds(), Equals, uint32(9))
+}
+
+func (s *QueryEventSuite) TestUnknownStatus(c *C) {
+	s.WriteEventStatus([]byte{
+		// flags2
+		0, 1, 0, 0, 0,
+		// unknown status code
+		100, 1, 2,
+		// lc time
+		7, 5, 0})
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	q, ok := event.(*QueryEvent)
+	c.Assert(ok, IsTrue)
+
+	// Parsing stops at the unknown status code.
+	c.Assert(q.Flags2(), NotNil)
+	c.Check(*q.Flags2(), Equals, uint32(1))
+	c.Check(q.LcTimeNamesNumber(), IsNil)
+	c.Check(len(q.StatusBytes()), Equals, 11)
+}
@@ -7,13 +7,46 @@ import (
 	"github.com/dropbox/godropbox/errors"
 )
 
+// ErrIncompleteEvent is returned by readers configured with
+// ReportIncompleteEvent when the source stream ends in the middle of an
+// event.  The partially read event is retained by the reader; calling
+// NextEvent again (after more bytes are available) resumes parsing.
+var ErrIncompleteEvent = errors.New("Incomplete binlog event")
+
+// Options for configuring the raw v4 event reader.
+type RawV4EventReaderOptions struct {
+	// When true, NextEvent returns ErrIncompleteEvent (instead of io.EOF) when
+	// the source stream ends in the middle of an event.  This allows tailers
+	// to distinguish a partially written trailing event from a clean end of
+	// stream.
+	ReportIncompleteEvent bool
+
+	// When true, each event retains a private copy of its raw bytes (as read
+	// from the source stream), which is accessible via Event.RawBytes.  This
+	// is useful for forwarding the original events downstream while also
+	// inspecting the decoded content.
+	RetainRawBytes bool
+
+	// When true, the reader verifies that the events' log_pos (i.e.,
+	// NextPosition) strictly increase within the stream, and returns the
+	// event along with an error on regression.  A regression indicates
+	// corruption or an out of order splice.  Events with zero log_pos (e.g.,
+	// artificial events in relay logs) are not checked.
+	ValidateLogPositions bool
+}
+
 type rawV4EventReader struct {
 	src             io.Reader
 	srcName         string
+	options         RawV4EventReaderOptions
 	logPosition     int64
 	rawHeaderBuffer []byte
 	isClosed        bool
 
+	// The largest log_pos seen so far (only tracked when validating log
+	// positions).
+	lastNextPosition uint32
+
 	// NOTE: new nextEvent, headerBuffer and bodyBuffer are allocated for each
 	// event.  The pointers are reset to nil upon successfully parsing an event.
 	nextEvent    *RawV4Event
@@ -30,10 +63,23 @@ type rawV4EventReader struct {
 // and checksum (i.e. event.VariableLengthData() will return the entire event
 // payload).
 func NewRawV4EventReader(src io.Reader, srcName string) EventReader {
+	return NewRawV4EventReaderWithOptions(
+		src,
+		srcName,
+		RawV4EventReaderOptions{})
+}
+
+// Same as NewRawV4EventReader, but with configurable options.
+func NewRawV4EventReaderWithOptions(
+	src io.Reader,
+	srcName string,
+	options RawV4EventReaderOptions) EventReader {
+
 	buf := make([]byte, sizeOfBasicV4EventHeader, sizeOfBasicV4EventHeader)
 	return &rawV4EventReader{
 		src:             src,
 		srcName:         srcName,
+		options:         options,
 		logPosition:     0,
 		rawHeaderBuffer: buf,
 		isClosed:        false,
@@ -80,11 +126,28 @@ func (r *rawV4EventReader) nextEventEndPosition() int64 {
 	return r.logPosition + int64(r.nextEvent.header.EventLength)
 }
 
+func (r *rawV4EventReader) NextPosition() int64 {
+	return r.logPosition
+}
+
 func (r *rawV4EventReader) Close() error {
 	r.isClosed = true
 	return nil
 }
 
+// See ResettableEventReader for documentation.  The raw header buffer is
+// retained.
+func (r *rawV4EventReader) Reset(src io.Reader, srcName string) {
+	r.src = src
+	r.srcName = srcName
+	r.logPosition = 0
+	r.isClosed = false
+	r.lastNextPosition = 0
+	r.nextEvent = nil
+	r.headerBuffer = nil
+	r.bodyBuffer = nil
+}
+
 func (r *rawV4EventReader) NextEvent() (Event, error) {
 	if r.isClosed {
 		return nil, errors.New("Event reader is closed")
@@ -100,10 +163,10 @@ func (r *rawV4EventReader) NextEvent() (Event, error) {
 	if r.nextEvent.data == nil { // still parsing the header
 		headerBytes, err := r.getHeaderBuffer().PeekAll()
 		if err != nil {
-			return nil, err
+			return nil, r.maybeIncompleteEventError(err)
 		}
 
-		_, err = readLittleEndian(headerBytes, &r.nextEvent.header)
+		err = parseBasicV4EventHeader(headerBytes, &r.nextEvent.header)
 		if err != nil {
 			return nil, err
 		}
@@ -130,7 +193,7 @@ func (r *rawV4EventReader) NextEvent() (Event, error) {
 
 	_, err := r.bodyBuffer.PeekAll()
 	if err != nil {
-		return nil, err
+		return nil, r.maybeIncompleteEventError(err)
 	}
 
 	// consume the constructed event and clean the look ahead buffers
@@ -141,5 +204,47 @@ func (r *rawV4EventReader) NextEvent() (Event, error) {
 
 	r.logPosition += int64(event.EventLength())
 
+	if r.options.RetainRawBytes {
+		event.rawBytes = make([]byte, len(event.data))
+		copy(event.rawBytes, event.data)
+	}
+
+	if r.options.ValidateLogPositions {
+		return event, r.validateLogPosition(event)
+	}
+
 	return event, nil
 }
+
+func (r *rawV4EventReader) validateLogPosition(event *RawV4Event) error {
+	nextPosition := event.NextPosition()
+	if nextPosition == 0 {
+		return nil
+	}
+
+	if nextPosition <= r.lastNextPosition {
+		return errors.Newf(
+			"Invalid log_pos for event at %s:%d: %d (previous log_pos: %d)",
+			event.SourceName(),
+			event.SourcePosition(),
+			nextPosition,
+			r.lastNextPosition)
+	}
+
+	r.lastNextPosition = nextPosition
+	return nil
+}
+
+// This converts io.EOF into ErrIncompleteEvent when the reader is configured
+// to report incomplete events and some of the event's bytes were read.
+func (r *rawV4EventReader) maybeIncompleteEventError(err error) error {
+	if err != io.EOF || !r.options.ReportIncompleteEvent {
+		return err
+	}
+
+	if r.bodyBuffer != nil || r.getHeaderBuffer().BytesBuffered() > 0 {
+		return ErrIncompleteEvent
+	}
+
+	return err
+}
@@ -6,6 +6,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	. "github.com/dropbox/godropbox/gocheck2"
 	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
 )
 
@@ -264,6 +265,73 @@ func (s *RawV4EventReaderSuite) TestEOFMidBody(c *C) {
 	c.Check(err, Equals, io.EOF)
 }
 
+func (s *RawV4EventReaderSuite) TestIncompleteEvent(c *C) {
+	s.reader = NewRawV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		RawV4EventReaderOptions{ReportIncompleteEvent: true})
+
+	eventBytes1 := s.GenerateEvent(1, 2, 3, 4, 5, 10)
+	eventBytes2 := s.GenerateEvent(6, 7, 8, 9, 10, 3)
+
+	// Nothing to read.
+	event, err := s.reader.NextEvent()
+	c.Assert(err, Equals, io.EOF)
+	c.Check(event, IsNil)
+
+	// Truncated mid header.
+	_, err = s.src.Write(eventBytes1[:5])
+	c.Assert(err, IsNil)
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, Equals, ErrIncompleteEvent)
+	c.Check(event, IsNil)
+	c.Check(IsRetryableError(err), IsTrue)
+
+	// Truncated mid body.
+	_, err = s.src.Write(eventBytes1[5 : sizeOfBasicV4EventHeader+4])
+	c.Assert(err, IsNil)
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, Equals, ErrIncompleteEvent)
+	c.Check(event, IsNil)
+
+	// Write the remaining bytes.
+	_, err = s.src.Write(eventBytes1[sizeOfBasicV4EventHeader+4:])
+	c.Assert(err, IsNil)
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.SourcePosition(), Equals, int64(0))
+	c.Check(event.Timestamp(), Equals, uint32(1))
+	c.Check(event.EventLength(), Equals, uint32(len(eventBytes1)))
+	c.Check(
+		event.VariableLengthData(),
+		DeepEquals,
+		[]byte("\xfe\xfe\xfe\xfe\xfe\xfe\xfe\xfe\xfe\xfe"))
+
+	// Clean end of stream.
+	event, err = s.reader.NextEvent()
+	c.Assert(err, Equals, io.EOF)
+	c.Check(event, IsNil)
+
+	_, err = s.src.Write(eventBytes2[:len(eventBytes2)-1])
+	c.Assert(err, IsNil)
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, Equals, ErrIncompleteEvent)
+	c.Check(event, IsNil)
+
+	_, err = s.src.Write(eventBytes2[len(eventBytes2)-1:])
+	c.Assert(err, IsNil)
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.SourcePosition(), Equals, int64(len(eventBytes1)))
+	c.Check(event.Timestamp(), Equals, uint32(6))
+	c.Check(event.VariableLengthData(), DeepEquals, []byte("\xfe\xfe\xfe"))
+}
+
 func (s *RawV4EventReaderSuite) TestInvalidNegBodyLength(c *C) {
 	eventBytes := []byte(
 		"\x04\x03\x02\x01" + // timestamp
@@ -410,3 +478,51 @@ func (s *RawV4EventReaderSuite) TestInvalidEOFMidBodyConsumeHeaderBytes(c *C) {
 	const expected = "Cannot consume header bytes"
 	c.Assert(err.Error()[:len(expected)], Equals, expected)
 }
+
+func (s *RawV4EventReaderSuite) TestValidateLogPositions(c *C) {
+	s.reader = NewRawV4EventReaderWithOptions(
+		s.src,
+		testSourceName,
+		RawV4EventReaderOptions{ValidateLogPositions: true})
+
+	eventBytes1 := s.GenerateEvent(1, 2, 3, 100, 0, 10)
+	eventBytes2 := s.GenerateEvent(1, 2, 3, 0, 0, 10) // artificial event
+	eventBytes3 := s.GenerateEvent(1, 2, 3, 200, 0, 10)
+	eventBytes4 := s.GenerateEvent(1, 2, 3, 150, 0, 10)
+	eventBytes5 := s.GenerateEvent(1, 2, 3, 300, 0, 10)
+
+	for _, b := range [][]byte{
+		eventBytes1,
+		eventBytes2,
+		eventBytes3,
+		eventBytes4,
+		eventBytes5,
+	} {
+		_, err := s.src.Write(b)
+		c.Assert(err, IsNil)
+	}
+
+	for _, expected := range []uint32{100, 0, 200} {
+		event, err := s.reader.NextEvent()
+		c.Assert(err, IsNil)
+		c.Check(event.NextPosition(), Equals, expected)
+	}
+
+	// log_pos goes backwards.
+	event, err := s.reader.NextEvent()
+	c.Assert(err, NotNil)
+	c.Check(
+		err.Error(),
+		Matches,
+		"(?s)Invalid log_pos for event at test_stream:87: "+
+			"150 \\(previous log_pos: 200\\).*")
+	c.Assert(event, NotNil)
+	c.Check(event.NextPosition(), Equals, uint32(150))
+
+	event, err = s.reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event.NextPosition(), Equals, uint32(300))
+
+	_, err = s.reader.NextEvent()
+	c.Assert(err, Equals, io.EOF)
+}
@@ -0,0 +1,29 @@
+package binlog
+
+// RawValue is the packed (binlog encoded) representation of a single decoded
+// column value, i.e., the exact bytes the value was decoded from.  This is
+// useful for diagnosing field descriptor bugs.  See DecodeOptions.RawValues.
+type RawValue struct {
+	// The value's offset relative to the beginning of the rows event's row
+	// data (see BaseRowsEvent.RowDataBytes).
+	Offset int
+
+	// The value's bytes.  This is nil for NULL values (which are only
+	// encoded in the row's null bitmap).  NOTE: Bytes references the rows
+	// event's data; it must not be modified.
+	Bytes []byte
+}
+
+// A single row's used columns raw values, in the same order as the row's
+// (decoded) RowValues.
+type RawRowValues []RawValue
+
+// A single update row's used columns raw values.
+type UpdateRawRowValues struct {
+	BeforeImage RawRowValues
+	AfterImage  RawRowValues
+}
+
+type rawValuesSetter interface {
+	SetRawValues(enabled bool)
+}
@@ -0,0 +1,181 @@
+package binlog
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type RawValuesSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&RawValuesSuite{})
+
+func (s *RawValuesSuite) useParsers(c *C, options DecodeOptions) {
+	varchar, _, err := NewVarcharFieldDescriptor(true, []byte{10, 0})
+	c.Assert(err, IsNil)
+
+	datetime, _, err := NewDateTime2FieldDescriptor(true, []byte{0})
+	c.Assert(err, IsNil)
+
+	s.parsers = NewV4EventParserMapWithOptions(options)
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+	s.parsers.SetTableContext(&testTableContext{
+		columns: []ColumnDescriptor{
+			NewColumnDescriptor(varchar, 0),
+			NewColumnDescriptor(NewLongFieldDescriptor(true), 1),
+			NewColumnDescriptor(datetime, 2),
+		},
+	})
+}
+
+// Row 0 (varchar = "abc", long = 7, datetime2 = 2015-06-17 23:45:12), and
+// row 1 (varchar = "", long = nil, datetime2 = 2015-06-17 23:45:12).
+func (s *RawValuesSuite) rowData() []byte {
+	buf := &bytes.Buffer{}
+
+	buf.WriteByte(0)
+	buf.Write([]byte{3, 'a', 'b', 'c'})
+	buf.Write([]byte{7, 0, 0, 0})
+	buf.Write(testDateTime2Bytes())
+
+	buf.WriteByte(2)
+	buf.Write([]byte{0})
+	buf.Write(testDateTime2Bytes())
+
+	return buf.Bytes()
+}
+
+func (s *RawValuesSuite) writeRows(eventType mysql_proto.LogEventType_Type) {
+	s.WriteEvent(
+		eventType,
+		uint16(0),
+		append(
+			[]byte{
+				// table id
+				testRowsTableId, 0, 0, 0, 0, 0,
+				// flags
+				0, 0,
+				// # of columns
+				3,
+				// used columns
+				7,
+			},
+			s.rowData()...))
+}
+
+func (s *RawValuesSuite) checkRawRows(c *C, rows []RawRowValues) {
+	c.Assert(rows, HasLen, 2)
+
+	c.Check(
+		rows[0],
+		DeepEquals,
+		RawRowValues{
+			{Offset: 1, Bytes: []byte{3, 'a', 'b', 'c'}},
+			{Offset: 5, Bytes: []byte{7, 0, 0, 0}},
+			{Offset: 9, Bytes: testDateTime2Bytes()},
+		})
+
+	c.Check(
+		rows[1],
+		DeepEquals,
+		RawRowValues{
+			{Offset: 15, Bytes: []byte{0}},
+			{Offset: 16, Bytes: nil},
+			{Offset: 16, Bytes: testDateTime2Bytes()},
+		})
+}
+
+func (s *RawValuesSuite) TestDisabled(c *C) {
+	s.useParsers(c, DecodeOptions{})
+	s.writeRows(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1)
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	w, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(w.InsertedRows(), HasLen, 2)
+	c.Check(w.InsertedRawRows(), IsNil)
+}
+
+func (s *RawValuesSuite) TestWriteRows(c *C) {
+	s.useParsers(c, DecodeOptions{RawValues: true})
+	s.writeRows(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1)
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	w, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(w.InsertedRows(), HasLen, 2)
+	c.Check(w.InsertedRows()[0][0], DeepEquals, []byte("abc"))
+	c.Check(w.InsertedRows()[1][1], IsNil)
+
+	s.checkRawRows(c, w.InsertedRawRows())
+
+	// The raw values reference the event's row data.
+	for _, row := range w.InsertedRawRows() {
+		for _, value := range row {
+			if value.Bytes == nil { // null
+				continue
+			}
+			c.Check(
+				w.RowDataBytes()[value.Offset:value.Offset+len(value.Bytes)],
+				DeepEquals,
+				value.Bytes)
+		}
+	}
+}
+
+func (s *RawValuesSuite) TestDeleteRows(c *C) {
+	s.useParsers(c, DecodeOptions{RawValues: true})
+	s.writeRows(mysql_proto.LogEventType_DELETE_ROWS_EVENT_V1)
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	d, ok := event.(*DeleteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(d.DeletedRows(), HasLen, 2)
+
+	s.checkRawRows(c, d.DeletedRawRows())
+}
+
+func (s *RawValuesSuite) TestUpdateRows(c *C) {
+	s.useParsers(c, DecodeOptions{RawValues: true})
+	s.WriteEvent(
+		mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1,
+		uint16(0),
+		append(
+			[]byte{
+				// table id
+				testRowsTableId, 0, 0, 0, 0, 0,
+				// flags
+				0, 0,
+				// # of columns
+				3,
+				// before image used columns
+				7,
+				// after image used columns
+				7,
+			},
+			s.rowData()...))
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	u, ok := event.(*UpdateRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Assert(u.UpdatedRows(), HasLen, 1)
+
+	rawRows := u.UpdatedRawRows()
+	c.Assert(rawRows, HasLen, 1)
+	s.checkRawRows(
+		c,
+		[]RawRowValues{rawRows[0].BeforeImage, rawRows[0].AfterImage})
+}
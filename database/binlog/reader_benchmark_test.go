@@ -0,0 +1,131 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// The reader benchmark streams a small fixture binlog (testdata/
+// reader_benchmark.binlog) from memory through the log file reader, and
+// reports the decode throughput in both MB/s and events/s.  Use
+//
+//	go test -run NONE -bench ReadFixture ./database/binlog/
+//
+// to run the benchmark.  The fixture is generated by
+// writeReaderBenchmarkFixture; ReaderBenchmarkSuite verifies that the
+// committed fixture is up to date.
+
+const (
+	readerBenchmarkFixturePath = "testdata/reader_benchmark.binlog"
+
+	readerBenchmarkNumTransactions = 200
+
+	// 1 FDE + 7 events per transaction + 1 rotate.
+	readerBenchmarkNumEvents = 2 + 7*readerBenchmarkNumTransactions
+)
+
+// Each transaction contains a gtid, a begin query, a table map, an insert, an
+// update, a delete and a xid event.  The log file ends with a rotate event.
+func writeReaderBenchmarkFixture(file *MockLogFile) {
+	file.WriteLogFileMagic()
+	file.Write56FDE()
+
+	for i := 0; i < readerBenchmarkNumTransactions; i++ {
+		file.WriteGtid(testSid1, uint64(i+1))
+		file.WriteBegin()
+		file.WriteTableMap()
+		file.WriteInsert(i)
+		file.WriteUpdate(i, i+1)
+		file.WriteDelete(i + 1)
+		file.WriteXid(uint64(i + 1))
+	}
+
+	file.WriteRotate(testBinPrefix, 2)
+}
+
+func loadReaderBenchmarkFixture() ([]byte, error) {
+	return ioutil.ReadFile(readerBenchmarkFixturePath)
+}
+
+func newReaderBenchmarkReader(data []byte) EventReader {
+	noop := func(pattern string, values ...interface{}) {}
+
+	return NewLogFileV4EventReader(
+		bytes.NewReader(data),
+		readerBenchmarkFixturePath,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        noop,
+			VerboseInfof: noop,
+		})
+}
+
+// Reads all events in data.  Returns the number of events read.
+func readAllReaderBenchmarkEvents(data []byte) (int, error) {
+	reader := newReaderBenchmarkReader(data)
+
+	numEvents := 0
+	for {
+		_, err := reader.NextEvent()
+		if err == io.EOF {
+			return numEvents, nil
+		}
+		if err != nil {
+			return numEvents, err
+		}
+		numEvents++
+	}
+}
+
+func BenchmarkReadFixture(b *testing.B) {
+	data, err := loadReaderBenchmarkFixture()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	start := time.Now()
+	totalEvents := 0
+	for i := 0; i < b.N; i++ {
+		numEvents, err := readAllReaderBenchmarkEvents(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		totalEvents += numEvents
+	}
+	elapsed := time.Since(start)
+
+	b.ReportMetric(float64(totalEvents)/elapsed.Seconds(), "events/s")
+}
+
+type ReaderBenchmarkSuite struct {
+}
+
+var _ = Suite(&ReaderBenchmarkSuite{})
+
+func (s *ReaderBenchmarkSuite) TestFixture(c *C) {
+	data, err := loadReaderBenchmarkFixture()
+	c.Assert(err, IsNil)
+
+	file := NewMockLogFile()
+	writeReaderBenchmarkFixture(file)
+	c.Assert(
+		bytes.Equal(data, file.logBuffer),
+		Equals,
+		true,
+		Commentf("%s is out of date", readerBenchmarkFixturePath))
+
+	numEvents, err := readAllReaderBenchmarkEvents(data)
+	c.Assert(err, IsNil)
+	c.Assert(numEvents, Equals, readerBenchmarkNumEvents)
+}
@@ -0,0 +1,209 @@
+package binlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// RedactionFunc returns the redacted replacement of a (non-null) column
+// value.
+type RedactionFunc func(value interface{}) interface{}
+
+// RedactionPolicy maps "<database>.<table>" to the table's redacted columns,
+// keyed by column name.  Columns are named col_<index> when the table map
+// event does not include the column names.  Columns without an entry are
+// kept as is.
+type RedactionPolicy map[string]map[string]RedactionFunc
+
+// Null redacts the value by replacing it with NULL (nil).
+func Null(value interface{}) interface{} {
+	return nil
+}
+
+// Hash redacts the value by replacing it with the (hex encoded) SHA-256 hash
+// of the value's bytes.  Values which are not []byte / string / *LazyBlob
+// (e.g., integers) are hashed by their default formatting.  The hash is
+// returned as []byte.  NOTE: low entropy values (e.g., phone numbers) can be
+// recovered from their hashes by brute force.
+func Hash(value interface{}) interface{} {
+	sum := sha256.Sum256(redactionBytes(value))
+
+	result := make([]byte, hex.EncodedLen(len(sum)))
+	hex.Encode(result, sum[:])
+	return result
+}
+
+// Mask redacts the value by replacing each of the value's characters with
+// '*'.  Values which are not []byte / string / *LazyBlob (e.g., integers)
+// are masked by their default formatting.  The mask is returned as []byte.
+func Mask(value interface{}) interface{} {
+	return bytes.Repeat(
+		[]byte{'*'},
+		utf8.RuneCount(redactionBytes(value)))
+}
+
+func redactionBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case *LazyBlob:
+		return v.RawBytes()
+	}
+	return []byte(fmt.Sprintf("%v", value))
+}
+
+// RedactingTransformer applies redaction functions to rows events' column
+// values (e.g., for scrubbing PII before writing the values to analytics
+// stores).  NULL values remain NULL; redaction functions are only applied to
+// non-null values.  Rows events for tables without policy entries are
+// returned as is.
+//
+// Since statements can embed redacted values in their text, and their tables
+// cannot be determined reliably, query text (query, rows query and annotate
+// rows events) has its string literals (and, for DML statements, numeric
+// literals' digits) masked, and LOAD DATA blocks have their alphanumeric
+// bytes masked, whenever the policy is non-empty.  Legacy (pre mysql 5.0.3)
+// load events cannot be redacted; Transform returns an error for these events
+// when the policy is non-empty.
+//
+// RedactingTransformer is thread safe (as long as the policy and the
+// redaction functions are).
+type RedactingTransformer struct {
+	policy RedactionPolicy
+}
+
+// This returns a redacting transformer which applies the policy.
+func NewRedactingTransformer(policy RedactionPolicy) *RedactingTransformer {
+	return &RedactingTransformer{
+		policy: policy,
+	}
+}
+
+// See Transformer for documentation.  Since the events' raw bytes include
+// the original values, redacted events have no raw bytes (nor raw rows; see
+// transformRowsEvent).
+func (t *RedactingTransformer) Transform(event Event) (Event, error) {
+	if len(t.policy) == 0 {
+		return event, nil
+	}
+
+	var context TableContext
+	switch e := event.(type) {
+	case *WriteRowsEvent:
+		context = e.Context()
+	case *DeleteRowsEvent:
+		context = e.Context()
+	case *UpdateRowsEvent:
+		context = e.Context()
+	default:
+		return redactStatement(event)
+	}
+
+	columns := t.tableColumns(context)
+	if len(columns) == 0 {
+		return event, nil
+	}
+
+	redacted, _, err := transformRowsEvent(
+		event,
+		func(
+			context TableContext,
+			usedColumns []ColumnDescriptor,
+			row RowValues) (RowValues, error) {
+
+			return redactRow(columns, context, usedColumns, row), nil
+		})
+	return redacted, err
+}
+
+func redactStatement(event Event) (Event, error) {
+	switch e := event.(type) {
+	case *QueryEvent:
+		return redactQueryEvent(e), nil
+	case *ExecuteLoadQueryEvent:
+		redacted := *e
+		redacted.QueryEvent = redactQueryEvent(e.QueryEvent)
+		return &redacted, nil
+	case *RowsQueryEvent:
+		redacted := *e
+		redacted.Event = scrubEventBytes(e.Event)
+		redacted.truncatedQuery = scrubQueryLiterals(
+			e.truncatedQuery,
+			maskedByte)
+		return &redacted, nil
+	case *MariadbAnnotateRowsLogEvent:
+		redacted := *e
+		redacted.Event = scrubEventBytes(e.Event)
+		redacted.query = scrubQueryLiterals(e.query, maskedByte)
+		return &redacted, nil
+	case *AppendBlockEvent:
+		redacted := redactBlock(e)
+		return &redacted, nil
+	case *BeginLoadQueryEvent:
+		redacted := *e
+		redacted.AppendBlockEvent = redactBlock(&e.AppendBlockEvent)
+		return &redacted, nil
+	case *LegacyLoadEvent, *CreateFileEvent:
+		return nil, errors.Newf("Cannot redact legacy load event (%T)", e)
+	}
+
+	return event, nil
+}
+
+func redactQueryEvent(e *QueryEvent) *QueryEvent {
+	redacted := *e
+	redacted.Event = scrubEventBytes(e.Event)
+	redacted.query = scrubQueryLiterals(e.query, maskedByte)
+	return &redacted
+}
+
+func redactBlock(e *AppendBlockEvent) AppendBlockEvent {
+	redacted := *e
+	redacted.Event = scrubEventBytes(e.Event)
+	redacted.blockData = scrubBlockData(e.blockData, maskedByte)
+	return redacted
+}
+
+// Letters are masked by '*', and digits (including hex / bit literals'
+// digits) by '0', which keeps hex / bit literals valid.
+func maskedByte(alphabet string) byte {
+	if alphabet == letterAlphabet {
+		return '*'
+	}
+	return '0'
+}
+
+func (t *RedactingTransformer) tableColumns(
+	context TableContext) map[string]RedactionFunc {
+
+	table := string(context.DatabaseName()) + "." + string(context.TableName())
+	return t.policy[table]
+}
+
+func redactRow(
+	columns map[string]RedactionFunc,
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) RowValues {
+
+	result := make(RowValues, len(row))
+	for idx, value := range row {
+		result[idx] = value
+		if value == nil {
+			continue
+		}
+
+		name := columnName(context, usedColumns[idx].IndexPosition())
+		if redact, ok := columns[name]; ok {
+			result[idx] = redact(value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,284 @@
+package binlog
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type RedactorSuite struct {
+	context *TableMapEvent
+}
+
+var _ = Suite(&RedactorSuite{})
+
+func (s *RedactorSuite) SetUpTest(c *C) {
+	varchar, _, err := NewVarcharFieldDescriptor(Nullable, []byte{100, 0})
+	c.Assert(err, IsNil)
+
+	s.context = &TableMapEvent{
+		databaseName: []byte("db"),
+		tableName:    []byte("users"),
+		columnDescriptors: []ColumnDescriptor{
+			NewColumnDescriptor(NewLongLongFieldDescriptor(Nullable), 0),
+			NewColumnDescriptor(varchar, 1),
+			NewColumnDescriptor(varchar, 2),
+			NewColumnDescriptor(NewLongLongFieldDescriptor(Nullable), 3),
+			NewColumnDescriptor(varchar, 4),
+		},
+		optionalMetadata: &TableMapOptionalMetadata{
+			ColumnNames: [][]byte{
+				[]byte("id"),
+				[]byte("email"),
+				[]byte("name"),
+				[]byte("phone"),
+				[]byte("ssn"),
+			},
+		},
+	}
+}
+
+func (s *RedactorSuite) policy() RedactionPolicy {
+	return RedactionPolicy{
+		"db.users": {
+			"email": Hash,
+			"name":  Mask,
+			"phone": Mask,
+			"ssn":   Null,
+		},
+		"db.other": {
+			"id": Null,
+		},
+	}
+}
+
+func (s *RedactorSuite) TestRedactionFuncs(c *C) {
+	c.Assert(Null([]byte("foo")), IsNil)
+
+	c.Assert(
+		string(Hash([]byte("alice@example.com")).([]byte)),
+		Equals,
+		"ff8d9819fc0e12bf0d24892e45987e249a28dce836a85cad60e28eaaa8c6d976")
+	c.Assert(
+		Hash("alice@example.com"),
+		DeepEquals,
+		Hash([]byte("alice@example.com")))
+	c.Assert(Hash(uint64(42)), DeepEquals, Hash("42"))
+
+	c.Assert(Mask([]byte("alice")), DeepEquals, []byte("*****"))
+	c.Assert(Mask("héllo"), DeepEquals, []byte("*****"))
+	c.Assert(Mask(uint64(5551234)), DeepEquals, []byte("*******"))
+	c.Assert(Mask([]byte{}), DeepEquals, []byte{})
+
+	// Lazy blobs are redacted by their bytes, whether or not the bytes were
+	// copied out.
+	blob := &LazyBlob{raw: []byte("alice")}
+	c.Assert(Hash(blob), DeepEquals, Hash("alice"))
+	c.Assert(Mask(blob), DeepEquals, []byte("*****"))
+	_ = blob.Bytes()
+	c.Assert(Hash(blob), DeepEquals, Hash("alice"))
+	c.Assert(Mask(blob), DeepEquals, []byte("*****"))
+}
+
+func (s *RedactorSuite) TestWriteRows(c *C) {
+	t := NewRedactingTransformer(s.policy())
+
+	original := &WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors(),
+		rows: []RowValues{
+			{
+				uint64(1),
+				[]byte("alice@example.com"),
+				[]byte("alice"),
+				uint64(5551234),
+				[]byte("123-45-6789"),
+			},
+			{uint64(2), nil, []byte("bob"), nil, nil},
+		},
+	}
+
+	event, err := t.Transform(original)
+	c.Assert(err, IsNil)
+	c.Assert(event, Not(Equals), original)
+
+	c.Assert(event.(*WriteRowsEvent).InsertedRows(), DeepEquals, []RowValues{
+		{
+			uint64(1),
+			Hash([]byte("alice@example.com")),
+			[]byte("*****"),
+			[]byte("*******"),
+			nil,
+		},
+		{uint64(2), nil, []byte("***"), nil, nil},
+	})
+
+	// The original event is not modified.
+	c.Assert(original.rows[0][1], DeepEquals, []byte("alice@example.com"))
+}
+
+func (s *RedactorSuite) TestUpdateAndDeleteRows(c *C) {
+	t := NewRedactingTransformer(s.policy())
+
+	columns := s.context.ColumnDescriptors()
+	event, err := t.Transform(&UpdateRowsEvent{
+		BaseRowsEvent:          BaseRowsEvent{context: s.context},
+		beforeImageUsedColumns: []ColumnDescriptor{columns[0], columns[4]},
+		afterImageUsedColumns:  []ColumnDescriptor{columns[2]},
+		rows: []UpdateRowValues{
+			{
+				BeforeImage: RowValues{uint64(7), []byte("123-45-6789")},
+				AfterImage:  RowValues{[]byte("carol")},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(
+		event.(*UpdateRowsEvent).UpdatedRows(),
+		DeepEquals,
+		[]UpdateRowValues{
+			{
+				BeforeImage: RowValues{uint64(7), nil},
+				AfterImage:  RowValues{[]byte("*****")},
+			},
+		})
+
+	event, err = t.Transform(&DeleteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   columns[:2],
+		rows:          []RowValues{{uint64(9), []byte("dave@example.com")}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(
+		event.(*DeleteRowsEvent).DeletedRows(),
+		DeepEquals,
+		[]RowValues{{uint64(9), Hash([]byte("dave@example.com"))}})
+}
+
+func (s *RedactorSuite) TestNoPolicy(c *C) {
+	t := NewRedactingTransformer(RedactionPolicy{"db.other": {"id": Null}})
+
+	original := &WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[:1],
+		rows:          []RowValues{{uint64(1)}},
+	}
+
+	// Events for tables without policy entries are returned as is.
+	event, err := t.Transform(original)
+	c.Assert(err, IsNil)
+	c.Assert(event, Equals, original)
+
+	xid := &XidEvent{}
+	event, err = t.Transform(xid)
+	c.Assert(err, IsNil)
+	c.Assert(event, Equals, xid)
+
+	// Statements are only redacted when the policy is non-empty.
+	query := &QueryEvent{query: []byte("SELECT 'secret'")}
+	event, err = NewRedactingTransformer(nil).Transform(query)
+	c.Assert(err, IsNil)
+	c.Assert(event, Equals, query)
+}
+
+func (s *RedactorSuite) TestStatements(c *C) {
+	t := NewRedactingTransformer(s.policy())
+
+	query := "INSERT INTO t VALUES ('alice@example.com', 31337, x'AB') -- 'ok'"
+	expected :=
+		"INSERT INTO t VALUES ('*****************', 00000, x'00') -- 'ok'"
+
+	event, err := t.Transform(&QueryEvent{
+		databaseName: []byte("db"),
+		query:        []byte(query),
+	})
+	c.Assert(err, IsNil)
+	queryEvent := event.(*QueryEvent)
+	c.Assert(string(queryEvent.Query()), Equals, expected)
+	c.Assert(string(queryEvent.DatabaseName()), Equals, "db")
+
+	event, err = t.Transform(&RowsQueryEvent{
+		truncatedQuery: []byte(query),
+	})
+	c.Assert(err, IsNil)
+	rowsQuery := event.(*RowsQueryEvent)
+	c.Assert(string(rowsQuery.TruncatedQuery()), Equals, expected)
+
+	event, err = t.Transform(&MariadbAnnotateRowsLogEvent{
+		query: []byte(query),
+	})
+	c.Assert(err, IsNil)
+	annotate := event.(*MariadbAnnotateRowsLogEvent)
+	c.Assert(string(annotate.Query()), Equals, expected)
+
+	// DDL's numbers are kept.
+	ddl := "ALTER TABLE t ADD c VARCHAR(32) DEFAULT 'secret'"
+	event, err = t.Transform(&QueryEvent{query: []byte(ddl)})
+	c.Assert(err, IsNil)
+	c.Assert(
+		string(event.(*QueryEvent).Query()),
+		Equals,
+		"ALTER TABLE t ADD c VARCHAR(32) DEFAULT '******'")
+
+	event, err = t.Transform(&AppendBlockEvent{
+		blockData: []byte("alice,31337\n"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(
+		string(event.(*AppendBlockEvent).BlockData()),
+		Equals,
+		"*****,00000\n")
+
+	_, err = t.Transform(&CreateFileEvent{})
+	c.Assert(err, NotNil)
+}
+
+type RedactorEventSuite struct {
+	AnonymizerEventSuite
+}
+
+var _ = Suite(&RedactorEventSuite{})
+
+func (s *RedactorEventSuite) TestNoOriginalBytes(c *C) {
+	s.writeEvents()
+
+	t := NewRedactingTransformer(RedactionPolicy{
+		"database.table": {
+			"col_0": Hash,
+			"col_1": Mask,
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+
+		// Sanity check: the original event includes the secrets.
+		original := reachableBytes(event)
+		if i < 2 {
+			c.Assert(
+				bytes.Contains(original, []byte(anonymizerSecretBlob)),
+				IsTrue)
+		} else {
+			c.Assert(bytes.Contains(original, []byte("31337")), IsTrue)
+		}
+
+		redacted, err := t.Transform(event)
+		c.Assert(err, IsNil)
+
+		result := reachableBytes(redacted)
+		for _, secret := range []string{
+			anonymizerSecretEmail,
+			anonymizerSecretBlob,
+			"alice",
+			"31337",
+		} {
+			c.Assert(
+				bytes.Contains(result, []byte(secret)),
+				IsFalse,
+				Commentf("%T leaks %s", redacted, secret))
+		}
+	}
+}
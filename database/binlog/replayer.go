@@ -0,0 +1,351 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/dropbox/godropbox/database/sqlbuilder"
+	"github.com/dropbox/godropbox/errors"
+)
+
+// Replayer applies row changes (see RowChangeNormalizer) to a target
+// database, e.g., for replicating a filtered / transformed change stream into
+// another mysql instance.  Consecutive changes with the same gtid (i.e., a
+// source transaction's changes) are applied within a single database
+// transaction.  NOTE: changes without gtid are grouped the same way; use
+// ApplyTransaction to apply a gtid-less source transaction by itself.
+//
+// The generated statements are idempotent w.r.t. the rows' keys (see
+// RowChange.Key), hence replaying changes which were already applied (e.g.,
+// when restarting from an older checkpoint) does not duplicate rows, and the
+// target converges to the source's state once the replay catches up:
+//
+//   - inserts are applied as INSERT ... ON DUPLICATE KEY UPDATE (i.e., as
+//     upserts).
+//   - updates and deletes identify the row by its key columns' before image
+//     values.  When the key is unknown, all before image columns are used
+//     instead, and at most one row is modified per change.
+//
+// The changes' column names must match the target table's column names,
+// i.e., the source must log column names (see binlog_row_metadata).  Signed
+// integer values are only replayed correctly when the source also logs the
+// columns' signedness (see RowChange).
+//
+// Replayer is thread safe (as long as the changes are not modified while
+// being applied).
+type Replayer struct {
+	db       *sql.DB
+	database string
+	location *time.Location
+}
+
+// ReplayerOptions controls how changes are replayed.  The zero value
+// corresponds to the default behavior.
+type ReplayerOptions struct {
+	// The location the changes' DATETIME values were decoded in (see
+	// DecodeOptions.DateTimeLocation).  Time values are replayed as
+	// wall-clock time in this location, i.e., DATETIME values are replayed
+	// as logged.  Defaults to UTC.  NOTE: TIMESTAMP values are also
+	// replayed in this location, hence the target session's time zone must
+	// match the location for TIMESTAMP columns.
+	Location *time.Location
+}
+
+// This returns a replayer which applies changes to db.  When database is
+// non-empty, changes are applied to the named database's tables; otherwise,
+// changes are applied to the tables in the changes' source schemas.
+func NewReplayer(db *sql.DB, database string) *Replayer {
+	return NewReplayerWithOptions(db, database, ReplayerOptions{})
+}
+
+// Same as NewReplayer, but with the given options.
+func NewReplayerWithOptions(
+	db *sql.DB,
+	database string,
+	options ReplayerOptions) *Replayer {
+
+	location := options.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &Replayer{
+		db:       db,
+		database: database,
+		location: location,
+	}
+}
+
+// Apply applies the changes in order, one transaction (i.e., run of changes
+// with the same gtid) at a time.  On error, the failed transaction is rolled
+// back; previously applied transactions remain committed.
+func (r *Replayer) Apply(ctx context.Context, changes []*RowChange) error {
+	for len(changes) > 0 {
+		end := 1
+		for end < len(changes) && changes[end].Gtid == changes[0].Gtid {
+			end++
+		}
+
+		err := r.ApplyTransaction(ctx, changes[:end])
+		if err != nil {
+			return err
+		}
+
+		changes = changes[end:]
+	}
+
+	return nil
+}
+
+// ApplyTransaction applies all changes within a single database transaction.
+func (r *Replayer) ApplyTransaction(
+	ctx context.Context,
+	changes []*RowChange) error {
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	queries := make([]string, 0, len(changes))
+	for _, change := range changes {
+		query, err := r.Query(change)
+		if err != nil {
+			return err
+		}
+		queries = append(queries, query)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Failed to begin transaction (gtid: %s)",
+			changes[0].Gtid)
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			_ = tx.Rollback()
+			return errors.Wrapf(
+				err,
+				"Failed to apply change (gtid: %s): %s",
+				changes[0].Gtid,
+				query)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Failed to commit transaction (gtid: %s)",
+			changes[0].Gtid)
+	}
+
+	return nil
+}
+
+// Query returns the sql statement which applies the change.
+func (r *Replayer) Query(change *RowChange) (string, error) {
+	database := r.database
+	if database == "" {
+		database = change.Schema
+	}
+
+	stmt, err := r.replayStatement(change)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"Failed to build %s statement for %s.%s",
+			change.Action.String(),
+			change.Schema,
+			change.Table)
+	}
+
+	return stmt.String(database)
+}
+
+func (r *Replayer) replayStatement(
+	change *RowChange) (sqlbuilder.Statement, error) {
+
+	table, err := replayTable(change)
+	if err != nil {
+		return nil, err
+	}
+
+	switch change.Action {
+	case InsertAction:
+		if len(change.After) == 0 {
+			return nil, errors.New("Empty after image")
+		}
+
+		names := sortedColumnNames(change.After)
+		columns := make([]sqlbuilder.NonAliasColumn, 0, len(names))
+		values := make([]sqlbuilder.Expression, 0, len(names))
+		for _, name := range names {
+			value, err := r.replayLiteral(change.After[name])
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, table.C(name))
+			values = append(values, value)
+		}
+
+		stmt := table.Insert(columns...).Add(values...)
+		for idx, col := range columns {
+			stmt = stmt.AddOnDuplicateKeyUpdate(col, values[idx])
+		}
+		return stmt, nil
+
+	case UpdateAction:
+		if len(change.After) == 0 {
+			return nil, errors.New("Empty after image")
+		}
+
+		where, limit, err := r.replayWhere(table, change)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt := table.Update().Where(where)
+		for _, name := range sortedColumnNames(change.After) {
+			value, err := r.replayLiteral(change.After[name])
+			if err != nil {
+				return nil, err
+			}
+			stmt = stmt.Set(table.C(name), value)
+		}
+		if limit {
+			stmt = stmt.Limit(1)
+		}
+		return stmt, nil
+
+	case DeleteAction:
+		where, limit, err := r.replayWhere(table, change)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt := table.Delete().Where(where)
+		if limit {
+			stmt = stmt.Limit(1)
+		}
+		return stmt, nil
+	}
+
+	return nil, errors.Newf("Unknown row change action: %s", change.Action)
+}
+
+// This returns the condition which identifies the change's row.  limit is
+// true when the condition may match multiple rows (i.e., the row's key is
+// unknown).
+func (r *Replayer) replayWhere(
+	table *sqlbuilder.Table,
+	change *RowChange) (
+	where sqlbuilder.BoolExpression,
+	limit bool,
+	err error) {
+
+	names := change.Key
+	if len(names) == 0 {
+		names = sortedColumnNames(change.Before)
+		limit = true
+	}
+
+	if len(names) == 0 {
+		return nil, false, errors.New("Empty before image")
+	}
+
+	conditions := make([]sqlbuilder.BoolExpression, 0, len(names))
+	for _, name := range names {
+		value, ok := change.Before[name]
+		if !ok {
+			return nil, false, errors.Newf(
+				"Key column %s is not in the before image",
+				name)
+		}
+
+		literal, err := r.replayLiteral(value)
+		if err != nil {
+			return nil, false, err
+		}
+		conditions = append(conditions, sqlbuilder.Eq(table.C(name), literal))
+	}
+
+	return sqlbuilder.And(conditions...), limit, nil
+}
+
+// This returns a table with all of the change's columns.  Since literals are
+// not type checked against columns, all columns are declared as bytes
+// columns.
+func replayTable(change *RowChange) (table *sqlbuilder.Table, err error) {
+	names := sortedColumnNames(change.Before)
+	for name := range change.After {
+		if _, ok := change.Before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	columns := make([]sqlbuilder.NonAliasColumn, 0, len(names))
+	for _, name := range names {
+		err = catchPanic(func() {
+			columns = append(
+				columns,
+				sqlbuilder.BytesColumn(name, sqlbuilder.Nullable))
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid column name: %s", name)
+		}
+	}
+
+	err = catchPanic(func() {
+		table = sqlbuilder.NewTable(change.Table, columns...)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid table: %s", change.Table)
+	}
+
+	return table, nil
+}
+
+// sqlbuilder panics on invalid identifiers.
+func catchPanic(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Newf("%v", r)
+		}
+	}()
+
+	f()
+	return nil
+}
+
+func (r *Replayer) replayLiteral(
+	value interface{}) (sqlbuilder.Expression, error) {
+
+	v, err := ToDriverValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoded time values are in UTC; sqlbuilder formats the wall-clock
+	// time as is.
+	if t, ok := v.(time.Time); ok {
+		v = t.In(r.location)
+	}
+
+	return sqlbuilder.Literal(v), nil
+}
+
+func sortedColumnNames(image map[string]interface{}) []string {
+	names := make([]string, 0, len(image))
+	for name := range image {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,594 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// A database/sql driver which records the executed statements, per
+// committed transaction.  The committed statements are also applied to an
+// in-memory copy of the target's tables (see fakeReplayDb.apply).
+type fakeReplayDb struct {
+	mutex     sync.Mutex
+	committed [][]string
+	rollbacks int
+	failOn    string // statements containing failOn fail.
+
+	// table name -> rows (column name -> sql literal).  Rows are keyed by
+	// the id column.
+	tables map[string][]map[string]string
+}
+
+var (
+	fakeInsertRe = regexp.MustCompile(
+		"^INSERT INTO (\\S+) \\((.*?)\\) VALUES \\((.*?)\\) ON DUPLICATE KEY")
+	fakeUpdateRe = regexp.MustCompile(
+		"^UPDATE (\\S+) SET (.*) WHERE (.*?)( LIMIT 1)?$")
+	fakeDeleteRe = regexp.MustCompile(
+		"^DELETE FROM (\\S+) WHERE (.*?)( LIMIT 1)?$")
+)
+
+// This interprets the statements generated by the replayer.  Literals are
+// compared as is, and must not contain commas.
+//
+// NOTE: the caller must hold the mutex.
+func (db *fakeReplayDb) apply(query string) error {
+	if db.tables == nil {
+		db.tables = map[string][]map[string]string{}
+	}
+
+	if m := fakeInsertRe.FindStringSubmatch(query); m != nil {
+		columns := strings.Split(m[2], ",")
+		values := strings.Split(m[3], ",")
+		if len(columns) != len(values) {
+			return errors.Newf("Mismatched insert values: %s", query)
+		}
+
+		row := map[string]string{}
+		for idx, col := range columns {
+			row[fakeColumnName(col)] = values[idx]
+		}
+
+		rows := db.tables[m[1]]
+		for idx, existing := range rows {
+			if existing["id"] == row["id"] {
+				rows[idx] = row
+				return nil
+			}
+		}
+		db.tables[m[1]] = append(rows, row)
+		return nil
+	}
+
+	if m := fakeUpdateRe.FindStringSubmatch(query); m != nil {
+		for _, row := range db.matchingRows(m[1], m[3]) {
+			for _, assignment := range strings.Split(m[2], ", ") {
+				parts := strings.SplitN(assignment, "=", 2)
+				row[fakeColumnName(parts[0])] = parts[1]
+			}
+		}
+		return nil
+	}
+
+	if m := fakeDeleteRe.FindStringSubmatch(query); m != nil {
+		matched := db.matchingRows(m[1], m[2])
+		remaining := []map[string]string{}
+		for _, row := range db.tables[m[1]] {
+			if len(matched) == 0 || !fakeSameRow(row, matched[0]) {
+				remaining = append(remaining, row)
+			}
+		}
+		db.tables[m[1]] = remaining
+		return nil
+	}
+
+	return errors.Newf("Unsupported statement: %s", query)
+}
+
+func (db *fakeReplayDb) matchingRows(
+	table string,
+	where string) []map[string]string {
+
+	where = strings.TrimSuffix(strings.TrimPrefix(where, "("), ")")
+
+	matched := []map[string]string{}
+	for _, row := range db.tables[table] {
+		match := true
+		for _, cond := range strings.Split(where, " AND ") {
+			if strings.HasSuffix(cond, " IS null") {
+				col := fakeColumnName(strings.TrimSuffix(cond, " IS null"))
+				match = match && row[col] == "null"
+			} else {
+				parts := strings.SplitN(cond, "=", 2)
+				match = match && row[fakeColumnName(parts[0])] == parts[1]
+			}
+		}
+		if match {
+			matched = append(matched, row)
+		}
+	}
+	return matched
+}
+
+// `t`.`col` -> col
+func fakeColumnName(column string) string {
+	parts := strings.Split(strings.TrimSpace(column), ".")
+	return strings.Trim(parts[len(parts)-1], "`")
+}
+
+func fakeSameRow(a map[string]string, b map[string]string) bool {
+	return a["id"] == b["id"]
+}
+
+var fakeReplayDbs = struct {
+	sync.Mutex
+	dbs map[string]*fakeReplayDb
+}{dbs: map[string]*fakeReplayDb{}}
+
+type fakeReplayDriver struct{}
+
+func init() {
+	sql.Register("binlog_replay_test", fakeReplayDriver{})
+}
+
+func (fakeReplayDriver) Open(name string) (driver.Conn, error) {
+	fakeReplayDbs.Lock()
+	defer fakeReplayDbs.Unlock()
+
+	return &fakeReplayConn{db: fakeReplayDbs.dbs[name]}, nil
+}
+
+type fakeReplayConn struct {
+	db      *fakeReplayDb
+	pending []string
+	inTx    bool
+}
+
+func (c *fakeReplayConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeReplayStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeReplayConn) Close() error {
+	return nil
+}
+
+func (c *fakeReplayConn) Begin() (driver.Tx, error) {
+	if c.inTx {
+		return nil, errors.New("Already in transaction")
+	}
+	c.inTx = true
+	c.pending = nil
+	return c, nil
+}
+
+func (c *fakeReplayConn) Commit() error {
+	c.db.mutex.Lock()
+	defer c.db.mutex.Unlock()
+
+	for _, query := range c.pending {
+		if err := c.db.apply(query); err != nil {
+			return err
+		}
+	}
+
+	c.db.committed = append(c.db.committed, c.pending)
+	c.inTx = false
+	c.pending = nil
+	return nil
+}
+
+func (c *fakeReplayConn) Rollback() error {
+	c.db.mutex.Lock()
+	defer c.db.mutex.Unlock()
+
+	c.db.rollbacks++
+	c.inTx = false
+	c.pending = nil
+	return nil
+}
+
+type fakeReplayStmt struct {
+	conn  *fakeReplayConn
+	query string
+}
+
+func (s *fakeReplayStmt) Close() error {
+	return nil
+}
+
+func (s *fakeReplayStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeReplayStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !s.conn.inTx {
+		return nil, errors.New("Not in transaction")
+	}
+
+	s.conn.db.mutex.Lock()
+	failOn := s.conn.db.failOn
+	s.conn.db.mutex.Unlock()
+
+	if failOn != "" && strings.Contains(s.query, failOn) {
+		return nil, errors.New("Injected failure")
+	}
+
+	s.conn.pending = append(s.conn.pending, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeReplayStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("Not supported")
+}
+
+type ReplayerSuite struct {
+	target   *fakeReplayDb
+	db       *sql.DB
+	replayer *Replayer
+}
+
+var _ = Suite(&ReplayerSuite{})
+
+func (s *ReplayerSuite) SetUpTest(c *C) {
+	name := c.TestName()
+
+	s.target = &fakeReplayDb{}
+	fakeReplayDbs.Lock()
+	fakeReplayDbs.dbs[name] = s.target
+	fakeReplayDbs.Unlock()
+
+	var err error
+	s.db, err = sql.Open("binlog_replay_test", name)
+	c.Assert(err, IsNil)
+
+	s.replayer = NewReplayer(s.db, "")
+}
+
+func (s *ReplayerSuite) TearDownTest(c *C) {
+	_ = s.db.Close()
+}
+
+func (s *ReplayerSuite) TestInsertQuery(c *C) {
+	query, err := s.replayer.Query(&RowChange{
+		Action: InsertAction,
+		After: map[string]interface{}{
+			"id":   uint64(1),
+			"name": []byte("foo"),
+			"note": nil,
+		},
+		Schema: "db",
+		Table:  "users",
+		Key:    []string{"id"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(
+		query,
+		Equals,
+		"INSERT INTO `db`.`users` (`users`.`id`,`users`.`name`,`users`.`note`) "+
+			"VALUES (1,X'666f6f',null) "+
+			"ON DUPLICATE KEY UPDATE `users`.`id`=1, "+
+			"`users`.`name`=X'666f6f', `users`.`note`=null")
+}
+
+func (s *ReplayerSuite) TestUpdateQuery(c *C) {
+	change := &RowChange{
+		Action: UpdateAction,
+		Before: map[string]interface{}{
+			"id":   uint64(1),
+			"name": []byte("foo"),
+			"note": nil,
+		},
+		After: map[string]interface{}{
+			"id":   uint64(1),
+			"name": []byte("bar"),
+		},
+		Schema: "db",
+		Table:  "users",
+		Key:    []string{"id"},
+	}
+
+	query, err := s.replayer.Query(change)
+	c.Assert(err, IsNil)
+	c.Check(
+		query,
+		Equals,
+		"UPDATE `db`.`users` SET `users`.`id`=1, `users`.`name`=X'626172' "+
+			"WHERE `users`.`id`=1")
+
+	// Without key, all before image columns identify the row.
+	change.Key = nil
+	query, err = NewReplayer(s.db, "other").Query(change)
+	c.Assert(err, IsNil)
+	c.Check(
+		query,
+		Equals,
+		"UPDATE `other`.`users` "+
+			"SET `users`.`id`=1, `users`.`name`=X'626172' "+
+			"WHERE (`users`.`id`=1 AND `users`.`name`=X'666f6f' AND "+
+			"`users`.`note` IS null) LIMIT 1")
+}
+
+func (s *ReplayerSuite) TestDeleteQuery(c *C) {
+	change := &RowChange{
+		Action: DeleteAction,
+		Before: map[string]interface{}{
+			"id":   uint64(1),
+			"name": []byte("foo"),
+		},
+		Schema: "db",
+		Table:  "users",
+		Key:    []string{"id"},
+	}
+
+	query, err := s.replayer.Query(change)
+	c.Assert(err, IsNil)
+	c.Check(query, Equals, "DELETE FROM `db`.`users` WHERE `users`.`id`=1")
+
+	change.Key = []string{"missing"}
+	_, err = s.replayer.Query(change)
+	c.Check(err, NotNil)
+}
+
+func (s *ReplayerSuite) TestInvalidChanges(c *C) {
+	for _, change := range []*RowChange{
+		{Action: InsertAction, Schema: "db", Table: "users"},
+		{Action: UpdateAction, Schema: "db", Table: "users"},
+		{
+			Action: DeleteAction,
+			Before: map[string]interface{}{"id": uint64(1)},
+			Schema: "db",
+			Table:  "bad table",
+		},
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"bad column": uint64(1)},
+			Schema: "db",
+			Table:  "users",
+		},
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"id": struct{}{}},
+			Schema: "db",
+			Table:  "users",
+		},
+		{
+			Action: RowChangeAction(42),
+			Before: map[string]interface{}{"id": uint64(1)},
+			Schema: "db",
+			Table:  "users",
+		},
+	} {
+		_, err := s.replayer.Query(change)
+		c.Check(err, NotNil, Commentf("%#v", change))
+	}
+}
+
+func (s *ReplayerSuite) replayChanges() []*RowChange {
+	gtid1 := "00010203-0405-0607-0809-0a0b0c0d0e0f:1"
+	gtid2 := "00010203-0405-0607-0809-0a0b0c0d0e0f:2"
+
+	return []*RowChange{
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"id": uint64(1)},
+			Schema: "db",
+			Table:  "t",
+			Key:    []string{"id"},
+			Gtid:   gtid1,
+		},
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"id": uint64(2)},
+			Schema: "db",
+			Table:  "t",
+			Key:    []string{"id"},
+			Gtid:   gtid1,
+		},
+		{
+			Action: DeleteAction,
+			Before: map[string]interface{}{"id": uint64(1)},
+			Schema: "db",
+			Table:  "t",
+			Key:    []string{"id"},
+			Gtid:   gtid2,
+		},
+	}
+}
+
+func (s *ReplayerSuite) TestApply(c *C) {
+	err := s.replayer.Apply(context.Background(), s.replayChanges())
+	c.Assert(err, IsNil)
+
+	c.Check(s.target.rollbacks, Equals, 0)
+	c.Check(s.target.committed, DeepEquals, [][]string{
+		{
+			"INSERT INTO `db`.`t` (`t`.`id`) VALUES (1) " +
+				"ON DUPLICATE KEY UPDATE `t`.`id`=1",
+			"INSERT INTO `db`.`t` (`t`.`id`) VALUES (2) " +
+				"ON DUPLICATE KEY UPDATE `t`.`id`=2",
+		},
+		{
+			"DELETE FROM `db`.`t` WHERE `t`.`id`=1",
+		},
+	})
+
+	c.Assert(s.replayer.Apply(context.Background(), nil), IsNil)
+	c.Check(s.target.committed, HasLen, 2)
+}
+
+func (s *ReplayerSuite) TestApplyFailure(c *C) {
+	s.target.failOn = "DELETE"
+
+	err := s.replayer.Apply(context.Background(), s.replayChanges())
+	c.Assert(err, NotNil)
+	c.Check(
+		errors.GetMessage(err),
+		Matches,
+		"(?s)Failed to apply change "+
+			"\\(gtid: 00010203-0405-0607-0809-0a0b0c0d0e0f:2\\).*")
+
+	// The first transaction is committed, the second is rolled back.
+	c.Check(s.target.committed, HasLen, 1)
+	c.Check(s.target.rollbacks, Equals, 1)
+}
+
+func (s *ReplayerSuite) TestApplyNormalizedChanges(c *C) {
+	tableContext := &TableMapEvent{
+		databaseName:      []byte("db"),
+		tableName:         []byte("t"),
+		columnDescriptors: newTestTableContext().ColumnDescriptors(),
+		optionalMetadata: &TableMapOptionalMetadata{
+			ColumnNames: [][]byte{
+				[]byte("a"),
+				[]byte("b"),
+				[]byte("c"),
+				[]byte("d"),
+				[]byte("e"),
+			},
+			PrimaryKey: []int{1},
+		},
+	}
+
+	columns := tableContext.ColumnDescriptors()
+	changes := NewRowChangeNormalizer().Normalize(&UpdateRowsEvent{
+		BaseRowsEvent:          BaseRowsEvent{context: tableContext},
+		beforeImageUsedColumns: columns[:2],
+		afterImageUsedColumns:  columns[:1],
+		rows: []UpdateRowValues{
+			{
+				BeforeImage: RowValues{uint64(1), uint64(2)},
+				AfterImage:  RowValues{uint64(3)},
+			},
+		},
+	})
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Key, DeepEquals, []string{"b"})
+
+	err := s.replayer.Apply(context.Background(), changes)
+	c.Assert(err, IsNil)
+	c.Check(s.target.committed, DeepEquals, [][]string{
+		{"UPDATE `db`.`t` SET `t`.`a`=3 WHERE `t`.`b`=2"},
+	})
+}
+
+func (s *ReplayerSuite) TestReplayTargetState(c *C) {
+	source := time.FixedZone("-08:00", -8*60*60)
+
+	events := &EventParserSuite{}
+	events.SetUpTest(c)
+	events.parsers = NewV4EventParserMapWithOptions(
+		DecodeOptions{DateTimeLocation: source})
+	events.reader = NewParsedV4EventReader(events.rawReader, events.parsers)
+
+	names := []byte{}
+	for _, name := range []string{"id", "n", "dt"} {
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+	}
+
+	// id BIGINT UNSIGNED NOT NULL PRIMARY KEY, n TINYINT NOT NULL,
+	// dt DATETIME NOT NULL
+	tableMap := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		1, 0,
+		// db name length
+		2,
+		// db name
+		'd', 'b', 0,
+		// table name length
+		1,
+		// table name
+		't', 0,
+		// number of columns
+		3,
+		// column types
+		8, 1, 18,
+		// metadata size
+		1,
+		// metadata (datetime2 precision)
+		0,
+		// null bits
+		0,
+	}
+	tableMap = append(
+		tableMap,
+		optionalMetadataField(optionalMetadataSignedness, 0x80)...)
+	tableMap = append(
+		tableMap,
+		optionalMetadataField(optionalMetadataColumnName, names...)...)
+	tableMap = append(
+		tableMap,
+		optionalMetadataField(optionalMetadataSimplePrimaryKey, 0)...)
+
+	row := func(id byte, n byte) []byte {
+		b := []byte{0, id, 0, 0, 0, 0, 0, 0, 0, n}
+		return append(b, testDateTime2Bytes()...)
+	}
+
+	rowsHeader := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		0, 0,
+		// number of columns
+		3,
+		// used columns
+		7,
+	}
+
+	inserts := append([]byte{}, rowsHeader...)
+	inserts = append(inserts, row(1, 0xff)...) // n = -1
+	inserts = append(inserts, row(2, 5)...)
+
+	updates := append([]byte{}, rowsHeader...)
+	updates = append(updates, 7) // after image used columns
+	updates = append(updates, row(1, 0xff)...)
+	updates = append(updates, row(1, 0x80)...) // n = -128
+
+	deletes := append([]byte{}, rowsHeader...)
+	deletes = append(deletes, row(2, 5)...)
+
+	events.WriteEvent(mysql_proto.LogEventType_TABLE_MAP_EVENT, 0, tableMap)
+	events.WriteEvent(mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1, 0, inserts)
+	events.WriteEvent(mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1, 0, updates)
+	events.WriteEvent(mysql_proto.LogEventType_DELETE_ROWS_EVENT_V1, 0, deletes)
+
+	normalizer := NewRowChangeNormalizer()
+	changes := []*RowChange{}
+	for i := 0; i < 4; i++ {
+		event, err := events.NextEvent()
+		c.Assert(err, IsNil)
+		changes = append(changes, normalizer.Normalize(event)...)
+	}
+	c.Assert(changes, HasLen, 4)
+	c.Check(changes[0].After["n"], Equals, int64(-1))
+
+	replayer := NewReplayerWithOptions(
+		s.db,
+		"",
+		ReplayerOptions{Location: source})
+	err := replayer.Apply(context.Background(), changes)
+	c.Assert(err, IsNil)
+
+	c.Check(s.target.tables, DeepEquals, map[string][]map[string]string{
+		"`db`.`t`": {
+			{
+				"id": "1",
+				"n":  "-128",
+				"dt": "'2015-06-17 23:45:12.000000'",
+			},
+		},
+	})
+}
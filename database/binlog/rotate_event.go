@@ -34,6 +34,15 @@ func (e *RotateEvent) NewPosition() uint64 {
 	return e.newPosition
 }
 
+// IsArtificial returns true if the rotate event is a fake rotate event, i.e.,
+// the event was generated by the master to announce the current log file
+// (e.g., at the beginning of a replication stream) rather than written on log
+// rotation.  Artificial rotate events have zero log_pos, and do not
+// terminate the log file they are in.
+func (e *RotateEvent) IsArtificial() bool {
+	return e.HeaderFlags().IsArtificial()
+}
+
 //
 // RotateEventParser ----------------------------------------------------------
 //
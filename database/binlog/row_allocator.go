@@ -0,0 +1,98 @@
+package binlog
+
+import (
+	"sync"
+)
+
+// RowAllocator controls where the rows parsers' decoded RowValues come from.
+// High throughput consumers may use a pooling allocator (e.g., RowPool) to
+// recycle rows once they are done with them.
+type RowAllocator interface {
+	// AllocateRow returns a RowValues of length numColumns.  The returned
+	// values are unspecified; the rows parser sets every value.
+	AllocateRow(numColumns int) RowValues
+
+	// FreeRow releases the row back to the allocator.  The caller must not
+	// use the row after calling FreeRow.
+	FreeRow(row RowValues)
+}
+
+type heapRowAllocator struct {
+}
+
+func (heapRowAllocator) AllocateRow(numColumns int) RowValues {
+	return make(RowValues, numColumns, numColumns)
+}
+
+func (heapRowAllocator) FreeRow(row RowValues) {
+	// Let the gc reclaim the row.
+}
+
+// The default row allocator, which always allocates new rows from the heap.
+var DefaultRowAllocator RowAllocator = heapRowAllocator{}
+
+// RowPool is a RowAllocator which recycles freed rows.  Free rows are kept
+// in per-width free lists (rows from the same table have the same width).
+//
+// RowPool is thread safe.
+type RowPool struct {
+	maxFreeRows int
+
+	mutex    sync.Mutex
+	numFree  int
+	freeRows map[int][]RowValues
+}
+
+// This returns a row pool which keeps up to maxFreeRows free rows (across
+// all widths).  Rows freed while the pool is full are left to the gc.
+func NewRowPool(maxFreeRows int) *RowPool {
+	return &RowPool{
+		maxFreeRows: maxFreeRows,
+		freeRows:    make(map[int][]RowValues),
+	}
+}
+
+// See RowAllocator for documentation.
+func (p *RowPool) AllocateRow(numColumns int) RowValues {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	free := p.freeRows[numColumns]
+	if len(free) == 0 {
+		return make(RowValues, numColumns, numColumns)
+	}
+
+	last := len(free) - 1
+	row := free[last]
+	free[last] = nil
+	p.freeRows[numColumns] = free[:last]
+	p.numFree--
+
+	return row
+}
+
+// See RowAllocator for documentation.
+func (p *RowPool) FreeRow(row RowValues) {
+	// Don't hold on to the decoded values.
+	for i := range row {
+		row[i] = nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.numFree >= p.maxFreeRows {
+		return
+	}
+
+	p.freeRows[len(row)] = append(p.freeRows[len(row)], row)
+	p.numFree++
+}
+
+// NumFreeRows returns the number of free rows held by the pool.
+func (p *RowPool) NumFreeRows() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.numFree
+}
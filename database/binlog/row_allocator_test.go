@@ -0,0 +1,80 @@
+package binlog
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type RowPoolSuite struct {
+}
+
+var _ = Suite(&RowPoolSuite{})
+
+func (s *RowPoolSuite) TestAllocateAndFree(c *C) {
+	pool := NewRowPool(2)
+
+	row := pool.AllocateRow(3)
+	c.Assert(len(row), Equals, 3)
+	c.Assert(pool.NumFreeRows(), Equals, 0)
+
+	row[0] = uint64(1234)
+	row[2] = []byte("foo")
+	pool.FreeRow(row)
+	c.Assert(pool.NumFreeRows(), Equals, 1)
+
+	// Rows are only reused for the same width.
+	other := pool.AllocateRow(2)
+	c.Assert(len(other), Equals, 2)
+	c.Assert(pool.NumFreeRows(), Equals, 1)
+
+	reused := pool.AllocateRow(3)
+	c.Assert(&reused[0], Equals, &row[0])
+	c.Assert(reused, DeepEquals, RowValues{nil, nil, nil})
+	c.Assert(pool.NumFreeRows(), Equals, 0)
+
+	// The pool holds at most 2 free rows.
+	pool.FreeRow(reused)
+	pool.FreeRow(other)
+	pool.FreeRow(pool.AllocateRow(5))
+	c.Assert(pool.NumFreeRows(), Equals, 2)
+}
+
+func (s *RowPoolSuite) TestDefaultRowAllocator(c *C) {
+	row := DefaultRowAllocator.AllocateRow(4)
+	c.Assert(len(row), Equals, 4)
+	DefaultRowAllocator.FreeRow(row)
+}
+
+func (s *RowPoolSuite) TestRowsParserUsesAllocator(c *C) {
+	pool := NewRowPool(10)
+
+	src := &bytes.Buffer{}
+	parsers := NewV4EventParserMapWithOptions(DecodeOptions{RowAllocator: pool})
+	parsers.SetTableContext(newTestTableContext())
+	reader := NewParsedV4EventReader(
+		NewRawV4EventReader(src, testSourceName),
+		parsers)
+
+	eventBytes := benchmarkWriteRowsEvent(1).data
+	_, _ = src.Write(eventBytes)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(len(rows), Equals, 1)
+	c.Assert(rows[0][4], Equals, uint64(0x1234567812345678))
+
+	pool.FreeRow(rows[0])
+	c.Assert(pool.NumFreeRows(), Equals, 1)
+
+	_, _ = src.Write(eventBytes)
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Assert(pool.NumFreeRows(), Equals, 0)
+
+	rows = event.(*WriteRowsEvent).InsertedRows()
+	c.Assert(rows[0][4], Equals, uint64(0x1234567812345678))
+	c.Assert(rows[0][0], IsNil)
+}
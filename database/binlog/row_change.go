@@ -0,0 +1,220 @@
+package binlog
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// RowChangeAction is the kind of change applied to a row.
+type RowChangeAction int
+
+const (
+	InsertAction RowChangeAction = iota
+	UpdateAction
+	DeleteAction
+)
+
+func (a RowChangeAction) String() string {
+	switch a {
+	case InsertAction:
+		return "INSERT"
+	case UpdateAction:
+		return "UPDATE"
+	case DeleteAction:
+		return "DELETE"
+	}
+	return "UNKNOWN(" + strconv.Itoa(int(a)) + ")"
+}
+
+// RowChange is a single row's change (i.e., a change data capture record).
+// Inserts only have the After image, deletes only have the Before image, and
+// updates have both images.  The images map column names to column values;
+// only the columns logged in the rows event are included.  Columns are named
+// col_<index> when the table map event does not include the column names
+// (see binlog_row_metadata).  Signed integer columns' values are sign
+// extended into int64 when the table map event includes the columns'
+// signedness (otherwise, integer values are uninterpreted, see SignExtend).
+type RowChange struct {
+	Action RowChangeAction
+
+	Before map[string]interface{}
+	After  map[string]interface{}
+
+	Schema string
+	Table  string
+
+	// The names of the columns which identify the row (i.e., the primary key
+	// columns, in key order), or nil when the primary key is unknown or is
+	// not fully included in the row's before image (after image for
+	// inserts).
+	Key []string
+
+	// The change's transaction gtid (e.g.,
+	// "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", or "0-1-23" for MariaDB),
+	// or empty when the transaction has no gtid.
+	Gtid string
+}
+
+// RowChangeNormalizer converts write / update / delete rows events into
+// RowChanges.  The normalizer tracks the current transaction's gtid, hence
+// all events (not just rows events) must be passed to Normalize in log order.
+//
+// RowChangeNormalizer is not thread safe.
+type RowChangeNormalizer struct {
+	gtid string
+}
+
+// This returns a normalizer without a current gtid.
+func NewRowChangeNormalizer() *RowChangeNormalizer {
+	return &RowChangeNormalizer{}
+}
+
+// Normalize returns the event's row changes (one per row, in the event's
+// row order).  Non-rows events return no changes.
+func (n *RowChangeNormalizer) Normalize(event Event) []*RowChange {
+	switch e := event.(type) {
+	case *GtidLogEvent:
+		n.gtid = fmt.Sprintf("%s:%d", formatSid(e.Sid()), e.Gno())
+	case *MariadbGtidLogEvent:
+		n.gtid = e.Gtid().String()
+	case *XidEvent:
+		n.gtid = ""
+	case *QueryEvent:
+		if isQuery(e, "COMMIT") || isQuery(e, "ROLLBACK") {
+			n.gtid = ""
+		}
+	case *WriteRowsEvent:
+		changes := make([]*RowChange, 0, len(e.InsertedRows()))
+		for _, row := range e.InsertedRows() {
+			change := n.newRowChange(InsertAction, e.Context())
+			change.After = rowImage(e.Context(), e.UsedColumns(), row)
+			change.Key = rowKeyColumns(e.Context(), e.UsedColumns())
+			changes = append(changes, change)
+		}
+		return changes
+	case *UpdateRowsEvent:
+		changes := make([]*RowChange, 0, len(e.UpdatedRows()))
+		for _, row := range e.UpdatedRows() {
+			change := n.newRowChange(UpdateAction, e.Context())
+			change.Before = rowImage(
+				e.Context(),
+				e.BeforeImageUsedColumns(),
+				row.BeforeImage)
+			change.After = rowImage(
+				e.Context(),
+				e.AfterImageUsedColumns(),
+				row.AfterImage)
+			change.Key = rowKeyColumns(
+				e.Context(),
+				e.BeforeImageUsedColumns())
+			changes = append(changes, change)
+		}
+		return changes
+	case *DeleteRowsEvent:
+		changes := make([]*RowChange, 0, len(e.DeletedRows()))
+		for _, row := range e.DeletedRows() {
+			change := n.newRowChange(DeleteAction, e.Context())
+			change.Before = rowImage(e.Context(), e.UsedColumns(), row)
+			change.Key = rowKeyColumns(e.Context(), e.UsedColumns())
+			changes = append(changes, change)
+		}
+		return changes
+	}
+
+	return nil
+}
+
+// NormalizeTransaction returns the row changes of all of the transaction's
+// rows events (see Normalize).
+func (n *RowChangeNormalizer) NormalizeTransaction(
+	txn *Transaction) []*RowChange {
+
+	var changes []*RowChange
+	for _, event := range txn.Events {
+		changes = append(changes, n.Normalize(event)...)
+	}
+	return changes
+}
+
+func (n *RowChangeNormalizer) newRowChange(
+	action RowChangeAction,
+	context TableContext) *RowChange {
+
+	return &RowChange{
+		Action: action,
+		Schema: string(context.DatabaseName()),
+		Table:  string(context.TableName()),
+		Gtid:   n.gtid,
+	}
+}
+
+// This returns the column's name (as logged in the table map event's
+// optional metadata), or col_<pos> when the name is not available.
+func columnName(context TableContext, pos int) string {
+	if tm, ok := context.(*TableMapEvent); ok && tm.OptionalMetadata() != nil {
+		names := tm.OptionalMetadata().ColumnNames
+		if pos < len(names) {
+			return string(names[pos])
+		}
+	}
+	return "col_" + strconv.Itoa(pos)
+}
+
+func rowImage(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) map[string]interface{} {
+
+	var unsigned []bool
+	if tm, ok := context.(*TableMapEvent); ok && tm.OptionalMetadata() != nil {
+		unsigned = tm.OptionalMetadata().UnsignedColumns
+	}
+
+	image := make(map[string]interface{}, len(usedColumns))
+	for idx, col := range usedColumns {
+		pos := col.IndexPosition()
+
+		value := row[idx]
+		if pos < len(unsigned) && !unsigned[pos] && isIntegerType(col.Type()) {
+			// nil and already converted (see DriverValues) values are
+			// kept as is.
+			if signed, err := SignExtend(col.Type(), value); err == nil {
+				value = signed
+			}
+		}
+
+		image[columnName(context, pos)] = value
+	}
+	return image
+}
+
+// This returns the primary key columns' names, or nil when the primary key is
+// unknown or is not fully included in the used columns.
+func rowKeyColumns(
+	context TableContext,
+	usedColumns []ColumnDescriptor) []string {
+
+	tm, ok := context.(*TableMapEvent)
+	if !ok || tm.OptionalMetadata() == nil {
+		return nil
+	}
+
+	pk := tm.OptionalMetadata().PrimaryKey
+	if len(pk) == 0 {
+		return nil
+	}
+
+	used := make(map[int]struct{}, len(usedColumns))
+	for _, col := range usedColumns {
+		used[col.IndexPosition()] = struct{}{}
+	}
+
+	key := make([]string, 0, len(pk))
+	for _, pos := range pk {
+		if _, ok := used[pos]; !ok {
+			return nil
+		}
+		key = append(key, columnName(context, pos))
+	}
+	return key
+}
@@ -0,0 +1,157 @@
+package binlog
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type RowChangeSuite struct {
+	context *TableMapEvent
+}
+
+var _ = Suite(&RowChangeSuite{})
+
+func (s *RowChangeSuite) SetUpTest(c *C) {
+	s.context = &TableMapEvent{
+		databaseName:      []byte("db"),
+		tableName:         []byte("users"),
+		columnDescriptors: newTestTableContext().ColumnDescriptors(),
+		optionalMetadata: &TableMapOptionalMetadata{
+			ColumnNames: [][]byte{
+				[]byte("a"),
+				[]byte("b"),
+				[]byte("c"),
+				[]byte("d"),
+				[]byte("e"),
+			},
+		},
+	}
+}
+
+func (s *RowChangeSuite) gtidEvent(gno uint64) *GtidLogEvent {
+	e := &GtidLogEvent{gno: gno}
+	for i := range e.sid {
+		e.sid[i] = byte(i)
+	}
+	return e
+}
+
+func (s *RowChangeSuite) TestInsert(c *C) {
+	n := NewRowChangeNormalizer()
+
+	c.Assert(n.Normalize(s.gtidEvent(23)), IsNil)
+
+	changes := n.Normalize(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[:2],
+		rows: []RowValues{
+			{uint64(1), uint64(2)},
+			{uint64(3), nil},
+		},
+	})
+
+	gtid := "00010203-0405-0607-0809-0a0b0c0d0e0f:23"
+	c.Assert(changes, DeepEquals, []*RowChange{
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"a": uint64(1), "b": uint64(2)},
+			Schema: "db",
+			Table:  "users",
+			Gtid:   gtid,
+		},
+		{
+			Action: InsertAction,
+			After:  map[string]interface{}{"a": uint64(3), "b": nil},
+			Schema: "db",
+			Table:  "users",
+			Gtid:   gtid,
+		},
+	})
+}
+
+func (s *RowChangeSuite) TestMariadbGtid(c *C) {
+	n := NewRowChangeNormalizer()
+
+	gtidEvent := &MariadbGtidLogEvent{
+		gtid: MariadbGtid{DomainId: 0, ServerId: 1, SeqNo: 23},
+	}
+	c.Assert(n.Normalize(gtidEvent), IsNil)
+
+	changes := n.Normalize(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[:1],
+		rows:          []RowValues{{uint64(1)}},
+	})
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Gtid, Equals, "0-1-23")
+
+	c.Assert(n.Normalize(&XidEvent{}), IsNil)
+
+	changes = n.Normalize(&WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: s.context},
+		usedColumns:   s.context.ColumnDescriptors()[:1],
+		rows:          []RowValues{{uint64(1)}},
+	})
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Gtid, Equals, "")
+}
+
+func (s *RowChangeSuite) TestUpdate(c *C) {
+	n := NewRowChangeNormalizer()
+
+	columns := s.context.ColumnDescriptors()
+	changes := n.Normalize(&UpdateRowsEvent{
+		BaseRowsEvent:          BaseRowsEvent{context: s.context},
+		beforeImageUsedColumns: []ColumnDescriptor{columns[0], columns[4]},
+		afterImageUsedColumns:  []ColumnDescriptor{columns[4]},
+		rows: []UpdateRowValues{
+			{
+				BeforeImage: RowValues{uint64(1), uint64(5)},
+				AfterImage:  RowValues{uint64(6)},
+			},
+		},
+	})
+
+	c.Assert(changes, DeepEquals, []*RowChange{
+		{
+			Action: UpdateAction,
+			Before: map[string]interface{}{"a": uint64(1), "e": uint64(5)},
+			After:  map[string]interface{}{"e": uint64(6)},
+			Schema: "db",
+			Table:  "users",
+		},
+	})
+}
+
+func (s *RowChangeSuite) TestDelete(c *C) {
+	n := NewRowChangeNormalizer()
+
+	n.Normalize(s.gtidEvent(1))
+	c.Assert(n.Normalize(&XidEvent{}), IsNil)
+
+	// Column names are not available.
+	context := newTestTableContext()
+	changes := n.Normalize(&DeleteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{context: context},
+		usedColumns:   context.ColumnDescriptors()[1:3],
+		rows:          []RowValues{{uint64(7), uint64(8)}},
+	})
+
+	c.Assert(changes, DeepEquals, []*RowChange{
+		{
+			Action: DeleteAction,
+			Before: map[string]interface{}{
+				"col_1": uint64(7),
+				"col_2": uint64(8),
+			},
+			Schema: "database",
+			Table:  "table",
+		},
+	})
+}
+
+func (s *RowChangeSuite) TestActionString(c *C) {
+	c.Assert(InsertAction.String(), Equals, "INSERT")
+	c.Assert(UpdateAction.String(), Equals, "UPDATE")
+	c.Assert(DeleteAction.String(), Equals, "DELETE")
+	c.Assert(RowChangeAction(7).String(), Equals, "UNKNOWN(7)")
+}
@@ -1,6 +1,8 @@
 package binlog
 
 import (
+	"time"
+
 	"github.com/dropbox/godropbox/errors"
 	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
 )
@@ -104,7 +106,8 @@ type WriteRowsEvent struct {
 
 	usedColumns []ColumnDescriptor
 
-	rows []RowValues
+	rows    []RowValues
+	rawRows []RawRowValues
 }
 
 // UsedColumns returns the column descriptors that are used by the event.
@@ -117,13 +120,20 @@ func (e *WriteRowsEvent) InsertedRows() []RowValues {
 	return e.rows
 }
 
+// InsertedRawRows returns the inserted rows' raw values, in the same order as
+// InsertedRows.  This is nil unless DecodeOptions.RawValues is set.
+func (e *WriteRowsEvent) InsertedRawRows() []RawRowValues {
+	return e.rawRows
+}
+
 // A representation of the v1 / v2 delete rows event.
 type DeleteRowsEvent struct {
 	BaseRowsEvent
 
 	usedColumns []ColumnDescriptor
 
-	rows []RowValues
+	rows    []RowValues
+	rawRows []RawRowValues
 }
 
 // UsedColumns returns the column descriptors that are used by the event.
@@ -136,6 +146,12 @@ func (e *DeleteRowsEvent) DeletedRows() []RowValues {
 	return e.rows
 }
 
+// DeletedRawRows returns the deleted rows' raw values, in the same order as
+// DeletedRows.  This is nil unless DecodeOptions.RawValues is set.
+func (e *DeleteRowsEvent) DeletedRawRows() []RawRowValues {
+	return e.rawRows
+}
+
 // A single update row's used columns values.
 type UpdateRowValues struct {
 	BeforeImage RowValues
@@ -149,7 +165,8 @@ type UpdateRowsEvent struct {
 	beforeImageUsedColumns []ColumnDescriptor
 	afterImageUsedColumns  []ColumnDescriptor
 
-	rows []UpdateRowValues
+	rows    []UpdateRowValues
+	rawRows []UpdateRawRowValues
 }
 
 // BeforeImageUsedColumns returns the before image column descriptors that
@@ -169,6 +186,12 @@ func (e *UpdateRowsEvent) UpdatedRows() []UpdateRowValues {
 	return e.rows
 }
 
+// UpdatedRawRows returns the updated rows' raw values, in the same order as
+// UpdatedRows.  This is nil unless DecodeOptions.RawValues is set.
+func (e *UpdateRowsEvent) UpdatedRawRows() []UpdateRawRowValues {
+	return e.rawRows
+}
+
 //
 // baseRowsEventParser --------------------------------------------------------
 //
@@ -185,6 +208,14 @@ type baseRowsEventParser struct {
 	version mysql_proto.RowsEventVersion_Type
 
 	context TableContext
+
+	allocator RowAllocator
+
+	metrics *DecodeMetrics
+
+	onLossyConversion LossyConversionHandler
+
+	rawValues bool
 }
 
 func (p *baseRowsEventParser) EventType() mysql_proto.LogEventType_Type {
@@ -202,6 +233,43 @@ func (p *baseRowsEventParser) SetTableContext(context TableContext) {
 	p.context = context
 }
 
+// SetRowAllocator sets the allocator used for decoded rows.  When nil,
+// DefaultRowAllocator is used.
+func (p *baseRowsEventParser) SetRowAllocator(allocator RowAllocator) {
+	p.allocator = allocator
+}
+
+// SetDecodeMetrics sets the metrics into which value decode timings and
+// table throughput are recorded (see DecodeOptions.Metrics).  When nil, no
+// metrics are recorded.
+func (p *baseRowsEventParser) SetDecodeMetrics(metrics *DecodeMetrics) {
+	p.metrics = metrics
+}
+
+// SetLossyConversionHandler sets the handler invoked for each lossily
+// decoded value.  When nil, lossy conversions are not reported.
+func (p *baseRowsEventParser) SetLossyConversionHandler(
+	handler LossyConversionHandler) {
+
+	p.onLossyConversion = handler
+}
+
+// SetRawValues sets whether or not the decoded values' packed bytes are
+// retained.
+func (p *baseRowsEventParser) SetRawValues(enabled bool) {
+	p.rawValues = enabled
+}
+
+// This records the parsed rows event's table throughput, if metrics is set.
+func (p *baseRowsEventParser) recordTableMetrics(
+	raw *RawV4Event,
+	numRows int) {
+
+	if p.metrics != nil {
+		p.metrics.recordTable(p.context, numRows, len(raw.Bytes()))
+	}
+}
+
 func (p *baseRowsEventParser) parseRowsHeader(raw *RawV4Event) (
 	id uint64,
 	flags uint16,
@@ -315,40 +383,64 @@ func (p *baseRowsEventParser) parseUsedColumns(
 	remaining []byte,
 	err error) {
 
-	usedColumnBits, remaining, err := readBitArray(data, width)
+	usedColumnBits, remaining, err := readBitmap(data, width)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	allColumns := p.context.ColumnDescriptors()
 
-	usedColumns = make([]ColumnDescriptor, 0, 0)
+	usedColumns = make([]ColumnDescriptor, 0, width)
 	for idx := 0; idx < width; idx++ {
-		if usedColumnBits[idx] {
-			usedColumns = append(usedColumns, allColumns[idx])
+		if !isBitSet(usedColumnBits, idx) {
+			continue
 		}
+		usedColumns = append(usedColumns, allColumns[idx])
 	}
 	return usedColumns, remaining, nil
 }
 
+// This parses a single row image from data (a suffix of the event's row
+// data, which is rowDataSize bytes long).  The row's raw values are only
+// returned when raw values are retained.
 func (p *baseRowsEventParser) parseRow(
+	raw *RawV4Event,
+	rowIdx int,
 	usedColumns []ColumnDescriptor,
+	rowDataSize int,
 	data []byte) (
 	row RowValues,
+	rawRow RawRowValues,
 	remaining []byte,
 	err error) {
 
 	numCols := len(usedColumns)
-	nullBits, remaining, err := readBitArray(data, numCols)
+	nullBits, remaining, err := readBitmap(data, numCols)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	allocator := p.allocator
+	if allocator == nil {
+		allocator = DefaultRowAllocator
+	}
+
+	values := allocator.AllocateRow(numCols)
+	if p.rawValues {
+		rawRow = make(RawRowValues, numCols)
 	}
 
-	values := make(RowValues, numCols, numCols)
+	timed := p.metrics != nil && p.metrics.isValueTimingEnabled()
+
 	for idx, descriptor := range usedColumns {
-		if nullBits[idx] {
+		if rawRow != nil {
+			rawRow[idx].Offset = rowDataSize - len(remaining)
+		}
+
+		if isBitSet(nullBits, idx) {
 			if !descriptor.IsNullable() {
-				return nil, nil, errors.Newf(
+				allocator.FreeRow(values)
+				return nil, nil, nil, errors.Newf(
 					"Null value in non-nullable column: %d table: %s",
 					descriptor.IndexPosition(),
 					string(p.context.TableName()))
@@ -357,16 +449,64 @@ func (p *baseRowsEventParser) parseRow(
 			continue
 		}
 
+		valueData := remaining
+
 		var val interface{}
-		val, remaining, err = descriptor.ParseValue(remaining)
+		var loss string
+		if timed {
+			start := time.Now()
+			val, remaining, loss, err = p.parseValue(descriptor, remaining)
+			p.metrics.record(descriptor.Type(), time.Since(start))
+		} else {
+			val, remaining, loss, err = p.parseValue(descriptor, remaining)
+		}
 		if err != nil {
-			return nil, nil, err
+			allocator.FreeRow(values)
+			return nil, nil, nil, err
+		}
+
+		if rawRow != nil {
+			rawRow[idx].Bytes = valueData[:len(valueData)-len(remaining)]
+		}
+
+		if loss != "" {
+			p.onLossyConversion(&LossyConversion{
+				SourceName:     raw.SourceName(),
+				SourcePosition: raw.SourcePosition(),
+				TableId:        p.context.TableId(),
+				DatabaseName:   string(p.context.DatabaseName()),
+				TableName:      string(p.context.TableName()),
+				RowIndex:       rowIdx,
+				ColumnIndex:    descriptor.IndexPosition(),
+				FieldType:      descriptor.Type(),
+				Reason:         loss,
+			})
 		}
 
 		values[idx] = val
 	}
 
-	return values, remaining, nil
+	return values, rawRow, remaining, nil
+}
+
+// This parses the value, and also returns the reason the value was decoded
+// lossily when lossy conversions are reported.
+func (p *baseRowsEventParser) parseValue(
+	descriptor ColumnDescriptor,
+	data []byte) (
+	value interface{},
+	remaining []byte,
+	loss string,
+	err error) {
+
+	if p.onLossyConversion != nil {
+		if lossy, ok := asLossyFieldDescriptor(descriptor); ok {
+			return lossy.parseValueWithLoss(data)
+		}
+	}
+
+	value, remaining, err = descriptor.ParseValue(data)
+	return value, remaining, "", err
 }
 
 //
@@ -424,13 +564,23 @@ func (p *WriteRowsEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	for len(remaining) > 0 {
 		var row RowValues
-		row, remaining, err = p.parseRow(descriptors, remaining)
+		var rawRow RawRowValues
+		row, rawRow, remaining, err = p.parseRow(
+			raw,
+			len(e.rows),
+			descriptors,
+			len(e.rowDataBytes),
+			remaining)
 		if err != nil {
 			return raw, err
 		}
 		e.rows = append(e.rows, row)
+		if rawRow != nil {
+			e.rawRows = append(e.rawRows, rawRow)
+		}
 	}
 
+	p.recordTableMetrics(raw, len(e.rows))
 	return e, nil
 }
 
@@ -495,23 +645,37 @@ func (p *UpdateRowsEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	for len(remaining) > 0 {
 		var beforeImage RowValues
-		beforeImage, remaining, err = p.parseRow(
+		var rawBeforeImage RawRowValues
+		beforeImage, rawBeforeImage, remaining, err = p.parseRow(
+			raw,
+			len(e.rows),
 			beforeDescriptors,
+			len(e.rowDataBytes),
 			remaining)
 		if err != nil {
 			return raw, err
 		}
 
 		var afterImage RowValues
-		afterImage, remaining, err = p.parseRow(
+		var rawAfterImage RawRowValues
+		afterImage, rawAfterImage, remaining, err = p.parseRow(
+			raw,
+			len(e.rows),
 			afterDescriptors,
+			len(e.rowDataBytes),
 			remaining)
 		if err != nil {
 			return raw, err
 		}
 		e.rows = append(e.rows, UpdateRowValues{beforeImage, afterImage})
+		if p.rawValues {
+			e.rawRows = append(
+				e.rawRows,
+				UpdateRawRowValues{rawBeforeImage, rawAfterImage})
+		}
 	}
 
+	p.recordTableMetrics(raw, len(e.rows))
 	return e, nil
 }
 
@@ -570,12 +734,22 @@ func (p *DeleteRowsEventParser) Parse(raw *RawV4Event) (Event, error) {
 
 	for len(remaining) > 0 {
 		var row RowValues
-		row, remaining, err = p.parseRow(descriptors, remaining)
+		var rawRow RawRowValues
+		row, rawRow, remaining, err = p.parseRow(
+			raw,
+			len(e.rows),
+			descriptors,
+			len(e.rowDataBytes),
+			remaining)
 		if err != nil {
 			return raw, err
 		}
 		e.rows = append(e.rows, row)
+		if rawRow != nil {
+			e.rawRows = append(e.rawRows, rawRow)
+		}
 	}
 
+	p.recordTableMetrics(raw, len(e.rows))
 	return e, nil
 }
@@ -0,0 +1,250 @@
+package binlog
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// RowKeyFunc returns the row's idempotency key, i.e., a key which uniquely
+// identifies the row within its table (e.g., the row's primary key values).
+// usedColumns are the row values' column descriptors.
+type RowKeyFunc func(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) []byte
+
+// DefaultRowKey uses the row's primary key values as the row's key when the
+// primary key is known (i.e., the table map event's optional metadata
+// includes the primary key, and all primary key columns are used by the
+// row).  Otherwise, all the row's values are used as the key.
+func DefaultRowKey(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) []byte {
+
+	buf := &bytes.Buffer{}
+
+	if tm, ok := context.(*TableMapEvent); ok && tm.OptionalMetadata() != nil {
+		pk := tm.OptionalMetadata().PrimaryKey
+
+		positions := make(map[int]int, len(usedColumns))
+		for idx, col := range usedColumns {
+			positions[col.IndexPosition()] = idx
+		}
+
+		complete := len(pk) > 0
+		for _, colIdx := range pk {
+			idx, ok := positions[colIdx]
+			if !ok {
+				complete = false
+				break
+			}
+			fmt.Fprintf(buf, "%v\x00", row[idx])
+		}
+
+		if complete {
+			return buf.Bytes()
+		}
+		buf.Reset()
+	}
+
+	for _, value := range row {
+		fmt.Fprintf(buf, "%v\x00", value)
+	}
+	return buf.Bytes()
+}
+
+// Sampler deterministically keeps a fraction of row changes, based on the
+// hash of the rows' idempotency keys (and table names).  Since the decision
+// only depends on the key, all changes to the same row are consistently
+// kept (or dropped), across streams and processes.
+//
+// Sampler is thread safe.
+type Sampler struct {
+	fraction  float64
+	threshold uint64 // keys whose hash < threshold are kept.
+	keepAll   bool
+	keyFunc   RowKeyFunc
+}
+
+// This returns a sampler which keeps the given fraction (within [0, 1]) of
+// row changes.  When keyFunc is nil, DefaultRowKey is used.
+func NewSampler(fraction float64, keyFunc RowKeyFunc) (*Sampler, error) {
+	if math.IsNaN(fraction) || fraction < 0 || fraction > 1 {
+		return nil, errors.Newf("Invalid sampling fraction: %v", fraction)
+	}
+
+	if keyFunc == nil {
+		keyFunc = DefaultRowKey
+	}
+
+	return &Sampler{
+		fraction:  fraction,
+		threshold: uint64(fraction * math.MaxUint64),
+		keepAll:   fraction == 1,
+		keyFunc:   keyFunc,
+	}, nil
+}
+
+// Fraction returns the fraction of row changes kept by the sampler.
+func (s *Sampler) Fraction() float64 {
+	return s.fraction
+}
+
+func sampleHash(key []byte) uint64 {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write(key)
+	h := hasher.Sum64()
+
+	// fnv's high bits are poorly mixed for short keys; finalize the hash
+	// using murmur's 64-bit finalizer.
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Keep returns true if the key is in the sample.
+func (s *Sampler) Keep(key []byte) bool {
+	if s.keepAll {
+		return true
+	}
+	return sampleHash(key) < s.threshold
+}
+
+// KeepRow returns true if the row is in the sample.  The table's database
+// and table names are part of the sampled key, hence rows in different
+// tables are sampled independently.
+func (s *Sampler) KeepRow(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) bool {
+
+	key := make([]byte, 0, 64)
+	key = append(key, context.DatabaseName()...)
+	key = append(key, 0)
+	key = append(key, context.TableName()...)
+	key = append(key, 0)
+	key = append(key, s.keyFunc(context, usedColumns, row)...)
+
+	return s.Keep(key)
+}
+
+// SampleEvent drops row changes which are not in the sample from rows
+// events.  This returns a shallow copy of the rows event with only the
+// sampled rows (the original event is not modified), and false if none of
+// the event's rows are sampled.  Non-rows events are returned as is.
+// NOTE: The copy's RowDataBytes still refers to all the original rows.
+//
+// Update row changes are sampled based on the before image.
+func (s *Sampler) SampleEvent(event Event) (Event, bool) {
+	switch e := event.(type) {
+	case *WriteRowsEvent:
+		rows := s.sampleRows(e.Context(), e.UsedColumns(), e.InsertedRows())
+		if len(rows) == 0 {
+			return nil, false
+		}
+
+		sampled := *e
+		sampled.rows = rows
+		return &sampled, true
+	case *DeleteRowsEvent:
+		rows := s.sampleRows(e.Context(), e.UsedColumns(), e.DeletedRows())
+		if len(rows) == 0 {
+			return nil, false
+		}
+
+		sampled := *e
+		sampled.rows = rows
+		return &sampled, true
+	case *UpdateRowsEvent:
+		rows := make([]UpdateRowValues, 0, len(e.UpdatedRows()))
+		for _, row := range e.UpdatedRows() {
+			if s.KeepRow(
+				e.Context(),
+				e.BeforeImageUsedColumns(),
+				row.BeforeImage) {
+
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) == 0 {
+			return nil, false
+		}
+
+		sampled := *e
+		sampled.rows = rows
+		return &sampled, true
+	}
+
+	return event, true
+}
+
+func (s *Sampler) sampleRows(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	rows []RowValues) []RowValues {
+
+	result := make([]RowValues, 0, len(rows))
+	for _, row := range rows {
+		if s.KeepRow(context, usedColumns, row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+type samplingEventReader struct {
+	reader  EventReader
+	sampler *Sampler
+}
+
+// This returns an EventReader which applies the sampler to the parsed events
+// returned by the reader.  Rows events without any sampled rows are skipped.
+// Events returned along with an error are passed through unsampled.
+func NewSamplingEventReader(reader EventReader, sampler *Sampler) EventReader {
+	return &samplingEventReader{
+		reader:  reader,
+		sampler: sampler,
+	}
+}
+
+func (r *samplingEventReader) peekHeaderBytes(numBytes int) ([]byte, error) {
+	return r.reader.peekHeaderBytes(numBytes)
+}
+
+func (r *samplingEventReader) consumeHeaderBytes(numBytes int) error {
+	return r.reader.consumeHeaderBytes(numBytes)
+}
+
+func (r *samplingEventReader) nextEventEndPosition() int64 {
+	return r.reader.nextEventEndPosition()
+}
+
+func (r *samplingEventReader) NextPosition() int64 {
+	return r.reader.NextPosition()
+}
+
+func (r *samplingEventReader) Close() error {
+	return r.reader.Close()
+}
+
+func (r *samplingEventReader) NextEvent() (Event, error) {
+	for {
+		event, err := r.reader.NextEvent()
+		if err != nil {
+			return event, err
+		}
+
+		sampled, ok := r.sampler.SampleEvent(event)
+		if ok {
+			return sampled, nil
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package binlog
+
+import (
+	"fmt"
+	"io"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type SamplerSuite struct {
+	context TableContext
+}
+
+var _ = Suite(&SamplerSuite{})
+
+func (s *SamplerSuite) SetUpTest(c *C) {
+	s.context = newTestTableContext()
+}
+
+func (s *SamplerSuite) writeRowsEvent(numRows int) *WriteRowsEvent {
+	rows := make([]RowValues, 0, numRows)
+	for i := 0; i < numRows; i++ {
+		rows = append(rows, RowValues{uint64(i), uint64(i % 7)})
+	}
+
+	return &WriteRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{
+			context: s.context,
+		},
+		usedColumns: s.context.ColumnDescriptors()[:2],
+		rows:        rows,
+	}
+}
+
+func (s *SamplerSuite) TestInvalidFraction(c *C) {
+	for _, fraction := range []float64{-0.1, 1.5} {
+		_, err := NewSampler(fraction, nil)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *SamplerSuite) TestKeepNoneOrAll(c *C) {
+	none, err := NewSampler(0, nil)
+	c.Assert(err, IsNil)
+
+	all, err := NewSampler(1, nil)
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		c.Assert(none.Keep(key), IsFalse)
+		c.Assert(all.Keep(key), IsTrue)
+	}
+}
+
+func (s *SamplerSuite) TestKeptFraction(c *C) {
+	numKeys := 100000
+
+	for _, fraction := range []float64{0.01, 0.1, 0.5} {
+		sampler, err := NewSampler(fraction, nil)
+		c.Assert(err, IsNil)
+		c.Check(sampler.Fraction(), Equals, fraction)
+
+		kept := 0
+		for i := 0; i < numKeys; i++ {
+			if sampler.Keep([]byte(fmt.Sprintf("%d", i))) {
+				kept++
+			}
+		}
+
+		actual := float64(kept) / float64(numKeys)
+		c.Check(
+			actual > fraction*0.9 && actual < fraction*1.1,
+			IsTrue,
+			Commentf("fraction: %v actual: %v", fraction, actual))
+	}
+}
+
+func (s *SamplerSuite) TestDeterministic(c *C) {
+	sampler1, err := NewSampler(0.3, nil)
+	c.Assert(err, IsNil)
+
+	sampler2, err := NewSampler(0.3, nil)
+	c.Assert(err, IsNil)
+
+	usedColumns := s.context.ColumnDescriptors()[:2]
+	for i := 0; i < 1000; i++ {
+		row := RowValues{uint64(i), uint64(2 * i)}
+		kept := sampler1.KeepRow(s.context, usedColumns, row)
+		c.Assert(sampler1.KeepRow(s.context, usedColumns, row), Equals, kept)
+		c.Assert(sampler2.KeepRow(s.context, usedColumns, row), Equals, kept)
+	}
+}
+
+func (s *SamplerSuite) TestDefaultRowKeyUsesPrimaryKey(c *C) {
+	tm := &TableMapEvent{
+		databaseName:      []byte("db"),
+		tableName:         []byte("t"),
+		columnDescriptors: s.context.ColumnDescriptors(),
+		optionalMetadata: &TableMapOptionalMetadata{
+			PrimaryKey: []int{2},
+		},
+	}
+
+	cols := tm.ColumnDescriptors()
+
+	// Only the primary key column (col 2) matters.
+	key1 := DefaultRowKey(
+		tm,
+		cols[1:3],
+		RowValues{uint64(1), uint64(10)})
+	key2 := DefaultRowKey(
+		tm,
+		cols[:3],
+		RowValues{uint64(5), uint64(6), uint64(10)})
+	c.Check(string(key1), Equals, string(key2))
+
+	key3 := DefaultRowKey(
+		tm,
+		cols[1:3],
+		RowValues{uint64(1), uint64(11)})
+	c.Check(string(key1), Not(Equals), string(key3))
+
+	// The whole row is used when the primary key column is not used.
+	key4 := DefaultRowKey(tm, cols[:2], RowValues{uint64(1), uint64(2)})
+	key5 := DefaultRowKey(tm, cols[:2], RowValues{uint64(1), uint64(3)})
+	c.Check(string(key4), Not(Equals), string(key5))
+}
+
+func (s *SamplerSuite) TestSampleEvent(c *C) {
+	sampler, err := NewSampler(0.2, nil)
+	c.Assert(err, IsNil)
+
+	event := s.writeRowsEvent(10000)
+
+	sampled, ok := sampler.SampleEvent(event)
+	c.Assert(ok, IsTrue)
+
+	rows := sampled.(*WriteRowsEvent).InsertedRows()
+	c.Check(len(rows) > 1800 && len(rows) < 2200, IsTrue, Commentf("%d", len(rows)))
+
+	// The original event is not modified.
+	c.Check(event.InsertedRows(), HasLen, 10000)
+
+	for _, row := range rows {
+		c.Assert(sampler.KeepRow(s.context, event.UsedColumns(), row), IsTrue)
+	}
+
+	// Non-rows events are passed through.
+	query := &QueryEvent{}
+	result, ok := sampler.SampleEvent(query)
+	c.Check(ok, IsTrue)
+	c.Check(result, Equals, query)
+}
+
+func (s *SamplerSuite) TestSampleUpdateEvent(c *C) {
+	sampler, err := NewSampler(0.5, nil)
+	c.Assert(err, IsNil)
+
+	rows := []UpdateRowValues{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, UpdateRowValues{
+			BeforeImage: RowValues{uint64(i)},
+			AfterImage:  RowValues{uint64(i + 1)},
+		})
+	}
+
+	event := &UpdateRowsEvent{
+		BaseRowsEvent: BaseRowsEvent{
+			context: s.context,
+		},
+		beforeImageUsedColumns: s.context.ColumnDescriptors()[:1],
+		afterImageUsedColumns:  s.context.ColumnDescriptors()[:1],
+		rows:                   rows,
+	}
+
+	sampled, ok := sampler.SampleEvent(event)
+	c.Assert(ok, IsTrue)
+
+	for _, row := range sampled.(*UpdateRowsEvent).UpdatedRows() {
+		c.Assert(
+			sampler.KeepRow(
+				s.context,
+				event.BeforeImageUsedColumns(),
+				row.BeforeImage),
+			IsTrue)
+	}
+	c.Check(len(sampled.(*UpdateRowsEvent).UpdatedRows()) < 1000, IsTrue)
+
+	// Everything is dropped.
+	none, err := NewSampler(0, nil)
+	c.Assert(err, IsNil)
+
+	_, ok = none.SampleEvent(event)
+	c.Check(ok, IsFalse)
+}
+
+type sliceEventReader struct {
+	EventReader
+
+	events []Event
+}
+
+func (r *sliceEventReader) NextEvent() (Event, error) {
+	if len(r.events) == 0 {
+		return nil, io.EOF
+	}
+
+	event := r.events[0]
+	r.events = r.events[1:]
+	return event, nil
+}
+
+func (s *SamplerSuite) TestSamplingEventReader(c *C) {
+	sampler, err := NewSampler(0.5, nil)
+	c.Assert(err, IsNil)
+
+	// Find a row that is dropped.
+	usedColumns := s.context.ColumnDescriptors()[:2]
+	var dropped RowValues
+	for i := 0; dropped == nil; i++ {
+		row := RowValues{uint64(i), uint64(0)}
+		if !sampler.KeepRow(s.context, usedColumns, row) {
+			dropped = row
+		}
+	}
+
+	droppedEvent := s.writeRowsEvent(0)
+	droppedEvent.rows = []RowValues{dropped}
+
+	query := &QueryEvent{}
+	reader := NewSamplingEventReader(
+		&sliceEventReader{
+			events: []Event{
+				droppedEvent,
+				query,
+				droppedEvent,
+				s.writeRowsEvent(100),
+			},
+		},
+		sampler)
+
+	event, err := reader.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(event, Equals, query)
+
+	event, err = reader.NextEvent()
+	c.Assert(err, IsNil)
+	rows := event.(*WriteRowsEvent).InsertedRows()
+	c.Check(len(rows) > 0 && len(rows) < 100, IsTrue)
+
+	_, err = reader.NextEvent()
+	c.Check(err, Equals, io.EOF)
+}
@@ -0,0 +1,209 @@
+package binlog
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/lrucache"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// The default maximum number of table schemas cached by the parsed event
+// reader.
+const DefaultSchemaCacheSize = 1024
+
+// SchemaCache is a bounded cache of table map derived table contexts (i.e.,
+// table schemas), keyed by table id.  When the cache is full, the least
+// recently used schema is evicted.  Evicting a schema is always safe since
+// mysql writes a table map event before the rows events (in the same event
+// group) which reference the table; the schema is re-derived from the next
+// table map event.
+//
+// In addition to the schema count limit, the cache can be bounded by the
+// schemas' estimated memory usage (see SetMemoryLimit), and can be asked to
+// release memory when the process is under memory pressure (see
+// ReleaseMemory).
+//
+// SchemaCache is thread safe.
+type SchemaCache struct {
+	mutex sync.Mutex
+	cache *lrucache.LRUCache
+
+	sizes       map[uint64]int64 // table id -> estimated schema size
+	memoryUsage int64
+	memoryLimit int64 // 0 means unlimited.
+}
+
+// This returns a schema cache which holds up to maxSize table schemas.
+func NewSchemaCache(maxSize int) *SchemaCache {
+	return &SchemaCache{
+		cache: lrucache.New(maxSize),
+		sizes: make(map[uint64]int64),
+	}
+}
+
+const (
+	// Rough estimates of the memory used by a table schema, in addition to
+	// the table map event's bytes.
+	schemaBaseOverhead   = 256
+	schemaColumnOverhead = 128
+)
+
+// This returns the table schema's estimated memory usage in bytes.
+func estimateSchemaSize(context TableContext) int64 {
+	size := int64(schemaBaseOverhead +
+		len(context.DatabaseName()) +
+		len(context.TableName()) +
+		schemaColumnOverhead*len(context.ColumnDescriptors()))
+
+	if event, ok := context.(Event); ok {
+		size += int64(len(event.Bytes()))
+	}
+
+	return size
+}
+
+func schemaCacheKey(tableId uint64) string {
+	return strconv.FormatUint(tableId, 10)
+}
+
+// Add adds (or replaces) the table's schema.
+func (c *SchemaCache) Add(context TableContext) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tableId := context.TableId()
+	key := schemaCacheKey(tableId)
+
+	if oldSize, ok := c.sizes[tableId]; ok {
+		c.memoryUsage -= oldSize
+	} else if c.cache.Len() >= c.cache.MaxSize() {
+		c.removeOldest()
+	}
+
+	size := estimateSchemaSize(context)
+	c.sizes[tableId] = size
+	c.memoryUsage += size
+	c.cache.Set(key, context)
+
+	// The newly added schema is never evicted, even when it alone exceeds
+	// the memory limit.
+	for c.memoryLimit > 0 &&
+		c.memoryUsage > c.memoryLimit &&
+		c.cache.Len() > 1 {
+
+		c.removeOldest()
+	}
+}
+
+// This removes the least recently used schema, and returns the schema's
+// estimated size.
+func (c *SchemaCache) removeOldest() int64 {
+	_, val, ok := c.cache.RemoveOldest()
+	if !ok {
+		return 0
+	}
+
+	tableId := val.(TableContext).TableId()
+	size := c.sizes[tableId]
+	delete(c.sizes, tableId)
+	c.memoryUsage -= size
+	return size
+}
+
+// MemoryUsage returns the cached schemas' estimated memory usage in bytes.
+func (c *SchemaCache) MemoryUsage() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.memoryUsage
+}
+
+// SetMemoryLimit bounds the cached schemas' estimated memory usage.  Least
+// recently used schemas are evicted (immediately, and whenever schemas are
+// added) until the usage is within the limit.  A non-positive limit removes
+// the bound.
+func (c *SchemaCache) SetMemoryLimit(limit int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if limit < 0 {
+		limit = 0
+	}
+	c.memoryLimit = limit
+
+	for c.memoryLimit > 0 && c.memoryUsage > c.memoryLimit {
+		c.removeOldest()
+	}
+}
+
+// MemoryLimit returns the memory limit (0 means unlimited).
+func (c *SchemaCache) MemoryLimit() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.memoryLimit
+}
+
+// ReleaseMemory evicts least recently used schemas until at least numBytes
+// (estimated) bytes are released, or the cache is empty.  This returns the
+// number of released bytes.  This is meant to be called from memory pressure
+// callbacks (e.g., when the process's heap usage crosses a threshold).
+func (c *SchemaCache) ReleaseMemory(numBytes int64) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	released := int64(0)
+	for released < numBytes && c.cache.Len() > 0 {
+		released += c.removeOldest()
+	}
+	return released
+}
+
+// Clear removes all cached schemas.
+func (c *SchemaCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for c.cache.Len() > 0 {
+		c.removeOldest()
+	}
+}
+
+// Get returns the table's schema, if it is cached.
+func (c *SchemaCache) Get(tableId uint64) (TableContext, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	val, ok := c.cache.Get(schemaCacheKey(tableId))
+	if !ok {
+		return nil, false
+	}
+	return val.(TableContext), true
+}
+
+// Len returns the number of cached schemas.
+func (c *SchemaCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.cache.Len()
+}
+
+// MaxSize returns the maximum number of cached schemas.
+func (c *SchemaCache) MaxSize() int {
+	return c.cache.MaxSize()
+}
+
+func isRowsEventType(t mysql_proto.LogEventType_Type) bool {
+	switch t {
+	case mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT,
+		mysql_proto.LogEventType_UPDATE_ROWS_EVENT_V1,
+		mysql_proto.LogEventType_UPDATE_ROWS_EVENT,
+		mysql_proto.LogEventType_DELETE_ROWS_EVENT_V1,
+		mysql_proto.LogEventType_DELETE_ROWS_EVENT:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package binlog
+
+import (
+	"bytes"
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type SchemaCacheSuite struct {
+	EventParserSuite
+
+	schemas *SchemaCache
+}
+
+var _ = Suite(&SchemaCacheSuite{})
+
+const testSchemaCacheSize = 4
+
+func (s *SchemaCacheSuite) SetUpTest(c *C) {
+	s.src = &bytes.Buffer{}
+	s.parsers = NewV4EventParserMap()
+	s.rawReader = NewRawV4EventReader(s.src, testSourceName)
+	s.schemas = NewSchemaCache(testSchemaCacheSize)
+	s.reader = NewParsedV4EventReaderWithSchemaCache(
+		s.rawReader,
+		s.parsers,
+		s.schemas)
+}
+
+func testTableName(tableId uint8) string {
+	return fmt.Sprintf("table%03d", tableId)
+}
+
+// Each table has a single non-null LONG column.
+func (s *SchemaCacheSuite) writeTableMap(tableId uint8) {
+	name := testTableName(tableId)
+
+	data := []byte{
+		// table id
+		tableId, 0, 0, 0, 0, 0,
+		// flags
+		1, 0,
+		// db name length
+		2,
+		// db name
+		'd', 'b', 0,
+		// table name length
+		byte(len(name)),
+	}
+	data = append(data, name...)
+	data = append(data,
+		0,
+		// number of columns
+		1,
+		3,
+		// metadata size
+		0,
+		// null bits
+		0)
+
+	s.WriteEvent(mysql_proto.LogEventType_TABLE_MAP_EVENT, uint16(0), data)
+}
+
+func (s *SchemaCacheSuite) writeRows(tableId uint8, value uint8) {
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		uint16(0),
+		[]byte{
+			// table id
+			tableId, 0, 0, 0, 0, 0,
+			// flags
+			1, 0,
+			// # of columns
+			1,
+			// used columns
+			1,
+			// null bits
+			0,
+			// value
+			value, 0, 0, 0,
+		})
+}
+
+func (s *SchemaCacheSuite) checkRows(c *C, tableId uint8, value uint8) {
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	w, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(w.TableId(), Equals, uint64(tableId))
+	c.Check(string(w.Context().TableName()), Equals, testTableName(tableId))
+
+	rows := w.InsertedRows()
+	c.Assert(len(rows), Equals, 1)
+	c.Check(rows[0][0], Equals, uint64(value))
+}
+
+func (s *SchemaCacheSuite) TestMultipleTablesInEventGroup(c *C) {
+	s.writeTableMap(1)
+	s.writeTableMap(2)
+	s.writeRows(1, 10)
+	s.writeRows(2, 20)
+	s.writeRows(1, 11)
+
+	for i := 0; i < 2; i++ {
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+		_, ok := event.(*TableMapEvent)
+		c.Assert(ok, IsTrue)
+	}
+
+	s.checkRows(c, 1, 10)
+	s.checkRows(c, 2, 20)
+	s.checkRows(c, 1, 11)
+}
+
+func (s *SchemaCacheSuite) TestEvictionUnderManyTables(c *C) {
+	for id := uint8(1); id <= 100; id++ {
+		s.writeTableMap(id)
+		s.writeRows(id, id)
+
+		event, err := s.NextEvent()
+		c.Assert(err, IsNil)
+		_, ok := event.(*TableMapEvent)
+		c.Assert(ok, IsTrue)
+
+		s.checkRows(c, id, id)
+
+		c.Check(s.schemas.Len() <= testSchemaCacheSize, IsTrue)
+	}
+
+	c.Check(s.schemas.Len(), Equals, testSchemaCacheSize)
+
+	for id := uint64(1); id <= 96; id++ {
+		_, ok := s.schemas.Get(id)
+		c.Check(ok, IsFalse)
+	}
+	for id := uint64(97); id <= 100; id++ {
+		context, ok := s.schemas.Get(id)
+		c.Assert(ok, IsTrue)
+		c.Check(context.TableId(), Equals, id)
+	}
+
+	// Rows event for an evicted table without a preceding table map is
+	// rejected ...
+	s.writeRows(1, 1)
+	event, err := s.NextEvent()
+	c.Assert(err, NotNil)
+	_, ok := event.(*RawV4Event)
+	c.Check(ok, IsTrue)
+
+	// ... and the schema is rebuilt from the next table map.
+	s.writeTableMap(1)
+	s.writeRows(1, 1)
+
+	_, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	s.checkRows(c, 1, 1)
+
+	_, ok = s.schemas.Get(1)
+	c.Check(ok, IsTrue)
+	c.Check(s.schemas.Len(), Equals, testSchemaCacheSize)
+}
+
+func (s *SchemaCacheSuite) TestLeastRecentlyUsedEviction(c *C) {
+	for id := uint8(1); id <= testSchemaCacheSize; id++ {
+		s.writeTableMap(id)
+		_, err := s.NextEvent()
+		c.Assert(err, IsNil)
+	}
+
+	// Using table 1 makes table 2 the least recently used.
+	s.writeRows(1, 1)
+	s.checkRows(c, 1, 1)
+
+	s.writeTableMap(testSchemaCacheSize + 1)
+	_, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	_, ok := s.schemas.Get(1)
+	c.Check(ok, IsTrue)
+	_, ok = s.schemas.Get(2)
+	c.Check(ok, IsFalse)
+	c.Check(s.schemas.MaxSize(), Equals, testSchemaCacheSize)
+}
+
+func newTestSchema(tableId uint64, numColumns int) *TableMapEvent {
+	columns := make([]ColumnDescriptor, 0, numColumns)
+	for i := 0; i < numColumns; i++ {
+		columns = append(
+			columns,
+			NewColumnDescriptor(NewLongFieldDescriptor(Nullable), i))
+	}
+
+	return &TableMapEvent{
+		Event:             &RawV4Event{data: make([]byte, 100)},
+		tableId:           tableId,
+		databaseName:      []byte("db"),
+		tableName:         []byte(fmt.Sprintf("table%03d", tableId)),
+		columnDescriptors: columns,
+	}
+}
+
+func (s *SchemaCacheSuite) TestMemoryUsage(c *C) {
+	cache := NewSchemaCache(testSchemaCacheSize)
+	c.Check(cache.MemoryUsage(), Equals, int64(0))
+
+	schema := newTestSchema(1, 10)
+	size := estimateSchemaSize(schema)
+	c.Check(size, Equals, int64(256+2+8+10*128+100))
+
+	cache.Add(schema)
+	c.Check(cache.MemoryUsage(), Equals, size)
+
+	// Replacing the schema does not double count.
+	cache.Add(newTestSchema(1, 20))
+	c.Check(cache.MemoryUsage(), Equals, estimateSchemaSize(newTestSchema(1, 20)))
+
+	// Count based evictions are accounted for.
+	for id := uint64(2); id <= testSchemaCacheSize+1; id++ {
+		cache.Add(newTestSchema(id, 10))
+	}
+	c.Check(cache.Len(), Equals, testSchemaCacheSize)
+	_, ok := cache.Get(1)
+	c.Check(ok, IsFalse)
+	c.Check(cache.MemoryUsage(), Equals, testSchemaCacheSize*size)
+}
+
+func (s *SchemaCacheSuite) TestSetMemoryLimit(c *C) {
+	cache := NewSchemaCache(testSchemaCacheSize)
+	size := estimateSchemaSize(newTestSchema(1, 10))
+
+	for id := uint64(1); id <= 3; id++ {
+		cache.Add(newTestSchema(id, 10))
+	}
+
+	// Table 1 is the most recently used.
+	_, ok := cache.Get(1)
+	c.Assert(ok, IsTrue)
+
+	// Setting the limit evicts immediately.
+	cache.SetMemoryLimit(2 * size)
+	c.Check(cache.MemoryLimit(), Equals, 2*size)
+	c.Check(cache.Len(), Equals, 2)
+	c.Check(cache.MemoryUsage(), Equals, 2*size)
+	_, ok = cache.Get(2)
+	c.Check(ok, IsFalse)
+
+	// Adding a large schema evicts multiple schemas, but never the newly
+	// added schema.
+	cache.Add(newTestSchema(4, 50))
+	c.Check(cache.Len(), Equals, 1)
+	_, ok = cache.Get(4)
+	c.Check(ok, IsTrue)
+
+	cache.SetMemoryLimit(0)
+	for id := uint64(5); id <= 7; id++ {
+		cache.Add(newTestSchema(id, 10))
+	}
+	c.Check(cache.Len(), Equals, testSchemaCacheSize)
+}
+
+func (s *SchemaCacheSuite) TestReleaseMemory(c *C) {
+	cache := NewSchemaCache(testSchemaCacheSize)
+	size := estimateSchemaSize(newTestSchema(1, 10))
+
+	for id := uint64(1); id <= testSchemaCacheSize; id++ {
+		cache.Add(newTestSchema(id, 10))
+	}
+
+	// Simulate memory pressure.
+	c.Check(cache.ReleaseMemory(size+1), Equals, 2*size)
+	c.Check(cache.Len(), Equals, testSchemaCacheSize-2)
+	_, ok := cache.Get(1)
+	c.Check(ok, IsFalse)
+	_, ok = cache.Get(3)
+	c.Check(ok, IsTrue)
+
+	c.Check(cache.ReleaseMemory(0), Equals, int64(0))
+
+	c.Check(cache.ReleaseMemory(100*size), Equals, 2*size)
+	c.Check(cache.Len(), Equals, 0)
+	c.Check(cache.MemoryUsage(), Equals, int64(0))
+}
+
+func (s *SchemaCacheSuite) TestClear(c *C) {
+	cache := NewSchemaCache(testSchemaCacheSize)
+	for id := uint64(1); id <= 3; id++ {
+		cache.Add(newTestSchema(id, 10))
+	}
+
+	cache.Clear()
+	c.Check(cache.Len(), Equals, 0)
+	c.Check(cache.MemoryUsage(), Equals, int64(0))
+	_, ok := cache.Get(1)
+	c.Check(ok, IsFalse)
+
+	cache.Add(newTestSchema(4, 10))
+	c.Check(cache.Len(), Equals, 1)
+}
+
+func (s *SchemaCacheSuite) TestMemoryLimitWithReader(c *C) {
+	for id := uint8(1); id <= 2; id++ {
+		s.writeTableMap(id)
+		_, err := s.NextEvent()
+		c.Assert(err, IsNil)
+	}
+	c.Assert(s.schemas.Len(), Equals, 2)
+
+	// Only one table map event worth of schema fits.
+	schema, ok := s.schemas.Get(2)
+	c.Assert(ok, IsTrue)
+	s.schemas.SetMemoryLimit(estimateSchemaSize(schema))
+	c.Check(s.schemas.Len(), Equals, 1)
+
+	// The table map is always written before its rows events, hence evicting
+	// schemas is safe.
+	s.writeTableMap(1)
+	_, err := s.NextEvent()
+	c.Assert(err, IsNil)
+	s.writeRows(1, 7)
+	s.checkRows(c, 1, 7)
+	c.Check(s.schemas.Len(), Equals, 1)
+}
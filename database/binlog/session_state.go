@@ -0,0 +1,181 @@
+package binlog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// SessionState tracks the replicated session's state (time zone, sql mode and
+// charset), as set by query events' status variables.  See
+// DecodeOptions.Session.
+//
+// mysql only logs the time zone status variable when the statement used the
+// time zone, hence the time zone is scoped to a single query event (and the
+// table map / rows events which follow it): query events without the status
+// variable reset the time zone to unknown, so that one session's time zone
+// does not carry over to later, unrelated transactions.  Time zones which
+// cannot be loaded (e.g., named time zones when tzdata is not installed) also
+// reset the time zone to unknown.  While the time zone is unknown, DATETIME
+// values fall back to DecodeOptions.DateTimeLocation.  The sql mode and
+// charset status variables are logged for every query event; query events
+// without them leave the tracked values unchanged.
+//
+// NOTE: SET statements are not logged as query events (their effects are
+// logged as the following query events' status variables), hence the query
+// text is not interpreted.
+//
+// The zero value is ready to use.  SessionState is thread safe.
+type SessionState struct {
+	mutex sync.RWMutex
+
+	timeZone string // "" when unknown
+	location *time.Location
+	sqlMode  *uint64
+	charset  []byte
+}
+
+// TimeZone returns the session's time zone (e.g., "SYSTEM", "+08:00" or
+// "America/Los_Angeles"), or "" if the time zone is unknown.
+func (s *SessionState) TimeZone() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.timeZone
+}
+
+// Location returns the session time zone's location, or nil if the time zone
+// is unknown or is the server's SYSTEM time zone.
+func (s *SessionState) Location() *time.Location {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.location
+}
+
+// SqlMode returns the session's sql mode, or nil if the sql mode is unknown.
+func (s *SessionState) SqlMode() *uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.sqlMode == nil {
+		return nil
+	}
+	mode := *s.sqlMode
+	return &mode
+}
+
+// IsModeEnabled returns true iff the sql mode is known and the mode bit is
+// set.
+func (s *SessionState) IsModeEnabled(
+	mode mysql_proto.SqlMode_BitPosition) bool {
+
+	sqlMode := s.SqlMode()
+	if sqlMode == nil {
+		return false
+	}
+
+	return (*sqlMode & (uint64(1) << uint(mode))) != 0
+}
+
+// Charset returns the session's charset, in the query event's charset status
+// format (see QueryEvent.Charset), or nil if the charset is unknown.
+func (s *SessionState) Charset() []byte {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.charset
+}
+
+// Reset clears the tracked state, e.g., when switching to a different
+// server's binlog.
+func (s *SessionState) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.timeZone = ""
+	s.location = nil
+	s.sqlMode = nil
+	s.charset = nil
+}
+
+// Update applies the query event's status variables to the session state.
+// The time zone is reset to unknown when the query event has no time zone
+// status variable; the other state is left unchanged when its status
+// variable is not set.
+func (s *SessionState) Update(q *QueryEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if q.TimeZone() != nil {
+		s.setTimeZone(string(q.TimeZone()))
+	} else {
+		s.timeZone = ""
+		s.location = nil
+	}
+
+	if q.SqlMode() != nil {
+		mode := *q.SqlMode()
+		s.sqlMode = &mode
+	}
+
+	if q.Charset() != nil {
+		s.charset = append([]byte(nil), q.Charset()...)
+	}
+}
+
+// This resets the time zone to unknown when tz is not recognized.
+//
+// NOTE: the caller must hold the write lock.
+func (s *SessionState) setTimeZone(tz string) {
+	loc, err := parseTimeZone(tz)
+	if err != nil {
+		s.timeZone = ""
+		s.location = nil
+		return
+	}
+
+	s.timeZone = tz
+	s.location = loc
+}
+
+// This converts a mysql time zone value into a location.  The SYSTEM time
+// zone maps to nil.
+func parseTimeZone(tz string) (*time.Location, error) {
+	if strings.EqualFold(tz, "SYSTEM") {
+		return nil, nil
+	}
+
+	if len(tz) > 0 && (tz[0] == '+' || tz[0] == '-') {
+		parts := strings.Split(tz[1:], ":")
+		if len(parts) != 2 {
+			return nil, errors.Newf("Invalid time zone offset: %s", tz)
+		}
+
+		hours, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil {
+			return nil, errors.Newf("Invalid time zone offset: %s", tz)
+		}
+
+		minutes, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil || minutes >= 60 {
+			return nil, errors.Newf("Invalid time zone offset: %s", tz)
+		}
+
+		offset := int(hours*60*60 + minutes*60)
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone(tz, offset), nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unknown time zone: %s", tz)
+	}
+	return loc, nil
+}
@@ -0,0 +1,358 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type SessionStateSuite struct {
+	EventParserSuite
+
+	session *SessionState
+}
+
+var _ = Suite(&SessionStateSuite{})
+
+func (s *SessionStateSuite) SetUpTest(c *C) {
+	s.session = &SessionState{}
+
+	s.src = &bytes.Buffer{}
+	s.parsers = NewV4EventParserMapWithOptions(
+		DecodeOptions{Session: s.session})
+	s.rawReader = NewRawV4EventReader(s.src, testSourceName)
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+}
+
+func (s *SessionStateSuite) WriteQuery(status []byte, query string) {
+	msg := []byte{
+		// thread id
+		1, 0, 0, 0,
+		// duration
+		0, 0, 0, 0,
+		// db name length
+		4,
+		// error code
+		0, 0}
+
+	writer := &bytes.Buffer{}
+	_ = binary.Write(writer, binary.LittleEndian, uint16(len(status)))
+	msg = append(msg, writer.Bytes()...)
+	msg = append(msg, status...)
+	msg = append(msg, []byte("test\x00")...)
+	msg = append(msg, []byte(query)...)
+
+	s.WriteEvent(mysql_proto.LogEventType_QUERY_EVENT, uint16(0), msg)
+}
+
+// Returns the query event's time zone status variable.
+func timeZoneStatus(tz string) []byte {
+	return append([]byte{5, byte(len(tz))}, tz...)
+}
+
+// Returns the query event's sql mode status variable.
+func sqlModeStatus(modes ...mysql_proto.SqlMode_BitPosition) []byte {
+	mode := uint64(0)
+	for _, m := range modes {
+		mode |= uint64(1) << uint(m)
+	}
+
+	status := make([]byte, 9)
+	status[0] = 1
+	binary.LittleEndian.PutUint64(status[1:], mode)
+	return status
+}
+
+// Writes a table map event for `test`.`t` (a single DATETIME column),
+// followed by a write rows event with a single 2015-06-17 23:45:12 row.
+func (s *SessionStateSuite) WriteDateTimeRow() {
+	s.WriteDateTimeRowBytes(testDateTimeBytes())
+}
+
+// Same as WriteDateTimeRow, but the row contains the encoded DATETIME value.
+func (s *SessionStateSuite) WriteDateTimeRowBytes(value []byte) {
+	s.WriteEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		uint16(0),
+		[]byte{
+			// table id
+			76, 0, 0, 0, 0, 0,
+			// flags
+			1, 0,
+			// db name length
+			4,
+			// db name
+			't', 'e', 's', 't', 0,
+			// table name length
+			1,
+			// table name
+			't', 0,
+			// number of columns
+			1,
+			// column types (datetime)
+			byte(mysql_proto.FieldType_DATETIME),
+			// metadata size
+			0,
+			// null bits
+			1})
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		uint16(0),
+		append(
+			[]byte{
+				// table id
+				76, 0, 0, 0, 0, 0,
+				// flags
+				0, 0,
+				// number of columns
+				1,
+				// used columns
+				1,
+				// null bits
+				0},
+			value...))
+}
+
+func (s *SessionStateSuite) NextDateTime(c *C) time.Time {
+	val, err := s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+
+	t, ok := val.(time.Time)
+	c.Assert(ok, IsTrue)
+	return t
+}
+
+// Returns the next rows event's decoded DATETIME value, or the rows event's
+// parse error.
+func (s *SessionStateSuite) NextDateTimeValue(c *C) (interface{}, error) {
+	for {
+		event, err := s.NextEvent()
+		if err != nil {
+			if event.EventType() == mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1 {
+				return nil, err
+			}
+			c.Fatal(err)
+		}
+
+		rows, ok := event.(*WriteRowsEvent)
+		if !ok {
+			continue
+		}
+
+		c.Assert(rows.InsertedRows(), HasLen, 1)
+		return rows.InsertedRows()[0][0], nil
+	}
+}
+
+func (s *SessionStateSuite) TestTimeZoneScopedToQueryEvent(c *C) {
+	s.WriteDateTimeRow()
+	s.WriteQuery(timeZoneStatus("-08:00"), "BEGIN")
+	s.WriteDateTimeRow()
+	s.WriteQuery(timeZoneStatus("SYSTEM"), "BEGIN")
+	s.WriteDateTimeRow()
+	s.WriteQuery(timeZoneStatus("-08:00"), "BEGIN")
+	s.WriteQuery(nil, "BEGIN")
+	s.WriteDateTimeRow()
+
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "-08:00")
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "SYSTEM")
+	c.Check(s.session.Location(), IsNil)
+
+	// The previous transaction's time zone does not carry over.
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "")
+	c.Check(s.session.Location(), IsNil)
+}
+
+func (s *SessionStateSuite) TestStatusTimeZone(c *C) {
+	s.WriteQuery(
+		[]byte{
+			// time zone
+			5, 6, '+', '0', '5', ':', '3', '0',
+			// sql mode
+			1, 0, 0, 0x20, 0, 0, 0, 0, 0,
+			// charset
+			4, 33, 0, 33, 0, 8, 0,
+		},
+		"BEGIN")
+	s.WriteDateTimeRow()
+
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 18, 15, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "+05:30")
+	c.Check(
+		s.session.IsModeEnabled(mysql_proto.SqlMode_STRICT_TRANS_TABLES),
+		IsTrue)
+	c.Check(s.session.Charset(), DeepEquals, []byte{33, 0, 33, 0, 8, 0})
+}
+
+func (s *SessionStateSuite) TestDateTimeLocationFallback(c *C) {
+	s.parsers = NewV4EventParserMapWithOptions(
+		DecodeOptions{Session: s.session, DateTimeLocation: testPST})
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+
+	s.WriteDateTimeRow()
+	s.WriteQuery(timeZoneStatus("UTC"), "BEGIN")
+	s.WriteDateTimeRow()
+	s.WriteQuery(nil, "BEGIN")
+	s.WriteDateTimeRow()
+
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC))
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC))
+}
+
+func (s *SessionStateSuite) TestInvalidTimeZone(c *C) {
+	s.WriteQuery(timeZoneStatus("+08:00"), "BEGIN")
+	s.WriteQuery(timeZoneStatus("+8"), "BEGIN")
+	s.WriteDateTimeRow()
+	s.WriteQuery(timeZoneStatus("No/Such_Zone"), "BEGIN")
+	s.WriteDateTimeRow()
+
+	// The time zone is unknown; DateTimeLocation (UTC) is used instead.
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "")
+	c.Check(s.session.Location(), IsNil)
+
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+	c.Check(s.session.TimeZone(), Equals, "")
+}
+
+func (s *SessionStateSuite) TestSqlMode(c *C) {
+	s.WriteQuery(
+		sqlModeStatus(
+			mysql_proto.SqlMode_NO_ZERO_DATE,
+			mysql_proto.SqlMode_ANSI_QUOTES),
+		"BEGIN")
+
+	_, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	c.Check(
+		s.session.IsModeEnabled(mysql_proto.SqlMode_NO_ZERO_DATE),
+		IsTrue)
+	c.Check(
+		s.session.IsModeEnabled(mysql_proto.SqlMode_ANSI_QUOTES),
+		IsTrue)
+	c.Check(
+		s.session.IsModeEnabled(mysql_proto.SqlMode_STRICT_TRANS_TABLES),
+		IsFalse)
+
+	// SET statements are not interpreted, and query events without the sql
+	// mode status variable leave the sql mode unchanged.
+	s.WriteQuery(nil, "SET sql_mode = ''")
+
+	_, err = s.NextEvent()
+	c.Assert(err, IsNil)
+	c.Check(
+		s.session.IsModeEnabled(mysql_proto.SqlMode_NO_ZERO_DATE),
+		IsTrue)
+
+	s.session.Reset()
+	c.Check(s.session.SqlMode(), IsNil)
+}
+
+func (s *SessionStateSuite) TestNoZeroDate(c *C) {
+	zero := make([]byte, 8)
+
+	// The sql mode is unknown, hence the zero date is decoded as is.
+	s.WriteDateTimeRowBytes(zero)
+	val, err := s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+	_, ok := val.(time.Time)
+	c.Check(ok, IsTrue)
+
+	s.WriteQuery(sqlModeStatus(), "BEGIN")
+	s.WriteDateTimeRowBytes(zero)
+	val, err = s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, "0000-00-00 00:00:00")
+
+	// Existing zero dates do not fail the stream under NO_ZERO_DATE.
+	s.WriteQuery(
+		sqlModeStatus(
+			mysql_proto.SqlMode_STRICT_TRANS_TABLES,
+			mysql_proto.SqlMode_NO_ZERO_DATE),
+		"BEGIN")
+	s.WriteDateTimeRowBytes(zero)
+	val, err = s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+	c.Check(val, IsNil)
+
+	// Non-zero dates are not affected.
+	s.WriteDateTimeRow()
+	c.Check(
+		s.NextDateTime(c),
+		Equals,
+		time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+
+	s.WriteQuery(
+		sqlModeStatus(mysql_proto.SqlMode_STRICT_TRANS_TABLES),
+		"BEGIN")
+	s.WriteDateTimeRowBytes(zero)
+	val, err = s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, "0000-00-00 00:00:00")
+}
+
+func (s *SessionStateSuite) TestNoZeroDateLossReporting(c *C) {
+	conversions := []*LossyConversion{}
+	s.parsers = NewV4EventParserMapWithOptions(DecodeOptions{
+		Session: s.session,
+		OnLossyConversion: func(conversion *LossyConversion) {
+			conversions = append(conversions, conversion)
+		},
+	})
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+
+	s.WriteQuery(sqlModeStatus(mysql_proto.SqlMode_NO_ZERO_DATE), "BEGIN")
+	s.WriteDateTimeRowBytes(make([]byte, 8))
+
+	val, err := s.NextDateTimeValue(c)
+	c.Assert(err, IsNil)
+	c.Check(val, IsNil)
+
+	c.Assert(conversions, HasLen, 1)
+	c.Check(
+		conversions[0].Reason,
+		Equals,
+		"Invalid zero date: 0000-00-00 00:00:00 (decoded as NULL)")
+}
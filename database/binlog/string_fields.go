@@ -75,6 +75,70 @@ func NewNullFieldDescriptor(nullable NullableColumn) FieldDescriptor {
 		func(b []byte) interface{} { return nil })
 }
 
+// This returns a field descriptor for FieldType_SET (i.e., Field_set).  The
+// value is the set's uint64 bitmask, where bit i corresponds to the set's
+// i-th label (in definition order).  See ExpandSetLabels.  numBytes is the
+// (real type) length from the column's metadata.
+func NewSetFieldDescriptor(nullable NullableColumn, numBytes int) (
+	FieldDescriptor,
+	error) {
+
+	if numBytes < 1 || numBytes > 8 {
+		return nil, errors.Newf("Invalid set length: %d", numBytes)
+	}
+
+	return newFixedLengthFieldDescriptor(
+		mysql_proto.FieldType_SET,
+		nullable,
+		numBytes,
+		func(b []byte) interface{} { return bytesToLEUint(b) }), nil
+}
+
+// This returns a field descriptor for FieldType_ENUM (i.e., Field_enum).  The
+// value is the enum's uint64 index, where 1 corresponds to the enum's first
+// label (in definition order), and 0 corresponds to the invalid value (i.e.,
+// the empty string).  numBytes is the (real type) length from the column's
+// metadata.
+func NewEnumFieldDescriptor(nullable NullableColumn, numBytes int) (
+	FieldDescriptor,
+	error) {
+
+	if numBytes != 1 && numBytes != 2 {
+		return nil, errors.Newf("Invalid enum length: %d", numBytes)
+	}
+
+	return newFixedLengthFieldDescriptor(
+		mysql_proto.FieldType_ENUM,
+		nullable,
+		numBytes,
+		func(b []byte) interface{} { return bytesToLEUint(b) }), nil
+}
+
+// ExpandSetLabels returns the labels of the bits set in a SET column's
+// bitmask, in definition order.  labels must be the set's labels in
+// definition order.  This returns an error if the bitmask has bits set
+// beyond the last label.
+func ExpandSetLabels(bitmask uint64, labels []string) ([]string, error) {
+	if len(labels) < 64 && bitmask>>uint(len(labels)) != 0 {
+		return nil, errors.Newf(
+			"Set bitmask %#x has bits beyond the %d labels",
+			bitmask,
+			len(labels))
+	}
+
+	result := []string{}
+	for i, label := range labels {
+		if i >= 64 {
+			break
+		}
+		if bitmask&(uint64(1)<<uint(i)) != 0 {
+			result = append(result, label)
+		}
+	}
+
+	return result, nil
+}
+
 //
 // packedLengthFieldDescriptor ------------------------------------------------
 //
@@ -135,6 +199,39 @@ func NewVarcharFieldDescriptor(nullable NullableColumn, metadata []byte) (
 		maxLen), metadata[2:], nil
 }
 
+// This returns a field descriptor for a column logged as FieldType_STRING,
+// i.e., CHAR / BINARY, ENUM or SET columns.  The column's real type and
+// length are packed into two metadata bytes (see parseTypeAndLength), where
+// the real type's unused high bits encode the length's bits beyond the
+// first byte (e.g., CHAR(255) in utf8mb4 has a max length of 1020 bytes).
+func NewPackedStringFieldDescriptor(
+	nullable NullableColumn,
+	metadata []byte) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	realType, length, remaining, err := parseTypeAndLength(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch realType {
+	case mysql_proto.FieldType_ENUM:
+		fd, err = NewEnumFieldDescriptor(nullable, length)
+	case mysql_proto.FieldType_SET:
+		fd, err = NewSetFieldDescriptor(nullable, length)
+	default: // FieldType_STRING / FieldType_VAR_STRING
+		fd = NewStringFieldDescriptor(realType, nullable, length)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fd, remaining, nil
+}
+
 func NewStringFieldDescriptor(
 	fieldType mysql_proto.FieldType_Type,
 	nullable NullableColumn,
@@ -189,6 +286,8 @@ func (d *stringFieldDescriptor) ParseValue(data []byte) (
 
 type blobFieldDescriptor struct {
 	packedLengthFieldDescriptor
+
+	lazy bool
 }
 
 // This returns a field descriptor for FieldType_BLOB (i.e., Field_blob)
@@ -197,6 +296,27 @@ func NewBlobFieldDescriptor(nullable NullableColumn, metadata []byte) (
 	remaining []byte,
 	err error) {
 
+	return newBlobFieldDescriptor(nullable, metadata, false)
+}
+
+// Same as NewBlobFieldDescriptor, but the parsed values are *LazyBlobs
+// instead of []byte.
+func NewLazyBlobFieldDescriptor(nullable NullableColumn, metadata []byte) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	return newBlobFieldDescriptor(nullable, metadata, true)
+}
+
+func newBlobFieldDescriptor(
+	nullable NullableColumn,
+	metadata []byte,
+	lazy bool) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
 	if len(metadata) < 1 {
 		return nil, nil, errors.New("Metadata has too few bytes")
 	}
@@ -215,6 +335,7 @@ func NewBlobFieldDescriptor(nullable NullableColumn, metadata []byte) (
 			},
 			packedLength: int(packedLen),
 		},
+		lazy: lazy,
 	}, metadata[1:], nil
 }
 
@@ -223,5 +344,44 @@ func (d *blobFieldDescriptor) ParseValue(data []byte) (
 	remaining []byte,
 	err error) {
 
-	return d.parseValue(data)
+	value, remaining, err = d.parseValue(data)
+	if err != nil || !d.lazy {
+		return value, remaining, err
+	}
+
+	return &LazyBlob{raw: value.([]byte)}, remaining, nil
+}
+
+// LazyBlob is a BLOB / TEXT value which references the value's byte range
+// within the rows event's data.  The value is only copied out of the event's
+// data when Bytes is called, hence consumers which skip the value never pay
+// for the copy.  NOTE: A LazyBlob pins the entire rows event's data in memory
+// until the LazyBlob is garbage collected.
+//
+// LazyBlob is not thread safe.
+type LazyBlob struct {
+	raw    []byte
+	copied []byte
+}
+
+// Len returns the value's length (without copying the value).
+func (b *LazyBlob) Len() int {
+	return len(b.raw)
+}
+
+// Bytes returns a copy of the value, which does not reference the event's
+// data.  The copy is made on the first call; subsequent calls return the
+// same slice.
+func (b *LazyBlob) Bytes() []byte {
+	if b.copied == nil {
+		b.copied = make([]byte, len(b.raw))
+		copy(b.copied, b.raw)
+	}
+	return b.copied
+}
+
+// RawBytes returns the value without copying.  The returned slice references
+// the event's data, and must not be modified.
+func (b *LazyBlob) RawBytes() []byte {
+	return b.raw
 }
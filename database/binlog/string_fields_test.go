@@ -78,6 +78,119 @@ func (s *StringFieldsSuite) TestStringTooFewDataBytes(c *C) {
 	c.Check(err, Not(IsNil))
 }
 
+func (s *StringFieldsSuite) TestPackedStringChar(c *C) {
+	d, remaining, err := NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_STRING), 5, 'a', 'b', 'c'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "abc")
+	c.Check(d.IsNullable(), IsTrue)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_STRING)
+
+	sd, ok := d.(*stringFieldDescriptor)
+	c.Assert(ok, IsTrue)
+	c.Check(sd.maxLength, Equals, 5)
+	c.Check(sd.packedLength, Equals, 1)
+
+	val, remaining, err := d.ParseValue(
+		[]byte{3, 'f', 'o', 'o', 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	c.Check(val, DeepEquals, []byte("foo\x00\x00"))
+}
+
+func (s *StringFieldsSuite) TestPackedStringLongChar(c *C) {
+	// char(255) in utf8mb4, i.e., string = 254, length = 1020
+	//
+	// >>> 254 ^ ((1020 & 0x300) >> 4)
+	// 206
+	// >>> 1020 & 0xff
+	// 252
+	d, remaining, err := NewPackedStringFieldDescriptor(
+		false,
+		[]byte{206, 252})
+	c.Assert(err, IsNil)
+	c.Check(remaining, HasLen, 0)
+	c.Check(d.IsNullable(), IsFalse)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_STRING)
+
+	sd, ok := d.(*stringFieldDescriptor)
+	c.Assert(ok, IsTrue)
+	c.Check(sd.maxLength, Equals, 1020)
+	c.Check(sd.packedLength, Equals, 2)
+
+	// The length prefix is two bytes wide, which must be consumed correctly
+	// in order to parse the subsequent columns.
+	val, remaining, err := d.ParseValue(
+		[]byte{3, 0, 'f', 'o', 'o', 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	real, ok := val.([]byte)
+	c.Assert(ok, IsTrue)
+	c.Check(len(real), Equals, 1020)
+	c.Check(string(real[:3]), Equals, "foo")
+}
+
+func (s *StringFieldsSuite) TestPackedStringEnum(c *C) {
+	d, remaining, err := NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_ENUM), 1, 'a', 'b', 'c'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "abc")
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_ENUM)
+
+	val, remaining, err := d.ParseValue([]byte{2, 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	c.Check(val, Equals, uint64(2))
+
+	// enums with more than 255 labels are stored in 2 bytes.
+	d, _, err = NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_ENUM), 2})
+	c.Assert(err, IsNil)
+
+	val, remaining, err = d.ParseValue([]byte{0x2c, 0x01, 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	c.Check(val, Equals, uint64(300))
+}
+
+func (s *StringFieldsSuite) TestPackedStringSet(c *C) {
+	d, remaining, err := NewPackedStringFieldDescriptor(
+		false,
+		[]byte{byte(mysql_proto.FieldType_SET), 8, 'a', 'b', 'c'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "abc")
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_SET)
+
+	val, remaining, err := d.ParseValue(
+		[]byte{1, 0, 0, 0, 0, 0, 0, 0x80, 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	c.Check(val, Equals, uint64(0x8000000000000001))
+}
+
+func (s *StringFieldsSuite) TestPackedStringInvalidMetadata(c *C) {
+	_, _, err := NewPackedStringFieldDescriptor(true, []byte{1})
+	c.Check(err, NotNil)
+
+	_, _, err = NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_NEWDECIMAL), 1})
+	c.Check(err, NotNil)
+
+	_, _, err = NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_ENUM), 3})
+	c.Check(err, NotNil)
+
+	_, _, err = NewPackedStringFieldDescriptor(
+		true,
+		[]byte{byte(mysql_proto.FieldType_SET), 9})
+	c.Check(err, NotNil)
+}
+
 func (s *StringFieldsSuite) TestVarcharTooFewMetadataBytes(c *C) {
 	_, _, err := NewVarcharFieldDescriptor(true, []byte{1})
 
@@ -146,6 +259,67 @@ func (s *StringFieldsSuite) TestVarcharTooFewDataBytes(c *C) {
 	c.Check(err, Not(IsNil))
 }
 
+func (s *StringFieldsSuite) TestSetParseValue(c *C) {
+	d, err := NewSetFieldDescriptor(true, 2)
+	c.Assert(err, IsNil)
+	c.Check(d.IsNullable(), IsTrue)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_SET)
+
+	val, remaining, err := d.ParseValue([]byte{0x05, 0x01, 'r', 'e', 's', 't'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+	c.Check(val, Equals, uint64(0x0105))
+
+	_, _, err = d.ParseValue([]byte{0x05})
+	c.Check(err, NotNil)
+
+	for _, numBytes := range []int{0, 9} {
+		_, err = NewSetFieldDescriptor(true, numBytes)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *StringFieldsSuite) TestExpandSetLabels(c *C) {
+	labels := []string{
+		"a", "b", "c", "d", "e", "f", "g", "h", "i", "j",
+	}
+
+	// Non-contiguous bits: 0, 2, 3 and 9.
+	active, err := ExpandSetLabels(0x020d, labels)
+	c.Assert(err, IsNil)
+	c.Check(active, DeepEquals, []string{"a", "c", "d", "j"})
+
+	active, err = ExpandSetLabels(0, labels)
+	c.Assert(err, IsNil)
+	c.Check(active, DeepEquals, []string{})
+
+	// Bits beyond the last label.
+	_, err = ExpandSetLabels(0x0400, labels)
+	c.Check(err, NotNil)
+
+	labels = make([]string, 64)
+	for i := range labels {
+		labels[i] = string(rune('0' + i))
+	}
+	active, err = ExpandSetLabels(uint64(1)<<63|2, labels)
+	c.Assert(err, IsNil)
+	c.Check(active, DeepEquals, []string{labels[1], labels[63]})
+}
+
+func (s *StringFieldsSuite) TestSetParseValueAndExpandLabels(c *C) {
+	d, err := NewSetFieldDescriptor(false, 1)
+	c.Assert(err, IsNil)
+
+	val, _, err := d.ParseValue([]byte{0x0a})
+	c.Assert(err, IsNil)
+
+	active, err := ExpandSetLabels(
+		val.(uint64),
+		[]string{"read", "write", "execute", "admin"})
+	c.Assert(err, IsNil)
+	c.Check(active, DeepEquals, []string{"write", "admin"})
+}
+
 func (s *StringFieldsSuite) TestBlobTooFewMetadataBytes(c *C) {
 	_, _, err := NewBlobFieldDescriptor(true, []byte{})
 	c.Check(err, Not(IsNil))
@@ -294,3 +468,63 @@ func (s *ParseTypeAndLengthSuite) TestInvalidEncodedType(c *C) {
 
 	c.Check(err, Not(IsNil))
 }
+
+func (s *StringFieldsSuite) TestLazyBlobParseValue(c *C) {
+	d, remaining, err := NewLazyBlobFieldDescriptor(true, []byte{1, 'a'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "a")
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_BLOB)
+
+	data := []byte{3, 'f', 'o', 'o', 'b', 'a', 'r'}
+	val, remaining, err := d.ParseValue(data)
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "bar")
+
+	blob, ok := val.(*LazyBlob)
+	c.Assert(ok, IsTrue)
+	c.Check(blob.Len(), Equals, 3)
+	c.Check(string(blob.RawBytes()), Equals, "foo")
+
+	copied := blob.Bytes()
+	c.Check(string(copied), Equals, "foo")
+
+	// The copy does not reference the event's data.
+	data[1] = 'g'
+	c.Check(string(blob.RawBytes()), Equals, "goo")
+	c.Check(string(copied), Equals, "foo")
+	c.Check(string(blob.Bytes()), Equals, "foo")
+}
+
+func (s *StringFieldsSuite) TestLazyBlobParseValueError(c *C) {
+	d, _, err := NewLazyBlobFieldDescriptor(true, []byte{1})
+	c.Assert(err, IsNil)
+
+	_, _, err = d.ParseValue([]byte{3, 'f', 'o'})
+	c.Check(err, NotNil)
+}
+
+func (s *StringFieldsSuite) TestTableMapLazyBlobs(c *C) {
+	table := &TableMapEvent{
+		columnTypesBytes: []byte{byte(mysql_proto.FieldType_BLOB)},
+		metadataBytes:    []byte{1},
+		nullColumnsBytes: []byte{0},
+	}
+
+	p := &TableMapEventParser{}
+	err := p.parseColumns(table)
+	c.Assert(err, IsNil)
+
+	val, _, err := table.ColumnDescriptors()[0].ParseValue([]byte{1, 'x'})
+	c.Assert(err, IsNil)
+	c.Check(val, DeepEquals, []byte("x"))
+
+	p = &TableMapEventParser{options: DecodeOptions{LazyBlobs: true}}
+	err = p.parseColumns(table)
+	c.Assert(err, IsNil)
+
+	val, _, err = table.ColumnDescriptors()[0].ParseValue([]byte{1, 'x'})
+	c.Assert(err, IsNil)
+	blob, ok := val.(*LazyBlob)
+	c.Assert(ok, IsTrue)
+	c.Check(string(blob.Bytes()), Equals, "x")
+}
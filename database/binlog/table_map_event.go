@@ -28,6 +28,8 @@ import (
 //          field metadata size
 //      w bytes for field metadata
 //      ceil(z / 8) bytes for nullable columns (1 bit per column)
+//  8.0 Specific:
+//      (optional) optional metadata fields (see TableMapOptionalMetadata)
 //  5.6 Specific:
 //      (optional) 4 bytes footer for checksum
 //  NOTE:
@@ -44,7 +46,13 @@ type TableMapEvent struct {
 	metadataBytes    []byte
 	nullColumnsBytes []byte
 
+	optionalMetadataBytes []byte
+
 	columnDescriptors []ColumnDescriptor
+
+	optionalMetadata *TableMapOptionalMetadata
+
+	generatedColumns []GeneratedColumnType
 }
 
 // TableId returns which table the following row event entries should act on.
@@ -94,12 +102,27 @@ func (e *TableMapEvent) ColumnDescriptors() []ColumnDescriptor {
 	return e.columnDescriptors
 }
 
+// OptionalMetadataBytes returns the optional metadata as uninterpreted bytes.
+func (e *TableMapEvent) OptionalMetadataBytes() []byte {
+	return e.optionalMetadataBytes
+}
+
+// OptionalMetadata returns the optional metadata parsed from
+// OptionalMetadataBytes.  This returns nil when the event does not have
+// optional metadata (i.e., the event is written by a pre-8.0 mysql, or
+// binlog_row_metadata is unset).
+func (e *TableMapEvent) OptionalMetadata() *TableMapOptionalMetadata {
+	return e.optionalMetadata
+}
+
 //
 // TableMapEventParser --------------------------------------------------------
 //
 
 type TableMapEventParser struct {
 	hasNoTableContext
+
+	options DecodeOptions
 }
 
 // TableMapEventParser's EventType always returns
@@ -181,16 +204,29 @@ func (p *TableMapEventParser) Parse(raw *RawV4Event) (Event, error) {
 		return raw, errors.Wrap(err, "Failed to read metadata")
 	}
 
-	table.nullColumnsBytes, _, err = readSlice(data, int((numColumns+7)/8))
+	table.nullColumnsBytes, data, err = readSlice(data, int((numColumns+7)/8))
 	if err != nil {
 		return raw, errors.Wrap(err, "Failed to read null bit vector")
 	}
 
+	table.optionalMetadataBytes = data
+
 	err = p.parseColumns(table)
 	if err != nil {
 		return raw, errors.Wrap(err, "Failed to parse column descriptions")
 	}
 
+	err = p.parseOptionalMetadata(table)
+	if err != nil {
+		return raw, errors.Wrap(err, "Failed to parse optional metadata")
+	}
+
+	p.markGeneratedColumns(table)
+
+	if p.options.DriverValues {
+		wrapDriverValueDescriptors(table)
+	}
+
 	return table, nil
 }
 
@@ -200,6 +236,26 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 
 	metadata := t.metadataBytes
 
+	options := p.options
+	lenientZeroDates := options.LenientDateTime
+	if options.Session != nil {
+		if loc := options.Session.Location(); loc != nil {
+			options.DateTimeLocation = loc
+		}
+		if options.Session.SqlMode() != nil {
+			options.ZeroDates = ZeroDateAsString
+			if options.Session.IsModeEnabled(
+				mysql_proto.SqlMode_NO_ZERO_DATE) {
+
+				// NO_ZERO_DATE only rejects new zero dates; existing
+				// rows may still have zero dates (e.g., in update
+				// before images), which must not fail the stream.
+				options.ZeroDates = ZeroDateInvalid
+				lenientZeroDates = true
+			}
+		}
+	}
+
 	nullVector, _, err := readBitArray(t.nullColumnsBytes, numCols)
 	if err != nil {
 		return err
@@ -209,18 +265,14 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 		colType := mysql_proto.FieldType_Type(colTypeByte)
 		realType := colType
 		metaLength := 0
-		if colType == mysql_proto.FieldType_STRING ||
-			colType == mysql_proto.FieldType_VAR_STRING {
-
+		if colType == mysql_proto.FieldType_VAR_STRING {
 			realType, metaLength, metadata, err = parseTypeAndLength(metadata)
 			if err != nil {
 				return err
 			}
 
 			// mysql_proto.FieldType_VAR_STRING is not type polymorphic.
-			if colType == mysql_proto.FieldType_VAR_STRING &&
-				colType != realType {
-
+			if colType != realType {
 				return errors.Newf("Invalid real type: %s (%d)",
 					realType.String(),
 					realType)
@@ -234,12 +286,15 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 		switch realType {
 		case mysql_proto.FieldType_DECIMAL:
 			fd = NewDecimalFieldDescriptor(nullable)
-		case mysql_proto.FieldType_TINY:
-			fd = NewTinyFieldDescriptor(nullable)
-		case mysql_proto.FieldType_SHORT:
-			fd = NewShortFieldDescriptor(nullable)
-		case mysql_proto.FieldType_LONG:
-			fd = NewLongFieldDescriptor(nullable)
+		case mysql_proto.FieldType_TINY,
+			mysql_proto.FieldType_SHORT,
+			mysql_proto.FieldType_INT24,
+			mysql_proto.FieldType_LONG,
+			mysql_proto.FieldType_LONGLONG:
+			fd, err = NewIntegerFieldDescriptorWithWidth(
+				realType,
+				nullable,
+				options.IntegerWidth)
 		case mysql_proto.FieldType_FLOAT:
 			fd, metadata, err = NewFloatFieldDescriptor(nullable, metadata)
 		case mysql_proto.FieldType_DOUBLE:
@@ -248,16 +303,14 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 			fd = NewNullFieldDescriptor(nullable)
 		case mysql_proto.FieldType_TIMESTAMP:
 			fd = NewTimestampFieldDescriptor(nullable)
-		case mysql_proto.FieldType_LONGLONG:
-			fd = NewLongLongFieldDescriptor(nullable)
-		case mysql_proto.FieldType_INT24:
-			fd = NewInt24FieldDescriptor(nullable)
 		case mysql_proto.FieldType_DATE:
 			return errors.New("TODO")
 		case mysql_proto.FieldType_TIME:
 			return errors.New("TODO")
 		case mysql_proto.FieldType_DATETIME:
-			fd = NewDateTimeFieldDescriptor(nullable)
+			fd = NewDateTimeFieldDescriptorInLocation(
+				nullable,
+				options.DateTimeLocation)
 		case mysql_proto.FieldType_YEAR:
 			fd = NewYearFieldDescriptor(nullable)
 		case mysql_proto.FieldType_NEWDATE:
@@ -269,14 +322,19 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 		case mysql_proto.FieldType_TIMESTAMP2:
 			fd, metadata, err = NewTimestamp2FieldDescriptor(nullable, metadata)
 		case mysql_proto.FieldType_DATETIME2:
-			fd, metadata, err = NewDateTime2FieldDescriptor(nullable, metadata)
+			fd, metadata, err = NewDateTime2FieldDescriptorWithOptions(
+				nullable,
+				metadata,
+				options)
 		case mysql_proto.FieldType_TIME2:
-			return errors.New("TODO")
+			fd, metadata, err = NewTime2FieldDescriptor(nullable, metadata)
 		case mysql_proto.FieldType_NEWDECIMAL:
 			fd, metadata, err = NewNewDecimalFieldDescriptor(nullable, metadata)
 		case mysql_proto.FieldType_ENUM:
+			// Enum columns are logged as strings with real type enum.
 			return errors.New("Enum type should not appear in binlog")
 		case mysql_proto.FieldType_SET:
+			// Set columns are logged as strings with real type set.
 			return errors.New("Set type should not appear in binlog")
 		case mysql_proto.FieldType_TINY_BLOB:
 			return errors.New("Tiny blog type should not appear in binlog")
@@ -285,11 +343,23 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 		case mysql_proto.FieldType_LONG_BLOB:
 			return errors.New("Long blog type should not appear in binlog")
 		case mysql_proto.FieldType_BLOB:
-			fd, metadata, err = NewBlobFieldDescriptor(nullable, metadata)
-		case mysql_proto.FieldType_VAR_STRING, mysql_proto.FieldType_STRING:
+			if options.LazyBlobs {
+				fd, metadata, err = NewLazyBlobFieldDescriptor(
+					nullable,
+					metadata)
+			} else {
+				fd, metadata, err = NewBlobFieldDescriptor(nullable, metadata)
+			}
+		case mysql_proto.FieldType_VAR_STRING:
 			fd = NewStringFieldDescriptor(realType, nullable, metaLength)
+		case mysql_proto.FieldType_STRING:
+			fd, metadata, err = NewPackedStringFieldDescriptor(
+				nullable,
+				metadata)
 		case mysql_proto.FieldType_GEOMETRY:
 			return errors.New("TODO")
+		case TypedArrayFieldType:
+			fd, metadata, err = NewTypedArrayFieldDescriptor(nullable, metadata)
 		default:
 			return errors.Newf("Unknown field type: %d", int(realType))
 		}
@@ -298,6 +368,8 @@ func (p *TableMapEventParser) parseColumns(t *TableMapEvent) error {
 			return err
 		}
 
+		fd = WithZeroDateMode(fd, options.ZeroDates, lenientZeroDates)
+
 		t.columnDescriptors[idx] = NewColumnDescriptor(fd, idx)
 	}
 
@@ -175,8 +175,14 @@ func (s *TableMapEventSuite) TestAllColumnTypes(c *C) {
 		{mysql_proto.FieldType_STRING,
 			mysql_proto.FieldType_VAR_STRING,
 			[]byte{byte(mysql_proto.FieldType_VAR_STRING), 123}},
-		// TODO mysql_proto.FieldType_ENUM
-		// TODO mysql_proto.FieldType_SET
+		// string -> enum
+		{mysql_proto.FieldType_STRING,
+			mysql_proto.FieldType_ENUM,
+			[]byte{byte(mysql_proto.FieldType_ENUM), 1}},
+		// string -> set
+		{mysql_proto.FieldType_STRING,
+			mysql_proto.FieldType_SET,
+			[]byte{byte(mysql_proto.FieldType_SET), 2}},
 	}
 
 	//
@@ -0,0 +1,593 @@
+package binlog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// Optional metadata field types, as defined by
+// Table_map_log_event::Optional_metadata_field_type (in
+// libbinlogevents/include/rows_event.h).  The optional metadata is logged by
+// mysql 8.0 (when binlog_row_metadata is MINIMAL or FULL).  Each field is
+// encoded as: 1 byte type, packed length, length bytes of value.
+const (
+	optionalMetadataSignedness               = 1
+	optionalMetadataDefaultCharset           = 2
+	optionalMetadataColumnCharset            = 3
+	optionalMetadataColumnName               = 4
+	optionalMetadataSetStrValue              = 5
+	optionalMetadataEnumStrValue             = 6
+	optionalMetadataGeometryType             = 7
+	optionalMetadataSimplePrimaryKey         = 8
+	optionalMetadataPrimaryKeyWithPrefix     = 9
+	optionalMetadataEnumAndSetDefaultCharset = 10
+	optionalMetadataEnumAndSetColumnCharset  = 11
+)
+
+// The binary charset / collation id.
+const binaryCharset = 63
+
+// TableMapOptionalMetadata is the table map event's decoded optional
+// metadata.  Fields which are not logged (e.g., column names are only logged
+// when binlog_row_metadata is FULL) are nil.
+type TableMapOptionalMetadata struct {
+	// The columns' names.
+	ColumnNames [][]byte
+
+	// Whether or not each column is unsigned.  Only meaningful for numeric
+	// columns.
+	UnsignedColumns []bool
+
+	// Each column's charset (collation) id.  Columns without a charset (i.e.,
+	// non-string columns) have id 0.
+	ColumnCharsets []uint64
+
+	// Each SET column's labels, in definition order (nil for non-set
+	// columns).  See ExpandSetLabels.
+	SetLabels [][]string
+
+	// Each ENUM column's labels, in definition order (nil for non-enum
+	// columns).  Enum value i (1-based) corresponds to EnumLabels[idx][i-1].
+	EnumLabels [][]string
+
+	// The primary key's column indices, in key order.
+	PrimaryKey []int
+
+	// The primary key columns' prefix lengths (0 means the full column is
+	// part of the key).  This has the same length as PrimaryKey.
+	PrimaryKeyPrefixLengths []uint64
+}
+
+func isNumericType(t mysql_proto.FieldType_Type) bool {
+	switch t {
+	case mysql_proto.FieldType_TINY,
+		mysql_proto.FieldType_SHORT,
+		mysql_proto.FieldType_INT24,
+		mysql_proto.FieldType_LONG,
+		mysql_proto.FieldType_LONGLONG,
+		mysql_proto.FieldType_FLOAT,
+		mysql_proto.FieldType_DOUBLE,
+		mysql_proto.FieldType_DECIMAL,
+		mysql_proto.FieldType_NEWDECIMAL:
+		return true
+	}
+	return false
+}
+
+func isCharacterType(t mysql_proto.FieldType_Type) bool {
+	switch t {
+	case mysql_proto.FieldType_STRING,
+		mysql_proto.FieldType_VAR_STRING,
+		mysql_proto.FieldType_VARCHAR,
+		mysql_proto.FieldType_BLOB:
+		return true
+	}
+	return false
+}
+
+func isEnumOrSetType(t mysql_proto.FieldType_Type) bool {
+	return t == mysql_proto.FieldType_ENUM || t == mysql_proto.FieldType_SET
+}
+
+// Returns the indices of the columns which satisfy the predicate.
+func (e *TableMapEvent) columnIndices(
+	pred func(mysql_proto.FieldType_Type) bool) []int {
+
+	indices := []int{}
+	for idx, col := range e.columnDescriptors {
+		if pred(col.Type()) {
+			indices = append(indices, idx)
+		}
+	}
+	return indices
+}
+
+func readPackedString(data []byte) (str []byte, remaining []byte, err error) {
+	length, remaining, err := readFieldLength(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length > uint64(len(remaining)) {
+		return nil, nil, errors.Newf("String too long: %d", length)
+	}
+	return readSlice(remaining, int(length))
+}
+
+func (p *TableMapEventParser) parseOptionalMetadata(t *TableMapEvent) error {
+	data := t.optionalMetadataBytes
+	if len(data) == 0 {
+		return nil
+	}
+
+	numCols := len(t.columnDescriptors)
+	m := &TableMapOptionalMetadata{}
+
+	for len(data) > 0 {
+		fieldType := data[0]
+
+		length, remaining, err := readFieldLength(data[1:])
+		if err != nil {
+			return err
+		}
+		if length > uint64(len(remaining)) {
+			return errors.Newf(
+				"Optional metadata field (%d) too long: %d",
+				fieldType,
+				length)
+		}
+
+		var value []byte
+		value, data, err = readSlice(remaining, int(length))
+		if err != nil {
+			return err
+		}
+
+		switch fieldType {
+		case optionalMetadataSignedness:
+			numeric := t.columnIndices(isNumericType)
+			if len(value)*8 < len(numeric) {
+				return errors.New("Not enough signedness bits")
+			}
+
+			m.UnsignedColumns = make([]bool, numCols)
+			for i, idx := range numeric {
+				// NOTE: bits are ordered from the most significant bit.
+				m.UnsignedColumns[idx] = value[i/8]&(0x80>>uint(i%8)) != 0
+			}
+		case optionalMetadataDefaultCharset,
+			optionalMetadataEnumAndSetDefaultCharset:
+
+			columns := t.columnIndices(isCharacterType)
+			if fieldType == optionalMetadataEnumAndSetDefaultCharset {
+				columns = t.columnIndices(isEnumOrSetType)
+			}
+
+			err = m.parseDefaultCharset(numCols, columns, value)
+		case optionalMetadataColumnCharset,
+			optionalMetadataEnumAndSetColumnCharset:
+
+			columns := t.columnIndices(isCharacterType)
+			if fieldType == optionalMetadataEnumAndSetColumnCharset {
+				columns = t.columnIndices(isEnumOrSetType)
+			}
+
+			err = m.parseColumnCharset(numCols, columns, value)
+		case optionalMetadataColumnName:
+			m.ColumnNames = make([][]byte, 0, numCols)
+			for len(value) > 0 {
+				var name []byte
+				name, value, err = readPackedString(value)
+				if err != nil {
+					return err
+				}
+				m.ColumnNames = append(m.ColumnNames, name)
+			}
+
+			if len(m.ColumnNames) != numCols {
+				return errors.Newf(
+					"Number of column names (%d) does not match # of "+
+						"columns (%d)",
+					len(m.ColumnNames),
+					numCols)
+			}
+		case optionalMetadataSetStrValue:
+			m.SetLabels, err = parseLabels(
+				"set",
+				64,
+				numCols,
+				t.columnIndices(func(t mysql_proto.FieldType_Type) bool {
+					return t == mysql_proto.FieldType_SET
+				}),
+				value)
+		case optionalMetadataEnumStrValue:
+			m.EnumLabels, err = parseLabels(
+				"enum",
+				65535,
+				numCols,
+				t.columnIndices(func(t mysql_proto.FieldType_Type) bool {
+					return t == mysql_proto.FieldType_ENUM
+				}),
+				value)
+		case optionalMetadataSimplePrimaryKey,
+			optionalMetadataPrimaryKeyWithPrefix:
+
+			withPrefix := fieldType == optionalMetadataPrimaryKeyWithPrefix
+			err = m.parsePrimaryKey(numCols, withPrefix, value)
+		default:
+			// Geometry columns are not supported.  Newer field types
+			// (e.g., column visibility) are ignored.
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	t.optionalMetadata = m
+	return nil
+}
+
+func (m *TableMapOptionalMetadata) setCharset(
+	numCols int,
+	idx int,
+	charset uint64) {
+
+	if m.ColumnCharsets == nil {
+		m.ColumnCharsets = make([]uint64, numCols)
+	}
+	m.ColumnCharsets[idx] = charset
+}
+
+func (m *TableMapOptionalMetadata) parseDefaultCharset(
+	numCols int,
+	columns []int,
+	value []byte) error {
+
+	defaultCharset, value, err := readFieldLength(value)
+	if err != nil {
+		return err
+	}
+
+	charsets := make([]uint64, len(columns))
+	for i := range charsets {
+		charsets[i] = defaultCharset
+	}
+
+	// Followed by (column index, charset) pairs for columns which do not use
+	// the default charset.  NOTE: the index is relative to columns.
+	for len(value) > 0 {
+		var i, charset uint64
+		i, value, err = readFieldLength(value)
+		if err != nil {
+			return err
+		}
+		charset, value, err = readFieldLength(value)
+		if err != nil {
+			return err
+		}
+
+		if i >= uint64(len(columns)) {
+			return errors.Newf("Invalid charset column index: %d", i)
+		}
+		charsets[i] = charset
+	}
+
+	for i, idx := range columns {
+		m.setCharset(numCols, idx, charsets[i])
+	}
+	return nil
+}
+
+func (m *TableMapOptionalMetadata) parseColumnCharset(
+	numCols int,
+	columns []int,
+	value []byte) error {
+
+	for _, idx := range columns {
+		var charset uint64
+		var err error
+		charset, value, err = readFieldLength(value)
+		if err != nil {
+			return err
+		}
+		m.setCharset(numCols, idx, charset)
+	}
+
+	if len(value) != 0 {
+		return errors.New("Too many column charsets")
+	}
+	return nil
+}
+
+// Parses set / enum labels.  kind is only used in error messages.
+func parseLabels(
+	kind string,
+	maxLabels uint64,
+	numCols int,
+	columns []int,
+	value []byte) ([][]string, error) {
+
+	result := make([][]string, numCols)
+	for _, idx := range columns {
+		numLabels, remaining, err := readFieldLength(value)
+		if err != nil {
+			return nil, err
+		}
+		value = remaining
+
+		if numLabels > maxLabels {
+			return nil, errors.Newf("Too many %s labels: %d", kind, numLabels)
+		}
+
+		labels := make([]string, numLabels)
+		for i := range labels {
+			var label []byte
+			label, value, err = readPackedString(value)
+			if err != nil {
+				return nil, err
+			}
+			labels[i] = string(label)
+		}
+		result[idx] = labels
+	}
+
+	if len(value) != 0 {
+		return nil, errors.Newf("Too many %s labels", kind)
+	}
+	return result, nil
+}
+
+func (m *TableMapOptionalMetadata) parsePrimaryKey(
+	numCols int,
+	withPrefix bool,
+	value []byte) error {
+
+	m.PrimaryKey = []int{}
+	m.PrimaryKeyPrefixLengths = []uint64{}
+
+	for len(value) > 0 {
+		idx, remaining, err := readFieldLength(value)
+		if err != nil {
+			return err
+		}
+		value = remaining
+
+		if idx >= uint64(numCols) {
+			return errors.Newf("Invalid primary key column index: %d", idx)
+		}
+
+		prefix := uint64(0)
+		if withPrefix {
+			prefix, value, err = readFieldLength(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		m.PrimaryKey = append(m.PrimaryKey, int(idx))
+		m.PrimaryKeyPrefixLengths = append(m.PrimaryKeyPrefixLengths, prefix)
+	}
+
+	return nil
+}
+
+//
+// Schema DDL -----------------------------------------------------------------
+//
+
+// Names of commonly used collations, keyed by id.
+var collationNames = map[uint64]string{
+	8:   "latin1_swedish_ci",
+	33:  "utf8_general_ci",
+	45:  "utf8mb4_general_ci",
+	46:  "utf8mb4_bin",
+	47:  "latin1_bin",
+	63:  "binary",
+	83:  "utf8_bin",
+	224: "utf8mb4_unicode_ci",
+	255: "utf8mb4_0900_ai_ci",
+}
+
+func quoteIdentifier(name []byte) string {
+	return "`" + strings.Replace(string(name), "`", "``", -1) + "`"
+}
+
+func quoteLabel(label string) string {
+	return "'" + strings.Replace(label, "'", "''", -1) + "'"
+}
+
+// This strips the column descriptor and the decode options' wrappers (see
+// WithZeroDateMode and DecodeOptions.DriverValues) off the field descriptor.
+func unwrapFieldDescriptor(fd FieldDescriptor) FieldDescriptor {
+	for {
+		switch d := fd.(type) {
+		case *columnDescriptorImpl:
+			fd = d.FieldDescriptor
+		case *zeroDateFieldDescriptor:
+			fd = d.FieldDescriptor
+		case *driverValueFieldDescriptor:
+			fd = d.FieldDescriptor
+		default:
+			return fd
+		}
+	}
+}
+
+// Returns the column's sql type, without attributes.  labels are the set /
+// enum column's labels (nil when not logged).
+func columnSqlType(
+	col ColumnDescriptor,
+	charset uint64,
+	labels []string) string {
+
+	isBinary := charset == binaryCharset
+
+	switch d := unwrapFieldDescriptor(col).(type) {
+	case *newDecimalFieldDescriptor:
+		return fmt.Sprintf("DECIMAL(%d,%d)", d.precision, d.decimals)
+	case *bitFieldDescriptor:
+		return fmt.Sprintf("BIT(%d)", d.numBits)
+	case *stringFieldDescriptor:
+		switch {
+		case d.fieldType == mysql_proto.FieldType_STRING && isBinary:
+			return fmt.Sprintf("BINARY(%d)", d.maxLength)
+		case d.fieldType == mysql_proto.FieldType_STRING:
+			return fmt.Sprintf("CHAR(%d)", d.maxLength)
+		case isBinary:
+			return fmt.Sprintf("VARBINARY(%d)", d.maxLength)
+		default:
+			// NOTE: The metadata only stores the max length in bytes.
+			return fmt.Sprintf("VARCHAR(%d)", d.maxLength)
+		}
+	case *typedArrayFieldDescriptor:
+		// Multi-valued index values are stored as json arrays.
+		return "JSON"
+	case *blobFieldDescriptor:
+		prefix := []string{"", "TINY", "", "MEDIUM", "LONG"}[d.packedLength]
+		if charset != 0 && !isBinary {
+			return prefix + "TEXT"
+		}
+		return prefix + "BLOB"
+	case *timestamp2FieldDescriptor:
+		return temporalSqlType("TIMESTAMP", d.microSecondPrecision)
+	case *datetime2FieldDescriptor:
+		return temporalSqlType("DATETIME", d.microSecondPrecision)
+	case *time2FieldDescriptor:
+		return temporalSqlType("TIME", d.microSecondPrecision)
+	}
+
+	switch col.Type() {
+	case mysql_proto.FieldType_TINY:
+		return "TINYINT"
+	case mysql_proto.FieldType_SHORT:
+		return "SMALLINT"
+	case mysql_proto.FieldType_INT24:
+		return "MEDIUMINT"
+	case mysql_proto.FieldType_LONG:
+		return "INT"
+	case mysql_proto.FieldType_LONGLONG:
+		return "BIGINT"
+	case mysql_proto.FieldType_SET, mysql_proto.FieldType_ENUM:
+		if len(labels) == 0 {
+			// SET() / ENUM() are not valid types.  Without the labels, the
+			// (numeric) bitmask / index values are all we know about.
+			return "TEXT"
+		}
+
+		quoted := make([]string, len(labels))
+		for i, label := range labels {
+			quoted[i] = quoteLabel(label)
+		}
+
+		name := "SET"
+		if col.Type() == mysql_proto.FieldType_ENUM {
+			name = "ENUM"
+		}
+		return name + "(" + strings.Join(quoted, ",") + ")"
+	case mysql_proto.FieldType_NULL:
+		return "NULL"
+	}
+
+	// FLOAT, DOUBLE, DECIMAL, TIMESTAMP, DATETIME, YEAR, etc.
+	return col.Type().String()
+}
+
+func temporalSqlType(name string, precision uint8) string {
+	if precision == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", name, precision)
+}
+
+// SchemaDDL renders an approximate CREATE TABLE statement for the table,
+// based on the column descriptors and the optional metadata.  The statement
+// is useful for sinks which need a schema, but it is not a faithful copy of
+// the source table's definition.  In particular:
+//   - columns are named col_<index> when the column names are not logged
+//   - column defaults, comments, and secondary indexes are not available
+//   - string lengths are in bytes (the binlog does not store the character
+//     length)
+//   - the unsigned attribute, charsets, set / enum labels and primary key
+//     are only available when logged in the optional metadata
+//   - set / enum columns without logged labels are rendered as TEXT
+func (e *TableMapEvent) SchemaDDL() string {
+	m := e.optionalMetadata
+	if m == nil {
+		m = &TableMapOptionalMetadata{}
+	}
+
+	columnName := func(idx int) string {
+		if m.ColumnNames != nil {
+			return quoteIdentifier(m.ColumnNames[idx])
+		}
+		return fmt.Sprintf("`col_%d`", idx)
+	}
+
+	buf := &bytes.Buffer{}
+	_, _ = fmt.Fprintf(
+		buf,
+		"CREATE TABLE %s.%s (",
+		quoteIdentifier(e.databaseName),
+		quoteIdentifier(e.tableName))
+
+	for idx, col := range e.columnDescriptors {
+		if idx > 0 {
+			_ = buf.WriteByte(',')
+		}
+
+		charset := uint64(0)
+		if m.ColumnCharsets != nil {
+			charset = m.ColumnCharsets[idx]
+		}
+
+		var labels []string
+		if m.SetLabels != nil && m.SetLabels[idx] != nil {
+			labels = m.SetLabels[idx]
+		} else if m.EnumLabels != nil {
+			labels = m.EnumLabels[idx]
+		}
+
+		_, _ = buf.WriteString("\n  ")
+		_, _ = buf.WriteString(columnName(idx))
+		_ = buf.WriteByte(' ')
+		_, _ = buf.WriteString(columnSqlType(col, charset, labels))
+
+		if m.UnsignedColumns != nil && m.UnsignedColumns[idx] {
+			_, _ = buf.WriteString(" UNSIGNED")
+		}
+
+		if charset != 0 && charset != binaryCharset {
+			if name, ok := collationNames[charset]; ok {
+				_, _ = buf.WriteString(" COLLATE ")
+				_, _ = buf.WriteString(name)
+			}
+		}
+
+		if col.IsNullable() {
+			_, _ = buf.WriteString(" NULL")
+		} else {
+			_, _ = buf.WriteString(" NOT NULL")
+		}
+	}
+
+	if len(m.PrimaryKey) > 0 {
+		_, _ = buf.WriteString(",\n  PRIMARY KEY (")
+		for i, idx := range m.PrimaryKey {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+			_, _ = buf.WriteString(columnName(idx))
+			if m.PrimaryKeyPrefixLengths[i] > 0 {
+				_, _ = fmt.Fprintf(buf, "(%d)", m.PrimaryKeyPrefixLengths[i])
+			}
+		}
+		_ = buf.WriteByte(')')
+	}
+
+	_, _ = buf.WriteString("\n)")
+	return buf.String()
+}
@@ -0,0 +1,321 @@
+package binlog
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type TableMapMetadataSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&TableMapMetadataSuite{})
+
+// Column types / metadata / null bits of the test table:
+//
+//	id BIGINT UNSIGNED NOT NULL
+//	name VARCHAR(400) NULL (utf8mb4)
+//	data BLOB NULL
+//	score DECIMAL(10,2) NOT NULL
+//	flags SET('a','b') NULL
+//	created DATETIME(3) NOT NULL
+func (s *TableMapMetadataSuite) writeTableMap(optionalMetadata []byte) {
+	data := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		1, 0,
+		// db name length
+		2,
+		// db name
+		'd', 'b', 0,
+		// table name length
+		5,
+		// table name
+		'u', 's', 'e', 'r', 's', 0,
+		// number of columns
+		6,
+		// column types
+		8, 15, 252, 246, 254, 18,
+		// metadata size
+		8,
+		// metadata
+		0x90, 0x01, // varchar (400 bytes)
+		2,     // blob
+		10, 2, // decimal
+		248, 1, // set
+		3, // datetime2
+		// null bits
+		0x16,
+	}
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		uint16(0),
+		append(data, optionalMetadata...))
+}
+
+func (s *TableMapMetadataSuite) nextTableMap(c *C) *TableMapEvent {
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	tm, ok := event.(*TableMapEvent)
+	c.Assert(ok, Equals, true)
+	return tm
+}
+
+func optionalMetadataField(fieldType byte, value ...byte) []byte {
+	return append([]byte{fieldType, byte(len(value))}, value...)
+}
+
+func fullOptionalMetadata() []byte {
+	buf := &bytes.Buffer{}
+
+	// id is unsigned, score is signed.
+	buf.Write(optionalMetadataField(optionalMetadataSignedness, 0x80))
+
+	// utf8mb4_0900_ai_ci by default; data is binary.
+	buf.Write(optionalMetadataField(
+		optionalMetadataDefaultCharset,
+		0xfc, 0xff, 0x00,
+		1, binaryCharset))
+
+	buf.Write(optionalMetadataField(
+		optionalMetadataEnumAndSetDefaultCharset,
+		45))
+
+	names := []byte{}
+	for _, name := range []string{"id", "name", "data", "score", "flags", "created"} {
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+	}
+	buf.Write(optionalMetadataField(optionalMetadataColumnName, names...))
+
+	buf.Write(optionalMetadataField(
+		optionalMetadataSetStrValue,
+		2, 1, 'a', 1, 'b'))
+
+	buf.Write(optionalMetadataField(optionalMetadataSimplePrimaryKey, 0))
+
+	// Column visibility (mysql 8.0.23+), which is ignored.
+	buf.Write(optionalMetadataField(12, 0xfc))
+
+	return buf.Bytes()
+}
+
+func (s *TableMapMetadataSuite) TestNoOptionalMetadata(c *C) {
+	s.writeTableMap(nil)
+
+	tm := s.nextTableMap(c)
+	c.Check(tm.OptionalMetadata(), IsNil)
+	c.Check(len(tm.OptionalMetadataBytes()), Equals, 0)
+
+	c.Check(
+		tm.SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`users` (\n"+
+			"  `col_0` BIGINT NOT NULL,\n"+
+			"  `col_1` VARCHAR(400) NULL,\n"+
+			"  `col_2` BLOB NULL,\n"+
+			"  `col_3` DECIMAL(10,2) NOT NULL,\n"+
+			"  `col_4` TEXT NULL,\n"+
+			"  `col_5` DATETIME(3) NOT NULL\n"+
+			")")
+}
+
+func (s *TableMapMetadataSuite) TestFullOptionalMetadata(c *C) {
+	s.writeTableMap(fullOptionalMetadata())
+
+	tm := s.nextTableMap(c)
+
+	m := tm.OptionalMetadata()
+	c.Assert(m, NotNil)
+	c.Check(
+		m.ColumnNames,
+		DeepEquals,
+		[][]byte{
+			[]byte("id"),
+			[]byte("name"),
+			[]byte("data"),
+			[]byte("score"),
+			[]byte("flags"),
+			[]byte("created"),
+		})
+	c.Check(
+		m.UnsignedColumns,
+		DeepEquals,
+		[]bool{true, false, false, false, false, false})
+	c.Check(m.ColumnCharsets, DeepEquals, []uint64{0, 255, 63, 0, 45, 0})
+	c.Check(
+		m.SetLabels,
+		DeepEquals,
+		[][]string{nil, nil, nil, nil, {"a", "b"}, nil})
+	c.Check(m.PrimaryKey, DeepEquals, []int{0})
+	c.Check(m.PrimaryKeyPrefixLengths, DeepEquals, []uint64{0})
+
+	c.Check(
+		tm.SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`users` (\n"+
+			"  `id` BIGINT UNSIGNED NOT NULL,\n"+
+			"  `name` VARCHAR(400) COLLATE utf8mb4_0900_ai_ci NULL,\n"+
+			"  `data` BLOB NULL,\n"+
+			"  `score` DECIMAL(10,2) NOT NULL,\n"+
+			"  `flags` SET('a','b') COLLATE utf8mb4_general_ci NULL,\n"+
+			"  `created` DATETIME(3) NOT NULL,\n"+
+			"  PRIMARY KEY (`id`)\n"+
+			")")
+}
+
+func (s *TableMapMetadataSuite) TestSchemaDDLWithDecodeOptions(c *C) {
+	// The decode options wrap the temporal columns' descriptors.
+	s.parsers = NewV4EventParserMapWithOptions(DecodeOptions{
+		ZeroDates:    ZeroDateAsString,
+		DriverValues: true,
+	})
+	s.reader = NewParsedV4EventReader(s.rawReader, s.parsers)
+
+	s.writeTableMap(fullOptionalMetadata())
+
+	c.Check(
+		s.nextTableMap(c).SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`users` (\n"+
+			"  `id` BIGINT UNSIGNED NOT NULL,\n"+
+			"  `name` VARCHAR(400) COLLATE utf8mb4_0900_ai_ci NULL,\n"+
+			"  `data` BLOB NULL,\n"+
+			"  `score` DECIMAL(10,2) NOT NULL,\n"+
+			"  `flags` SET('a','b') COLLATE utf8mb4_general_ci NULL,\n"+
+			"  `created` DATETIME(3) NOT NULL,\n"+
+			"  PRIMARY KEY (`id`)\n"+
+			")")
+}
+
+func (s *TableMapMetadataSuite) TestColumnCharsetAndPrefixPrimaryKey(c *C) {
+	buf := &bytes.Buffer{}
+	buf.Write(optionalMetadataField(optionalMetadataColumnCharset, 33, 8))
+	buf.Write(optionalMetadataField(
+		optionalMetadataPrimaryKeyWithPrefix,
+		1, 10,
+		0, 0))
+
+	s.writeTableMap(buf.Bytes())
+
+	tm := s.nextTableMap(c)
+
+	m := tm.OptionalMetadata()
+	c.Assert(m, NotNil)
+	c.Check(m.ColumnNames, IsNil)
+	c.Check(m.UnsignedColumns, IsNil)
+	c.Check(m.ColumnCharsets, DeepEquals, []uint64{0, 33, 8, 0, 0, 0})
+	c.Check(m.PrimaryKey, DeepEquals, []int{1, 0})
+	c.Check(m.PrimaryKeyPrefixLengths, DeepEquals, []uint64{10, 0})
+
+	c.Check(
+		tm.SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`users` (\n"+
+			"  `col_0` BIGINT NOT NULL,\n"+
+			"  `col_1` VARCHAR(400) COLLATE utf8_general_ci NULL,\n"+
+			"  `col_2` TEXT COLLATE latin1_swedish_ci NULL,\n"+
+			"  `col_3` DECIMAL(10,2) NOT NULL,\n"+
+			"  `col_4` TEXT NULL,\n"+
+			"  `col_5` DATETIME(3) NOT NULL,\n"+
+			"  PRIMARY KEY (`col_1`(10),`col_0`)\n"+
+			")")
+}
+
+// Column types / metadata of the enum test table:
+//
+//	state ENUM('on','off') NULL
+//	flags SET('a','b') NULL
+func (s *TableMapMetadataSuite) writeEnumTableMap(optionalMetadata []byte) {
+	data := []byte{
+		// table id
+		1, 0, 0, 0, 0, 0,
+		// flags
+		1, 0,
+		// db name length
+		2,
+		// db name
+		'd', 'b', 0,
+		// table name length
+		1,
+		// table name
+		't', 0,
+		// number of columns
+		2,
+		// column types
+		254, 254,
+		// metadata size
+		4,
+		// metadata
+		247, 1, // enum
+		248, 1, // set
+		// null bits
+		0x03,
+	}
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		uint16(0),
+		append(data, optionalMetadata...))
+}
+
+func (s *TableMapMetadataSuite) TestEnumLabels(c *C) {
+	s.writeEnumTableMap(nil)
+
+	tm := s.nextTableMap(c)
+	c.Check(
+		tm.SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`t` (\n"+
+			"  `col_0` TEXT NULL,\n"+
+			"  `col_1` TEXT NULL\n"+
+			")")
+
+	buf := &bytes.Buffer{}
+	buf.Write(optionalMetadataField(
+		optionalMetadataEnumStrValue,
+		2, 2, 'o', 'n', 3, 'o', 'f', 'f'))
+	buf.Write(optionalMetadataField(
+		optionalMetadataSetStrValue,
+		2, 1, 'a', 1, 'b'))
+
+	s.writeEnumTableMap(buf.Bytes())
+
+	tm = s.nextTableMap(c)
+
+	m := tm.OptionalMetadata()
+	c.Assert(m, NotNil)
+	c.Check(m.EnumLabels, DeepEquals, [][]string{{"on", "off"}, nil})
+	c.Check(m.SetLabels, DeepEquals, [][]string{nil, {"a", "b"}})
+
+	c.Check(
+		tm.SchemaDDL(),
+		Equals,
+		"CREATE TABLE `db`.`t` (\n"+
+			"  `col_0` ENUM('on','off') NULL,\n"+
+			"  `col_1` SET('a','b') NULL\n"+
+			")")
+}
+
+func (s *TableMapMetadataSuite) TestInvalidOptionalMetadata(c *C) {
+	for _, metadata := range [][]byte{
+		// Truncated field.
+		{optionalMetadataSignedness, 2, 0x80},
+		// Wrong number of column names.
+		optionalMetadataField(optionalMetadataColumnName, 2, 'i', 'd'),
+		// Invalid primary key index.
+		optionalMetadataField(optionalMetadataSimplePrimaryKey, 6),
+		// Too many column charsets.
+		optionalMetadataField(optionalMetadataColumnCharset, 33, 33, 33),
+	} {
+		s.writeTableMap(metadata)
+		_, err := s.NextEvent()
+		c.Check(err, NotNil)
+	}
+}
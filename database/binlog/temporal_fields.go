@@ -1,6 +1,7 @@
 package binlog
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
@@ -57,6 +58,20 @@ func NewTimestampFieldDescriptor(nullable NullableColumn) FieldDescriptor {
 // (i.e., Field_datetime).  See number_to_datetime (in sql-common/my_time.c)
 // for encoding detail.
 func NewDateTimeFieldDescriptor(nullable NullableColumn) FieldDescriptor {
+	return NewDateTimeFieldDescriptorInLocation(nullable, nil)
+}
+
+// Same as NewDateTimeFieldDescriptor, but the wall-clock time is interpreted
+// in the source location and converted to UTC.  When loc is nil, the
+// wall-clock time is returned as-is in UTC.
+func NewDateTimeFieldDescriptorInLocation(
+	nullable NullableColumn,
+	loc *time.Location) FieldDescriptor {
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
 	return newFixedLengthFieldDescriptor(
 		mysql_proto.FieldType_DATETIME,
 		nullable,
@@ -73,7 +88,7 @@ func NewDateTimeFieldDescriptor(nullable NullableColumn) FieldDescriptor {
 				int((t%10000)/100),        // minute
 				int(t%100),                // second
 				0,                         // nanosecond
-				time.UTC)
+				loc).UTC()
 		})
 }
 
@@ -192,8 +207,14 @@ func (d *timestamp2FieldDescriptor) ParseValue(data []byte) (
 // equivalent to DATETIMEF_INT_OFS
 const datetimefIntOffset = 0x8000000000
 
+// The largest year representable by DATETIME2.
+const maxDateTime2Year = 9999
+
 type datetime2FieldDescriptor struct {
 	usecTemporalFieldDescriptor
+
+	location *time.Location
+	lenient  bool
 }
 
 // This returns a field descriptor for FieldType_DATETIME2
@@ -204,7 +225,46 @@ func NewDateTime2FieldDescriptor(nullable NullableColumn, metadata []byte) (
 	remaining []byte,
 	err error) {
 
-	d := &datetime2FieldDescriptor{}
+	return NewDateTime2FieldDescriptorInLocation(nullable, metadata, nil)
+}
+
+// Same as NewDateTime2FieldDescriptor, but the wall-clock time is interpreted
+// in the source location and converted to UTC.  When loc is nil, the
+// wall-clock time is returned as-is in UTC.
+func NewDateTime2FieldDescriptorInLocation(
+	nullable NullableColumn,
+	metadata []byte,
+	loc *time.Location) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	return NewDateTime2FieldDescriptorWithOptions(
+		nullable,
+		metadata,
+		DecodeOptions{DateTimeLocation: loc})
+}
+
+// Same as NewDateTime2FieldDescriptor, but the wall-clock time is interpreted
+// in options.DateTimeLocation, and values with out of range components are
+// decoded as nil when options.LenientDateTime is set.
+func NewDateTime2FieldDescriptorWithOptions(
+	nullable NullableColumn,
+	metadata []byte,
+	options DecodeOptions) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	loc := options.DateTimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	d := &datetime2FieldDescriptor{
+		location: loc,
+		lenient:  options.LenientDateTime,
+	}
 
 	remaining, err = d.init(
 		mysql_proto.FieldType_DATETIME2,
@@ -224,9 +284,21 @@ func (d *datetime2FieldDescriptor) ParseValue(data []byte) (
 	remaining []byte,
 	err error) {
 
+	value, remaining, _, err = d.parseValueWithLoss(data)
+	return value, remaining, err
+}
+
+// Invalid values are decoded as nil in lenient mode, which is reported as a
+// lossy conversion.
+func (d *datetime2FieldDescriptor) parseValueWithLoss(data []byte) (
+	value interface{},
+	remaining []byte,
+	loss string,
+	err error) {
+
 	dtBytes, msec, remaining, err := d.readData(data)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	ymdhms := BigEndian.Uint40(dtBytes) - datetimefIntOffset
@@ -243,6 +315,28 @@ func (d *datetime2FieldDescriptor) ParseValue(data []byte) (
 	minute := (hms >> 6) % (1 << 6)
 	hour := hms >> 12
 
+	// NOTE: Corrupted data may yield components which time.Date silently
+	// normalizes (or overflows).  Zero month / day are allowed since mysql
+	// permits zero dates.
+	if year > maxDateTime2Year || day > 31 || hour > 23 || minute > 59 ||
+		second > 59 {
+
+		msg := fmt.Sprintf(
+			"Invalid datetime2 value: %04d-%02d-%02d %02d:%02d:%02d",
+			year,
+			month,
+			day,
+			hour,
+			minute,
+			second)
+
+		if d.lenient {
+			return nil, remaining, msg + " (decoded as NULL)", nil
+		}
+
+		return nil, nil, "", errors.New(msg)
+	}
+
 	return time.Date(
 		int(year),
 		time.Month(month),
@@ -251,5 +345,282 @@ func (d *datetime2FieldDescriptor) ParseValue(data []byte) (
 		int(minute),
 		int(second),
 		int(msec)*1000, // nanosecond
-		time.UTC), remaining, nil
+		d.location).UTC(), remaining, "", nil
+}
+
+// equivalent to TIMEF_INT_OFS
+const timefIntOffset = 0x800000
+
+// equivalent to TIMEF_OFS
+const timefOffset = 0x800000000000
+
+// TimeValue is a decoded TIME2 value.  Unlike time.Time, TIME values are
+// signed durations (in the range of -838:59:59 to 838:59:59) rather than
+// points in time.
+type TimeValue struct {
+	negative    bool
+	hour        uint32
+	minute      uint8
+	second      uint8
+	microsecond uint32
+
+	// Number of fractional second digits.
+	precision uint8
+}
+
+// IsNegative returns true if the value is negative.
+func (t TimeValue) IsNegative() bool {
+	return t.negative
+}
+
+// Precision returns the number of fractional second digits.
+func (t TimeValue) Precision() uint8 {
+	return t.precision
+}
+
+// Duration returns the value as a signed time.Duration.
+func (t TimeValue) Duration() time.Duration {
+	d := time.Duration(t.hour)*time.Hour +
+		time.Duration(t.minute)*time.Minute +
+		time.Duration(t.second)*time.Second +
+		time.Duration(t.microsecond)*time.Microsecond
+
+	if t.negative {
+		return -d
+	}
+	return d
+}
+
+// String returns the value in mysql's canonical format, e.g.,
+// "-12:34:56.789000".  The number of fractional second digits matches the
+// column's precision (the fractional part is omitted when the precision is
+// zero).
+func (t TimeValue) String() string {
+	sign := ""
+	if t.negative {
+		sign = "-"
+	}
+
+	result := fmt.Sprintf("%s%02d:%02d:%02d", sign, t.hour, t.minute, t.second)
+	if t.precision > 0 {
+		usec := fmt.Sprintf("%06d", t.microsecond)
+		result += "." + usec[:t.precision]
+	}
+
+	return result
+}
+
+type time2FieldDescriptor struct {
+	usecTemporalFieldDescriptor
+}
+
+// This returns a field descriptor for FieldType_TIME2 (i.e., Field_timef).
+// The parsed values are TimeValues.  See my_time_packed_from_binary and
+// TIME_from_longlong_time_packed (in sql-common/my_time.c) for encoding
+// detail.
+func NewTime2FieldDescriptor(nullable NullableColumn, metadata []byte) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	t := &time2FieldDescriptor{}
+	remaining, err = t.init(
+		mysql_proto.FieldType_TIME2,
+		nullable,
+		3,
+		metadata)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t, remaining, nil
+}
+
+func (d *time2FieldDescriptor) ParseValue(data []byte) (
+	value interface{},
+	remaining []byte,
+	err error) {
+
+	raw, remaining, err := readSlice(data, d.neededBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	intPart := int64(BigEndian.Uint24(raw)) - timefIntOffset
+
+	// The packed representation is (int part << 24) + microseconds.  For
+	// negative values with a non-zero fractional part, the stored int part is
+	// rounded towards negative infinity, and the fractional part is stored in
+	// reverse order (i.e., as a positive offset from the int part) for binary
+	// sort compatibility.
+	var packed int64
+	switch d.microSecondPrecision {
+	case 0:
+		packed = intPart << 24
+	case 1, 2:
+		frac := int64(raw[3])
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x100
+		}
+		packed = intPart<<24 + frac*10000
+	case 3, 4:
+		frac := int64(BigEndian.Uint16(raw[3:]))
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		packed = intPart<<24 + frac*100
+	case 5, 6:
+		packed = int64(BigEndian.Uint48(raw)) - timefOffset
+	}
+
+	t := TimeValue{precision: d.microSecondPrecision}
+	if packed < 0 {
+		t.negative = true
+		packed = -packed
+	}
+
+	hms := packed >> 24
+	t.hour = uint32((hms >> 12) % (1 << 10))
+	t.minute = uint8((hms >> 6) % (1 << 6))
+	t.second = uint8(hms % (1 << 6))
+	t.microsecond = uint32(packed % (1 << 24))
+
+	return t, remaining, nil
+}
+
+// ZeroDateMode specifies how zero dates (i.e., temporal values with zero
+// year, month and day, such as '0000-00-00 00:00:00') are decoded.  mysql
+// permits zero dates unless the NO_ZERO_DATE sql mode is enabled.
+type ZeroDateMode int
+
+const (
+	// Zero dates are decoded as time.Time values, as is (i.e., time.Date
+	// normalizes the zero components; zero TIMESTAMP / TIMESTAMP2 values are
+	// decoded as the unix epoch).
+	ZeroDateAsTime ZeroDateMode = iota
+
+	// Zero dates are decoded as the "0000-00-00 00:00:00" sentinel string
+	// (with fractional second digits matching the column's precision).
+	ZeroDateAsString
+
+	// Zero dates are treated as invalid values, i.e., decoding returns an
+	// error, or decodes the value as nil (which is reported as a lossy
+	// conversion) when the decoding is lenient.
+	ZeroDateInvalid
+)
+
+const zeroDateString = "0000-00-00 00:00:00"
+
+type zeroDateFieldDescriptor struct {
+	FieldDescriptor
+
+	mode    ZeroDateMode
+	lenient bool
+
+	// The zero date's sentinel string.
+	sentinel string
+
+	// Returns true if the (successfully parsed) value's bytes are a zero
+	// date.
+	isZero func(data []byte) bool
+}
+
+// This returns a field descriptor which decodes the DATETIME / DATETIME2 /
+// TIMESTAMP / TIMESTAMP2 descriptor's zero dates according to the mode.
+// When lenient is set, invalid zero dates are decoded as nil instead of
+// returning an error.  Other descriptors (and descriptors in ZeroDateAsTime
+// mode) are returned as is.
+func WithZeroDateMode(
+	fd FieldDescriptor,
+	mode ZeroDateMode,
+	lenient bool) FieldDescriptor {
+
+	if mode == ZeroDateAsTime {
+		return fd
+	}
+
+	d := &zeroDateFieldDescriptor{
+		FieldDescriptor: fd,
+		mode:            mode,
+		lenient:         lenient,
+		sentinel:        zeroDateString,
+	}
+
+	switch fd.Type() {
+	case mysql_proto.FieldType_DATETIME:
+		d.isZero = func(data []byte) bool {
+			return LittleEndian.Uint64(data)/1000000 == 0
+		}
+	case mysql_proto.FieldType_TIMESTAMP:
+		d.isZero = func(data []byte) bool {
+			return LittleEndian.Uint32(data) == 0
+		}
+	case mysql_proto.FieldType_DATETIME2:
+		d.isZero = func(data []byte) bool {
+			return (BigEndian.Uint40(data)-datetimefIntOffset)>>17 == 0
+		}
+		d.sentinel += fractionalZeros(fd)
+	case mysql_proto.FieldType_TIMESTAMP2:
+		d.isZero = func(data []byte) bool {
+			return BigEndian.Int32(data) == 0
+		}
+		d.sentinel += fractionalZeros(fd)
+	default:
+		return fd
+	}
+
+	return d
+}
+
+func fractionalZeros(fd FieldDescriptor) string {
+	precision := uint8(0)
+	switch d := fd.(type) {
+	case *datetime2FieldDescriptor:
+		precision = d.microSecondPrecision
+	case *timestamp2FieldDescriptor:
+		precision = d.microSecondPrecision
+	}
+
+	if precision == 0 {
+		return ""
+	}
+	return "." + "000000"[:precision]
+}
+
+func (d *zeroDateFieldDescriptor) ParseValue(data []byte) (
+	value interface{},
+	remaining []byte,
+	err error) {
+
+	value, remaining, _, err = d.parseValueWithLoss(data)
+	return value, remaining, err
+}
+
+func (d *zeroDateFieldDescriptor) parseValueWithLoss(data []byte) (
+	value interface{},
+	remaining []byte,
+	loss string,
+	err error) {
+
+	if lossy, ok := asLossyFieldDescriptor(d.FieldDescriptor); ok {
+		value, remaining, loss, err = lossy.parseValueWithLoss(data)
+	} else {
+		value, remaining, err = d.FieldDescriptor.ParseValue(data)
+	}
+	if err != nil || value == nil || !d.isZero(data) {
+		return value, remaining, loss, err
+	}
+
+	if d.mode == ZeroDateAsString {
+		return d.sentinel, remaining, "", nil
+	}
+
+	msg := fmt.Sprintf("Invalid zero date: %s", d.sentinel)
+	if d.lenient {
+		return nil, remaining, msg + " (decoded as NULL)", nil
+	}
+	return nil, nil, "", errors.New(msg)
 }
@@ -0,0 +1,469 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type TemporalFieldsSuite struct {
+}
+
+var _ = Suite(&TemporalFieldsSuite{})
+
+var testPST = time.FixedZone("PST", -8*60*60)
+
+// 2015-06-17 23:45:12 (wall-clock)
+func testDateTimeBytes() []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, 20150617234512)
+	return b
+}
+
+// 2015-06-17 23:45:12 (wall-clock), with 0 fractional precision.
+func testDateTime2Bytes() []byte {
+	ym := uint64(2015*13 + 6)
+	ymd := ym<<5 | 17
+	hms := uint64(23<<12 | 45<<6 | 12)
+	packed := ymd<<17 | hms + datetimefIntOffset
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, packed)
+	return b[3:]
+}
+
+func (s *TemporalFieldsSuite) TestDateTimeBasic(c *C) {
+	d := NewDateTimeFieldDescriptor(true)
+	c.Check(d.IsNullable(), IsTrue)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_DATETIME)
+}
+
+func (s *TemporalFieldsSuite) TestDateTimeParseValue(c *C) {
+	d := NewDateTimeFieldDescriptor(true)
+
+	val, remaining, err := d.ParseValue(append(testDateTimeBytes(), "rest"...))
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+
+	t, ok := val.(time.Time)
+	c.Assert(ok, IsTrue)
+	c.Check(t.Location(), Equals, time.UTC)
+	c.Check(t, Equals, time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+}
+
+func (s *TemporalFieldsSuite) TestDateTimeParseValueInLocation(c *C) {
+	d := NewDateTimeFieldDescriptorInLocation(true, testPST)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_DATETIME)
+
+	val, _, err := d.ParseValue(testDateTimeBytes())
+	c.Assert(err, IsNil)
+
+	t, ok := val.(time.Time)
+	c.Assert(ok, IsTrue)
+	c.Check(t.Location(), Equals, time.UTC)
+	c.Check(t, Equals, time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC))
+}
+
+func (s *TemporalFieldsSuite) TestDateTime2ParseValue(c *C) {
+	d, remaining, err := NewDateTime2FieldDescriptor(true, []byte{0, 'r'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "r")
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_DATETIME2)
+
+	val, remaining, err := d.ParseValue(append(testDateTime2Bytes(), "rest"...))
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "rest")
+
+	t, ok := val.(time.Time)
+	c.Assert(ok, IsTrue)
+	c.Check(t.Location(), Equals, time.UTC)
+	c.Check(t, Equals, time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+}
+
+func (s *TemporalFieldsSuite) TestDateTime2ParseValueInLocation(c *C) {
+	d, _, err := NewDateTime2FieldDescriptorInLocation(
+		true,
+		[]byte{0},
+		testPST)
+	c.Assert(err, IsNil)
+
+	val, _, err := d.ParseValue(testDateTime2Bytes())
+	c.Assert(err, IsNil)
+
+	t, ok := val.(time.Time)
+	c.Assert(ok, IsTrue)
+	c.Check(t.Location(), Equals, time.UTC)
+	c.Check(t, Equals, time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC))
+}
+
+func (s *TemporalFieldsSuite) TestTableMapDateTimeLocation(c *C) {
+	p := &TableMapEventParser{
+		options: DecodeOptions{DateTimeLocation: testPST},
+	}
+
+	table := &TableMapEvent{
+		columnTypesBytes: []byte{
+			byte(mysql_proto.FieldType_DATETIME),
+			byte(mysql_proto.FieldType_DATETIME2),
+		},
+		metadataBytes:    []byte{0},
+		nullColumnsBytes: []byte{0},
+	}
+
+	err := p.parseColumns(table)
+	c.Assert(err, IsNil)
+	c.Assert(len(table.ColumnDescriptors()), Equals, 2)
+
+	expected := time.Date(2015, 6, 18, 7, 45, 12, 0, time.UTC)
+
+	val, _, err := table.ColumnDescriptors()[0].ParseValue(testDateTimeBytes())
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, expected)
+
+	val, _, err = table.ColumnDescriptors()[1].ParseValue(testDateTime2Bytes())
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, expected)
+}
+
+// Encodes the time in mysql's TIME2 binary format.  See
+// my_time_packed_to_binary (in sql-common/my_time.c).
+func testTime2Bytes(
+	negative bool,
+	hour int64,
+	minute int64,
+	second int64,
+	usec int64,
+	precision uint8) []byte {
+
+	packed := (hour<<12|minute<<6|second)<<24 + usec
+	if negative {
+		packed = -packed
+	}
+
+	intPart := packed >> 24
+	fracPart := packed % (1 << 24)
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(intPart+timefIntOffset))
+	switch precision {
+	case 0:
+		return b[1:4]
+	case 1, 2:
+		return append(b[1:4], byte(fracPart/10000))
+	case 3, 4:
+		frac := make([]byte, 2)
+		binary.BigEndian.PutUint16(frac, uint16(fracPart/100))
+		return append(b[1:4], frac...)
+	default:
+		binary.BigEndian.PutUint64(b, uint64(packed+timefOffset))
+		return b[2:]
+	}
+}
+
+func (s *TemporalFieldsSuite) TestTime2Basic(c *C) {
+	d, remaining, err := NewTime2FieldDescriptor(true, []byte{3, 'r'})
+	c.Assert(err, IsNil)
+	c.Check(string(remaining), Equals, "r")
+	c.Check(d.IsNullable(), IsTrue)
+	c.Check(d.Type(), Equals, mysql_proto.FieldType_TIME2)
+
+	_, _, err = NewTime2FieldDescriptor(true, []byte{7})
+	c.Check(err, NotNil)
+
+	_, _, err = NewTime2FieldDescriptor(true, []byte{})
+	c.Check(err, NotNil)
+}
+
+func (s *TemporalFieldsSuite) TestTime2ParseValue(c *C) {
+	type testCase struct {
+		negative  bool
+		hour      int64
+		minute    int64
+		second    int64
+		usec      int64
+		precision uint8
+
+		str      string
+		duration time.Duration
+	}
+
+	hms := func(h, m, s int64) time.Duration {
+		return time.Duration(h)*time.Hour +
+			time.Duration(m)*time.Minute +
+			time.Duration(s)*time.Second
+	}
+
+	for _, t := range []testCase{
+		{false, 0, 0, 0, 0, 0, "00:00:00", 0},
+		{false, 0, 0, 0, 0, 3, "00:00:00.000", 0},
+		{false, 0, 0, 0, 0, 6, "00:00:00.000000", 0},
+		{false, 12, 34, 56, 0, 0, "12:34:56", hms(12, 34, 56)},
+		{true, 12, 34, 56, 0, 0, "-12:34:56", -hms(12, 34, 56)},
+		{false, 838, 59, 59, 0, 0, "838:59:59", hms(838, 59, 59)},
+		{true, 838, 59, 59, 0, 0, "-838:59:59", -hms(838, 59, 59)},
+		{
+			false, 1, 2, 3, 450000, 2,
+			"01:02:03.45",
+			hms(1, 2, 3) + 450*time.Millisecond,
+		},
+		{
+			true, 1, 2, 3, 450000, 2,
+			"-01:02:03.45",
+			-(hms(1, 2, 3) + 450*time.Millisecond),
+		},
+		{
+			false, 0, 0, 0, 500000, 1,
+			"00:00:00.5",
+			500 * time.Millisecond,
+		},
+		{
+			true, 0, 0, 0, 500000, 1,
+			"-00:00:00.5",
+			-500 * time.Millisecond,
+		},
+		{
+			false, 12, 34, 56, 789000, 3,
+			"12:34:56.789",
+			hms(12, 34, 56) + 789*time.Millisecond,
+		},
+		{
+			true, 12, 34, 56, 789000, 4,
+			"-12:34:56.7890",
+			-(hms(12, 34, 56) + 789*time.Millisecond),
+		},
+		{
+			false, 12, 34, 56, 789012, 6,
+			"12:34:56.789012",
+			hms(12, 34, 56) + 789012*time.Microsecond,
+		},
+		{
+			true, 12, 34, 56, 789000, 6,
+			"-12:34:56.789000",
+			-(hms(12, 34, 56) + 789*time.Millisecond),
+		},
+		{
+			true, 0, 0, 0, 1, 5,
+			"-00:00:00.00000",
+			-time.Microsecond,
+		},
+	} {
+		d, _, err := NewTime2FieldDescriptor(true, []byte{t.precision})
+		c.Assert(err, IsNil)
+
+		data := testTime2Bytes(
+			t.negative,
+			t.hour,
+			t.minute,
+			t.second,
+			t.usec,
+			t.precision)
+
+		val, remaining, err := d.ParseValue(append(data, "rest"...))
+		c.Assert(err, IsNil)
+		c.Check(string(remaining), Equals, "rest")
+
+		v, ok := val.(TimeValue)
+		c.Assert(ok, IsTrue)
+		c.Check(v.String(), Equals, t.str)
+		c.Check(v.Duration(), Equals, t.duration)
+		c.Check(v.IsNegative(), Equals, t.negative)
+		c.Check(v.Precision(), Equals, t.precision)
+	}
+}
+
+func (s *TemporalFieldsSuite) TestTime2ParseValueMysqlVectors(c *C) {
+	// Binary values written by mysql.
+	for _, t := range []struct {
+		precision uint8
+		data      []byte
+		str       string
+	}{
+		// TIME(0) '-00:00:01'
+		{0, []byte{0x7f, 0xff, 0xff}, "-00:00:01"},
+		// TIME(1) '-00:00:00.1'
+		{1, []byte{0x7f, 0xff, 0xff, 0xf6}, "-00:00:00.1"},
+		// TIME(3) '-00:00:01.500'
+		{3, []byte{0x7f, 0xff, 0xfe, 0xec, 0x78}, "-00:00:01.500"},
+		// TIME(6) '10:11:12.000001'
+		{6, []byte{0x80, 0xa2, 0xcc, 0x00, 0x00, 0x01}, "10:11:12.000001"},
+	} {
+		d, _, err := NewTime2FieldDescriptor(true, []byte{t.precision})
+		c.Assert(err, IsNil)
+
+		val, remaining, err := d.ParseValue(t.data)
+		c.Assert(err, IsNil)
+		c.Check(len(remaining), Equals, 0)
+		c.Check(val.(TimeValue).String(), Equals, t.str)
+	}
+
+	d, _, err := NewTime2FieldDescriptor(true, []byte{6})
+	c.Assert(err, IsNil)
+	_, _, err = d.ParseValue([]byte{0x80, 0xa2, 0xcc, 0x00, 0x00})
+	c.Check(err, NotNil)
+}
+
+func testPackedDateTime2Bytes(
+	year uint64,
+	month uint64,
+	day uint64,
+	hour uint64,
+	minute uint64,
+	second uint64) []byte {
+
+	ymd := (year*13+month)<<5 | day
+	hms := hour<<12 | minute<<6 | second
+	packed := ymd<<17 | hms + datetimefIntOffset
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, packed)
+	return b[3:]
+}
+
+func (s *TemporalFieldsSuite) TestDateTime2OutOfRange(c *C) {
+	strict, _, err := NewDateTime2FieldDescriptor(true, []byte{0})
+	c.Assert(err, IsNil)
+
+	lenient, _, err := NewDateTime2FieldDescriptorWithOptions(
+		true,
+		[]byte{0},
+		DecodeOptions{LenientDateTime: true})
+	c.Assert(err, IsNil)
+
+	for _, data := range [][]byte{
+		// year 10082
+		{0xff, 0xff, 0xff, 0xff, 0xff},
+		// below DATETIMEF_INT_OFS (i.e., the packed value underflows)
+		{0x00, 0x00, 0x00, 0x00, 0x00},
+		testPackedDateTime2Bytes(10000, 1, 1, 0, 0, 0),
+		testPackedDateTime2Bytes(2015, 6, 17, 24, 0, 0),
+		testPackedDateTime2Bytes(2015, 6, 17, 23, 60, 0),
+		testPackedDateTime2Bytes(2015, 6, 17, 23, 0, 63),
+	} {
+		_, _, err := strict.ParseValue(data)
+		c.Check(err, NotNil)
+
+		val, remaining, err := lenient.ParseValue(append(data, "rest"...))
+		c.Check(err, IsNil)
+		c.Check(val, IsNil)
+		c.Check(string(remaining), Equals, "rest")
+	}
+
+	// Boundary values are accepted.
+	val, _, err := strict.ParseValue(
+		testPackedDateTime2Bytes(9999, 12, 31, 23, 59, 59))
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC))
+
+	val, _, err = lenient.ParseValue(
+		testPackedDateTime2Bytes(1000, 1, 1, 0, 0, 0))
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *TemporalFieldsSuite) TestTableMapLenientDateTime(c *C) {
+	p := &TableMapEventParser{
+		options: DecodeOptions{LenientDateTime: true},
+	}
+
+	table := &TableMapEvent{
+		columnTypesBytes: []byte{byte(mysql_proto.FieldType_DATETIME2)},
+		metadataBytes:    []byte{0},
+		nullColumnsBytes: []byte{0},
+	}
+
+	err := p.parseColumns(table)
+	c.Assert(err, IsNil)
+
+	val, _, err := table.ColumnDescriptors()[0].ParseValue(
+		[]byte{0xff, 0xff, 0xff, 0xff, 0xff})
+	c.Assert(err, IsNil)
+	c.Check(val, IsNil)
+}
+
+func (s *TemporalFieldsSuite) TestZeroDateMode(c *C) {
+	datetime2, _, err := NewDateTime2FieldDescriptor(true, []byte{3})
+	c.Assert(err, IsNil)
+	timestamp2, _, err := NewTimestamp2FieldDescriptor(true, []byte{0})
+	c.Assert(err, IsNil)
+
+	// 5 bytes of packed datetime (offset by 0x8000000000) followed by 2
+	// bytes of fractional seconds.
+	zeroDateTime2 := []byte{0x80, 0, 0, 0, 0, 0, 0}
+
+	tests := []struct {
+		fd       FieldDescriptor
+		zero     []byte
+		sentinel string
+	}{
+		{
+			NewDateTimeFieldDescriptor(true),
+			make([]byte, 8),
+			"0000-00-00 00:00:00",
+		},
+		{
+			NewTimestampFieldDescriptor(true),
+			make([]byte, 4),
+			"0000-00-00 00:00:00",
+		},
+		{
+			datetime2,
+			zeroDateTime2,
+			"0000-00-00 00:00:00.000",
+		},
+		{
+			timestamp2,
+			make([]byte, 4),
+			"0000-00-00 00:00:00",
+		},
+	}
+
+	for _, test := range tests {
+		comment := Commentf("%s", test.fd.Type())
+		data := append(test.zero, "rest"...)
+
+		c.Check(
+			WithZeroDateMode(test.fd, ZeroDateAsTime, false),
+			Equals,
+			test.fd)
+
+		fd := WithZeroDateMode(test.fd, ZeroDateAsString, false)
+		c.Check(fd.Type(), Equals, test.fd.Type())
+		val, remaining, err := fd.ParseValue(data)
+		c.Assert(err, IsNil, comment)
+		c.Check(val, Equals, test.sentinel, comment)
+		c.Check(string(remaining), Equals, "rest", comment)
+
+		fd = WithZeroDateMode(test.fd, ZeroDateInvalid, false)
+		_, _, err = fd.ParseValue(data)
+		c.Check(err, NotNil, comment)
+
+		fd = WithZeroDateMode(test.fd, ZeroDateInvalid, true)
+		val, remaining, err = fd.ParseValue(data)
+		c.Assert(err, IsNil, comment)
+		c.Check(val, IsNil, comment)
+		c.Check(string(remaining), Equals, "rest", comment)
+
+		lossy, ok := asLossyFieldDescriptor(fd)
+		c.Assert(ok, IsTrue)
+		_, _, loss, err := lossy.parseValueWithLoss(data)
+		c.Assert(err, IsNil, comment)
+		c.Check(loss, Not(Equals), "", comment)
+	}
+
+	// Non-zero dates are not affected.
+	fd := WithZeroDateMode(
+		NewDateTimeFieldDescriptor(true),
+		ZeroDateInvalid,
+		false)
+	val, _, err := fd.ParseValue(testDateTimeBytes())
+	c.Assert(err, IsNil)
+	c.Check(val, Equals, time.Date(2015, 6, 17, 23, 45, 12, 0, time.UTC))
+
+	// Non-temporal descriptors are returned as is.
+	long := NewLongFieldDescriptor(true)
+	c.Check(WithZeroDateMode(long, ZeroDateInvalid, false), Equals, long)
+}
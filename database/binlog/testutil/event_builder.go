@@ -0,0 +1,285 @@
+// Package testutil provides helpers for generating synthetic binlog events
+// (as raw bytes) in tests.
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+const (
+	// The size of the v4 event header (timestamp, event type, server id,
+	// event length, next position and flags).
+	EventHeaderSize = 19
+
+	// The size of the crc32 checksum footer.
+	ChecksumSize = 4
+
+	// The table id used by BuildTableMapEvent.
+	DefaultTableId = uint64(1)
+
+	// The server id used by BuildFormatDescriptionEvent and
+	// BuildTableMapEvent.
+	DefaultServerId = uint32(1)
+)
+
+// LogFileMagic is the marker at the beginning of every binlog file.
+var LogFileMagic = []byte{0xfe, 'b', 'i', 'n'}
+
+// EventBuilder builds raw v4 binlog events, i.e., the common event header
+// followed by the event body, and optionally the crc32 checksum footer.
+type EventBuilder struct {
+	eventType    mysql_proto.LogEventType_Type
+	serverId     uint32
+	timestamp    uint32
+	nextPosition uint32
+	flags        uint16
+	body         []byte
+	checksum     bool
+}
+
+// This returns a builder for an event of the given type, with zero timestamp,
+// next position and flags, and an empty body.
+func NewEventBuilder(
+	eventType mysql_proto.LogEventType_Type,
+	serverId uint32) *EventBuilder {
+
+	return &EventBuilder{
+		eventType: eventType,
+		serverId:  serverId,
+	}
+}
+
+// SetTimestamp sets the event's timestamp (truncated to seconds).
+func (b *EventBuilder) SetTimestamp(t time.Time) *EventBuilder {
+	b.timestamp = uint32(t.Unix())
+	return b
+}
+
+// SetNextPosition sets the event's next position (i.e., log position) field.
+func (b *EventBuilder) SetNextPosition(pos uint32) *EventBuilder {
+	b.nextPosition = pos
+	return b
+}
+
+// SetFlags sets the event's header flags.
+func (b *EventBuilder) SetFlags(flags uint16) *EventBuilder {
+	b.flags = flags
+	return b
+}
+
+// SetBody sets the event's body (i.e., the post header and the payload).
+func (b *EventBuilder) SetBody(body []byte) *EventBuilder {
+	b.body = body
+	return b
+}
+
+// SetChecksum controls whether or not the crc32 checksum footer is appended
+// to the event (the event length includes the footer).  The footer is
+// omitted by default.
+func (b *EventBuilder) SetChecksum(enabled bool) *EventBuilder {
+	b.checksum = enabled
+	return b
+}
+
+// Build returns the event's raw bytes.
+func (b *EventBuilder) Build() []byte {
+	length := EventHeaderSize + len(b.body)
+	if b.checksum {
+		length += ChecksumSize
+	}
+
+	event := make([]byte, EventHeaderSize, length)
+	binary.LittleEndian.PutUint32(event[0:], b.timestamp)
+	event[4] = byte(b.eventType)
+	binary.LittleEndian.PutUint32(event[5:], b.serverId)
+	binary.LittleEndian.PutUint32(event[9:], uint32(length))
+	binary.LittleEndian.PutUint32(event[13:], b.nextPosition)
+	binary.LittleEndian.PutUint16(event[17:], b.flags)
+
+	event = append(event, b.body...)
+
+	if b.checksum {
+		crc := [ChecksumSize]byte{}
+		binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(event))
+		event = append(event, crc[:]...)
+	}
+
+	return event
+}
+
+// mysql 5.6's post header lengths, indexed by event type - 1.
+var formatDescriptionPostHeaderLengths = []byte{
+	56, 13, 0, 8, 0, 18, 0, 4, 4, 4, 4, 18, 0, 0, 92, 0, 4, 26,
+	8, 0, 0, 0, 8, 8, 8, 2, 0, 0, 0, 10, 10, 10, 25, 25, 0,
+}
+
+// BuildFormatDescriptionEvent returns a mysql 5.6 style format description
+// event.  When checksum is true, the event specifies the crc32 checksum
+// algorithm (and is itself checksummed); subsequent events should be built
+// with SetChecksum(true).
+func BuildFormatDescriptionEvent(checksum bool) []byte {
+	body := &bytes.Buffer{}
+
+	// binlog version
+	_ = binary.Write(body, binary.LittleEndian, uint16(4))
+
+	serverVersion := [50]byte{}
+	copy(serverVersion[:], "5.6.15-log")
+	_, _ = body.Write(serverVersion[:])
+
+	// created timestamp
+	_ = binary.Write(body, binary.LittleEndian, uint32(0))
+
+	_ = body.WriteByte(EventHeaderSize)
+	_, _ = body.Write(formatDescriptionPostHeaderLengths)
+
+	builder := NewEventBuilder(
+		mysql_proto.LogEventType_FORMAT_DESCRIPTION_EVENT,
+		DefaultServerId)
+
+	if checksum {
+		_ = body.WriteByte(byte(mysql_proto.ChecksumAlgorithm_CRC32))
+		builder.SetChecksum(true)
+	} else {
+		_ = body.WriteByte(byte(mysql_proto.ChecksumAlgorithm_OFF))
+		// The checksum footer is always present in the format description
+		// event.
+		_, _ = body.Write(make([]byte, ChecksumSize))
+	}
+
+	return builder.SetBody(body.Bytes()).Build()
+}
+
+// BuildTableMapEvent returns a table map event (without checksum) for the
+// given table, with DefaultTableId and DefaultServerId.  All columns are
+// nullable, and use the default metadata (see ColumnMetadata).
+func BuildTableMapEvent(
+	schema string,
+	table string,
+	colTypes []mysql_proto.FieldType_Type) []byte {
+
+	return NewEventBuilder(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		DefaultServerId).SetBody(
+		TableMapEventBody(DefaultTableId, schema, table, colTypes)).Build()
+}
+
+// TableMapEventBody returns a table map event's body.  See
+// BuildTableMapEvent.
+func TableMapEventBody(
+	tableId uint64,
+	schema string,
+	table string,
+	colTypes []mysql_proto.FieldType_Type) []byte {
+
+	body := &bytes.Buffer{}
+
+	id := [8]byte{}
+	binary.LittleEndian.PutUint64(id[:], tableId)
+	_, _ = body.Write(id[:6])
+
+	// flags
+	_ = binary.Write(body, binary.LittleEndian, uint16(1))
+
+	_ = body.WriteByte(byte(len(schema)))
+	_, _ = body.WriteString(schema)
+	_ = body.WriteByte(0)
+
+	_ = body.WriteByte(byte(len(table)))
+	_, _ = body.WriteString(table)
+	_ = body.WriteByte(0)
+
+	_, _ = body.Write(PackedLength(uint64(len(colTypes))))
+
+	metadata := []byte{}
+	for _, colType := range colTypes {
+		loggedType, colMetadata := ColumnMetadata(colType)
+		_ = body.WriteByte(byte(loggedType))
+		metadata = append(metadata, colMetadata...)
+	}
+
+	_, _ = body.Write(PackedLength(uint64(len(metadata))))
+	_, _ = body.Write(metadata)
+
+	// null bits
+	nullBits := make([]byte, (len(colTypes)+7)/8)
+	for i := range colTypes {
+		nullBits[i/8] |= 1 << uint(i%8)
+	}
+	_, _ = body.Write(nullBits)
+
+	return body.Bytes()
+}
+
+// ColumnMetadata returns the column type as logged in table map events, and
+// the column's default table map metadata.  ENUM and SET columns are logged
+// as STRING columns (with the real type packed into the metadata).  The
+// defaults are:
+//   - FLOAT / DOUBLE: 4 / 8 bytes.
+//   - VARCHAR, VAR_STRING and STRING: 255 (max length in bytes).
+//   - ENUM / SET: 1 byte.
+//   - BLOB (and the tiny / medium / long variants): 2 length bytes.
+//   - BIT: 8 bits.
+//   - NEWDECIMAL: DECIMAL(10, 2).
+//   - TIMESTAMP2 / DATETIME2 / TIME2: no fractional seconds.
+//   - GEOMETRY: 4 length bytes.
+func ColumnMetadata(
+	colType mysql_proto.FieldType_Type) (
+	loggedType mysql_proto.FieldType_Type,
+	metadata []byte) {
+
+	switch colType {
+	case mysql_proto.FieldType_FLOAT:
+		return colType, []byte{4}
+	case mysql_proto.FieldType_DOUBLE:
+		return colType, []byte{8}
+	case mysql_proto.FieldType_VARCHAR:
+		return colType, []byte{255, 0}
+	case mysql_proto.FieldType_VAR_STRING,
+		mysql_proto.FieldType_STRING:
+		return colType, []byte{byte(colType), 255}
+	case mysql_proto.FieldType_ENUM,
+		mysql_proto.FieldType_SET:
+		return mysql_proto.FieldType_STRING, []byte{byte(colType), 1}
+	case mysql_proto.FieldType_TINY_BLOB,
+		mysql_proto.FieldType_MEDIUM_BLOB,
+		mysql_proto.FieldType_LONG_BLOB,
+		mysql_proto.FieldType_BLOB:
+		return mysql_proto.FieldType_BLOB, []byte{2}
+	case mysql_proto.FieldType_BIT:
+		return colType, []byte{1, 0}
+	case mysql_proto.FieldType_NEWDECIMAL:
+		return colType, []byte{10, 2}
+	case mysql_proto.FieldType_TIMESTAMP2,
+		mysql_proto.FieldType_DATETIME2,
+		mysql_proto.FieldType_TIME2:
+		return colType, []byte{0}
+	case mysql_proto.FieldType_GEOMETRY:
+		return colType, []byte{4}
+	}
+
+	return colType, nil
+}
+
+// PackedLength returns the length encoded integer (see net_store_length in
+// mysys/pack.c).
+func PackedLength(val uint64) []byte {
+	switch {
+	case val < 251:
+		return []byte{byte(val)}
+	case val < 1<<16:
+		return []byte{252, byte(val), byte(val >> 8)}
+	case val < 1<<24:
+		return []byte{253, byte(val), byte(val >> 8), byte(val >> 16)}
+	}
+
+	result := make([]byte, 9)
+	result[0] = 254
+	binary.LittleEndian.PutUint64(result[1:], val)
+	return result
+}
@@ -0,0 +1,189 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/database/binlog"
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type EventBuilderSuite struct {
+}
+
+var _ = Suite(&EventBuilderSuite{})
+
+func (s *EventBuilderSuite) TestBuild(c *C) {
+	event := NewEventBuilder(mysql_proto.LogEventType_XID_EVENT, 123).
+		SetTimestamp(time.Unix(1500000000, 999)).
+		SetNextPosition(456).
+		SetFlags(8).
+		SetBody([]byte{1, 2, 3}).
+		Build()
+
+	c.Check(event, DeepEquals, []byte{
+		// timestamp
+		0x00, 0x2f, 0x68, 0x59,
+		// event type
+		byte(mysql_proto.LogEventType_XID_EVENT),
+		// server id
+		123, 0, 0, 0,
+		// event length
+		22, 0, 0, 0,
+		// next position
+		0xc8, 0x01, 0, 0,
+		// flags
+		8, 0,
+		// body
+		1, 2, 3,
+	})
+}
+
+func (s *EventBuilderSuite) TestBuildWithChecksum(c *C) {
+	event := NewEventBuilder(mysql_proto.LogEventType_XID_EVENT, 1).
+		SetBody([]byte{1, 2, 3}).
+		SetChecksum(true).
+		Build()
+
+	c.Assert(event, HasLen, EventHeaderSize+3+ChecksumSize)
+	c.Check(
+		binary.LittleEndian.Uint32(event[9:]),
+		Equals,
+		uint32(len(event)))
+
+	body := event[:len(event)-ChecksumSize]
+	c.Check(body[EventHeaderSize:], DeepEquals, []byte{1, 2, 3})
+	c.Check(
+		binary.LittleEndian.Uint32(event[len(body):]),
+		Equals,
+		crc32.ChecksumIEEE(body))
+}
+
+func (s *EventBuilderSuite) TestPackedLength(c *C) {
+	c.Check(PackedLength(250), DeepEquals, []byte{250})
+	c.Check(PackedLength(251), DeepEquals, []byte{252, 251, 0})
+	c.Check(PackedLength(0x10000), DeepEquals, []byte{253, 0, 0, 1})
+	c.Check(
+		PackedLength(0x1000000),
+		DeepEquals,
+		[]byte{254, 0, 0, 0, 1, 0, 0, 0, 0})
+}
+
+func (s *EventBuilderSuite) readEvents(
+	c *C,
+	events ...[]byte) []binlog.Event {
+
+	stream := append([]byte{}, LogFileMagic...)
+	for _, event := range events {
+		stream = append(stream, event...)
+	}
+
+	reader := binlog.NewLogFileV4EventReaderWithOptions(
+		bytes.NewReader(stream),
+		"test",
+		binlog.NewV4EventParserMap(),
+		binlog.Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		},
+		binlog.LogFileV4EventReaderOptions{VerifyChecksum: true})
+
+	result := []binlog.Event{}
+	for {
+		event, err := reader.NextEvent()
+		if err == io.EOF {
+			return result
+		}
+		c.Assert(err, IsNil)
+		result = append(result, event)
+	}
+}
+
+func (s *EventBuilderSuite) checkTableMap(c *C, event binlog.Event) {
+	tm, ok := event.(*binlog.TableMapEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(tm.TableId(), Equals, DefaultTableId)
+	c.Check(string(tm.DatabaseName()), Equals, "db")
+	c.Check(string(tm.TableName()), Equals, "tbl")
+
+	expected := []mysql_proto.FieldType_Type{
+		mysql_proto.FieldType_LONGLONG,
+		mysql_proto.FieldType_VARCHAR,
+		mysql_proto.FieldType_DOUBLE,
+		mysql_proto.FieldType_ENUM,
+		mysql_proto.FieldType_SET,
+		mysql_proto.FieldType_STRING,
+		mysql_proto.FieldType_BLOB,
+		mysql_proto.FieldType_DATETIME2,
+		mysql_proto.FieldType_NEWDECIMAL,
+	}
+
+	descriptors := tm.ColumnDescriptors()
+	c.Assert(descriptors, HasLen, len(expected))
+	for i, d := range descriptors {
+		c.Check(d.Type(), Equals, expected[i])
+		c.Check(d.IsNullable(), IsTrue)
+	}
+}
+
+var testColumnTypes = []mysql_proto.FieldType_Type{
+	mysql_proto.FieldType_LONGLONG,
+	mysql_proto.FieldType_VARCHAR,
+	mysql_proto.FieldType_DOUBLE,
+	mysql_proto.FieldType_ENUM,
+	mysql_proto.FieldType_SET,
+	mysql_proto.FieldType_STRING,
+	mysql_proto.FieldType_MEDIUM_BLOB,
+	mysql_proto.FieldType_DATETIME2,
+	mysql_proto.FieldType_NEWDECIMAL,
+}
+
+func (s *EventBuilderSuite) TestParseTableMapEvent(c *C) {
+	events := s.readEvents(
+		c,
+		BuildFormatDescriptionEvent(false),
+		BuildTableMapEvent("db", "tbl", testColumnTypes))
+
+	c.Assert(events, HasLen, 2)
+	_, ok := events[0].(*binlog.FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+	s.checkTableMap(c, events[1])
+}
+
+func (s *EventBuilderSuite) TestParseChecksummedEvents(c *C) {
+	tableMap := NewEventBuilder(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		DefaultServerId).
+		SetBody(TableMapEventBody(
+			DefaultTableId,
+			"db",
+			"tbl",
+			testColumnTypes)).
+		SetChecksum(true).
+		Build()
+
+	events := s.readEvents(c, BuildFormatDescriptionEvent(true), tableMap)
+
+	c.Assert(events, HasLen, 2)
+	fde, ok := events[0].(*binlog.FormatDescriptionEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(
+		fde.ChecksumAlgorithm(),
+		Equals,
+		mysql_proto.ChecksumAlgorithm_CRC32)
+	c.Check(events[1].ChecksumStatus(), Equals, binlog.ChecksumVerified)
+	s.checkTableMap(c, events[1])
+}
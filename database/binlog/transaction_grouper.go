@@ -0,0 +1,171 @@
+package binlog
+
+import (
+	"bytes"
+)
+
+// Transaction is a group of events which were logged as a single unit.
+// Events which are not part of any transaction (e.g., format description
+// or rotate events) are returned as single event groups.
+type Transaction struct {
+	Events []Event
+
+	// Committed is true when the group ended with a commit marker (XID event
+	// or COMMIT query), or when the group is not subjected to commit semantics
+	// (non-transactional events and implicitly committed statements such as
+	// DDL).
+	Committed bool
+}
+
+// TransactionGrouper groups the events returned by an EventReader into
+// transactions.  A transaction starts at a (MySQL or MariaDB) GTID event or a
+// BEGIN query event, and ends at a XID event or a COMMIT / ROLLBACK query
+// event.  A transaction which is interrupted by the start of another
+// transaction is considered incomplete.
+//
+// TransactionGrouper is not threadsafe.
+type TransactionGrouper struct {
+	reader EventReader
+
+	// When true, incomplete and rolled back transactions are discarded
+	// instead of being returned.
+	commitOnly bool
+
+	current  *Transaction
+	sawBegin bool
+
+	// An event which terminated the previous (incomplete) transaction and
+	// must start the next one.
+	pending Event
+}
+
+// This returns a TransactionGrouper which reads events from the reader.  When
+// commitOnly is true, the grouper withholds a transaction until it sees the
+// transaction's commit marker, and silently discards transactions which end
+// without one.
+func NewTransactionGrouper(
+	reader EventReader,
+	commitOnly bool) *TransactionGrouper {
+
+	return &TransactionGrouper{
+		reader:     reader,
+		commitOnly: commitOnly,
+	}
+}
+
+// Close closes the underlying reader.
+func (g *TransactionGrouper) Close() error {
+	return g.reader.Close()
+}
+
+// NextTransaction returns the next group of events.  When the underlying
+// reader returns an error, the partially assembled transaction is retained,
+// hence it is safe to call NextTransaction again on retryable errors.
+func (g *TransactionGrouper) NextTransaction() (*Transaction, error) {
+	for {
+		txn, err := g.nextGroup()
+		if err != nil {
+			return nil, err
+		}
+
+		if txn.Committed || !g.commitOnly {
+			return txn, nil
+		}
+	}
+}
+
+func (g *TransactionGrouper) nextGroup() (*Transaction, error) {
+	for {
+		var event Event
+		if g.pending != nil {
+			event = g.pending
+			g.pending = nil
+		} else {
+			var err error
+			event, err = g.reader.NextEvent()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if g.current == nil {
+			if !isTransactionStart(event) {
+				return &Transaction{
+					Events:    []Event{event},
+					Committed: true,
+				}, nil
+			}
+
+			g.current = &Transaction{Events: []Event{event}}
+			g.sawBegin = isBeginQuery(event) || isMariadbBegin(event)
+			continue
+		}
+
+		// The current transaction consists of only the GTID event when a
+		// BEGIN query has not been seen.
+		gtidOnly := !g.sawBegin && len(g.current.Events) == 1
+
+		if isTransactionStart(event) && !(gtidOnly && isBeginQuery(event)) {
+			// The current transaction ended without a commit marker.
+			g.pending = event
+			return g.finishGroup(false), nil
+		}
+
+		g.current.Events = append(g.current.Events, event)
+
+		switch e := event.(type) {
+		case *XidEvent:
+			return g.finishGroup(true), nil
+		case *QueryEvent:
+			if isBeginQuery(e) {
+				g.sawBegin = true
+				continue
+			}
+			if isQuery(e, "COMMIT") {
+				return g.finishGroup(true), nil
+			}
+			if isQuery(e, "ROLLBACK") {
+				return g.finishGroup(false), nil
+			}
+			if gtidOnly {
+				// Statements (e.g., DDL) which are not wrapped by BEGIN are
+				// implicitly committed.
+				return g.finishGroup(true), nil
+			}
+		}
+	}
+}
+
+func (g *TransactionGrouper) finishGroup(committed bool) *Transaction {
+	txn := g.current
+	txn.Committed = committed
+
+	g.current = nil
+	g.sawBegin = false
+
+	return txn
+}
+
+func isTransactionStart(event Event) bool {
+	switch event.(type) {
+	case *GtidLogEvent, *MariadbGtidLogEvent:
+		return true
+	}
+	return isBeginQuery(event)
+}
+
+// MariaDB does not log BEGIN query events; instead, a non-standalone gtid
+// event implies BEGIN.
+func isMariadbBegin(event Event) bool {
+	gtid, ok := event.(*MariadbGtidLogEvent)
+	return ok && !gtid.IsStandalone()
+}
+
+func isBeginQuery(event Event) bool {
+	q, ok := event.(*QueryEvent)
+	return ok && isQuery(q, "BEGIN")
+}
+
+func isQuery(q *QueryEvent, query string) bool {
+	return bytes.EqualFold(bytes.TrimSpace(q.Query()), []byte(query))
+}
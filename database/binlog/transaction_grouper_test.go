@@ -0,0 +1,173 @@
+package binlog
+
+import (
+	"io"
+	"log"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type TransactionGrouperSuite struct {
+	file *MockLogFile
+}
+
+var _ = Suite(&TransactionGrouperSuite{})
+
+func (s *TransactionGrouperSuite) SetUpTest(c *C) {
+	s.file = NewMockLogFile()
+	s.file.WriteLogFileMagic()
+	s.file.WriteFDE()
+}
+
+func (s *TransactionGrouperSuite) NewGrouper(
+	commitOnly bool) *TransactionGrouper {
+
+	reader := NewLogFileV4EventReader(
+		s.file.GetReader(),
+		testSourceName,
+		NewV4EventParserMap(),
+		Logger{
+			Fatalf:       log.Fatalf,
+			Infof:        log.Printf,
+			VerboseInfof: log.Printf,
+		})
+
+	return NewTransactionGrouper(reader, commitOnly)
+}
+
+func (s *TransactionGrouperSuite) WriteCompleteTransaction(id uint64) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(int(id))
+	s.file.WriteXid(id)
+}
+
+func (s *TransactionGrouperSuite) WriteIncompleteTransaction(id uint64) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(int(id))
+}
+
+func (s *TransactionGrouperSuite) NextTransaction(
+	c *C,
+	g *TransactionGrouper) *Transaction {
+
+	txn, err := g.NextTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(txn, NotNil)
+	return txn
+}
+
+func (s *TransactionGrouperSuite) CheckXid(
+	c *C,
+	txn *Transaction,
+	xid uint64) {
+
+	c.Assert(len(txn.Events), Equals, 4)
+	q, ok := txn.Events[0].(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, "BEGIN")
+	x, ok := txn.Events[3].(*XidEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(x.Xid(), Equals, xid)
+}
+
+func (s *TransactionGrouperSuite) TestCompleteTransaction(c *C) {
+	s.WriteCompleteTransaction(1)
+
+	g := s.NewGrouper(true)
+
+	txn := s.NextTransaction(c, g)
+	c.Assert(len(txn.Events), Equals, 1)
+	_, ok := txn.Events[0].(*FormatDescriptionEvent)
+	c.Check(ok, IsTrue)
+	c.Check(txn.Committed, IsTrue)
+
+	txn = s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	s.CheckXid(c, txn, 1)
+
+	_, err := g.NextTransaction()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *TransactionGrouperSuite) TestIncompleteTransaction(c *C) {
+	s.WriteCompleteTransaction(1)
+	s.WriteIncompleteTransaction(2)
+	s.WriteCompleteTransaction(3)
+
+	g := s.NewGrouper(true)
+
+	_ = s.NextTransaction(c, g) // FDE
+
+	txn := s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	s.CheckXid(c, txn, 1)
+
+	// The incomplete transaction is silently discarded.
+	txn = s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	s.CheckXid(c, txn, 3)
+
+	_, err := g.NextTransaction()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *TransactionGrouperSuite) TestIncompleteTransactionNotCommitOnly(
+	c *C) {
+
+	s.WriteIncompleteTransaction(2)
+	s.WriteCompleteTransaction(3)
+
+	g := s.NewGrouper(false)
+
+	_ = s.NextTransaction(c, g) // FDE
+
+	txn := s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsFalse)
+	c.Check(len(txn.Events), Equals, 3)
+
+	txn = s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	s.CheckXid(c, txn, 3)
+}
+
+func (s *TransactionGrouperSuite) TestWithholdUntilCommit(c *C) {
+	s.WriteIncompleteTransaction(1)
+
+	g := s.NewGrouper(true)
+
+	_ = s.NextTransaction(c, g) // FDE
+
+	// The transaction is not returned until the commit marker is seen.
+	_, err := g.NextTransaction()
+	c.Assert(err, Equals, io.EOF)
+
+	s.file.WriteXid(1)
+
+	txn := s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	s.CheckXid(c, txn, 1)
+}
+
+func (s *TransactionGrouperSuite) TestRollback(c *C) {
+	s.file.WriteBegin()
+	s.file.WriteTableMap()
+	s.file.WriteInsert(1)
+	s.file.WriteQuery("ROLLBACK")
+	s.file.WriteQuery("CREATE TABLE foo (id int)")
+
+	g := s.NewGrouper(true)
+
+	_ = s.NextTransaction(c, g) // FDE
+
+	// The rolled back transaction is discarded.
+	txn := s.NextTransaction(c, g)
+	c.Check(txn.Committed, IsTrue)
+	c.Assert(len(txn.Events), Equals, 1)
+	q, ok := txn.Events[0].(*QueryEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(string(q.Query()), Equals, "CREATE TABLE foo (id int)")
+}
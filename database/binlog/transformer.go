@@ -0,0 +1,187 @@
+package binlog
+
+// Transformer rewrites parsed events (e.g., for anonymizing row values).
+// Implementations must not modify the original event; the transformed event
+// should be a copy instead.  NOTE: a shallow copy of a rows event still
+// exposes the original values via the event's bytes (Bytes, RawBytes,
+// RowDataBytes and the raw rows); see transformRowsEvent.
+type Transformer interface {
+	// Transform returns the transformed event.
+	Transform(event Event) (Event, error)
+}
+
+type transformingEventReader struct {
+	reader      EventReader
+	transformer Transformer
+}
+
+// This returns an EventReader which applies the transformer to the parsed
+// events returned by the reader.  Events returned along with an error are
+// passed through untransformed.
+func NewTransformingEventReader(
+	reader EventReader,
+	transformer Transformer) EventReader {
+
+	return &transformingEventReader{
+		reader:      reader,
+		transformer: transformer,
+	}
+}
+
+func (r *transformingEventReader) peekHeaderBytes(numBytes int) ([]byte, error) {
+	return r.reader.peekHeaderBytes(numBytes)
+}
+
+func (r *transformingEventReader) consumeHeaderBytes(numBytes int) error {
+	return r.reader.consumeHeaderBytes(numBytes)
+}
+
+func (r *transformingEventReader) nextEventEndPosition() int64 {
+	return r.reader.nextEventEndPosition()
+}
+
+func (r *transformingEventReader) NextPosition() int64 {
+	return r.reader.NextPosition()
+}
+
+func (r *transformingEventReader) Close() error {
+	return r.reader.Close()
+}
+
+func (r *transformingEventReader) NextEvent() (Event, error) {
+	event, err := r.reader.NextEvent()
+	if err != nil {
+		return event, err
+	}
+
+	return r.transformer.Transform(event)
+}
+
+// This returns a copy of the raw event whose variable length data (and
+// checksum) are zeroed, and which does not retain the raw bytes.  The header
+// and the fixed length data are kept as is.  Events which are not raw
+// events are returned as is.
+func scrubEventBytes(event Event) Event {
+	raw, ok := event.(*RawV4Event)
+	if !ok {
+		return event
+	}
+
+	scrubbed := *raw
+	scrubbed.rawBytes = nil
+
+	end := sizeOfBasicV4EventHeader +
+		raw.extraHeadersSize +
+		raw.fixedLengthDataSize
+	scrubbed.data = make([]byte, len(raw.data))
+	copy(scrubbed.data, raw.data[:end])
+
+	return &scrubbed
+}
+
+// This returns a copy of the rows event base, without the original event's
+// row data bytes (see scrubEventBytes).
+func scrubRowsEventBytes(e BaseRowsEvent) BaseRowsEvent {
+	e.Event = scrubEventBytes(e.Event)
+	e.rowDataBytes = nil
+	return e
+}
+
+// Transforms a single row image.  usedColumns are the image's columns.
+type rowTransformFunc func(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	row RowValues) (RowValues, error)
+
+// This returns a copy of the write / update / delete rows event whose row
+// images are replaced by transformRow's results.  Since the original event's
+// bytes include the original values, the copy's raw event bytes are
+// scrubbed, and the copy has no row data bytes nor raw rows.  The second
+// return value is false (and the event is returned as is) for non-rows
+// events.  transformRow's first error is returned as is.
+func transformRowsEvent(
+	event Event,
+	transformRow rowTransformFunc) (Event, bool, error) {
+
+	switch e := event.(type) {
+	case *WriteRowsEvent:
+		rows, err := transformRows(
+			e.Context(),
+			e.UsedColumns(),
+			e.rows,
+			transformRow)
+		if err != nil {
+			return nil, true, err
+		}
+
+		transformed := *e
+		transformed.BaseRowsEvent = scrubRowsEventBytes(e.BaseRowsEvent)
+		transformed.rows = rows
+		transformed.rawRows = nil
+		return &transformed, true, nil
+	case *DeleteRowsEvent:
+		rows, err := transformRows(
+			e.Context(),
+			e.UsedColumns(),
+			e.rows,
+			transformRow)
+		if err != nil {
+			return nil, true, err
+		}
+
+		transformed := *e
+		transformed.BaseRowsEvent = scrubRowsEventBytes(e.BaseRowsEvent)
+		transformed.rows = rows
+		transformed.rawRows = nil
+		return &transformed, true, nil
+	case *UpdateRowsEvent:
+		rows := make([]UpdateRowValues, 0, len(e.rows))
+		for _, row := range e.rows {
+			before, err := transformRow(
+				e.Context(),
+				e.BeforeImageUsedColumns(),
+				row.BeforeImage)
+			if err != nil {
+				return nil, true, err
+			}
+
+			after, err := transformRow(
+				e.Context(),
+				e.AfterImageUsedColumns(),
+				row.AfterImage)
+			if err != nil {
+				return nil, true, err
+			}
+
+			rows = append(rows, UpdateRowValues{
+				BeforeImage: before,
+				AfterImage:  after,
+			})
+		}
+
+		transformed := *e
+		transformed.BaseRowsEvent = scrubRowsEventBytes(e.BaseRowsEvent)
+		transformed.rows = rows
+		transformed.rawRows = nil
+		return &transformed, true, nil
+	}
+
+	return event, false, nil
+}
+
+func transformRows(
+	context TableContext,
+	usedColumns []ColumnDescriptor,
+	rows []RowValues,
+	transformRow rowTransformFunc) ([]RowValues, error) {
+
+	result := make([]RowValues, 0, len(rows))
+	for _, row := range rows {
+		transformed, err := transformRow(context, usedColumns, row)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, transformed)
+	}
+	return result, nil
+}
@@ -0,0 +1,291 @@
+package binlog
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// MYSQL_TYPE_TYPED_ARRAY, which is used by mysql 8.0 for multi-valued index
+// columns.  NOTE: The type is not part of the FieldType proto enum.
+const TypedArrayFieldType mysql_proto.FieldType_Type = 244
+
+// Mysql binary json value types (as defined in sql/json_binary.h).  Only the
+// types which may appear in typed arrays are decoded.
+const (
+	jsonbSmallArray = 0x02
+	jsonbLargeArray = 0x03
+	jsonbLiteral    = 0x04
+	jsonbInt16      = 0x05
+	jsonbUint16     = 0x06
+	jsonbInt32      = 0x07
+	jsonbUint32     = 0x08
+	jsonbInt64      = 0x09
+	jsonbUint64     = 0x0a
+	jsonbDouble     = 0x0b
+	jsonbString     = 0x0c
+	jsonbOpaque     = 0x0f
+
+	jsonbLiteralNull  = 0x00
+	jsonbLiteralTrue  = 0x01
+	jsonbLiteralFalse = 0x02
+)
+
+//
+// typedArrayFieldDescriptor --------------------------------------------------
+//
+
+// The typed array value is stored as a binary json array (like json values),
+// with a 4 bytes packed length.
+type typedArrayFieldDescriptor struct {
+	packedLengthFieldDescriptor
+
+	elementType     mysql_proto.FieldType_Type
+	elementMetadata []byte
+}
+
+// This returns a field descriptor for TypedArrayFieldType (i.e.,
+// Field_typed_array).  The metadata consists of the element type (1 byte),
+// followed by the element's metadata (see
+// Field_typed_array::do_save_field_metadata).
+func NewTypedArrayFieldDescriptor(nullable NullableColumn, metadata []byte) (
+	fd FieldDescriptor,
+	remaining []byte,
+	err error) {
+
+	if len(metadata) < 1 {
+		return nil, nil, errors.New("Metadata has too few bytes")
+	}
+
+	elementType := mysql_proto.FieldType_Type(metadata[0])
+	metadata = metadata[1:]
+
+	elementMetadataSize := 0
+	switch elementType {
+	case mysql_proto.FieldType_TINY,
+		mysql_proto.FieldType_SHORT,
+		mysql_proto.FieldType_INT24,
+		mysql_proto.FieldType_LONG,
+		mysql_proto.FieldType_LONGLONG,
+		mysql_proto.FieldType_YEAR,
+		mysql_proto.FieldType_DATE,
+		mysql_proto.FieldType_NEWDATE,
+		mysql_proto.FieldType_FLOAT,
+		mysql_proto.FieldType_DOUBLE:
+		// no element metadata
+	case mysql_proto.FieldType_TIME2,
+		mysql_proto.FieldType_DATETIME2,
+		mysql_proto.FieldType_TIMESTAMP2:
+		// fractional seconds precision
+		elementMetadataSize = 1
+	case mysql_proto.FieldType_VARCHAR,
+		mysql_proto.FieldType_VAR_STRING,
+		mysql_proto.FieldType_STRING,
+		mysql_proto.FieldType_NEWDECIMAL:
+		// max length, or precision + decimals
+		elementMetadataSize = 2
+	default:
+		return nil, nil, errors.Newf(
+			"Unsupported typed array element type: %s (%d)",
+			elementType.String(),
+			elementType)
+	}
+
+	if len(metadata) < elementMetadataSize {
+		return nil, nil, errors.New("Metadata has too few bytes")
+	}
+
+	return &typedArrayFieldDescriptor{
+		packedLengthFieldDescriptor: packedLengthFieldDescriptor{
+			baseFieldDescriptor: baseFieldDescriptor{
+				fieldType:  TypedArrayFieldType,
+				isNullable: nullable,
+			},
+			packedLength: 4,
+		},
+		elementType:     elementType,
+		elementMetadata: metadata[:elementMetadataSize],
+	}, metadata[elementMetadataSize:], nil
+}
+
+// ElementType returns the array's element type.
+func (d *typedArrayFieldDescriptor) ElementType() mysql_proto.FieldType_Type {
+	return d.elementType
+}
+
+// ElementMetadata returns the array element's raw metadata bytes.
+func (d *typedArrayFieldDescriptor) ElementMetadata() []byte {
+	return d.elementMetadata
+}
+
+func (d *typedArrayFieldDescriptor) ParseValue(data []byte) (
+	value interface{},
+	remaining []byte,
+	err error) {
+
+	value, remaining, err = d.parseValue(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	elements, err := decodeJsonbArray(value.([]byte))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to decode typed array")
+	}
+
+	return elements, remaining, nil
+}
+
+// This decodes a binary json array of scalars.  Integers are decoded as
+// int64 / uint64, doubles as float64, strings as []byte, literals as nil /
+// true / false, and opaque values (e.g., decimal or temporal values) as the
+// opaque value's raw []byte.
+func decodeJsonbArray(data []byte) ([]interface{}, error) {
+	if len(data) < 1 {
+		return nil, errors.New("Empty json value")
+	}
+
+	offsetSize := 2
+	switch data[0] {
+	case jsonbSmallArray:
+	case jsonbLargeArray:
+		offsetSize = 4
+	default:
+		return nil, errors.Newf("Not a json array (type: %d)", data[0])
+	}
+
+	body := data[1:]
+	if len(body) < 2*offsetSize {
+		return nil, errors.New("Json array header has too few bytes")
+	}
+
+	count := bytesToLEUint(body[:offsetSize])
+	size := bytesToLEUint(body[offsetSize : 2*offsetSize])
+	if size > uint64(len(body)) {
+		return nil, errors.Newf(
+			"Json array size (%d) is larger than the value (%d)",
+			size,
+			len(body))
+	}
+	body = body[:size]
+
+	entrySize := uint64(1 + offsetSize)
+	if uint64(2*offsetSize)+count*entrySize > size {
+		return nil, errors.Newf("Invalid json array element count: %d", count)
+	}
+
+	elements := make([]interface{}, 0, count)
+	for i := uint64(0); i < count; i++ {
+		entry := body[uint64(2*offsetSize)+i*entrySize:]
+		valueType := entry[0]
+		inlined := entry[1 : 1+offsetSize]
+
+		var element interface{}
+		var err error
+
+		switch valueType {
+		case jsonbLiteral, jsonbInt16, jsonbUint16:
+			element, err = decodeJsonbScalar(valueType, inlined)
+		case jsonbInt32, jsonbUint32:
+			if offsetSize == 4 {
+				element, err = decodeJsonbScalar(valueType, inlined)
+				break
+			}
+			fallthrough
+		default:
+			offset := bytesToLEUint(inlined)
+			if offset >= size {
+				return nil, errors.Newf(
+					"Invalid json array element offset: %d",
+					offset)
+			}
+			element, err = decodeJsonbScalar(valueType, body[offset:])
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode element %d", i)
+		}
+
+		elements = append(elements, element)
+	}
+
+	return elements, nil
+}
+
+func decodeJsonbScalar(valueType byte, data []byte) (interface{}, error) {
+	fixedSize := 0
+	switch valueType {
+	case jsonbLiteral:
+		fixedSize = 1
+	case jsonbInt16, jsonbUint16:
+		fixedSize = 2
+	case jsonbInt32, jsonbUint32:
+		fixedSize = 4
+	case jsonbInt64, jsonbUint64, jsonbDouble:
+		fixedSize = 8
+	}
+
+	if len(data) < fixedSize {
+		return nil, errors.New("Json value has too few bytes")
+	}
+
+	switch valueType {
+	case jsonbLiteral:
+		switch data[0] {
+		case jsonbLiteralNull:
+			return nil, nil
+		case jsonbLiteralTrue:
+			return true, nil
+		case jsonbLiteralFalse:
+			return false, nil
+		}
+		return nil, errors.Newf("Invalid json literal: %d", data[0])
+	case jsonbInt16:
+		return int64(int16(LittleEndian.Uint16(data))), nil
+	case jsonbUint16:
+		return uint64(LittleEndian.Uint16(data)), nil
+	case jsonbInt32:
+		return int64(int32(LittleEndian.Uint32(data))), nil
+	case jsonbUint32:
+		return uint64(LittleEndian.Uint32(data)), nil
+	case jsonbInt64:
+		return int64(LittleEndian.Uint64(data)), nil
+	case jsonbUint64:
+		return LittleEndian.Uint64(data), nil
+	case jsonbDouble:
+		return LittleEndian.Float64(data), nil
+	case jsonbString:
+		value, _, err := readJsonbVariableLengthSlice(data)
+		return value, err
+	case jsonbOpaque:
+		// The opaque value's field type is followed by the value.
+		if len(data) < 1 {
+			return nil, errors.New("Json value has too few bytes")
+		}
+		value, _, err := readJsonbVariableLengthSlice(data[1:])
+		return value, err
+	}
+
+	return nil, errors.Newf("Unsupported json array element type: %d", valueType)
+}
+
+// The length is encoded using 7 bits per byte (least significant bits
+// first), where the high bit indicates whether more bytes follow.
+func readJsonbVariableLengthSlice(data []byte) (
+	value []byte,
+	remaining []byte,
+	err error) {
+
+	length := uint64(0)
+	for i := 0; i < 5; i++ {
+		if i >= len(data) {
+			return nil, nil, errors.New("Json value has too few bytes")
+		}
+
+		length |= uint64(data[i]&0x7f) << uint(7*i)
+		if data[i]&0x80 == 0 {
+			return readSlice(data[i+1:], int(length))
+		}
+	}
+
+	return nil, nil, errors.New("Invalid json variable length")
+}
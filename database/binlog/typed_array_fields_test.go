@@ -0,0 +1,239 @@
+package binlog
+
+import (
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+type TypedArrayFieldsSuite struct {
+	EventParserSuite
+}
+
+var _ = Suite(&TypedArrayFieldsSuite{})
+
+func typedArrayValueBytes(jsonb []byte) []byte {
+	size := len(jsonb)
+	return append(
+		[]byte{byte(size), byte(size >> 8), byte(size >> 16), byte(size >> 24)},
+		jsonb...)
+}
+
+// [1, -2, 5000000000]
+var testIntArrayJsonb = []byte{
+	jsonbSmallArray,
+	// count + size
+	3, 0, 21, 0,
+	// entries
+	jsonbInt16, 1, 0,
+	jsonbInt16, 0xfe, 0xff,
+	jsonbInt64, 13, 0,
+	// values
+	0x00, 0xf2, 0x05, 0x2a, 0x01, 0, 0, 0,
+}
+
+func (s *TypedArrayFieldsSuite) TestIntegerArray(c *C) {
+	fd, remaining, err := NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_LONGLONG), 42})
+	c.Assert(err, IsNil)
+	c.Check(remaining, DeepEquals, []byte{42})
+	c.Check(fd.Type(), Equals, TypedArrayFieldType)
+	c.Check(fd.IsNullable(), IsTrue)
+
+	d := fd.(*typedArrayFieldDescriptor)
+	c.Check(d.ElementType(), Equals, mysql_proto.FieldType_LONGLONG)
+	c.Check(d.ElementMetadata(), DeepEquals, []byte{})
+
+	value, remaining, err := fd.ParseValue(
+		append(typedArrayValueBytes(testIntArrayJsonb), 7))
+	c.Assert(err, IsNil)
+	c.Check(remaining, DeepEquals, []byte{7})
+	c.Check(
+		value,
+		DeepEquals,
+		[]interface{}{int64(1), int64(-2), int64(5000000000)})
+}
+
+func (s *TypedArrayFieldsSuite) TestStringArray(c *C) {
+	fd, remaining, err := NewTypedArrayFieldDescriptor(
+		NotNullable,
+		[]byte{byte(mysql_proto.FieldType_VARCHAR), 32, 0})
+	c.Assert(err, IsNil)
+	c.Check(remaining, DeepEquals, []byte{})
+	c.Check(
+		fd.(*typedArrayFieldDescriptor).ElementMetadata(),
+		DeepEquals,
+		[]byte{32, 0})
+
+	// ["ab", "c"]
+	jsonb := []byte{
+		jsonbSmallArray,
+		2, 0, 15, 0,
+		jsonbString, 10, 0,
+		jsonbString, 13, 0,
+		2, 'a', 'b',
+		1, 'c',
+	}
+
+	value, remaining, err := fd.ParseValue(typedArrayValueBytes(jsonb))
+	c.Assert(err, IsNil)
+	c.Check(remaining, DeepEquals, []byte{})
+	c.Check(
+		value,
+		DeepEquals,
+		[]interface{}{[]byte("ab"), []byte("c")})
+}
+
+func (s *TypedArrayFieldsSuite) TestLargeArrayAndEmptyArray(c *C) {
+	fd, _, err := NewTypedArrayFieldDescriptor(
+		NotNullable,
+		[]byte{byte(mysql_proto.FieldType_LONG)})
+	c.Assert(err, IsNil)
+
+	// [-70000, 4000000000] (int32 / uint32 are inlined in large arrays)
+	jsonb := []byte{
+		jsonbLargeArray,
+		2, 0, 0, 0, 18, 0, 0, 0,
+		jsonbInt32, 0x90, 0xee, 0xfe, 0xff,
+		jsonbUint32, 0x00, 0x28, 0x6b, 0xee,
+	}
+
+	value, _, err := fd.ParseValue(typedArrayValueBytes(jsonb))
+	c.Assert(err, IsNil)
+	c.Check(
+		value,
+		DeepEquals,
+		[]interface{}{int64(-70000), uint64(4000000000)})
+
+	value, _, err = fd.ParseValue(
+		typedArrayValueBytes([]byte{jsonbSmallArray, 0, 0, 4, 0}))
+	c.Assert(err, IsNil)
+	c.Check(value, DeepEquals, []interface{}{})
+}
+
+func (s *TypedArrayFieldsSuite) TestInvalidMetadata(c *C) {
+	_, _, err := NewTypedArrayFieldDescriptor(Nullable, []byte{})
+	c.Check(err, NotNil)
+
+	_, _, err = NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_BLOB), 2})
+	c.Check(err, NotNil)
+
+	_, _, err = NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_NEWDECIMAL), 10})
+	c.Check(err, NotNil)
+}
+
+func (s *TypedArrayFieldsSuite) TestInvalidValue(c *C) {
+	fd, _, err := NewTypedArrayFieldDescriptor(
+		Nullable,
+		[]byte{byte(mysql_proto.FieldType_LONGLONG)})
+	c.Assert(err, IsNil)
+
+	for _, jsonb := range [][]byte{
+		// Not an array.
+		{jsonbString, 1, 'a'},
+		// Truncated header.
+		{jsonbSmallArray, 1, 0},
+		// Size is larger than the value.
+		{jsonbSmallArray, 1, 0, 30, 0, jsonbInt16, 1, 0},
+		// Too many elements.
+		{jsonbSmallArray, 2, 0, 7, 0, jsonbInt16, 1, 0},
+		// Invalid offset.
+		{jsonbSmallArray, 1, 0, 7, 0, jsonbInt64, 100, 0},
+		// Nested array.
+		{jsonbSmallArray, 1, 0, 11, 0, jsonbSmallArray, 7, 0, 0, 0, 4, 0},
+	} {
+		_, _, err := fd.ParseValue(typedArrayValueBytes(jsonb))
+		c.Check(err, NotNil, Commentf("jsonb: %v", jsonb))
+	}
+
+	// Truncated value.
+	_, _, err = fd.ParseValue(typedArrayValueBytes(testIntArrayJsonb)[:10])
+	c.Check(err, NotNil)
+}
+
+// Table with a multi-valued index:
+//
+//	CREATE TABLE t (
+//	  id INT NOT NULL PRIMARY KEY,
+//	  zips BIGINT ARRAY (the multi-valued index's hidden column),
+//	  name VARCHAR(10)
+//	)
+func (s *TypedArrayFieldsSuite) TestWriteRows(c *C) {
+	s.WriteEvent(
+		mysql_proto.LogEventType_TABLE_MAP_EVENT,
+		uint16(0),
+		[]byte{
+			// table id + flags
+			35, 0, 0, 0, 0, 0,
+			1, 0,
+			// db length + name
+			2, 'd', 'b', 0,
+			// table length + name
+			1, 't', 0,
+			// # columns + column types
+			3,
+			3, byte(TypedArrayFieldType), 15,
+			// metadata
+			3,
+			8,     // typed array element type (bigint)
+			10, 0, // varchar
+			// null bits
+			6})
+
+	row := []byte{
+		// null bits
+		0,
+		// id
+		1, 0, 0, 0,
+	}
+	row = append(row, typedArrayValueBytes(testIntArrayJsonb)...)
+	row = append(row, 3, 'f', 'o', 'o')
+
+	s.WriteEvent(
+		mysql_proto.LogEventType_WRITE_ROWS_EVENT_V1,
+		uint16(0),
+		append(
+			[]byte{
+				// table id + row flags
+				35, 0, 0, 0, 0, 0,
+				1, 0,
+				// # columns + used columns bit map
+				3, 7,
+			},
+			row...))
+
+	event, err := s.NextEvent()
+	c.Assert(err, IsNil)
+
+	context, ok := event.(*TableMapEvent)
+	c.Assert(ok, IsTrue)
+	c.Check(
+		context.ColumnDescriptors()[1].Type(),
+		Equals,
+		TypedArrayFieldType)
+
+	s.parsers.SetTableContext(context)
+
+	event, err = s.NextEvent()
+	c.Assert(err, IsNil)
+
+	w, ok := event.(*WriteRowsEvent)
+	c.Assert(ok, IsTrue)
+
+	rows := w.InsertedRows()
+	c.Assert(len(rows), Equals, 1)
+	c.Check(
+		rows[0],
+		DeepEquals,
+		RowValues{
+			uint64(1),
+			[]interface{}{int64(1), int64(-2), int64(5000000000)},
+			[]byte("foo"),
+		})
+}
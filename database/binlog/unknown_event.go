@@ -0,0 +1,69 @@
+package binlog
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	mysql_proto "github.com/dropbox/godropbox/proto/mysql"
+)
+
+// UnknownEventMode controls how the parsed event reader handles event type
+// codes which are not modeled by this package (e.g., event types introduced
+// by newer mysql versions).  NOTE: Event types which are modeled but do not
+// have a registered parser are always returned as RawV4Event.
+type UnknownEventMode int
+
+const (
+	// Unknown events are returned as RawV4Event (the default).
+	PassThroughUnknownEvents UnknownEventMode = iota
+
+	// Unknown events are skipped over (by event size) and returned as
+	// UnknownEvent.
+	LenientUnknownEvents
+
+	// The reader returns the raw event along with an UnknownEventTypeError.
+	StrictUnknownEvents
+)
+
+// A representation of an event whose type code is not modeled by this
+// package.  The event's raw bytes are accessible via Bytes.
+type UnknownEvent struct {
+	Event
+}
+
+// TypeCode returns the event's type code.
+func (e *UnknownEvent) TypeCode() uint8 {
+	return uint8(e.EventType())
+}
+
+// This error is returned by readers configured with StrictUnknownEvents.
+type UnknownEventTypeError struct {
+	errors.DropboxError
+	TypeCode uint8
+}
+
+// MariaDB specific event types are only known in MariaDB streams.
+func isKnownEventType(t mysql_proto.LogEventType_Type, mariadb bool) bool {
+	if isMariadbEventType(t) {
+		return mariadb
+	}
+
+	_, ok := mysql_proto.LogEventType_Type_name[int32(t)]
+	return ok
+}
+
+func handleUnknownEvent(raw *RawV4Event, mode UnknownEventMode) (Event, error) {
+	switch mode {
+	case LenientUnknownEvents:
+		return &UnknownEvent{Event: raw}, nil
+	case StrictUnknownEvents:
+		return raw, &UnknownEventTypeError{
+			DropboxError: errors.Newf(
+				"Unknown event type %d for event at %s:%d",
+				raw.EventType(),
+				raw.SourceName(),
+				raw.SourcePosition()),
+			TypeCode: uint8(raw.EventType()),
+		}
+	}
+
+	return raw, nil
+}
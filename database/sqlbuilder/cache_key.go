@@ -0,0 +1,125 @@
+package sqlbuilder
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// CacheKey returns a deterministic cache key (hex encoded SHA-256 digest) for
+// the sql query and its arguments, suitable for keying query result caches.
+// Both the sql string and the arguments' types / values are included in the
+// key, e.g., int64(1) and "1" produce different keys.  driver.Valuer
+// arguments are hashed by their driver values, and pointer arguments are
+// hashed by the values they point to (nil pointers hash as nil).
+func CacheKey(sql string, args []interface{}) string {
+	h := sha256.New()
+
+	writeCacheKeyField(h, sql)
+
+	if args == nil {
+		_, _ = h.Write([]byte{0})
+	} else {
+		_, _ = h.Write([]byte{1})
+		writeCacheKeyLength(h, len(args))
+		for _, arg := range args {
+			typeName, value := serializeCacheKeyArg(arg)
+			writeCacheKeyField(h, typeName)
+			writeCacheKeyField(h, value)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Each field is length prefixed to ensure different sql / args combinations
+// cannot serialize into the same byte sequence.
+func writeCacheKeyField(h hash.Hash, field string) {
+	writeCacheKeyLength(h, len(field))
+	_, _ = h.Write([]byte(field))
+}
+
+func writeCacheKeyLength(h hash.Hash, length int) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(length))
+	_, _ = h.Write(buf[:n])
+}
+
+func serializeCacheKeyArg(arg interface{}) (typeName string, value string) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err == nil {
+			arg = v
+		}
+	}
+
+	// Pointers are dereferenced the same way sqltypes.BuildValue does;
+	// hashing the address would give identical queries different keys.
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return serializeCacheKeyArg(nil)
+		}
+		rv = rv.Elem()
+		arg = rv.Interface()
+	}
+
+	typeName = fmt.Sprintf("%T", arg)
+
+	switch v := arg.(type) {
+	case nil:
+		return typeName, ""
+	case bool:
+		return typeName, strconv.FormatBool(v)
+	case int:
+		return typeName, strconv.FormatInt(int64(v), 10)
+	case int8:
+		return typeName, strconv.FormatInt(int64(v), 10)
+	case int16:
+		return typeName, strconv.FormatInt(int64(v), 10)
+	case int32:
+		return typeName, strconv.FormatInt(int64(v), 10)
+	case int64:
+		return typeName, strconv.FormatInt(v, 10)
+	case uint:
+		return typeName, strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return typeName, strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return typeName, strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return typeName, strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return typeName, strconv.FormatUint(v, 10)
+	case float32:
+		return typeName, strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return typeName, strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return typeName, v
+	case []byte:
+		return typeName, string(v)
+	case time.Time:
+		// Equal instants in different locations are distinct sql values.
+		return typeName, v.Format(time.RFC3339Nano)
+	}
+
+	return typeName, fmt.Sprintf("%#v", arg)
+}
+
+// StatementCacheKey returns the cache key of the statement's generated sql
+// (see CacheKey).  Since the generated sql has all values inlined, two
+// statements share the same key iff they generate the same sql.
+func StatementCacheKey(stmt Statement, database string) (string, error) {
+	sql, err := stmt.String(database)
+	if err != nil {
+		return "", err
+	}
+	return CacheKey(sql, nil), nil
+}
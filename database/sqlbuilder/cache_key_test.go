@@ -0,0 +1,97 @@
+package sqlbuilder
+
+import (
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+type CacheKeySuite struct {
+}
+
+var _ = gc.Suite(&CacheKeySuite{})
+
+func (s *CacheKeySuite) TestDeterministic(c *gc.C) {
+	sql := "SELECT * FROM t WHERE a = ? AND b = ?"
+	args := []interface{}{int64(1), "foo"}
+
+	key := CacheKey(sql, args)
+	c.Assert(key, gc.HasLen, 64)
+	c.Assert(CacheKey(sql, []interface{}{int64(1), "foo"}), gc.Equals, key)
+}
+
+func (s *CacheKeySuite) TestDistinctKeys(c *gc.C) {
+	date := time.Date(1999, 1, 2, 3, 4, 5, 0, time.UTC)
+	sql := "SELECT * FROM t WHERE a = ?"
+
+	keys := []string{
+		CacheKey(sql, nil),
+		CacheKey(sql, []interface{}{}),
+		CacheKey(sql, []interface{}{nil}),
+		CacheKey(sql, []interface{}{int64(1)}),
+		CacheKey(sql, []interface{}{int32(1)}),
+		CacheKey(sql, []interface{}{"1"}),
+		CacheKey(sql, []interface{}{[]byte("1")}),
+		CacheKey(sql, []interface{}{int64(2)}),
+		CacheKey(sql, []interface{}{true}),
+		CacheKey(sql, []interface{}{1.5}),
+		CacheKey(sql, []interface{}{date}),
+		CacheKey(sql, []interface{}{date.Add(time.Nanosecond)}),
+		CacheKey(sql, []interface{}{"a", "b"}),
+		CacheKey(sql, []interface{}{"ab"}),
+		CacheKey(sql+"?", []interface{}{"b"}),
+		CacheKey("SELECT * FROM t WHERE a = ?a", []interface{}{"b"}),
+		CacheKey("SELECT * FROM t", nil),
+	}
+
+	seen := make(map[string]int)
+	for i, key := range keys {
+		prev, ok := seen[key]
+		c.Assert(ok, gc.Equals, false, gc.Commentf("%d collides with %d", i, prev))
+		seen[key] = i
+	}
+}
+
+func (s *CacheKeySuite) TestSelectStatement(c *gc.C) {
+	q := table1.Select(table1Col1)
+
+	key, err := q.CacheKey("db")
+	c.Assert(err, gc.IsNil)
+
+	sql, err := q.String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(key, gc.Equals, CacheKey(sql, nil))
+
+	other, err := q.Copy().Where(GtL(table1Col1, 123)).CacheKey("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(other, gc.Not(gc.Equals), key)
+
+	other, err = q.CacheKey("db2")
+	c.Assert(err, gc.IsNil)
+	c.Assert(other, gc.Not(gc.Equals), key)
+
+	_, err = table1.Select().CacheKey("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *CacheKeySuite) TestPointerArgs(c *gc.C) {
+	sql := "SELECT * FROM t WHERE a = ? AND b = ?"
+
+	str1 := "foo"
+	str2 := "foo"
+	num1 := int64(1)
+	num2 := int64(1)
+
+	key := CacheKey(sql, []interface{}{&str1, &num1})
+	c.Assert(CacheKey(sql, []interface{}{&str2, &num2}), gc.Equals, key)
+	c.Assert(CacheKey(sql, []interface{}{"foo", int64(1)}), gc.Equals, key)
+
+	str2 = "bar"
+	c.Assert(CacheKey(sql, []interface{}{&str2, &num2}), gc.Not(gc.Equals), key)
+
+	var nilStr *string
+	c.Assert(
+		CacheKey(sql, []interface{}{nilStr, nil}),
+		gc.Equals,
+		CacheKey(sql, []interface{}{nil, nil}))
+}
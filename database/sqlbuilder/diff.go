@@ -0,0 +1,389 @@
+package sqlbuilder
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// DiffKind identifies which part of a select statement a DiffItem refers to.
+type DiffKind int
+
+const (
+	// A FROM / JOIN table.
+	TableDiff DiffKind = iota
+
+	// A selected column (projection).
+	ColumnDiff
+
+	// A WHERE predicate (i.e., a top level AND operand).
+	PredicateDiff
+
+	// An ORDER BY clause.
+	OrderDiff
+
+	// Any other clause (GROUP BY, LIMIT, DISTINCT, locking, comment).
+	ClauseDiff
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case TableDiff:
+		return "table"
+	case ColumnDiff:
+		return "column"
+	case PredicateDiff:
+		return "predicate"
+	case OrderDiff:
+		return "order"
+	case ClauseDiff:
+		return "clause"
+	}
+	return "unknown(" + strconv.Itoa(int(k)) + ")"
+}
+
+// DiffItem is a single structural difference between two select statements.
+type DiffItem struct {
+	Kind DiffKind
+
+	// A human readable description of the difference, e.g.,
+	// "JOIN `users` ON `users`.`id`=`posts`.`user_id`" for an added join, or
+	// "`posts`.`id`=1 -> `posts`.`id` IN (1,2)" for a changed predicate.
+	Description string
+}
+
+func (i DiffItem) String() string {
+	return i.Kind.String() + ": " + i.Description
+}
+
+// QueryDiff is the structural difference between two select statements (see
+// Diff).
+type QueryDiff struct {
+	added   []DiffItem
+	removed []DiffItem
+	changed []DiffItem
+}
+
+// Added returns the items which are only in the second statement.
+func (d *QueryDiff) Added() []DiffItem {
+	return d.added
+}
+
+// Removed returns the items which are only in the first statement.
+func (d *QueryDiff) Removed() []DiffItem {
+	return d.removed
+}
+
+// Changed returns the items which are in both statements, but differ.
+func (d *QueryDiff) Changed() []DiffItem {
+	return d.changed
+}
+
+// IsEmpty returns true if the statements are structurally equal.
+func (d *QueryDiff) IsEmpty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+// String returns a human readable summary of the diff (one item per line).
+func (d *QueryDiff) String() string {
+	buf := &bytes.Buffer{}
+	for _, item := range d.added {
+		_, _ = buf.WriteString("added " + item.String() + "\n")
+	}
+	for _, item := range d.removed {
+		_, _ = buf.WriteString("removed " + item.String() + "\n")
+	}
+	for _, item := range d.changed {
+		_, _ = buf.WriteString("changed " + item.String() + "\n")
+	}
+	return buf.String()
+}
+
+// Diff returns the structural difference between two select statements.
+// This is mainly a debugging / testing tool, e.g., for verifying that a
+// refactored query builder generates the intended queries.
+//
+// The statements are compared part by part rather than by their generated
+// sql: tables are compared by name (changing a table's join type or join
+// condition is reported as a change), WHERE predicates are compared per top
+// level AND operand (changing a comparison's / IN expression's right hand
+// side is reported as a change), and ORDER BY clauses are compared per
+// ordered expression (changing the direction is reported as a change).
+// Items are matched regardless of their positions.
+func Diff(a SelectStatement, b SelectStatement) (*QueryDiff, error) {
+	aEntries, err := diffEntries(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to diff first statement")
+	}
+
+	bEntries, err := diffEntries(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to diff second statement")
+	}
+
+	diff := &QueryDiff{}
+	for kind := TableDiff; kind <= ClauseDiff; kind++ {
+		diff.diff(kind, aEntries[kind], bEntries[kind])
+	}
+	return diff, nil
+}
+
+// A comparable part of a statement.  Entries with the same key are compared
+// by their descriptions.
+type diffEntry struct {
+	key         string
+	description string
+}
+
+func (d *QueryDiff) diff(kind DiffKind, a []diffEntry, b []diffEntry) {
+	// Entries with duplicate keys are matched in order.
+	unmatched := make(map[string][]int)
+	for idx, entry := range b {
+		unmatched[entry.key] = append(unmatched[entry.key], idx)
+	}
+
+	matched := make([]bool, len(b))
+	for _, entry := range a {
+		indices := unmatched[entry.key]
+		if len(indices) == 0 {
+			d.removed = append(d.removed, DiffItem{kind, entry.description})
+			continue
+		}
+
+		other := b[indices[0]]
+		unmatched[entry.key] = indices[1:]
+		matched[indices[0]] = true
+
+		if entry.description != other.description {
+			d.changed = append(d.changed, DiffItem{
+				Kind:        kind,
+				Description: entry.description + " -> " + other.description,
+			})
+		}
+	}
+
+	for idx, entry := range b {
+		if !matched[idx] {
+			d.added = append(d.added, DiffItem{kind, entry.description})
+		}
+	}
+}
+
+func diffEntries(stmt SelectStatement) (map[DiffKind][]diffEntry, error) {
+	q, ok := stmt.(*selectStatementImpl)
+	if !ok {
+		return nil, errors.Newf("Unsupported select statement: %T", stmt)
+	}
+
+	entries := make(map[DiffKind][]diffEntry)
+
+	if q.table == nil {
+		return nil, errors.New("nil table")
+	}
+	tables, err := tableDiffEntries(q.table, "FROM ")
+	if err != nil {
+		return nil, err
+	}
+	entries[TableDiff] = tables
+
+	for _, projection := range q.projections {
+		if projection == nil {
+			return nil, errors.New("nil column selected")
+		}
+
+		buf := &bytes.Buffer{}
+		err := projection.SerializeSqlForColumnList(buf)
+		if err != nil {
+			return nil, err
+		}
+		entries[ColumnDiff] = append(
+			entries[ColumnDiff],
+			diffEntry{buf.String(), buf.String()})
+	}
+
+	if q.where != nil {
+		predicates := []BoolExpression{q.where}
+		conj, ok := q.where.(*conjunctExpression)
+		if ok && string(conj.conjunction) == " AND " {
+			predicates = conj.expressions
+		}
+
+		for _, predicate := range predicates {
+			entry, err := predicateDiffEntry(predicate)
+			if err != nil {
+				return nil, err
+			}
+			entries[PredicateDiff] = append(entries[PredicateDiff], entry)
+		}
+	}
+
+	if q.order != nil {
+		for _, clause := range q.order.clauses {
+			entry, err := orderDiffEntry(clause)
+			if err != nil {
+				return nil, err
+			}
+			entries[OrderDiff] = append(entries[OrderDiff], entry)
+		}
+	}
+
+	clauses, err := clauseDiffEntries(q)
+	if err != nil {
+		return nil, err
+	}
+	entries[ClauseDiff] = clauses
+
+	return entries, nil
+}
+
+func serializeClause(clause Clause) (string, error) {
+	if clause == nil {
+		return "", errors.New("nil clause")
+	}
+
+	buf := &bytes.Buffer{}
+	err := clause.SerializeSql(buf)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// This flattens the (possibly joined) table into its tables.  The first
+// table's description is prefixed by prefix.
+func tableDiffEntries(
+	table ReadableTable,
+	prefix string) ([]diffEntry, error) {
+
+	switch t := table.(type) {
+	case *Table:
+		description := prefix + "`" + t.Name() + "`"
+		if t.forcedIndex != "" {
+			description += " FORCE INDEX (`" + t.forcedIndex + "`)"
+		}
+		return []diffEntry{{t.Name(), description}}, nil
+	case *joinTable:
+		if t.lhs == nil || t.rhs == nil {
+			return nil, errors.New("nil join table")
+		}
+
+		lhs, err := tableDiffEntries(t.lhs, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		joinPrefix := "JOIN "
+		switch t.join_type {
+		case LEFT_JOIN:
+			joinPrefix = "LEFT JOIN "
+		case RIGHT_JOIN:
+			joinPrefix = "RIGHT JOIN "
+		}
+
+		rhs, err := tableDiffEntries(t.rhs, joinPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		on, err := serializeClause(t.onCondition)
+		if err != nil {
+			return nil, err
+		}
+		rhs[len(rhs)-1].description += " ON " + on
+
+		return append(lhs, rhs...), nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := table.SerializeSql("", buf)
+	if err != nil {
+		return nil, err
+	}
+	return []diffEntry{{buf.String(), prefix + buf.String()}}, nil
+}
+
+// Comparisons and IN expressions are keyed by their left hand side, hence
+// modifying the right hand side is reported as a change.
+func predicateDiffEntry(predicate BoolExpression) (diffEntry, error) {
+	description, err := serializeClause(predicate)
+	if err != nil {
+		return diffEntry{}, err
+	}
+
+	var lhs Expression
+	switch p := predicate.(type) {
+	case *boolExpression:
+		lhs = p.lhs
+	case *inExpression:
+		lhs = p.lhs
+	default:
+		return diffEntry{description, description}, nil
+	}
+
+	key, err := serializeClause(lhs)
+	if err != nil {
+		return diffEntry{}, err
+	}
+	return diffEntry{key, description}, nil
+}
+
+func orderDiffEntry(clause Clause) (diffEntry, error) {
+	description, err := serializeClause(clause)
+	if err != nil {
+		return diffEntry{}, err
+	}
+
+	order, ok := clause.(*orderByClause)
+	if !ok {
+		return diffEntry{description, description}, nil
+	}
+
+	key, err := serializeClause(order.expression)
+	if err != nil {
+		return diffEntry{}, err
+	}
+	return diffEntry{key, description}, nil
+}
+
+func clauseDiffEntries(q *selectStatementImpl) ([]diffEntry, error) {
+	var entries []diffEntry
+
+	if q.group != nil {
+		for _, clause := range q.group.clauses {
+			sql, err := serializeClause(clause)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, diffEntry{
+				key:         "GROUP BY " + sql,
+				description: "GROUP BY " + sql,
+			})
+		}
+	}
+
+	if q.limit >= 0 {
+		description := fmt.Sprintf("LIMIT %d", q.limit)
+		if q.offset >= 0 {
+			description += fmt.Sprintf(" OFFSET %d", q.offset)
+		}
+		entries = append(entries, diffEntry{"LIMIT", description})
+	}
+
+	if q.distinct {
+		entries = append(entries, diffEntry{"DISTINCT", "DISTINCT"})
+	}
+
+	if q.forUpdate {
+		entries = append(entries, diffEntry{"LOCK", "FOR UPDATE"})
+	} else if q.withSharedLock {
+		entries = append(entries, diffEntry{"LOCK", "LOCK IN SHARE MODE"})
+	}
+
+	if q.comment != "" {
+		entries = append(entries, diffEntry{"COMMENT", "/* " + q.comment + " */"})
+	}
+
+	return entries, nil
+}
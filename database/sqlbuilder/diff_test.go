@@ -0,0 +1,159 @@
+package sqlbuilder
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type DiffSuite struct {
+}
+
+var _ = gc.Suite(&DiffSuite{})
+
+func (s *DiffSuite) TestEqual(c *gc.C) {
+	a := table1.Select(table1Col1, table1Col2).
+		Where(And(EqL(table1Col1, 1), GtL(table1Col2, 2))).
+		OrderBy(Desc(table1Col2)).
+		Limit(10)
+	b := a.Copy()
+
+	diff, err := Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.IsEmpty(), gc.Equals, true)
+	c.Assert(diff.Added(), gc.HasLen, 0)
+	c.Assert(diff.Removed(), gc.HasLen, 0)
+	c.Assert(diff.Changed(), gc.HasLen, 0)
+	c.Assert(diff.String(), gc.Equals, "")
+}
+
+func (s *DiffSuite) TestTablesAndColumns(c *gc.C) {
+	a := table1.Select(table1Col1, table1Col2)
+	b := table1.InnerJoinOn(table2, Eq(table1Col3, table2Col3)).
+		Select(table1Col1, table2Col4)
+
+	diff, err := Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Added(), gc.DeepEquals, []DiffItem{
+		{TableDiff, "JOIN `table2` ON `table1`.`col3`=`table2`.`col3`"},
+		{ColumnDiff, "`table2`.`col4`"},
+	})
+	c.Assert(diff.Removed(), gc.DeepEquals, []DiffItem{
+		{ColumnDiff, "`table1`.`col2`"},
+	})
+	c.Assert(diff.Changed(), gc.HasLen, 0)
+
+	// Changing the join type / condition is a change.
+	a = table1.InnerJoinOn(table2, Eq(table1Col3, table2Col3)).
+		LeftJoinOn(table3, Eq(table1Col1, table3Col1)).
+		Select(table1Col1)
+	b = table1.LeftJoinOn(table2, Eq(table1Col3, table2Col3)).
+		InnerJoinOn(table3, Eq(table1Col1, table3Col1)).
+		Select(table1Col1)
+
+	diff, err = Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Added(), gc.HasLen, 0)
+	c.Assert(diff.Removed(), gc.HasLen, 0)
+	c.Assert(diff.Changed(), gc.DeepEquals, []DiffItem{
+		{
+			TableDiff,
+			"JOIN `table2` ON `table1`.`col3`=`table2`.`col3` -> " +
+				"LEFT JOIN `table2` ON `table1`.`col3`=`table2`.`col3`",
+		},
+		{
+			TableDiff,
+			"LEFT JOIN `table3` ON `table1`.`col1`=`table3`.`col1` -> " +
+				"JOIN `table3` ON `table1`.`col1`=`table3`.`col1`",
+		},
+	})
+}
+
+func (s *DiffSuite) TestPredicates(c *gc.C) {
+	a := table1.Select(table1Col1).
+		Where(And(
+			EqL(table1Col1, 1),
+			GtL(table1Col2, 2),
+			Not(EqL(table1Col3, 3))))
+	b := table1.Select(table1Col1).
+		Where(And(
+			In(table1Col1, []int{1, 2}),
+			GtL(table1Col2, 2),
+			LtL(table1Col4, 5)))
+
+	diff, err := Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Added(), gc.DeepEquals, []DiffItem{
+		{PredicateDiff, "`table1`.`col4`<5"},
+	})
+	c.Assert(diff.Removed(), gc.DeepEquals, []DiffItem{
+		{PredicateDiff, "NOT (`table1`.`col3`=3)"},
+	})
+	c.Assert(diff.Changed(), gc.DeepEquals, []DiffItem{
+		{PredicateDiff, "`table1`.`col1`=1 -> `table1`.`col1` IN (1,2)"},
+	})
+
+	// A single (non-AND) predicate.
+	diff, err = Diff(
+		table1.Select(table1Col1),
+		table1.Select(table1Col1).Where(
+			Or(EqL(table1Col1, 1), EqL(table1Col2, 2))))
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Added(), gc.DeepEquals, []DiffItem{
+		{PredicateDiff, "(`table1`.`col1`=1 OR `table1`.`col2`=2)"},
+	})
+	c.Assert(diff.Removed(), gc.HasLen, 0)
+	c.Assert(diff.Changed(), gc.HasLen, 0)
+}
+
+func (s *DiffSuite) TestOrderAndClauses(c *gc.C) {
+	a := table1.Select(table1Col1).
+		OrderBy(Asc(table1Col1), table1Col2).
+		GroupBy(table1Col1).
+		Limit(10).
+		ForUpdate()
+	b := table1.Select(table1Col1).
+		OrderBy(Desc(table1Col1), Asc(table1Col3)).
+		Limit(20).
+		Offset(5).
+		Distinct().
+		WithSharedLock().
+		Comment("foo")
+
+	diff, err := Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Added(), gc.DeepEquals, []DiffItem{
+		{OrderDiff, "`table1`.`col3` ASC"},
+		{ClauseDiff, "DISTINCT"},
+		{ClauseDiff, "/* foo */"},
+	})
+	c.Assert(diff.Removed(), gc.DeepEquals, []DiffItem{
+		{OrderDiff, "`table1`.`col2`"},
+		{ClauseDiff, "GROUP BY `table1`.`col1`"},
+	})
+	c.Assert(diff.Changed(), gc.DeepEquals, []DiffItem{
+		{OrderDiff, "`table1`.`col1` ASC -> `table1`.`col1` DESC"},
+		{ClauseDiff, "LIMIT 10 -> LIMIT 20 OFFSET 5"},
+		{ClauseDiff, "FOR UPDATE -> LOCK IN SHARE MODE"},
+	})
+
+	c.Assert(
+		diff.String(),
+		gc.Equals,
+		"added order: `table1`.`col3` ASC\n"+
+			"added clause: DISTINCT\n"+
+			"added clause: /* foo */\n"+
+			"removed order: `table1`.`col2`\n"+
+			"removed clause: GROUP BY `table1`.`col1`\n"+
+			"changed order: `table1`.`col1` ASC -> `table1`.`col1` DESC\n"+
+			"changed clause: LIMIT 10 -> LIMIT 20 OFFSET 5\n"+
+			"changed clause: FOR UPDATE -> LOCK IN SHARE MODE\n")
+}
+
+func (s *DiffSuite) TestInvalidStatement(c *gc.C) {
+	_, err := Diff(
+		table1.Select(table1Col1),
+		table1.Select(table1Col1).Where(In(table1Col1, "invalid")))
+	c.Assert(err, gc.NotNil)
+
+	_, err = Diff(table1.Select(nil), table1.Select(table1Col1))
+	c.Assert(err, gc.NotNil)
+}
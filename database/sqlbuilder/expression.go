@@ -4,6 +4,7 @@ package sqlbuilder
 import (
 	"bytes"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -405,6 +406,59 @@ func RegexpL(lhs Expression, val string) BoolExpression {
 	return Regexp(lhs, Literal(val))
 }
 
+func NotRegexp(lhs, rhs Expression) BoolExpression {
+	return newBoolExpression(lhs, rhs, []byte(" NOT REGEXP "))
+}
+
+func NotRegexpL(lhs Expression, val string) BoolExpression {
+	return NotRegexp(lhs, Literal(val))
+}
+
+// Representation of REGEXP predicates whose pattern was validated when the
+// expression was constructed.
+type strictRegexpExpression struct {
+	isExpression
+	isBoolExpression
+
+	expr BoolExpression
+	err  error
+}
+
+func (c *strictRegexpExpression) SerializeSql(out *bytes.Buffer) error {
+	if c.err != nil {
+		return errors.Wrapf(
+			c.err,
+			"Invalid REGEXP pattern.  Generated sql: %s",
+			out.String())
+	}
+	return c.expr.SerializeSql(out)
+}
+
+func newStrictRegexpExpression(
+	expr BoolExpression,
+	pattern string) BoolExpression {
+
+	_, err := regexp.Compile(pattern)
+	return &strictRegexpExpression{
+		expr: expr,
+		err:  err,
+	}
+}
+
+// Same as RegexpL, but the pattern must also be a valid go regexp (the
+// expression's serialization will fail otherwise).  This is useful for
+// catching malformed patterns early.  NOTE: mysql supports syntax that go does
+// not (e.g., [[:<:]] word boundaries in 5.x), use RegexpL for those patterns.
+func RegexpStrictL(lhs Expression, pattern string) BoolExpression {
+	return newStrictRegexpExpression(RegexpL(lhs, pattern), pattern)
+}
+
+// Same as NotRegexpL, but the pattern must also be a valid go regexp (the
+// expression's serialization will fail otherwise).
+func NotRegexpStrictL(lhs Expression, pattern string) BoolExpression {
+	return newStrictRegexpExpression(NotRegexpL(lhs, pattern), pattern)
+}
+
 // Returns a representation of "c[0] + ... + c[n-1]" for c in clauses
 func Add(expressions ...Expression) Expression {
 	return &arithmeticExpression{
@@ -730,3 +784,142 @@ func (cv *columnValueExpression) SerializeSql(out *bytes.Buffer) error {
 	_ = out.WriteByte(')')
 	return nil
 }
+
+// Representation of the json column path operators, col->path and col->>path.
+type jsonPathExpression struct {
+	isExpression
+	column   NonAliasColumn
+	path     string
+	operator []byte
+}
+
+func (c *jsonPathExpression) SerializeSql(out *bytes.Buffer) error {
+	if c.column == nil {
+		return errors.Newf("nil json column.  Generated sql: %s", out.String())
+	}
+	if !validJsonPath(c.path) {
+		return errors.Newf(
+			"Invalid json path: %s.  Generated sql: %s",
+			c.path,
+			out.String())
+	}
+
+	if err := c.column.SerializeSql(out); err != nil {
+		return err
+	}
+	_, _ = out.Write(c.operator)
+	return Literal(c.path).SerializeSql(out)
+}
+
+// A (very) basic sanity check; mysql will validate the rest of the path.
+func validJsonPath(path string) bool {
+	return strings.HasPrefix(path, "$")
+}
+
+// Returns a representation of "col->'path'" (i.e., JSON_EXTRACT(col, 'path')).
+// NOTE: mysql only supports the operator on columns.  Use JsonExtract for
+// other expressions.
+func JsonColumnPath(col NonAliasColumn, path string) Expression {
+	return &jsonPathExpression{
+		column:   col,
+		path:     path,
+		operator: []byte("->"),
+	}
+}
+
+// Returns a representation of "col->>'path'" (i.e.,
+// JSON_UNQUOTE(JSON_EXTRACT(col, 'path'))).
+func JsonColumnPathUnquote(col NonAliasColumn, path string) Expression {
+	return &jsonPathExpression{
+		column:   col,
+		path:     path,
+		operator: []byte("->>"),
+	}
+}
+
+// Representation of json functions which take path arguments.
+type jsonFuncExpression struct {
+	isExpression
+	isBoolExpression
+
+	funcName string
+	args     []Expression
+	paths    []string
+
+	err error
+}
+
+func (c *jsonFuncExpression) SerializeSql(out *bytes.Buffer) error {
+	if c.err != nil {
+		return errors.Wrapf(
+			c.err,
+			"Invalid %s expression.  Generated sql: %s",
+			c.funcName,
+			out.String())
+	}
+
+	for _, path := range c.paths {
+		if !validJsonPath(path) {
+			return errors.Newf(
+				"Invalid json path: %s.  Generated sql: %s",
+				path,
+				out.String())
+		}
+	}
+
+	args := make([]Expression, 0, len(c.args)+len(c.paths))
+	args = append(args, c.args...)
+	for _, path := range c.paths {
+		args = append(args, Literal(path))
+	}
+
+	return SqlFunc(c.funcName, args...).SerializeSql(out)
+}
+
+// Returns a representation of "JSON_EXTRACT(expr, 'path[0]', ...)".
+// At least one path must be specified.
+func JsonExtract(expr Expression, paths ...string) Expression {
+	e := &jsonFuncExpression{
+		funcName: "JSON_EXTRACT",
+		args:     []Expression{expr},
+		paths:    paths,
+	}
+	if len(paths) == 0 {
+		e.err = errors.New("JsonExtract requires at least one path")
+	}
+	return e
+}
+
+// Returns a representation of "JSON_UNQUOTE(expr)".
+func JsonUnquote(expr Expression) Expression {
+	return SqlFunc("JSON_UNQUOTE", expr)
+}
+
+// Returns a representation of "JSON_CONTAINS(target, candidate[, 'path'])".
+// At most one path may be specified.
+func JsonContains(
+	target Expression,
+	candidate Expression,
+	path ...string) BoolExpression {
+
+	e := &jsonFuncExpression{
+		funcName: "JSON_CONTAINS",
+		args:     []Expression{target, candidate},
+		paths:    path,
+	}
+	if len(path) > 1 {
+		e.err = errors.Newf(
+			"JsonContains accepts at most one path (got %d)",
+			len(path))
+	}
+	return e
+}
+
+// Same as JsonContains, but the candidate is a json document string literal.
+func JsonContainsL(
+	target Expression,
+	candidate string,
+	path ...string) BoolExpression {
+
+	return JsonContains(target, Literal(candidate), path...)
+}
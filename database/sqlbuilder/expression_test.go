@@ -93,6 +93,157 @@ func (s *ExprSuite) TestRegexExpr(c *gc.C) {
 
 }
 
+func (s *ExprSuite) TestNotRegexExpr(c *gc.C) {
+	expr := NotRegexpL(table1Col1, "^foo")
+
+	buf := &bytes.Buffer{}
+
+	err := expr.SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+
+	sql := buf.String()
+	c.Assert(sql, gc.Equals, "`table1`.`col1` NOT REGEXP '^foo'")
+}
+
+func (s *ExprSuite) TestStrictRegexExpr(c *gc.C) {
+	expr := RegexpStrictL(table1Col1, "^it's [a-z]+$")
+
+	buf := &bytes.Buffer{}
+
+	err := expr.SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+
+	sql := buf.String()
+	c.Assert(sql, gc.Equals, "`table1`.`col1` REGEXP '^it\\'s [a-z]+$'")
+
+	expr = NotRegexpStrictL(table1Col1, "^foo\\d")
+
+	buf = &bytes.Buffer{}
+
+	err = expr.SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+
+	sql = buf.String()
+	c.Assert(sql, gc.Equals, "`table1`.`col1` NOT REGEXP '^foo\\\\d'")
+}
+
+func (s *ExprSuite) TestStrictRegexExprInvalidPattern(c *gc.C) {
+	buf := &bytes.Buffer{}
+	err := RegexpStrictL(table1Col1, "(foo").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	buf = &bytes.Buffer{}
+	err = NotRegexpStrictL(table1Col1, "[[:<:]]foo").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	_, err = table1.Select(table1Col1).
+		Where(RegexpStrictL(table1Col1, "*")).
+		String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ExprSuite) TestJsonColumnPath(c *gc.C) {
+	buf := &bytes.Buffer{}
+
+	err := JsonColumnPath(table1Col1, "$.a").SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, "`table1`.`col1`->'$.a'")
+
+	buf = &bytes.Buffer{}
+
+	err = JsonColumnPathUnquote(table1Col1, "$.it's").SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, "`table1`.`col1`->>'$.it\\'s'")
+}
+
+func (s *ExprSuite) TestJsonExtract(c *gc.C) {
+	buf := &bytes.Buffer{}
+
+	err := JsonExtract(table1Col1, "$.a", "$.b[0]").SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"JSON_EXTRACT(`table1`.`col1`,'$.a','$.b[0]')")
+
+	buf = &bytes.Buffer{}
+
+	err = JsonUnquote(JsonExtract(table1Col1, "$.a")).SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"JSON_UNQUOTE(JSON_EXTRACT(`table1`.`col1`,'$.a'))")
+}
+
+func (s *ExprSuite) TestJsonContains(c *gc.C) {
+	buf := &bytes.Buffer{}
+
+	err := JsonContainsL(table1Col1, `{"a": 1}`).SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"JSON_CONTAINS(`table1`.`col1`,'{\\\"a\\\": 1}')")
+
+	buf = &bytes.Buffer{}
+
+	err = JsonContains(table1Col1, table1Col2, "$.a").SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"JSON_CONTAINS(`table1`.`col1`,`table1`.`col2`,'$.a')")
+}
+
+func (s *ExprSuite) TestJsonInvalidPath(c *gc.C) {
+	buf := &bytes.Buffer{}
+	err := JsonColumnPath(table1Col1, "a").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	buf = &bytes.Buffer{}
+	err = JsonExtract(table1Col1, "$.a", "").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	buf = &bytes.Buffer{}
+	err = JsonContainsL(table1Col1, "1", "a").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ExprSuite) TestJsonInvalidPathCount(c *gc.C) {
+	buf := &bytes.Buffer{}
+	err := JsonExtract(table1Col1).SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	buf = &bytes.Buffer{}
+	err = JsonContainsL(table1Col1, "1", "$.a", "$.b").SerializeSql(buf)
+	c.Assert(err, gc.NotNil)
+
+	q := table1.Select(table1Col1).Where(
+		JsonContainsL(table1Col2, "2", "$.a", "$.b"))
+	_, err = q.String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ExprSuite) TestJsonSelectWhere(c *gc.C) {
+	q := table1.Select(
+		table1Col1,
+		Alias("a", JsonColumnPathUnquote(table1Col2, "$.a"))).
+		Where(And(
+			EqL(JsonColumnPath(table1Col2, "$.b"), 1),
+			JsonContainsL(table1Col2, "2", "$.c")))
+
+	sql, err := q.String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1`,(`table1`.`col2`->>'$.a') AS `a` "+
+			"FROM `db`.`table1` "+
+			"WHERE (`table1`.`col2`->'$.b'=1 AND "+
+			"JSON_CONTAINS(`table1`.`col2`,'2','$.c'))")
+}
+
 func (s *ExprSuite) TestAndExpr(c *gc.C) {
 	expr := And(EqL(table1Col1, 1), EqL(table1Col2, 2), EqL(table1Col3, 3))
 
@@ -0,0 +1,468 @@
+package sqlbuilder
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Format reformats a (flat) sql statement into a human readable multi-line
+// form, which is useful for logging / debugging generated statements.  Each
+// clause (SELECT, FROM, WHERE, ORDER BY, VALUES, SET, etc.) starts on its own
+// line, joins are indented under the FROM clause, and join conditions are
+// further indented under their joins.  Subqueries (and parenthesized UNION
+// operands) are indented by their nesting level.  Keywords are capitalized,
+// and whitespace between tokens is normalized.
+//
+// Format only tokenizes the statement; it does not validate the statement,
+// nor does it interpret the values.  Identifiers, string literals, comments
+// and ? placeholders are copied verbatim.  Format is not a sql parser, hence
+// the output is only intended to be read by humans.
+func Format(sql string) string {
+	tokens := tokenizeSql(sql)
+
+	f := &sqlFormatter{}
+	for i := 0; i < len(tokens); i++ {
+		i += f.writeToken(tokens, i)
+	}
+
+	return f.buf.String()
+}
+
+type sqlTokenKind int
+
+const (
+	wordToken        sqlTokenKind = iota // keywords, names, numbers.
+	identifierToken                      // names containing backquotes.
+	stringToken                          // quoted string literals.
+	commentToken                         // /* ... */, -- ... and # ...
+	operatorToken                        // =, <=, +, etc.
+	placeholderToken                     // ?
+	openParenToken
+	closeParenToken
+	commaToken
+	semicolonToken
+)
+
+type sqlToken struct {
+	kind  sqlTokenKind
+	value string
+}
+
+// This splits the sql statement into tokens, dropping all whitespace.
+func tokenizeSql(sql string) []sqlToken {
+	tokens := []sqlToken{}
+
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+		start := i
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+		case ch == '\'' || ch == '"':
+			i = scanQuoted(sql, i)
+			tokens = append(tokens, sqlToken{stringToken, sql[start:i]})
+		case ch == '#' ||
+			(ch == '-' && strings.HasPrefix(sql[i:], "-- ")):
+
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			tokens = append(tokens, sqlToken{commentToken, sql[start:i]})
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			tokens = append(tokens, sqlToken{commentToken, sql[start:i]})
+		case ch == '`' || isSqlWordChar(ch):
+			kind := wordToken
+			for i < len(sql) {
+				if sql[i] == '`' {
+					kind = identifierToken
+					i = scanQuoted(sql, i)
+				} else if isSqlWordChar(sql[i]) ||
+					(sql[i] == '*' && sql[i-1] == '.') || // e.g., `t`.*
+					isSqlExponentSign(sql, start, i) {
+
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, sqlToken{kind, sql[start:i]})
+		case ch == '?':
+			i++
+			tokens = append(tokens, sqlToken{placeholderToken, "?"})
+		case ch == '(':
+			i++
+			tokens = append(tokens, sqlToken{openParenToken, "("})
+		case ch == ')':
+			i++
+			tokens = append(tokens, sqlToken{closeParenToken, ")"})
+		case ch == ',':
+			i++
+			tokens = append(tokens, sqlToken{commaToken, ","})
+		case ch == ';':
+			i++
+			tokens = append(tokens, sqlToken{semicolonToken, ";"})
+		default:
+			i++
+			for _, op := range multiCharSqlOperators {
+				if strings.HasPrefix(sql[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+			tokens = append(tokens, sqlToken{operatorToken, sql[start:i]})
+		}
+	}
+
+	return tokens
+}
+
+var multiCharSqlOperators = []string{
+	"<=>", "<=", ">=", "<>", "!=", "<<", ">>", "||", "&&", ":=",
+}
+
+func isSqlWordChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9') ||
+		ch == '_' ||
+		ch == '$' ||
+		ch == '@' ||
+		ch == '.' ||
+		ch >= 0x80
+}
+
+// This returns true if sql[i] is the exponent's sign within a numeric
+// literal (e.g., 1.5e-3) starting at sql[start].
+func isSqlExponentSign(sql string, start int, i int) bool {
+	return (sql[i] == '-' || sql[i] == '+') &&
+		sql[start] >= '0' && sql[start] <= '9' &&
+		(sql[i-1] == 'e' || sql[i-1] == 'E')
+}
+
+// This returns the index right after the quoted string (or identifier)
+// starting at sql[start].  Unterminated strings extend to the end of sql.
+func scanQuoted(sql string, start int) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if quote != '`' {
+				i++ // skip the escaped character.
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote { // doubled quote.
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+type sqlPhraseKind int
+
+const (
+	// The phrase starts a new line at the current indentation.
+	clausePhrase sqlPhraseKind = iota
+	// The phrase starts a new line, indented under the FROM clause.
+	joinPhrase
+	// The phrase starts a new line, indented under its join.
+	joinConditionPhrase
+)
+
+type sqlPhrase struct {
+	words []string
+	kind  sqlPhraseKind
+}
+
+// NOTE: longer phrases must precede their prefixes.
+var sqlPhrases = []sqlPhrase{
+	{[]string{"INSERT", "IGNORE", "INTO"}, clausePhrase},
+	{[]string{"INSERT", "INTO"}, clausePhrase},
+	{[]string{"INSERT"}, clausePhrase},
+	{[]string{"REPLACE", "INTO"}, clausePhrase},
+	{[]string{"DELETE", "FROM"}, clausePhrase},
+	{[]string{"DELETE"}, clausePhrase},
+	{[]string{"SELECT"}, clausePhrase},
+	{[]string{"FROM"}, clausePhrase},
+	{[]string{"WHERE"}, clausePhrase},
+	{[]string{"GROUP", "BY"}, clausePhrase},
+	{[]string{"HAVING"}, clausePhrase},
+	{[]string{"ORDER", "BY"}, clausePhrase},
+	{[]string{"LIMIT"}, clausePhrase},
+	{[]string{"FOR", "UPDATE"}, clausePhrase},
+	{[]string{"LOCK", "IN", "SHARE", "MODE"}, clausePhrase},
+	{[]string{"UNION", "ALL"}, clausePhrase},
+	{[]string{"UNION"}, clausePhrase},
+	{[]string{"VALUES"}, clausePhrase},
+	{[]string{"ON", "DUPLICATE", "KEY", "UPDATE"}, clausePhrase},
+	{[]string{"UPDATE"}, clausePhrase},
+	{[]string{"SET"}, clausePhrase},
+	{[]string{"LEFT", "OUTER", "JOIN"}, joinPhrase},
+	{[]string{"RIGHT", "OUTER", "JOIN"}, joinPhrase},
+	{[]string{"LEFT", "JOIN"}, joinPhrase},
+	{[]string{"RIGHT", "JOIN"}, joinPhrase},
+	{[]string{"INNER", "JOIN"}, joinPhrase},
+	{[]string{"CROSS", "JOIN"}, joinPhrase},
+	{[]string{"STRAIGHT_JOIN"}, joinPhrase},
+	{[]string{"JOIN"}, joinPhrase},
+	{[]string{"ON"}, joinConditionPhrase},
+}
+
+// Words which are capitalized by Format (in addition to the phrases' words).
+var sqlKeywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BINARY": true, "BY": true, "CASE": true, "COLLATE": true,
+	"DESC": true, "DISTINCT": true, "DIV": true, "ELSE": true, "END": true,
+	"EXISTS": true, "EXPLAIN": true, "FALSE": true, "FORCE": true,
+	"IGNORE": true, "IN": true, "INDEX": true, "INTERVAL": true, "IS": true,
+	"KEY": true, "LIKE": true, "MOD": true, "NOT": true, "NULL": true,
+	"OFFSET": true, "OR": true, "REGEXP": true, "THEN": true, "TRUE": true,
+	"USE": true, "WHEN": true, "XOR": true,
+}
+
+func init() {
+	for _, phrase := range sqlPhrases {
+		for _, word := range phrase.words {
+			sqlKeywords[word] = true
+		}
+	}
+}
+
+const sqlIndent = "  "
+
+type sqlFormatter struct {
+	buf bytes.Buffer
+
+	// One entry per unclosed parenthesis; true if the parenthesis starts a
+	// subquery.
+	parens []bool
+
+	// The current clause of the innermost subquery (or of the statement).
+	clause string
+	// The enclosing statements' clauses, one entry per open subquery.
+	outerClauses []string
+
+	prev        sqlToken
+	prevIsUnary bool // true if prev is a unary operator.
+	atLineStart bool
+}
+
+// This writes the token at tokens[i] and returns the number of additional
+// tokens consumed (i.e., the rest of a multi-word phrase).
+func (f *sqlFormatter) writeToken(tokens []sqlToken, i int) int {
+	token := tokens[i]
+
+	switch token.kind {
+	case wordToken:
+		if !f.breaksClauses() {
+			break
+		}
+
+		phrase, ok := matchSqlPhrase(tokens, i)
+		if !ok || f.isFalsePhraseMatch(phrase) {
+			break
+		}
+
+		if !f.isPhraseInline(phrase) {
+			indent := len(f.outerClauses)
+			switch phrase.kind {
+			case joinPhrase:
+				indent++
+			case joinConditionPhrase:
+				indent += 2
+			}
+			f.newLine(indent)
+		}
+
+		if phrase.kind == clausePhrase {
+			f.clause = strings.Join(phrase.words, " ")
+		}
+
+		for j := range phrase.words {
+			f.write(tokens[i+j])
+		}
+		return len(phrase.words) - 1
+	case openParenToken:
+		isSubquery := i+1 < len(tokens) &&
+			tokens[i+1].kind == wordToken &&
+			strings.ToUpper(tokens[i+1].value) == "SELECT"
+
+		f.write(token)
+		f.parens = append(f.parens, isSubquery)
+		if isSubquery {
+			f.outerClauses = append(f.outerClauses, f.clause)
+			f.clause = ""
+		}
+		return 0
+	case closeParenToken:
+		if n := len(f.parens); n > 0 {
+			if f.parens[n-1] {
+				last := len(f.outerClauses) - 1
+				f.clause = f.outerClauses[last]
+				f.outerClauses = f.outerClauses[:last]
+			}
+			f.parens = f.parens[:n-1]
+		}
+	}
+
+	f.write(token)
+	return 0
+}
+
+// Clauses are only broken into lines at the statement's top level, or at
+// the top level of subqueries (i.e., not within function calls, tuples,
+// etc).
+func (f *sqlFormatter) breaksClauses() bool {
+	return len(f.parens) == 0 || f.parens[len(f.parens)-1]
+}
+
+func (f *sqlFormatter) isPhraseInline(phrase sqlPhrase) bool {
+	if f.buf.Len() == 0 || f.prev.kind == openParenToken {
+		return true
+	}
+
+	if f.prev.kind == wordToken && strings.ToUpper(f.prev.value) == "EXPLAIN" {
+		return true
+	}
+
+	if phrase.kind != clausePhrase {
+		// Joins (and their conditions) only appear within the FROM clause.
+		return f.clause != "FROM"
+	}
+
+	return false
+}
+
+// This returns true if the phrase's words are not used as a clause / join.
+func (f *sqlFormatter) isFalsePhraseMatch(phrase sqlPhrase) bool {
+	switch strings.Join(phrase.words, " ") {
+	case "VALUES":
+		// VALUES(col) refers to the inserted value within the ON DUPLICATE
+		// KEY UPDATE clause.
+		return f.clause == "ON DUPLICATE KEY UPDATE"
+	case "SET":
+		// e.g., CHARACTER SET
+		return f.prev.kind == wordToken &&
+			strings.ToUpper(f.prev.value) == "CHARACTER"
+	}
+	return false
+}
+
+func matchSqlPhrase(tokens []sqlToken, i int) (sqlPhrase, bool) {
+	for _, phrase := range sqlPhrases {
+		if i+len(phrase.words) > len(tokens) {
+			continue
+		}
+
+		matched := true
+		for j, word := range phrase.words {
+			token := tokens[i+j]
+			if token.kind != wordToken ||
+				strings.ToUpper(token.value) != word {
+
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return phrase, true
+		}
+	}
+
+	return sqlPhrase{}, false
+}
+
+func (f *sqlFormatter) newLine(indent int) {
+	if f.buf.Len() == 0 || f.atLineStart {
+		return
+	}
+
+	_ = f.buf.WriteByte('\n')
+	_, _ = f.buf.WriteString(strings.Repeat(sqlIndent, indent))
+	f.atLineStart = true
+}
+
+func isSqlKeyword(token sqlToken) bool {
+	return token.kind == wordToken && sqlKeywords[strings.ToUpper(token.value)]
+}
+
+// This returns true if the token may precede a unary operator.
+func precedesUnaryOperator(token sqlToken) bool {
+	switch token.kind {
+	case operatorToken, openParenToken, commaToken:
+		return true
+	case wordToken:
+		switch strings.ToUpper(token.value) {
+		case "NULL", "TRUE", "FALSE", "END":
+			return false
+		}
+		return isSqlKeyword(token)
+	}
+	return false
+}
+
+func (f *sqlFormatter) needsSpace(token sqlToken) bool {
+	if f.buf.Len() == 0 || f.atLineStart || f.prevIsUnary {
+		return false
+	}
+
+	switch token.kind {
+	case commaToken, semicolonToken, closeParenToken:
+		return false
+	case openParenToken:
+		// No space between function names and their arguments.
+		if f.prev.kind != wordToken {
+			return true
+		}
+		if strings.ToUpper(f.prev.value) == "VALUES" {
+			return f.clause != "ON DUPLICATE KEY UPDATE"
+		}
+		return isSqlKeyword(f.prev)
+	}
+
+	return f.prev.kind != openParenToken
+}
+
+func (f *sqlFormatter) write(token sqlToken) {
+	if f.needsSpace(token) {
+		_ = f.buf.WriteByte(' ')
+	}
+
+	value := token.value
+	if isSqlKeyword(token) {
+		value = strings.ToUpper(value)
+	}
+	_, _ = f.buf.WriteString(value)
+
+	isUnary := false
+	if token.kind == operatorToken {
+		switch token.value {
+		case "-", "+", "~", "!":
+			isUnary = f.buf.Len() == len(value) ||
+				precedesUnaryOperator(f.prev)
+		}
+	}
+
+	f.prev = token
+	f.prevIsUnary = isUnary
+	f.atLineStart = false
+
+	if token.kind == commentToken && !strings.HasPrefix(value, "/*") {
+		// Line comments extend to the end of the line.
+		f.newLine(len(f.outerClauses))
+	}
+}
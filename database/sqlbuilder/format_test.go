@@ -0,0 +1,117 @@
+package sqlbuilder
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type FormatSuite struct {
+}
+
+var _ = gc.Suite(&FormatSuite{})
+
+func (s *FormatSuite) TestEmpty(c *gc.C) {
+	c.Assert(Format(""), gc.Equals, "")
+	c.Assert(Format("  \n "), gc.Equals, "")
+}
+
+func (s *FormatSuite) TestSelect(c *gc.C) {
+	sql, err := table1.Select(table1Col1, table1Col2).
+		Where(And(GtL(table1Col1, 123), EqL(table1Col2, 321))).
+		OrderBy(Desc(table1Col1)).
+		Limit(5).
+		String("db")
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(
+		Format(sql),
+		gc.Equals,
+		"SELECT `table1`.`col1`, `table1`.`col2`\n"+
+			"FROM `db`.`table1`\n"+
+			"WHERE (`table1`.`col1` > 123 AND `table1`.`col2` = 321)\n"+
+			"ORDER BY `table1`.`col1` DESC\n"+
+			"LIMIT 5")
+}
+
+func (s *FormatSuite) TestSelectJoins(c *gc.C) {
+	c.Assert(
+		Format("select `t1`.`a`,`t2`.`b` from `db`.`t1` "+
+			"join `db`.`t2` on `t1`.`a`=`t2`.`a` "+
+			"left join `db`.`t3` on (`t1`.`a`=`t3`.`a` and `t3`.`b`=-1) "+
+			"where `t1`.`c` in (1,2,3) for update"),
+		gc.Equals,
+		"SELECT `t1`.`a`, `t2`.`b`\n"+
+			"FROM `db`.`t1`\n"+
+			"  JOIN `db`.`t2`\n"+
+			"    ON `t1`.`a` = `t2`.`a`\n"+
+			"  LEFT JOIN `db`.`t3`\n"+
+			"    ON (`t1`.`a` = `t3`.`a` AND `t3`.`b` = -1)\n"+
+			"WHERE `t1`.`c` IN (1, 2, 3)\n"+
+			"FOR UPDATE")
+}
+
+func (s *FormatSuite) TestSubqueries(c *gc.C) {
+	c.Assert(
+		Format("(SELECT `a` FROM `t` WHERE `b` IN "+
+			"(SELECT `c` FROM `u` WHERE `d`=1)) "+
+			"UNION ALL (SELECT `a` FROM `v`) ORDER BY `a`"),
+		gc.Equals,
+		"(SELECT `a`\n"+
+			"  FROM `t`\n"+
+			"  WHERE `b` IN (SELECT `c`\n"+
+			"    FROM `u`\n"+
+			"    WHERE `d` = 1))\n"+
+			"UNION ALL (SELECT `a`\n"+
+			"  FROM `v`)\n"+
+			"ORDER BY `a`")
+}
+
+func (s *FormatSuite) TestInsert(c *gc.C) {
+	c.Assert(
+		Format("INSERT INTO `db`.`table1` (`table1`.`col1`,`table1`.`col2`) "+
+			"VALUES (?,?), (?,?) "+
+			"ON DUPLICATE KEY UPDATE `table1`.`col2`=VALUES(`col2`)"),
+		gc.Equals,
+		"INSERT INTO `db`.`table1` (`table1`.`col1`, `table1`.`col2`)\n"+
+			"VALUES (?, ?), (?, ?)\n"+
+			"ON DUPLICATE KEY UPDATE `table1`.`col2` = VALUES(`col2`)")
+}
+
+func (s *FormatSuite) TestUpdate(c *gc.C) {
+	sql, err := table1.Update().
+		Set(table1Col1, Literal(1)).
+		Set(table1Col2, Literal(2)).
+		Where(EqL(table1Col3, 3)).
+		String("db")
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(
+		Format(sql),
+		gc.Equals,
+		"UPDATE `db`.`table1`\n"+
+			"SET `table1`.`col1` = 1, `table1`.`col2` = 2\n"+
+			"WHERE `table1`.`col3` = 3")
+}
+
+func (s *FormatSuite) TestDelete(c *gc.C) {
+	c.Assert(
+		Format("delete from `db`.`table1` where `col1`=? "+
+			"and not (`col2` is null) limit 10;"),
+		gc.Equals,
+		"DELETE FROM `db`.`table1`\n"+
+			"WHERE `col1` = ? AND NOT (`col2` IS NULL)\n"+
+			"LIMIT 10;")
+}
+
+func (s *FormatSuite) TestLiteralsAreVerbatim(c *gc.C) {
+	// Keywords, punctuation and whitespace within strings / identifiers /
+	// comments are not reformatted.
+	c.Assert(
+		Format("SELECT `from`, 'a  where'' b,(c)' , \"x\\\"y\", 1.5e-3 "+
+			"/* select  from */ FROM `order by` -- where x\n"+
+			"WHERE `select`<=>?"),
+		gc.Equals,
+		"SELECT `from`, 'a  where'' b,(c)', \"x\\\"y\", 1.5e-3 "+
+			"/* select  from */\n"+
+			"FROM `order by` -- where x\n"+
+			"WHERE `select` <=> ?")
+}
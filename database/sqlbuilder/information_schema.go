@@ -0,0 +1,122 @@
+package sqlbuilder
+
+// The database name to pass to the INFORMATION_SCHEMA statements' String
+// method, e.g., InformationSchema.Tables("foo").String(InformationSchemaDatabase)
+const InformationSchemaDatabase = "information_schema"
+
+func infoSchemaStr(name string) NonAliasColumn {
+	return StrColumn(name, UTF8, UTF8CaseInsensitive, Nullable)
+}
+
+func infoSchemaInt(name string) NonAliasColumn {
+	return IntColumn(name, Nullable)
+}
+
+var (
+	infoSchemaTables = NewTable(
+		"TABLES",
+		infoSchemaStr("TABLE_SCHEMA"),
+		infoSchemaStr("TABLE_NAME"),
+		infoSchemaStr("TABLE_TYPE"),
+		infoSchemaStr("ENGINE"),
+		infoSchemaInt("TABLE_ROWS"),
+		infoSchemaInt("AUTO_INCREMENT"),
+		DateTimeColumn("CREATE_TIME", Nullable),
+		infoSchemaStr("TABLE_COLLATION"),
+		infoSchemaStr("TABLE_COMMENT"))
+
+	infoSchemaColumns = NewTable(
+		"COLUMNS",
+		infoSchemaStr("TABLE_SCHEMA"),
+		infoSchemaStr("TABLE_NAME"),
+		infoSchemaStr("COLUMN_NAME"),
+		infoSchemaInt("ORDINAL_POSITION"),
+		infoSchemaStr("COLUMN_DEFAULT"),
+		infoSchemaStr("IS_NULLABLE"),
+		infoSchemaStr("DATA_TYPE"),
+		infoSchemaInt("CHARACTER_MAXIMUM_LENGTH"),
+		infoSchemaInt("NUMERIC_PRECISION"),
+		infoSchemaInt("NUMERIC_SCALE"),
+		infoSchemaStr("CHARACTER_SET_NAME"),
+		infoSchemaStr("COLLATION_NAME"),
+		infoSchemaStr("COLUMN_TYPE"),
+		infoSchemaStr("COLUMN_KEY"),
+		infoSchemaStr("EXTRA"),
+		infoSchemaStr("COLUMN_COMMENT"))
+
+	infoSchemaStatistics = NewTable(
+		"STATISTICS",
+		infoSchemaStr("TABLE_SCHEMA"),
+		infoSchemaStr("TABLE_NAME"),
+		infoSchemaStr("INDEX_NAME"),
+		infoSchemaInt("NON_UNIQUE"),
+		infoSchemaInt("SEQ_IN_INDEX"),
+		infoSchemaStr("COLUMN_NAME"),
+		infoSchemaInt("SUB_PART"),
+		infoSchemaStr("NULLABLE"),
+		infoSchemaStr("INDEX_TYPE"))
+
+	infoSchemaKeyColumnUsage = NewTable(
+		"KEY_COLUMN_USAGE",
+		infoSchemaStr("CONSTRAINT_NAME"),
+		infoSchemaStr("TABLE_SCHEMA"),
+		infoSchemaStr("TABLE_NAME"),
+		infoSchemaStr("COLUMN_NAME"),
+		infoSchemaInt("ORDINAL_POSITION"),
+		infoSchemaStr("REFERENCED_TABLE_SCHEMA"),
+		infoSchemaStr("REFERENCED_TABLE_NAME"),
+		infoSchemaStr("REFERENCED_COLUMN_NAME"))
+)
+
+// InformationSchemaQueries builds the common schema introspection queries.
+// The returned statements are regular select statements, hence they may be
+// further refined (e.g., with AndWhere or Limit).  NOTE: The statements must
+// be serialized with InformationSchemaDatabase as the database.
+type InformationSchemaQueries struct{}
+
+var InformationSchema = InformationSchemaQueries{}
+
+// Tables selects the schema's tables, ordered by table name.
+func (InformationSchemaQueries) Tables(schema string) SelectStatement {
+	t := infoSchemaTables
+	return t.Select(t.Projections()...).
+		Where(EqL(t.C("TABLE_SCHEMA"), schema)).
+		OrderBy(Asc(t.C("TABLE_NAME")))
+}
+
+// Columns selects the table's columns, ordered by ordinal position.
+func (InformationSchemaQueries) Columns(schema, table string) SelectStatement {
+	t := infoSchemaColumns
+	return t.Select(t.Projections()...).
+		Where(And(
+			EqL(t.C("TABLE_SCHEMA"), schema),
+			EqL(t.C("TABLE_NAME"), table))).
+		OrderBy(Asc(t.C("ORDINAL_POSITION")))
+}
+
+// Indexes selects the table's index columns (one row per index column),
+// ordered by index name and the column's position within the index.
+func (InformationSchemaQueries) Indexes(schema, table string) SelectStatement {
+	t := infoSchemaStatistics
+	return t.Select(t.Projections()...).
+		Where(And(
+			EqL(t.C("TABLE_SCHEMA"), schema),
+			EqL(t.C("TABLE_NAME"), table))).
+		OrderBy(Asc(t.C("INDEX_NAME")), Asc(t.C("SEQ_IN_INDEX")))
+}
+
+// ForeignKeys selects the table's foreign key columns (one row per foreign
+// key column), ordered by constraint name and the column's position within
+// the constraint.
+func (InformationSchemaQueries) ForeignKeys(
+	schema string,
+	table string) SelectStatement {
+
+	t := infoSchemaKeyColumnUsage
+	return t.Select(t.Projections()...).
+		Where(And(
+			EqL(t.C("TABLE_SCHEMA"), schema),
+			EqL(t.C("TABLE_NAME"), table),
+			NeqL(t.C("REFERENCED_TABLE_NAME"), nil))).
+		OrderBy(Asc(t.C("CONSTRAINT_NAME")), Asc(t.C("ORDINAL_POSITION")))
+}
@@ -0,0 +1,99 @@
+package sqlbuilder
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type InformationSchemaSuite struct {
+}
+
+var _ = gc.Suite(&InformationSchemaSuite{})
+
+func (s *InformationSchemaSuite) TestTables(c *gc.C) {
+	sql, err := InformationSchema.Tables("db").String(InformationSchemaDatabase)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `TABLES`.`TABLE_SCHEMA`,`TABLES`.`TABLE_NAME`,"+
+			"`TABLES`.`TABLE_TYPE`,`TABLES`.`ENGINE`,`TABLES`.`TABLE_ROWS`,"+
+			"`TABLES`.`AUTO_INCREMENT`,`TABLES`.`CREATE_TIME`,"+
+			"`TABLES`.`TABLE_COLLATION`,`TABLES`.`TABLE_COMMENT` "+
+			"FROM `information_schema`.`TABLES` "+
+			"WHERE `TABLES`.`TABLE_SCHEMA`='db' "+
+			"ORDER BY `TABLES`.`TABLE_NAME` ASC")
+}
+
+func (s *InformationSchemaSuite) TestColumns(c *gc.C) {
+	stmt := InformationSchema.Columns("db", "users").Limit(10)
+	sql, err := stmt.String(InformationSchemaDatabase)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `COLUMNS`.`TABLE_SCHEMA`,`COLUMNS`.`TABLE_NAME`,"+
+			"`COLUMNS`.`COLUMN_NAME`,`COLUMNS`.`ORDINAL_POSITION`,"+
+			"`COLUMNS`.`COLUMN_DEFAULT`,`COLUMNS`.`IS_NULLABLE`,"+
+			"`COLUMNS`.`DATA_TYPE`,`COLUMNS`.`CHARACTER_MAXIMUM_LENGTH`,"+
+			"`COLUMNS`.`NUMERIC_PRECISION`,`COLUMNS`.`NUMERIC_SCALE`,"+
+			"`COLUMNS`.`CHARACTER_SET_NAME`,`COLUMNS`.`COLLATION_NAME`,"+
+			"`COLUMNS`.`COLUMN_TYPE`,`COLUMNS`.`COLUMN_KEY`,"+
+			"`COLUMNS`.`EXTRA`,`COLUMNS`.`COLUMN_COMMENT` "+
+			"FROM `information_schema`.`COLUMNS` "+
+			"WHERE (`COLUMNS`.`TABLE_SCHEMA`='db' AND "+
+			"`COLUMNS`.`TABLE_NAME`='users') "+
+			"ORDER BY `COLUMNS`.`ORDINAL_POSITION` ASC LIMIT 10")
+}
+
+func (s *InformationSchemaSuite) TestIndexes(c *gc.C) {
+	sql, err := InformationSchema.Indexes("db", "users").String(
+		InformationSchemaDatabase)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `STATISTICS`.`TABLE_SCHEMA`,`STATISTICS`.`TABLE_NAME`,"+
+			"`STATISTICS`.`INDEX_NAME`,`STATISTICS`.`NON_UNIQUE`,"+
+			"`STATISTICS`.`SEQ_IN_INDEX`,`STATISTICS`.`COLUMN_NAME`,"+
+			"`STATISTICS`.`SUB_PART`,`STATISTICS`.`NULLABLE`,"+
+			"`STATISTICS`.`INDEX_TYPE` "+
+			"FROM `information_schema`.`STATISTICS` "+
+			"WHERE (`STATISTICS`.`TABLE_SCHEMA`='db' AND "+
+			"`STATISTICS`.`TABLE_NAME`='users') "+
+			"ORDER BY `STATISTICS`.`INDEX_NAME` ASC,"+
+			"`STATISTICS`.`SEQ_IN_INDEX` ASC")
+}
+
+func (s *InformationSchemaSuite) TestForeignKeys(c *gc.C) {
+	sql, err := InformationSchema.ForeignKeys("db", "users").String(
+		InformationSchemaDatabase)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `KEY_COLUMN_USAGE`.`CONSTRAINT_NAME`,"+
+			"`KEY_COLUMN_USAGE`.`TABLE_SCHEMA`,"+
+			"`KEY_COLUMN_USAGE`.`TABLE_NAME`,"+
+			"`KEY_COLUMN_USAGE`.`COLUMN_NAME`,"+
+			"`KEY_COLUMN_USAGE`.`ORDINAL_POSITION`,"+
+			"`KEY_COLUMN_USAGE`.`REFERENCED_TABLE_SCHEMA`,"+
+			"`KEY_COLUMN_USAGE`.`REFERENCED_TABLE_NAME`,"+
+			"`KEY_COLUMN_USAGE`.`REFERENCED_COLUMN_NAME` "+
+			"FROM `information_schema`.`KEY_COLUMN_USAGE` "+
+			"WHERE (`KEY_COLUMN_USAGE`.`TABLE_SCHEMA`='db' AND "+
+			"`KEY_COLUMN_USAGE`.`TABLE_NAME`='users' AND "+
+			"`KEY_COLUMN_USAGE`.`REFERENCED_TABLE_NAME` IS NOT null) "+
+			"ORDER BY `KEY_COLUMN_USAGE`.`CONSTRAINT_NAME` ASC,"+
+			"`KEY_COLUMN_USAGE`.`ORDINAL_POSITION` ASC")
+}
+
+func (s *InformationSchemaSuite) TestEscaping(c *gc.C) {
+	sql, err := InformationSchema.Tables("d'b").String(InformationSchemaDatabase)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql[len(sql)-len("WHERE `TABLES`.`TABLE_SCHEMA`='d\\'b' "+
+			"ORDER BY `TABLES`.`TABLE_NAME` ASC"):],
+		gc.Equals,
+		"WHERE `TABLES`.`TABLE_SCHEMA`='d\\'b' "+
+			"ORDER BY `TABLES`.`TABLE_NAME` ASC")
+}
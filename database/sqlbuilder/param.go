@@ -0,0 +1,134 @@
+package sqlbuilder
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dropbox/godropbox/database/sqltypes"
+	"github.com/dropbox/godropbox/errors"
+)
+
+// Lazy parameters are serialized as marker delimited names (the marker never
+// appears in properly escaped sql), which are resolved into either ?
+// placeholders (see SelectStatement.ToSql) or inlined values (see
+// Statement.String) once the statement's sql is generated.
+const paramMarker = '\x00'
+
+// LazyParam is a named bind parameter whose value is provided after the
+// statement is constructed (see SelectStatement.BindParams).  This allows the
+// statement's structure (i.e., the sql generated by SelectStatement.ToSql) to
+// remain stable across executions with different parameter values.
+type LazyParam interface {
+	Expression
+
+	// Name returns the parameter's name.
+	Name() string
+}
+
+type lazyParam struct {
+	isExpression
+	name string
+}
+
+// Returns a representation of the named parameter.  A parameter may be
+// referenced multiple times within the same statement.  Only select
+// statements can bind parameters; other statements (including unions, whose
+// inner selects are bound individually) return an error when they reference
+// a parameter.
+func Param(name string) LazyParam {
+	if !validIdentifierName(name) {
+		panic(errors.Newf("Invalid parameter name: %s", name))
+	}
+	return &lazyParam{name: name}
+}
+
+func (p *lazyParam) Name() string {
+	return p.name
+}
+
+func (p *lazyParam) SerializeSql(out *bytes.Buffer) error {
+	_ = out.WriteByte(paramMarker)
+	_, _ = out.WriteString(p.name)
+	_ = out.WriteByte(paramMarker)
+	return nil
+}
+
+// This replaces the lazy parameters in the generated sql with ? placeholders,
+// and returns the placeholders' values (in order).
+func bindLazyParams(
+	sql string,
+	params map[string]interface{}) (string, []interface{}, error) {
+
+	args := []interface{}{}
+	result, err := resolveLazyParams(
+		sql,
+		params,
+		func(out *bytes.Buffer, value interface{}) error {
+			_ = out.WriteByte('?')
+			args = append(args, value)
+			return nil
+		})
+	if err != nil {
+		return "", nil, err
+	}
+	return result, args, nil
+}
+
+// This replaces the lazy parameters in the generated sql with their (properly
+// escaped) values.
+func inlineLazyParams(
+	sql string,
+	params map[string]interface{}) (string, error) {
+
+	return resolveLazyParams(
+		sql,
+		params,
+		func(out *bytes.Buffer, value interface{}) error {
+			v, err := sqltypes.BuildValue(value)
+			if err != nil {
+				return errors.Wrap(err, "Invalid parameter value")
+			}
+			v.EncodeSql(out)
+			return nil
+		})
+}
+
+func resolveLazyParams(
+	sql string,
+	params map[string]interface{},
+	writeParam func(out *bytes.Buffer, value interface{}) error) (
+	string,
+	error) {
+
+	if strings.IndexByte(sql, paramMarker) < 0 {
+		return sql, nil
+	}
+
+	buf := new(bytes.Buffer)
+	for {
+		start := strings.IndexByte(sql, paramMarker)
+		if start < 0 {
+			_, _ = buf.WriteString(sql)
+			return buf.String(), nil
+		}
+
+		end := strings.IndexByte(sql[start+1:], paramMarker)
+		if end < 0 {
+			return "", errors.New("Malformed lazy parameter")
+		}
+		end += start + 1
+
+		name := sql[start+1 : end]
+		value, ok := params[name]
+		if !ok {
+			return "", errors.Newf("Unbound parameter: %s", name)
+		}
+
+		_, _ = buf.WriteString(sql[:start])
+		if err := writeParam(buf, value); err != nil {
+			return "", errors.Wrapf(err, "Failed to bind parameter %s", name)
+		}
+
+		sql = sql[end+1:]
+	}
+}
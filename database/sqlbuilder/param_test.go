@@ -0,0 +1,138 @@
+package sqlbuilder
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type ParamSuite struct {
+}
+
+var _ = gc.Suite(&ParamSuite{})
+
+func (s *ParamSuite) newStatement() SelectStatement {
+	return table1.Select(table1Col1).Where(
+		And(
+			Eq(table1Col2, Param("a")),
+			Gt(table1Col3, Param("b")),
+			Lt(table1Col3, Param("a"))))
+}
+
+func (s *ParamSuite) TestToSql(c *gc.C) {
+	stmt := s.newStatement()
+
+	expected := "SELECT `table1`.`col1` FROM `db`.`table1` " +
+		"WHERE (`table1`.`col2`=? AND `table1`.`col3`>? AND " +
+		"`table1`.`col3`<?)"
+
+	sql, args, err := stmt.BindParams(
+		map[string]interface{}{"a": 1, "b": "x"}).ToSql("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(sql, gc.Equals, expected)
+	c.Assert(args, gc.DeepEquals, []interface{}{1, "x", 1})
+
+	// The generated sql does not depend on the bound values.
+	sql, args, err = stmt.BindParams(
+		map[string]interface{}{"a": 2, "b": "y", "unused": 3}).ToSql("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(sql, gc.Equals, expected)
+	c.Assert(args, gc.DeepEquals, []interface{}{2, "y", 2})
+}
+
+func (s *ParamSuite) TestString(c *gc.C) {
+	sql, err := s.newStatement().BindParams(
+		map[string]interface{}{"a": 1, "b": "x'y"}).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1` FROM `db`.`table1` "+
+			"WHERE (`table1`.`col2`=1 AND `table1`.`col3`>'x\\'y' AND "+
+			"`table1`.`col3`<1)")
+}
+
+func (s *ParamSuite) TestBindParamsCopies(c *gc.C) {
+	stmt := s.newStatement()
+
+	partial := stmt.BindParams(map[string]interface{}{"a": 1})
+	full := partial.BindParams(map[string]interface{}{"a": 5, "b": 2})
+
+	// Neither the original nor the partially bound statement is modified.
+	_, _, err := stmt.ToSql("db")
+	c.Assert(err, gc.NotNil)
+	_, _, err = partial.ToSql("db")
+	c.Assert(err, gc.NotNil)
+
+	_, args, err := full.ToSql("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(args, gc.DeepEquals, []interface{}{5, 2, 5})
+}
+
+func (s *ParamSuite) TestUnbound(c *gc.C) {
+	_, err := s.newStatement().String("db")
+	c.Assert(err, gc.NotNil)
+
+	_, err = table1.Delete().Where(Eq(table1Col1, Param("a"))).String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ParamSuite) TestUnion(c *gc.C) {
+	inner := table1.Select(table1Col1).Where(Eq(table1Col2, Param("a")))
+
+	// The inner selects' parameters are bound individually.
+	sql, err := Union(
+		inner.BindParams(map[string]interface{}{"a": 1}),
+		inner.BindParams(map[string]interface{}{"a": "x"})).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"(SELECT `table1`.`col1` FROM `db`.`table1` "+
+			"WHERE `table1`.`col2`=1) "+
+			"UNION (SELECT `table1`.`col1` FROM `db`.`table1` "+
+			"WHERE `table1`.`col2`='x')")
+
+	_, err = Union(inner, table1.Select(table1Col1)).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// The union's own clauses cannot reference parameters.
+	bound := inner.BindParams(map[string]interface{}{"a": 1})
+	for _, union := range []UnionStatement{
+		Union(bound, bound).Where(Eq(table1Col1, Param("x"))),
+		Union(bound, bound).GroupBy(Param("x")),
+		UnionAll(bound, bound).OrderBy(Asc(Param("x"))),
+	} {
+		sql, err = union.String("db")
+		c.Assert(err, gc.NotNil)
+		c.Assert(sql, gc.Equals, "")
+	}
+
+	_, err = NewShowVariablesStatement().Where(
+		Eq(table1Col1, Param("x"))).String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ParamSuite) TestNoParams(c *gc.C) {
+	sql, args, err := table1.Select(table1Col1).Where(
+		EqL(table1Col2, 1)).ToSql("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1` FROM `db`.`table1` WHERE `table1`.`col2`=1")
+	c.Assert(args, gc.DeepEquals, []interface{}{})
+}
+
+func (s *ParamSuite) TestInvalidValue(c *gc.C) {
+	_, err := s.newStatement().BindParams(
+		map[string]interface{}{"a": 1, "b": struct{}{}}).String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ParamSuite) TestInvalidName(c *gc.C) {
+	for _, name := range []string{"", "a\x00b", "a b"} {
+		c.Assert(
+			func() { Param(name) },
+			gc.PanicMatches,
+			"(?s).*Invalid parameter name.*")
+	}
+}
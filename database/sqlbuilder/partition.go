@@ -0,0 +1,359 @@
+package sqlbuilder
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/dropbox/godropbox/database/sqltypes"
+	"github.com/dropbox/godropbox/errors"
+)
+
+type maxValue struct{}
+
+// MaxValue is the upper bound of the last range partition, i.e.,
+// VALUES LESS THAN MAXVALUE.
+var MaxValue = maxValue{}
+
+type partitionDefinition struct {
+	name   string
+	values []interface{}
+}
+
+// PartitionBuilder builds the PARTITION BY clause of a partitioned table.
+// The partitioning method is selected by calling one of ByRange, ByList,
+// ByHash or ByKey.
+// See https://dev.mysql.com/doc/refman/8.0/en/partitioning-types.html
+type PartitionBuilder struct {
+	method        string
+	linear        bool
+	columnsForm   bool
+	columns       []Column
+	numPartitions int
+	partitions    []partitionDefinition
+}
+
+// NewPartitionBuilder returns a partition builder without a partitioning
+// method.
+func NewPartitionBuilder() *PartitionBuilder {
+	return &PartitionBuilder{}
+}
+
+// RangePartitionBuilder adds partitions to a PARTITION BY RANGE clause.
+type RangePartitionBuilder struct {
+	builder *PartitionBuilder
+}
+
+// ListPartitionBuilder adds partitions to a PARTITION BY LIST clause.
+type ListPartitionBuilder struct {
+	builder *PartitionBuilder
+}
+
+// HashPartitionBuilder configures a PARTITION BY [LINEAR] HASH / KEY clause.
+type HashPartitionBuilder struct {
+	builder *PartitionBuilder
+}
+
+// ByRange partitions the table by ranges of the column's values.  The
+// partitions must be added in increasing order of their upper bounds.
+// The bounds must be integers unless Columns is called.
+func (pb *PartitionBuilder) ByRange(col Column) *RangePartitionBuilder {
+	pb.method = "RANGE"
+	pb.linear = false
+	pb.columnsForm = false
+	pb.columns = []Column{col}
+	pb.numPartitions = 0
+	pb.partitions = nil
+	return &RangePartitionBuilder{builder: pb}
+}
+
+// ByList partitions the table by lists of the column's values.  The values
+// must be integers (or nil) unless Columns is called.
+func (pb *PartitionBuilder) ByList(col Column) *ListPartitionBuilder {
+	pb.method = "LIST"
+	pb.linear = false
+	pb.columnsForm = false
+	pb.columns = []Column{col}
+	pb.numPartitions = 0
+	pb.partitions = nil
+	return &ListPartitionBuilder{builder: pb}
+}
+
+// ByHash partitions the table into the given number of partitions by the
+// hash of the column's value.
+func (pb *PartitionBuilder) ByHash(
+	col Column,
+	partitions int) *HashPartitionBuilder {
+
+	pb.method = "HASH"
+	pb.linear = false
+	pb.columnsForm = false
+	pb.columns = []Column{col}
+	pb.numPartitions = partitions
+	pb.partitions = nil
+	return &HashPartitionBuilder{builder: pb}
+}
+
+// ByKey partitions the table into the given number of partitions by the
+// (mysql computed) hash of the columns' values.  When no columns are
+// specified, the table's primary key is used.
+func (pb *PartitionBuilder) ByKey(
+	partitions int,
+	cols ...Column) *HashPartitionBuilder {
+
+	pb.method = "KEY"
+	pb.linear = false
+	pb.columnsForm = false
+	pb.columns = cols
+	pb.numPartitions = partitions
+	pb.partitions = nil
+	return &HashPartitionBuilder{builder: pb}
+}
+
+// AddPartition adds a partition which holds the rows whose column value is
+// less than lessThan (use MaxValue for the last partition).
+func (b *RangePartitionBuilder) AddPartition(
+	name string,
+	lessThan interface{}) *RangePartitionBuilder {
+
+	b.builder.partitions = append(
+		b.builder.partitions,
+		partitionDefinition{name: name, values: []interface{}{lessThan}})
+	return b
+}
+
+// Columns uses RANGE COLUMNS partitioning, which accepts non-integer
+// (e.g., string or date) bounds.
+func (b *RangePartitionBuilder) Columns() *RangePartitionBuilder {
+	b.builder.columnsForm = true
+	return b
+}
+
+// Builder returns the partition builder.
+func (b *RangePartitionBuilder) Builder() *PartitionBuilder {
+	return b.builder
+}
+
+// AddPartition adds a partition which holds the rows whose column value is
+// one of the values.
+func (b *ListPartitionBuilder) AddPartition(
+	name string,
+	values ...interface{}) *ListPartitionBuilder {
+
+	b.builder.partitions = append(
+		b.builder.partitions,
+		partitionDefinition{name: name, values: values})
+	return b
+}
+
+// Columns uses LIST COLUMNS partitioning, which accepts non-integer
+// (e.g., string or date) values.
+func (b *ListPartitionBuilder) Columns() *ListPartitionBuilder {
+	b.builder.columnsForm = true
+	return b
+}
+
+// Builder returns the partition builder.
+func (b *ListPartitionBuilder) Builder() *PartitionBuilder {
+	return b.builder
+}
+
+// Linear uses linear hashing (powers-of-two algorithm) instead of modulus
+// hashing.
+func (b *HashPartitionBuilder) Linear() *HashPartitionBuilder {
+	b.builder.linear = true
+	return b
+}
+
+// Builder returns the partition builder.
+func (b *HashPartitionBuilder) Builder() *PartitionBuilder {
+	return b.builder
+}
+
+// NOTE: Without COLUMNS, mysql only accepts integer partition values (and
+// NULL for list partitions).
+func serializePartitionValue(
+	value interface{},
+	integerOnly bool,
+	out *bytes.Buffer) error {
+
+	if _, ok := value.(maxValue); ok {
+		_, _ = out.WriteString("MAXVALUE")
+		return nil
+	}
+
+	v, err := sqltypes.BuildValue(value)
+	if err != nil {
+		return errors.Wrap(err, "Invalid partition value")
+	}
+	if v.IsNull() {
+		_, _ = out.WriteString("NULL")
+		return nil
+	}
+
+	if integerOnly && !v.IsNumeric() {
+		return errors.Newf(
+			"Non-integer partition value %v requires COLUMNS partitioning",
+			value)
+	}
+
+	v.EncodeSql(out)
+	return nil
+}
+
+// SerializeSql generates the PARTITION BY clause.
+func (pb *PartitionBuilder) SerializeSql(out *bytes.Buffer) error {
+	if pb.method == "" {
+		return errors.New("No partitioning method specified")
+	}
+
+	_, _ = out.WriteString("PARTITION BY ")
+	if pb.linear {
+		_, _ = out.WriteString("LINEAR ")
+	}
+	_, _ = out.WriteString(pb.method)
+	if pb.columnsForm {
+		_, _ = out.WriteString(" COLUMNS")
+	}
+	_, _ = out.WriteString(" (")
+	for i, col := range pb.columns {
+		if col == nil {
+			return errors.Newf(
+				"nil partition column.  Generated sql: %s",
+				out.String())
+		}
+		if !validIdentifierName(col.Name()) {
+			return errors.Newf(
+				"Invalid partition column name: %s",
+				col.Name())
+		}
+
+		if i > 0 {
+			_, _ = out.WriteString(",")
+		}
+		_, _ = out.WriteString("`")
+		_, _ = out.WriteString(col.Name())
+		_, _ = out.WriteString("`")
+	}
+	_, _ = out.WriteString(")")
+
+	isRange := pb.method == "RANGE"
+	isList := pb.method == "LIST"
+
+	if !isRange && !isList {
+		if len(pb.columns) == 0 && pb.method != "KEY" {
+			return errors.New("No partition column specified")
+		}
+		if pb.numPartitions < 1 {
+			return errors.Newf(
+				"Invalid number of partitions: %d",
+				pb.numPartitions)
+		}
+
+		_, _ = out.WriteString(" PARTITIONS ")
+		_, _ = out.WriteString(strconv.Itoa(pb.numPartitions))
+		return nil
+	}
+
+	if len(pb.partitions) == 0 {
+		return errors.Newf(
+			"No partitions specified.  Generated sql: %s",
+			out.String())
+	}
+
+	_, _ = out.WriteString(" (")
+	for i, p := range pb.partitions {
+		if !validIdentifierName(p.name) {
+			return errors.Newf("Invalid partition name: %s", p.name)
+		}
+
+		if i > 0 {
+			_, _ = out.WriteString(", ")
+		}
+		_, _ = out.WriteString("PARTITION `")
+		_, _ = out.WriteString(p.name)
+		_, _ = out.WriteString("` VALUES ")
+
+		if isRange {
+			_, _ = out.WriteString("LESS THAN ")
+			if _, ok := p.values[0].(maxValue); ok {
+				_, _ = out.WriteString("MAXVALUE")
+				continue
+			}
+		} else {
+			if len(p.values) == 0 {
+				return errors.Newf("Partition %s has no values", p.name)
+			}
+			_, _ = out.WriteString("IN ")
+		}
+
+		_, _ = out.WriteString("(")
+		for j, value := range p.values {
+			if isList {
+				if _, ok := value.(maxValue); ok {
+					return errors.New(
+						"MAXVALUE is only valid for range partitions")
+				}
+			}
+
+			if j > 0 {
+				_, _ = out.WriteString(",")
+			}
+			if isRange && value == nil {
+				return errors.New(
+					"NULL is not a valid range partition bound")
+			}
+
+			err := serializePartitionValue(value, !pb.columnsForm, out)
+			if err != nil {
+				return err
+			}
+		}
+		_, _ = out.WriteString(")")
+	}
+	_, _ = out.WriteString(")")
+
+	return nil
+}
+
+// NOTE: There's no CREATE TABLE statement builder; partitioning is applied to
+// existing tables via ALTER TABLE.
+type partitionTableStatement struct {
+	table       *Table
+	partitioner *PartitionBuilder
+}
+
+// PartitionBy returns an ALTER TABLE statement which (re)partitions the
+// table.
+func (t *Table) PartitionBy(pb *PartitionBuilder) Statement {
+	return &partitionTableStatement{
+		table:       t,
+		partitioner: pb,
+	}
+}
+
+func (s *partitionTableStatement) String(
+	database string) (sql string, err error) {
+
+	if !validIdentifierName(database) {
+		return "", errors.New("Invalid database name specified")
+	}
+
+	if s.partitioner == nil {
+		return "", errors.New("No partition builder specified")
+	}
+
+	buf := new(bytes.Buffer)
+	_, _ = buf.WriteString("ALTER TABLE ")
+
+	if err = s.table.SerializeSql(database, buf); err != nil {
+		return
+	}
+
+	_, _ = buf.WriteString(" ")
+
+	if err = s.partitioner.SerializeSql(buf); err != nil {
+		return
+	}
+
+	return inlineLazyParams(buf.String(), nil)
+}
@@ -0,0 +1,199 @@
+package sqlbuilder
+
+import (
+	"bytes"
+
+	gc "gopkg.in/check.v1"
+)
+
+type PartitionSuite struct {
+}
+
+var _ = gc.Suite(&PartitionSuite{})
+
+func (s *PartitionSuite) TestRange(c *gc.C) {
+	pb := NewPartitionBuilder()
+	pb.ByRange(table1Col1).
+		AddPartition("p0", 100).
+		AddPartition("p1", 200).
+		AddPartition("pmax", MaxValue)
+
+	sql, err := table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` PARTITION BY RANGE (`col1`) ("+
+			"PARTITION `p0` VALUES LESS THAN (100), "+
+			"PARTITION `p1` VALUES LESS THAN (200), "+
+			"PARTITION `pmax` VALUES LESS THAN MAXVALUE)")
+}
+
+func (s *PartitionSuite) TestList(c *gc.C) {
+	pb := NewPartitionBuilder().
+		ByList(table1Col1).
+		AddPartition("west", 1, 2, 3).
+		AddPartition("east", 4, nil).
+		Builder()
+
+	buf := &bytes.Buffer{}
+	err := pb.SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"PARTITION BY LIST (`col1`) ("+
+			"PARTITION `west` VALUES IN (1,2,3), "+
+			"PARTITION `east` VALUES IN (4,NULL))")
+}
+
+func (s *PartitionSuite) TestColumns(c *gc.C) {
+	pb := NewPartitionBuilder().
+		ByList(table1Col2).
+		Columns().
+		AddPartition("west", "a", "b").
+		AddPartition("east", "a'b", nil).
+		Builder()
+
+	buf := &bytes.Buffer{}
+	err := pb.SerializeSql(buf)
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		buf.String(),
+		gc.Equals,
+		"PARTITION BY LIST COLUMNS (`col2`) ("+
+			"PARTITION `west` VALUES IN ('a','b'), "+
+			"PARTITION `east` VALUES IN ('a\\'b',NULL))")
+
+	pb.ByRange(table1Col2).
+		Columns().
+		AddPartition("p0", "m").
+		AddPartition("pmax", MaxValue)
+
+	sql, err := table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` PARTITION BY RANGE COLUMNS (`col2`) ("+
+			"PARTITION `p0` VALUES LESS THAN ('m'), "+
+			"PARTITION `pmax` VALUES LESS THAN MAXVALUE)")
+
+	// Switching the partitioning method resets the COLUMNS form.
+	pb.ByList(table1Col2).AddPartition("p0", "a")
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *PartitionSuite) TestHashAndKey(c *gc.C) {
+	pb := NewPartitionBuilder()
+	pb.ByHash(table1Col1, 8)
+
+	sql, err := table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` PARTITION BY HASH (`col1`) PARTITIONS 8")
+
+	pb.ByHash(table1Col1, 4).Linear().Linear()
+
+	sql, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` "+
+			"PARTITION BY LINEAR HASH (`col1`) PARTITIONS 4")
+
+	pb.ByKey(2, table1Col1).Linear()
+
+	sql, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` "+
+			"PARTITION BY LINEAR KEY (`col1`) PARTITIONS 2")
+
+	pb.ByKey(2, table1Col1, table1Col2)
+
+	sql, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` "+
+			"PARTITION BY KEY (`col1`,`col2`) PARTITIONS 2")
+
+	// Partition by primary key.
+	pb.ByKey(3)
+
+	sql, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"ALTER TABLE `db`.`table1` PARTITION BY KEY () PARTITIONS 3")
+}
+
+func (s *PartitionSuite) TestErrors(c *gc.C) {
+	// No partitioning method.
+	_, err := table1.PartitionBy(NewPartitionBuilder()).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// No partition builder.
+	_, err = table1.PartitionBy(nil).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// No partitions.
+	pb := NewPartitionBuilder()
+	pb.ByRange(table1Col1)
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid partition name.
+	pb.ByRange(table1Col1).AddPartition("p 0", 1)
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid value.
+	pb.ByRange(table1Col1).AddPartition("p0", struct{}{})
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Non-integer range bound without COLUMNS.
+	pb.ByRange(table1Col1).AddPartition("p0", "a")
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// NULL range bound.
+	pb.ByRange(table1Col1).AddPartition("p0", nil)
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Non-integer list value without COLUMNS.
+	pb.ByList(table1Col1).AddPartition("p0", 1, "a")
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// MAXVALUE in list partition.
+	pb.ByList(table1Col1).AddPartition("p0", MaxValue)
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Empty list partition.
+	pb.ByList(table1Col1).AddPartition("p0")
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid number of partitions.
+	pb.ByHash(table1Col1, 0)
+	_, err = table1.PartitionBy(pb).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid database name.
+	pb.ByHash(table1Col1, 2)
+	_, err = table1.PartitionBy(pb).String("my db")
+	c.Assert(err, gc.NotNil)
+}
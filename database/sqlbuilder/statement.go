@@ -27,6 +27,40 @@ type SelectStatement interface {
 	Offset(offset int64) SelectStatement
 	Comment(comment string) SelectStatement
 	Copy() SelectStatement
+
+	// IntoOutfile returns a SELECT ... INTO OUTFILE statement which writes
+	// the selected rows into the (server side) file.  The select statement
+	// is copied; further modifications to it do not affect the returned
+	// statement.
+	IntoOutfile(path string) OutfileSelectStatement
+
+	// CacheKey returns a query result cache key for the statement's
+	// generated sql (see CacheKey).
+	CacheKey(database string) (string, error)
+
+	// BindParams returns a copy of the statement with the lazy parameters'
+	// (see Param) values bound by name.  Previously bound values are kept
+	// unless they are overridden.
+	BindParams(params map[string]interface{}) SelectStatement
+
+	// ToSql returns the generated sql with lazy parameters replaced by ?
+	// placeholders, along with the placeholders' bound values (in order).
+	// Unlike String, which inlines the bound values, the generated sql does
+	// not depend on the parameters' values.
+	ToSql(database string) (sql string, args []interface{}, err error)
+}
+
+// OutfileSelectStatement exports a SELECT statement's rows to a file.  This
+// is the inverse of LoadDataInfileStatement.
+// See https://dev.mysql.com/doc/refman/8.0/en/select-into.html
+type OutfileSelectStatement interface {
+	Statement
+
+	FieldsTerminatedBy(separator string) OutfileSelectStatement
+	FieldsEnclosedBy(char byte) OutfileSelectStatement
+	FieldsEscapedBy(char byte) OutfileSelectStatement
+	LinesStartingBy(prefix string) OutfileSelectStatement
+	LinesTerminatedBy(terminator string) OutfileSelectStatement
 }
 
 type InsertStatement interface {
@@ -100,6 +134,45 @@ type GtidNextStatement interface {
 	Statement
 }
 
+// ExplainStatement wraps a SELECT / INSERT / UPDATE / DELETE / UNION statement
+// with EXPLAIN.
+// See https://dev.mysql.com/doc/refman/8.0/en/explain.html
+type ExplainStatement interface {
+	Statement
+
+	Format(format ExplainFormat) ExplainStatement
+}
+
+// LoadDataInfileStatement bulk loads rows from a text file into a table.
+// See https://dev.mysql.com/doc/refman/8.0/en/load-data.html
+type LoadDataInfileStatement interface {
+	Statement
+
+	InFile(path string) LoadDataInfileStatement
+	IntoTable(table WritableTable) LoadDataInfileStatement
+	FieldsTerminatedBy(separator string) LoadDataInfileStatement
+	FieldsEnclosedBy(char byte) LoadDataInfileStatement
+	LinesTerminatedBy(terminator string) LoadDataInfileStatement
+	IgnoreLines(n int) LoadDataInfileStatement
+	Columns(columns ...NonAliasColumn) LoadDataInfileStatement
+	SetClause(column NonAliasColumn, expression Expression) LoadDataInfileStatement
+}
+
+// ShowStatement reads server variables (SHOW VARIABLES) or status counters
+// (SHOW STATUS).  At most one of Like / Where may be specified.
+// See https://dev.mysql.com/doc/refman/8.0/en/show-variables.html
+type ShowStatement interface {
+	Statement
+
+	// Global / Session sets the scope of the variables.  When neither is
+	// specified, mysql defaults to the session scope.
+	Global() ShowStatement
+	Session() ShowStatement
+
+	Like(pattern string) ShowStatement
+	Where(expression BoolExpression) ShowStatement
+}
+
 //
 // UNION SELECT Statement ======================================================
 //
@@ -268,7 +341,10 @@ func (us *unionStatementImpl) String(database string) (sql string, err error) {
 			_, _ = buf.WriteString(fmt.Sprintf(" LIMIT %d", us.limit))
 		}
 	}
-	return buf.String(), nil
+
+	// The inner selects' lazy parameters are already inlined; the union's
+	// own clauses cannot reference (unbound) lazy parameters.
+	return inlineLazyParams(buf.String(), nil)
 }
 
 //
@@ -303,6 +379,12 @@ type selectStatementImpl struct {
 	withSharedLock bool
 	forUpdate      bool
 	distinct       bool
+
+	// Set by IntoOutfile.
+	outfile *outfileClause
+
+	// Set by BindParams.
+	params map[string]interface{}
 }
 
 func (s *selectStatementImpl) Copy() SelectStatement {
@@ -382,8 +464,46 @@ func (q *selectStatementImpl) Comment(comment string) SelectStatement {
 	return q
 }
 
-// Return the properly escaped SQL statement, against the specified database
+// Return the properly escaped SQL statement, against the specified database.
+// Lazy parameters are replaced by their bound values.
 func (q *selectStatementImpl) String(database string) (sql string, err error) {
+	sql, err = q.serialize(database)
+	if err != nil {
+		return "", err
+	}
+	return inlineLazyParams(sql, q.params)
+}
+
+func (q *selectStatementImpl) ToSql(
+	database string) (sql string, args []interface{}, err error) {
+
+	sql, err = q.serialize(database)
+	if err != nil {
+		return "", nil, err
+	}
+	return bindLazyParams(sql, q.params)
+}
+
+func (q *selectStatementImpl) BindParams(
+	params map[string]interface{}) SelectStatement {
+
+	bound := make(map[string]interface{}, len(q.params)+len(params))
+	for name, value := range q.params {
+		bound[name] = value
+	}
+	for name, value := range params {
+		bound[name] = value
+	}
+
+	ret := *q
+	ret.params = bound
+	return &ret
+}
+
+// This returns the generated sql, with lazy parameters unresolved.
+func (q *selectStatementImpl) serialize(
+	database string) (sql string, err error) {
+
 	if !validIdentifierName(database) {
 		return "", errors.New("Invalid database name specified")
 	}
@@ -456,6 +576,14 @@ func (q *selectStatementImpl) String(database string) (sql string, err error) {
 		}
 	}
 
+	if q.outfile != nil {
+		// NOTE: The INTO clause must precede the locking clause for
+		// compatibility with mysql versions prior to 8.0.20.
+		if err = q.outfile.SerializeSql(buf); err != nil {
+			return
+		}
+	}
+
 	if q.forUpdate {
 		_, _ = buf.WriteString(" FOR UPDATE")
 	} else if q.withSharedLock {
@@ -465,6 +593,155 @@ func (q *selectStatementImpl) String(database string) (sql string, err error) {
 	return buf.String(), nil
 }
 
+func (q *selectStatementImpl) CacheKey(database string) (string, error) {
+	return StatementCacheKey(q, database)
+}
+
+func (q *selectStatementImpl) IntoOutfile(
+	path string) OutfileSelectStatement {
+
+	stmt := *q
+	stmt.outfile = &outfileClause{path: path}
+	return &outfileSelectStatementImpl{
+		selectStmt: &stmt,
+		outfile:    stmt.outfile,
+	}
+}
+
+//
+// SELECT ... INTO OUTFILE Statement ===========================================
+//
+
+type outfileClause struct {
+	path string
+	fileFormat
+}
+
+func (c *outfileClause) SerializeSql(out *bytes.Buffer) error {
+	_, _ = out.WriteString(" INTO OUTFILE ")
+
+	if c.path == "" {
+		return errors.Newf("No file specified.  Generated sql: %s", out.String())
+	}
+
+	if err := Literal(c.path).SerializeSql(out); err != nil {
+		return err
+	}
+
+	return c.fileFormat.SerializeSql(out)
+}
+
+// The select statement is not embedded since the outfile statement must not
+// expose the select statement's methods (e.g., Where or CacheKey).
+type outfileSelectStatementImpl struct {
+	selectStmt *selectStatementImpl
+	outfile    *outfileClause
+}
+
+func (o *outfileSelectStatementImpl) String(database string) (string, error) {
+	return o.selectStmt.String(database)
+}
+
+func (o *outfileSelectStatementImpl) FieldsTerminatedBy(
+	separator string) OutfileSelectStatement {
+
+	o.outfile.fieldsTerminatedBy = &separator
+	return o
+}
+
+func (o *outfileSelectStatementImpl) FieldsEnclosedBy(
+	char byte) OutfileSelectStatement {
+
+	o.outfile.fieldsEnclosedBy = &char
+	return o
+}
+
+func (o *outfileSelectStatementImpl) FieldsEscapedBy(
+	char byte) OutfileSelectStatement {
+
+	o.outfile.fieldsEscapedBy = &char
+	return o
+}
+
+func (o *outfileSelectStatementImpl) LinesStartingBy(
+	prefix string) OutfileSelectStatement {
+
+	o.outfile.linesStartingBy = &prefix
+	return o
+}
+
+func (o *outfileSelectStatementImpl) LinesTerminatedBy(
+	terminator string) OutfileSelectStatement {
+
+	o.outfile.linesTerminatedBy = &terminator
+	return o
+}
+
+// The FIELDS / LINES clauses shared by LOAD DATA INFILE and SELECT ... INTO
+// OUTFILE.  Unset options are omitted (i.e., mysql's defaults are used).
+type fileFormat struct {
+	fieldsTerminatedBy *string
+	fieldsEnclosedBy   *byte
+	fieldsEscapedBy    *byte
+	linesStartingBy    *string
+	linesTerminatedBy  *string
+}
+
+func (f *fileFormat) SerializeSql(out *bytes.Buffer) error {
+	if f.fieldsTerminatedBy != nil ||
+		f.fieldsEnclosedBy != nil ||
+		f.fieldsEscapedBy != nil {
+
+		_, _ = out.WriteString(" FIELDS")
+
+		if f.fieldsTerminatedBy != nil {
+			_, _ = out.WriteString(" TERMINATED BY ")
+			err := Literal(*f.fieldsTerminatedBy).SerializeSql(out)
+			if err != nil {
+				return err
+			}
+		}
+
+		if f.fieldsEnclosedBy != nil {
+			_, _ = out.WriteString(" ENCLOSED BY ")
+			err := Literal(string([]byte{*f.fieldsEnclosedBy})).SerializeSql(out)
+			if err != nil {
+				return err
+			}
+		}
+
+		if f.fieldsEscapedBy != nil {
+			_, _ = out.WriteString(" ESCAPED BY ")
+			err := Literal(string([]byte{*f.fieldsEscapedBy})).SerializeSql(out)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if f.linesStartingBy != nil || f.linesTerminatedBy != nil {
+		_, _ = out.WriteString(" LINES")
+
+		if f.linesStartingBy != nil {
+			_, _ = out.WriteString(" STARTING BY ")
+			err := Literal(*f.linesStartingBy).SerializeSql(out)
+			if err != nil {
+				return err
+			}
+		}
+
+		if f.linesTerminatedBy != nil {
+			_, _ = out.WriteString(" TERMINATED BY ")
+			err := Literal(*f.linesTerminatedBy).SerializeSql(out)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 //
 // INSERT Statement ============================================================
 //
@@ -641,7 +918,7 @@ func (s *insertStatementImpl) String(database string) (sql string, err error) {
 		}
 	}
 
-	return buf.String(), nil
+	return inlineLazyParams(buf.String(), nil)
 }
 
 //
@@ -786,7 +1063,7 @@ func (u *updateStatementImpl) String(database string) (sql string, err error) {
 		_, _ = buf.WriteString(fmt.Sprintf(" LIMIT %d", u.limit))
 	}
 
-	return buf.String(), nil
+	return inlineLazyParams(buf.String(), nil)
 }
 
 //
@@ -872,7 +1149,7 @@ func (d *deleteStatementImpl) String(database string) (sql string, err error) {
 		_, _ = buf.WriteString(fmt.Sprintf(" LIMIT %d", d.limit))
 	}
 
-	return buf.String(), nil
+	return inlineLazyParams(buf.String(), nil)
 }
 
 //
@@ -982,6 +1259,354 @@ func (s *gtidNextStatementImpl) String(database string) (sql string, err error)
 	return buf.String(), nil
 }
 
+//
+// EXPLAIN statement ===========================================================
+//
+
+// The output format of EXPLAIN statements.
+type ExplainFormat int
+
+const (
+	ExplainTraditional ExplainFormat = iota
+	ExplainJSON
+	// NOTE: FORMAT=TREE requires MySQL 8.0.16+
+	ExplainTree
+)
+
+// NewExplainStatement returns a SQL statement that explains the execution
+// plan of the given statement.  The wrapped statement is serialized when the
+// explain statement is serialized, hence the same statement can be reused for
+// both EXPLAIN and execution.
+func NewExplainStatement(stmt Statement) ExplainStatement {
+	return &explainStatementImpl{
+		stmt:   stmt,
+		format: ExplainTraditional,
+	}
+}
+
+type explainStatementImpl struct {
+	stmt   Statement
+	format ExplainFormat
+}
+
+func (e *explainStatementImpl) Format(format ExplainFormat) ExplainStatement {
+	e.format = format
+	return e
+}
+
+func (e *explainStatementImpl) String(database string) (sql string, err error) {
+	buf := new(bytes.Buffer)
+	_, _ = buf.WriteString("EXPLAIN ")
+
+	switch e.format {
+	case ExplainTraditional:
+	case ExplainJSON:
+		_, _ = buf.WriteString("FORMAT=JSON ")
+	case ExplainTree:
+		_, _ = buf.WriteString("FORMAT=TREE ")
+	default:
+		return "", errors.Newf("Invalid explain format: %d", e.format)
+	}
+
+	switch e.stmt.(type) {
+	case SelectStatement, UnionStatement, InsertStatement,
+		UpdateStatement, DeleteStatement:
+	case nil:
+		return "", errors.Newf("nil statement.  Generated sql: %s", buf.String())
+	default:
+		return "", errors.Newf(
+			"Cannot explain %T.  Generated sql: %s",
+			e.stmt,
+			buf.String())
+	}
+
+	stmtSql, err := e.stmt.String(database)
+	if err != nil {
+		return "", err
+	}
+	_, _ = buf.WriteString(stmtSql)
+
+	return buf.String(), nil
+}
+
+//
+// LOAD DATA INFILE statement =================================================
+//
+
+// NewLoadDataInfileStatement returns a SQL statement which loads the rows
+// from the (server side) file into the table.  Unless specified otherwise,
+// mysql's default format is used, i.e., fields are terminated by tab, fields
+// are not enclosed, and lines are terminated by newline.
+func NewLoadDataInfileStatement() LoadDataInfileStatement {
+	return &loadDataInfileStatementImpl{}
+}
+
+type loadDataInfileStatementImpl struct {
+	path        string
+	table       WritableTable
+	format      fileFormat
+	ignoreLines int
+	columns     []NonAliasColumn
+	setValues   []columnAssignment
+}
+
+func (l *loadDataInfileStatementImpl) InFile(
+	path string) LoadDataInfileStatement {
+
+	l.path = path
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) IntoTable(
+	table WritableTable) LoadDataInfileStatement {
+
+	l.table = table
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) FieldsTerminatedBy(
+	separator string) LoadDataInfileStatement {
+
+	l.format.fieldsTerminatedBy = &separator
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) FieldsEnclosedBy(
+	char byte) LoadDataInfileStatement {
+
+	l.format.fieldsEnclosedBy = &char
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) LinesTerminatedBy(
+	terminator string) LoadDataInfileStatement {
+
+	l.format.linesTerminatedBy = &terminator
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) IgnoreLines(
+	n int) LoadDataInfileStatement {
+
+	l.ignoreLines = n
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) Columns(
+	columns ...NonAliasColumn) LoadDataInfileStatement {
+
+	l.columns = columns
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) SetClause(
+	column NonAliasColumn,
+	expression Expression) LoadDataInfileStatement {
+
+	l.setValues = append(
+		l.setValues,
+		columnAssignment{col: column, expr: expression})
+	return l
+}
+
+func (l *loadDataInfileStatementImpl) String(
+	database string) (sql string, err error) {
+
+	if !validIdentifierName(database) {
+		return "", errors.New("Invalid database name specified")
+	}
+
+	buf := new(bytes.Buffer)
+	_, _ = buf.WriteString("LOAD DATA INFILE ")
+
+	if l.path == "" {
+		return "", errors.Newf("No file specified.  Generated sql: %s", buf.String())
+	}
+
+	if err = Literal(l.path).SerializeSql(buf); err != nil {
+		return
+	}
+
+	_, _ = buf.WriteString(" INTO TABLE ")
+
+	if l.table == nil {
+		return "", errors.Newf("nil table.  Generated sql: %s", buf.String())
+	}
+
+	if err = l.table.SerializeSql(database, buf); err != nil {
+		return
+	}
+
+	if err = l.format.SerializeSql(buf); err != nil {
+		return
+	}
+
+	if l.ignoreLines < 0 {
+		return "", errors.Newf(
+			"Invalid number of ignored lines: %d.  Generated sql: %s",
+			l.ignoreLines,
+			buf.String())
+	}
+
+	if l.ignoreLines > 0 {
+		_, _ = buf.WriteString(fmt.Sprintf(" IGNORE %d LINES", l.ignoreLines))
+	}
+
+	if len(l.columns) > 0 {
+		_, _ = buf.WriteString(" (")
+		for i, col := range l.columns {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+
+			if col == nil {
+				return "", errors.Newf(
+					"nil column in columns list.  Generated sql: %s",
+					buf.String())
+			}
+
+			if err = col.SerializeSqlForColumnList(buf); err != nil {
+				return
+			}
+		}
+		_ = buf.WriteByte(')')
+	}
+
+	for i, assignment := range l.setValues {
+		if i == 0 {
+			_, _ = buf.WriteString(" SET ")
+		} else {
+			_, _ = buf.WriteString(", ")
+		}
+
+		if assignment.col == nil {
+			return "", errors.Newf(
+				"nil column.  Generated sql: %s",
+				buf.String())
+		}
+
+		if assignment.expr == nil {
+			return "", errors.Newf(
+				"nil value.  Generated sql: %s",
+				buf.String())
+		}
+
+		if err = assignment.col.SerializeSqlForColumnList(buf); err != nil {
+			return
+		}
+
+		_ = buf.WriteByte('=')
+		if err = assignment.expr.SerializeSql(buf); err != nil {
+			return
+		}
+	}
+
+	return inlineLazyParams(buf.String(), nil)
+}
+
+//
+// SHOW VARIABLES / SHOW STATUS statement =====================================
+//
+
+// The columns of SHOW VARIABLES / SHOW STATUS results.  These are useful for
+// building ShowStatement's WHERE clause.
+var (
+	ShowVariableNameColumn = StrColumn(
+		"Variable_name",
+		UTF8,
+		UTF8CaseInsensitive,
+		NotNullable)
+	ShowValueColumn = StrColumn(
+		"Value",
+		UTF8,
+		UTF8CaseInsensitive,
+		Nullable)
+)
+
+type showScope int
+
+const (
+	showDefaultScope showScope = iota
+	showGlobalScope
+	showSessionScope
+)
+
+// NewShowVariablesStatement returns a SHOW VARIABLES statement.
+func NewShowVariablesStatement() ShowStatement {
+	return &showStatementImpl{what: "VARIABLES"}
+}
+
+// NewShowStatusStatement returns a SHOW STATUS statement.
+func NewShowStatusStatement() ShowStatement {
+	return &showStatementImpl{what: "STATUS"}
+}
+
+type showStatementImpl struct {
+	what  string
+	scope showScope
+	like  *string
+	where BoolExpression
+}
+
+func (s *showStatementImpl) Global() ShowStatement {
+	s.scope = showGlobalScope
+	return s
+}
+
+func (s *showStatementImpl) Session() ShowStatement {
+	s.scope = showSessionScope
+	return s
+}
+
+func (s *showStatementImpl) Like(pattern string) ShowStatement {
+	s.like = &pattern
+	return s
+}
+
+func (s *showStatementImpl) Where(expression BoolExpression) ShowStatement {
+	s.where = expression
+	return s
+}
+
+// NOTE: SHOW VARIABLES / SHOW STATUS are not database specific; the database
+// is ignored.
+func (s *showStatementImpl) String(database string) (sql string, err error) {
+	buf := new(bytes.Buffer)
+	_, _ = buf.WriteString("SHOW ")
+
+	switch s.scope {
+	case showDefaultScope:
+	case showGlobalScope:
+		_, _ = buf.WriteString("GLOBAL ")
+	case showSessionScope:
+		_, _ = buf.WriteString("SESSION ")
+	}
+
+	_, _ = buf.WriteString(s.what)
+
+	if s.like != nil && s.where != nil {
+		return "", errors.Newf(
+			"Cannot specify both LIKE and WHERE.  Generated sql: %s",
+			buf.String())
+	}
+
+	if s.like != nil {
+		_, _ = buf.WriteString(" LIKE ")
+		if err = Literal(*s.like).SerializeSql(buf); err != nil {
+			return
+		}
+	}
+
+	if s.where != nil {
+		_, _ = buf.WriteString(" WHERE ")
+		if err = s.where.SerializeSql(buf); err != nil {
+			return
+		}
+	}
+
+	return inlineLazyParams(buf.String(), nil)
+}
+
 //
 // Util functions =============================================================
 //
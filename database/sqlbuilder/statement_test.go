@@ -658,3 +658,251 @@ func (s *StmtSuite) TestComplicatedUnionSelectWithWhereStatement(c *gc.C) {
 			"LIMIT 5")
 
 }
+
+func (s *StmtSuite) TestExplainStatement(c *gc.C) {
+	q := table1.Select(table1Col1).Where(EqL(table1Col2, 1))
+
+	sql, err := NewExplainStatement(q).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"EXPLAIN SELECT `table1`.`col1` FROM `db`.`table1` "+
+			"WHERE `table1`.`col2`=1")
+
+	sql, err = NewExplainStatement(q).Format(ExplainJSON).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"EXPLAIN FORMAT=JSON SELECT `table1`.`col1` FROM `db`.`table1` "+
+			"WHERE `table1`.`col2`=1")
+
+	d := table1.Delete().Where(EqL(table1Col1, 1))
+	sql, err = NewExplainStatement(d).Format(ExplainTree).String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"EXPLAIN FORMAT=TREE DELETE FROM `db`.`table1` "+
+			"WHERE `table1`.`col1`=1")
+
+	// The wrapped statement is not modified.
+	sql, err = q.String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1` FROM `db`.`table1` WHERE `table1`.`col2`=1")
+}
+
+func (s *StmtSuite) TestExplainStatementErrors(c *gc.C) {
+	_, err := NewExplainStatement(NewUnlockStatement()).String("db")
+	c.Assert(err, gc.NotNil)
+
+	_, err = NewExplainStatement(nil).String("db")
+	c.Assert(err, gc.NotNil)
+
+	q := table1.Select(table1Col1)
+	_, err = NewExplainStatement(q).Format(ExplainFormat(10)).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Errors from the wrapped statement are propagated.
+	_, err = NewExplainStatement(table1.Delete()).String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *StmtSuite) TestLoadDataInfileStatement(c *gc.C) {
+	sql, err := NewLoadDataInfileStatement().
+		InFile("/tmp/data.csv").
+		IntoTable(table1).
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"LOAD DATA INFILE '/tmp/data.csv' INTO TABLE `db`.`table1`")
+
+	sql, err = NewLoadDataInfileStatement().
+		InFile("/tmp/it's.csv").
+		IntoTable(table1).
+		FieldsTerminatedBy(",").
+		FieldsEnclosedBy('"').
+		LinesTerminatedBy("\r\n").
+		IgnoreLines(1).
+		Columns(table1Col1, table1Col2).
+		SetClause(table1Col3, Add(table1Col1, Literal(1))).
+		SetClause(table1Col4, SqlFunc("NOW")).
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"LOAD DATA INFILE '/tmp/it\\'s.csv' INTO TABLE `db`.`table1` "+
+			"FIELDS TERMINATED BY ',' ENCLOSED BY '\\\"' "+
+			"LINES TERMINATED BY '\\r\\n' IGNORE 1 LINES "+
+			"(`table1`.`col1`,`table1`.`col2`) "+
+			"SET `table1`.`col3`=(`table1`.`col1` + 1), `table1`.`col4`=NOW()")
+}
+
+func (s *StmtSuite) TestSelectIntoOutfile(c *gc.C) {
+	sql, err := table1.Select(table1Col1, table1Col2).
+		Where(GtL(table1Col1, 1)).
+		IntoOutfile("/tmp/out.csv").
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1`,`table1`.`col2` FROM `db`.`table1` "+
+			"WHERE `table1`.`col1`>1 INTO OUTFILE '/tmp/out.csv'")
+
+	sql, err = table1.Select(table1Col1, table1Col2).
+		OrderBy(table1Col1).
+		Limit(10).
+		ForUpdate().
+		IntoOutfile("/tmp/it's.csv").
+		FieldsTerminatedBy(",").
+		FieldsEnclosedBy('"').
+		FieldsEscapedBy('\\').
+		LinesStartingBy("> ").
+		LinesTerminatedBy("\n").
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1`,`table1`.`col2` FROM `db`.`table1` "+
+			"ORDER BY `table1`.`col1` LIMIT 10 "+
+			"INTO OUTFILE '/tmp/it\\'s.csv' "+
+			"FIELDS TERMINATED BY ',' ENCLOSED BY '\\\"' ESCAPED BY '\\\\' "+
+			"LINES STARTING BY '> ' TERMINATED BY '\\n' FOR UPDATE")
+}
+
+func (s *StmtSuite) TestSelectIntoOutfileCopiesSelect(c *gc.C) {
+	selectStmt := table1.Select(table1Col1)
+	outfile := selectStmt.IntoOutfile("/tmp/out.csv")
+
+	selectStmt.Where(GtL(table1Col1, 1))
+
+	sql, err := outfile.String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1` FROM `db`.`table1` INTO OUTFILE '/tmp/out.csv'")
+
+	sql, err = selectStmt.String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SELECT `table1`.`col1` FROM `db`.`table1` WHERE `table1`.`col1`>1")
+}
+
+func (s *StmtSuite) TestSelectIntoOutfileErrors(c *gc.C) {
+	// No file
+	_, err := table1.Select(table1Col1).IntoOutfile("").String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid database
+	_, err = table1.Select(table1Col1).IntoOutfile("/tmp/out.csv").String("db`")
+	c.Assert(err, gc.NotNil)
+
+	// No column selected
+	_, err = table1.Select().IntoOutfile("/tmp/out.csv").String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *StmtSuite) TestLoadDataInfileStatementErrors(c *gc.C) {
+	// No file
+	_, err := NewLoadDataInfileStatement().IntoTable(table1).String("db")
+	c.Assert(err, gc.NotNil)
+
+	// No table
+	_, err = NewLoadDataInfileStatement().InFile("/tmp/data.csv").String("db")
+	c.Assert(err, gc.NotNil)
+
+	// Invalid database
+	_, err = NewLoadDataInfileStatement().
+		InFile("/tmp/data.csv").
+		IntoTable(table1).
+		String("db`")
+	c.Assert(err, gc.NotNil)
+
+	// Negative ignore lines
+	_, err = NewLoadDataInfileStatement().
+		InFile("/tmp/data.csv").
+		IntoTable(table1).
+		IgnoreLines(-1).
+		String("db")
+	c.Assert(err, gc.NotNil)
+
+	// nil column
+	_, err = NewLoadDataInfileStatement().
+		InFile("/tmp/data.csv").
+		IntoTable(table1).
+		Columns(table1Col1, nil).
+		String("db")
+	c.Assert(err, gc.NotNil)
+
+	// nil set value
+	_, err = NewLoadDataInfileStatement().
+		InFile("/tmp/data.csv").
+		IntoTable(table1).
+		SetClause(table1Col1, nil).
+		String("db")
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *StmtSuite) TestShowStatement(c *gc.C) {
+	sql, err := NewShowVariablesStatement().String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(sql, gc.Equals, "SHOW VARIABLES")
+
+	sql, err = NewShowVariablesStatement().
+		Like("max_connections").
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(sql, gc.Equals, "SHOW VARIABLES LIKE 'max_connections'")
+
+	sql, err = NewShowVariablesStatement().
+		Global().
+		Like("innodb_" + EscapeForLike("buffer_pool") + "%").
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SHOW GLOBAL VARIABLES LIKE 'innodb_buffer\\_pool%'")
+
+	sql, err = NewShowStatusStatement().
+		Where(EqL(ShowVariableNameColumn, "Threads_connected")).
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SHOW STATUS WHERE `Variable_name`='Threads_connected'")
+
+	sql, err = NewShowStatusStatement().
+		Session().
+		Where(Or(
+			EqL(ShowVariableNameColumn, "Uptime"),
+			EqL(ShowValueColumn, "ON"))).
+		String("db")
+	c.Assert(err, gc.IsNil)
+	c.Assert(
+		sql,
+		gc.Equals,
+		"SHOW SESSION STATUS WHERE "+
+			"(`Variable_name`='Uptime' OR `Value`='ON')")
+}
+
+func (s *StmtSuite) TestShowStatementErrors(c *gc.C) {
+	_, err := NewShowStatusStatement().
+		Like("Uptime").
+		Where(EqL(ShowVariableNameColumn, "Uptime")).
+		String("db")
+	c.Assert(err, gc.NotNil)
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"strconv"
+)
+
+// Predefined application error codes.  The codes match the http status
+// codes with the same semantic.
+const (
+	CodeBadRequest      = 400
+	CodeUnauthorized    = 401
+	CodeForbidden       = 403
+	CodeNotFound        = 404
+	CodeConflict        = 409
+	CodeTooManyRequests = 429
+	CodeInternal        = 500
+	CodeNotImplemented  = 501
+	CodeUnavailable     = 503
+	CodeTimeout         = 504
+)
+
+// An error which carries an application defined error code.
+type CodedError interface {
+	error
+
+	// ErrorCode returns the error's application defined error code.
+	ErrorCode() int
+}
+
+// Error wrapper which carries an application defined error code.
+type codedError struct {
+	*baseError
+	code int
+}
+
+func (e *codedError) ErrorCode() int {
+	return e.code
+}
+
+// This wraps the error with the given error code.  The code can be extracted
+// from the error chain using Code.  This returns nil when err is nil.
+func WithCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+
+	return &codedError{
+		baseError: newBaseError(err, "Error code: "+strconv.Itoa(code)),
+		code:      code,
+	}
+}
+
+// This returns the (outermost) error code in the error chain.  The second
+// return value is false if none of the errors in the chain implements
+// CodedError.
+func Code(err error) (int, bool) {
+	found := findInChain(err, func(e error) bool {
+		_, ok := e.(CodedError)
+		return ok
+	})
+	if e, ok := found.(CodedError); ok {
+		return e.ErrorCode(), true
+	}
+
+	return 0, false
+}
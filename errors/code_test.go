@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type customCodedError struct {
+	code int
+}
+
+func (e *customCodedError) Error() string {
+	return "custom"
+}
+
+func (e *customCodedError) ErrorCode() int {
+	return e.code
+}
+
+func TestCode(t *testing.T) {
+	inner := fmt.Errorf("inner")
+
+	_, ok := Code(inner)
+	require.False(t, ok)
+
+	_, ok = Code(nil)
+	require.False(t, ok)
+
+	require.Nil(t, WithCode(nil, CodeNotFound))
+
+	err := WithCode(inner, CodeNotFound)
+	code, ok := Code(err)
+	require.True(t, ok)
+	require.Equal(t, 404, code)
+	require.Equal(t, inner, RootError(err))
+
+	if strings.Index(err.Error(), "Error code: 404\ninner") == -1 {
+		t.Errorf("couldn't find error code in:\n%s", err.Error())
+	}
+}
+
+func TestCodeWrapped(t *testing.T) {
+	err := Wrap(WithCode(New("inner"), CodeNotFound), "middle")
+
+	code, ok := Code(err)
+	require.True(t, ok)
+	require.Equal(t, CodeNotFound, code)
+
+	// The outermost code wins.
+	err = Wrap(WithCode(err, CodeInternal), "outer")
+
+	code, ok = Code(err)
+	require.True(t, ok)
+	require.Equal(t, CodeInternal, code)
+
+	// Standard library wrapped errors are also traversed.
+	code, ok = Code(fmt.Errorf("std: %w", err))
+	require.True(t, ok)
+	require.Equal(t, CodeInternal, code)
+}
+
+func TestCustomCodedError(t *testing.T) {
+	var err error = &customCodedError{code: CodeConflict}
+
+	code, ok := Code(err)
+	require.True(t, ok)
+	require.Equal(t, CodeConflict, code)
+
+	code, ok = Code(Wrap(err, "wrapped"))
+	require.True(t, ok)
+	require.Equal(t, CodeConflict, code)
+
+	code, ok = Code(WithCode(err, CodeUnavailable))
+	require.True(t, ok)
+	require.Equal(t, CodeUnavailable, code)
+}
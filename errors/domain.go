@@ -0,0 +1,73 @@
+package errors
+
+// Domain categorizes application errors by the subsystem they originate
+// from (e.g., for alerting rules which treat database errors differently
+// than authentication errors).
+type Domain string
+
+// Predefined error domains.
+const (
+	DomainDB         Domain = "db"
+	DomainNetwork    Domain = "network"
+	DomainAuth       Domain = "auth"
+	DomainValidation Domain = "validation"
+	DomainStorage    Domain = "storage"
+	DomainInternal   Domain = "internal"
+)
+
+// An error which belongs to an error domain.
+type DomainError interface {
+	error
+
+	// ErrorDomain returns the error's domain.
+	ErrorDomain() Domain
+}
+
+// Error wrapper which carries an error domain.
+type domainError struct {
+	*baseError
+	domain Domain
+}
+
+func (e *domainError) ErrorDomain() Domain {
+	return e.domain
+}
+
+// This wraps the error with the given domain.  The domain can be extracted
+// from the error chain using DomainOf.  This returns nil when err is nil.
+func WithDomain(err error, domain Domain) error {
+	if err == nil {
+		return nil
+	}
+
+	return &domainError{
+		baseError: newBaseError(err, "Error domain: "+string(domain)),
+		domain:    domain,
+	}
+}
+
+// This returns the (outermost) domain in the error chain.  The second return
+// value is false if none of the errors in the chain implements DomainError.
+func DomainOf(err error) (Domain, bool) {
+	found := findInChain(err, func(e error) bool {
+		_, ok := e.(DomainError)
+		return ok
+	})
+	if e, ok := found.(DomainError); ok {
+		return e.ErrorDomain(), true
+	}
+
+	return "", false
+}
+
+// This returns the errors (in order) whose (outermost) domain is the given
+// domain.  Nil errors are skipped.
+func FilterByDomain(errs []error, domain Domain) []error {
+	var result []error
+	for _, err := range errs {
+		if d, ok := DomainOf(err); ok && d == domain {
+			result = append(result, err)
+		}
+	}
+	return result
+}
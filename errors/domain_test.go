@@ -0,0 +1,96 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type customDomainError struct {
+	domain Domain
+}
+
+func (e *customDomainError) Error() string {
+	return "custom"
+}
+
+func (e *customDomainError) ErrorDomain() Domain {
+	return e.domain
+}
+
+func TestDomain(t *testing.T) {
+	inner := fmt.Errorf("inner")
+
+	_, ok := DomainOf(inner)
+	require.False(t, ok)
+
+	_, ok = DomainOf(nil)
+	require.False(t, ok)
+
+	require.Nil(t, WithDomain(nil, DomainDB))
+
+	err := WithDomain(inner, DomainDB)
+	domain, ok := DomainOf(err)
+	require.True(t, ok)
+	require.Equal(t, DomainDB, domain)
+	require.Equal(t, inner, RootError(err))
+
+	if strings.Index(err.Error(), "Error domain: db\ninner") == -1 {
+		t.Errorf("couldn't find error domain in:\n%s", err.Error())
+	}
+}
+
+func TestDomainWrapped(t *testing.T) {
+	err := Wrap(WithDomain(New("inner"), DomainNetwork), "middle")
+
+	domain, ok := DomainOf(err)
+	require.True(t, ok)
+	require.Equal(t, DomainNetwork, domain)
+
+	// The outermost domain wins.
+	err = Wrap(WithDomain(err, DomainDB), "outer")
+
+	domain, ok = DomainOf(err)
+	require.True(t, ok)
+	require.Equal(t, DomainDB, domain)
+
+	// Standard library wrapped errors are also traversed.
+	domain, ok = DomainOf(fmt.Errorf("std: %w", err))
+	require.True(t, ok)
+	require.Equal(t, DomainDB, domain)
+
+	// Domains and codes are independent.
+	coded := WithCode(WithDomain(New("denied"), DomainAuth), CodeForbidden)
+
+	domain, ok = DomainOf(coded)
+	require.True(t, ok)
+	require.Equal(t, DomainAuth, domain)
+
+	code, ok := Code(coded)
+	require.True(t, ok)
+	require.Equal(t, CodeForbidden, code)
+}
+
+func TestCustomDomainError(t *testing.T) {
+	var err error = &customDomainError{domain: Domain("billing")}
+
+	domain, ok := DomainOf(Wrap(err, "wrapped"))
+	require.True(t, ok)
+	require.Equal(t, Domain("billing"), domain)
+}
+
+func TestFilterByDomain(t *testing.T) {
+	dbErr1 := WithDomain(New("deadlock"), DomainDB)
+	dbErr2 := Wrap(WithDomain(New("too many connections"), DomainDB), "get")
+	authErr := WithDomain(New("bad token"), DomainAuth)
+	plainErr := New("plain")
+
+	errs := []error{dbErr1, authErr, nil, plainErr, dbErr2}
+
+	require.Equal(t, []error{dbErr1, dbErr2}, FilterByDomain(errs, DomainDB))
+	require.Equal(t, []error{authErr}, FilterByDomain(errs, DomainAuth))
+	require.Nil(t, FilterByDomain(errs, DomainValidation))
+	require.Nil(t, FilterByDomain(nil, DomainDB))
+}
@@ -218,6 +218,25 @@ func unwrapError(ierr error) (nerr error) {
 	return errV.Interface().(error)
 }
 
+// Returns the outermost error in the chain (following Unwrap) which
+// satisfies the predicate, or nil if there is none.  The chain's depth is
+// bounded to guard against cyclic chains.
+func findInChain(err error, pred func(error) bool) error {
+	for i := 0; err != nil && i < 100; i++ {
+		if pred(err) {
+			return err
+		}
+
+		unwrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = unwrapper.Unwrap()
+	}
+
+	return nil
+}
+
 // Keep peeling away layers or context until a primitive error is revealed.
 func RootError(ierr error) (nerr error) {
 	nerr = ierr
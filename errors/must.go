@@ -0,0 +1,40 @@
+package errors
+
+// Error wrapper used as the panic value by Must / MustNoError.
+type mustError struct {
+	*baseError
+}
+
+// The wrapped error's message and original stack trace, followed by the
+// Must call site's stack trace (when the wrapped error has its own stack).
+func (e *mustError) Error() string {
+	msg := extractFullErrorMessage(e, true)
+	if _, ok := e.inner.(DropboxError); ok {
+		msg += "\nMUST CALL SITE STACK TRACE:\n" + e.GetStack()
+	}
+	return msg
+}
+
+const mustErrorMessage = "Must: unexpected error"
+
+// Must returns val when err is nil, and panics otherwise.  The panic value is
+// an error which wraps err, with the stack trace of the Must call site.  This
+// is meant for test helpers and program initialization, e.g.,
+//
+//	var tmpl = errors.Must(template.New("t").Parse(text)).(*template.Template)
+//
+// NOTE: The caller must type assert the returned value (this package does not
+// depend on generics).
+func Must(val interface{}, err error) interface{} {
+	if err != nil {
+		panic(&mustError{baseError: newBaseError(err, mustErrorMessage)})
+	}
+	return val
+}
+
+// MustNoError panics when err is not nil.  See Must for details.
+func MustNoError(err error) {
+	if err != nil {
+		panic(&mustError{baseError: newBaseError(err, mustErrorMessage)})
+	}
+}
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func recoverMustPanic(fn func()) (recovered error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recovered = r.(error)
+		}
+	}()
+	fn()
+	return nil
+}
+
+func TestMust(t *testing.T) {
+	require.Equal(t, 5, Must(5, nil).(int))
+
+	inner := fmt.Errorf("inner")
+	err := recoverMustPanic(func() {
+		_ = Must(nil, inner)
+	})
+	require.NotNil(t, err)
+	require.Equal(t, inner, RootError(err))
+	require.Equal(t, "Must: unexpected error\ninner", GetMessage(err))
+
+	// The stack trace starts at the Must call site.
+	stack := err.(DropboxError).GetStack()
+	require.True(t, strings.Contains(stack, "TestMust"), stack)
+	require.False(t, strings.Contains(stack, "errors.Must"), stack)
+	require.True(t, strings.Contains(err.Error(), "TestMust"))
+}
+
+func TestMustNoError(t *testing.T) {
+	require.Nil(t, recoverMustPanic(func() { MustNoError(nil) }))
+
+	inner := New("inner")
+	err := recoverMustPanic(func() {
+		MustNoError(inner)
+	})
+	require.NotNil(t, err)
+	require.Equal(t, inner, RootError(err))
+
+	// Both the original and the call site stack traces are included.
+	msg := err.Error()
+	require.True(t, strings.Contains(msg, "ORIGINAL STACK TRACE:"), msg)
+	require.True(t, strings.Contains(msg, "MUST CALL SITE STACK TRACE:"), msg)
+	require.True(t, strings.Contains(msg, "TestMustNoError.func2"), msg)
+}
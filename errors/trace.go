@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"context"
+)
+
+type contextKey struct {
+	name string
+}
+
+// The context key for the request's trace id.  The context value must be a
+// string.
+var TraceIDContextKey = &contextKey{"trace-id"}
+
+// Error wrapper which carries the trace id of the request / operation which
+// originated the error.
+type traceIDError struct {
+	*baseError
+	traceID string
+}
+
+func traceIDMessage(traceID string) string {
+	return "Trace ID: " + traceID
+}
+
+// This wraps the error with the given trace id.  The trace id can be
+// extracted from the error chain using TraceID.  This returns err as is when
+// err is nil or traceID is empty.
+func WithTraceID(err error, traceID string) error {
+	if err == nil || traceID == "" {
+		return err
+	}
+
+	return &traceIDError{
+		baseError: newBaseError(err, traceIDMessage(traceID)),
+		traceID:   traceID,
+	}
+}
+
+// Same as WithTraceID, but uses the trace id stored in the context (under
+// TraceIDContextKey).  This returns err as is when the context does not
+// have a trace id.
+func WithContextTraceID(ctx context.Context, err error) error {
+	if err == nil || ctx == nil {
+		return err
+	}
+
+	traceID, ok := ctx.Value(TraceIDContextKey).(string)
+	if !ok || traceID == "" {
+		return err
+	}
+
+	return &traceIDError{
+		baseError: newBaseError(err, traceIDMessage(traceID)),
+		traceID:   traceID,
+	}
+}
+
+// This returns a copy of the parent context which carries the trace id.
+func ContextWithTraceID(
+	parent context.Context,
+	traceID string) context.Context {
+
+	return context.WithValue(parent, TraceIDContextKey, traceID)
+}
+
+// This returns the (outermost) trace id in the error chain.  The second
+// return value is false if none of the errors in the chain has a trace id.
+func TraceID(err error) (string, bool) {
+	found := findInChain(err, func(e error) bool {
+		_, ok := e.(*traceIDError)
+		return ok
+	})
+	if e, ok := found.(*traceIDError); ok {
+		return e.traceID, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceID(t *testing.T) {
+	inner := fmt.Errorf("inner")
+
+	_, ok := TraceID(inner)
+	require.False(t, ok)
+
+	_, ok = TraceID(nil)
+	require.False(t, ok)
+
+	require.Nil(t, WithTraceID(nil, "abc"))
+	require.Equal(t, inner, WithTraceID(inner, ""))
+
+	err := WithTraceID(inner, "abc")
+	traceID, ok := TraceID(err)
+	require.True(t, ok)
+	require.Equal(t, "abc", traceID)
+	require.Equal(t, inner, RootError(err))
+
+	if strings.Index(err.Error(), "Trace ID: abc\ninner") == -1 {
+		t.Errorf("couldn't find trace id in:\n%s", err.Error())
+	}
+
+	if strings.Index(err.Error(), "errors.TestTraceID") == -1 {
+		t.Errorf("couldn't find this function in stack trace:\n%s", err.Error())
+	}
+}
+
+func TestTraceIDWrapped(t *testing.T) {
+	err := Wrap(WithTraceID(New("inner"), "inner-id"), "middle")
+
+	traceID, ok := TraceID(err)
+	require.True(t, ok)
+	require.Equal(t, "inner-id", traceID)
+
+	// The outermost trace id wins.
+	err = Wrap(WithTraceID(err, "outer-id"), "outer")
+
+	traceID, ok = TraceID(err)
+	require.True(t, ok)
+	require.Equal(t, "outer-id", traceID)
+
+	// Standard library wrapped errors are also traversed.
+	stdErr := fmt.Errorf("std: %w", err)
+
+	traceID, ok = TraceID(stdErr)
+	require.True(t, ok)
+	require.Equal(t, "outer-id", traceID)
+}
+
+func TestWithContextTraceID(t *testing.T) {
+	inner := New("inner")
+
+	require.Equal(t, inner, WithContextTraceID(context.Background(), inner))
+
+	ctx := ContextWithTraceID(context.Background(), "ctx-id")
+	require.Nil(t, WithContextTraceID(ctx, nil))
+
+	err := WithContextTraceID(ctx, inner)
+	traceID, ok := TraceID(err)
+	require.True(t, ok)
+	require.Equal(t, "ctx-id", traceID)
+
+	ctx = context.WithValue(context.Background(), TraceIDContextKey, 1234)
+	require.Equal(t, inner, WithContextTraceID(ctx, inner))
+}
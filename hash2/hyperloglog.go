@@ -0,0 +1,181 @@
+package hash2
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const (
+	MinHyperLogLogPrecision = 4
+	MaxHyperLogLogPrecision = 18
+
+	hyperLogLogEncodingVersion = 1
+)
+
+// HyperLogLog estimates the number of distinct items in a stream using a
+// fixed amount of memory (2^precision bytes).  The estimate's relative
+// standard error is roughly 1.04 / sqrt(2^precision), e.g., 0.81% for
+// precision 14 (16KB).
+//
+// Implementation details: each item is hashed using 64-bit FNV-1a, mixed
+// using murmur's 64-bit finalizer.  Small cardinalities are estimated using
+// linear counting.  See "HyperLogLog: the analysis of a near-optimal
+// cardinality estimation algorithm" by Flajolet et al. for additional details.
+//
+// HyperLogLog is not thread safe.
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+// This returns an empty HyperLogLog with 2^precision registers.  precision
+// must be within [4, 18].
+func NewHyperLogLog(precision uint8) (*HyperLogLog, error) {
+	if precision < MinHyperLogLogPrecision ||
+		precision > MaxHyperLogLogPrecision {
+
+		return nil, errors.Newf(
+			"Invalid precision: %d (must be between %d and %d)",
+			precision,
+			MinHyperLogLogPrecision,
+			MaxHyperLogLogPrecision)
+	}
+
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// Precision returns the number of register index bits.
+func (h *HyperLogLog) Precision() uint8 {
+	return h.precision
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// Add adds the item to the set.
+func (h *HyperLogLog) Add(item []byte) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write(item)
+	hash := fmix64(hasher.Sum64())
+
+	idx := hash >> (64 - h.precision)
+
+	// The sentinel bit bounds the rank when the remaining bits are all zero.
+	remaining := hash<<h.precision | 1<<(h.precision-1)
+	rank := uint8(bits.LeadingZeros64(remaining) + 1)
+
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Count returns the estimated number of distinct items added to the set.
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1.0 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// Merge adds all items in other to this set (i.e., this set becomes the
+// union of the two sets).  Both sets must have the same precision.
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if other.precision != h.precision {
+		return errors.Newf(
+			"Cannot merge HyperLogLogs with different precisions (%d vs %d)",
+			h.precision,
+			other.precision)
+	}
+
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the set as: version (1 byte), precision (1 byte),
+// registers (2^precision bytes).
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 2, 2+len(h.registers))
+	data[0] = hyperLogLogEncodingVersion
+	data[1] = h.precision
+	return append(data, h.registers...), nil
+}
+
+// UnmarshalBinary replaces the set's content with the set encoded by
+// MarshalBinary.
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("HyperLogLog data is too short")
+	}
+
+	if data[0] != hyperLogLogEncodingVersion {
+		return errors.Newf("Unsupported HyperLogLog version: %d", data[0])
+	}
+
+	precision := data[1]
+	if precision < MinHyperLogLogPrecision ||
+		precision > MaxHyperLogLogPrecision {
+
+		return errors.Newf("Invalid precision: %d", precision)
+	}
+
+	registers := data[2:]
+	if len(registers) != 1<<precision {
+		return errors.Newf(
+			"Invalid number of registers: %d (expected %d)",
+			len(registers),
+			1<<precision)
+	}
+
+	maxRank := uint8(64 - precision + 1)
+	for _, r := range registers {
+		if r > maxRank {
+			return errors.Newf("Invalid register value: %d", r)
+		}
+	}
+
+	h.precision = precision
+	h.registers = append([]uint8{}, registers...)
+	return nil
+}
@@ -0,0 +1,119 @@
+package hash2
+
+import (
+	"encoding/binary"
+	"math"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type HyperLogLogSuite struct {
+}
+
+var _ = Suite(&HyperLogLogSuite{})
+
+func uint64Item(i uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, i)
+	return b
+}
+
+func newHyperLogLog(c *C, precision uint8) *HyperLogLog {
+	h, err := NewHyperLogLog(precision)
+	c.Assert(err, IsNil)
+	return h
+}
+
+func relativeError(estimate uint64, actual uint64) float64 {
+	return math.Abs(float64(estimate)-float64(actual)) / float64(actual)
+}
+
+func (s *HyperLogLogSuite) TestInvalidPrecision(c *C) {
+	_, err := NewHyperLogLog(3)
+	c.Assert(err, NotNil)
+
+	_, err = NewHyperLogLog(19)
+	c.Assert(err, NotNil)
+
+	h := newHyperLogLog(c, 4)
+	c.Assert(h.Precision(), Equals, uint8(4))
+
+	h = newHyperLogLog(c, 18)
+	c.Assert(h.Precision(), Equals, uint8(18))
+}
+
+func (s *HyperLogLogSuite) TestEmpty(c *C) {
+	h := newHyperLogLog(c, 14)
+	c.Assert(h.Count(), Equals, uint64(0))
+}
+
+func (s *HyperLogLogSuite) TestSmallCardinality(c *C) {
+	h := newHyperLogLog(c, 14)
+	for i := uint64(0); i < 100; i++ {
+		h.Add(uint64Item(i))
+		// Duplicates do not affect the estimate.
+		h.Add(uint64Item(i))
+	}
+	c.Assert(h.Count(), Equals, uint64(100))
+}
+
+func (s *HyperLogLogSuite) TestErrorRate(c *C) {
+	h := newHyperLogLog(c, 14)
+
+	actual := uint64(1000000)
+	for i := uint64(0); i < actual; i++ {
+		h.Add(uint64Item(i))
+	}
+
+	c.Assert(relativeError(h.Count(), actual) < 0.02, IsTrue)
+}
+
+func (s *HyperLogLogSuite) TestMerge(c *C) {
+	h1 := newHyperLogLog(c, 12)
+	h2 := newHyperLogLog(c, 12)
+
+	// 10000 items in h1, 10000 items in h2, 5000 items in common.
+	for i := uint64(0); i < 10000; i++ {
+		h1.Add(uint64Item(i))
+		h2.Add(uint64Item(i + 5000))
+	}
+
+	c.Assert(h1.Merge(h2), IsNil)
+	c.Assert(relativeError(h1.Count(), 15000) < 0.05, IsTrue)
+
+	// h2 is unmodified.
+	c.Assert(relativeError(h2.Count(), 10000) < 0.05, IsTrue)
+
+	c.Assert(h1.Merge(newHyperLogLog(c, 13)), NotNil)
+}
+
+func (s *HyperLogLogSuite) TestMarshalBinary(c *C) {
+	h := newHyperLogLog(c, 10)
+	for i := uint64(0); i < 5000; i++ {
+		h.Add(uint64Item(i))
+	}
+
+	data, err := h.MarshalBinary()
+	c.Assert(err, IsNil)
+	c.Assert(len(data), Equals, 2+1024)
+
+	restored := newHyperLogLog(c, 4)
+	c.Assert(restored.UnmarshalBinary(data), IsNil)
+	c.Assert(restored.Precision(), Equals, uint8(10))
+	c.Assert(restored.Count(), Equals, h.Count())
+
+	// The restored set is independent of the encoded data.
+	data[2] = 0
+	c.Assert(restored.Count(), Equals, h.Count())
+
+	invalid := newHyperLogLog(c, 4)
+	c.Assert(invalid.UnmarshalBinary(nil), NotNil)
+	c.Assert(invalid.UnmarshalBinary([]byte{2, 10}), NotNil)
+	c.Assert(invalid.UnmarshalBinary([]byte{1, 3}), NotNil)
+	c.Assert(invalid.UnmarshalBinary(data[:100]), NotNil)
+
+	data[2] = 64
+	c.Assert(invalid.UnmarshalBinary(data), NotNil)
+}
@@ -0,0 +1,177 @@
+package hash2
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MinHash computes MinHash signatures, which are compact representations of
+// sets that can be used to estimate the sets' jaccard similarity.
+//
+// Implementation details: each element is hashed once using 64-bit FNV-1a.
+// The i-th hash function is then derived from the two halves of the
+// element's hash via double hashing (h1 + i * h2), and mixed using murmur's
+// finalizer.  See "Less Hashing, Same Performance: Building a Better Bloom
+// Filter" by Kirsch and Mitzenmacher for additional details.
+type MinHash struct {
+	numHashFunctions int
+}
+
+// This returns a MinHash which generates signatures of length
+// numHashFunctions.  More hash functions yield more accurate similarity
+// estimates at the cost of larger signatures.  This panics if
+// numHashFunctions is less than 1.
+func NewMinHash(numHashFunctions int) *MinHash {
+	if numHashFunctions < 1 {
+		panic("numHashFunctions must be positive")
+	}
+	return &MinHash{
+		numHashFunctions: numHashFunctions,
+	}
+}
+
+// Signature returns the set's MinHash signature.  Duplicate elements in the
+// set do not affect the signature.  The signature of an empty set consists
+// of math.MaxUint32 values.
+func (m *MinHash) Signature(set []string) []uint32 {
+	sig := make([]uint32, m.numHashFunctions)
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+
+	for _, elem := range set {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(elem))
+		sum := h.Sum64()
+
+		h1 := uint32(sum)
+		h2 := uint32(sum >> 32)
+		for i := range sig {
+			v := simpleMurmur32(h1 + uint32(i)*h2)
+			if v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+
+	return sig
+}
+
+// Similarity returns the estimated jaccard similarity of the two sets
+// represented by the signatures.  This returns 0 if the signatures have
+// different lengths or are empty.
+func Similarity(sig1, sig2 []uint32) float64 {
+	if len(sig1) != len(sig2) || len(sig1) == 0 {
+		return 0
+	}
+
+	matches := 0
+	for i, v := range sig1 {
+		if v == sig2[i] {
+			matches++
+		}
+	}
+
+	return float64(matches) / float64(len(sig1))
+}
+
+// LSHIndex is a locality sensitive hashing index for MinHash signatures.
+// Each signature is divided into bands of rows; two signatures are candidate
+// pairs if all rows in at least one band agree.  Candidates are then filtered
+// by their estimated similarity.  LSHIndex is thread safe.
+type LSHIndex struct {
+	numBands int
+	numRows  int
+
+	mutex      sync.RWMutex
+	signatures map[string][]uint32
+	buckets    []map[string][]string // band -> band key -> ids
+}
+
+// This returns an LSHIndex for signatures of length numBands * numRows.
+// For a pair of sets with similarity s, the probability of them becoming
+// candidates is 1 - (1 - s^numRows)^numBands.  This panics if numBands or
+// numRows is less than 1.
+func NewLSHIndex(numBands int, numRows int) *LSHIndex {
+	if numBands < 1 || numRows < 1 {
+		panic("numBands and numRows must be positive")
+	}
+
+	buckets := make([]map[string][]string, numBands)
+	for i := range buckets {
+		buckets[i] = make(map[string][]string)
+	}
+
+	return &LSHIndex{
+		numBands:   numBands,
+		numRows:    numRows,
+		signatures: make(map[string][]uint32),
+		buckets:    buckets,
+	}
+}
+
+func (l *LSHIndex) bandKey(sig []uint32, band int) string {
+	buf := make([]byte, 4*l.numRows)
+	for i, v := range sig[band*l.numRows : (band+1)*l.numRows] {
+		binary.LittleEndian.PutUint32(buf[4*i:], v)
+	}
+	return string(buf)
+}
+
+// Add inserts the signature into the index.  Adding an id which is already
+// in the index is a no-op.  This panics if the signature's length does not
+// match the index's configuration.
+func (l *LSHIndex) Add(id string, sig []uint32) {
+	if len(sig) != l.numBands*l.numRows {
+		panic("signature length does not match numBands * numRows")
+	}
+
+	sigCopy := make([]uint32, len(sig))
+	copy(sigCopy, sig)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.signatures[id]; ok {
+		return
+	}
+	l.signatures[id] = sigCopy
+
+	for band, bucket := range l.buckets {
+		key := l.bandKey(sigCopy, band)
+		bucket[key] = append(bucket[key], id)
+	}
+}
+
+// Query returns the (sorted) ids of indexed signatures whose estimated
+// similarity to sig is at least threshold.  Note that LSH is probabilistic;
+// similar signatures which do not share a band will not be returned.
+func (l *LSHIndex) Query(sig []uint32, threshold float64) []string {
+	if len(sig) != l.numBands*l.numRows {
+		return nil
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	seen := make(map[string]struct{})
+	result := make([]string, 0)
+	for band, bucket := range l.buckets {
+		for _, id := range bucket[l.bandKey(sig, band)] {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+
+			if Similarity(sig, l.signatures[id]) >= threshold {
+				result = append(result, id)
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
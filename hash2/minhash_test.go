@@ -0,0 +1,85 @@
+package hash2
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type MinHashSuite struct {
+}
+
+var _ = Suite(&MinHashSuite{})
+
+func words(prefix string, n int) []string {
+	result := make([]string, n)
+	for i := 0; i < n; i++ {
+		result[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return result
+}
+
+func (s *MinHashSuite) TestSignature(c *C) {
+	m := NewMinHash(128)
+
+	set := words("w", 100)
+	sig := m.Signature(set)
+	c.Assert(len(sig), Equals, 128)
+
+	// Signatures are deterministic and independent of order / duplicates.
+	reversed := make([]string, 0, len(set)+1)
+	for i := len(set) - 1; i >= 0; i-- {
+		reversed = append(reversed, set[i])
+	}
+	reversed = append(reversed, set[0])
+	c.Assert(m.Signature(reversed), DeepEquals, sig)
+
+	c.Assert(Similarity(sig, sig), Equals, 1.0)
+	c.Assert(Similarity(sig, sig[1:]), Equals, 0.0)
+	c.Assert(Similarity(nil, nil), Equals, 0.0)
+}
+
+func (s *MinHashSuite) TestSimilarity(c *C) {
+	m := NewMinHash(256)
+
+	// jaccard(a, b) = 80 / 120 = 0.67
+	a := words("w", 100)
+	b := append(words("w", 80), words("x", 20)...)
+	sim := Similarity(m.Signature(a), m.Signature(b))
+	c.Assert(sim > 0.55 && sim < 0.8, IsTrue, Commentf("%v", sim))
+
+	// disjoint sets
+	d := words("y", 100)
+	sim = Similarity(m.Signature(a), m.Signature(d))
+	c.Assert(sim < 0.1, IsTrue, Commentf("%v", sim))
+}
+
+func (s *MinHashSuite) TestInvalidNumHashFunctions(c *C) {
+	c.Assert(func() { NewMinHash(0) }, Panics, "numHashFunctions must be positive")
+}
+
+func (s *MinHashSuite) TestLSHIndex(c *C) {
+	m := NewMinHash(64)
+	index := NewLSHIndex(16, 4)
+
+	a := words("w", 100)
+	nearA := append(words("w", 95), words("x", 5)...)
+	other := words("y", 100)
+
+	index.Add("a", m.Signature(a))
+	index.Add("other", m.Signature(other))
+
+	c.Assert(index.Query(m.Signature(nearA), 0.7), DeepEquals, []string{"a"})
+	c.Assert(index.Query(m.Signature(other), 0.7), DeepEquals, []string{"other"})
+	c.Assert(index.Query(m.Signature(words("z", 100)), 0.7), HasLen, 0)
+
+	// The threshold filters out candidates.
+	c.Assert(index.Query(m.Signature(nearA), 1.0), HasLen, 0)
+
+	// Mismatched signature length.
+	c.Assert(index.Query([]uint32{1, 2, 3}, 0), IsNil)
+	c.Assert(func() { index.Add("bad", []uint32{1}) }, Panics,
+		"signature length does not match numBands * numRows")
+}
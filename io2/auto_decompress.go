@@ -0,0 +1,231 @@
+package io2
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type CompressionFormat int
+
+const (
+	Uncompressed CompressionFormat = iota
+	Gzip
+	Xz
+	Lz4
+	Zstd
+)
+
+func (f CompressionFormat) String() string {
+	switch f {
+	case Uncompressed:
+		return "uncompressed"
+	case Gzip:
+		return "gzip"
+	case Xz:
+		return "xz"
+	case Lz4:
+		return "lz4"
+	case Zstd:
+		return "zstd"
+	}
+	return "unknown"
+}
+
+type compressionMagic struct {
+	format CompressionFormat
+	magic  []byte
+}
+
+var compressionMagics = []compressionMagic{
+	{Gzip, []byte("\x1f\x8b")},
+	{Xz, []byte("\xfd7zXZ\x00")},
+	{Lz4, []byte("\x04\x22\x4d\x18")},
+	{Zstd, []byte("\x28\xb5\x2f\xfd")},
+}
+
+// The maximum number of bytes needed for detecting the compression format.
+const maxCompressionMagicSize = 6
+
+// Decompression commands for formats not supported by the standard library.
+// The commands read the compressed stream from stdin and write the
+// decompressed stream to stdout.
+var decompressCommands = map[CompressionFormat][]string{
+	Xz:   {"xz", "-d", "-c"},
+	Lz4:  {"lz4", "-d", "-c"},
+	Zstd: {"zstd", "-d", "-c", "-q"},
+}
+
+// DetectCompressionFormat returns the compression format identified by the
+// header's magic bytes.  Uncompressed is returned when no magic matches.
+func DetectCompressionFormat(header []byte) CompressionFormat {
+	for _, m := range compressionMagics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format
+		}
+	}
+	return Uncompressed
+}
+
+// AutoDecompressOptions controls how AutoDecompressReaderWithOptions
+// decompresses streams.
+type AutoDecompressOptions struct {
+	// When true, xz, lz4 and zstd streams are decompressed by piping the
+	// stream through the corresponding command line tool (which must be in
+	// PATH).  Otherwise, an error is returned for these formats.
+	AllowSubprocess bool
+}
+
+// AutoDecompressReader is AutoDecompressReaderWithOptions with the default
+// options, i.e., only gzip compressed (and uncompressed) streams are
+// supported.
+func AutoDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	return AutoDecompressReaderWithOptions(r, AutoDecompressOptions{})
+}
+
+// AutoDecompressReaderWithOptions peeks at the first few bytes of r to
+// detect the stream's compression format (gzip, xz, lz4 or zstd), and
+// returns a reader which decompresses the stream.  The stream is returned as
+// is when it is not compressed by any of the known formats.
+//
+// gzip is decompressed in process.  xz, lz4 and zstd are only supported when
+// options.AllowSubprocess is set.  Truncated or corrupted streams are
+// reported by Read (in place of io.EOF).  The returned reader must be closed
+// to release the decompression resources (closing the reader does not close
+// r).
+func AutoDecompressReaderWithOptions(
+	r io.Reader,
+	options AutoDecompressOptions) (io.ReadCloser, error) {
+
+	buffered := bufio.NewReader(r)
+
+	header, err := buffered.Peek(maxCompressionMagicSize)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "Failed to read compression header")
+	}
+
+	format := DetectCompressionFormat(header)
+	switch format {
+	case Uncompressed:
+		return ioutil.NopCloser(buffered), nil
+	case Gzip:
+		reader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create gzip reader")
+		}
+		return reader, nil
+	}
+
+	if !options.AllowSubprocess {
+		return nil, errors.Newf(
+			"Decompressing %s streams requires AllowSubprocess",
+			format)
+	}
+
+	args := decompressCommands[format]
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = buffered
+
+	reader := &subprocessDecompressor{
+		format: format,
+		cmd:    cmd,
+	}
+	cmd.Stderr = &reader.stderr
+
+	reader.stdout, err = cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to start %s decompressor",
+			format)
+	}
+	return reader, nil
+}
+
+// A reader which surfaces the decompression command's exit status.  Unlike
+// ReadSubprocess, a command failure (e.g., due to truncated input) is
+// returned by Read once the output is exhausted, rather than only by Close.
+type subprocessDecompressor struct {
+	format CompressionFormat
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+
+	waited  bool
+	waitErr error
+}
+
+func (d *subprocessDecompressor) wait() error {
+	if d.waited {
+		return d.waitErr
+	}
+	d.waited = true
+
+	err := d.cmd.Wait()
+	if err != nil {
+		d.waitErr = d.commandError(err)
+	}
+	return d.waitErr
+}
+
+func (d *subprocessDecompressor) commandError(err error) error {
+	return errors.Wrapf(
+		err,
+		"%s decompressor failed: %s",
+		d.format,
+		strings.TrimSpace(d.stderr.String()))
+}
+
+func (d *subprocessDecompressor) Read(p []byte) (int, error) {
+	if d.waited {
+		if d.waitErr != nil {
+			return 0, d.waitErr
+		}
+		return 0, io.EOF
+	}
+
+	n, err := d.stdout.Read(p)
+	if err == io.EOF {
+		waitErr := d.wait()
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (d *subprocessDecompressor) Close() error {
+	if d.waited {
+		return d.waitErr
+	}
+	d.waited = true
+
+	// Closing the pipe before the output is exhausted kills the command with
+	// SIGPIPE, which is expected.
+	_ = d.stdout.Close()
+	err := d.cmd.Wait()
+	if err != nil && !isBrokenPipe(err) {
+		d.waitErr = d.commandError(err)
+	}
+	return d.waitErr
+}
+
+func isBrokenPipe(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+}
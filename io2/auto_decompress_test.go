@@ -0,0 +1,158 @@
+package io2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type AutoDecompressSuite struct {
+	testData []byte
+}
+
+var _ = Suite(&AutoDecompressSuite{})
+
+func (s *AutoDecompressSuite) SetUpSuite(c *C) {
+	s.testData = []byte(strings.Repeat(strings.Join(words, " "), 10))
+}
+
+func (s *AutoDecompressSuite) compressWithCommand(
+	c *C,
+	name string) []byte {
+
+	if _, err := exec.LookPath(name); err != nil {
+		c.Skip(name + " is not available")
+	}
+
+	cmd := exec.Command(name, "-c")
+	cmd.Stdin = bytes.NewReader(s.testData)
+	out, err := cmd.Output()
+	c.Assert(err, IsNil)
+	return out
+}
+
+func (s *AutoDecompressSuite) checkDecompress(
+	c *C,
+	compressed []byte,
+	expectedFormat CompressionFormat) {
+
+	c.Assert(DetectCompressionFormat(compressed), Equals, expectedFormat)
+
+	reader, err := AutoDecompressReaderWithOptions(
+		bytes.NewReader(compressed),
+		AutoDecompressOptions{AllowSubprocess: true})
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Close(), IsNil)
+
+	c.Assert(bytes.Equal(data, s.testData), Equals, true)
+}
+
+func (s *AutoDecompressSuite) TestGzip(c *C) {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(s.testData)
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+
+	s.checkDecompress(c, buf.Bytes(), Gzip)
+}
+
+func (s *AutoDecompressSuite) checkTruncated(c *C, name string) {
+	compressed := s.compressWithCommand(c, name)
+
+	for _, size := range []int{len(compressed) / 2, len(compressed) - 1} {
+		reader, err := AutoDecompressReaderWithOptions(
+			bytes.NewReader(compressed[:size]),
+			AutoDecompressOptions{AllowSubprocess: true})
+		c.Assert(err, IsNil)
+
+		_, err = ioutil.ReadAll(reader)
+		c.Assert(err, NotNil)
+		c.Assert(reader.Close(), NotNil)
+	}
+}
+
+func (s *AutoDecompressSuite) checkEarlyClose(c *C, name string) {
+	if _, err := exec.LookPath(name); err != nil {
+		c.Skip(name + " is not available")
+	}
+
+	// Large enough for the command to block on a full stdout pipe.
+	cmd := exec.Command(name, "-c")
+	cmd.Stdin = bytes.NewReader(bytes.Repeat(s.testData, 1000))
+	compressed, err := cmd.Output()
+	c.Assert(err, IsNil)
+
+	reader, err := AutoDecompressReaderWithOptions(
+		bytes.NewReader(compressed),
+		AutoDecompressOptions{AllowSubprocess: true})
+	c.Assert(err, IsNil)
+
+	buf := make([]byte, 10)
+	_, err = io.ReadFull(reader, buf)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Close(), IsNil)
+}
+
+func (s *AutoDecompressSuite) TestTruncated(c *C) {
+	s.checkTruncated(c, "xz")
+	s.checkTruncated(c, "lz4")
+	s.checkTruncated(c, "zstd")
+}
+
+func (s *AutoDecompressSuite) TestEarlyClose(c *C) {
+	s.checkEarlyClose(c, "xz")
+	s.checkEarlyClose(c, "lz4")
+	s.checkEarlyClose(c, "zstd")
+}
+
+func (s *AutoDecompressSuite) TestSubprocessRequiresOptIn(c *C) {
+	for _, magic := range compressionMagics {
+		if magic.format == Gzip {
+			continue
+		}
+
+		_, err := AutoDecompressReader(bytes.NewReader(magic.magic))
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *AutoDecompressSuite) TestXz(c *C) {
+	s.checkDecompress(c, s.compressWithCommand(c, "xz"), Xz)
+}
+
+func (s *AutoDecompressSuite) TestLz4(c *C) {
+	s.checkDecompress(c, s.compressWithCommand(c, "lz4"), Lz4)
+}
+
+func (s *AutoDecompressSuite) TestZstd(c *C) {
+	s.checkDecompress(c, s.compressWithCommand(c, "zstd"), Zstd)
+}
+
+func (s *AutoDecompressSuite) TestUncompressed(c *C) {
+	s.checkDecompress(c, s.testData, Uncompressed)
+
+	// Streams shorter than the longest magic.
+	for _, input := range []string{"", "a", "\x1f"} {
+		reader, err := AutoDecompressReader(strings.NewReader(input))
+		c.Assert(err, IsNil)
+
+		data, err := ioutil.ReadAll(reader)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, input)
+		c.Assert(reader.Close(), IsNil)
+	}
+}
+
+func (s *AutoDecompressSuite) TestCorruptedGzip(c *C) {
+	_, err := AutoDecompressReader(strings.NewReader("\x1f\x8bgarbage"))
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,139 @@
+package io2
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// Buffers larger than this are not pooled.
+	maxPooledBufferShift = 24
+	maxPooledBufferSize  = 1 << maxPooledBufferShift
+
+	borrowedReaderBufferSize = 32 * 1024
+)
+
+// BytePool is a pool of byte slices, bucketed by power-of-two sizes.  Reusing
+// buffers via the pool reduces gc pressure for short-lived buffers, e.g.,
+// buffers used for reading binlog events or network messages.  Buffers
+// larger than 16MB are not pooled.
+//
+// BytePool is thread safe.
+type BytePool struct {
+	buckets [maxPooledBufferShift + 1]sync.Pool
+}
+
+// This returns an empty byte pool.
+func NewBytePool() *BytePool {
+	return &BytePool{}
+}
+
+// Get returns a slice of length size, with capacity of at least size.  The
+// slice's content is unspecified (i.e., it may contain data from a previous
+// user).
+func (p *BytePool) Get(size int) []byte {
+	if size < 0 {
+		panic("Invalid buffer size")
+	}
+
+	if size > maxPooledBufferSize {
+		return make([]byte, size)
+	}
+
+	// The smallest bucket whose buffers are guaranteed to fit size bytes.
+	idx := 0
+	if size > 1 {
+		idx = bits.Len(uint(size - 1))
+	}
+
+	if b, ok := p.buckets[idx].Get().([]byte); ok {
+		return b[:size]
+	}
+	return make([]byte, size, 1<<uint(idx))
+}
+
+// Put returns the slice to the pool.  The caller must not use the slice
+// (or any slice which shares its underlying array) after calling Put.
+func (p *BytePool) Put(b []byte) {
+	c := cap(b)
+	if c == 0 || c > maxPooledBufferSize {
+		return
+	}
+
+	// The largest bucket whose buffers are no larger than b.
+	idx := bits.Len(uint(c)) - 1
+	p.buckets[idx].Put(b[:0])
+}
+
+type borrowedReader struct {
+	pool *BytePool
+	src  io.Reader
+
+	buf   []byte // nil when nothing is buffered.
+	start int
+	end   int
+	err   error
+}
+
+// BorrowedReader returns a buffered reader which borrows its internal buffer
+// from the pool.  The buffer is only held while it contains unread data; it
+// is returned to the pool as soon as it is drained.  Hence, an idle reader
+// does not hold on to any memory.
+//
+// The returned reader also implements io.Closer, which returns the buffer
+// (and discards any unread data) early.  NOTE: Close does not close the
+// source reader.
+func BorrowedReader(pool *BytePool, r io.Reader) io.Reader {
+	return &borrowedReader{
+		pool: pool,
+		src:  r,
+	}
+}
+
+func (r *borrowedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if r.buf == nil {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if len(p) >= borrowedReaderBufferSize {
+			// Large read; bypass the buffer.
+			n, err := r.src.Read(p)
+			r.err = err
+			return n, err
+		}
+
+		r.buf = r.pool.Get(borrowedReaderBufferSize)
+		r.start = 0
+		r.end, r.err = r.src.Read(r.buf)
+	}
+
+	n := copy(p, r.buf[r.start:r.end])
+	r.start += n
+
+	if r.start == r.end {
+		r.release()
+		if n == 0 {
+			return 0, r.err
+		}
+	}
+
+	return n, nil
+}
+
+func (r *borrowedReader) Close() error {
+	r.release()
+	return nil
+}
+
+func (r *borrowedReader) release() {
+	if r.buf != nil {
+		r.pool.Put(r.buf)
+		r.buf = nil
+	}
+}
@@ -0,0 +1,166 @@
+package io2
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type BytePoolSuite struct {
+}
+
+var _ = Suite(&BytePoolSuite{})
+
+func (s *BytePoolSuite) TestGet(c *C) {
+	pool := NewBytePool()
+
+	for _, t := range []struct {
+		size        int
+		expectedCap int
+	}{
+		{0, 1},
+		{1, 1},
+		{2, 2},
+		{3, 4},
+		{1000, 1024},
+		{1024, 1024},
+		{1025, 2048},
+		{maxPooledBufferSize, maxPooledBufferSize},
+		{maxPooledBufferSize + 1, maxPooledBufferSize + 1},
+	} {
+		b := pool.Get(t.size)
+		c.Check(len(b), Equals, t.size)
+		c.Check(cap(b), Equals, t.expectedCap)
+	}
+
+	c.Check(func() { pool.Get(-1) }, Panics, "Invalid buffer size")
+}
+
+func (s *BytePoolSuite) TestPutAndReuse(c *C) {
+	pool := NewBytePool()
+
+	// NOTE: sync.Pool may drop pooled items at any time, hence we only check
+	// that the returned slices are usable.
+	for i := 0; i < 100; i++ {
+		b := pool.Get(100)
+		c.Assert(len(b), Equals, 100)
+		c.Assert(cap(b) >= 100, Equals, true)
+		b[99] = 1
+		pool.Put(b)
+	}
+
+	// Slices with non power-of-two capacity are put into the next smaller
+	// bucket.
+	pool.Put(make([]byte, 10, 100))
+	for i := 0; i < 10; i++ {
+		b := pool.Get(64)
+		c.Assert(len(b), Equals, 64)
+		c.Assert(cap(b) >= 64, Equals, true)
+	}
+
+	// Not pooled.
+	pool.Put(nil)
+	pool.Put(make([]byte, maxPooledBufferSize+1))
+}
+
+// Returns at most n bytes per read.
+type shortReader struct {
+	src io.Reader
+	n   int
+}
+
+func (r *shortReader) Read(p []byte) (int, error) {
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	return r.src.Read(p)
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func (s *BytePoolSuite) TestBorrowedReader(c *C) {
+	pool := NewBytePool()
+	data := testData(3*borrowedReaderBufferSize + 123)
+
+	for _, readSize := range []int{1, 7, 4096, borrowedReaderBufferSize, 100000} {
+		r := BorrowedReader(pool, bytes.NewReader(data))
+
+		result := []byte{}
+		buf := make([]byte, readSize)
+		for {
+			n, err := r.Read(buf)
+			result = append(result, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			c.Assert(err, IsNil)
+		}
+
+		c.Assert(result, DeepEquals, data)
+	}
+}
+
+func (s *BytePoolSuite) TestBorrowedReaderReleasesDrainedBuffer(c *C) {
+	pool := NewBytePool()
+	data := testData(100)
+
+	r := BorrowedReader(pool, &shortReader{src: bytes.NewReader(data), n: 10})
+	br := r.(*borrowedReader)
+
+	buf := make([]byte, 4)
+	n, err := r.Read(buf)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 4)
+	c.Assert(br.buf, NotNil)
+
+	// Drains the remaining 6 buffered bytes.
+	n, err = r.Read(make([]byte, 100))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 6)
+	c.Assert(br.buf, IsNil)
+
+	n, err = r.Read(buf)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 4)
+	c.Assert(br.buf, NotNil)
+
+	c.Assert(r.(io.Closer).Close(), IsNil)
+	c.Assert(br.buf, IsNil)
+
+	rest, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, data[20:])
+	c.Assert(br.buf, IsNil)
+}
+
+func BenchmarkBytePoolGetPut(b *testing.B) {
+	pool := NewBytePool()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := pool.Get(16 * 1024)
+		buf[0] = 1
+		pool.Put(buf)
+	}
+}
+
+var benchmarkSink []byte
+
+func BenchmarkMakeBytes(b *testing.B) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = make([]byte, 16*1024)
+		benchmarkSink[0] = 1
+	}
+}
@@ -0,0 +1,136 @@
+package io2
+
+import (
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// The (approximate) number of bytes read from the file per read call.
+const directReadBufferSize = 128 * 1024
+
+// DirectReader reads a file through a block aligned buffer, in block size
+// multiples.  This is required for reading files opened with O_DIRECT (i.e.,
+// bypassing the page cache), since linux requires the read buffers, sizes and
+// file offsets to be aligned to the device's logical block size (typically
+// 512 or 4096 bytes).  On linux, the buffer is allocated via an anonymous
+// mmap, outside of the go heap.  On other platforms, DirectReader falls back
+// to normal (pass through) reads.
+//
+// NOTE: the file's offset must be block aligned when the reader is created.
+// DirectReader does not close the file; the caller must Close the reader to
+// release the buffer.  DirectReader is not thread safe.
+type DirectReader struct {
+	file      *os.File
+	blockSize int
+
+	raw    []byte // the allocated buffer
+	buf    []byte // the aligned portion of raw
+	start  int
+	end    int
+	err    error
+	closed bool
+}
+
+// This returns a reader which reads f in multiples of blockSize.  blockSize
+// must be a power of two.
+func NewDirectReader(f *os.File, blockSize int) (*DirectReader, error) {
+	if blockSize <= 0 || blockSize&(blockSize-1) != 0 {
+		return nil, errors.Newf("Invalid block size: %d", blockSize)
+	}
+
+	r := &DirectReader{
+		file:      f,
+		blockSize: blockSize,
+	}
+
+	if !directIOSupported {
+		return r, nil
+	}
+
+	size := directReadBufferSize
+	if rem := size % blockSize; rem != 0 {
+		size += blockSize - rem
+	}
+
+	// Over allocate by a block, since the allocation may not be aligned to
+	// blocks which are larger than a page.
+	raw, err := allocateDirectBuffer(size + blockSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to allocate direct read buffer")
+	}
+
+	offset := 0
+	addr := uintptr(unsafe.Pointer(&raw[0]))
+	if rem := int(addr % uintptr(blockSize)); rem != 0 {
+		offset = blockSize - rem
+	}
+
+	r.raw = raw
+	r.buf = raw[offset : offset+size]
+	return r, nil
+}
+
+// See io.Reader for documentation.
+func (r *DirectReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("Reading from closed direct reader")
+	}
+
+	if !directIOSupported {
+		return r.file.Read(p)
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for r.start == r.end {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		// NOTE: Only the final read (at the end of file) may return a partial
+		// block, hence the file offset remains block aligned.
+		n, err := r.file.Read(r.buf)
+		r.start = 0
+		r.end = n
+
+		if err != nil {
+			if err != io.EOF {
+				err = errors.Wrapf(err, "Failed to read %s", r.file.Name())
+			}
+			r.err = err
+		} else if n%r.blockSize != 0 {
+			r.err = io.EOF
+		}
+	}
+
+	n := copy(p, r.buf[r.start:r.end])
+	r.start += n
+	return n, nil
+}
+
+// Close releases the reader's buffer.  This does not close the underlying
+// file.
+func (r *DirectReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	raw := r.raw
+	r.raw = nil
+	r.buf = nil
+	if raw == nil {
+		return nil
+	}
+
+	err := freeDirectBuffer(raw)
+	if err != nil {
+		return errors.Wrap(err, "Failed to free direct read buffer")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package io2
+
+import (
+	"syscall"
+)
+
+const directIOSupported = true
+
+// The buffer is page aligned.
+func allocateDirectBuffer(size int) ([]byte, error) {
+	return syscall.Mmap(
+		-1,
+		0,
+		size,
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+func freeDirectBuffer(buf []byte) error {
+	return syscall.Munmap(buf)
+}
@@ -0,0 +1,23 @@
+package io2
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *DirectReaderSuite) TestODirect(c *C) {
+	path, expected := s.writeFile(c, 100000)
+
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECT, 0)
+	if err != nil {
+		// e.g., tmpfs does not support O_DIRECT.
+		c.Skip("O_DIRECT is not supported: " + err.Error())
+	}
+	defer f.Close()
+
+	data := s.readAll(c, f, 4096)
+	c.Assert(bytes.Equal(data, expected), Equals, true)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package io2
+
+// O_DIRECT is linux specific; other platforms use normal reads.
+const directIOSupported = false
+
+func allocateDirectBuffer(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+func freeDirectBuffer(buf []byte) error {
+	return nil
+}
@@ -0,0 +1,123 @@
+package io2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	. "gopkg.in/check.v1"
+)
+
+type DirectReaderSuite struct {
+	dir string
+}
+
+var _ = Suite(&DirectReaderSuite{})
+
+func (s *DirectReaderSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *DirectReaderSuite) writeFile(c *C, size int) (string, []byte) {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	path := filepath.Join(s.dir, "data")
+	err := ioutil.WriteFile(path, data, 0644)
+	c.Assert(err, IsNil)
+
+	return path, data
+}
+
+func (s *DirectReaderSuite) readAll(
+	c *C,
+	f *os.File,
+	blockSize int) []byte {
+
+	reader, err := NewDirectReader(f, blockSize)
+	c.Assert(err, IsNil)
+	defer func() {
+		c.Assert(reader.Close(), IsNil)
+	}()
+
+	if directIOSupported {
+		addr := uintptr(unsafe.Pointer(&reader.buf[0]))
+		c.Assert(addr%uintptr(blockSize), Equals, uintptr(0))
+		c.Assert(len(reader.buf)%blockSize, Equals, 0)
+	}
+
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	return data
+}
+
+func (s *DirectReaderSuite) TestInvalidBlockSize(c *C) {
+	for _, blockSize := range []int{-512, 0, 3, 1000} {
+		_, err := NewDirectReader(nil, blockSize)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *DirectReaderSuite) TestRead(c *C) {
+	for _, size := range []int{0, 1, 512, 4096, 100000, 3*128*1024 + 17} {
+		for _, blockSize := range []int{512, 4096, 64 * 1024} {
+			path, expected := s.writeFile(c, size)
+
+			f, err := os.Open(path)
+			c.Assert(err, IsNil)
+
+			data := s.readAll(c, f, blockSize)
+			c.Check(
+				bytes.Equal(data, expected),
+				Equals,
+				true,
+				Commentf("size: %d block size: %d", size, blockSize))
+
+			c.Assert(f.Close(), IsNil)
+		}
+	}
+}
+
+func (s *DirectReaderSuite) TestSmallReads(c *C) {
+	path, expected := s.writeFile(c, 10000)
+
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	reader, err := NewDirectReader(f, 512)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+
+	data := []byte{}
+	chunk := make([]byte, 7)
+	for {
+		n, err := reader.Read(chunk)
+		data = append(data, chunk[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	c.Assert(bytes.Equal(data, expected), Equals, true)
+}
+
+func (s *DirectReaderSuite) TestClose(c *C) {
+	path, _ := s.writeFile(c, 100)
+
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	reader, err := NewDirectReader(f, 512)
+	c.Assert(err, IsNil)
+
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(reader.Close(), IsNil)
+
+	_, err = reader.Read(make([]byte, 10))
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,235 @@
+package io2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const (
+	encryptionNonceSize  = 12
+	encryptionTagSize    = 16
+	encryptionLengthSize = 4
+
+	// Larger writes are split into multiple frames.
+	MaxEncryptedFrameSize = 1 << 20
+)
+
+// Returned by the decrypting reader when a frame fails authentication (i.e.,
+// the stream was tampered with, or the wrong key was used).
+var ErrAuthFailed = errors.New("Message authentication failed")
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.Newf(
+			"Invalid key length: %d (must be 16, 24, or 32 bytes)",
+			len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create aes cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create gcm cipher")
+	}
+	return gcm, nil
+}
+
+// The frame's sequence number and whether or not it's the final frame are
+// authenticated (but not transmitted), which guards against reordered,
+// dropped, or truncated frames.
+func frameAdditionalData(seq uint64, isFinal bool) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint64(data, seq)
+	if isFinal {
+		data[8] = 1
+	}
+	return data
+}
+
+type encryptingWriter struct {
+	w      io.Writer
+	gcm    cipher.AEAD
+	seq    uint64
+	closed bool
+}
+
+// This returns a writer which encrypts data using AES-GCM (the key length
+// determines AES-128, AES-192, or AES-256).  Each write is framed as
+//
+//	[4-byte ciphertext length][12-byte nonce][ciphertext][16-byte auth tag]
+//
+// where the nonce is randomly generated per frame, and the length is big
+// endian.  Close must be called to write the (empty) final frame, which lets
+// the reader detect truncated streams.  Close does not close w.
+func EncryptingWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{
+		w:   w,
+		gcm: gcm,
+	}, nil
+}
+
+func (e *encryptingWriter) writeFrame(plaintext []byte, isFinal bool) error {
+	frame := make(
+		[]byte,
+		encryptionLengthSize+encryptionNonceSize,
+		encryptionLengthSize+encryptionNonceSize+
+			len(plaintext)+encryptionTagSize)
+
+	binary.BigEndian.PutUint32(frame, uint32(len(plaintext)))
+
+	nonce := frame[encryptionLengthSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.Wrap(err, "Failed to generate nonce")
+	}
+
+	frame = e.gcm.Seal(
+		frame,
+		nonce,
+		plaintext,
+		frameAdditionalData(e.seq, isFinal))
+	e.seq++
+
+	_, err := e.w.Write(frame)
+	return err
+}
+
+func (e *encryptingWriter) Write(data []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("Writing to closed encrypting writer")
+	}
+
+	written := 0
+	for written < len(data) {
+		chunk := data[written:]
+		if len(chunk) > MaxEncryptedFrameSize {
+			chunk = chunk[:MaxEncryptedFrameSize]
+		}
+
+		if err := e.writeFrame(chunk, false); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+
+	return written, nil
+}
+
+func (e *encryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	return e.writeFrame(nil, true)
+}
+
+type decryptingReader struct {
+	r   io.Reader
+	gcm cipher.AEAD
+	seq uint64
+
+	header []byte
+	frame  []byte
+
+	plaintext []byte // unread decrypted data
+	sawFinal  bool
+	err       error
+}
+
+// This returns a reader which decrypts the stream written by
+// EncryptingWriter.  The reader returns ErrAuthFailed when a frame fails
+// authentication, and io.ErrUnexpectedEOF when the stream ends before the
+// final frame.
+func DecryptingReader(r io.Reader, key []byte) (io.Reader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		r:      r,
+		gcm:    gcm,
+		header: make([]byte, encryptionLengthSize+encryptionNonceSize),
+	}, nil
+}
+
+func (d *decryptingReader) readFrame() error {
+	_, err := io.ReadFull(d.r, d.header)
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	length := binary.BigEndian.Uint32(d.header)
+	if length > MaxEncryptedFrameSize {
+		return ErrAuthFailed
+	}
+
+	frameSize := int(length) + encryptionTagSize
+	if cap(d.frame) < frameSize {
+		d.frame = make([]byte, frameSize)
+	}
+	d.frame = d.frame[:frameSize]
+
+	_, err = io.ReadFull(d.r, d.frame)
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	nonce := d.header[encryptionLengthSize:]
+	isFinal := length == 0
+
+	// Decrypt in place.
+	plaintext, err := d.gcm.Open(
+		d.frame[:0],
+		nonce,
+		d.frame,
+		frameAdditionalData(d.seq, isFinal))
+	if err != nil {
+		return ErrAuthFailed
+	}
+	d.seq++
+
+	d.plaintext = plaintext
+	d.sawFinal = isFinal
+	return nil
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.plaintext) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		if d.sawFinal {
+			d.err = io.EOF
+			continue
+		}
+
+		d.err = d.readFrame()
+	}
+
+	n := copy(p, d.plaintext)
+	d.plaintext = d.plaintext[n:]
+	return n, nil
+}
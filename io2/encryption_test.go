@@ -0,0 +1,140 @@
+package io2
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	. "gopkg.in/check.v1"
+)
+
+type EncryptionSuite struct {
+	key []byte
+}
+
+var _ = Suite(&EncryptionSuite{})
+
+func (s *EncryptionSuite) SetUpTest(c *C) {
+	s.key = []byte("0123456789abcdef0123456789abcdef")
+}
+
+func (s *EncryptionSuite) encrypt(c *C, chunks ...string) []byte {
+	buf := &bytes.Buffer{}
+
+	w, err := EncryptingWriter(buf, s.key)
+	c.Assert(err, IsNil)
+
+	for _, chunk := range chunks {
+		n, err := w.Write([]byte(chunk))
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, len(chunk))
+	}
+
+	c.Assert(w.Close(), IsNil)
+	return buf.Bytes()
+}
+
+func (s *EncryptionSuite) decrypt(data []byte) ([]byte, error) {
+	r, err := DecryptingReader(bytes.NewReader(data), s.key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (s *EncryptionSuite) TestRoundTrip(c *C) {
+	data := s.encrypt(c, "hello", " ", "world")
+
+	// 3 data frames + final frame.
+	c.Check(len(data), Equals, 4*(4+12+16)+len("hello world"))
+
+	plaintext, err := s.decrypt(data)
+	c.Assert(err, IsNil)
+	c.Check(string(plaintext), Equals, "hello world")
+}
+
+func (s *EncryptionSuite) TestKeyLengths(c *C) {
+	for _, size := range []int{16, 24, 32} {
+		s.key = bytes.Repeat([]byte{'k'}, size)
+
+		plaintext, err := s.decrypt(s.encrypt(c, "foo"))
+		c.Assert(err, IsNil)
+		c.Check(string(plaintext), Equals, "foo")
+	}
+
+	for _, size := range []int{0, 15, 33} {
+		key := bytes.Repeat([]byte{'k'}, size)
+
+		_, err := EncryptingWriter(&bytes.Buffer{}, key)
+		c.Check(err, NotNil)
+
+		_, err = DecryptingReader(&bytes.Buffer{}, key)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *EncryptionSuite) TestLargeWrite(c *C) {
+	chunk := string(bytes.Repeat([]byte{'x'}, MaxEncryptedFrameSize+10))
+
+	plaintext, err := s.decrypt(s.encrypt(c, chunk))
+	c.Assert(err, IsNil)
+	c.Check(string(plaintext), Equals, chunk)
+}
+
+func (s *EncryptionSuite) TestRandomNonce(c *C) {
+	data1 := s.encrypt(c, "hello")
+	data2 := s.encrypt(c, "hello")
+	c.Check(bytes.Equal(data1, data2), Equals, false)
+}
+
+func (s *EncryptionSuite) TestTamperedCiphertext(c *C) {
+	data := s.encrypt(c, "hello world")
+
+	// Flip a bit in the first frame's ciphertext.
+	data[4+12] ^= 0x1
+
+	_, err := s.decrypt(data)
+	c.Check(err, Equals, ErrAuthFailed)
+}
+
+func (s *EncryptionSuite) TestTamperedTag(c *C) {
+	data := s.encrypt(c, "hello world")
+
+	data[4+12+len("hello world")] ^= 0x1
+
+	_, err := s.decrypt(data)
+	c.Check(err, Equals, ErrAuthFailed)
+}
+
+func (s *EncryptionSuite) TestWrongKey(c *C) {
+	data := s.encrypt(c, "hello world")
+
+	s.key = []byte("fedcba9876543210")
+	_, err := s.decrypt(data)
+	c.Check(err, Equals, ErrAuthFailed)
+}
+
+func (s *EncryptionSuite) TestReorderedFrames(c *C) {
+	data := s.encrypt(c, "aaaa", "bbbb")
+
+	frameSize := 4 + 12 + 4 + 16
+	reordered := append([]byte{}, data[frameSize:2*frameSize]...)
+	reordered = append(reordered, data[:frameSize]...)
+	reordered = append(reordered, data[2*frameSize:]...)
+
+	_, err := s.decrypt(reordered)
+	c.Check(err, Equals, ErrAuthFailed)
+}
+
+func (s *EncryptionSuite) TestTruncated(c *C) {
+	data := s.encrypt(c, "aaaa", "bbbb")
+
+	// Drop the final frame.
+	frameSize := 4 + 12 + 4 + 16
+	_, err := s.decrypt(data[:2*frameSize])
+	c.Check(err, Equals, io.ErrUnexpectedEOF)
+
+	// Partial frame.
+	_, err = s.decrypt(data[:frameSize+5])
+	c.Check(err, Equals, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,132 @@
+package io2
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// MultipartBuilder constructs a multipart/form-data body (e.g., for http file
+// uploads).  Parts are written in the order they are added.
+//
+// The body returned by Build is streamed: only the parts' headers (and field
+// values) are held in memory; file contents are read from their readers as
+// the body is read.
+//
+// MultipartBuilder is not thread safe.
+type MultipartBuilder struct {
+	buffer *bytes.Buffer
+	writer *multipart.Writer
+
+	readers []io.Reader
+	err     error
+	built   bool
+}
+
+// This returns a multipart builder with a randomly generated boundary.
+func NewMultipartBuilder() *MultipartBuilder {
+	buffer := &bytes.Buffer{}
+	return &MultipartBuilder{
+		buffer: buffer,
+		writer: multipart.NewWriter(buffer),
+	}
+}
+
+// Boundary returns the multipart body's boundary.
+func (b *MultipartBuilder) Boundary() string {
+	return b.writer.Boundary()
+}
+
+// Moves the bytes written by the multipart writer into the readers list.
+func (b *MultipartBuilder) flushBuffer() {
+	if b.buffer.Len() == 0 {
+		return
+	}
+
+	written := make([]byte, b.buffer.Len())
+	copy(written, b.buffer.Bytes())
+	b.buffer.Reset()
+
+	b.readers = append(b.readers, bytes.NewReader(written))
+}
+
+// AddFile adds a file part.  content is not read until the built body is
+// read.
+func (b *MultipartBuilder) AddFile(
+	fieldName string,
+	fileName string,
+	content io.Reader) {
+
+	if b.err != nil {
+		return
+	}
+
+	if b.built {
+		b.err = errors.New("Cannot add parts after Build")
+		return
+	}
+
+	if content == nil {
+		b.err = errors.Newf("nil content for file field %s", fieldName)
+		return
+	}
+
+	_, err := b.writer.CreateFormFile(fieldName, fileName)
+	if err != nil {
+		b.err = errors.Wrapf(err, "Failed to add file field %s", fieldName)
+		return
+	}
+
+	b.flushBuffer()
+	b.readers = append(b.readers, content)
+}
+
+// AddField adds a form field part.
+func (b *MultipartBuilder) AddField(name string, value string) {
+	if b.err != nil {
+		return
+	}
+
+	if b.built {
+		b.err = errors.New("Cannot add parts after Build")
+		return
+	}
+
+	err := b.writer.WriteField(name, value)
+	if err != nil {
+		b.err = errors.Wrapf(err, "Failed to add field %s", name)
+		return
+	}
+
+	b.flushBuffer()
+}
+
+// Build returns the multipart body and its content type (including the
+// boundary).  Errors encountered by AddFile / AddField are returned by Build.
+// Build may only be called once since the files' contents are consumed by
+// reading the body.
+func (b *MultipartBuilder) Build() (
+	body io.Reader,
+	contentType string,
+	err error) {
+
+	if b.err != nil {
+		return nil, "", b.err
+	}
+
+	if b.built {
+		return nil, "", errors.New("Build already called")
+	}
+	b.built = true
+
+	err = b.writer.Close()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to close multipart writer")
+	}
+
+	b.flushBuffer()
+
+	return io.MultiReader(b.readers...), b.writer.FormDataContentType(), nil
+}
@@ -0,0 +1,115 @@
+package io2
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type MultipartBuilderSuite struct {
+}
+
+var _ = Suite(&MultipartBuilderSuite{})
+
+// A reader which counts the number of Read calls.
+type lazyContentReader struct {
+	reader   io.Reader
+	numReads int
+}
+
+func (r *lazyContentReader) Read(p []byte) (int, error) {
+	r.numReads++
+	return r.reader.Read(p)
+}
+
+func (s *MultipartBuilderSuite) TestBuild(c *C) {
+	content := &lazyContentReader{reader: strings.NewReader("file content")}
+
+	builder := NewMultipartBuilder()
+	builder.AddField("name", "value")
+	builder.AddFile("upload", "test.txt", content)
+	builder.AddField("other", "other value")
+
+	body, contentType, err := builder.Build()
+	c.Assert(err, IsNil)
+
+	// The file's content is only read when the body is read.
+	c.Check(content.numReads, Equals, 0)
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	c.Assert(err, IsNil)
+	c.Check(mediaType, Equals, "multipart/form-data")
+	c.Check(params["boundary"], Equals, builder.Boundary())
+
+	reader := multipart.NewReader(body, params["boundary"])
+
+	part, err := reader.NextPart()
+	c.Assert(err, IsNil)
+	c.Check(part.FormName(), Equals, "name")
+	c.Check(part.FileName(), Equals, "")
+	value, err := ioutil.ReadAll(part)
+	c.Assert(err, IsNil)
+	c.Check(string(value), Equals, "value")
+
+	part, err = reader.NextPart()
+	c.Assert(err, IsNil)
+	c.Check(part.FormName(), Equals, "upload")
+	c.Check(part.FileName(), Equals, "test.txt")
+	value, err = ioutil.ReadAll(part)
+	c.Assert(err, IsNil)
+	c.Check(string(value), Equals, "file content")
+
+	part, err = reader.NextPart()
+	c.Assert(err, IsNil)
+	c.Check(part.FormName(), Equals, "other")
+	value, err = ioutil.ReadAll(part)
+	c.Assert(err, IsNil)
+	c.Check(string(value), Equals, "other value")
+
+	_, err = reader.NextPart()
+	c.Check(err, Equals, io.EOF)
+}
+
+func (s *MultipartBuilderSuite) TestBuildEmpty(c *C) {
+	body, contentType, err := NewMultipartBuilder().Build()
+	c.Assert(err, IsNil)
+
+	_, params, err := mime.ParseMediaType(contentType)
+	c.Assert(err, IsNil)
+
+	_, err = multipart.NewReader(body, params["boundary"]).NextPart()
+	c.Check(err, Equals, io.EOF)
+}
+
+func (s *MultipartBuilderSuite) TestBuildTwice(c *C) {
+	builder := NewMultipartBuilder()
+	builder.AddField("name", "value")
+
+	_, _, err := builder.Build()
+	c.Assert(err, IsNil)
+
+	_, _, err = builder.Build()
+	c.Check(errors.GetMessage(err), Equals, "Build already called")
+
+	builder.AddField("late", "value")
+	_, _, err = builder.Build()
+	c.Check(errors.GetMessage(err), Equals, "Cannot add parts after Build")
+}
+
+func (s *MultipartBuilderSuite) TestNilFileContent(c *C) {
+	builder := NewMultipartBuilder()
+	builder.AddFile("upload", "test.txt", nil)
+	builder.AddField("name", "value")
+
+	_, _, err := builder.Build()
+	c.Check(
+		errors.GetMessage(err),
+		Equals,
+		"nil content for file field upload")
+}
@@ -0,0 +1,142 @@
+package io2
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// SpoolWriter buffers written data in memory, up to a memory limit.  Once the
+// limit is exceeded, the buffered data is moved into a temp file, and all
+// subsequent writes go to the temp file.  This is useful for handling data of
+// unknown size (e.g., http request bodies) without risking running out of
+// memory, and without paying the cost of temp files for small payloads.
+//
+// SpoolWriter is not thread safe.
+type SpoolWriter struct {
+	memLimit int64
+	dir      string
+
+	buf    *bytes.Buffer
+	file   *os.File
+	size   int64
+	closed bool
+}
+
+// This returns a spool writer which keeps up to memLimit bytes in memory.  The
+// temp file (if any) is created in dir (the default temp directory is used
+// when dir is empty).  The caller must Close the writer to remove the temp
+// file.
+func NewSpoolWriter(memLimit int64, dir string) *SpoolWriter {
+	return &SpoolWriter{
+		memLimit: memLimit,
+		dir:      dir,
+		buf:      &bytes.Buffer{},
+	}
+}
+
+// See io.Writer for documentation.
+func (w *SpoolWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("Writing to closed spool writer")
+	}
+
+	if w.file == nil && w.size+int64(len(p)) > w.memLimit {
+		err := w.spoolToFile()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	var err error
+	if w.file != nil {
+		n, err = w.file.Write(p)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to write to %s", w.file.Name())
+		}
+	} else {
+		n, err = w.buf.Write(p)
+	}
+
+	w.size += int64(n)
+	return n, err
+}
+
+// This moves the in-memory buffer's content into a new temp file.
+func (w *SpoolWriter) spoolToFile() error {
+	file, err := ioutil.TempFile(w.dir, "spool")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create spool file")
+	}
+
+	_, err = file.Write(w.buf.Bytes())
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return errors.Wrapf(err, "Failed to write to %s", file.Name())
+	}
+
+	w.file = file
+	w.buf = nil
+	return nil
+}
+
+// Size returns the total number of bytes written.
+func (w *SpoolWriter) Size() int64 {
+	return w.size
+}
+
+// IsSpooled returns true if the data was moved into a temp file.
+func (w *SpoolWriter) IsSpooled() bool {
+	return w.file != nil
+}
+
+// Reader returns a reader over all data written so far, starting from byte
+// zero.  Each call returns an independent reader; the readers must be closed
+// by the caller.  Readers should not be used after the writer is closed.
+func (w *SpoolWriter) Reader() (io.ReadCloser, error) {
+	if w.closed {
+		return nil, errors.New("Reading from closed spool writer")
+	}
+
+	if w.file == nil {
+		return ioutil.NopCloser(bytes.NewReader(w.buf.Bytes())), nil
+	}
+
+	file, err := os.Open(w.file.Name())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open %s", w.file.Name())
+	}
+
+	return file, nil
+}
+
+// Close releases the buffered data, and removes the temp file (if any).
+func (w *SpoolWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.buf = nil
+
+	if w.file == nil {
+		return nil
+	}
+
+	name := w.file.Name()
+	closeErr := w.file.Close()
+	removeErr := os.Remove(name)
+	w.file = nil
+
+	if closeErr != nil {
+		return errors.Wrapf(closeErr, "Failed to close %s", name)
+	}
+	if removeErr != nil {
+		return errors.Wrapf(removeErr, "Failed to remove %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package io2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type SpoolWriterSuite struct {
+	dir string
+}
+
+var _ = Suite(&SpoolWriterSuite{})
+
+func (s *SpoolWriterSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *SpoolWriterSuite) spoolFiles(c *C) []string {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*"))
+	c.Assert(err, IsNil)
+	return files
+}
+
+func (s *SpoolWriterSuite) readAll(c *C, w *SpoolWriter) string {
+	reader, err := w.Reader()
+	c.Assert(err, IsNil)
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func (s *SpoolWriterSuite) TestInMemory(c *C) {
+	w := NewSpoolWriter(10, s.dir)
+
+	_, err := w.Write([]byte("hello"))
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("world"))
+	c.Assert(err, IsNil)
+
+	c.Assert(w.Size(), Equals, int64(10))
+	c.Assert(w.IsSpooled(), IsFalse)
+	c.Assert(s.spoolFiles(c), HasLen, 0)
+
+	c.Assert(s.readAll(c, w), Equals, "helloworld")
+	c.Assert(s.readAll(c, w), Equals, "helloworld")
+
+	c.Assert(w.Close(), IsNil)
+	_, err = w.Reader()
+	c.Assert(err, NotNil)
+}
+
+func (s *SpoolWriterSuite) TestOverflowToFile(c *C) {
+	w := NewSpoolWriter(10, s.dir)
+
+	_, err := w.Write([]byte("hello"))
+	c.Assert(err, IsNil)
+	c.Assert(w.IsSpooled(), IsFalse)
+
+	n, err := w.Write([]byte("world!"))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 6)
+	c.Assert(w.IsSpooled(), IsTrue)
+	c.Assert(s.spoolFiles(c), HasLen, 1)
+
+	c.Assert(s.readAll(c, w), Equals, "helloworld!")
+
+	_, err = w.Write([]byte(" more"))
+	c.Assert(err, IsNil)
+	c.Assert(w.Size(), Equals, int64(16))
+
+	// Readers are independent of each other, and of the writer.
+	reader, err := w.Reader()
+	c.Assert(err, IsNil)
+	buf := make([]byte, 5)
+	_, err = reader.Read(buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "hello")
+
+	c.Assert(s.readAll(c, w), Equals, "helloworld! more")
+
+	rest, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(string(rest), Equals, "world! more")
+	c.Assert(reader.Close(), IsNil)
+
+	c.Assert(w.Close(), IsNil)
+	c.Assert(s.spoolFiles(c), HasLen, 0)
+
+	_, err = w.Write([]byte("x"))
+	c.Assert(err, NotNil)
+	c.Assert(w.Close(), IsNil)
+}
+
+func (s *SpoolWriterSuite) TestZeroLimit(c *C) {
+	w := NewSpoolWriter(0, s.dir)
+	defer w.Close()
+
+	c.Assert(s.readAll(c, w), Equals, "")
+
+	_, err := w.Write([]byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(w.IsSpooled(), IsTrue)
+	c.Assert(s.readAll(c, w), Equals, "a")
+}
+
+func (s *SpoolWriterSuite) TestBadDir(c *C) {
+	w := NewSpoolWriter(1, filepath.Join(s.dir, "missing"))
+	defer w.Close()
+
+	_, err := w.Write([]byte("a"))
+	c.Assert(err, IsNil)
+
+	_, err = w.Write([]byte("b"))
+	c.Assert(err, NotNil)
+	c.Assert(w.IsSpooled(), IsFalse)
+
+	// The buffered data is retained.
+	c.Assert(s.readAll(c, w), Equals, "a")
+
+	_, err = os.Stat(filepath.Join(s.dir, "missing"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
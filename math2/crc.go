@@ -0,0 +1,103 @@
+package math2
+
+import (
+	"hash/crc32"
+	"hash/crc64"
+)
+
+const (
+	// The IEEE polynomial, which is used by mysql binlog event checksums
+	// (as well as zlib, gzip, png, etc).
+	CRC32IEEE = crc32.IEEE
+
+	// The Castagnoli polynomial, which is used by InnoDB page checksums
+	// (innodb_checksum_algorithm=crc32), iSCSI, ext4, etc.
+	CRC32Castagnoli = crc32.Castagnoli
+
+	// The Koopman polynomial.
+	CRC32Koopman = crc32.Koopman
+)
+
+const (
+	// The ISO polynomial, defined in ISO 3309 and used in HDLC.
+	CRC64ISO = crc64.ISO
+
+	// The ECMA polynomial, defined in ECMA 182.
+	CRC64ECMA = crc64.ECMA
+)
+
+// CRC32 is an incremental crc32 hasher with a chainable api, e.g.,
+//
+//	math2.NewCRC32(math2.CRC32IEEE).Write(header).Write(body).Sum32()
+//
+// NOTE: CRC32 is not thread safe.
+type CRC32 struct {
+	table *crc32.Table
+	crc   uint32
+}
+
+// This returns a crc32 hasher for the given polynomial.  The tables for
+// CRC32IEEE and CRC32Castagnoli are precomputed / hardware accelerated.
+func NewCRC32(polynomial uint32) *CRC32 {
+	var table *crc32.Table
+	if polynomial == CRC32IEEE {
+		table = crc32.IEEETable
+	} else {
+		table = crc32.MakeTable(polynomial)
+	}
+
+	return &CRC32{
+		table: table,
+	}
+}
+
+// This updates the checksum with p, and returns the hasher.
+func (c *CRC32) Write(p []byte) *CRC32 {
+	c.crc = crc32.Update(c.crc, c.table, p)
+	return c
+}
+
+// This returns the checksum of the data written so far.
+func (c *CRC32) Sum32() uint32 {
+	return c.crc
+}
+
+// This resets the checksum, and returns the hasher.
+func (c *CRC32) Reset() *CRC32 {
+	c.crc = 0
+	return c
+}
+
+// CRC64 is an incremental crc64 hasher with a chainable api, e.g.,
+//
+//	math2.NewCRC64(math2.CRC64ECMA).Write(header).Write(body).Sum64()
+//
+// NOTE: CRC64 is not thread safe.
+type CRC64 struct {
+	table *crc64.Table
+	crc   uint64
+}
+
+// This returns a crc64 hasher for the given polynomial.
+func NewCRC64(polynomial uint64) *CRC64 {
+	return &CRC64{
+		table: crc64.MakeTable(polynomial),
+	}
+}
+
+// This updates the checksum with p, and returns the hasher.
+func (c *CRC64) Write(p []byte) *CRC64 {
+	c.crc = crc64.Update(c.crc, c.table, p)
+	return c
+}
+
+// This returns the checksum of the data written so far.
+func (c *CRC64) Sum64() uint64 {
+	return c.crc
+}
+
+// This resets the checksum, and returns the hasher.
+func (c *CRC64) Reset() *CRC64 {
+	c.crc = 0
+	return c
+}
@@ -0,0 +1,58 @@
+package math2
+
+import (
+	"hash/crc32"
+	"hash/crc64"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type CRCSuite struct {
+}
+
+var _ = Suite(&CRCSuite{})
+
+func (s *CRCSuite) TestCRC32(c *C) {
+	data := []byte("hello world")
+
+	for _, poly := range []uint32{CRC32IEEE, CRC32Castagnoli, CRC32Koopman} {
+		expected := crc32.Checksum(data, crc32.MakeTable(poly))
+
+		h := NewCRC32(poly)
+		c.Assert(h.Sum32(), Equals, uint32(0))
+
+		c.Assert(h.Write(data[:5]).Write(nil).Write(data[5:]).Sum32(), Equals, expected)
+		c.Assert(NewCRC32(poly).Write(data).Sum32(), Equals, expected)
+
+		c.Assert(h.Reset().Sum32(), Equals, uint32(0))
+		c.Assert(h.Write(data).Sum32(), Equals, expected)
+	}
+
+	c.Assert(
+		NewCRC32(CRC32IEEE).Write([]byte("123456789")).Sum32(),
+		Equals,
+		uint32(0xcbf43926))
+	c.Assert(
+		NewCRC32(CRC32Castagnoli).Write([]byte("123456789")).Sum32(),
+		Equals,
+		uint32(0xe3069283))
+}
+
+func (s *CRCSuite) TestCRC64(c *C) {
+	data := []byte("hello world")
+
+	for _, poly := range []uint64{CRC64ISO, CRC64ECMA} {
+		expected := crc64.Checksum(data, crc64.MakeTable(poly))
+
+		h := NewCRC64(poly)
+		c.Assert(h.Write(data[:3]).Write(data[3:]).Sum64(), Equals, expected)
+
+		c.Assert(h.Reset().Sum64(), Equals, uint64(0))
+		c.Assert(h.Write(data).Sum64(), Equals, expected)
+	}
+}
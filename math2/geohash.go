@@ -0,0 +1,224 @@
+package math2
+
+import (
+	"math"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const (
+	// The minimum / maximum geohash precision (i.e., # of characters)
+	// supported by GeohashCodec.Encode.
+	MinGeohashPrecision = 1
+	MaxGeohashPrecision = 12
+
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+var geohashDecodeMap = func() [256]int8 {
+	m := [256]int8{}
+	for i := range m {
+		m[i] = -1
+	}
+	for i := 0; i < len(geohashAlphabet); i++ {
+		m[geohashAlphabet[i]] = int8(i)
+	}
+	return m
+}()
+
+// GeohashCodec encodes / decodes geohashes (see
+// https://en.wikipedia.org/wiki/Geohash), which map (latitude, longitude)
+// points onto a grid of rectangular cells.  Nearby points usually share a
+// common geohash prefix, hence geohashes are useful for clustering / indexing
+// points.  Use the Geohash variable instead of creating a codec.
+type GeohashCodec struct {
+}
+
+// Geohash is the standard (base32) geohash codec.
+var Geohash GeohashCodec
+
+// Encode returns the geohash of the cell which contains the point.  The
+// precision (# of characters) is clamped to [MinGeohashPrecision,
+// MaxGeohashPrecision], and the latitude / longitude are clamped to
+// [-90, 90] / [-180, 180].
+func (GeohashCodec) Encode(lat float64, lon float64, precision int) string {
+	if precision < MinGeohashPrecision {
+		precision = MinGeohashPrecision
+	} else if precision > MaxGeohashPrecision {
+		precision = MaxGeohashPrecision
+	}
+
+	lat = math.Max(-90, math.Min(90, lat))
+	lon = math.Max(-180, math.Min(180, lon))
+
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	hash := make([]byte, precision)
+	isLon := true // bits alternate between longitude and latitude.
+	for i := 0; i < precision; i++ {
+		idx := 0
+		for bit := 0; bit < 5; bit++ {
+			idx <<= 1
+			if isLon {
+				mid := (minLon + maxLon) / 2
+				if lon >= mid {
+					idx |= 1
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if lat >= mid {
+					idx |= 1
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			isLon = !isLon
+		}
+		hash[i] = geohashAlphabet[idx]
+	}
+
+	return string(hash)
+}
+
+func (GeohashCodec) boundingBox(hash string) (
+	minLat float64,
+	minLon float64,
+	maxLat float64,
+	maxLon float64,
+	err error) {
+
+	if hash == "" {
+		return 0, 0, 0, 0, errors.New("Empty geohash")
+	}
+
+	if len(hash) > MaxGeohashPrecision {
+		return 0, 0, 0, 0, errors.Newf(
+			"Geohash %q is longer than %d characters",
+			hash,
+			MaxGeohashPrecision)
+	}
+
+	minLat, maxLat = -90.0, 90.0
+	minLon, maxLon = -180.0, 180.0
+
+	isLon := true
+	for i := 0; i < len(hash); i++ {
+		idx := geohashDecodeMap[hash[i]]
+		if idx < 0 {
+			return 0, 0, 0, 0, errors.Newf(
+				"Invalid geohash character %q in %q",
+				hash[i],
+				hash)
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			set := (idx>>uint(bit))&1 == 1
+			if isLon {
+				mid := (minLon + maxLon) / 2
+				if set {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			isLon = !isLon
+		}
+	}
+
+	return minLat, minLon, maxLat, maxLon, nil
+}
+
+// Decode returns the center of the geohash's cell, along with the maximum
+// latitude / longitude errors (i.e., half of the cell's height / width).
+func (g GeohashCodec) Decode(hash string) (
+	lat float64,
+	lon float64,
+	latErr float64,
+	lonErr float64,
+	err error) {
+
+	minLat, minLon, maxLat, maxLon, err := g.boundingBox(hash)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	return (minLat + maxLat) / 2,
+		(minLon + maxLon) / 2,
+		(maxLat - minLat) / 2,
+		(maxLon - minLon) / 2,
+		nil
+}
+
+// BoundingBox returns the geohash's cell boundaries.  This returns all zeros
+// for invalid geohashes.
+func (g GeohashCodec) BoundingBox(hash string) (
+	minLat float64,
+	minLon float64,
+	maxLat float64,
+	maxLon float64) {
+
+	minLat, minLon, maxLat, maxLon, err := g.boundingBox(hash)
+	if err != nil {
+		return 0, 0, 0, 0
+	}
+	return minLat, minLon, maxLat, maxLon
+}
+
+// Neighbors returns the 8 cells (of the same precision) surrounding the
+// geohash's cell, in the order: north, north east, east, south east, south,
+// south west, west, north west.  Longitudes wrap around the antimeridian.
+// Cells adjacent to a pole have no neighbors beyond the pole; the cell's own
+// row is returned in their place.  This returns all empty strings for
+// invalid geohashes.
+func (g GeohashCodec) Neighbors(hash string) [8]string {
+	neighbors := [8]string{}
+
+	lat, lon, latErr, lonErr, err := g.Decode(hash)
+	if err != nil {
+		return neighbors
+	}
+
+	height := 2 * latErr
+	width := 2 * lonErr
+
+	offsets := [8][2]float64{
+		{1, 0},   // north
+		{1, 1},   // north east
+		{0, 1},   // east
+		{-1, 1},  // south east
+		{-1, 0},  // south
+		{-1, -1}, // south west
+		{0, -1},  // west
+		{1, -1},  // north west
+	}
+
+	for i, offset := range offsets {
+		nLat := lat + offset[0]*height
+		if nLat > 90 || nLat < -90 {
+			nLat = lat
+		}
+
+		nLon := lon + offset[1]*width
+		if nLon > 180 {
+			nLon -= 360
+		} else if nLon < -180 {
+			nLon += 360
+		}
+
+		neighbors[i] = g.Encode(nLat, nLon, len(hash))
+	}
+
+	return neighbors
+}
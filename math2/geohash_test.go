@@ -0,0 +1,133 @@
+package math2
+
+import (
+	"math"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type GeohashSuite struct {
+}
+
+var _ = Suite(&GeohashSuite{})
+
+func (s *GeohashSuite) TestEncode(c *C) {
+	c.Check(Geohash.Encode(42.6, -5.6, 5), Equals, "ezs42")
+	c.Check(Geohash.Encode(57.64911, 10.40744, 11), Equals, "u4pruydqqvj")
+	c.Check(Geohash.Encode(0, 0, 1), Equals, "s")
+	c.Check(Geohash.Encode(-90, -180, 3), Equals, "000")
+	c.Check(Geohash.Encode(90, 180, 3), Equals, "zzz")
+
+	// precision is clamped.
+	c.Check(Geohash.Encode(42.6, -5.6, 0), Equals, "e")
+	c.Check(len(Geohash.Encode(42.6, -5.6, 20)), Equals, MaxGeohashPrecision)
+}
+
+func (s *GeohashSuite) TestDecode(c *C) {
+	lat, lon, latErr, lonErr, err := Geohash.Decode("ezs42")
+	c.Assert(err, IsNil)
+	c.Check(math.Abs(lat-42.605) < 0.001, Equals, true, Commentf("%v", lat))
+	c.Check(math.Abs(lon+5.603) < 0.001, Equals, true, Commentf("%v", lon))
+	c.Check(latErr, Equals, 90.0/math.Pow(2, 12))
+	c.Check(lonErr, Equals, 180.0/math.Pow(2, 13))
+}
+
+func (s *GeohashSuite) TestDecodeInvalid(c *C) {
+	_, _, _, _, err := Geohash.Decode("")
+	c.Check(errors.GetMessage(err), Equals, "Empty geohash")
+
+	// 'a' is not in the alphabet.
+	_, _, _, _, err = Geohash.Decode("ezsa2")
+	c.Check(
+		errors.GetMessage(err),
+		Equals,
+		`Invalid geohash character 'a' in "ezsa2"`)
+
+	_, _, _, _, err = Geohash.Decode("0123456789bcd")
+	c.Check(err, NotNil)
+}
+
+func (s *GeohashSuite) TestRoundTrip(c *C) {
+	points := [][2]float64{
+		{0, 0},
+		{42.6, -5.6},
+		{37.7749, -122.4194},
+		{-33.8688, 151.2093},
+		{89.999, 179.999},
+		{-89.999, -179.999},
+	}
+
+	for prec := MinGeohashPrecision; prec <= MaxGeohashPrecision; prec++ {
+		for _, p := range points {
+			hash := Geohash.Encode(p[0], p[1], prec)
+			c.Assert(len(hash), Equals, prec)
+
+			lat, lon, latErr, lonErr, err := Geohash.Decode(hash)
+			c.Assert(err, IsNil)
+
+			// Each character encodes 5 bits, alternating between longitude
+			// and latitude (starting with longitude).
+			lonBits := (5*prec + 1) / 2
+			latBits := 5 * prec / 2
+			c.Check(latErr, Equals, 90/math.Pow(2, float64(latBits)))
+			c.Check(lonErr, Equals, 180/math.Pow(2, float64(lonBits)))
+
+			comment := Commentf("prec: %d point: %v", prec, p)
+			c.Check(math.Abs(lat-p[0]) <= latErr, Equals, true, comment)
+			c.Check(math.Abs(lon-p[1]) <= lonErr, Equals, true, comment)
+
+			// Re-encoding the cell's center yields the same cell.
+			c.Check(Geohash.Encode(lat, lon, prec), Equals, hash, comment)
+		}
+	}
+}
+
+func (s *GeohashSuite) TestBoundingBox(c *C) {
+	minLat, minLon, maxLat, maxLon := Geohash.BoundingBox("s")
+	c.Check(minLat, Equals, 0.0)
+	c.Check(minLon, Equals, 0.0)
+	c.Check(maxLat, Equals, 45.0)
+	c.Check(maxLon, Equals, 45.0)
+
+	minLat, minLon, maxLat, maxLon = Geohash.BoundingBox("ezs42")
+	c.Check(minLat <= 42.6 && 42.6 <= maxLat, Equals, true)
+	c.Check(minLon <= -5.6 && -5.6 <= maxLon, Equals, true)
+
+	minLat, minLon, maxLat, maxLon = Geohash.BoundingBox("invalid")
+	c.Check(minLat, Equals, 0.0)
+	c.Check(minLon, Equals, 0.0)
+	c.Check(maxLat, Equals, 0.0)
+	c.Check(maxLon, Equals, 0.0)
+}
+
+func (s *GeohashSuite) TestNeighbors(c *C) {
+	c.Check(
+		Geohash.Neighbors("dqcjqc"),
+		Equals,
+		[8]string{
+			"dqcjqf", // north
+			"dqcjr4", // north east
+			"dqcjr1", // east
+			"dqcjr0", // south east
+			"dqcjqb", // south
+			"dqcjq8", // south west
+			"dqcjq9", // west
+			"dqcjqd", // north west
+		})
+}
+
+func (s *GeohashSuite) TestNeighborsWrapAround(c *C) {
+	// "b" (the north west corner cell) is adjacent to "z" (the north east
+	// corner cell) across the antimeridian.
+	neighbors := Geohash.Neighbors("b")
+	c.Check(neighbors[6], Equals, "z") // west
+
+	// No neighbors beyond the north pole.
+	c.Check(neighbors[0], Equals, "b") // north
+}
+
+func (s *GeohashSuite) TestNeighborsInvalid(c *C) {
+	c.Check(Geohash.Neighbors(""), Equals, [8]string{})
+}
@@ -0,0 +1,124 @@
+package math2
+
+// LevenshteinDistance returns the edit distance between a and b, i.e., the
+// minimum number of single character insertions, deletions and substitutions
+// needed for transforming a into b.  Strings are compared by runes (not by
+// bytes).  This uses O(min(m, n)) space.
+func LevenshteinDistance(a string, b string) int {
+	return levenshteinDistance([]rune(a), []rune(b))
+}
+
+func levenshteinDistance(a []rune, b []rune) int {
+	// Keep the shorter string in b, since the rows are indexed by b.
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	// row[j] is the distance between the current prefix of a and b[:j].
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0] // distance(a[:i-1], b[:j-1])
+		row[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			next := minInt(
+				row[j]+1,   // deletion
+				row[j-1]+1, // insertion
+				diagonal+cost)
+
+			diagonal = row[j]
+			row[j] = next
+		}
+	}
+
+	return row[len(b)]
+}
+
+// NormalizedSimilarity returns 1 - distance / max(len(a), len(b)), where
+// distance is the Levenshtein distance between a and b, and the lengths are
+// in runes.  The similarity ranges from 0 (completely different) to 1
+// (identical).  Two empty strings are identical.
+func NormalizedSimilarity(a string, b string) float64 {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	if maxLen == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshteinDistance(ra, rb))/float64(maxLen)
+}
+
+// FuzzyContains returns true if some substring of text is within maxDist
+// (Levenshtein) edit distance of pattern.  An empty pattern is contained in
+// any text.  This uses O(len(pattern)) space and O(len(text) * len(pattern))
+// time.
+func FuzzyContains(text string, pattern string, maxDist int) bool {
+	if maxDist < 0 {
+		return false
+	}
+
+	p := []rune(pattern)
+	if len(p) <= maxDist {
+		// Deleting the whole pattern is within budget.
+		return true
+	}
+
+	// col[i] is the minimum distance between p[:i] and any substring of
+	// text which ends at the current text position.  Unlike the regular
+	// Levenshtein distance, the match may start anywhere in the text, hence
+	// the first entry is always zero.
+	col := make([]int, len(p)+1)
+	for i := range col {
+		col[i] = i
+	}
+
+	for _, r := range text {
+		diagonal := col[0]
+		col[0] = 0
+
+		for i := 1; i <= len(p); i++ {
+			cost := 1
+			if p[i-1] == r {
+				cost = 0
+			}
+
+			next := minInt(
+				col[i]+1,   // skip the text character
+				col[i-1]+1, // skip the pattern character
+				diagonal+cost)
+
+			diagonal = col[i]
+			col[i] = next
+		}
+
+		if col[len(p)] <= maxDist {
+			return true
+		}
+	}
+
+	return false
+}
+
+func minInt(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,82 @@
+package math2
+
+import (
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type LevenshteinSuite struct {
+}
+
+var _ = Suite(&LevenshteinSuite{})
+
+func (s *LevenshteinSuite) TestLevenshteinDistance(c *C) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abc", "abc", 0},
+		{"kitten", "sitting", 3},
+		{"sitting", "kitten", 3},
+		{"flaw", "lawn", 2},
+		{"saturday", "sunday", 3},
+		{"abc", "xyz", 3},
+		{"abcdef", "azced", 3},
+		// Multi-byte characters count as single edits.
+		{"héllo", "hello", 1},
+		{"日本語", "日本", 1},
+	}
+
+	for _, test := range tests {
+		c.Check(
+			LevenshteinDistance(test.a, test.b),
+			Equals,
+			test.expected,
+			Commentf("%q %q", test.a, test.b))
+	}
+}
+
+func (s *LevenshteinSuite) TestNormalizedSimilarity(c *C) {
+	c.Check(NormalizedSimilarity("", ""), Equals, 1.0)
+	c.Check(NormalizedSimilarity("abc", "abc"), Equals, 1.0)
+	c.Check(NormalizedSimilarity("abc", ""), Equals, 0.0)
+	c.Check(NormalizedSimilarity("abc", "xyz"), Equals, 0.0)
+	c.Check(NormalizedSimilarity("abcd", "abce"), Equals, 0.75)
+	c.Check(NormalizedSimilarity("ab", "abcd"), Equals, 0.5)
+	c.Check(NormalizedSimilarity("日本", "日本語語"), Equals, 0.5)
+}
+
+func (s *LevenshteinSuite) TestFuzzyContains(c *C) {
+	// Exact matches.
+	c.Check(FuzzyContains("hello world", "world", 0), IsTrue)
+	c.Check(FuzzyContains("hello world", "lo wo", 0), IsTrue)
+	c.Check(FuzzyContains("hello world", "word", 0), IsFalse)
+
+	// Substitution, insertion and deletion.
+	c.Check(FuzzyContains("hello world", "wprld", 1), IsTrue)
+	c.Check(FuzzyContains("hello world", "wrld", 1), IsTrue)
+	c.Check(FuzzyContains("hello world", "worlds", 1), IsTrue)
+	c.Check(FuzzyContains("hello world", "wprld", 0), IsFalse)
+	c.Check(FuzzyContains("hello world", "wxyzd", 2), IsFalse)
+	c.Check(FuzzyContains("hello world", "wxyzd", 3), IsTrue)
+
+	// The pattern may be longer than the text.
+	c.Check(FuzzyContains("cat", "cats", 1), IsTrue)
+	c.Check(FuzzyContains("cat", "catsup", 2), IsFalse)
+
+	// Empty strings.
+	c.Check(FuzzyContains("", "", 0), IsTrue)
+	c.Check(FuzzyContains("abc", "", 0), IsTrue)
+	c.Check(FuzzyContains("", "ab", 1), IsFalse)
+	c.Check(FuzzyContains("", "ab", 2), IsTrue)
+
+	c.Check(FuzzyContains("abc", "abc", -1), IsFalse)
+
+	c.Check(FuzzyContains("東京タワー", "京タ", 0), IsTrue)
+	c.Check(FuzzyContains("東京タワー", "京ワ", 1), IsTrue)
+}
@@ -0,0 +1,321 @@
+package net2
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/dropbox/godropbox/time2"
+)
+
+const (
+	defaultProxyReadBufferSize = 32 * 1024
+	defaultProxyRateWindow     = 10 * time.Second
+
+	// The number of buckets in the rate measurement sliding window.
+	numRateBuckets = 10
+)
+
+// Options for configuring the backpressure proxy.
+type BackpressureProxyOptions struct {
+	// The proxy stops reading from the source when the number of buffered
+	// (read but not yet written) bytes reaches HighWaterMark.  Must be
+	// positive.
+	HighWaterMark int
+
+	// The proxy resumes reading from the source when the number of buffered
+	// bytes drops to LowWaterMark (or below).  Must be non-negative and less
+	// than HighWaterMark.
+	LowWaterMark int
+
+	// The maximum number of bytes read from the source per read call.  When
+	// non-positive, 32KB is used.
+	ReadBufferSize int
+
+	// The sliding window over which the producer / consumer rates are
+	// measured.  When non-positive, 10 seconds is used.
+	RateWindow time.Duration
+
+	// When nil, time2.DefaultClock is used.
+	Clock time2.Clock
+}
+
+// BackpressureProxy copies data from a source (producer) to a destination
+// (consumer), e.g., for streaming binlog fanout.  Reading from the source and
+// writing to the destination are decoupled by a buffer.  When the consumer
+// can't keep up with the producer, the buffer grows until it reaches the high
+// water mark, at which point the proxy pauses reading from the source (i.e.,
+// backpressure is propagated to the producer instead of bloating the
+// buffer).  Reading resumes once the consumer drains the buffer down to the
+// low water mark.
+//
+// BufferDepth, ConsumerRate, ProducerRate and IsPaused are thread safe, and
+// may be called concurrently with Run.
+type BackpressureProxy struct {
+	src     io.Reader
+	dst     io.Writer
+	options BackpressureProxyOptions
+
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	buffer    []byte
+	paused    bool
+	srcDone   bool
+	srcErr    error
+	dstFailed bool
+	started   bool
+
+	producerRate *slidingWindowRate
+	consumerRate *slidingWindowRate
+}
+
+// This returns a proxy which copies data from src to dst (see Run).
+func NewBackpressureProxy(
+	src io.Reader,
+	dst io.Writer,
+	options BackpressureProxyOptions) (*BackpressureProxy, error) {
+
+	if options.HighWaterMark <= 0 {
+		return nil, errors.Newf(
+			"Invalid high water mark: %d",
+			options.HighWaterMark)
+	}
+	if options.LowWaterMark < 0 ||
+		options.LowWaterMark >= options.HighWaterMark {
+
+		return nil, errors.Newf(
+			"Invalid low water mark: %d (high water mark: %d)",
+			options.LowWaterMark,
+			options.HighWaterMark)
+	}
+	if options.ReadBufferSize <= 0 {
+		options.ReadBufferSize = defaultProxyReadBufferSize
+	}
+	if options.RateWindow <= 0 {
+		options.RateWindow = defaultProxyRateWindow
+	}
+	if options.Clock == nil {
+		options.Clock = time2.DefaultClock
+	}
+
+	p := &BackpressureProxy{
+		src:          src,
+		dst:          dst,
+		options:      options,
+		producerRate: newSlidingWindowRate(options.Clock, options.RateWindow),
+		consumerRate: newSlidingWindowRate(options.Clock, options.RateWindow),
+	}
+	p.cond = sync.NewCond(&p.mutex)
+	return p, nil
+}
+
+// Run copies data from the source to the destination until the source
+// returns io.EOF (in which case Run returns nil after all buffered data is
+// written), the source returns an error (in which case Run returns the error
+// after all buffered data is written), or the destination returns an error
+// (in which case Run returns the error immediately, and buffered data is
+// discarded).  Closing the source / destination stops the proxy.  Run may
+// only be called once.
+//
+// NOTE: when Run returns due to a destination error, the source reading
+// goroutine exits once its pending read call returns.
+func (p *BackpressureProxy) Run() error {
+	p.mutex.Lock()
+	if p.started {
+		p.mutex.Unlock()
+		return errors.New("Backpressure proxy already started")
+	}
+	p.started = true
+	p.mutex.Unlock()
+
+	go p.readLoop()
+
+	chunk := make([]byte, p.options.ReadBufferSize)
+	for {
+		p.mutex.Lock()
+		for len(p.buffer) == 0 && !p.srcDone {
+			p.cond.Wait()
+		}
+
+		if len(p.buffer) == 0 { // the source is done and the buffer is drained
+			err := p.srcErr
+			p.mutex.Unlock()
+			return err
+		}
+
+		// Data is removed from the buffer only after it is written, hence
+		// in-flight data counts towards the buffer depth.
+		n := copy(chunk, p.buffer)
+		p.mutex.Unlock()
+
+		_, err := p.dst.Write(chunk[:n])
+
+		p.mutex.Lock()
+		if err != nil {
+			p.dstFailed = true
+			p.cond.Broadcast()
+			p.mutex.Unlock()
+			return errors.Wrap(err, "Failed to write to destination")
+		}
+
+		p.buffer = p.buffer[n:]
+		if len(p.buffer) == 0 {
+			p.buffer = nil // release the drained buffer's memory
+		}
+		p.consumerRate.add(n)
+
+		if p.paused && len(p.buffer) <= p.options.LowWaterMark {
+			p.paused = false
+			p.cond.Broadcast()
+		}
+		p.mutex.Unlock()
+	}
+}
+
+func (p *BackpressureProxy) readLoop() {
+	chunk := make([]byte, p.options.ReadBufferSize)
+	for {
+		p.mutex.Lock()
+		for p.paused && !p.dstFailed {
+			p.cond.Wait()
+		}
+		dstFailed := p.dstFailed
+		p.mutex.Unlock()
+
+		if dstFailed {
+			return
+		}
+
+		n, err := p.src.Read(chunk)
+
+		p.mutex.Lock()
+		if n > 0 {
+			p.buffer = append(p.buffer, chunk[:n]...)
+			p.producerRate.add(n)
+
+			if len(p.buffer) >= p.options.HighWaterMark {
+				p.paused = true
+			}
+		}
+
+		if err != nil {
+			p.srcDone = true
+			if err != io.EOF {
+				p.srcErr = errors.Wrap(err, "Failed to read from source")
+			}
+		}
+
+		p.cond.Broadcast()
+		p.mutex.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// BufferDepth returns the number of read but not yet written bytes.
+func (p *BackpressureProxy) BufferDepth() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.buffer)
+}
+
+// IsPaused returns true if the proxy stopped reading from the source due to
+// backpressure.
+func (p *BackpressureProxy) IsPaused() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.paused
+}
+
+// ConsumerRate returns the rate (in bytes per second) at which data is
+// written to the destination, measured over the rate window.
+func (p *BackpressureProxy) ConsumerRate() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.consumerRate.rate()
+}
+
+// ProducerRate returns the rate (in bytes per second) at which data is read
+// from the source, measured over the rate window.
+func (p *BackpressureProxy) ProducerRate() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.producerRate.rate()
+}
+
+// slidingWindowRate measures a byte rate over a sliding time window.  The
+// window is divided into fixed width buckets; the oldest bucket is expired
+// as a whole, hence the measurement's granularity is window / numRateBuckets.
+// NOTE: slidingWindowRate is not thread safe.
+type slidingWindowRate struct {
+	clock       time2.Clock
+	window      time.Duration
+	bucketWidth time.Duration
+	start       time.Time
+
+	counts []int64
+	epochs []int64 // the bucket index (since start) of each count
+}
+
+func newSlidingWindowRate(
+	clock time2.Clock,
+	window time.Duration) *slidingWindowRate {
+
+	bucketWidth := window / numRateBuckets
+	if bucketWidth <= 0 {
+		bucketWidth = 1
+	}
+
+	return &slidingWindowRate{
+		clock:       clock,
+		window:      window,
+		bucketWidth: bucketWidth,
+		start:       clock.Now(),
+		counts:      make([]int64, numRateBuckets),
+		epochs:      make([]int64, numRateBuckets),
+	}
+}
+
+func (r *slidingWindowRate) currentEpoch() int64 {
+	return int64(r.clock.Since(r.start) / r.bucketWidth)
+}
+
+func (r *slidingWindowRate) add(n int) {
+	epoch := r.currentEpoch()
+	idx := epoch % numRateBuckets
+	if r.epochs[idx] != epoch {
+		r.epochs[idx] = epoch
+		r.counts[idx] = 0
+	}
+	r.counts[idx] += int64(n)
+}
+
+func (r *slidingWindowRate) rate() float64 {
+	elapsed := r.clock.Since(r.start)
+	if elapsed <= 0 {
+		return 0
+	}
+
+	epoch := r.currentEpoch()
+
+	// The rate is measured over the elapsed time until the window is filled.
+	if elapsed > r.window {
+		elapsed = r.window
+	}
+
+	total := int64(0)
+	for idx, count := range r.counts {
+		if epoch-r.epochs[idx] < numRateBuckets {
+			total += count
+		}
+	}
+
+	return float64(total) / elapsed.Seconds()
+}
@@ -0,0 +1,291 @@
+package net2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+	"github.com/dropbox/godropbox/time2"
+)
+
+type BackpressureProxySuite struct {
+}
+
+var _ = Suite(&BackpressureProxySuite{})
+
+// A destination which blocks writes until it's unblocked.
+type gatedWriter struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	blocked bool
+	buf     bytes.Buffer
+	err     error
+}
+
+func newGatedWriter(blocked bool) *gatedWriter {
+	w := &gatedWriter{blocked: blocked}
+	w.cond = sync.NewCond(&w.mutex)
+	return w
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for w.blocked {
+		w.cond.Wait()
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) setBlocked(blocked bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.blocked = blocked
+	w.cond.Broadcast()
+}
+
+func (w *gatedWriter) bytes() []byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.buf.Bytes()
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func waitFor(c *C, condition func() bool) {
+	for i := 0; i < 1000; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Fatal("Timed out waiting for condition")
+}
+
+func (s *BackpressureProxySuite) TestInvalidOptions(c *C) {
+	for _, options := range []BackpressureProxyOptions{
+		{HighWaterMark: 0, LowWaterMark: 0},
+		{HighWaterMark: 10, LowWaterMark: -1},
+		{HighWaterMark: 10, LowWaterMark: 10},
+	} {
+		_, err := NewBackpressureProxy(
+			&bytes.Buffer{},
+			&bytes.Buffer{},
+			options)
+		c.Check(err, NotNil)
+	}
+}
+
+func (s *BackpressureProxySuite) TestCopy(c *C) {
+	data := testData(100000)
+	dst := &bytes.Buffer{}
+
+	proxy, err := NewBackpressureProxy(
+		bytes.NewReader(data),
+		dst,
+		BackpressureProxyOptions{
+			HighWaterMark:  4096,
+			LowWaterMark:   1024,
+			ReadBufferSize: 1000,
+		})
+	c.Assert(err, IsNil)
+
+	c.Assert(proxy.Run(), IsNil)
+	c.Assert(dst.Bytes(), DeepEquals, data)
+	c.Assert(proxy.BufferDepth(), Equals, 0)
+	c.Assert(proxy.IsPaused(), IsFalse)
+
+	c.Assert(proxy.Run(), NotNil)
+}
+
+func (s *BackpressureProxySuite) TestBackpressure(c *C) {
+	data := testData(1000)
+	dst := newGatedWriter(true)
+
+	proxy, err := NewBackpressureProxy(
+		bytes.NewReader(data),
+		dst,
+		BackpressureProxyOptions{
+			HighWaterMark:  100,
+			LowWaterMark:   20,
+			ReadBufferSize: 10,
+		})
+	c.Assert(err, IsNil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- proxy.Run()
+	}()
+
+	// The consumer is stalled, hence the buffer fills up to the high water
+	// mark, and the proxy stops reading.
+	waitFor(c, proxy.IsPaused)
+	depth := proxy.BufferDepth()
+	c.Assert(depth >= 100 && depth < 110, IsTrue, Commentf("%d", depth))
+
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(proxy.BufferDepth(), Equals, depth)
+	c.Assert(proxy.IsPaused(), IsTrue)
+
+	dst.setBlocked(false)
+
+	c.Assert(<-done, IsNil)
+	c.Assert(dst.bytes(), DeepEquals, data)
+	c.Assert(proxy.BufferDepth(), Equals, 0)
+	c.Assert(proxy.IsPaused(), IsFalse)
+}
+
+// An endless source which returns one byte per read.
+type countingReader struct {
+	mutex sync.Mutex
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.reads++
+	p[0] = 'x'
+	return 1, nil
+}
+
+func (r *countingReader) numReads() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.reads
+}
+
+func (s *BackpressureProxySuite) TestDestinationError(c *C) {
+	src := &countingReader{}
+	dst := newGatedWriter(true)
+
+	proxy, err := NewBackpressureProxy(
+		src,
+		dst,
+		BackpressureProxyOptions{
+			HighWaterMark:  50,
+			LowWaterMark:   10,
+			ReadBufferSize: 20,
+		})
+	c.Assert(err, IsNil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- proxy.Run()
+	}()
+
+	waitFor(c, proxy.IsPaused)
+	c.Assert(proxy.BufferDepth(), Equals, 50)
+	c.Assert(src.numReads(), Equals, 50)
+
+	// The proxy stops on write failure, without reading more data from the
+	// source.
+	dst.mutex.Lock()
+	dst.err = fmt.Errorf("broken pipe")
+	dst.mutex.Unlock()
+	dst.setBlocked(false)
+
+	err = <-done
+	c.Assert(err, NotNil)
+	c.Assert(proxy.IsPaused(), IsTrue)
+	c.Assert(src.numReads(), Equals, 50)
+}
+
+func (s *BackpressureProxySuite) TestSourceError(c *C) {
+	data := testData(100)
+	dst := &bytes.Buffer{}
+
+	proxy, err := NewBackpressureProxy(
+		io.MultiReader(
+			bytes.NewReader(data),
+			&errorReader{fmt.Errorf("connection reset")}),
+		dst,
+		BackpressureProxyOptions{
+			HighWaterMark: 1000,
+			LowWaterMark:  100,
+		})
+	c.Assert(err, IsNil)
+
+	// Buffered data is written before the error is returned.
+	c.Assert(proxy.Run(), NotNil)
+	c.Assert(dst.Bytes(), DeepEquals, data)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func (s *BackpressureProxySuite) TestSlidingWindowRate(c *C) {
+	clock := time2.NewMockClock(time.Unix(1000, 0))
+	rate := newSlidingWindowRate(clock, 10*time.Second)
+
+	c.Assert(rate.rate(), Equals, 0.0)
+
+	rate.add(100)
+	clock.Advance(time.Second)
+	rate.add(100)
+	c.Assert(rate.rate(), Equals, 200.0)
+
+	clock.Advance(8500 * time.Millisecond)
+	c.Assert(rate.rate(), Equals, 200.0/9.5)
+
+	// The first bucket expired.
+	clock.Advance(500 * time.Millisecond)
+	c.Assert(rate.rate(), Equals, 10.0)
+
+	rate.add(400)
+	c.Assert(rate.rate(), Equals, 50.0)
+
+	// All buckets expired.
+	clock.Advance(time.Minute)
+	c.Assert(rate.rate(), Equals, 0.0)
+}
+
+func (s *BackpressureProxySuite) TestRates(c *C) {
+	clock := time2.NewMockClock(time.Unix(1000, 0))
+	data := testData(5000)
+
+	proxy, err := NewBackpressureProxy(
+		bytes.NewReader(data),
+		&bytes.Buffer{},
+		BackpressureProxyOptions{
+			HighWaterMark: 10000,
+			LowWaterMark:  0,
+			RateWindow:    10 * time.Second,
+			Clock:         clock,
+		})
+	c.Assert(err, IsNil)
+
+	c.Assert(proxy.ProducerRate(), Equals, 0.0)
+	c.Assert(proxy.ConsumerRate(), Equals, 0.0)
+
+	c.Assert(proxy.Run(), IsNil)
+
+	clock.Advance(2 * time.Second)
+	c.Assert(proxy.ProducerRate(), Equals, 2500.0)
+	c.Assert(proxy.ConsumerRate(), Equals, 2500.0)
+}
@@ -1,10 +1,14 @@
 package net2
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/dropbox/godropbox/errors"
 	rp "github.com/dropbox/godropbox/resource_pool"
 )
 
@@ -46,7 +50,24 @@ func newBaseConnectionPool(
 
 	openFunc := func(loc string) (interface{}, error) {
 		network, address := parseResourceLocation(loc)
-		return dial(network, address)
+		conn, err := dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		if options.InitFunc != nil {
+			err = options.InitFunc(context.Background(), conn)
+			if err != nil {
+				_ = conn.Close()
+				return nil, errors.Wrapf(
+					err,
+					"Failed to initialize connection to %s %s",
+					network,
+					address)
+			}
+		}
+
+		return conn, nil
 	}
 
 	closeFunc := func(handle interface{}) error {
@@ -153,6 +174,110 @@ func (p *connectionPoolImpl) Discard(conn ManagedConn) error {
 	return conn.DiscardConnection()
 }
 
+// See ConnectionPool for documentation.  Each connection is acquired from
+// (and then released back to) the underlying pool, hence idle connections
+// which are already in the pool count towards WarmUpConnections.
+func (p *connectionPoolImpl) WarmUp(ctx context.Context) error {
+	num := p.options.WarmUpConnections
+	if num <= 0 {
+		return nil
+	}
+
+	if p.options.MaxIdleConnections == 0 {
+		return errors.Newf(
+			"Cannot warm up %d connections per host: MaxIdleConnections is 0 "+
+				"(warmed up connections are kept idle)",
+			num)
+	}
+
+	if max := p.options.MaxActiveConnections; max > 0 && int(max) < num {
+		num = int(max)
+	}
+	if max := p.options.MaxIdleConnections; int(max) < num {
+		num = int(max)
+	}
+	if num <= 0 {
+		return nil
+	}
+
+	// The underlying pool fails (instead of waits) when there are too many
+	// concurrent dials.
+	concurrency := num
+	if max := p.options.DialMaxConcurrency; max > 0 && max < concurrency {
+		concurrency = max
+	}
+
+	type warmUpResult struct {
+		location string
+		handle   rp.ManagedHandle
+		err      error
+	}
+
+	locations := p.pool.ListRegistered()
+	results := make(chan warmUpResult, num*len(locations))
+	tokens := make(chan struct{}, concurrency)
+
+	wg := sync.WaitGroup{}
+	for _, location := range locations {
+		for i := 0; i < num; i++ {
+			wg.Add(1)
+			go func(location string) {
+				defer wg.Done()
+
+				tokens <- struct{}{}
+				defer func() { <-tokens }()
+
+				if err := ctx.Err(); err != nil {
+					results <- warmUpResult{location: location, err: err}
+					return
+				}
+
+				handle, err := p.pool.Get(location)
+				results <- warmUpResult{location, handle, err}
+			}(location)
+		}
+	}
+
+	wg.Wait()
+	close(results)
+
+	failures := []string{}
+	total := 0
+	for result := range results {
+		total++
+		if result.err != nil {
+			failures = append(
+				failures,
+				fmt.Sprintf(
+					"%s: %s",
+					result.location,
+					errors.GetMessage(result.err)))
+			continue
+		}
+
+		// Connections are only released once all connections are dialed;
+		// otherwise, the same connection would be reused.
+		if err := result.handle.Release(); err != nil {
+			failures = append(
+				failures,
+				fmt.Sprintf(
+					"%s: %s",
+					result.location,
+					errors.GetMessage(err)))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Newf(
+			"Failed to warm up %d of %d connections: %s",
+			len(failures),
+			total,
+			strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
 // See ConnectionPool for documentation.
 func (p *connectionPoolImpl) EnterLameDuckMode() {
 	p.pool.EnterLameDuckMode()
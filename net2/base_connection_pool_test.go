@@ -1,9 +1,11 @@
 package net2
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -30,6 +32,7 @@ type mockConn struct {
 	nowFunc       func() time.Time
 	readDeadline  *time.Time
 	writeDeadline *time.Time
+	closed        bool
 }
 
 func (c *mockConn) Id() int { return c.id }
@@ -47,7 +50,7 @@ func (c *mockConn) Write(b []byte) (n int, err error) {
 		return 0, fmt.Errorf("timeout")
 	}
 }
-func (c *mockConn) Close() error         { return nil }
+func (c *mockConn) Close() error         { c.closed = true; return nil }
 func (c *mockConn) LocalAddr() net.Addr  { return nil }
 func (c *mockConn) RemoteAddr() net.Addr { return nil }
 func (c *mockConn) SetDeadline(t time.Time) error {
@@ -632,3 +635,202 @@ func (s *BaseConnectionPoolSuite) TestManyConcurrentDialsWhichTimeout(c *C) {
 	c.Assert(duration > dialer.dialLatency, IsTrue)
 	c.Assert(duration < dialer.dialLatency*2, IsTrue)
 }
+
+func (s *BaseConnectionPoolSuite) TestInitFunc(c *C) {
+	dialer := fakeDialer{}
+
+	var initialized []*mockConn
+	fail := false
+	options := ConnectionOptions{
+		MaxIdleConnections: 10,
+		Dial:               dialer.FakeDial,
+		InitFunc: func(ctx context.Context, conn net.Conn) error {
+			c.Assert(ctx, NotNil)
+
+			mock := conn.(*mockConn)
+			initialized = append(initialized, mock)
+			if fail {
+				return fmt.Errorf("SET NAMES failed")
+			}
+			return nil
+		},
+	}
+
+	pool := NewSimpleConnectionPool(options)
+	err := pool.Register("foo", "bar")
+	c.Assert(err, IsNil)
+
+	c1, err := pool.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(len(initialized), Equals, 1)
+	c.Assert(c1.RawConn(), Equals, net.Conn(initialized[0]))
+
+	err = c1.ReleaseConnection()
+	c.Assert(err, IsNil)
+
+	// Recycled connections are not re-initialized.
+	c2, err := pool.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(SameConnection(c1, c2), IsTrue)
+	c.Assert(len(initialized), Equals, 1)
+
+	// Connections which fail to initialize are discarded.
+	fail = true
+	_, err = pool.Get("foo", "bar")
+	c.Assert(err, NotNil)
+	c.Assert(len(initialized), Equals, 2)
+	c.Assert(initialized[1].closed, IsTrue)
+	c.Assert(pool.NumActive(), Equals, int32(1))
+
+	fail = false
+	c3, err := pool.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(len(initialized), Equals, 3)
+	c.Assert(c3.RawConn().(*mockConn).closed, IsFalse)
+	c.Assert(pool.NumActive(), Equals, int32(2))
+}
+
+type warmUpDialer struct {
+	numDials int32
+	fail     bool
+}
+
+func (d *warmUpDialer) Dial(network string, address string) (net.Conn, error) {
+	id := atomic.AddInt32(&d.numDials, 1)
+	if d.fail {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return &mockConn{id: int(id), nowFunc: time.Now}, nil
+}
+
+func (d *warmUpDialer) NumDials() int32 {
+	return atomic.LoadInt32(&d.numDials)
+}
+
+func (s *BaseConnectionPoolSuite) TestWarmUp(c *C) {
+	dialer := &warmUpDialer{}
+	options := ConnectionOptions{
+		MaxIdleConnections: 10,
+		DialMaxConcurrency: 2,
+		WarmUpConnections:  5,
+		Dial:               dialer.Dial,
+	}
+
+	pool := NewSimpleConnectionPool(options)
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(5))
+	c.Assert(pool.NumIdle(), Equals, 5)
+	c.Assert(pool.NumActive(), Equals, int32(0))
+
+	// Warmed up connections are reused.
+	conn, err := pool.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(5))
+	c.Assert(conn.ReleaseConnection(), IsNil)
+
+	// Idle connections count towards the warm up connections.
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(5))
+	c.Assert(pool.NumIdle(), Equals, 5)
+}
+
+func (s *BaseConnectionPoolSuite) TestWarmUpLimits(c *C) {
+	// Warm up is disabled by default.
+	dialer := &warmUpDialer{}
+	pool := NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		Dial:               dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(0))
+
+	// Bounded by the max active connections.
+	dialer = &warmUpDialer{}
+	pool = NewSimpleConnectionPool(ConnectionOptions{
+		MaxActiveConnections: 3,
+		MaxIdleConnections:   10,
+		WarmUpConnections:    20,
+		Dial:                 dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(3))
+	c.Assert(pool.NumIdle(), Equals, 3)
+
+	// Bounded by the max idle connections.
+	dialer = &warmUpDialer{}
+	pool = NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 2,
+		WarmUpConnections:  20,
+		Dial:               dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(2))
+	c.Assert(pool.NumIdle(), Equals, 2)
+
+	// Warming up without idle connections is an error.
+	dialer = &warmUpDialer{}
+	pool = NewSimpleConnectionPool(ConnectionOptions{
+		WarmUpConnections: 20,
+		Dial:              dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+	c.Assert(pool.WarmUp(context.Background()), NotNil)
+	c.Assert(dialer.NumDials(), Equals, int32(0))
+	c.Assert(pool.NumIdle(), Equals, 0)
+}
+
+func (s *BaseConnectionPoolSuite) TestWarmUpMultiplePools(c *C) {
+	dialer := &warmUpDialer{}
+	pool := NewMultiConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		WarmUpConnections:  4,
+		Dial:               dialer.Dial,
+	})
+	c.Assert(pool.Register("tcp", "localhost:11211"), IsNil)
+	c.Assert(pool.Register("tcp", "localhost:11212"), IsNil)
+
+	c.Assert(pool.WarmUp(context.Background()), IsNil)
+	c.Assert(dialer.NumDials(), Equals, int32(8))
+	c.Assert(pool.NumIdle(), Equals, 8)
+}
+
+func (s *BaseConnectionPoolSuite) TestWarmUpErrors(c *C) {
+	dialer := &warmUpDialer{fail: true}
+	pool := NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		WarmUpConnections:  4,
+		Dial:               dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+
+	err := pool.WarmUp(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(
+		err,
+		ErrorMatches,
+		"(?s)Failed to warm up 4 of 4 connections: .*connection refused.*")
+	c.Assert(dialer.NumDials(), Equals, int32(4))
+	c.Assert(pool.NumActive(), Equals, int32(0))
+
+	// Attempts are skipped once the context is done.
+	dialer = &warmUpDialer{}
+	pool = NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		WarmUpConnections:  4,
+		Dial:               dialer.Dial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = pool.WarmUp(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(dialer.NumDials(), Equals, int32(0))
+	c.Assert(pool.NumIdle(), Equals, 0)
+}
@@ -1,6 +1,7 @@
 package net2
 
 import (
+	"context"
 	"net"
 	"time"
 )
@@ -22,10 +23,25 @@ type ConnectionOptions struct {
 	// DialMaxConcurrency is non-positive).
 	DialMaxConcurrency int
 
+	// The number of connections per host which WarmUp pre-dials (up to
+	// MaxActiveConnections and MaxIdleConnections, since warmed up
+	// connections are kept idle).  WarmUp is a no-op when WarmUpConnections
+	// is non-positive.  NOTE: MaxIdleConnections defaults to 0 (i.e., no
+	// connections are kept idle), in which case WarmUp returns an error
+	// rather than dialing connections which are closed right away.
+	WarmUpConnections int
+
 	// Dial specifies the dial function for creating network connections.
 	// If Dial is nil, net.DialTimeout is used, with timeout set to 1 second.
 	Dial func(network string, address string) (net.Conn, error)
 
+	// When InitFunc is non-nil, it is called on every newly dialed connection
+	// before the connection is handed to the caller (e.g., for running
+	// "SET NAMES utf8mb4" on a mysql connection).  The connection is closed
+	// and discarded if InitFunc returns an error.  NOTE: InitFunc is
+	// responsible for setting deadlines on the connection.
+	InitFunc func(ctx context.Context, conn net.Conn) error
+
 	// This specifies the now time function.  When the function is non-nil, the
 	// connection pool will use the specified function instead of time.Now to
 	// generate the current time.
@@ -89,6 +105,14 @@ type ConnectionPool interface {
 	// This discards an active connection from the connection pool.
 	Discard(conn ManagedConn) error
 
+	// This pre-dials connections to all registered (network, address) entries
+	// (see ConnectionOptions.WarmUpConnections), e.g., to avoid paying for
+	// the TCP handshakes on the first requests after startup.  WarmUp returns
+	// once all dial attempts completed; the returned error aggregates all
+	// failed attempts.  Attempts which have not started when ctx is done are
+	// skipped (and are reported as failed).
+	WarmUp(ctx context.Context) error
+
 	// Enter the connection pool into lame duck mode.  The connection pool
 	// will no longer return connections, and all idle connections are closed
 	// immediately (including active connections that are released back to the
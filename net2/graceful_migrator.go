@@ -0,0 +1,267 @@
+package net2
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// ReconnectHinter sends a protocol specific reconnect hint to the peer (e.g.,
+// an http "Connection: close" / http2 GOAWAY, or a redis-like "MOVED"
+// message), which tells the peer to reconnect (to the new process).
+type ReconnectHinter interface {
+	// SendReconnectHint is called on each active connection once its
+	// current request completes (i.e., when the connection is released)
+	// while the pool is draining.  The connection is closed after the hint
+	// is sent.  The connection is discarded if this returns an error.
+	SendReconnectHint(conn net.Conn) error
+}
+
+// GracefulMigrator wraps a connection pool for rolling restarts.  When the
+// process receives SIGUSR1 (or when StartDraining is called), the pool is
+// marked as draining: no new connections are handed out, idle connections
+// are closed, and in-flight requests are allowed to complete.  When an
+// active connection is released, the reconnect hint is sent to the peer,
+// and the connection is closed.
+//
+// GracefulMigrator implements ConnectionPool, and is thread safe.
+type GracefulMigrator struct {
+	pool   ConnectionPool
+	hinter ReconnectHinter
+
+	mutex      sync.Mutex
+	numActive  int
+	isDraining bool
+	drained    chan struct{} // closed once draining and numActive is 0.
+
+	signals  chan os.Signal
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// This returns a graceful migrator for the connection pool, which starts
+// listening for SIGUSR1.  hinter may be nil, in which case connections are
+// closed without sending reconnect hints.  Call Stop to stop listening for
+// the signal.
+func NewGracefulMigrator(
+	pool ConnectionPool,
+	hinter ReconnectHinter) *GracefulMigrator {
+
+	m := &GracefulMigrator{
+		pool:    pool,
+		hinter:  hinter,
+		drained: make(chan struct{}),
+		signals: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+	}
+
+	signal.Notify(m.signals, syscall.SIGUSR1)
+	go m.listen()
+
+	return m
+}
+
+func (m *GracefulMigrator) listen() {
+	for {
+		select {
+		case <-m.signals:
+			m.StartDraining()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Stop stops listening for SIGUSR1.  This does not affect draining.
+func (m *GracefulMigrator) Stop() {
+	m.stopOnce.Do(func() {
+		signal.Stop(m.signals)
+		close(m.stop)
+	})
+}
+
+// StartDraining marks the pool as draining (the underlying pool enters lame
+// duck mode).  This is a no-op if the pool is already draining.
+func (m *GracefulMigrator) StartDraining() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.isDraining {
+		return
+	}
+	m.isDraining = true
+
+	m.pool.EnterLameDuckMode()
+
+	if m.numActive == 0 {
+		close(m.drained)
+	}
+}
+
+// IsDraining returns true if the pool is draining.
+func (m *GracefulMigrator) IsDraining() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.isDraining
+}
+
+// WaitForDrain blocks until the pool is draining and all active connections
+// are returned to the pool, or until timeout is reached (in which case an
+// error is returned).  NOTE: this also waits for draining to start.
+func (m *GracefulMigrator) WaitForDrain(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-m.drained:
+		return nil
+	case <-timer.C:
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.isDraining {
+		return errors.New("Timed out waiting for draining to start")
+	}
+	return errors.Newf(
+		"Timed out waiting for %d active connections to drain",
+		m.numActive)
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) NumActive() int32 {
+	return m.pool.NumActive()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) ActiveHighWaterMark() int32 {
+	return m.pool.ActiveHighWaterMark()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) NumIdle() int {
+	return m.pool.NumIdle()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) Register(network string, address string) error {
+	return m.pool.Register(network, address)
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) Unregister(network string, address string) error {
+	return m.pool.Unregister(network, address)
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) ListRegistered() []NetworkAddress {
+	return m.pool.ListRegistered()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) Get(
+	network string,
+	address string) (ManagedConn, error) {
+
+	m.mutex.Lock()
+	if m.isDraining {
+		m.mutex.Unlock()
+		return nil, errors.New("Connection pool is draining")
+	}
+	m.numActive++
+	m.mutex.Unlock()
+
+	conn, err := m.pool.Get(network, address)
+	if err != nil {
+		m.connectionDone()
+		return nil, err
+	}
+
+	return &migratingConn{
+		ManagedConn: conn,
+		migrator:    m,
+	}, nil
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) Release(conn ManagedConn) error {
+	return conn.ReleaseConnection()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) Discard(conn ManagedConn) error {
+	return conn.DiscardConnection()
+}
+
+// See ConnectionPool for documentation.
+func (m *GracefulMigrator) WarmUp(ctx context.Context) error {
+	return m.pool.WarmUp(ctx)
+}
+
+// See ConnectionPool for documentation.  NOTE: Unlike StartDraining, this
+// does not send reconnect hints.
+func (m *GracefulMigrator) EnterLameDuckMode() {
+	m.pool.EnterLameDuckMode()
+}
+
+func (m *GracefulMigrator) connectionDone() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.numActive--
+	if m.isDraining && m.numActive == 0 {
+		close(m.drained)
+	}
+}
+
+// A managed connection which notifies the migrator when it's returned.
+type migratingConn struct {
+	ManagedConn
+
+	migrator *GracefulMigrator
+	once     sync.Once
+}
+
+// See ManagedConn for documentation.
+func (c *migratingConn) Owner() ConnectionPool {
+	return c.migrator
+}
+
+// See ManagedConn for documentation.
+func (c *migratingConn) ReleaseConnection() error {
+	var err error = errors.New("Connection already returned to the pool")
+	c.once.Do(func() {
+		defer c.migrator.connectionDone()
+
+		if c.migrator.IsDraining() && c.migrator.hinter != nil {
+			hintErr := c.migrator.hinter.SendReconnectHint(c.ManagedConn)
+			if hintErr != nil {
+				_ = c.ManagedConn.DiscardConnection()
+				err = errors.Wrap(hintErr, "Failed to send reconnect hint")
+				return
+			}
+		}
+
+		err = c.ManagedConn.ReleaseConnection()
+	})
+	return err
+}
+
+// See ManagedConn for documentation.
+func (c *migratingConn) DiscardConnection() error {
+	var err error = errors.New("Connection already returned to the pool")
+	c.once.Do(func() {
+		defer c.migrator.connectionDone()
+
+		err = c.ManagedConn.DiscardConnection()
+	})
+	return err
+}
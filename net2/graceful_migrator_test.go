@@ -0,0 +1,154 @@
+package net2
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type GracefulMigratorSuite struct {
+}
+
+var _ = Suite(&GracefulMigratorSuite{})
+
+type recordingHinter struct {
+	mutex sync.Mutex
+	conns []net.Conn
+	err   error
+}
+
+func (h *recordingHinter) SendReconnectHint(conn net.Conn) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.conns = append(h.conns, conn)
+	return h.err
+}
+
+func (h *recordingHinter) numHints() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.conns)
+}
+
+func (s *GracefulMigratorSuite) newMigrator(
+	c *C,
+	hinter ReconnectHinter) *GracefulMigrator {
+
+	dialer := &fakeDialer{}
+	pool := NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		Dial:               dialer.FakeDial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+
+	return NewGracefulMigrator(pool, hinter)
+}
+
+func (s *GracefulMigratorSuite) TestNotDraining(c *C) {
+	hinter := &recordingHinter{}
+	m := s.newMigrator(c, hinter)
+	defer m.Stop()
+
+	conn, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(conn.Owner(), Equals, m)
+	c.Assert(m.NumActive(), Equals, int32(1))
+
+	c.Assert(conn.ReleaseConnection(), IsNil)
+	c.Assert(conn.ReleaseConnection(), NotNil)
+	c.Assert(m.NumActive(), Equals, int32(0))
+	c.Assert(m.NumIdle(), Equals, 1)
+
+	c.Assert(hinter.numHints(), Equals, 0)
+	c.Assert(m.IsDraining(), IsFalse)
+	c.Assert(m.WaitForDrain(10*time.Millisecond), NotNil)
+}
+
+func (s *GracefulMigratorSuite) TestDrain(c *C) {
+	hinter := &recordingHinter{}
+	m := s.newMigrator(c, hinter)
+	defer m.Stop()
+
+	idle, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(idle.ReleaseConnection(), IsNil)
+
+	c1, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c2, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(m.NumIdle(), Equals, 0)
+
+	m.StartDraining()
+	m.StartDraining() // no-op
+	c.Assert(m.IsDraining(), IsTrue)
+
+	_, err = m.Get("foo", "bar")
+	c.Assert(errors.GetMessage(err), Equals, "Connection pool is draining")
+
+	err = m.WaitForDrain(10 * time.Millisecond)
+	c.Assert(
+		errors.GetMessage(err),
+		Equals,
+		"Timed out waiting for 2 active connections to drain")
+
+	// The in-flight request completes.
+	c.Assert(m.Release(c1), IsNil)
+	c.Assert(hinter.numHints(), Equals, 1)
+	c.Assert(c1.RawConn().(*mockConn).closed, IsTrue)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.WaitForDrain(5 * time.Second)
+	}()
+
+	c.Assert(c2.DiscardConnection(), IsNil)
+	c.Assert(<-done, IsNil)
+
+	// Discarded connections are not hinted.
+	c.Assert(hinter.numHints(), Equals, 1)
+	c.Assert(m.NumActive(), Equals, int32(0))
+	c.Assert(m.NumIdle(), Equals, 0)
+}
+
+func (s *GracefulMigratorSuite) TestHintError(c *C) {
+	hinter := &recordingHinter{err: errors.New("broken pipe")}
+	m := s.newMigrator(c, hinter)
+	defer m.Stop()
+
+	conn, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+
+	m.StartDraining()
+
+	c.Assert(conn.ReleaseConnection(), NotNil)
+	c.Assert(hinter.numHints(), Equals, 1)
+	c.Assert(m.WaitForDrain(5*time.Second), IsNil)
+}
+
+func (s *GracefulMigratorSuite) TestSignal(c *C) {
+	m := s.newMigrator(c, nil)
+	defer m.Stop()
+
+	conn, err := m.Get("foo", "bar")
+	c.Assert(err, IsNil)
+
+	c.Assert(syscall.Kill(syscall.Getpid(), syscall.SIGUSR1), IsNil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !m.IsDraining() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(m.IsDraining(), IsTrue)
+
+	c.Assert(conn.ReleaseConnection(), IsNil)
+	c.Assert(m.WaitForDrain(5*time.Second), IsNil)
+}
@@ -110,7 +110,7 @@ func NewSimplePool(addr string, params ConnectionParams) *SimplePool {
 	transport.Dial = pool.conns.Dial
 
 	if params.UseSSL {
-		transport.TLSClientConfig = params.TLSClientConfig
+		transport.TLSClientConfig = applyTLSPolicy(params.TLSClientConfig)
 
 		// Silently ignore error for now, but probably need to change api
 		// to return error.
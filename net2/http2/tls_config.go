@@ -0,0 +1,93 @@
+package http2
+
+import (
+	"crypto/tls"
+)
+
+// FIPS 140-2 approved TLS 1.2 cipher suites (AES-GCM only).  This matches the
+// cipher suites permitted by go's crypto/tls/fipsonly.
+var FIPSCipherSuites = []uint16{
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// FIPS 140-2 approved elliptic curves.
+var FIPSCurves = []tls.CurveID{
+	tls.CurveP256,
+	tls.CurveP384,
+	tls.CurveP521,
+}
+
+// DefaultTLSClientConfig returns the tls config used by pools which set
+// UseSSL without specifying TLSClientConfig.  When built with the fips build
+// tag, the config only permits FIPS approved cipher suites and curves (see
+// FIPSMode).
+func DefaultTLSClientConfig() *tls.Config {
+	return applyTLSPolicy(&tls.Config{})
+}
+
+// This applies the build's tls policy to the config.  Without the fips build
+// tag, the config (which may be nil) is returned as is.
+func applyTLSPolicy(config *tls.Config) *tls.Config {
+	if !FIPSMode {
+		return config
+	}
+
+	return RestrictToFIPS(config)
+}
+
+// RestrictToFIPS returns a copy of the config (the original config is not
+// modified) which only permits FIPS approved cipher suites and curves, and
+// TLS 1.2 (the TLS 1.3 cipher suites are not configurable in crypto/tls).
+// Non-FIPS cipher suites / curves are removed from the config.  When none of
+// the config's cipher suites (or curves) are FIPS approved, all FIPS approved
+// cipher suites (or curves) are used instead, since an empty list means
+// crypto/tls's defaults.  A nil config is treated as an empty config.
+//
+// NOTE: This only restricts the tls parameters.  FIPS validated cryptographic
+// primitives require building with a BoringCrypto enabled go toolchain (see
+// FIPSMode).
+func RestrictToFIPS(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	suites := []uint16{}
+	for _, suite := range config.CipherSuites {
+		for _, approved := range FIPSCipherSuites {
+			if suite == approved {
+				suites = append(suites, suite)
+				break
+			}
+		}
+	}
+	if len(suites) == 0 {
+		suites = append(suites, FIPSCipherSuites...)
+	}
+	config.CipherSuites = suites
+
+	curves := []tls.CurveID{}
+	for _, curve := range config.CurvePreferences {
+		for _, approved := range FIPSCurves {
+			if curve == approved {
+				curves = append(curves, curve)
+				break
+			}
+		}
+	}
+	if len(curves) == 0 {
+		curves = append(curves, FIPSCurves...)
+	}
+	config.CurvePreferences = curves
+
+	config.MinVersion = tls.VersionTLS12
+	config.MaxVersion = tls.VersionTLS12
+
+	return config
+}
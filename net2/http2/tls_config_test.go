@@ -0,0 +1,58 @@
+package http2
+
+import (
+	"crypto/tls"
+
+	. "gopkg.in/check.v1"
+)
+
+type TLSConfigSuite struct {
+}
+
+var _ = Suite(&TLSConfigSuite{})
+
+func (s *TLSConfigSuite) TestRestrictToFIPS(c *C) {
+	config := &tls.Config{
+		ServerName: "example.com",
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP384},
+	}
+
+	restricted := RestrictToFIPS(config)
+	c.Check(restricted.ServerName, Equals, "example.com")
+	c.Check(restricted.CipherSuites, DeepEquals, []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	})
+	c.Check(restricted.CurvePreferences, DeepEquals, []tls.CurveID{
+		tls.CurveP384,
+	})
+	c.Check(restricted.MinVersion, Equals, uint16(tls.VersionTLS12))
+	c.Check(restricted.MaxVersion, Equals, uint16(tls.VersionTLS12))
+
+	// The original config is not modified.
+	c.Check(len(config.CipherSuites), Equals, 4)
+	c.Check(config.MinVersion, Equals, uint16(0))
+}
+
+func (s *TLSConfigSuite) TestRestrictToFIPSDefaults(c *C) {
+	for _, config := range []*tls.Config{
+		nil,
+		{},
+		{
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			},
+			CurvePreferences: []tls.CurveID{tls.X25519},
+		},
+	} {
+		restricted := RestrictToFIPS(config)
+		c.Check(restricted.CipherSuites, DeepEquals, FIPSCipherSuites)
+		c.Check(restricted.CurvePreferences, DeepEquals, FIPSCurves)
+	}
+}
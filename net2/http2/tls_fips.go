@@ -0,0 +1,23 @@
+//go:build fips
+// +build fips
+
+package http2
+
+// FIPSMode is true when the package is built with the fips build tag, in
+// which case the pools' tls configs (including DefaultTLSClientConfig) only
+// permit FIPS approved cipher suites (see RestrictToFIPS).
+//
+// The fips build tag only restricts the tls parameters.  For FIPS 140-2
+// validated cryptographic primitives, the binary must also be built with a
+// BoringCrypto enabled go toolchain:
+//
+//   - go 1.19 and later: GOEXPERIMENT=boringcrypto (linux/amd64 and
+//     linux/arm64 only)
+//   - go 1.8 to 1.18: the dev.boringcrypto toolchain releases (e.g.,
+//     go1.18.10b7)
+//
+// When both are used, crypto/tls/fipsonly is also linked in, which restricts
+// all of crypto/tls (not just the pools) to FIPS approved settings.  (go 1.24
+// and later also ship a native FIPS 140-3 module, enabled via GOFIPS140 /
+// GODEBUG=fips140=on, which is independent of this build tag.)
+const FIPSMode = true
@@ -0,0 +1,9 @@
+//go:build fips && boringcrypto
+// +build fips,boringcrypto
+
+package http2
+
+import (
+	// Restricts crypto/tls to FIPS approved settings process wide.
+	_ "crypto/tls/fipsonly"
+)
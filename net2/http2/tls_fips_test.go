@@ -0,0 +1,65 @@
+//go:build fips
+// +build fips
+
+package http2
+
+import (
+	"crypto/tls"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type FIPSSuite struct {
+}
+
+var _ = Suite(&FIPSSuite{})
+
+func isFIPSCipherSuite(suite uint16) bool {
+	for _, approved := range FIPSCipherSuites {
+		if suite == approved {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FIPSSuite) TestDefaultConfig(c *C) {
+	c.Assert(FIPSMode, IsTrue)
+
+	config := DefaultTLSClientConfig()
+	c.Assert(len(config.CipherSuites) > 0, IsTrue)
+	for _, suite := range config.CipherSuites {
+		c.Check(isFIPSCipherSuite(suite), IsTrue, Commentf("%x", suite))
+	}
+
+	// None of crypto/tls's non-FIPS suites remain.
+	for _, suite := range tls.CipherSuites() {
+		if isFIPSCipherSuite(suite.ID) {
+			continue
+		}
+		for _, configured := range config.CipherSuites {
+			c.Check(configured, Not(Equals), suite.ID)
+		}
+	}
+}
+
+func (s *FIPSSuite) TestPoolConfigIsRestricted(c *C) {
+	params := DefaultPoolParams()
+	params.UseSSL = true
+	params.TLSClientConfig = &tls.Config{
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		},
+	}
+
+	pool := NewSimplePool("127.0.0.1:1", params)
+	defer pool.Close()
+
+	c.Check(
+		pool.transport.TLSClientConfig.CipherSuites,
+		DeepEquals,
+		[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})
+}
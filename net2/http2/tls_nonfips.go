@@ -0,0 +1,8 @@
+//go:build !fips
+// +build !fips
+
+package http2
+
+// FIPSMode is true when the package is built with the fips build tag.  See
+// tls_fips.go for details.
+const FIPSMode = false
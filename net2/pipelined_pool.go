@@ -0,0 +1,289 @@
+package net2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+// ResponseReader reads a single (complete) response from the reader, and
+// returns the response's raw bytes.
+type ResponseReader func(reader *bufio.Reader) ([]byte, error)
+
+// PipelineConn queues commands for a single pipeline.  See
+// PipelinedPool.Pipeline for details.
+type PipelineConn interface {
+	// Send queues the command.  The command is not sent to the server
+	// until the pipeline's function returns (hence Send never blocks).
+	Send(cmd []byte)
+
+	// NumQueued returns the number of queued commands.
+	NumQueued() int
+}
+
+type pipelineConnImpl struct {
+	buffer    bytes.Buffer
+	numQueued int
+}
+
+// See PipelineConn for documentation.
+func (c *pipelineConnImpl) Send(cmd []byte) {
+	_, _ = c.buffer.Write(cmd)
+	c.numQueued++
+}
+
+// See PipelineConn for documentation.
+func (c *pipelineConnImpl) NumQueued() int {
+	return c.numQueued
+}
+
+// PipelinedPool sends batches of commands (e.g., redis commands) over pooled
+// connections, without waiting for individual responses.  All commands in a
+// batch are written using a single write, and the responses are then read
+// back in order; hence, a batch of N commands takes a single round trip
+// instead of N.
+//
+// The server must respond to every command with exactly one response, and
+// must process the commands in order (redis pipelining semantic).
+//
+// PipelinedPool is thread safe.
+type PipelinedPool struct {
+	pool         ConnectionPool
+	network      string
+	address      string
+	readResponse ResponseReader
+}
+
+// This returns a pipelined pool which sends commands to (network, address)
+// using connections from the connection pool.  (network, address) must be
+// registered with the connection pool.  When readResponse is nil,
+// ReadRedisResponse is used.
+func NewPipelinedPool(
+	pool ConnectionPool,
+	network string,
+	address string,
+	readResponse ResponseReader) *PipelinedPool {
+
+	if readResponse == nil {
+		readResponse = ReadRedisResponse
+	}
+
+	return &PipelinedPool{
+		pool:         pool,
+		network:      network,
+		address:      address,
+		readResponse: readResponse,
+	}
+}
+
+// Pipeline calls fn to queue the batch's commands.  Once fn returns, a
+// connection is acquired from the pool, the queued commands are flushed to
+// the server in a single write, and all responses are read back.  The responses
+// are returned in the same order as their commands.
+//
+// Nothing is sent to the server if fn returns an error.  The connection is
+// discarded (instead of released back to the pool) when the batch fails
+// midway, since the connection may have unread responses.  The connection's
+// deadline is set by the context's deadline (when specified), and the batch
+// is aborted when the context is canceled.
+func (p *PipelinedPool) Pipeline(
+	ctx context.Context,
+	fn func(conn PipelineConn) error) ([][]byte, error) {
+
+	pipelineConn := &pipelineConnImpl{}
+	err := fn(pipelineConn)
+	if err != nil {
+		return nil, err
+	}
+
+	if pipelineConn.numQueued == 0 {
+		return nil, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn, err := p.pool.Get(p.network, p.address)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to get connection to %s %s",
+			p.network,
+			p.address)
+	}
+
+	responses, err := p.run(ctx, conn, pipelineConn)
+	if err != nil {
+		_ = conn.DiscardConnection()
+		return nil, err
+	}
+
+	_ = conn.ReleaseConnection()
+	return responses, nil
+}
+
+func (p *PipelinedPool) run(
+	ctx context.Context,
+	conn ManagedConn,
+	pipelineConn *pipelineConnImpl) ([][]byte, error) {
+
+	raw := conn.RawConn()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = raw.SetDeadline(deadline)
+	}
+	defer func() { _ = raw.SetDeadline(time.Time{}) }()
+
+	// Interrupt blocked reads / writes when the context is canceled.
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = raw.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	_, err := conn.Write(pipelineConn.buffer.Bytes())
+	if err != nil {
+		return nil, p.contextError(ctx, err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	responses := make([][]byte, 0, pipelineConn.numQueued)
+	for i := 0; i < pipelineConn.numQueued; i++ {
+		response, err := p.readResponse(reader)
+		if err != nil {
+			return nil, p.contextError(
+				ctx,
+				errors.Wrapf(err, "Failed to read response %d", i))
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func (p *PipelinedPool) contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	// The connection's deadline may expire slightly before the context's
+	// timer fires.
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func readRedisLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.Newf("Invalid redis response line: %q", line)
+	}
+
+	return line, nil
+}
+
+const (
+	// Same as redis' default proto-max-bulk-len.
+	maxRedisBulkLength = 512 * 1024 * 1024
+
+	redisReadChunkSize = 64 * 1024
+)
+
+func parseRedisLength(line []byte) (int, error) {
+	n, err := strconv.Atoi(string(line[1 : len(line)-2]))
+	if err != nil || n < -1 {
+		return 0, errors.Newf("Invalid redis length: %q", line)
+	}
+	return n, nil
+}
+
+func appendRedisResponse(
+	response []byte,
+	reader *bufio.Reader) ([]byte, error) {
+
+	line, err := readRedisLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	response = append(response, line...)
+
+	switch line[0] {
+	case '+', '-', ':':
+		return response, nil
+	case '$':
+		n, err := parseRedisLength(line)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 { // null bulk string
+			return response, nil
+		}
+
+		if n > maxRedisBulkLength {
+			return nil, errors.Newf("Redis bulk string too long: %d", n)
+		}
+
+		// The buffer grows as the data arrives (rather than upfront), hence
+		// a bogus length does not trigger a huge allocation.
+		remaining := n + 2
+		for remaining > 0 {
+			chunk := remaining
+			if chunk > redisReadChunkSize {
+				chunk = redisReadChunkSize
+			}
+
+			start := len(response)
+			response = append(response, make([]byte, chunk)...)
+			_, err := io.ReadFull(reader, response[start:])
+			if err != nil {
+				return nil, err
+			}
+			remaining -= chunk
+		}
+		if !bytes.HasSuffix(response, []byte("\r\n")) {
+			return nil, errors.New("Invalid redis bulk string terminator")
+		}
+		return response, nil
+	case '*':
+		n, err := parseRedisLength(line)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			response, err = appendRedisResponse(response, reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return response, nil
+	}
+
+	return nil, errors.Newf("Invalid redis response type: %q", line[0])
+}
+
+// ReadRedisResponse reads a single RESP (redis serialization protocol)
+// response, and returns the response's raw bytes (e.g., "+OK\r\n",
+// "$3\r\nfoo\r\n").  Error responses are returned as regular responses.
+func ReadRedisResponse(reader *bufio.Reader) ([]byte, error) {
+	return appendRedisResponse(nil, reader)
+}
@@ -0,0 +1,223 @@
+package net2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PipelinedPoolSuite struct {
+}
+
+var _ = Suite(&PipelinedPoolSuite{})
+
+type countingConn struct {
+	net.Conn
+
+	mutex     sync.Mutex
+	numWrites int
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	c.numWrites++
+	c.mutex.Unlock()
+
+	return c.Conn.Write(b)
+}
+
+func (c *countingConn) NumWrites() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.numWrites
+}
+
+// A fake redis server which echos each command's first argument (or stalls
+// forever when the argument is "STALL").
+func serveFakeRedis(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		cmd, err := ReadRedisResponse(reader)
+		if err != nil {
+			return
+		}
+
+		if bytes.Contains(cmd, []byte("STALL")) {
+			<-make(chan struct{})
+		}
+
+		// *2\r\n$4\r\nECHO\r\n$<n>\r\n<arg>\r\n
+		lines := bytes.Split(cmd, []byte("\r\n"))
+		arg := lines[4]
+		_, err = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(arg), arg)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func echoCmd(arg string) []byte {
+	return []byte(fmt.Sprintf("*2\r\n$4\r\nECHO\r\n$%d\r\n%s\r\n", len(arg), arg))
+}
+
+func (s *PipelinedPoolSuite) newPool(
+	c *C) (*PipelinedPool, ConnectionPool, *[]*countingConn) {
+
+	conns := []*countingConn{}
+
+	options := ConnectionOptions{
+		MaxIdleConnections: 1,
+		Dial: func(network string, address string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go serveFakeRedis(server)
+
+			conn := &countingConn{Conn: client}
+			conns = append(conns, conn)
+			return conn, nil
+		},
+	}
+
+	pool := NewSimpleConnectionPool(options)
+	err := pool.Register("tcp", "fake-redis")
+	c.Assert(err, IsNil)
+
+	return NewPipelinedPool(pool, "tcp", "fake-redis", nil), pool, &conns
+}
+
+func (s *PipelinedPoolSuite) TestPipeline(c *C) {
+	pipelined, pool, conns := s.newPool(c)
+
+	responses, err := pipelined.Pipeline(
+		context.Background(),
+		func(conn PipelineConn) error {
+			conn.Send(echoCmd("foo"))
+			conn.Send(echoCmd(""))
+			conn.Send(echoCmd("bar"))
+			c.Assert(conn.NumQueued(), Equals, 3)
+			return nil
+		})
+	c.Assert(err, IsNil)
+	c.Assert(
+		responses,
+		DeepEquals,
+		[][]byte{
+			[]byte("$3\r\nfoo\r\n"),
+			[]byte("$0\r\n\r\n"),
+			[]byte("$3\r\nbar\r\n"),
+		})
+
+	c.Assert(*conns, HasLen, 1)
+	c.Assert((*conns)[0].NumWrites(), Equals, 1)
+	c.Assert(pool.NumActive(), Equals, int32(0))
+	c.Assert(pool.NumIdle(), Equals, 1)
+
+	// The connection is reused.
+	responses, err = pipelined.Pipeline(
+		context.Background(),
+		func(conn PipelineConn) error {
+			conn.Send(echoCmd("baz"))
+			return nil
+		})
+	c.Assert(err, IsNil)
+	c.Assert(responses, DeepEquals, [][]byte{[]byte("$3\r\nbaz\r\n")})
+
+	c.Assert(*conns, HasLen, 1)
+	c.Assert((*conns)[0].NumWrites(), Equals, 2)
+}
+
+func (s *PipelinedPoolSuite) TestEmptyAndFailedBatch(c *C) {
+	pipelined, pool, conns := s.newPool(c)
+
+	responses, err := pipelined.Pipeline(
+		context.Background(),
+		func(conn PipelineConn) error {
+			return nil
+		})
+	c.Assert(err, IsNil)
+	c.Assert(responses, HasLen, 0)
+
+	_, err = pipelined.Pipeline(
+		context.Background(),
+		func(conn PipelineConn) error {
+			conn.Send(echoCmd("foo"))
+			return fmt.Errorf("bad batch")
+		})
+	c.Assert(err, ErrorMatches, "bad batch")
+
+	// Nothing is sent.
+	c.Assert(*conns, HasLen, 0)
+	c.Assert(pool.NumActive(), Equals, int32(0))
+}
+
+func (s *PipelinedPoolSuite) TestContextTimeout(c *C) {
+	pipelined, pool, conns := s.newPool(c)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+
+	_, err := pipelined.Pipeline(
+		ctx,
+		func(conn PipelineConn) error {
+			conn.Send(echoCmd("foo"))
+			conn.Send(echoCmd("STALL"))
+			return nil
+		})
+	c.Assert(err, Equals, context.DeadlineExceeded)
+
+	// The connection has unread responses, hence it's discarded.
+	c.Assert(*conns, HasLen, 1)
+	c.Assert(pool.NumActive(), Equals, int32(0))
+	c.Assert(pool.NumIdle(), Equals, 0)
+}
+
+func (s *PipelinedPoolSuite) TestReadRedisResponse(c *C) {
+	input := "+OK\r\n" +
+		"-ERR unknown command\r\n" +
+		":42\r\n" +
+		"$-1\r\n" +
+		"$5\r\nhe\r\no\r\n" +
+		"$70000\r\n" + strings.Repeat("x", 70000) + "\r\n" +
+		"*3\r\n:1\r\n*-1\r\n*1\r\n$1\r\na\r\n" +
+		"*0\r\n"
+
+	reader := bufio.NewReader(bytes.NewBufferString(input))
+
+	for _, expected := range []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":42\r\n",
+		"$-1\r\n",
+		"$5\r\nhe\r\no\r\n",
+		"$70000\r\n" + strings.Repeat("x", 70000) + "\r\n",
+		"*3\r\n:1\r\n*-1\r\n*1\r\n$1\r\na\r\n",
+		"*0\r\n",
+	} {
+		response, err := ReadRedisResponse(reader)
+		c.Assert(err, IsNil)
+		c.Assert(string(response), Equals, expected)
+	}
+
+	for _, input := range []string{
+		"",
+		"OK\r\n",
+		"+OK\n",
+		"$abc\r\n",
+		"$3\r\nfoobar\r\n",
+		"*2\r\n:1\r\n",
+		"$536870913\r\n",
+		"$100000\r\nfoo\r\n",
+	} {
+		_, err := ReadRedisResponse(
+			bufio.NewReader(bytes.NewBufferString(input)))
+		c.Assert(err, NotNil, Commentf("input: %q", input))
+	}
+}
@@ -0,0 +1,239 @@
+package net2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConnectionInfo describes a connection which is checked out of the pool.
+type ConnectionInfo struct {
+	// The (network, address) entry used for creating the connection.
+	Key NetworkAddress
+
+	// The connection's remote address (or Key's address when the remote
+	// address is not available).
+	RemoteAddr string
+
+	// When the connection was checked out of the pool.
+	CheckoutTime time.Time
+
+	// The stack trace of the goroutine which checked out the connection.
+	GoroutineStack []byte
+}
+
+// PoolInspector wraps a connection pool for debugging connection leaks.  In
+// debug mode, the inspector tracks all checked out connections, along with
+// the checkout time and the stack trace of the goroutine which checked out
+// the connection.  When debug mode is off, connections are passed through
+// as is, without any tracking overhead.
+//
+// PoolInspector implements ConnectionPool, and is thread safe.
+type PoolInspector struct {
+	pool      ConnectionPool
+	debugMode bool
+	nowFunc   func() time.Time
+
+	mutex      sync.Mutex
+	checkedOut map[*inspectedConn]*ConnectionInfo
+}
+
+// This returns an inspector for the connection pool.  Checked out
+// connections are only tracked when debugMode is true.
+func NewPoolInspector(pool ConnectionPool, debugMode bool) *PoolInspector {
+	return &PoolInspector{
+		pool:       pool,
+		debugMode:  debugMode,
+		nowFunc:    time.Now,
+		checkedOut: make(map[*inspectedConn]*ConnectionInfo),
+	}
+}
+
+// DebugMode returns true if checked out connections are tracked.
+func (p *PoolInspector) DebugMode() bool {
+	return p.debugMode
+}
+
+// CheckedOut returns the tracked checked out connections, ordered by
+// checkout time (oldest first).  This always returns nil when debug mode is
+// off.
+func (p *PoolInspector) CheckedOut() []ConnectionInfo {
+	if !p.debugMode {
+		return nil
+	}
+
+	p.mutex.Lock()
+	result := make([]ConnectionInfo, 0, len(p.checkedOut))
+	for _, info := range p.checkedOut {
+		result = append(result, *info)
+	}
+	p.mutex.Unlock()
+
+	sort.Slice(result, func(i int, j int) bool {
+		return result[i].CheckoutTime.Before(result[j].CheckoutTime)
+	})
+
+	return result
+}
+
+// DumpLeaks returns a human readable report of the connections which have
+// been checked out for at least minAge.
+func (p *PoolInspector) DumpLeaks(minAge time.Duration) string {
+	if !p.debugMode {
+		return "Connection tracking is disabled (debug mode is off)\n"
+	}
+
+	now := p.nowFunc()
+
+	var leaks []ConnectionInfo
+	for _, info := range p.CheckedOut() {
+		if now.Sub(info.CheckoutTime) >= minAge {
+			leaks = append(leaks, info)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(
+		buf,
+		"%d connection(s) checked out for at least %v\n",
+		len(leaks),
+		minAge)
+
+	for _, info := range leaks {
+		fmt.Fprintf(
+			buf,
+			"\n%s %s (remote: %s) checked out for %v (since %s) by:\n%s",
+			info.Key.Network,
+			info.Key.Address,
+			info.RemoteAddr,
+			now.Sub(info.CheckoutTime),
+			info.CheckoutTime.Format(time.RFC3339Nano),
+			info.GoroutineStack)
+	}
+
+	return buf.String()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) NumActive() int32 {
+	return p.pool.NumActive()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) ActiveHighWaterMark() int32 {
+	return p.pool.ActiveHighWaterMark()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) NumIdle() int {
+	return p.pool.NumIdle()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) Register(network string, address string) error {
+	return p.pool.Register(network, address)
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) Unregister(network string, address string) error {
+	return p.pool.Unregister(network, address)
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) ListRegistered() []NetworkAddress {
+	return p.pool.ListRegistered()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) Get(
+	network string,
+	address string) (ManagedConn, error) {
+
+	conn, err := p.pool.Get(network, address)
+	if err != nil || !p.debugMode {
+		return conn, err
+	}
+
+	info := &ConnectionInfo{
+		Key:            conn.Key(),
+		RemoteAddr:     conn.Key().Address,
+		CheckoutTime:   p.nowFunc(),
+		GoroutineStack: debug.Stack(),
+	}
+	if raw := conn.RawConn(); raw != nil && raw.RemoteAddr() != nil {
+		info.RemoteAddr = raw.RemoteAddr().String()
+	}
+
+	inspected := &inspectedConn{
+		ManagedConn: conn,
+		inspector:   p,
+	}
+
+	p.mutex.Lock()
+	p.checkedOut[inspected] = info
+	p.mutex.Unlock()
+
+	return inspected, nil
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) Release(conn ManagedConn) error {
+	return conn.ReleaseConnection()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) Discard(conn ManagedConn) error {
+	return conn.DiscardConnection()
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) WarmUp(ctx context.Context) error {
+	return p.pool.WarmUp(ctx)
+}
+
+// See ConnectionPool for documentation.
+func (p *PoolInspector) EnterLameDuckMode() {
+	p.pool.EnterLameDuckMode()
+}
+
+func (p *PoolInspector) checkIn(conn *inspectedConn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.checkedOut, conn)
+}
+
+// A managed connection which is untracked by the inspector when it's
+// returned.
+type inspectedConn struct {
+	ManagedConn
+
+	inspector *PoolInspector
+}
+
+// See ManagedConn for documentation.
+func (c *inspectedConn) Owner() ConnectionPool {
+	return c.inspector
+}
+
+// See ManagedConn for documentation.
+func (c *inspectedConn) ReleaseConnection() error {
+	c.inspector.checkIn(c)
+	return c.ManagedConn.ReleaseConnection()
+}
+
+// See ManagedConn for documentation.
+func (c *inspectedConn) DiscardConnection() error {
+	c.inspector.checkIn(c)
+	return c.ManagedConn.DiscardConnection()
+}
+
+// See net.Conn for documentation.  Closing a managed connection discards it.
+func (c *inspectedConn) Close() error {
+	c.inspector.checkIn(c)
+	return c.ManagedConn.Close()
+}
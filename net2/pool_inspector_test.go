@@ -0,0 +1,162 @@
+package net2
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type PoolInspectorSuite struct {
+	now time.Time
+}
+
+var _ = Suite(&PoolInspectorSuite{})
+
+func (s *PoolInspectorSuite) newInspector(
+	c *C,
+	debugMode bool) *PoolInspector {
+
+	dialer := &fakeDialer{}
+	pool := NewSimpleConnectionPool(ConnectionOptions{
+		MaxIdleConnections: 10,
+		Dial:               dialer.FakeDial,
+	})
+	c.Assert(pool.Register("foo", "bar"), IsNil)
+
+	s.now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	inspector := NewPoolInspector(pool, debugMode)
+	inspector.nowFunc = func() time.Time { return s.now }
+	return inspector
+}
+
+func (s *PoolInspectorSuite) TestDebugModeOff(c *C) {
+	inspector := s.newInspector(c, false)
+	c.Assert(inspector.DebugMode(), IsFalse)
+
+	conn, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+
+	// The underlying pool's connection is returned as is.
+	_, ok := conn.(*inspectedConn)
+	c.Assert(ok, IsFalse)
+
+	c.Assert(inspector.NumActive(), Equals, int32(1))
+	c.Assert(inspector.CheckedOut(), IsNil)
+	c.Assert(
+		inspector.DumpLeaks(0),
+		Equals,
+		"Connection tracking is disabled (debug mode is off)\n")
+
+	c.Assert(inspector.Release(conn), IsNil)
+	c.Assert(inspector.NumActive(), Equals, int32(0))
+}
+
+func (s *PoolInspectorSuite) TestCheckedOut(c *C) {
+	inspector := s.newInspector(c, true)
+	c.Assert(inspector.DebugMode(), IsTrue)
+
+	conn1, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(conn1.Owner(), Equals, inspector)
+
+	s.now = s.now.Add(time.Minute)
+
+	conn2, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+
+	checkedOut := inspector.CheckedOut()
+	c.Assert(checkedOut, HasLen, 2)
+	c.Assert(
+		checkedOut[0].CheckoutTime,
+		Equals,
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	c.Assert(
+		checkedOut[1].CheckoutTime,
+		Equals,
+		time.Date(2020, 1, 2, 3, 5, 5, 0, time.UTC))
+
+	for _, info := range checkedOut {
+		c.Assert(info.Key, Equals, NetworkAddress{"foo", "bar"})
+		c.Assert(info.RemoteAddr, Equals, "bar")
+		c.Assert(
+			strings.Contains(
+				string(info.GoroutineStack),
+				"PoolInspectorSuite).TestCheckedOut"),
+			IsTrue)
+	}
+
+	c.Assert(conn1.ReleaseConnection(), IsNil)
+	c.Assert(inspector.Discard(conn2), IsNil)
+
+	c.Assert(inspector.CheckedOut(), HasLen, 0)
+	c.Assert(inspector.NumActive(), Equals, int32(0))
+	c.Assert(inspector.NumIdle(), Equals, 1)
+}
+
+func (s *PoolInspectorSuite) TestClose(c *C) {
+	inspector := s.newInspector(c, true)
+
+	conn, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(inspector.CheckedOut(), HasLen, 1)
+
+	c.Assert(conn.Close(), IsNil)
+
+	c.Assert(inspector.CheckedOut(), HasLen, 0)
+	c.Assert(
+		strings.HasPrefix(
+			inspector.DumpLeaks(0),
+			"0 connection(s) checked out"),
+		IsTrue,
+		Commentf(inspector.DumpLeaks(0)))
+	c.Assert(inspector.NumActive(), Equals, int32(0))
+	c.Assert(inspector.NumIdle(), Equals, 0)
+}
+
+func (s *PoolInspectorSuite) TestDumpLeaks(c *C) {
+	inspector := s.newInspector(c, true)
+
+	leaked, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	defer leaked.ReleaseConnection()
+
+	s.now = s.now.Add(10 * time.Minute)
+
+	recent, err := inspector.Get("foo", "bar")
+	c.Assert(err, IsNil)
+	defer recent.ReleaseConnection()
+
+	s.now = s.now.Add(time.Minute)
+
+	report := inspector.DumpLeaks(5 * time.Minute)
+	c.Assert(
+		strings.HasPrefix(
+			report,
+			"1 connection(s) checked out for at least 5m0s\n\n"+
+				"foo bar (remote: bar) checked out for 11m0s "+
+				"(since 2020-01-02T03:04:05Z) by:\n"),
+		IsTrue,
+		Commentf(report))
+	c.Assert(strings.Contains(report, "TestDumpLeaks"), IsTrue)
+
+	report = inspector.DumpLeaks(0)
+	c.Assert(
+		strings.HasPrefix(
+			report,
+			"2 connection(s) checked out for at least 0s\n"),
+		IsTrue,
+		Commentf(report))
+	c.Assert(
+		strings.Contains(report, "checked out for 1m0s"),
+		IsTrue,
+		Commentf(report))
+
+	c.Assert(
+		inspector.DumpLeaks(time.Hour),
+		Equals,
+		"0 connection(s) checked out for at least 1h0m0s\n")
+}
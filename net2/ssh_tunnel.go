@@ -0,0 +1,199 @@
+package net2
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const defaultSSHKeepAliveInterval = 30 * time.Second
+
+// A generic interface for dialing network connections.  The Dial method can
+// be used as ConnectionOptions.Dial.
+type Dialer interface {
+	Dial(network string, address string) (net.Conn, error)
+}
+
+// The subset of ssh client functionality used by SSHTunnelDialer.
+// *ssh.Client (from golang.org/x/crypto/ssh) satisfies this interface.
+type SSHClient interface {
+	// This opens a connection to address from the remote ssh host.
+	Dial(network string, address string) (net.Conn, error)
+
+	// This sends a global request to the remote ssh host.
+	SendRequest(
+		name string,
+		wantReply bool,
+		payload []byte) (bool, []byte, error)
+
+	Close() error
+}
+
+// This establishes an ssh client connection to the bastion host.  The
+// function is responsible for the ssh client config (user, auth methods,
+// host key callback, etc).  For example:
+//
+//	func(network string, address string) (net2.SSHClient, error) {
+//		return ssh.Dial(network, address, config)
+//	}
+type SSHClientDialFunc func(
+	network string,
+	bastionAddress string) (SSHClient, error)
+
+// SSHTunnelDialer dials connections through an ssh bastion (jump) host.  A
+// single ssh connection to the bastion is shared by all tunneled
+// connections.  The ssh connection is kept alive by periodically sending
+// keepalive requests; when the ssh connection is broken, the dialer
+// reconnects on the next Dial call.
+type SSHTunnelDialer struct {
+	bastionNetwork    string
+	bastionAddress    string
+	dialSSH           SSHClientDialFunc
+	keepAliveInterval time.Duration
+
+	mutex  sync.Mutex
+	client SSHClient
+	stop   chan struct{} // stops the current client's keepalive goroutine
+	closed bool
+}
+
+var _ Dialer = &SSHTunnelDialer{}
+
+// This returns a dialer which tunnels connections through the ssh bastion at
+// bastionAddress (host:port).  When keepAliveInterval is non-positive, a
+// default interval of 30 seconds is used.
+func NewSSHTunnelDialer(
+	bastionAddress string,
+	dialSSH SSHClientDialFunc,
+	keepAliveInterval time.Duration) *SSHTunnelDialer {
+
+	if keepAliveInterval <= 0 {
+		keepAliveInterval = defaultSSHKeepAliveInterval
+	}
+
+	return &SSHTunnelDialer{
+		bastionNetwork:    "tcp",
+		bastionAddress:    bastionAddress,
+		dialSSH:           dialSSH,
+		keepAliveInterval: keepAliveInterval,
+	}
+}
+
+// This returns the shared ssh client, connecting to the bastion if needed.
+func (d *SSHTunnelDialer) getClient() (SSHClient, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil, errors.New("SSH tunnel dialer is closed")
+	}
+
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	client, err := d.dialSSH(d.bastionNetwork, d.bastionAddress)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to connect to ssh bastion %s",
+			d.bastionAddress)
+	}
+
+	d.client = client
+	d.stop = make(chan struct{})
+	go d.keepAlive(client, d.stop)
+
+	return client, nil
+}
+
+// This closes the client and clears it (if it's still the shared client), so
+// that the next Dial call reconnects to the bastion.
+func (d *SSHTunnelDialer) discardClient(client SSHClient) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.client != client {
+		return
+	}
+
+	close(d.stop)
+	_ = d.client.Close()
+	d.client = nil
+	d.stop = nil
+}
+
+func isSSHClientAlive(client SSHClient) bool {
+	_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
+
+func (d *SSHTunnelDialer) keepAlive(client SSHClient, stop chan struct{}) {
+	ticker := time.NewTicker(d.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !isSSHClientAlive(client) {
+				d.discardClient(client)
+				return
+			}
+		}
+	}
+}
+
+// This opens a connection to address (host:port) through the ssh bastion.
+func (d *SSHTunnelDialer) Dial(
+	network string,
+	address string) (net.Conn, error) {
+
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.Dial(network, address)
+	if err != nil {
+		// The target may have rejected the connection; only discard the
+		// shared ssh connection when it's broken.
+		if !isSSHClientAlive(client) {
+			d.discardClient(client)
+		}
+		return nil, errors.Wrapf(
+			err,
+			"Failed to dial %s %s through ssh bastion %s",
+			network,
+			address,
+			d.bastionAddress)
+	}
+
+	return conn, nil
+}
+
+// This closes the shared ssh connection.  Connections previously opened
+// through the tunnel are also closed.
+func (d *SSHTunnelDialer) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+
+	if d.client == nil {
+		return nil
+	}
+
+	close(d.stop)
+	err := d.client.Close()
+	d.client = nil
+	d.stop = nil
+
+	return err
+}
@@ -0,0 +1,211 @@
+package net2
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type SSHTunnelDialerSuite struct {
+}
+
+var _ = Suite(&SSHTunnelDialerSuite{})
+
+type fakeSSHClient struct {
+	mutex      sync.Mutex
+	dialed     []string
+	broken     bool
+	rejectDial bool
+	closed     bool
+}
+
+func (c *fakeSSHClient) Dial(network string, address string) (net.Conn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broken || c.rejectDial {
+		return nil, fmt.Errorf("dial failed")
+	}
+
+	c.dialed = append(c.dialed, network+" "+address)
+	return &mockConn{id: len(c.dialed)}, nil
+}
+
+func (c *fakeSSHClient) SendRequest(
+	name string,
+	wantReply bool,
+	payload []byte) (bool, []byte, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broken {
+		return false, nil, fmt.Errorf("connection lost")
+	}
+	return true, nil, nil
+}
+
+func (c *fakeSSHClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	return nil
+}
+
+func (c *fakeSSHClient) IsClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+func (c *fakeSSHClient) Break() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.broken = true
+}
+
+type fakeSSHBastion struct {
+	mutex   sync.Mutex
+	clients []*fakeSSHClient
+}
+
+func (b *fakeSSHBastion) Dial(
+	network string,
+	address string) (SSHClient, error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if address != "bastion:22" {
+		return nil, fmt.Errorf("unknown bastion")
+	}
+
+	client := &fakeSSHClient{}
+	b.clients = append(b.clients, client)
+	return client, nil
+}
+
+func (b *fakeSSHBastion) Clients() []*fakeSSHClient {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return append([]*fakeSSHClient{}, b.clients...)
+}
+
+func (s *SSHTunnelDialerSuite) TestSharedConnection(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("bastion:22", bastion.Dial, time.Hour)
+	defer dialer.Close()
+
+	conn1, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+	conn2, err := dialer.Dial("tcp", "db2:3306")
+	c.Assert(err, IsNil)
+	c.Check(conn1.(*mockConn).Id(), Equals, 1)
+	c.Check(conn2.(*mockConn).Id(), Equals, 2)
+
+	clients := bastion.Clients()
+	c.Assert(len(clients), Equals, 1)
+	c.Check(clients[0].dialed, DeepEquals, []string{"tcp db1:3306", "tcp db2:3306"})
+}
+
+func (s *SSHTunnelDialerSuite) TestBastionDialFailure(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("unknown:22", bastion.Dial, time.Hour)
+	defer dialer.Close()
+
+	_, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, NotNil)
+}
+
+func (s *SSHTunnelDialerSuite) TestRejectedDial(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("bastion:22", bastion.Dial, time.Hour)
+	defer dialer.Close()
+
+	_, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+
+	client := bastion.Clients()[0]
+	client.rejectDial = true
+
+	_, err = dialer.Dial("tcp", "db2:3306")
+	c.Assert(err, NotNil)
+
+	// The ssh connection is still healthy; it should not be discarded.
+	c.Check(client.IsClosed(), IsFalse)
+
+	client.rejectDial = false
+	_, err = dialer.Dial("tcp", "db2:3306")
+	c.Assert(err, IsNil)
+	c.Check(len(bastion.Clients()), Equals, 1)
+}
+
+func (s *SSHTunnelDialerSuite) TestReconnectAfterBrokenDial(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("bastion:22", bastion.Dial, time.Hour)
+	defer dialer.Close()
+
+	_, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+
+	bastion.Clients()[0].Break()
+
+	_, err = dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, NotNil)
+	c.Check(bastion.Clients()[0].IsClosed(), IsTrue)
+
+	_, err = dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+
+	clients := bastion.Clients()
+	c.Assert(len(clients), Equals, 2)
+	c.Check(clients[1].dialed, DeepEquals, []string{"tcp db1:3306"})
+}
+
+func (s *SSHTunnelDialerSuite) TestKeepAlive(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("bastion:22", bastion.Dial, time.Millisecond)
+	defer dialer.Close()
+
+	_, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+
+	client := bastion.Clients()[0]
+	client.Break()
+
+	// The keepalive goroutine should detect the broken connection.
+	for i := 0; i < 1000 && !client.IsClosed(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(client.IsClosed(), IsTrue)
+
+	_, err = dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+	c.Check(len(bastion.Clients()), Equals, 2)
+}
+
+func (s *SSHTunnelDialerSuite) TestClose(c *C) {
+	bastion := &fakeSSHBastion{}
+	dialer := NewSSHTunnelDialer("bastion:22", bastion.Dial, time.Hour)
+
+	_, err := dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, IsNil)
+
+	c.Assert(dialer.Close(), IsNil)
+	c.Check(bastion.Clients()[0].IsClosed(), IsTrue)
+
+	_, err = dialer.Dial("tcp", "db1:3306")
+	c.Assert(err, NotNil)
+
+	// Closing twice is a no-op.
+	c.Assert(dialer.Close(), IsNil)
+}
@@ -0,0 +1,86 @@
+package sync2
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// COWMap is a copy-on-write map, optimized for read-mostly workloads (e.g.,
+// configuration maps which are read millions of times per second, but are
+// rarely updated).  Load is a single atomic load plus a map lookup, without
+// any locking.  Store and Delete copy the entire map, update the copy, and
+// atomically swap in the copy; i.e., each mutation is O(n).
+//
+// Keys must be comparable (see the builtin map type's requirements).  The
+// zero value is an empty map ready to use.  COWMap is thread safe, but must
+// not be copied after first use.
+type COWMap struct {
+	// Holds a map[interface{}]interface{}, which is never mutated once
+	// stored.
+	current atomic.Value
+
+	// Serializes mutations.
+	mutex sync.Mutex
+}
+
+func (m *COWMap) load() map[interface{}]interface{} {
+	current, _ := m.current.Load().(map[interface{}]interface{})
+	return current
+}
+
+// Load returns the value stored for the key, and whether the key is present.
+func (m *COWMap) Load(key interface{}) (value interface{}, ok bool) {
+	value, ok = m.load()[key]
+	return value, ok
+}
+
+// Store sets the value for the key.
+func (m *COWMap) Store(key interface{}, value interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current := m.load()
+	updated := make(map[interface{}]interface{}, len(current)+1)
+	for k, v := range current {
+		updated[k] = v
+	}
+	updated[key] = value
+
+	m.current.Store(updated)
+}
+
+// Delete removes the key from the map (if present).
+func (m *COWMap) Delete(key interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current := m.load()
+	if _, ok := current[key]; !ok {
+		return
+	}
+
+	updated := make(map[interface{}]interface{}, len(current)-1)
+	for k, v := range current {
+		if k != key {
+			updated[k] = v
+		}
+	}
+
+	m.current.Store(updated)
+}
+
+// Len returns the number of entries in the map.
+func (m *COWMap) Len() int {
+	return len(m.load())
+}
+
+// Range calls f for each entry in the map (in no particular order), until f
+// returns false.  Range iterates over a consistent snapshot of the map;
+// mutations made during iteration (including by f) are not observed.
+func (m *COWMap) Range(f func(key interface{}, value interface{}) bool) {
+	for k, v := range m.load() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
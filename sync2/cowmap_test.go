@@ -0,0 +1,181 @@
+package sync2
+
+import (
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type COWMapSuite struct {
+}
+
+var _ = Suite(&COWMapSuite{})
+
+func (s *COWMapSuite) TestEmpty(c *C) {
+	m := &COWMap{}
+
+	_, ok := m.Load("foo")
+	c.Assert(ok, IsFalse)
+	c.Assert(m.Len(), Equals, 0)
+
+	m.Delete("foo")
+	c.Assert(m.Len(), Equals, 0)
+
+	m.Range(func(key interface{}, value interface{}) bool {
+		c.Fatalf("Unexpected entry: %v", key)
+		return true
+	})
+}
+
+func (s *COWMapSuite) TestStoreLoadDelete(c *C) {
+	m := &COWMap{}
+
+	m.Store("foo", 1)
+	m.Store("bar", 2)
+	m.Store(3, nil)
+	m.Store("foo", 4)
+
+	c.Assert(m.Len(), Equals, 3)
+
+	val, ok := m.Load("foo")
+	c.Assert(ok, IsTrue)
+	c.Assert(val, Equals, 4)
+
+	val, ok = m.Load(3)
+	c.Assert(ok, IsTrue)
+	c.Assert(val, IsNil)
+
+	m.Delete("bar")
+	c.Assert(m.Len(), Equals, 2)
+
+	_, ok = m.Load("bar")
+	c.Assert(ok, IsFalse)
+
+	entries := make(map[interface{}]interface{})
+	m.Range(func(key interface{}, value interface{}) bool {
+		entries[key] = value
+		return true
+	})
+	c.Assert(entries, DeepEquals, map[interface{}]interface{}{
+		"foo": 4,
+		3:     nil,
+	})
+}
+
+func (s *COWMapSuite) TestRangeSnapshot(c *C) {
+	m := &COWMap{}
+	m.Store(1, 1)
+	m.Store(2, 2)
+
+	visited := 0
+	m.Range(func(key interface{}, value interface{}) bool {
+		visited++
+		m.Store(key.(int)+10, value)
+		m.Delete(key)
+		return true
+	})
+	c.Assert(visited, Equals, 2)
+
+	_, ok := m.Load(1)
+	c.Assert(ok, IsFalse)
+	c.Assert(m.Len(), Equals, 2)
+
+	visited = 0
+	m.Range(func(key interface{}, value interface{}) bool {
+		visited++
+		return false
+	})
+	c.Assert(visited, Equals, 1)
+}
+
+func (s *COWMapSuite) TestConcurrentAccess(c *C) {
+	m := &COWMap{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Store(i*100+j, j)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if val, ok := m.Load(j); ok {
+					c.Check(val, Equals, j%100)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(m.Len(), Equals, 1000)
+}
+
+const cowMapBenchmarkSize = 1000
+
+// Each goroutine performs 1 store per 100 operations (i.e., a 99% read
+// workload).
+func benchmarkReadMostly(
+	b *testing.B,
+	load func(key int) (interface{}, bool),
+	store func(key int, value int)) {
+
+	for i := 0; i < cowMapBenchmarkSize; i++ {
+		store(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := i % cowMapBenchmarkSize
+			if i%100 == 0 {
+				store(key, i)
+			} else {
+				_, _ = load(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCOWMapReadMostly(b *testing.B) {
+	m := &COWMap{}
+	benchmarkReadMostly(
+		b,
+		func(key int) (interface{}, bool) { return m.Load(key) },
+		func(key int, value int) { m.Store(key, value) })
+}
+
+func BenchmarkSyncMapReadMostly(b *testing.B) {
+	m := &sync.Map{}
+	benchmarkReadMostly(
+		b,
+		func(key int) (interface{}, bool) { return m.Load(key) },
+		func(key int, value int) { m.Store(key, value) })
+}
+
+func BenchmarkRWMutexMapReadMostly(b *testing.B) {
+	m := make(map[int]int)
+	mutex := sync.RWMutex{}
+	benchmarkReadMostly(
+		b,
+		func(key int) (interface{}, bool) {
+			mutex.RLock()
+			defer mutex.RUnlock()
+			val, ok := m[key]
+			return val, ok
+		},
+		func(key int, value int) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			m[key] = value
+		})
+}
@@ -0,0 +1,86 @@
+package sync2
+
+import (
+	"context"
+)
+
+type mapResult struct {
+	out interface{}
+	err error
+}
+
+// MapReduce applies mapFn to all items in parallel using up to workers
+// goroutines (at least one worker is used), and folds the map results into
+// initial using reduceFn.  The results are passed to reduceFn in an
+// unspecified order.  reduceFn is only called from the calling goroutine,
+// hence it does not need to be thread safe.
+//
+// The first error returned by mapFn cancels the remaining work and is
+// returned (along with the partially reduced value).  Similarly, the
+// context's error is returned if the context is done before all items are
+// processed.  NOTE: mapFn calls which are already running are not
+// interrupted.
+func MapReduce(
+	ctx context.Context,
+	items []interface{},
+	mapFn func(item interface{}) (interface{}, error),
+	reduceFn func(acc interface{}, out interface{}) interface{},
+	initial interface{},
+	workers int) (interface{}, error) {
+
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	if err := ctx.Err(); err != nil {
+		return initial, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indices := make(chan int)
+	results := make(chan mapResult)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for idx := range indices {
+				out, err := mapFn(items[idx])
+				select {
+				case results <- mapResult{out: out, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+		for idx := range items {
+			select {
+			case indices <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	acc := initial
+	for i := 0; i < len(items); i++ {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				return acc, result.err
+			}
+			acc = reduceFn(acc, result.out)
+		case <-ctx.Done():
+			return acc, ctx.Err()
+		}
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,121 @@
+package sync2
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type MapReduceSuite struct {
+}
+
+var _ = Suite(&MapReduceSuite{})
+
+func square(item interface{}) (interface{}, error) {
+	v := item.(int)
+	return v * v, nil
+}
+
+func sum(acc interface{}, out interface{}) interface{} {
+	return acc.(int) + out.(int)
+}
+
+func intItems(n int) []interface{} {
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = i + 1
+	}
+	return items
+}
+
+func (s *MapReduceSuite) TestSumOfSquares(c *C) {
+	for _, workers := range []int{-1, 0, 1, 4, 1000} {
+		result, err := MapReduce(
+			context.Background(),
+			intItems(100),
+			square,
+			sum,
+			0,
+			workers)
+		c.Assert(err, IsNil)
+		c.Assert(result, Equals, 338350)
+	}
+}
+
+func (s *MapReduceSuite) TestNoItems(c *C) {
+	result, err := MapReduce(
+		context.Background(),
+		nil,
+		square,
+		sum,
+		42,
+		4)
+	c.Assert(err, IsNil)
+	c.Assert(result, Equals, 42)
+}
+
+func (s *MapReduceSuite) TestRunsInParallel(c *C) {
+	var running int32
+	var maxRunning int32
+
+	_, err := MapReduce(
+		context.Background(),
+		intItems(20),
+		func(item interface{}) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return item, nil
+		},
+		sum,
+		0,
+		4)
+	c.Assert(err, IsNil)
+
+	max := atomic.LoadInt32(&maxRunning)
+	c.Assert(max > 1, Equals, true)
+	c.Assert(max <= 4, Equals, true)
+}
+
+func (s *MapReduceSuite) TestErrorCancelsRemainingWork(c *C) {
+	var calls int32
+
+	_, err := MapReduce(
+		context.Background(),
+		intItems(1000),
+		func(item interface{}) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			if item.(int) == 10 {
+				return nil, errors.New("map failed")
+			}
+			time.Sleep(time.Millisecond)
+			return item, nil
+		},
+		sum,
+		0,
+		2)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, "map failed(.|\n)*")
+
+	// Give the workers a chance to (incorrectly) continue processing.
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&calls) < 1000, Equals, true)
+}
+
+func (s *MapReduceSuite) TestContextCanceled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := MapReduce(ctx, intItems(10), square, sum, 0, 2)
+	c.Assert(err, Equals, context.Canceled)
+}
@@ -0,0 +1,240 @@
+package sync2
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type subscriber struct {
+	ch chan interface{}
+
+	// done is closed when the subscriber is unsubscribed, to unblock
+	// blocking publishers.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// Blocking publishers send to ch without holding the pubsub's lock.
+	// sendMutex ensures ch is only closed once no such send is in flight.
+	sendMutex sync.RWMutex
+	isClosed  bool
+}
+
+func (s *subscriber) markDone() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// This closes the subscriber's channel, after unblocking blocking
+// publishers.
+func (s *subscriber) close() {
+	s.markDone()
+
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+
+	s.isClosed = true
+	close(s.ch)
+}
+
+// This waits for room in the subscriber's channel, and sends the message.
+// The message is skipped if the subscriber is (or becomes) unsubscribed.
+func (s *subscriber) send(
+	ctx context.Context,
+	closing <-chan struct{},
+	msg interface{}) error {
+
+	s.sendMutex.RLock()
+	defer s.sendMutex.RUnlock()
+
+	if s.isClosed {
+		return nil
+	}
+
+	select {
+	case s.ch <- msg:
+	case <-s.done:
+	case <-closing:
+		return errors.New("PubSub is closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// PubSub is an in-process topic based message broker, which distributes
+// each published message to all of the topic's subscribers.  Messages are
+// delivered through buffered channels; each subscriber receives the
+// messages in publish order.
+//
+// PubSub is thread safe.
+type PubSub struct {
+	bufferSize int
+
+	// closing is closed by Close to unblock blocking publishers.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	mutex    sync.RWMutex
+	topics   map[string]map[*subscriber]struct{}
+	isClosed bool
+}
+
+// This returns a pubsub broker whose subscriber channels buffer up to
+// bufferSize messages.
+func NewPubSub(bufferSize int) *PubSub {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	return &PubSub{
+		bufferSize: bufferSize,
+		closing:    make(chan struct{}),
+		topics:     make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe returns a channel which receives the topic's messages, and a
+// function which unsubscribes the channel (the function closes the channel,
+// and is safe to call multiple times).  The returned channel is already
+// closed if the broker is closed.
+func (p *PubSub) Subscribe(topic string) (<-chan interface{}, func()) {
+	sub := &subscriber{
+		ch:   make(chan interface{}, p.bufferSize),
+		done: make(chan struct{}),
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isClosed {
+		sub.close()
+		return sub.ch, func() {}
+	}
+
+	subs, ok := p.topics[topic]
+	if !ok {
+		subs = make(map[*subscriber]struct{})
+		p.topics[topic] = subs
+	}
+	subs[sub] = struct{}{}
+
+	return sub.ch, func() { p.unsubscribe(topic, sub) }
+}
+
+func (p *PubSub) unsubscribe(topic string, sub *subscriber) {
+	// Unblock publishers which are waiting on the subscriber before taking
+	// the write lock.
+	sub.markDone()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	subs, ok := p.topics[topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return // already unsubscribed
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(p.topics, topic)
+	}
+	sub.close()
+}
+
+// Publish sends the message to all of the topic's subscribers without
+// blocking.  The message is dropped for subscribers whose channel is full.
+// This returns the number of subscribers which received the message.
+func (p *PubSub) Publish(topic string, msg interface{}) int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	numSent := 0
+	for sub := range p.topics[topic] {
+		select {
+		case sub.ch <- msg:
+			numSent++
+		default:
+		}
+	}
+	return numSent
+}
+
+// PublishBlocking sends the message to all of the topic's subscribers,
+// waiting for room in full subscriber channels.  Subscribers which
+// unsubscribe while the message is pending are skipped.  This returns an
+// error if the context is done before the message is delivered to all
+// subscribers (some subscribers may have received the message), or if the
+// broker is closed.
+func (p *PubSub) PublishBlocking(
+	ctx context.Context,
+	topic string,
+	msg interface{}) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Snapshot the subscribers; the lock is not held while waiting on
+	// subscribers, which may themselves (un)subscribe.
+	p.mutex.RLock()
+	if p.isClosed {
+		p.mutex.RUnlock()
+		return errors.New("PubSub is closed")
+	}
+
+	subs := make([]*subscriber, 0, len(p.topics[topic]))
+	for sub := range p.topics[topic] {
+		subs = append(subs, sub)
+	}
+	p.mutex.RUnlock()
+
+	for _, sub := range subs {
+		err := sub.send(ctx, p.closing, msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Topics returns the (sorted) topics which have at least one subscriber.
+func (p *PubSub) Topics() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	topics := make([]string, 0, len(p.topics))
+	for topic := range p.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Close unsubscribes all subscribers, i.e., closes all subscriber channels
+// (subscribers may still drain their buffered messages).  Publishing to a
+// closed broker is a no-op, and subscribing to a closed broker returns a
+// closed channel.
+func (p *PubSub) Close() {
+	// Unblock blocking publishers before taking the write lock.
+	p.closeOnce.Do(func() { close(p.closing) })
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isClosed {
+		return
+	}
+	p.isClosed = true
+
+	for _, subs := range p.topics {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+	p.topics = make(map[string]map[*subscriber]struct{})
+}
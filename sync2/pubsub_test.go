@@ -0,0 +1,211 @@
+package sync2
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+	. "github.com/dropbox/godropbox/gocheck2"
+)
+
+type PubSubSuite struct {
+}
+
+var _ = Suite(&PubSubSuite{})
+
+func (s *PubSubSuite) TestPublish(c *C) {
+	p := NewPubSub(2)
+	defer p.Close()
+
+	a, unsubA := p.Subscribe("foo")
+	defer unsubA()
+	b, unsubB := p.Subscribe("foo")
+	defer unsubB()
+	other, unsubOther := p.Subscribe("bar")
+	defer unsubOther()
+
+	c.Assert(p.Publish("foo", 1), Equals, 2)
+	c.Assert(p.Publish("foo", 2), Equals, 2)
+	c.Assert(p.Publish("baz", 3), Equals, 0)
+
+	c.Assert(<-a, Equals, 1)
+	c.Assert(<-a, Equals, 2)
+	c.Assert(<-b, Equals, 1)
+	c.Assert(<-b, Equals, 2)
+
+	select {
+	case msg := <-other:
+		c.Fatalf("Unexpected message: %v", msg)
+	default:
+	}
+}
+
+func (s *PubSubSuite) TestPublishDropsWhenFull(c *C) {
+	p := NewPubSub(1)
+	defer p.Close()
+
+	ch, unsubscribe := p.Subscribe("foo")
+	defer unsubscribe()
+
+	c.Assert(p.Publish("foo", 1), Equals, 1)
+	c.Assert(p.Publish("foo", 2), Equals, 0)
+
+	c.Assert(<-ch, Equals, 1)
+	select {
+	case msg := <-ch:
+		c.Fatalf("Unexpected message: %v", msg)
+	default:
+	}
+}
+
+func (s *PubSubSuite) TestPublishBlocking(c *C) {
+	p := NewPubSub(0)
+	defer p.Close()
+
+	ch, unsubscribe := p.Subscribe("foo")
+	defer unsubscribe()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.PublishBlocking(context.Background(), "foo", "msg")
+	}()
+
+	c.Assert(<-ch, Equals, "msg")
+	c.Assert(<-errs, IsNil)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+
+	err := p.PublishBlocking(ctx, "foo", "dropped")
+	c.Assert(err, Equals, context.DeadlineExceeded)
+}
+
+func (s *PubSubSuite) TestUnsubscribeUnblocksPublisher(c *C) {
+	p := NewPubSub(0)
+	defer p.Close()
+
+	ch, unsubscribe := p.Subscribe("foo")
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.PublishBlocking(context.Background(), "foo", "msg")
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	unsubscribe()
+	unsubscribe() // no-op
+
+	c.Assert(<-errs, IsNil)
+
+	_, ok := <-ch
+	c.Assert(ok, IsFalse)
+	c.Assert(p.Topics(), DeepEquals, []string{})
+}
+
+func (s *PubSubSuite) TestBlockedPublisherDoesNotBlockSubscribers(c *C) {
+	p := NewPubSub(0)
+	defer p.Close()
+
+	ch, unsubscribe := p.Subscribe("foo")
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.PublishBlocking(context.Background(), "foo", "msg")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	subscribed := make(chan struct{})
+	go func() {
+		_, unsubBar := p.Subscribe("bar")
+		unsubBar()
+		close(subscribed)
+	}()
+
+	select {
+	case <-subscribed:
+	case <-time.After(time.Second):
+		c.Fatal("Subscribe blocked on a blocking publisher")
+	}
+
+	c.Assert(<-ch, Equals, "msg")
+	c.Assert(<-errs, IsNil)
+
+	go func() {
+		errs <- p.PublishBlocking(context.Background(), "foo", "msg2")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// Unsubscribing while a publisher is blocked on the subscriber does not
+	// deadlock.
+	unsubscribe()
+	c.Assert(<-errs, IsNil)
+
+	_, ok := <-ch
+	c.Assert(ok, IsFalse)
+}
+
+func (s *PubSubSuite) TestTopics(c *C) {
+	p := NewPubSub(1)
+	defer p.Close()
+
+	c.Assert(p.Topics(), DeepEquals, []string{})
+
+	_, unsubFoo := p.Subscribe("foo")
+	_, unsubBar1 := p.Subscribe("bar")
+	_, unsubBar2 := p.Subscribe("bar")
+	c.Assert(p.Topics(), DeepEquals, []string{"bar", "foo"})
+
+	unsubFoo()
+	unsubBar1()
+	c.Assert(p.Topics(), DeepEquals, []string{"bar"})
+
+	unsubBar2()
+	c.Assert(p.Topics(), DeepEquals, []string{})
+}
+
+func (s *PubSubSuite) TestClose(c *C) {
+	p := NewPubSub(2)
+
+	ch, unsubscribe := p.Subscribe("foo")
+	c.Assert(p.Publish("foo", 1), Equals, 1)
+
+	blocked, unsubBlocked := p.Subscribe("bar")
+	defer unsubBlocked()
+	c.Assert(p.Publish("bar", 1), Equals, 1)
+	c.Assert(p.Publish("bar", 2), Equals, 1)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.PublishBlocking(context.Background(), "bar", 3)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	p.Close()
+	p.Close() // no-op
+
+	c.Assert(<-errs, NotNil)
+
+	// Buffered messages are still drained.
+	c.Assert(<-ch, Equals, 1)
+	_, ok := <-ch
+	c.Assert(ok, IsFalse)
+	unsubscribe()
+
+	c.Assert(<-blocked, Equals, 1)
+	c.Assert(<-blocked, Equals, 2)
+	_, ok = <-blocked
+	c.Assert(ok, IsFalse)
+
+	c.Assert(p.Topics(), DeepEquals, []string{})
+	c.Assert(p.Publish("foo", 2), Equals, 0)
+	err := p.PublishBlocking(context.Background(), "foo", 2)
+	c.Assert(errors.GetMessage(err), Equals, "PubSub is closed")
+
+	closed, _ := p.Subscribe("foo")
+	_, ok = <-closed
+	c.Assert(ok, IsFalse)
+}
@@ -0,0 +1,122 @@
+package sync2
+
+import (
+	"io"
+	"sync"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type refCountedResource struct {
+	resource io.Closer
+
+	// The number of outstanding acquisitions, plus one while the resource
+	// is RefCounted's current resource.  Protected by RefCounted's mutex.
+	refs int
+}
+
+// RefCounted manages a shared resource (e.g., a connection, or a TLS
+// certificate) which is closed once all of its users are done with it.  Users
+// Acquire the resource, and invoke the returned release function when they
+// are done.  The resource may be hot swapped via ReplaceResource; existing
+// holders keep using the old resource, which is closed once the last holder
+// releases it.
+//
+// RefCounted is thread safe.
+type RefCounted struct {
+	mutex   sync.Mutex
+	current *refCountedResource // nil once closed
+}
+
+// This returns a RefCounted which manages the resource.  RefCounted takes
+// ownership of the resource.
+func NewRefCounted(resource io.Closer) *RefCounted {
+	return &RefCounted{
+		current: &refCountedResource{
+			resource: resource,
+			refs:     1,
+		},
+	}
+}
+
+// Acquire returns the current resource, and a function which releases the
+// caller's reference.  The release function must be called exactly once
+// (subsequent calls are no-ops).  When the last reference to a replaced
+// resource is released, the resource is closed (by the releasing goroutine);
+// the close error is discarded.
+//
+// Acquire returns a nil resource (and a no-op release function) once the
+// RefCounted is closed.
+func (r *RefCounted) Acquire() (io.Closer, func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current := r.current
+	if current == nil {
+		return nil, func() {}
+	}
+
+	current.refs++
+
+	once := sync.Once{}
+	return current.resource, func() {
+		once.Do(func() {
+			_ = r.release(current)
+		})
+	}
+}
+
+// ReplaceResource replaces the current resource with newResource for all
+// future Acquire calls.  The old resource is closed once all of its holders
+// release it; when there are no holders, the old resource is closed
+// immediately, and its close error is returned.  RefCounted takes ownership
+// of newResource, unless the RefCounted is closed (in which case an error is
+// returned).
+func (r *RefCounted) ReplaceResource(newResource io.Closer) error {
+	r.mutex.Lock()
+	old := r.current
+	if old == nil {
+		r.mutex.Unlock()
+		return errors.New("RefCounted is closed")
+	}
+
+	r.current = &refCountedResource{
+		resource: newResource,
+		refs:     1,
+	}
+	r.mutex.Unlock()
+
+	return r.release(old)
+}
+
+// Close releases RefCounted's reference to the current resource.  The
+// resource is closed once all of its holders release it; when there are no
+// holders, the resource is closed immediately, and its close error is
+// returned.  Subsequent calls to Close are no-ops.
+func (r *RefCounted) Close() error {
+	r.mutex.Lock()
+	current := r.current
+	r.current = nil
+	r.mutex.Unlock()
+
+	if current == nil {
+		return nil
+	}
+
+	return r.release(current)
+}
+
+// This drops a single reference to the resource, and closes the resource
+// when the reference is the last one.
+func (r *RefCounted) release(res *refCountedResource) error {
+	r.mutex.Lock()
+	res.refs--
+	refs := res.refs
+	r.mutex.Unlock()
+
+	if refs > 0 {
+		return nil
+	}
+
+	return res.resource.Close()
+}
@@ -0,0 +1,139 @@
+package sync2
+
+import (
+	"sync"
+	"sync/atomic"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+type testResource struct {
+	closed int32
+	err    error
+}
+
+func (r *testResource) Close() error {
+	atomic.AddInt32(&r.closed, 1)
+	return r.err
+}
+
+func (r *testResource) numClosed() int32 {
+	return atomic.LoadInt32(&r.closed)
+}
+
+type RefCountedSuite struct {
+}
+
+var _ = Suite(&RefCountedSuite{})
+
+func (s *RefCountedSuite) TestAcquireRelease(c *C) {
+	res := &testResource{}
+	r := NewRefCounted(res)
+
+	acquired, release1 := r.Acquire()
+	c.Assert(acquired, Equals, res)
+
+	_, release2 := r.Acquire()
+
+	release1()
+	release1() // no-op
+	release2()
+
+	// RefCounted still holds a reference.
+	c.Assert(res.numClosed(), Equals, int32(0))
+
+	c.Assert(r.Close(), IsNil)
+	c.Assert(res.numClosed(), Equals, int32(1))
+
+	c.Assert(r.Close(), IsNil)
+	c.Assert(res.numClosed(), Equals, int32(1))
+
+	acquired, release := r.Acquire()
+	c.Assert(acquired, IsNil)
+	release()
+}
+
+func (s *RefCountedSuite) TestCloseWithHolders(c *C) {
+	res := &testResource{}
+	r := NewRefCounted(res)
+
+	_, release := r.Acquire()
+
+	c.Assert(r.Close(), IsNil)
+	c.Assert(res.numClosed(), Equals, int32(0))
+
+	release()
+	c.Assert(res.numClosed(), Equals, int32(1))
+}
+
+func (s *RefCountedSuite) TestReplaceResource(c *C) {
+	a := &testResource{}
+	b := &testResource{}
+	r := NewRefCounted(a)
+
+	acquired, releaseA := r.Acquire()
+	c.Assert(acquired, Equals, a)
+
+	c.Assert(r.ReplaceResource(b), IsNil)
+
+	// The holder keeps the old resource open.
+	c.Assert(a.numClosed(), Equals, int32(0))
+
+	acquired, releaseB := r.Acquire()
+	c.Assert(acquired, Equals, b)
+
+	releaseA()
+	c.Assert(a.numClosed(), Equals, int32(1))
+
+	releaseB()
+	c.Assert(b.numClosed(), Equals, int32(0))
+
+	// Without holders, the old resource is closed immediately.
+	d := &testResource{err: errors.New("close failed")}
+	c.Assert(r.ReplaceResource(d), IsNil)
+	c.Assert(b.numClosed(), Equals, int32(1))
+
+	c.Assert(r.Close(), NotNil)
+	c.Assert(d.numClosed(), Equals, int32(1))
+
+	e := &testResource{}
+	c.Assert(r.ReplaceResource(e), NotNil)
+	c.Assert(e.numClosed(), Equals, int32(0))
+}
+
+func (s *RefCountedSuite) TestConcurrent(c *C) {
+	resources := []*testResource{}
+	for i := 0; i < 10; i++ {
+		resources = append(resources, &testResource{})
+	}
+
+	r := NewRefCounted(resources[0])
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				res, release := r.Acquire()
+				if res.(*testResource).numClosed() != 0 {
+					panic("Acquired a closed resource")
+				}
+				release()
+			}
+		}()
+	}
+
+	for _, res := range resources[1:] {
+		c.Assert(r.ReplaceResource(res), IsNil)
+	}
+
+	wg.Wait()
+	c.Assert(r.Close(), IsNil)
+
+	for _, res := range resources {
+		c.Check(res.numClosed(), Equals, int32(1))
+	}
+}
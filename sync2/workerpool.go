@@ -0,0 +1,193 @@
+package sync2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+)
+
+const (
+	defaultWorkerIdleTimeout = time.Minute
+	defaultSpawnThreshold    = 1
+)
+
+// WorkerPool is a goroutine pool which grows and shrinks with load.  The pool
+// always keeps at least minWorkers workers alive.  When the number of queued
+// (not yet running) tasks exceeds SpawnThreshold, additional workers are
+// spawned, up to maxWorkers.  Workers above minWorkers terminate after being
+// idle for IdleTimeout.
+type WorkerPool struct {
+	// Workers above minWorkers are terminated after being idle for this long.
+	// NOTE: This must be set before the first Submit call.
+	IdleTimeout time.Duration
+
+	// A new worker is spawned (if the pool is not at maxWorkers) when the
+	// number of queued tasks exceeds this threshold.  NOTE: This must be set
+	// before the first Submit call.
+	SpawnThreshold int
+
+	minWorkers int
+	maxWorkers int
+
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	queue       []func()
+	numWorkers  int
+	idleWorkers int
+	isShutdown  bool
+
+	workersWg sync.WaitGroup
+}
+
+// This returns a new WorkerPool with minWorkers workers started.  This panics
+// if minWorkers is negative, maxWorkers is less than 1, or minWorkers is
+// greater than maxWorkers.
+func NewWorkerPool(minWorkers, maxWorkers int) *WorkerPool {
+	if minWorkers < 0 || maxWorkers < 1 || minWorkers > maxWorkers {
+		panic("Invalid WorkerPool size")
+	}
+
+	p := &WorkerPool{
+		IdleTimeout:    defaultWorkerIdleTimeout,
+		SpawnThreshold: defaultSpawnThreshold,
+		minWorkers:     minWorkers,
+		maxWorkers:     maxWorkers,
+	}
+	p.cond = sync.NewCond(&p.mutex)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i := 0; i < minWorkers; i++ {
+		p.spawnWorker()
+	}
+
+	return p
+}
+
+// Submit queues fn for execution.  This returns an error if the context is
+// already done or if the pool is shut down.
+func (p *WorkerPool) Submit(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isShutdown {
+		return errors.New("WorkerPool is shut down")
+	}
+
+	p.queue = append(p.queue, fn)
+
+	if p.numWorkers < p.maxWorkers &&
+		(p.numWorkers == 0 || len(p.queue) > p.SpawnThreshold) {
+
+		p.spawnWorker()
+	} else if p.idleWorkers > 0 {
+		p.cond.Signal()
+	}
+
+	return nil
+}
+
+// ActiveWorkers returns the number of live workers (both busy and idle).
+func (p *WorkerPool) ActiveWorkers() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.numWorkers
+}
+
+// QueueDepth returns the number of tasks waiting for a worker.
+func (p *WorkerPool) QueueDepth() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.queue)
+}
+
+// Shutdown stops accepting new tasks, and waits for the workers to finish all
+// queued tasks.  This returns the context's error if the context is done
+// before the workers finish (the workers will continue to drain the queue in
+// the background).
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	p.mutex.Lock()
+	p.isShutdown = true
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.workersWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NOTE: The caller must hold the mutex.
+func (p *WorkerPool) spawnWorker() {
+	p.numWorkers++
+	p.workersWg.Add(1)
+	go p.runWorker()
+}
+
+func (p *WorkerPool) runWorker() {
+	defer p.workersWg.Done()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for {
+		idleSince := time.Now()
+		for len(p.queue) == 0 {
+			if p.isShutdown {
+				p.numWorkers--
+				return
+			}
+
+			canRetire := p.numWorkers > p.minWorkers
+			if canRetire && time.Since(idleSince) >= p.IdleTimeout {
+				p.numWorkers--
+				return
+			}
+
+			var timer *time.Timer
+			if canRetire {
+				// sync.Cond does not support timed waits.  Wake up all
+				// waiters once the idle timeout expires so that they can
+				// recheck their idle time.
+				timer = time.AfterFunc(p.IdleTimeout, func() {
+					p.mutex.Lock()
+					p.cond.Broadcast()
+					p.mutex.Unlock()
+				})
+			}
+
+			p.idleWorkers++
+			p.cond.Wait()
+			p.idleWorkers--
+
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+
+		fn := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+
+		p.mutex.Unlock()
+		fn()
+		p.mutex.Lock()
+	}
+}
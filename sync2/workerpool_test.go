@@ -0,0 +1,126 @@
+package sync2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type WorkerPoolSuite struct {
+}
+
+var _ = Suite(&WorkerPoolSuite{})
+
+func waitFor(c *C, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *WorkerPoolSuite) TestInvalidSize(c *C) {
+	c.Assert(func() { NewWorkerPool(-1, 1) }, Panics, "Invalid WorkerPool size")
+	c.Assert(func() { NewWorkerPool(0, 0) }, Panics, "Invalid WorkerPool size")
+	c.Assert(func() { NewWorkerPool(2, 1) }, Panics, "Invalid WorkerPool size")
+}
+
+func (s *WorkerPoolSuite) TestSubmit(c *C) {
+	p := NewWorkerPool(2, 4)
+	c.Assert(p.ActiveWorkers(), Equals, 2)
+
+	wg := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+	count := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		err := p.Submit(context.Background(), func() {
+			mutex.Lock()
+			count++
+			mutex.Unlock()
+			wg.Done()
+		})
+		c.Assert(err, IsNil)
+	}
+	wg.Wait()
+	c.Assert(count, Equals, 100)
+
+	c.Assert(p.Shutdown(context.Background()), IsNil)
+	c.Assert(p.ActiveWorkers(), Equals, 0)
+
+	err := p.Submit(context.Background(), func() {})
+	c.Assert(err, NotNil)
+}
+
+func (s *WorkerPoolSuite) TestSubmitCanceledContext(c *C) {
+	p := NewWorkerPool(1, 1)
+	defer p.Shutdown(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.Assert(p.Submit(ctx, func() {}), Equals, context.Canceled)
+	c.Assert(p.QueueDepth(), Equals, 0)
+}
+
+func (s *WorkerPoolSuite) TestResize(c *C) {
+	p := NewWorkerPool(1, 3)
+	p.IdleTimeout = 10 * time.Millisecond
+	p.SpawnThreshold = 1
+
+	block := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		err := p.Submit(context.Background(), func() { <-block })
+		c.Assert(err, IsNil)
+	}
+
+	waitFor(c, func() bool { return p.QueueDepth() == 2 })
+	c.Assert(p.ActiveWorkers(), Equals, 3)
+
+	close(block)
+	waitFor(c, func() bool { return p.QueueDepth() == 0 })
+
+	// Idle workers above minWorkers are terminated.
+	waitFor(c, func() bool { return p.ActiveWorkers() == 1 })
+
+	c.Assert(p.Shutdown(context.Background()), IsNil)
+}
+
+func (s *WorkerPoolSuite) TestMinWorkersZero(c *C) {
+	p := NewWorkerPool(0, 1)
+	p.IdleTimeout = time.Millisecond
+	c.Assert(p.ActiveWorkers(), Equals, 0)
+
+	done := make(chan struct{})
+	err := p.Submit(context.Background(), func() { close(done) })
+	c.Assert(err, IsNil)
+	<-done
+
+	waitFor(c, func() bool { return p.ActiveWorkers() == 0 })
+
+	c.Assert(p.Shutdown(context.Background()), IsNil)
+}
+
+func (s *WorkerPoolSuite) TestShutdownTimeout(c *C) {
+	p := NewWorkerPool(1, 1)
+
+	block := make(chan struct{})
+	err := p.Submit(context.Background(), func() { <-block })
+	c.Assert(err, IsNil)
+	err = p.Submit(context.Background(), func() {})
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Assert(p.Shutdown(ctx), Equals, context.DeadlineExceeded)
+
+	// Queued tasks are drained after shutdown.
+	close(block)
+	c.Assert(p.Shutdown(context.Background()), IsNil)
+	c.Assert(p.QueueDepth(), Equals, 0)
+	c.Assert(p.ActiveWorkers(), Equals, 0)
+}